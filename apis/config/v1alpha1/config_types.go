@@ -35,6 +35,9 @@ type ConfigSpec struct {
 
 	// Configuration for readiness tracker
 	Readiness ReadinessSpec `json:"readiness,omitempty"`
+
+	// Configuration for per-namespace enforcement overrides
+	Overrides Overrides `json:"overrides,omitempty"`
 }
 
 type Validation struct {
@@ -67,6 +70,18 @@ type MatchEntry struct {
 	ExcludedNamespaces []util.PrefixWildcard `json:"excludedNamespaces,omitempty"`
 }
 
+type Overrides struct {
+	// Constraints which NamespacePolicyOverrides may downgrade from deny to warn
+	Overridable []OverridableConstraint `json:"overridable,omitempty"`
+}
+
+type OverridableConstraint struct {
+	// Kind of the constraints, for example `K8sRequiredLabels`
+	Kind string `json:"kind"`
+	// Name of the constraint. If unset, every constraint of Kind may be overridden
+	Name string `json:"name,omitempty"`
+}
+
 type ReadinessSpec struct {
 	StatsEnabled bool `json:"statsEnabled,omitempty"`
 }
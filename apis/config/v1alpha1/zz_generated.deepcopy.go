@@ -96,6 +96,7 @@ func (in *ConfigSpec) DeepCopyInto(out *ConfigSpec) {
 		}
 	}
 	out.Readiness = in.Readiness
+	in.Overrides.DeepCopyInto(&out.Overrides)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigSpec.
@@ -163,6 +164,41 @@ func (in *MatchEntry) DeepCopy() *MatchEntry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverridableConstraint) DeepCopyInto(out *OverridableConstraint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverridableConstraint.
+func (in *OverridableConstraint) DeepCopy() *OverridableConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(OverridableConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Overrides) DeepCopyInto(out *Overrides) {
+	*out = *in
+	if in.Overridable != nil {
+		in, out := &in.Overridable, &out.Overridable
+		*out = make([]OverridableConstraint, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Overrides.
+func (in *Overrides) DeepCopy() *Overrides {
+	if in == nil {
+		return nil
+	}
+	out := new(Overrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReadinessSpec) DeepCopyInto(out *ReadinessSpec) {
 	*out = *in
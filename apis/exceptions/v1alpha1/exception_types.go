@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExceptionSpec defines the desired state of Exception.
+type ExceptionSpec struct {
+	// Constraints lists the constraints the matched objects are exempted from.
+	// +kubebuilder:validation:MinItems=1
+	Constraints []ConstraintReference `json:"constraints"`
+	// Match selects the objects which are exempted. An empty match selects
+	// every object.
+	Match match.Match `json:"match,omitempty"`
+	// ExpiresAt is when the Exception stops exempting objects.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+	// Justification records why the Exception was granted.
+	Justification Justification `json:"justification"`
+}
+
+// ConstraintReference refers to the constraints of a kind, or a single one.
+type ConstraintReference struct {
+	// Kind is the kind of the constraint, for example `K8sRequiredLabels`.
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+	// Name is the name of the constraint. If unset, every constraint of Kind
+	// is referred to.
+	Name string `json:"name,omitempty"`
+}
+
+// Justification records why an Exception was granted.
+type Justification struct {
+	// Reason explains why the objects are exempted.
+	// +kubebuilder:validation:MinLength=1
+	Reason string `json:"reason"`
+	// Owner is who is responsible for the Exception.
+	Owner string `json:"owner,omitempty"`
+	// Ticket refers to where the Exception is tracked.
+	Ticket string `json:"ticket,omitempty"`
+}
+
+// ExceptionStatus defines the observed state of Exception.
+type ExceptionStatus struct {
+	// AuditTimestamp is when the audit which last counted the exemptions
+	// started.
+	AuditTimestamp string `json:"auditTimestamp,omitempty"`
+	// Expired is whether the Exception had expired when last audited.
+	Expired bool `json:"expired,omitempty"`
+	// TotalExemptions is the number of violations exempted by the last audit.
+	TotalExemptions int64 `json:"totalExemptions,omitempty"`
+	// Exemptions lists the violations exempted by the last audit, up to
+	// --constraint-violations-limit.
+	Exemptions []Exemption `json:"exemptions,omitempty"`
+}
+
+// Exemption is a violation of a constraint by an object which an Exception
+// exempted.
+type Exemption struct {
+	ConstraintKind string `json:"constraintKind"`
+	ConstraintName string `json:"constraintName"`
+	Kind           string `json:"kind"`
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="exceptions"
+// +kubebuilder:resource:scope="Cluster"
+// +kubebuilder:subresource:status
+
+// Exception is the Schema for the exceptions API.
+type Exception struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExceptionSpec   `json:"spec,omitempty"`
+	Status ExceptionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ExceptionList contains a list of Exception.
+type ExceptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Exception `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Exception{}, &ExceptionList{})
+}
@@ -0,0 +1,76 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespacePolicyOverrideSpec defines the desired state of
+// NamespacePolicyOverride.
+type NamespacePolicyOverrideSpec struct {
+	// Constraints lists the constraints downgraded from deny to warn for the
+	// objects in the namespace of the override. Only constraints allowed by
+	// spec.overrides.overridable of the Config are downgraded.
+	// +kubebuilder:validation:MinItems=1
+	Constraints []ConstraintReference `json:"constraints"`
+	// Reason records why the constraints are downgraded.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NamespacePolicyOverrideStatus defines the observed state of
+// NamespacePolicyOverride.
+type NamespacePolicyOverrideStatus struct {
+	// AuditTimestamp is when the audit which last counted the downgraded
+	// violations started.
+	AuditTimestamp string `json:"auditTimestamp,omitempty"`
+	// Active lists the constraints of spec.constraints which the Config
+	// allows to be overridden.
+	Active []ConstraintReference `json:"active,omitempty"`
+	// Denied lists the constraints of spec.constraints which the Config does
+	// not allow to be overridden, and which are still enforced.
+	Denied []ConstraintReference `json:"denied,omitempty"`
+	// TotalDowngraded is the number of violations downgraded to warn by the
+	// last audit.
+	TotalDowngraded int64 `json:"totalDowngraded,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="namespacepolicyoverrides"
+// +kubebuilder:resource:scope="Namespaced"
+// +kubebuilder:subresource:status
+
+// NamespacePolicyOverride is the Schema for the namespacepolicyoverrides API.
+type NamespacePolicyOverride struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NamespacePolicyOverrideSpec   `json:"spec,omitempty"`
+	Status NamespacePolicyOverrideStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NamespacePolicyOverrideList contains a list of NamespacePolicyOverride.
+type NamespacePolicyOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespacePolicyOverride `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespacePolicyOverride{}, &NamespacePolicyOverrideList{})
+}
@@ -0,0 +1,86 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SuppressionSpec defines the desired state of Suppression.
+type SuppressionSpec struct {
+	// Constraints lists the constraints whose violations by the matched
+	// objects are suppressed.
+	// +kubebuilder:validation:MinItems=1
+	Constraints []ConstraintReference `json:"constraints"`
+	// Match selects the objects whose violations are suppressed. An empty
+	// match selects every object.
+	Match match.Match `json:"match,omitempty"`
+	// Justification records why the violations are accepted.
+	Justification Justification `json:"justification"`
+}
+
+// SuppressionStatus defines the observed state of Suppression.
+type SuppressionStatus struct {
+	// AuditTimestamp is when the audit which last counted the suppressed
+	// violations started.
+	AuditTimestamp string `json:"auditTimestamp,omitempty"`
+	// TotalSuppressed is the number of violations suppressed by the last
+	// audit.
+	TotalSuppressed int64 `json:"totalSuppressed,omitempty"`
+	// Suppressed lists the violations suppressed by the last audit, up to
+	// --constraint-violations-limit.
+	Suppressed []SuppressedViolation `json:"suppressed,omitempty"`
+}
+
+// SuppressedViolation is a violation of a constraint by an object which a
+// Suppression hid from the status of the constraint.
+type SuppressedViolation struct {
+	ConstraintKind    string `json:"constraintKind"`
+	ConstraintName    string `json:"constraintName"`
+	Kind              string `json:"kind"`
+	Name              string `json:"name"`
+	Namespace         string `json:"namespace,omitempty"`
+	Message           string `json:"message"`
+	EnforcementAction string `json:"enforcementAction"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="suppressions"
+// +kubebuilder:resource:scope="Cluster"
+// +kubebuilder:subresource:status
+
+// Suppression is the Schema for the suppressions API.
+type Suppression struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SuppressionSpec   `json:"spec,omitempty"`
+	Status SuppressionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SuppressionList contains a list of Suppression.
+type SuppressionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Suppression `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Suppression{}, &SuppressionList{})
+}
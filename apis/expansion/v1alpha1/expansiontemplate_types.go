@@ -0,0 +1,90 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ExpansionTemplateSpec defines the desired state of ExpansionTemplate.
+type ExpansionTemplateSpec struct {
+	// ApplyTo lists the kinds of parent resources which are expanded.
+	ApplyTo []match.ApplyTo `json:"applyTo,omitempty"`
+	// TemplateSource is the path of the field of the parent which holds the
+	// template of the generated resource, for example `spec.template` for
+	// a Deployment or `spec.jobTemplate` for a CronJob. If unset, the
+	// generated resource only holds the fields set by FieldMappings.
+	TemplateSource string `json:"templateSource,omitempty"`
+	// GeneratedGVK is the kind of the resource generated from TemplateSource.
+	GeneratedGVK GeneratedGVK `json:"generatedGVK,omitempty"`
+	// FieldMappings copy fields of the parent into the generated resource,
+	// for fields the controller of the parent sets on the resources it
+	// creates which are not part of the template.
+	FieldMappings []FieldMapping `json:"fieldMappings,omitempty"`
+	// EnforcementAction overrides the enforcement action of the constraints
+	// violated by the generated resource, if set.
+	EnforcementAction string `json:"enforcementAction,omitempty"`
+	// SkipMutation disables applying mutators to the generated resource
+	// before it is validated, as they would be when it is created.
+	SkipMutation bool `json:"skipMutation,omitempty"`
+}
+
+// GeneratedGVK is the group, version and kind of a generated resource.
+type GeneratedGVK struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+// ToGroupVersionKind returns g as a schema.GroupVersionKind.
+func (g GeneratedGVK) ToGroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: g.Group, Version: g.Version, Kind: g.Kind}
+}
+
+// FieldMapping copies the value at From in the parent to To in the generated
+// resource. Both are paths of object fields, for example
+// `metadata.labels`.
+type FieldMapping struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="expansiontemplates"
+// +kubebuilder:resource:scope="Cluster"
+
+// ExpansionTemplate is the Schema for the expansiontemplates API.
+type ExpansionTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ExpansionTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ExpansionTemplateList contains a list of ExpansionTemplate.
+type ExpansionTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExpansionTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ExpansionTemplate{}, &ExpansionTemplateList{})
+}
@@ -57,11 +57,50 @@ type Parameters struct {
 	// is merged, we can use an actual object
 	AssignIf runtime.RawExtension `json:"assignIf,omitempty"`
 
-	// Assign.value holds the value to be assigned
+	// Assign.value holds the value to be assigned. It must not be set if
+	// operation is "delete".
 	// +kubebuilder:validation:XPreserveUnknownFields
 	Assign runtime.RawExtension `json:"assign,omitempty"`
+
+	// Operation describes whether the value is assigned to location ("set"),
+	// or the field at location is removed if present ("delete"). Default
+	// value is "set"
+	// +kubebuilder:validation:Enum=set;delete
+	Operation AssignOperation `json:"operation,omitempty"`
+
+	// ValueType is the type of the field at location. If set, the value is
+	// converted to it, for example from "true" to true for a boolean field,
+	// and the Assign is rejected if it cannot be.
+	ValueType ValueType `json:"valueType,omitempty"`
 }
 
+// ValueType is the type of a scalar field.
+// +kubebuilder:validation:Enum=string;integer;number;boolean
+type ValueType string
+
+const (
+	// ValueTypeString is a string field.
+	ValueTypeString = ValueType("string")
+	// ValueTypeInteger is an integer field.
+	ValueTypeInteger = ValueType("integer")
+	// ValueTypeNumber is a floating point field.
+	ValueTypeNumber = ValueType("number")
+	// ValueTypeBoolean is a boolean field.
+	ValueTypeBoolean = ValueType("boolean")
+)
+
+// AssignOperation is what an Assign does to the field at its location.
+type AssignOperation string
+
+const (
+	// SetOp means that the field at location is set to assign.value.
+	SetOp AssignOperation = "set"
+
+	// DeleteOp means that the field at location is removed if present. Missing
+	// parents of the field are not created.
+	DeleteOp AssignOperation = "delete"
+)
+
 // PathTest allows the user to customize how the mutation works if parent
 // paths are missing. It traverses the list in order. All sub paths are
 // tested against the provided condition, if the test fails, the mutation is
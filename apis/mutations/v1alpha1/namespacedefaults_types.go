@@ -0,0 +1,73 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespaceDefaultsSpec defines the desired state of NamespaceDefaults.
+type NamespaceDefaultsSpec struct {
+	// Labels are added to the objects in the namespace of the
+	// NamespaceDefaults which do not already have them. Labels in the
+	// gatekeeper.sh domain are reserved.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Tolerations are added to the Pods in the namespace of the
+	// NamespaceDefaults which do not already have them.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// ImagePullSecrets are added to the Pods in the namespace of the
+	// NamespaceDefaults which do not already have them.
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// NamespaceDefaultsStatus defines the observed state of NamespaceDefaults.
+type NamespaceDefaultsStatus struct {
+	// ObservedGeneration is the generation of the NamespaceDefaults last
+	// compiled into mutators.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Enforced is whether the defaults are applied.
+	Enforced bool `json:"enforced,omitempty"`
+	// Errors are the reasons the defaults are not applied.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="namespacedefaults"
+// +kubebuilder:resource:scope="Namespaced"
+// +kubebuilder:subresource:status
+
+// NamespaceDefaults is the Schema for the namespacedefaults API.
+type NamespaceDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NamespaceDefaultsSpec   `json:"spec,omitempty"`
+	Status NamespaceDefaultsStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NamespaceDefaultsList contains a list of NamespaceDefaults.
+type NamespaceDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespaceDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespaceDefaults{}, &NamespaceDefaultsList{})
+}
@@ -0,0 +1,124 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyRecipeSpec defines the desired state of PolicyRecipe. Exactly one
+// recipe must be set.
+type PolicyRecipeSpec struct {
+	// RequiredLabels requires objects to have labels.
+	RequiredLabels *RequiredLabels `json:"requiredLabels,omitempty"`
+	// AllowedRegistries requires the images of containers to come from
+	// registries.
+	AllowedRegistries *AllowedRegistries `json:"allowedRegistries,omitempty"`
+	// ResourceLimits requires containers to set resource limits no higher
+	// than a maximum.
+	ResourceLimits *ResourceLimits `json:"resourceLimits,omitempty"`
+
+	// Match selects the objects the recipe applies to. If no kinds are
+	// listed, AllowedRegistries and ResourceLimits apply to Pods.
+	Match match.Match `json:"match,omitempty"`
+	// EnforcementAction is the enforcement action of the generated
+	// constraint, `deny` if unset.
+	// +kubebuilder:validation:Enum=deny;dryrun;warn
+	EnforcementAction string `json:"enforcementAction,omitempty"`
+}
+
+// RequiredLabels requires objects to have labels.
+type RequiredLabels struct {
+	// +kubebuilder:validation:MinItems=1
+	Labels []RequiredLabel `json:"labels"`
+}
+
+// RequiredLabel is a label objects must have.
+type RequiredLabel struct {
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+	// AllowedRegex is a regular expression the value of the label must
+	// match, if set.
+	AllowedRegex string `json:"allowedRegex,omitempty"`
+}
+
+// AllowedRegistries requires the images of containers to come from
+// registries.
+type AllowedRegistries struct {
+	// Registries are the prefixes allowed images start with, for example
+	// `registry.example.com/`.
+	// +kubebuilder:validation:MinItems=1
+	Registries []string `json:"registries"`
+}
+
+// ResourceLimits requires containers to set resource limits no higher than a
+// maximum. At least one of CPU and Memory must be set.
+type ResourceLimits struct {
+	// CPU is the maximum CPU limit, for example `500m`. If set, containers
+	// must set a CPU limit.
+	CPU string `json:"cpu,omitempty"`
+	// Memory is the maximum memory limit, for example `1Gi`. If set,
+	// containers must set a memory limit.
+	Memory string `json:"memory,omitempty"`
+}
+
+// PolicyRecipeStatus defines the observed state of PolicyRecipe.
+type PolicyRecipeStatus struct {
+	// ObservedGeneration is the generation of the PolicyRecipe last compiled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Template is the name of the ConstraintTemplate the recipe compiles to.
+	Template string `json:"template,omitempty"`
+	// Constraint refers to the constraint generated for the recipe.
+	Constraint *ConstraintReference `json:"constraint,omitempty"`
+	// Created is whether the template and the constraint are up to date.
+	Created bool `json:"created,omitempty"`
+	// Errors are the errors compiling or creating the recipe.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ConstraintReference refers to a constraint.
+type ConstraintReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path="policyrecipes"
+// +kubebuilder:resource:scope="Cluster"
+// +kubebuilder:subresource:status
+
+// PolicyRecipe is the Schema for the policyrecipes API.
+type PolicyRecipe struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PolicyRecipeSpec   `json:"spec,omitempty"`
+	Status PolicyRecipeStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PolicyRecipeList contains a list of PolicyRecipe.
+type PolicyRecipeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolicyRecipe `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PolicyRecipe{}, &PolicyRecipeList{})
+}
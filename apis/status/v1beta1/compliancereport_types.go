@@ -0,0 +1,93 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ComplianceReportName is the name of the ComplianceReport of each namespace.
+const ComplianceReportName = "gatekeeper"
+
+// The trends of the violations in a namespace between two audits.
+const (
+	TrendImproving = "Improving"
+	TrendWorsening = "Worsening"
+	TrendUnchanged = "Unchanged"
+)
+
+// ComplianceReportStatus summarizes the violations found by audit in the
+// namespace of the report.
+type ComplianceReportStatus struct {
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// AuditTimestamp is the start time of the audit which found the
+	// violations.
+	AuditTimestamp string `json:"auditTimestamp,omitempty"`
+	// TotalViolations is the number of violations by objects in the
+	// namespace, including those beyond the violations limit of constraints.
+	TotalViolations int64 `json:"totalViolations"`
+	// ViolationsBySeverity are the violations by the severity of the
+	// violated constraints.
+	ViolationsBySeverity map[string]int64 `json:"violationsBySeverity,omitempty"`
+	// TopViolatedConstraints are the constraints violated the most in the
+	// namespace, most violated first.
+	TopViolatedConstraints []ConstraintViolations `json:"topViolatedConstraints,omitempty"`
+	// PreviousTotalViolations is the TotalViolations of the report before
+	// this audit, if there was one.
+	PreviousTotalViolations *int64 `json:"previousTotalViolations,omitempty"`
+	// Trend is how TotalViolations changed since PreviousTotalViolations:
+	// Improving, Worsening or Unchanged.
+	Trend string `json:"trend,omitempty"`
+}
+
+// ConstraintViolations are the violations of a constraint in a namespace.
+type ConstraintViolations struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Severity   string `json:"severity,omitempty"`
+	Violations int64  `json:"violations"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced
+// +kubebuilder:printcolumn:name="Violations",type="integer",JSONPath=".status.totalViolations"
+// +kubebuilder:printcolumn:name="Trend",type="string",JSONPath=".status.trend"
+// +kubebuilder:printcolumn:name="Audited",type="string",JSONPath=".status.auditTimestamp"
+
+// ComplianceReport is the Schema for the compliancereports API. Audit
+// publishes one named "gatekeeper" in each namespace with violations, so
+// that namespace owners can see them without reading cluster-scoped
+// constraints.
+type ComplianceReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ComplianceReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ComplianceReportList contains a list of ComplianceReport.
+type ComplianceReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComplianceReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ComplianceReport{}, &ComplianceReportList{})
+}
@@ -44,6 +44,14 @@ type ConstraintPodStatusStatus struct {
 	Enforced           bool      `json:"enforced,omitempty"`
 	Errors             []Error   `json:"errors,omitempty"`
 	ObservedGeneration int64     `json:"observedGeneration,omitempty"`
+	// Frozen is whether the constraint is frozen with the
+	// policy.gatekeeper.sh/frozen annotation, in which case the pod enforces
+	// the version it loaded before it was frozen.
+	Frozen bool `json:"frozen,omitempty"`
+	// Warnings are likely mistakes in the parameters of the constraint which
+	// do not prevent it from being enforced, such as an allowlist allowing
+	// every value.
+	Warnings []Warning `json:"warnings,omitempty"`
 }
 
 // Error represents a single error caught while adding a constraint to OPA.
@@ -53,6 +61,14 @@ type Error struct {
 	Location string `json:"location,omitempty"`
 }
 
+// Warning represents a likely mistake found in the parameters of a constraint.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Location is the path of the parameter, such as `parameters.repos[0]`.
+	Location string `json:"location,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Namespaced
 
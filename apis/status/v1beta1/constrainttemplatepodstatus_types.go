@@ -34,6 +34,28 @@ type ConstraintTemplatePodStatusStatus struct {
 	Operations         []string                           `json:"operations,omitempty"`
 	ObservedGeneration int64                              `json:"observedGeneration,omitempty"`
 	Errors             []*templatesv1beta1.CreateCRDError `json:"errors,omitempty"`
+	// Cost is the estimated cost of evaluating the Rego of the template.
+	Cost *CostEstimate `json:"cost,omitempty"`
+	// Frozen is whether the template is frozen with the
+	// policy.gatekeeper.sh/frozen annotation, in which case the pod enforces
+	// the version it loaded before it was frozen.
+	Frozen bool `json:"frozen,omitempty"`
+}
+
+// CostEstimate is the estimated cost of evaluating the Rego of a template,
+// found by static analysis.
+type CostEstimate struct {
+	// Class is one of Low, Medium or High.
+	Class    string        `json:"class"`
+	Findings []CostFinding `json:"findings,omitempty"`
+}
+
+// CostFinding is a costly pattern found in the Rego of a template.
+type CostFinding struct {
+	Code     string `json:"code"`
+	Class    string `json:"class"`
+	Message  string `json:"message"`
+	Location string `json:"location,omitempty"`
 }
 
 // +kubebuilder:object:root=true
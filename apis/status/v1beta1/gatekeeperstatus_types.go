@@ -0,0 +1,160 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GatekeeperStatusName is the name of the GatekeeperStatus singleton.
+const GatekeeperStatusName = "gatekeeper"
+
+// GatekeeperStatusStatus defines the observed state of GatekeeperStatus.
+type GatekeeperStatusStatus struct {
+	// Important: Run "make" to regenerate code after modifying this file
+
+	Pods []GatekeeperPodHealth `json:"pods,omitempty"`
+}
+
+// GatekeeperPodHealth is the health of a single Gatekeeper pod, as last
+// reported by that pod.
+type GatekeeperPodHealth struct {
+	ID         string   `json:"id"`
+	Operations []string `json:"operations,omitempty"`
+	// Ready is true once the pod has ingested all pre-existing policy and
+	// replicated data.
+	Ready bool `json:"ready"`
+	// WebhookCertificate is only reported by pods serving the webhook.
+	WebhookCertificate *CertificateStatus `json:"webhookCertificate,omitempty"`
+	Sync               *SyncStatus        `json:"sync,omitempty"`
+	// Audit is only reported by the pod running audit.
+	Audit *AuditStatus `json:"audit,omitempty"`
+	// PolicyFreeze is only reported by pods where the policy can be frozen.
+	PolicyFreeze *PolicyFreezeStatus `json:"policyFreeze,omitempty"`
+	// Providers is the health of the custom builtins, such as those fetching
+	// data from external providers, as observed by the calls of the pod.
+	Providers []ProviderStatus `json:"providers,omitempty"`
+	// LastHeartbeatTime is when the pod last reported its health. Entries of
+	// pods which stop reporting are eventually removed.
+	LastHeartbeatTime metav1.Time `json:"lastHeartbeatTime"`
+}
+
+// CertificateStatus is the state of the certificate served by the webhook.
+type CertificateStatus struct {
+	Valid    bool         `json:"valid"`
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// SyncStatus is the progress of replicating data into the pod.
+type SyncStatus struct {
+	Kinds   int `json:"kinds"`
+	Objects int `json:"objects"`
+	Errors  int `json:"errors"`
+}
+
+// ProviderStatus is the health of a custom builtin, which may fetch data from
+// an external provider.
+type ProviderStatus struct {
+	Name string `json:"name"`
+	// Healthy is false if the last call of the builtin failed.
+	Healthy bool `json:"healthy"`
+	// Calls is the number of times the builtin was called, not counting calls
+	// answered by the cache of --builtin-cache-dir.
+	Calls int64 `json:"calls"`
+	// Errors is the number of calls which failed or timed out.
+	Errors int64 `json:"errors"`
+	// LastError is the error of the last failed call.
+	LastError       string       `json:"lastError,omitempty"`
+	LastErrorTime   *metav1.Time `json:"lastErrorTime,omitempty"`
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+}
+
+// PolicyFreezeStatus is whether the pod is ingesting changes to
+// ConstraintTemplates and constraints, or enforcing those it had loaded when
+// its policy was frozen.
+type PolicyFreezeStatus struct {
+	Frozen bool `json:"frozen"`
+	// Since is when the policy was frozen.
+	Since *metav1.Time `json:"since,omitempty"`
+	// Reason is why the policy was frozen.
+	Reason string `json:"reason,omitempty"`
+	// DeferredCount is the number of ConstraintTemplates and constraints
+	// whose changes are deferred until the policy is thawed.
+	DeferredCount int `json:"deferredCount,omitempty"`
+	// Deferred lists the first of them, as `<kind>/<name>`.
+	Deferred []string `json:"deferred,omitempty"`
+}
+
+// AuditStatus is the timing of the most recent audit run.
+type AuditStatus struct {
+	LastRunStartTime *metav1.Time `json:"lastRunStartTime,omitempty"`
+	LastRunEndTime   *metav1.Time `json:"lastRunEndTime,omitempty"`
+	// Coverage is what the most recent complete audit run evaluated.
+	Coverage *AuditCoverage `json:"coverage,omitempty"`
+}
+
+// AuditCoverage is what an audit run evaluated, so operators can show which
+// kinds and namespaces policy is enforced on.
+type AuditCoverage struct {
+	// KindsDiscovered is the number of kinds audit could have listed: those
+	// served by the API server, or those replicated into the cache when
+	// auditing from it.
+	KindsDiscovered int `json:"kindsDiscovered"`
+	// KindsAudited is the number of discovered kinds whose objects were all
+	// listed and reviewed.
+	KindsAudited int `json:"kindsAudited"`
+	// UnauditedKinds are the discovered kinds which were not audited, either
+	// because no constraint matches them or because listing them failed.
+	UnauditedKinds []string `json:"unauditedKinds,omitempty"`
+	// FailedKinds are the kinds whose objects could not be listed.
+	FailedKinds []string `json:"failedKinds,omitempty"`
+	// ExcludedNamespaces are the namespaces whose objects were skipped
+	// because the Config excludes them from audit.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	// ObjectsReviewed is the number of objects reviewed.
+	ObjectsReviewed int64 `json:"objectsReviewed"`
+	// ObjectsExcluded is the number of objects skipped in excluded namespaces.
+	ObjectsExcluded int64 `json:"objectsExcluded"`
+	// ObjectsUnmatched is the number of reviewed objects which no constraint
+	// matches. It is only counted when query statistics are collected.
+	ObjectsUnmatched *int64 `json:"objectsUnmatched,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// GatekeeperStatus is the Schema for the gatekeeperstatuses API. It is a
+// singleton named "gatekeeper", aggregating the health of every Gatekeeper pod.
+type GatekeeperStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status GatekeeperStatusStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GatekeeperStatusList contains a list of GatekeeperStatus.
+type GatekeeperStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GatekeeperStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GatekeeperStatus{}, &GatekeeperStatusList{})
+}
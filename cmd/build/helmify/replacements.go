@@ -60,6 +60,12 @@ var replacements = map[string]string{
     - DELETE
     {{- end}}`,
 
+	"resources: HELMSUBST_VALIDATING_WEBHOOK_RESOURCE_RULES": `resources:
+    - '*'
+    {{- range .Values.validatingWebhookSubresources }}
+    - {{ . }}
+    {{- end }}`,
+
 	"HELMSUBST_PDB_CONTROLLER_MANAGER_MINAVAILABLE": `{{ .Values.pdb.controllerManager.minAvailable }}`,
 
 	`HELMSUBST_SERVICE_TYPE: ""`: `{{- if .Values.service }}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
 	"os"
 
+	"github.com/open-policy-agent/gatekeeper/cmd/gator/lint"
+	"github.com/open-policy-agent/gatekeeper/cmd/gator/printrules"
+	"github.com/open-policy-agent/gatekeeper/cmd/gator/replay"
+	"github.com/open-policy-agent/gatekeeper/cmd/gator/sync"
 	"github.com/open-policy-agent/gatekeeper/cmd/gator/test"
 	"github.com/spf13/cobra"
 )
@@ -11,6 +16,10 @@ const version = "alpha"
 
 func init() {
 	rootCmd.AddCommand(test.Cmd)
+	rootCmd.AddCommand(lint.Cmd)
+	rootCmd.AddCommand(sync.Cmd)
+	rootCmd.AddCommand(printrules.Cmd)
+	rootCmd.AddCommand(replay.Cmd)
 }
 
 var rootCmd = &cobra.Command{
@@ -24,7 +33,13 @@ var rootCmd = &cobra.Command{
 
 func main() {
 	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if err == nil {
+		return
 	}
+	// Subcommands may exit with codes which tell why they failed.
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	os.Exit(1)
 }
@@ -0,0 +1,115 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/gktest"
+	"github.com/open-policy-agent/gatekeeper/pkg/regocost"
+	"github.com/spf13/cobra"
+)
+
+const (
+	examples = `  # Estimate the cost of the ConstraintTemplate in template.yaml
+  gator lint template.yaml
+
+  # Estimate the cost of every ConstraintTemplate in templates/ and its
+  # subdirectories, failing if any is estimated to be High.
+  gator lint templates/... --max-cost=Medium`
+)
+
+var maxCost string
+
+func init() {
+	Cmd.Flags().StringVar(&maxCost, "max-cost", "",
+		`highest estimated cost class allowed, one of [Low, Medium, High]. Estimates are only reported if empty`)
+}
+
+// Cmd is the gator lint subcommand.
+var Cmd = &cobra.Command{
+	Use:     "lint path [--max-cost=class]",
+	Short:   "lint estimates the cost of evaluating the Rego of ConstraintTemplates",
+	Example: examples,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runE,
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	path := args[0]
+
+	var max regocost.Class
+	if maxCost != "" {
+		max = regocost.Class(maxCost)
+		if max != regocost.Low && max != regocost.Medium && max != regocost.High {
+			return fmt.Errorf("invalid --max-cost %q, must be one of [Low, Medium, High]", maxCost)
+		}
+	}
+
+	// Paths are made absolute and read from the root file system in the same
+	// way as by gator test.
+	var err error
+	if !filepath.IsAbs(path) {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("getting absolute path: %w", err)
+		}
+	}
+	fileSystem := getFS(path)
+
+	recursive := false
+	if strings.HasSuffix(path, "/...") {
+		recursive = true
+		path = strings.TrimSuffix(path, "...")
+	}
+	path = strings.Trim(path, "/")
+
+	results, err := gktest.Lint(fileSystem, path, recursive)
+	if err != nil {
+		return fmt.Errorf("listing template files: %w", err)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	isFailure := false
+	w := &strings.Builder{}
+	for _, r := range results {
+		if r.Error != nil {
+			isFailure = true
+			fmt.Fprintf(w, "%s: %v\n", r.Path, r.Error)
+			continue
+		}
+		status := ""
+		if max != "" && regocost.Exceeds(r.Estimate.Class, max) {
+			isFailure = true
+			status = " FAIL"
+		}
+		fmt.Fprintf(w, "%s: %s: %s%s\n", r.Path, r.Template, r.Estimate.Class, status)
+		for _, f := range r.Estimate.Findings {
+			fmt.Fprintf(w, "    %s %s %s: %s\n", f.Location, f.Class, f.Code, f.Message)
+		}
+	}
+	fmt.Print(w)
+
+	if isFailure {
+		return errors.New("FAIL")
+	}
+	return nil
+}
+
+func getFS(path string) fs.FS {
+	root := filepath.VolumeName(path)
+	if root == "" {
+		// We are running on a unix-like filesystem without volume names, so the
+		// file system root is `/`.
+		root = "/"
+	}
+
+	return os.DirFS(root)
+}
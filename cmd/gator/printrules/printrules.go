@@ -0,0 +1,164 @@
+package printrules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/cluster"
+	"github.com/open-policy-agent/gatekeeper/pkg/gktest"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	examples = `  # List the Constraints and mutators in policies/ and its subdirectories
+  # which would apply to the Deployment in deployment.yaml.
+  gator print-rules deployment.yaml --policies=policies/...
+
+  # Match namespaceSelectors against the labels of the Namespace in
+  # namespace.yaml, and print the result as JSON.
+  gator print-rules deployment.yaml --policies=policies/... --namespace=namespace.yaml -o json`
+)
+
+var (
+	policies      string
+	namespace     string
+	clusterLabels map[string]string
+	output        string
+)
+
+func init() {
+	Cmd.Flags().StringVar(&policies, "policies", "",
+		`path to the Constraints and mutators to match, ending in "/..." to include subdirectories`)
+	Cmd.Flags().StringVar(&namespace, "namespace", "",
+		`path to the Namespace of the object. Defaults to a Namespace without labels`)
+	Cmd.Flags().StringToStringVar(&clusterLabels, "cluster-label", nil,
+		`labels identifying the cluster, as key=value, to match clusterSelectors against. Defaults to no labels`)
+	Cmd.Flags().StringVarP(&output, "output", "o", "",
+		`output format. One of: json. Defaults to a list of rules`)
+	_ = Cmd.MarkFlagRequired("policies")
+}
+
+// Cmd is the gator print-rules subcommand.
+var Cmd = &cobra.Command{
+	Use:     "print-rules object --policies=path [--namespace=path] [--cluster-label=key=value] [-o json]",
+	Short:   "print-rules lists the Constraints and mutators which would apply to an object",
+	Example: examples,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runE,
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if output != "" && output != "json" {
+		return fmt.Errorf("invalid --output %q, must be json or empty", output)
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := readYAML(args[0], &obj.Object); err != nil {
+		return err
+	}
+	var ns *corev1.Namespace
+	if namespace != "" {
+		ns = &corev1.Namespace{}
+		if err := readYAML(namespace, ns); err != nil {
+			return err
+		}
+	}
+	cluster.SetLabels(clusterLabels)
+
+	// Paths are made absolute and read from the root file system in the same
+	// way as by gator test.
+	path := policies
+	if !filepath.IsAbs(path) {
+		var err error
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("getting absolute path: %w", err)
+		}
+	}
+	fileSystem := getFS(path)
+	root := filepath.VolumeName(path) + string(filepath.Separator)
+
+	recursive := false
+	if strings.HasSuffix(path, "/...") {
+		recursive = true
+		path = strings.TrimSuffix(path, "...")
+	}
+	path = strings.Trim(path, "/")
+
+	explanation, err := gktest.Explain(fileSystem, path, recursive, obj, ns)
+	if err != nil {
+		return fmt.Errorf("matching policies: %w", err)
+	}
+	// Paths are relative to the root of fileSystem.
+	for _, rules := range [][]gktest.Rule{explanation.Constraints, explanation.Mutators} {
+		for i := range rules {
+			rules[i].Path = filepath.Join(root, rules[i].Path)
+		}
+	}
+
+	if output == "json" {
+		out, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	w := &strings.Builder{}
+	printRules(w, "Constraints", explanation.Constraints)
+	printRules(w, "Mutators", explanation.Mutators)
+	fmt.Print(w)
+	return nil
+}
+
+func printRules(w *strings.Builder, title string, rules []gktest.Rule) {
+	if len(rules) == 0 {
+		fmt.Fprintf(w, "%s: none\n", title)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, r := range rules {
+		action := ""
+		if r.EnforcementAction != "" {
+			action = fmt.Sprintf(" (%s)", r.EnforcementAction)
+		}
+		fmt.Fprintf(w, "  %s/%s%s: %s\n", r.Kind, r.Name, action, r.Path)
+		if len(r.Criteria) == 0 {
+			fmt.Fprintf(w, "    matches every object\n")
+		}
+		for _, c := range r.Criteria {
+			fmt.Fprintf(w, "    %s\n", c)
+		}
+	}
+}
+
+func readYAML(path string, into interface{}) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %q: %w", path, err)
+	}
+	if err := yaml.Unmarshal(bytes, into); err != nil {
+		return fmt.Errorf("parsing %q: %w", path, err)
+	}
+	return nil
+}
+
+func getFS(path string) fs.FS {
+	root := filepath.VolumeName(path)
+	if root == "" {
+		// We are running on a unix-like filesystem without volume names, so the
+		// file system root is `/`.
+		root = "/"
+	}
+
+	return os.DirFS(root)
+}
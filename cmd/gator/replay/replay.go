@@ -0,0 +1,132 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/gktest"
+	"github.com/spf13/cobra"
+)
+
+const (
+	examples = `  # Replay the requests in audit.log against the policies in policies/ and its
+  # subdirectories, and list those which would be denied or warned about.
+  gator replay audit.log --policies=policies/...
+
+  # Print the result as JSON.
+  gator replay audit.log --policies=policies/... -o json`
+)
+
+var (
+	policies string
+	output   string
+)
+
+func init() {
+	Cmd.Flags().StringVar(&policies, "policies", "",
+		`path to the ConstraintTemplates, Constraints and mutators to replay against, ending in "/..." to include subdirectories`)
+	Cmd.Flags().StringVarP(&output, "output", "o", "",
+		`output format. One of: json. Defaults to a list of requests`)
+	_ = Cmd.MarkFlagRequired("policies")
+}
+
+// Cmd is the gator replay subcommand.
+var Cmd = &cobra.Command{
+	Use:     "replay audit-log --policies=path [-o json]",
+	Short:   "replay reviews the requests in a Kubernetes audit log against policies, and lists those which would be denied",
+	Example: examples,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runE,
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	if output != "" && output != "json" {
+		return fmt.Errorf("invalid --output %q, must be json or empty", output)
+	}
+
+	log, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer log.Close()
+
+	// Paths are made absolute and read from the root file system in the same
+	// way as by gator test.
+	path := policies
+	if !filepath.IsAbs(path) {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("getting absolute path: %w", err)
+		}
+	}
+	fileSystem := getFS(path)
+
+	recursive := false
+	if strings.HasSuffix(path, "/...") {
+		recursive = true
+		path = strings.TrimSuffix(path, "...")
+	}
+	path = strings.Trim(path, "/")
+
+	result, err := gktest.Replay(context.Background(), fileSystem, path, recursive, log)
+	if err != nil {
+		return fmt.Errorf("replaying audit log: %w", err)
+	}
+
+	if output == "json" {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	w := &strings.Builder{}
+	for i := range result.Requests {
+		printRequest(w, &result.Requests[i])
+	}
+	fmt.Fprintln(w, result)
+	fmt.Print(w)
+	return nil
+}
+
+func printRequest(w *strings.Builder, r *gktest.ReplayedRequest) {
+	verdict := "WARN"
+	if r.Denied() {
+		verdict = "DENY"
+	}
+	name := r.Name
+	if r.Namespace != "" {
+		name = r.Namespace + "/" + r.Name
+	}
+	fmt.Fprintf(w, "%s %s %s %s %s by %s", verdict, r.Timestamp.Format(time.RFC3339), r.Operation, r.Kind, name, r.User)
+	if r.ResponseCode != 0 {
+		fmt.Fprintf(w, " (was %d)", r.ResponseCode)
+	}
+	fmt.Fprintln(w)
+	for _, msg := range r.Denials {
+		fmt.Fprintf(w, "  %s\n", msg)
+	}
+	for _, msg := range r.Warnings {
+		fmt.Fprintf(w, "  warning: %s\n", msg)
+	}
+}
+
+func getFS(path string) fs.FS {
+	root := filepath.VolumeName(path)
+	if root == "" {
+		// We are running on a unix-like filesystem without volume names, so the
+		// file system root is `/`.
+		root = "/"
+	}
+
+	return os.DirFS(root)
+}
@@ -0,0 +1,124 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/gktest"
+	"github.com/spf13/cobra"
+)
+
+const (
+	examples = `  # Check the Config in policy/ replicates the kinds read by the
+  # ConstraintTemplates in policy/
+  gator sync test policy/
+
+  # Check the Configs and ConstraintTemplates in policy/ and its
+  # subdirectories
+  gator sync test policy/...`
+)
+
+// Cmd is the gator sync subcommand.
+var Cmd = &cobra.Command{
+	Use:   "sync",
+	Short: "sync checks the configuration of data replication",
+}
+
+var testCmd = &cobra.Command{
+	Use:     "test path",
+	Short:   "test reports the kinds ConstraintTemplates read from data.inventory which no Config replicates",
+	Example: examples,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runTest,
+}
+
+func init() {
+	Cmd.AddCommand(testCmd)
+}
+
+func runTest(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	path := args[0]
+
+	// Paths are made absolute and read from the root file system in the same
+	// way as by gator test.
+	var err error
+	if !filepath.IsAbs(path) {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("getting absolute path: %w", err)
+		}
+	}
+	fileSystem := getFS(path)
+
+	recursive := false
+	if strings.HasSuffix(path, "/...") {
+		recursive = true
+		path = strings.TrimSuffix(path, "...")
+	}
+	path = strings.Trim(path, "/")
+
+	check, err := gktest.CheckSync(fileSystem, path, recursive)
+	if err != nil {
+		return fmt.Errorf("listing files: %w", err)
+	}
+	sort.Slice(check.Results, func(i, j int) bool {
+		return check.Results[i].Path < check.Results[j].Path
+	})
+
+	isFailure := false
+	w := &strings.Builder{}
+	if len(check.Configs) == 0 {
+		fmt.Fprintln(w, "warning: no Config found, so nothing is replicated")
+	}
+	for _, r := range check.Results {
+		if r.Error != nil {
+			isFailure = true
+			fmt.Fprintf(w, "%s: %v\n", r.Path, r.Error)
+			continue
+		}
+		status := "ok"
+		if len(r.Missing) > 0 {
+			isFailure = true
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", r.Path, r.Template, status)
+		missing := make(map[string]bool)
+		for _, gvk := range r.Missing {
+			missing[gvk.String()] = true
+		}
+		for _, gvk := range r.Required {
+			if missing[gvk.String()] {
+				fmt.Fprintf(w, "    not synced: %s\n", gvk)
+			} else {
+				fmt.Fprintf(w, "    synced: %s\n", gvk)
+			}
+		}
+		for _, d := range r.Dynamic {
+			fmt.Fprintf(w, "    unknown kind: %s\n", d)
+		}
+	}
+	fmt.Print(w)
+
+	if isFailure {
+		return errors.New("FAIL")
+	}
+	return nil
+}
+
+func getFS(path string) fs.FS {
+	root := filepath.VolumeName(path)
+	if root == "" {
+		// We are running on a unix-like filesystem without volume names, so the
+		// file system root is `/`.
+		root = "/"
+	}
+
+	return os.DirFS(root)
+}
@@ -2,7 +2,6 @@ package test
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -28,12 +27,40 @@ const (
 
   # Run all cases that are either named "forbid-labels" or are
   # in tests named "forbid-labels".
-  gator test tests/... --run '^forbid-labels$'`
+  gator test tests/... --run '^forbid-labels$'
+
+  # Summarize each suite in a table before listing failures.
+  gator test tests/... -o wide
+
+  # Print the statistics of the queries reviewing the object of each case.
+  gator test tests/... --stats
+
+  # Write a self-contained HTML report of the results.
+  gator test tests/... -o html > report.html
+
+  # Write a JUnit XML report for the test report panel of a CI system.
+  gator test tests/... -o junit > report.xml
+
+  # Print the results as JSON for tools which post-process them.
+  gator test tests/... -o json
+
+  # Write a SARIF log locating failed cases at their objects, for code
+  # scanning annotations on pull requests. Paths are relative to the current
+  # directory.
+  gator test tests/... -o sarif > results.sarif
+
+  # Print the results in the Test Anything Protocol for TAP harnesses.
+  gator test tests/... -o tap`
 )
 
 var (
-	run     string
-	verbose bool
+	run           string
+	verbose       bool
+	output        string
+	stats         bool
+	trace         bool
+	parallel      int
+	parallelTests int
 )
 
 func init() {
@@ -41,11 +68,21 @@ func init() {
 		`regular expression which filters tests to run by name`)
 	Cmd.Flags().BoolVarP(&verbose, "verbose", "v", false,
 		`print extended test output`)
+	Cmd.Flags().StringVarP(&output, "output", "o", "",
+		`output format. One of: table|wide|html|junit|json|sarif|tap. Defaults to the format of go test`)
+	Cmd.Flags().BoolVar(&stats, "stats", false,
+		`print the number of queries, evaluation time, constraints matched, external data calls and cache hits of each case. Implies --verbose`)
+	Cmd.Flags().BoolVar(&trace, "trace", false,
+		`print the trace of the queries reviewing the object of each failed case. Implies --verbose`)
+	Cmd.Flags().IntVar(&parallel, "parallel", 1,
+		`the number of suites run at once`)
+	Cmd.Flags().IntVar(&parallelTests, "parallel-tests", 1,
+		`the number of tests of each suite run at once`)
 }
 
 // Cmd is the gator test subcommand.
 var Cmd = &cobra.Command{
-	Use:     "test path [--run=name]",
+	Use:     "test path [--run=name] [-o table|wide|html|junit|json|sarif|tap]",
 	Short:   "test runs suites of tests on Gatekeeper Constraints",
 	Example: examples,
 	Args:    cobra.ExactArgs(1),
@@ -83,57 +120,61 @@ func runE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("listing test files: %w", err)
 	}
-	filter, err := gktest.NewFilter(run)
+	wd, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("compiling filter: %w", err)
+		return fmt.Errorf("getting working directory: %w", err)
 	}
-
-	return runSuites(cmd.Context(), fileSystem, suites, filter)
-}
-
-func runSuites(ctx context.Context, fileSystem fs.FS, suites map[string]*gktest.Suite, filter gktest.Filter) error {
-	isFailure := false
-
-	runner := gktest.Runner{
-		FS:        fileSystem,
-		NewClient: gktest.NewOPAClient,
+	opts, err := gktest.NewRunOptions(
+		gktest.WithFilter(run),
+		gktest.WithParallelism(parallel),
+		gktest.WithTestParallelism(parallelTests),
+		gktest.WithTrace(trace),
+		gktest.WithStats(stats),
+		gktest.WithOutput(output),
+		// Like path, paths in fileSystem are relative to its root.
+		gktest.WithSourceRoot(strings.Trim(wd, "/")),
+	)
+	if err != nil {
+		return err
 	}
 
-	results := make([]gktest.SuiteResult, len(suites))
-	i := 0
-
-	for path, suite := range suites {
-		suiteResult := runner.Run(ctx, filter, path, suite)
-		for _, testResult := range suiteResult.TestResults {
-			if testResult.Error != nil {
-				isFailure = true
-			}
-			for _, caseResult := range testResult.CaseResults {
-				if caseResult.Error != nil {
-					isFailure = true
-				}
-			}
-		}
+	return runSuites(cmd.Context(), fileSystem, suites, opts)
+}
 
-		results[i] = suiteResult
-		i++
-	}
+func runSuites(ctx context.Context, fileSystem fs.FS, suites map[string]*gktest.Suite, opts *gktest.RunOptions) error {
+	results := gktest.RunSuites(ctx, fileSystem, suites, opts)
 	w := &strings.Builder{}
-	printer := gktest.PrinterGo{}
-	err := printer.Print(w, results, verbose)
+	err := opts.Printer().Print(w, results, verbose || opts.Verbose())
 	if err != nil {
 		return err
 	}
 	fmt.Println(w)
 
-	if isFailure {
+	summary := gktest.Summarize(results)
+	if summary.IsFailure() {
 		// At least one test failed or there was a problem executing tests in at
 		// least one file.
-		return errors.New("FAIL")
+		return &FailError{Summary: summary}
 	}
 	return nil
 }
 
+// FailError is returned if any test failed. gator exits with the code of its
+// Summary.
+type FailError struct {
+	Summary gktest.Summary
+}
+
+func (e *FailError) Error() string {
+	return fmt.Sprintf("FAIL: %d assertion failures, %d suite errors, %d infrastructure errors",
+		e.Summary.AssertionFailures, e.Summary.SuiteErrors, e.Summary.InfrastructureErrors)
+}
+
+// ExitCode returns the exit code gator exits with.
+func (e *FailError) ExitCode() int {
+	return e.Summary.ExitCode()
+}
+
 func getFS(path string) fs.FS {
 	// TODO(#1397): Check that this produces the correct file system string on
 	//  Windows. We may need to add a trailing `/` for fs.FS to function properly.
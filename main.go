@@ -17,28 +17,51 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/go-logr/zapr"
 	"github.com/open-policy-agent/cert-controller/pkg/rotator"
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
 	api "github.com/open-policy-agent/gatekeeper/apis"
 	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
 	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
 	statusv1beta1 "github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
 	"github.com/open-policy-agent/gatekeeper/pkg/audit"
+	"github.com/open-policy-agent/gatekeeper/pkg/builtins"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/debug"
+	"github.com/open-policy-agent/gatekeeper/pkg/election"
+	"github.com/open-policy-agent/gatekeeper/pkg/engine"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
+	"github.com/open-policy-agent/gatekeeper/pkg/faultinject"
+	"github.com/open-policy-agent/gatekeeper/pkg/gatekeeperstatus"
+	"github.com/open-policy-agent/gatekeeper/pkg/incremental"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	"github.com/open-policy-agent/gatekeeper/pkg/inventory"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/openapi"
 	"github.com/open-policy-agent/gatekeeper/pkg/operations"
+	"github.com/open-policy-agent/gatekeeper/pkg/policyfreeze"
+	"github.com/open-policy-agent/gatekeeper/pkg/policygraph"
+	"github.com/open-policy-agent/gatekeeper/pkg/policysnapshot"
+	"github.com/open-policy-agent/gatekeeper/pkg/prune"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/regolimit"
+	"github.com/open-policy-agent/gatekeeper/pkg/remoteopa"
+	"github.com/open-policy-agent/gatekeeper/pkg/shutdown"
+	"github.com/open-policy-agent/gatekeeper/pkg/statusaggregation"
 	"github.com/open-policy-agent/gatekeeper/pkg/target"
 	"github.com/open-policy-agent/gatekeeper/pkg/upgrade"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
@@ -51,6 +74,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
@@ -92,14 +118,15 @@ var (
 var (
 	logLevel            = flag.String("log-level", "INFO", "Minimum log level. For example, DEBUG, INFO, WARNING, ERROR. Defaulted to INFO if unspecified.")
 	logLevelKey         = flag.String("log-level-key", "level", "JSON key for the log level field, defaults to `level`")
+	logConfigMap        = flag.String("log-configmap", "", "(alpha) name of a ConfigMap in the gatekeeper namespace holding runtime logging configuration, such as per-subsystem log levels, sampling and field redaction. Disabled if empty")
 	logLevelEncoder     = flag.String("log-level-encoder", "lower", "Encoder for the value of the log level field. Valid values: [`lower`, `capital`, `color`, `capitalcolor`], default: `lower`")
 	healthAddr          = flag.String("health-addr", ":9090", "The address to which the health endpoint binds.")
 	metricsAddr         = flag.String("metrics-addr", "0", "The address the metric endpoint binds to.")
 	port                = flag.Int("port", 443, "port for the server. defaulted to 443 if unspecified ")
 	certDir             = flag.String("cert-dir", "/certs", "The directory where certs are stored, defaults to /certs")
 	disableCertRotation = flag.Bool("disable-cert-rotation", false, "disable automatic generation and rotation of webhook TLS certificates/keys")
-	enableProfile       = flag.Bool("enable-pprof", false, "enable pprof profiling")
-	profilePort         = flag.Int("pprof-port", 6060, "port for pprof profiling. defaulted to 6060 if unspecified")
+	enableProfile       = flag.Bool("enable-pprof", false, "enable the debug server on localhost, serving pprof profiles, expvar runtime metrics and constraint framework cache statistics")
+	profilePort         = flag.Int("pprof-port", 6060, "port of the debug server. defaulted to 6060 if unspecified")
 	disabledBuiltins    = util.NewFlagSet()
 )
 
@@ -123,31 +150,39 @@ func main() {
 		os.Exit(1)
 	}
 
+	// driverStats is only collected when it can be served.
+	var driverStats *debug.DriverStats
 	if *enableProfile {
-		setupLog.Info("Starting profiling on port %s", *profilePort)
+		driverStats = debug.NewDriverStats()
+		addr := fmt.Sprintf("%s:%d", "localhost", *profilePort)
+		setupLog.Info("starting debug server", "addr", addr)
 		go func() {
-			addr := fmt.Sprintf("%s:%d", "localhost", *profilePort)
-			setupLog.Error(http.ListenAndServe(addr, nil), "unable to start profiling server")
+			setupLog.Error(http.ListenAndServe(addr, debug.Handler(driverStats)), "unable to start debug server")
 		}()
 	}
 
+	// Log levels and sampling are enforced by logConfig, so they may be changed
+	// at runtime with --log-configmap.
+	logConfig := logging.NewDynamicConfig(defaultLogSettings(*logLevel))
 	switch *logLevel {
 	case "DEBUG":
 		eCfg := zap.NewDevelopmentEncoderConfig()
 		eCfg.LevelKey = *logLevelKey
 		eCfg.EncodeLevel = encoder
-		logger := crzap.New(crzap.UseDevMode(true), crzap.Encoder(zapcore.NewConsoleEncoder(eCfg)))
+		logger := crzap.New(crzap.UseDevMode(true), crzap.Encoder(zapcore.NewConsoleEncoder(eCfg)),
+			crzap.Level(logging.AllLevels), crzap.RawZapOpts(zap.WrapCore(logConfig.WrapCore)))
 		ctrl.SetLogger(logger)
 		klog.SetLogger(logger)
 	case "WARNING", "ERROR":
-		setLoggerForProduction(encoder)
+		setLoggerForProduction(encoder, logConfig)
 	case "INFO":
 		fallthrough
 	default:
 		eCfg := zap.NewProductionEncoderConfig()
 		eCfg.LevelKey = *logLevelKey
 		eCfg.EncodeLevel = encoder
-		logger := crzap.New(crzap.UseDevMode(false), crzap.Encoder(zapcore.NewJSONEncoder(eCfg)))
+		logger := crzap.New(crzap.UseDevMode(false), crzap.Encoder(zapcore.NewJSONEncoder(eCfg)),
+			crzap.Level(logging.AllLevels), crzap.RawZapOpts(zap.WrapCore(logConfig.WrapCore)))
 		ctrl.SetLogger(logger)
 		klog.SetLogger(logger)
 	}
@@ -168,6 +203,9 @@ func main() {
 		Port:                   *port,
 		CertDir:                *certDir,
 		HealthProbeBindAddress: *healthAddr,
+		// In-flight admission reviews, and the status updates and exports
+		// flushed on shutdown, are given this long to finish.
+		GracefulShutdownTimeout: shutdown.GracePeriod,
 		MapperProvider: func(c *rest.Config) (meta.RESTMapper, error) {
 			return apiutil.NewDynamicRESTMapper(c)
 		},
@@ -177,11 +215,80 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *logConfigMap != "" {
+		setupLog.Info("watching logging configuration", "configmap", *logConfigMap)
+		w := logging.NewConfigMapWatcher(kubernetes.NewForConfigOrDie(config), util.GetNamespace(), *logConfigMap, logConfig, setupLog)
+		if err := mgr.Add(w); err != nil {
+			setupLog.Error(err, "unable to register logging configuration watcher")
+			os.Exit(1)
+		}
+	}
+
+	if err := introspection.Validate(*certDir); err != nil {
+		setupLog.Error(err, "unable to serve the introspection endpoint")
+		os.Exit(1)
+	}
+	// Pods serve their introspection endpoint with the webhook certificates,
+	// and verify those of the pods they fetch from.
+	introspection.SetCerts(introspection.NewCerts(*certDir, dnsName))
+	if err := statusaggregation.Validate(); err != nil {
+		setupLog.Error(err, "unable to aggregate constraint status")
+		os.Exit(1)
+	}
+	if err := policysnapshot.Validate(); err != nil {
+		setupLog.Error(err, "unable to restore policy snapshots")
+		os.Exit(1)
+	}
+	if err := policyfreeze.Validate(); err != nil {
+		setupLog.Error(err, "unable to serve the policy freeze")
+		os.Exit(1)
+	}
+	if err := faultinject.Validate(); err != nil {
+		setupLog.Error(err, "unable to inject faults")
+		os.Exit(1)
+	}
+	if err := builtins.LoadCapabilities(); err != nil {
+		setupLog.Error(err, "unable to load Rego capabilities")
+		os.Exit(1)
+	}
+	if err := builtins.LoadCache(); err != nil {
+		setupLog.Error(err, "unable to load the cache of builtin results")
+		os.Exit(1)
+	}
+	if err := remoteopa.Validate(); err != nil {
+		setupLog.Error(err, "unable to evaluate policy with a remote OPA")
+		os.Exit(1)
+	}
+	if err := regolimit.Validate(); err != nil {
+		setupLog.Error(err, "unable to limit the memory of templates")
+		os.Exit(1)
+	}
+	if regolimit.Enabled() && *remoteopa.URL != "" {
+		setupLog.Error(errors.New("--rego-memory-limit cannot be set with --remote-opa-url"), "unable to limit the memory of templates")
+		os.Exit(1)
+	}
+	if *metrics.TemplateDuration && *remoteopa.URL != "" {
+		setupLog.Error(errors.New("--metrics-template-duration cannot be set with --remote-opa-url"), "unable to time templates")
+		os.Exit(1)
+	}
+
 	// Make sure certs are generated and valid if cert rotation is enabled.
 	setupFinished := make(chan struct{})
 	if !*disableCertRotation && operations.IsAssigned(operations.Webhook) {
 		setupLog.Info("setting up cert rotation")
-		if err := rotator.AddRotator(mgr, &rotator.CertRotator{
+		rotatorMgr, err := election.Manager(mgr, election.CertRotation)
+		if err != nil {
+			setupLog.Error(err, "unable to set up cert rotation leader election")
+			os.Exit(1)
+		}
+		certsReady := setupFinished
+		if election.IsElected(election.CertRotation) {
+			// Only the leader rotates certificates, so every pod instead waits
+			// for them to be mounted.
+			certsReady = make(chan struct{})
+			go waitForCerts(*certDir, setupFinished)
+		}
+		if err := rotator.AddRotator(rotatorMgr, &rotator.CertRotator{
 			SecretKey: types.NamespacedName{
 				Namespace: util.GetNamespace(),
 				Name:      secretName,
@@ -190,7 +297,7 @@ func main() {
 			CAName:         caName,
 			CAOrganization: caOrganization,
 			DNSName:        dnsName,
-			IsReady:        setupFinished,
+			IsReady:        certsReady,
 			Webhooks:       webhooks,
 		}); err != nil {
 			setupLog.Error(err, "unable to set up cert rotation")
@@ -211,18 +318,91 @@ func main() {
 		os.Exit(1)
 	}
 
+	// recorder keeps the policy of this pod, so that it can be served to other
+	// pods restoring it, or saved to disk.
+	var recorder *policysnapshot.Recorder
+	if *policysnapshot.Enabled || *policysnapshot.Dir != "" {
+		recorder = policysnapshot.NewRecorder()
+	}
+	if *policysnapshot.Enabled {
+		// Every pod campaigns, so that starting pods find a running one to
+		// restore policy from.
+		if _, err := election.Manager(mgr, election.PolicySnapshot); err != nil {
+			setupLog.Error(err, "unable to set up policy snapshot leader election")
+			os.Exit(1)
+		}
+	}
+
+	// remote evaluates policy with an external OPA, which downloads it from
+	// the introspection endpoint.
+	var remote *remoteopa.Driver
+	if *remoteopa.URL != "" {
+		remote, err = remoteopa.NewFromFlags()
+		if err != nil {
+			setupLog.Error(err, "unable to set up remote OPA")
+			os.Exit(1)
+		}
+	}
+
+	if *introspection.Addr != "" {
+		srv := introspection.NewServer(*introspection.Addr, introspection.Get(), kubernetes.NewForConfigOrDie(config))
+		srv.Handle(policygraph.Path, policygraph.Handler(mgr.GetAPIReader(), *mutation.MutationEnabled))
+		if *statusaggregation.Enabled {
+			srv.Handle(statusaggregation.ReportsPath, statusaggregation.Get())
+		}
+		if *policysnapshot.Enabled {
+			srv.Handle(policysnapshot.Path, recorder.Handler(tracker.Satisfied, introspection.GetCerts()))
+		}
+		if remote != nil {
+			srv.Handle(remoteopa.BundlePath, remote.BundleHandler(tracker.Satisfied))
+		}
+		if *policyfreeze.Enabled {
+			srv.Handle(policyfreeze.Path, policyfreeze.Get())
+		}
+		if err := mgr.Add(srv); err != nil {
+			setupLog.Error(err, "unable to register introspection server")
+			os.Exit(1)
+		}
+	}
+
+	if *gatekeeperstatus.Enabled {
+		probes := gatekeeperstatus.Probes{
+			Ready:     tracker.Satisfied,
+			Sync:      gatekeeperstatus.SyncProbe(introspection.Get()),
+			Providers: gatekeeperstatus.ProviderProbe(),
+		}
+		if operations.IsAssigned(operations.Webhook) {
+			probes.WebhookCertificate = gatekeeperstatus.CertificateProbe(*certDir)
+		}
+		if operations.IsAssigned(operations.Audit) {
+			probes.Audit = gatekeeperstatus.AuditProbe()
+		}
+		if *policyfreeze.Enabled {
+			probes.PolicyFreeze = policyfreeze.Get().Status
+		}
+		if err := mgr.Add(gatekeeperstatus.New(mgr.GetClient(), mgr.GetAPIReader(), probes)); err != nil {
+			setupLog.Error(err, "unable to register gatekeeper status reporter")
+			os.Exit(1)
+		}
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("default", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to create health check")
 		os.Exit(1)
 	}
+	drainer := shutdown.NewDrainer(*shutdown.Delay)
+	if err := mgr.AddReadyzCheck("shutdown", drainer.Checker); err != nil {
+		setupLog.Error(err, "unable to create shutdown readiness check")
+		os.Exit(1)
+	}
 	// Setup controllers asynchronously, they will block for certificate generation if needed.
-	go setupControllers(mgr, sw, tracker, setupFinished)
+	go setupControllers(mgr, sw, tracker, driverStats, recorder, remote, setupFinished)
 
 	setupLog.Info("starting manager")
 	hadError := false
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(drainer.Context(ctrl.SetupSignalHandler())); err != nil {
 		setupLog.Error(err, "problem running manager")
 		hadError = true
 	}
@@ -238,12 +418,63 @@ func main() {
 	}
 }
 
-func setupControllers(mgr ctrl.Manager, sw *watch.ControllerSwitch, tracker *readiness.Tracker, setupFinished chan struct{}) {
+func setupControllers(mgr ctrl.Manager, sw *watch.ControllerSwitch, tracker *readiness.Tracker, driverStats *debug.DriverStats, recorder *policysnapshot.Recorder, remote *remoteopa.Driver, setupFinished chan struct{}) {
 	// Block until the setup (certificate generation) finishes.
 	<-setupFinished
 
 	// initialize OPA
-	driver := local.New(local.Tracing(false), local.DisableBuiltins(disabledBuiltins.ToSlice()...))
+	// Templates are compiled by the evaluation engines they prefer, falling
+	// back to the local driver, the remote OPA if one is set, or a local
+	// driver limiting the memory of templates or timing them.
+	var rego drivers.Driver = local.New(local.Tracing(false), local.DisableBuiltins(disabledBuiltins.ToSlice()...))
+	switch {
+	case remote != nil:
+		rego = remote
+	case regolimit.Enabled():
+		limited, err := regolimit.NewFromFlags(disabledBuiltins.ToSlice())
+		if err != nil {
+			setupLog.Error(err, "unable to limit the memory of templates")
+			os.Exit(1)
+		}
+		rego = limited
+	case *metrics.TemplateDuration:
+		rego = regolimit.NewDriver(0, disabledBuiltins.ToSlice()...)
+	}
+	engines, err := engine.NewDriver(rego, engine.Enabled())
+	if err != nil {
+		setupLog.Error(err, "unable to set up evaluation engines")
+		os.Exit(1)
+	}
+	// Templates whose Rego is unchanged are not recompiled.
+	driver := incremental.NewDriver(engines)
+	// Injected faults are beneath the other wrappers, so the stats they
+	// collect include them.
+	driver = faultinject.Get().Wrap(driver)
+	// Replicated objects are indexed for the gatekeeper.inventory.lookup
+	// builtin.
+	index := inventory.NewIndex()
+	driver = index.Wrap(driver)
+	if *inventory.CacheSize > 0 {
+		// Cached reviews are invalidated by the changes to the inventory the
+		// index is notified of.
+		driver = inventory.NewResultCache(index, *inventory.CacheSize).Wrap(driver)
+	}
+	if *prune.Enabled {
+		// The paths of reviewed objects templates read are inferred from
+		// every module put by the client.
+		driver = prune.Get().Wrap(driver)
+	}
+	if recorder != nil {
+		// The recorder wraps the incremental driver, so it sees every module
+		// put by the client, whether or not it is recompiled.
+		driver = recorder.Wrap(driver)
+	}
+	if *querystats.Enabled {
+		driver = querystats.Wrap(driver)
+	}
+	if driverStats != nil {
+		driver = driverStats.Wrap(driver)
+	}
 	backend, err := opa.NewBackend(opa.Driver(driver))
 	if err != nil {
 		setupLog.Error(err, "unable to set up OPA backend")
@@ -256,6 +487,8 @@ func setupControllers(mgr ctrl.Manager, sw *watch.ControllerSwitch, tracker *rea
 	}
 
 	mutationSystem := mutation.NewSystem(mutation.SystemOpts{Reporter: mutation.NewStatsReporter()})
+	introspection.Get().SetMutatorSource(introspection.MutatorSource(mutationSystem))
+	expansionSystem := expansion.NewSystem(mutationSystem)
 
 	c := mgr.GetCache()
 	dc, ok := c.(watch.RemovableCache)
@@ -287,6 +520,41 @@ func setupControllers(mgr ctrl.Manager, sw *watch.ControllerSwitch, tracker *rea
 		Tracker:          tracker,
 		ProcessExcluder:  processExcluder,
 		MutationSystem:   mutationSystem,
+		ExpansionSystem:  expansionSystem,
+		EngineSelector:   engines,
+	}
+	if recorder != nil {
+		opts.Restore = func(ctx context.Context) error {
+			// The snapshot of a running pod is preferred, as it is more
+			// recent than the one saved to disk.
+			restored := *policysnapshot.Enabled && recorder.RestoreFromLeader(ctx, mgr.GetConfig(), driver)
+			if !restored && *policysnapshot.Dir != "" {
+				restored = recorder.RestoreFromDir(ctx, *policysnapshot.Dir, *policysnapshot.MaxAge, driver)
+			}
+			if restored {
+				tracker.Restored()
+			}
+			return nil
+		}
+		if err := mgr.Add(recorder.Pruner(driver, tracker.Satisfied)); err != nil {
+			setupLog.Error(err, "unable to register policy snapshot pruner")
+			os.Exit(1)
+		}
+		if *policysnapshot.Dir != "" {
+			if err := mgr.Add(recorder.Saver(*policysnapshot.Dir, tracker.Satisfied)); err != nil {
+				setupLog.Error(err, "unable to register policy snapshot saver")
+				os.Exit(1)
+			}
+		}
+	}
+
+	if *mutation.MutationEnabled && *openapi.Enabled {
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			setupLog.Error(err, "unable to create discovery client for mutator schema validation")
+			os.Exit(1)
+		}
+		opts.SchemaValidator = openapi.NewValidator(discoveryClient)
 	}
 
 	ctx := context.Background()
@@ -297,14 +565,14 @@ func setupControllers(mgr ctrl.Manager, sw *watch.ControllerSwitch, tracker *rea
 
 	if operations.IsAssigned(operations.Webhook) {
 		setupLog.Info("setting up webhooks")
-		if err := webhook.AddToManager(mgr, client, processExcluder, mutationSystem); err != nil {
+		if err := webhook.AddToManager(mgr, client, processExcluder, mutationSystem, expansionSystem); err != nil {
 			setupLog.Error(err, "unable to register webhooks with the manager")
 			os.Exit(1)
 		}
 	}
 	if operations.IsAssigned(operations.Audit) {
 		setupLog.Info("setting up audit")
-		if err := audit.AddToManager(mgr, client, processExcluder); err != nil {
+		if err := audit.AddToManager(mgr, client, processExcluder, wm); err != nil {
 			setupLog.Error(err, "unable to register audit with the manager")
 			os.Exit(1)
 		}
@@ -323,20 +591,45 @@ func setupControllers(mgr ctrl.Manager, sw *watch.ControllerSwitch, tracker *rea
 	}
 }
 
-func setLoggerForProduction(encoder zapcore.LevelEncoder) {
+// waitForCerts closes ready once a serving certificate is mounted in certDir.
+func waitForCerts(certDir string, ready chan<- struct{}) {
+	_ = wait.PollImmediateInfinite(time.Second, func() (bool, error) {
+		_, err := os.Stat(filepath.Join(certDir, "tls.crt"))
+		return err == nil, nil
+	})
+	setupLog.Info("serving certificate is mounted")
+	close(ready)
+}
+
+// defaultLogSettings returns the logging settings implied by --log-level.
+func defaultLogSettings(level string) logging.Settings {
+	switch level {
+	case "DEBUG":
+		return logging.Settings{Level: zapcore.DebugLevel}
+	case "WARNING", "ERROR":
+		return logging.Settings{
+			Level:    zapcore.WarnLevel,
+			Sampling: map[string]logging.Sampling{"": {Initial: 100, Thereafter: 100}},
+		}
+	default:
+		return logging.Settings{
+			Level:    zapcore.InfoLevel,
+			Sampling: map[string]logging.Sampling{"": {Initial: 100, Thereafter: 100}},
+		}
+	}
+}
+
+func setLoggerForProduction(encoder zapcore.LevelEncoder, logConfig *logging.DynamicConfig) {
 	sink := zapcore.AddSync(os.Stderr)
 	var opts []zap.Option
 	encCfg := zap.NewProductionEncoderConfig()
 	encCfg.LevelKey = *logLevelKey
 	encCfg.EncodeLevel = encoder
 	enc := zapcore.NewJSONEncoder(encCfg)
-	lvl := zap.NewAtomicLevelAt(zap.WarnLevel)
 	opts = append(opts, zap.AddStacktrace(zap.ErrorLevel),
-		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-			return zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
-		}),
+		zap.WrapCore(logConfig.WrapCore),
 		zap.AddCallerSkip(1), zap.ErrorOutput(sink))
-	zlog := zap.New(zapcore.NewCore(&crzap.KubeAwareEncoder{Encoder: enc, Verbose: false}, sink, lvl))
+	zlog := zap.New(zapcore.NewCore(&crzap.KubeAwareEncoder{Encoder: enc, Verbose: false}, sink, logging.AllLevels))
 	zlog = zlog.WithOptions(opts...)
 	newlogger := zapr.NewLogger(zlog)
 	ctrl.SetLogger(newlogger)
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"sort"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxTopConstraints is the number of constraints listed in a ComplianceReport.
+const maxTopConstraints = 5
+
+// compliance tallies the violations found by an audit in each namespace, to
+// be published as ComplianceReports.
+type compliance struct {
+	namespaces map[string]*namespaceCompliance
+}
+
+// namespaceCompliance are the violations by the objects in a namespace.
+type namespaceCompliance struct {
+	total       int64
+	bySeverity  map[severity.Level]int64
+	constraints map[constraintKey]*v1beta1.ConstraintViolations
+}
+
+type constraintKey struct {
+	kind string
+	name string
+}
+
+func newCompliance() *compliance {
+	return &compliance{namespaces: make(map[string]*namespaceCompliance)}
+}
+
+// add records that constraint is violated by resource. Violations by
+// cluster-scoped resources are not in any namespace, so they are ignored.
+func (c *compliance) add(constraint, resource *unstructured.Unstructured) {
+	ns := resource.GetNamespace()
+	if ns == "" {
+		return
+	}
+	nc := c.namespaces[ns]
+	if nc == nil {
+		nc = &namespaceCompliance{
+			bySeverity:  make(map[severity.Level]int64),
+			constraints: make(map[constraintKey]*v1beta1.ConstraintViolations),
+		}
+		c.namespaces[ns] = nc
+	}
+	level, _ := severity.Of(constraint)
+	nc.total++
+	nc.bySeverity[level]++
+	key := constraintKey{kind: constraint.GetKind(), name: constraint.GetName()}
+	cv := nc.constraints[key]
+	if cv == nil {
+		cv = &v1beta1.ConstraintViolations{Kind: key.kind, Name: key.name}
+		if level != severity.None {
+			cv.Severity = string(level)
+		}
+		nc.constraints[key] = cv
+	}
+	cv.Violations++
+}
+
+// status returns the status of the ComplianceReport of namespace for the
+// audit at timestamp. previous is the status of its report before the
+// audit, or nil if it had none.
+func (c *compliance) status(namespace, timestamp string, previous *v1beta1.ComplianceReportStatus) v1beta1.ComplianceReportStatus {
+	s := v1beta1.ComplianceReportStatus{AuditTimestamp: timestamp}
+	if nc := c.namespaces[namespace]; nc != nil {
+		s.TotalViolations = nc.total
+		s.ViolationsBySeverity = make(map[string]int64)
+		for level, v := range nc.bySeverity {
+			s.ViolationsBySeverity[string(level)] = v
+		}
+		for _, cv := range nc.constraints {
+			s.TopViolatedConstraints = append(s.TopViolatedConstraints, *cv)
+		}
+		sort.Slice(s.TopViolatedConstraints, func(i, j int) bool {
+			a, b := s.TopViolatedConstraints[i], s.TopViolatedConstraints[j]
+			if a.Violations != b.Violations {
+				return a.Violations > b.Violations
+			}
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			return a.Name < b.Name
+		})
+		if len(s.TopViolatedConstraints) > maxTopConstraints {
+			s.TopViolatedConstraints = s.TopViolatedConstraints[:maxTopConstraints]
+		}
+	}
+	if previous != nil {
+		total := previous.TotalViolations
+		s.PreviousTotalViolations = &total
+		switch {
+		case s.TotalViolations < total:
+			s.Trend = v1beta1.TrendImproving
+		case s.TotalViolations > total:
+			s.Trend = v1beta1.TrendWorsening
+		default:
+			s.Trend = v1beta1.TrendUnchanged
+		}
+	}
+	return s
+}
+
+// writeComplianceReports publishes the ComplianceReport of every namespace
+// with violations found by the current audit. The reports of namespaces
+// without violations are reset to none, and are then left alone until
+// violations are found in them again.
+func (am *Manager) writeComplianceReports(ctx context.Context, timestamp string) {
+	list := &v1beta1.ComplianceReportList{}
+	if err := am.client.List(ctx, list); err != nil {
+		am.log.Error(err, "unable to list compliance reports")
+		return
+	}
+	existing := make(map[string]*v1beta1.ComplianceReport)
+	for i := range list.Items {
+		if list.Items[i].GetName() == v1beta1.ComplianceReportName {
+			existing[list.Items[i].GetNamespace()] = &list.Items[i]
+		}
+	}
+
+	for ns := range am.compliance.namespaces {
+		if _, ok := existing[ns]; ok {
+			continue
+		}
+		report := &v1beta1.ComplianceReport{}
+		report.SetName(v1beta1.ComplianceReportName)
+		report.SetNamespace(ns)
+		report.Status = am.compliance.status(ns, timestamp, nil)
+		if err := am.client.Create(ctx, report); err != nil {
+			am.log.Error(err, "unable to create compliance report", "namespace", ns)
+		}
+	}
+	for ns, report := range existing {
+		if _, ok := am.compliance.namespaces[ns]; !ok && report.Status.TotalViolations == 0 {
+			continue
+		}
+		report.Status = am.compliance.status(ns, timestamp, &report.Status)
+		if err := am.client.Update(ctx, report); err != nil {
+			am.log.Error(err, "unable to update compliance report", "namespace", ns)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reportClient holds ComplianceReports by namespace.
+type reportClient struct {
+	client.Client
+	reports map[string]v1beta1.ComplianceReport
+	updates int
+}
+
+func (c *reportClient) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	l := list.(*v1beta1.ComplianceReportList)
+	for _, r := range c.reports {
+		l.Items = append(l.Items, *r.DeepCopy())
+	}
+	return nil
+}
+
+func (c *reportClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	r := obj.(*v1beta1.ComplianceReport)
+	c.reports[r.GetNamespace()] = *r.DeepCopy()
+	return nil
+}
+
+func (c *reportClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	r := obj.(*v1beta1.ComplianceReport)
+	c.reports[r.GetNamespace()] = *r.DeepCopy()
+	c.updates++
+	return nil
+}
+
+func TestWriteComplianceReports(t *testing.T) {
+	ctx := context.Background()
+	c := &reportClient{reports: make(map[string]v1beta1.ComplianceReport)}
+	am := &Manager{client: c, log: logr.Discard()}
+
+	critical := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sRequiredLabels", "", "critical")
+	critical.Object["spec"] = map[string]interface{}{"severity": "critical"}
+	unset := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sAllowedRepos", "", "unset")
+
+	am.compliance = newCompliance()
+	am.compliance.add(critical, deltaObject("v1", "Pod", "a", "pod-1"))
+	am.compliance.add(critical, deltaObject("v1", "Pod", "a", "pod-2"))
+	am.compliance.add(unset, deltaObject("v1", "Pod", "a", "pod-1"))
+	am.compliance.add(unset, deltaObject("v1", "Pod", "b", "pod"))
+	// Cluster-scoped resources are in no namespace.
+	am.compliance.add(critical, deltaObject("v1", "Namespace", "", "a"))
+	am.writeComplianceReports(ctx, "t1")
+
+	want := map[string]v1beta1.ComplianceReportStatus{
+		"a": {
+			AuditTimestamp:       "t1",
+			TotalViolations:      3,
+			ViolationsBySeverity: map[string]int64{"critical": 2, "none": 1},
+			TopViolatedConstraints: []v1beta1.ConstraintViolations{
+				{Kind: "K8sRequiredLabels", Name: "critical", Severity: "critical", Violations: 2},
+				{Kind: "K8sAllowedRepos", Name: "unset", Violations: 1},
+			},
+		},
+		"b": {
+			AuditTimestamp:         "t1",
+			TotalViolations:        1,
+			ViolationsBySeverity:   map[string]int64{"none": 1},
+			TopViolatedConstraints: []v1beta1.ConstraintViolations{{Kind: "K8sAllowedRepos", Name: "unset", Violations: 1}},
+		},
+	}
+	if diff := cmp.Diff(want, statuses(c)); diff != "" {
+		t.Error(diff)
+	}
+
+	// The next audit finds fewer violations in a and none in b.
+	am.compliance = newCompliance()
+	am.compliance.add(critical, deltaObject("v1", "Pod", "a", "pod-1"))
+	am.writeComplianceReports(ctx, "t2")
+	want = map[string]v1beta1.ComplianceReportStatus{
+		"a": {
+			AuditTimestamp:          "t2",
+			TotalViolations:         1,
+			ViolationsBySeverity:    map[string]int64{"critical": 1},
+			TopViolatedConstraints:  []v1beta1.ConstraintViolations{{Kind: "K8sRequiredLabels", Name: "critical", Severity: "critical", Violations: 1}},
+			PreviousTotalViolations: int64Ptr(3),
+			Trend:                   v1beta1.TrendImproving,
+		},
+		"b": {
+			AuditTimestamp:          "t2",
+			PreviousTotalViolations: int64Ptr(1),
+			Trend:                   v1beta1.TrendImproving,
+		},
+	}
+	if diff := cmp.Diff(want, statuses(c)); diff != "" {
+		t.Error(diff)
+	}
+
+	// Reports without violations are not updated again.
+	c.updates = 0
+	am.compliance = newCompliance()
+	am.compliance.add(critical, deltaObject("v1", "Pod", "a", "pod-1"))
+	am.writeComplianceReports(ctx, "t3")
+	if c.updates != 1 {
+		t.Errorf("got %d reports updated, want only that of a", c.updates)
+	}
+	if got := c.reports["a"].Status.Trend; got != v1beta1.TrendUnchanged {
+		t.Errorf("got trend %q, want %q", got, v1beta1.TrendUnchanged)
+	}
+}
+
+func TestComplianceTopConstraints(t *testing.T) {
+	c := newCompliance()
+	for i, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		constraint := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sRequiredLabels", "", name)
+		for j := 0; j <= i; j++ {
+			c.add(constraint, deltaObject("v1", "Pod", "ns", "pod"))
+		}
+	}
+	var got []string
+	for _, cv := range c.status("ns", "t", nil).TopViolatedConstraints {
+		got = append(got, cv.Name)
+	}
+	if diff := cmp.Diff([]string{"g", "f", "e", "d", "c"}, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func statuses(c *reportClient) map[string]v1beta1.ComplianceReportStatus {
+	out := make(map[string]v1beta1.ComplianceReportStatus)
+	for ns, r := range c.reports {
+		out[ns] = r.Status
+	}
+	return out
+}
@@ -15,16 +15,22 @@ package audit
 import (
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/election"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 // AddToManager adds audit manager to the Manager.
-func AddToManager(m manager.Manager, opa *opa.Client, processExcluder *process.Excluder) error {
+func AddToManager(m manager.Manager, opa *opa.Client, processExcluder *process.Excluder, wm *watch.Manager) error {
 	if *auditInterval == 0 {
 		log.Info("auditing is disabled")
 		return nil
 	}
-	am, err := New(m, opa, processExcluder)
+	m, err := election.Manager(m, election.Audit)
+	if err != nil {
+		return err
+	}
+	am, err := New(m, opa, processExcluder, wm)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"sort"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// coverage tallies what a single audit run evaluated. A nil coverage tallies
+// nothing, so iterators built outside of an audit run need not track it.
+type coverage struct {
+	// discovered are the kinds the audit could have listed.
+	discovered []schema.GroupVersionKind
+	// listed are the kinds with at least one page listed, and failed those
+	// for which listing a page failed.
+	listed map[schema.GroupVersionKind]bool
+	failed map[schema.GroupVersionKind]bool
+	// excludedNamespaces are the namespaces of the objects skipped because
+	// their namespace is excluded.
+	excludedNamespaces map[string]bool
+
+	objectsReviewed  int64
+	objectsExcluded  int64
+	objectsUnmatched int64
+	// countUnmatched is whether the constraints matching each reviewed object
+	// are known, so objectsUnmatched is counted.
+	countUnmatched bool
+}
+
+func newCoverage(countUnmatched bool) *coverage {
+	return &coverage{
+		listed:             make(map[schema.GroupVersionKind]bool),
+		failed:             make(map[schema.GroupVersionKind]bool),
+		excludedNamespaces: make(map[string]bool),
+		countUnmatched:     countUnmatched,
+	}
+}
+
+func (c *coverage) discover(kinds []schema.GroupVersionKind) {
+	if c != nil {
+		c.discovered = append(c.discovered, kinds...)
+	}
+}
+
+func (c *coverage) list(gvk schema.GroupVersionKind, err error) {
+	if c == nil {
+		return
+	}
+	if err != nil {
+		c.failed[gvk] = true
+		return
+	}
+	c.listed[gvk] = true
+}
+
+func (c *coverage) exclude(namespace string) {
+	if c == nil {
+		return
+	}
+	c.objectsExcluded++
+	// Cluster-scoped objects, such as Gatekeeper's own CRDs, are excluded
+	// without a namespace.
+	if namespace != "" {
+		c.excludedNamespaces[namespace] = true
+	}
+}
+
+// review counts a reviewed object which constraintsMatched constraints match.
+func (c *coverage) review(constraintsMatched int) {
+	if c == nil {
+		return
+	}
+	c.objectsReviewed++
+	if c.countUnmatched && constraintsMatched == 0 {
+		c.objectsUnmatched++
+	}
+}
+
+// audited returns whether every object of gvk was listed.
+func (c *coverage) audited(gvk schema.GroupVersionKind) bool {
+	return c.listed[gvk] && !c.failed[gvk]
+}
+
+// status returns the coverage as reported in the GatekeeperStatus.
+func (c *coverage) status() *v1beta1.AuditCoverage {
+	s := &v1beta1.AuditCoverage{
+		KindsDiscovered: len(c.discovered),
+		ObjectsReviewed: c.objectsReviewed,
+		ObjectsExcluded: c.objectsExcluded,
+	}
+	for _, gvk := range c.discovered {
+		if c.audited(gvk) {
+			s.KindsAudited++
+		} else {
+			s.UnauditedKinds = append(s.UnauditedKinds, gvk.String())
+		}
+	}
+	for gvk := range c.failed {
+		s.FailedKinds = append(s.FailedKinds, gvk.String())
+	}
+	for ns := range c.excludedNamespaces {
+		s.ExcludedNamespaces = append(s.ExcludedNamespaces, ns)
+	}
+	sort.Strings(s.UnauditedKinds)
+	sort.Strings(s.FailedKinds)
+	sort.Strings(s.ExcludedNamespaces)
+	if c.countUnmatched {
+		unmatched := c.objectsUnmatched
+		s.ObjectsUnmatched = &unmatched
+	}
+	return s
+}
+
+// reportCoverage records s in the audit metrics.
+func (r *reporter) reportCoverage(s *v1beta1.AuditCoverage) error {
+	kinds := map[string]int64{
+		coverageDiscovered: int64(s.KindsDiscovered),
+		coverageAudited:    int64(s.KindsAudited),
+		coverageFailed:     int64(len(s.FailedKinds)),
+	}
+	for state, v := range kinds {
+		if err := r.reportCoverageKinds(state, v); err != nil {
+			return err
+		}
+	}
+	objects := map[string]int64{
+		coverageReviewed: s.ObjectsReviewed,
+		coverageExcluded: s.ObjectsExcluded,
+	}
+	if s.ObjectsUnmatched != nil {
+		objects[coverageUnmatched] = *s.ObjectsUnmatched
+	}
+	for state, v := range objects {
+		if err := r.reportCoverageObjects(state, v); err != nil {
+			return err
+		}
+	}
+	return r.reportExcludedNamespaces(int64(len(s.ExcludedNamespaces)))
+}
+
+// reportCoverage publishes what the current audit evaluated in its log, its
+// metrics and, through LastCoverage, the GatekeeperStatus.
+func (am *Manager) reportCoverage() {
+	s := am.coverage.status()
+	keysAndValues := []interface{}{
+		"kinds_discovered", s.KindsDiscovered,
+		"kinds_audited", s.KindsAudited,
+		"unaudited_kinds", len(s.UnauditedKinds),
+		"failed_kinds", s.FailedKinds,
+		"excluded_namespaces", s.ExcludedNamespaces,
+		"objects_reviewed", s.ObjectsReviewed,
+		"objects_excluded", s.ObjectsExcluded,
+	}
+	if s.ObjectsUnmatched != nil {
+		keysAndValues = append(keysAndValues, "objects_unmatched", *s.ObjectsUnmatched)
+	}
+	am.log.Info("audit coverage", keysAndValues...)
+
+	lastRun.covered(s)
+	if err := am.reporter.reportCoverage(s); err != nil {
+		am.log.Error(err, "failed to report audit coverage")
+	}
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCoverage_Unmatched(t *testing.T) {
+	tcs := []struct {
+		name           string
+		countUnmatched bool
+		want           *int64
+	}{
+		{name: "matches not counted"},
+		{name: "matches counted", countUnmatched: true, want: int64Ptr(2)},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newCoverage(tc.countUnmatched)
+			for _, matched := range []int{0, 3, 0, 1} {
+				c.review(matched)
+			}
+
+			s := c.status()
+			if s.ObjectsReviewed != 4 {
+				t.Errorf("got %d objects reviewed, want 4", s.ObjectsReviewed)
+			}
+			if (s.ObjectsUnmatched == nil) != (tc.want == nil) || (tc.want != nil && *s.ObjectsUnmatched != *tc.want) {
+				t.Errorf("got objects unmatched %v, want %v", s.ObjectsUnmatched, tc.want)
+			}
+		})
+	}
+}
+
+func TestCoverage_PartiallyListed(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	c := newCoverage(false)
+	c.discover([]schema.GroupVersionKind{gvk})
+	// The first page was listed, but a later one failed.
+	c.list(gvk, nil)
+	c.list(gvk, errors.New("timeout"))
+
+	s := c.status()
+	if s.KindsAudited != 0 || len(s.UnauditedKinds) != 1 || len(s.FailedKinds) != 1 {
+		t.Errorf("got %+v, want the kind to be unaudited and failed", s)
+	}
+}
+
+func TestCoverage_Nil(t *testing.T) {
+	var c *coverage
+	c.discover([]schema.GroupVersionKind{{Version: "v1", Kind: "Pod"}})
+	c.list(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, nil)
+	c.exclude("kube-system")
+	c.review(0)
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
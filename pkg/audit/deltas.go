@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"hash/fnv"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// violationSet holds the violations found by an audit, keyed by constraint.
+type violationSet map[util.KindVersionResource]*constraintViolations
+
+// constraintViolations are the violations of a constraint, as hashes of the
+// violating resources.
+type constraintViolations struct {
+	kind      string
+	name      string
+	resources map[uint64]bool
+}
+
+// add records that constraint is violated by resource.
+func (s violationSet) add(constraint, resource *unstructured.Unstructured) {
+	h := fnv.New64a()
+	for _, part := range []string{resource.GroupVersionKind().GroupKind().String(), resource.GetNamespace(), resource.GetName()} {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	key := util.GetUniqueKey(*constraint)
+	if s[key] == nil {
+		s[key] = &constraintViolations{kind: constraint.GetKind(), name: constraint.GetName(), resources: make(map[uint64]bool)}
+	}
+	s[key].resources[h.Sum64()] = true
+}
+
+// has returns whether s holds the violation of the constraint with key by the
+// resource with hash.
+func (s violationSet) has(key util.KindVersionResource, hash uint64) bool {
+	c, ok := s[key]
+	return ok && c.resources[hash]
+}
+
+// violationDelta is how the violations of a constraint changed between two
+// audits.
+type violationDelta struct {
+	// New is the number of violations found which the previous audit did not
+	// find.
+	New int64
+	// Resolved is the number of violations the previous audit found which
+	// were not found.
+	Resolved int64
+	// Unchanged is the number of violations found by both audits.
+	Unchanged int64
+
+	kind string
+	name string
+}
+
+// diffViolations returns the deltas of every constraint violated in either
+// previous or current.
+func diffViolations(previous, current violationSet) map[util.KindVersionResource]violationDelta {
+	deltas := make(map[util.KindVersionResource]violationDelta)
+	for key, c := range current {
+		d := violationDelta{kind: c.kind, name: c.name}
+		for v := range c.resources {
+			if previous.has(key, v) {
+				d.Unchanged++
+			} else {
+				d.New++
+			}
+		}
+		deltas[key] = d
+	}
+	for key, c := range previous {
+		d, ok := deltas[key]
+		if !ok {
+			d = violationDelta{kind: c.kind, name: c.name}
+		}
+		for v := range c.resources {
+			if !current.has(key, v) {
+				d.Resolved++
+			}
+		}
+		deltas[key] = d
+	}
+	return deltas
+}
+
+// reportDeltas reports the deltas of each constraint, summed over the
+// constraints of a kind whose names are not recorded.
+func (am *Manager) reportDeltas(deltas map[util.KindVersionResource]violationDelta) {
+	type tags struct {
+		kind, name string
+	}
+	sums := make(map[tags]violationDelta)
+	for _, d := range deltas {
+		// The name is empty if constraint names are not recorded.
+		name, _ := metrics.ConstraintNameTagValue(d.name)
+		t := tags{kind: d.kind, name: name}
+		sum := sums[t]
+		sum.New += d.New
+		sum.Resolved += d.Resolved
+		sum.Unchanged += d.Unchanged
+		sums[t] = sum
+	}
+	for t, d := range sums {
+		if err := am.reporter.reportViolationDelta(t.kind, t.name, d); err != nil {
+			am.log.Error(err, "failed to report violation changes")
+		}
+	}
+}
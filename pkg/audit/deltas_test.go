@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"go.opencensus.io/stats/view"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deltaObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestDiffViolations(t *testing.T) {
+	ownerLabel := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sRequiredLabels", "", "owner-label")
+	uniqueHost := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sUniqueIngressHost", "", "unique-host")
+	podA := deltaObject("v1", "Pod", "a", "pod")
+	podB := deltaObject("v1", "Pod", "b", "pod")
+	nsA := deltaObject("v1", "Namespace", "", "a")
+
+	previous := make(violationSet)
+	previous.add(ownerLabel, podA)
+	previous.add(ownerLabel, nsA)
+	previous.add(uniqueHost, podA)
+
+	current := make(violationSet)
+	current.add(ownerLabel, podA)
+	current.add(ownerLabel, podB)
+	// Violations are counted once per resource.
+	current.add(ownerLabel, podB)
+
+	got := diffViolations(previous, current)
+	want := map[util.KindVersionResource]violationDelta{
+		util.GetUniqueKey(*ownerLabel): {New: 1, Resolved: 1, Unchanged: 1, kind: "K8sRequiredLabels", name: "owner-label"},
+		util.GetUniqueKey(*uniqueHost): {Resolved: 1, kind: "K8sUniqueIngressHost", name: "unique-host"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(util.KindVersionResource{}, violationDelta{})); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestReportViolationDelta(t *testing.T) {
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reportViolationDelta("K8sRequiredLabels", "", violationDelta{New: 3, Resolved: 2, Unchanged: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(violationChangesMetricName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, row := range rows {
+		var change string
+		for _, tag := range row.Tags {
+			switch tag.Key.Name() {
+			case "change":
+				change = tag.Value
+			case "constraint_name":
+				t.Errorf("got constraint name %q recorded", tag.Value)
+			}
+		}
+		got[change] = row.Data.(*view.LastValueData).Value
+	}
+	want := map[string]float64{"new": 3, "resolved": 2, "unchanged": 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+
+	constraintTypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// exemptions are the violations exempted by an Exception during an audit.
+type exemptions struct {
+	total int64
+	items []exceptionsv1alpha1.Exemption
+}
+
+// exempt returns the results for obj which are not exempted by an Exception,
+// recording the others against the Exceptions exempting them.
+func (am *Manager) exempt(obj interface{}, results []*constraintTypes.Result) []*constraintTypes.Result {
+	var ns *corev1.Namespace
+	if au, ok := obj.(target.AugmentedUnstructured); ok {
+		ns = au.Namespace
+	}
+	kept, exempted := exception.Get().Filter(results, ns)
+	for r, names := range exempted {
+		resource, ok := r.Resource.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			am.recordExemption(name, exceptionsv1alpha1.Exemption{
+				ConstraintKind: r.Constraint.GetKind(),
+				ConstraintName: r.Constraint.GetName(),
+				Kind:           resource.GetKind(),
+				Name:           resource.GetName(),
+				Namespace:      resource.GetNamespace(),
+			})
+		}
+	}
+	return kept
+}
+
+func (am *Manager) recordExemption(name string, e exceptionsv1alpha1.Exemption) {
+	if am.exemptions == nil {
+		am.exemptions = make(map[string]*exemptions)
+	}
+	ex, ok := am.exemptions[name]
+	if !ok {
+		ex = &exemptions{}
+		am.exemptions[name] = ex
+	}
+	ex.total++
+	if uint(len(ex.items)) < *constraintViolationsLimit {
+		ex.items = append(ex.items, e)
+	}
+}
+
+// writeExceptionStatuses records the exemptions of the audit started at
+// timestamp in the status of every Exception.
+func (am *Manager) writeExceptionStatuses(ctx context.Context, timestamp string) {
+	list := &exceptionsv1alpha1.ExceptionList{}
+	if err := am.client.List(ctx, list); err != nil {
+		am.log.Error(err, "unable to list exceptions")
+		return
+	}
+	for i := range list.Items {
+		e := &list.Items[i]
+		e.Status = exceptionsv1alpha1.ExceptionStatus{
+			AuditTimestamp: timestamp,
+			Expired:        exception.Get().Expired(e),
+		}
+		if ex, ok := am.exemptions[e.GetName()]; ok {
+			e.Status.TotalExemptions = ex.total
+			e.Status.Exemptions = ex.items
+		}
+		if err := am.client.Status().Update(ctx, e); err != nil {
+			am.log.Error(err, "unable to update exception status", "exceptionName", e.GetName())
+		}
+	}
+}
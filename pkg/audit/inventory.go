@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/inventory"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// checkInventory logs the templates which read kinds from data.inventory that
+// are not replicated. Their constraints find no objects of those kinds, so
+// audit may miss their violations.
+func (am *Manager) checkInventory(ctx context.Context) {
+	templateList := &v1beta1.ConstraintTemplateList{}
+	if err := am.client.List(ctx, templateList); err != nil {
+		am.log.Error(err, "unable to list templates to check the data they read is replicated")
+		return
+	}
+	synced, err := am.syncedKinds(ctx)
+	if err != nil {
+		am.log.Error(err, "unable to get replicated kinds to check templates against")
+		return
+	}
+	replicated := make(map[schema.GroupVersionKind]bool, len(synced))
+	for _, gvk := range synced {
+		replicated[gvk] = true
+	}
+
+	for i := range templateList.Items {
+		ct := &templateList.Items[i]
+		// Templates whose Rego does not parse are reported by the template
+		// controller.
+		usage, err := inventory.ExtractV1Beta1(ct)
+		if err != nil {
+			continue
+		}
+		missing := usage.Missing(replicated)
+		if len(missing) == 0 {
+			continue
+		}
+		kinds := make([]string, len(missing))
+		for j, gvk := range missing {
+			kinds[j] = gvk.String()
+		}
+		am.log.Info("template reads kinds which are not replicated, so its constraints may miss violations", "template", ct.GetName(), "kinds", kinds)
+	}
+}
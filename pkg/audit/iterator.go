@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/review"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectIterator yields the objects of each kind listed from a reader, one page
+// at a time, ready to be reviewed.
+type objectIterator struct {
+	reader client.Reader
+	kinds  []schema.GroupVersionKind
+	// limit is the number of objects listed per page, or 0 to list all objects
+	// of a kind at once.
+	limit int64
+
+	// excluded is the processes whose excluded namespaces are skipped.
+	excluded        []process.Process
+	processExcluder *process.Excluder
+	namespaces      *nsCache
+	nsClient        client.Client
+	log             logr.Logger
+	// coverage tallies the kinds listed and objects excluded, if set.
+	coverage *coverage
+
+	page            *unstructured.UnstructuredList
+	next            int
+	resourceVersion string
+	continueToken   string
+}
+
+var _ review.Iterator = &objectIterator{}
+
+func (it *objectIterator) Next(ctx context.Context) (interface{}, bool, error) {
+	for {
+		for it.page != nil && it.next < len(it.page.Items) {
+			obj := &it.page.Items[it.next]
+			it.next++
+			if augmented, ok := it.prepare(ctx, obj); ok {
+				return augmented, true, nil
+			}
+		}
+
+		if it.page == nil || it.continueToken == "" {
+			// The current kind is done.
+			if len(it.kinds) == 0 {
+				it.page = nil
+				return nil, false, nil
+			}
+			it.page = &unstructured.UnstructuredList{}
+			it.page.SetGroupVersionKind(it.kinds[0].GroupVersion().WithKind(it.kinds[0].Kind + "List"))
+			it.kinds = it.kinds[1:]
+			it.resourceVersion = ""
+		}
+		it.list(ctx)
+	}
+}
+
+// list fetches the next page of the current kind. A kind which cannot be listed
+// is logged and skipped.
+func (it *objectIterator) list(ctx context.Context) {
+	gvk := it.page.GroupVersionKind()
+	opts := &client.ListOptions{Limit: it.limit, Continue: it.continueToken}
+	it.page.SetResourceVersion(it.resourceVersion)
+	it.page.Items = nil
+	it.next = 0
+	err := it.reader.List(ctx, it.page, opts)
+	it.coverage.list(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: strings.TrimSuffix(gvk.Kind, "List")}, err)
+	if err != nil {
+		it.log.Error(err, "Unable to list objects for gvk", "group", gvk.Group, "version", gvk.Version, "kind", gvk.Kind)
+		it.page.Items = nil
+		it.continueToken = ""
+		return
+	}
+	it.resourceVersion = it.page.GetResourceVersion()
+	it.continueToken = it.page.GetContinue()
+}
+
+// prepare returns obj with its namespace for review, or false if obj is not to
+// be reviewed.
+func (it *objectIterator) prepare(ctx context.Context, obj *unstructured.Unstructured) (interface{}, bool) {
+	for _, p := range it.excluded {
+		excluded, err := it.processExcluder.IsNamespaceExcluded(p, obj)
+		if err != nil {
+			it.log.Error(err, "error while excluding namespaces")
+		}
+		if excluded {
+			it.coverage.exclude(obj.GetNamespace())
+			return nil, false
+		}
+	}
+
+	ns := corev1.Namespace{}
+	if objNamespace := obj.GetNamespace(); objNamespace != "" {
+		var err error
+		ns, err = it.namespaces.Get(ctx, it.nsClient, objNamespace)
+		if err != nil {
+			gvk := obj.GroupVersionKind()
+			it.log.Error(err, "Unable to look up object namespace", "group", gvk.Group, "version", gvk.Version, "kind", gvk.Kind)
+			return nil, false
+		}
+	}
+
+	return target.AugmentedUnstructured{
+		Object:    *obj,
+		Namespace: &ns,
+	}, true
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	statusv1beta1 "github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// pagingReader lists objects by kind a page at a time, and fails to list kinds
+// it has no objects for.
+type pagingReader struct {
+	client.Reader
+	objects map[string][]unstructured.Unstructured
+	lists   int
+}
+
+func (r *pagingReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.lists++
+	u := list.(*unstructured.UnstructuredList)
+	objs, ok := r.objects[u.GetKind()]
+	if !ok {
+		return errors.New("no such kind")
+	}
+	o := &client.ListOptions{}
+	o.ApplyOptions(opts)
+
+	start := 0
+	if o.Continue != "" {
+		start, _ = strconv.Atoi(o.Continue)
+	}
+	end := len(objs)
+	if o.Limit > 0 && start+int(o.Limit) < end {
+		end = start + int(o.Limit)
+		u.SetContinue(strconv.Itoa(end))
+	} else {
+		u.SetContinue("")
+	}
+	u.Items = append([]unstructured.Unstructured(nil), objs[start:end]...)
+	return nil
+}
+
+// namespaceClient gets every namespace with a label naming it.
+type namespaceClient struct {
+	client.Client
+}
+
+func (c *namespaceClient) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	ns := obj.(*corev1.Namespace)
+	ns.Name = key.Name
+	ns.Labels = map[string]string{"name": key.Name}
+	return nil
+}
+
+func object(kind, namespace, name string) unstructured.Unstructured {
+	u := unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: kind})
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestObjectIterator(t *testing.T) {
+	reader := &pagingReader{objects: map[string][]unstructured.Unstructured{
+		"PodList": {
+			object("Pod", "default", "a"),
+			object("Pod", "kube-system", "excluded"),
+			object("Pod", "default", "b"),
+			object("Pod", "other", "c"),
+		},
+		"NodeList": {object("Node", "", "n")},
+	}}
+	excluder := process.New()
+	excluder.Add([]v1alpha1.MatchEntry{{
+		Processes:          []string{string(process.Audit)},
+		ExcludedNamespaces: []util.PrefixWildcard{"kube-*"},
+	}})
+
+	kinds := []schema.GroupVersionKind{
+		{Version: "v1", Kind: "Pod"},
+		{Version: "v1", Kind: "Broken"},
+		{Version: "v1", Kind: "Node"},
+	}
+	cov := newCoverage(false)
+	cov.discover(kinds)
+	it := &objectIterator{
+		reader:          reader,
+		kinds:           kinds,
+		limit:           2,
+		excluded:        []process.Process{process.Audit},
+		processExcluder: excluder,
+		namespaces:      newNSCache(),
+		nsClient:        &namespaceClient{},
+		log:             logf.Log,
+		coverage:        cov,
+	}
+
+	var got []string
+	for {
+		obj, ok, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		augmented := obj.(target.AugmentedUnstructured)
+		if ns := augmented.Object.GetNamespace(); ns != "" && augmented.Namespace.Labels["name"] != ns {
+			t.Errorf("got namespace %q for object in namespace %q", augmented.Namespace.Name, ns)
+		}
+		got = append(got, augmented.Object.GetName())
+	}
+
+	want := []string{"a", "b", "c", "n"}
+	if len(got) != len(want) {
+		t.Fatalf("got objects %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got objects %v, want %v", got, want)
+			break
+		}
+	}
+	// Two pages of Pods, one failed list of Broken, and one page of Nodes.
+	if reader.lists != 4 {
+		t.Errorf("got %d lists, want 4", reader.lists)
+	}
+
+	gotCoverage := cov.status()
+	wantCoverage := &statusv1beta1.AuditCoverage{
+		KindsDiscovered:    3,
+		KindsAudited:       2,
+		UnauditedKinds:     []string{"/v1, Kind=Broken"},
+		FailedKinds:        []string{"/v1, Kind=Broken"},
+		ExcludedNamespaces: []string{"kube-system"},
+		ObjectsExcluded:    1,
+	}
+	if diff := cmp.Diff(wantCoverage, gotCoverage); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/review"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MaxInFlightAnnotation is set on a ConstraintTemplate to the number of objects
+// its constraints match which audit may review at once, so that an expensive
+// template, such as one reading the whole inventory, cannot occupy every audit
+// worker.
+const MaxInFlightAnnotation = "audit.gatekeeper.sh/max-in-flight"
+
+var auditWorkers = flag.Int("audit-workers", 1, "(alpha) number of objects audit reviews at once. ConstraintTemplates may limit how many of them are matched by their constraints with the "+MaxInFlightAnnotation+" annotation")
+
+// templateLimiter bounds the objects reviewed at once which are matched by
+// the constraints of each template with the MaxInFlightAnnotation.
+//
+// Every object is reviewed against all templates at once, so an object
+// matched by a limited template holds one of its slots while it is reviewed
+// against every template. Objects which no limited template matches are
+// reviewed by whichever workers are free.
+type templateLimiter struct {
+	// limits are sorted by template name, the order their slots are acquired
+	// in so that workers cannot deadlock.
+	limits []*templateLimit
+}
+
+var _ review.Limiter = &templateLimiter{}
+
+// templateLimit holds the slots of a template.
+type templateLimit struct {
+	template string
+	slots    chan struct{}
+	// kinds are the kinds matched by the template's constraints, with "*"
+	// for any group or kind. Every kind is matched if matchAll is set.
+	kinds    map[schema.GroupKind]bool
+	matchAll bool
+}
+
+// newTemplateLimit returns the limit of template to max objects matched by
+// constraints at once.
+func newTemplateLimit(template string, max int, constraints []unstructured.Unstructured) *templateLimit {
+	l := &templateLimit{
+		template: template,
+		slots:    make(chan struct{}, max),
+		kinds:    make(map[schema.GroupKind]bool),
+	}
+	for i := range constraints {
+		kinds, found, err := unstructured.NestedSlice(constraints[i].Object, "spec", "match", "kinds")
+		if err != nil || !found {
+			// Constraints without kinds match every kind.
+			l.matchAll = true
+			return l
+		}
+		for _, k := range kinds {
+			selector, ok := k.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			groups, _, _ := unstructured.NestedStringSlice(selector, "apiGroups")
+			names, _, _ := unstructured.NestedStringSlice(selector, "kinds")
+			for _, group := range groups {
+				for _, name := range names {
+					l.kinds[schema.GroupKind{Group: group, Kind: name}] = true
+				}
+			}
+		}
+	}
+	return l
+}
+
+func (l *templateLimit) matches(gk schema.GroupKind) bool {
+	return l.matchAll ||
+		l.kinds[gk] ||
+		l.kinds[schema.GroupKind{Group: "*", Kind: gk.Kind}] ||
+		l.kinds[schema.GroupKind{Group: gk.Group, Kind: "*"}] ||
+		l.kinds[schema.GroupKind{Group: "*", Kind: "*"}]
+}
+
+// Acquire takes a slot of every limited template matching obj.
+func (t *templateLimiter) Acquire(ctx context.Context, obj interface{}) (func(), error) {
+	augmented, ok := obj.(target.AugmentedUnstructured)
+	if !ok {
+		return func() {}, nil
+	}
+	gk := augmented.Object.GroupVersionKind().GroupKind()
+
+	var held []*templateLimit
+	release := func() {
+		for _, l := range held {
+			<-l.slots
+		}
+	}
+	for _, l := range t.limits {
+		if !l.matches(gk) {
+			continue
+		}
+		select {
+		case l.slots <- struct{}{}:
+			held = append(held, l)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// maxInFlight returns the limit set by the MaxInFlightAnnotation of ct, or
+// false if it has none.
+func maxInFlight(ct *v1beta1.ConstraintTemplate) (int, bool, error) {
+	value, ok := ct.GetAnnotations()[MaxInFlightAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+	max, err := strconv.Atoi(value)
+	if err != nil || max < 1 {
+		return 0, false, fmt.Errorf("%s must be a positive integer, got %q", MaxInFlightAnnotation, value)
+	}
+	return max, true, nil
+}
+
+// newTemplateLimiter returns the limiter of the templates with the
+// MaxInFlightAnnotation, or nil if there are none.
+func (am *Manager) newTemplateLimiter(ctx context.Context) *templateLimiter {
+	templateList := &v1beta1.ConstraintTemplateList{}
+	if err := am.client.List(ctx, templateList); err != nil {
+		am.log.Error(err, "unable to list templates to limit the objects they review at once")
+		return nil
+	}
+
+	limiter := &templateLimiter{}
+	for i := range templateList.Items {
+		ct := &templateList.Items[i]
+		max, ok, err := maxInFlight(ct)
+		if err != nil {
+			am.log.Error(err, "ignoring invalid annotation", "template", ct.GetName())
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		constraints := &unstructured.UnstructuredList{}
+		constraints.SetAPIVersion(constraintsGV)
+		constraints.SetKind(ct.Spec.CRD.Spec.Names.Kind + "List")
+		if err := am.client.List(ctx, constraints); err != nil {
+			am.log.Error(err, "unable to list constraints to limit the objects their template reviews at once", "template", ct.GetName())
+			continue
+		}
+		limiter.limits = append(limiter.limits, newTemplateLimit(ct.GetName(), max, constraints.Items))
+	}
+	if len(limiter.limits) == 0 {
+		return nil
+	}
+	sort.Slice(limiter.limits, func(i, j int) bool {
+		return limiter.limits[i].template < limiter.limits[j].template
+	})
+	return limiter
+}
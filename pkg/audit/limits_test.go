@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+)
+
+func limitedConstraint(kinds ...interface{}) unstructured.Unstructured {
+	u := unstructured.Unstructured{Object: map[string]interface{}{}}
+	if kinds != nil {
+		_ = unstructured.SetNestedSlice(u.Object, kinds, "spec", "match", "kinds")
+	}
+	return u
+}
+
+func kindSelector(groups, kinds []interface{}) interface{} {
+	return map[string]interface{}{"apiGroups": groups, "kinds": kinds}
+}
+
+func TestTemplateLimitMatches(t *testing.T) {
+	pod := schema.GroupKind{Kind: "Pod"}
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	ingress := schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}
+
+	testCases := []struct {
+		name        string
+		constraints []unstructured.Unstructured
+		want        map[schema.GroupKind]bool
+	}{
+		{
+			name: "no constraints",
+			want: map[schema.GroupKind]bool{pod: false, deployment: false},
+		},
+		{
+			name:        "constraint without kinds",
+			constraints: []unstructured.Unstructured{limitedConstraint()},
+			want:        map[schema.GroupKind]bool{pod: true, deployment: true},
+		},
+		{
+			name: "kinds of every constraint",
+			constraints: []unstructured.Unstructured{
+				limitedConstraint(kindSelector([]interface{}{""}, []interface{}{"Pod"})),
+				limitedConstraint(kindSelector([]interface{}{"apps"}, []interface{}{"Deployment"})),
+			},
+			want: map[schema.GroupKind]bool{pod: true, deployment: true, ingress: false},
+		},
+		{
+			name: "any group",
+			constraints: []unstructured.Unstructured{
+				limitedConstraint(kindSelector([]interface{}{"*"}, []interface{}{"Ingress"})),
+			},
+			want: map[schema.GroupKind]bool{pod: false, ingress: true},
+		},
+		{
+			name: "any kind",
+			constraints: []unstructured.Unstructured{
+				limitedConstraint(kindSelector([]interface{}{"apps"}, []interface{}{"*"})),
+			},
+			want: map[schema.GroupKind]bool{pod: false, deployment: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newTemplateLimit("template", 1, tc.constraints)
+			for gk, want := range tc.want {
+				if got := l.matches(gk); got != want {
+					t.Errorf("got matches(%v) = %t, want %t", gk, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateLimiterAcquire(t *testing.T) {
+	limiter := &templateLimiter{limits: []*templateLimit{
+		newTemplateLimit("pods", 1, []unstructured.Unstructured{
+			limitedConstraint(kindSelector([]interface{}{""}, []interface{}{"Pod"})),
+		}),
+	}}
+	object := func(kind string) target.AugmentedUnstructured {
+		u := unstructured.Unstructured{}
+		u.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: kind})
+		return target.AugmentedUnstructured{Object: u}
+	}
+
+	ctx := context.Background()
+	release, err := limiter.Acquire(ctx, object("Pod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects the template does not match are not limited.
+	releaseNamespace, err := limiter.Acquire(ctx, object("Namespace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseNamespace()
+
+	// A second pod waits for the first to be reviewed.
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(timeout, object("Pod")); err == nil {
+		t.Fatal("got a second pod reviewed at once, want it to wait")
+	}
+
+	release()
+	releasePod, err := limiter.Acquire(ctx, object("Pod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	releasePod()
+}
+
+func TestMaxInFlight(t *testing.T) {
+	testCases := []struct {
+		name    string
+		value   *string
+		want    int
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "limit", value: pointer.StringPtr("2"), want: 2, wantOK: true},
+		{name: "zero", value: pointer.StringPtr("0"), wantErr: true},
+		{name: "not a number", value: pointer.StringPtr("many"), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ct := &v1beta1.ConstraintTemplate{}
+			if tc.value != nil {
+				ct.SetAnnotations(map[string]string{MaxInFlightAnnotation: *tc.value})
+			}
+			got, ok, err := maxInFlight(ct)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tc.wantErr)
+			}
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("got %d, %t, want %d, %t", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
@@ -7,15 +7,29 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	constraintTypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/keys"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
-	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"github.com/open-policy-agent/gatekeeper/pkg/override"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/gatekeeper/pkg/remediation"
+	"github.com/open-policy-agent/gatekeeper/pkg/review"
+	"github.com/open-policy-agent/gatekeeper/pkg/schedule"
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
+	"github.com/open-policy-agent/gatekeeper/pkg/shutdown"
+	"github.com/open-policy-agent/gatekeeper/pkg/suppression"
+	"github.com/open-policy-agent/gatekeeper/pkg/ticketing"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -41,6 +55,7 @@ const (
 	crdName                          = "constrainttemplates.templates.gatekeeper.sh"
 	constraintsGV                    = "constraints.gatekeeper.sh/v1beta1"
 	msgSize                          = 256
+	remediationSize                  = 1024
 	defaultAuditInterval             = 60
 	defaultConstraintViolationsLimit = 20
 	defaultListLimit                 = 0
@@ -52,6 +67,10 @@ var (
 	auditChunkSize            = flag.Uint64("audit-chunk-size", defaultListLimit, "(alpha) Kubernetes API chunking List results when retrieving cluster resources using discovery client. defaulted to 0 if unspecified")
 	auditFromCache            = flag.Bool("audit-from-cache", false, "pull resources from OPA cache when auditing")
 	emitAuditEvents           = flag.Bool("emit-audit-events", false, "(alpha) emit Kubernetes events in gatekeeper namespace with detailed info for each violation from an audit")
+	auditEventsInvolvedNs     = flag.Bool("audit-events-involved-namespace", false, "(alpha) emit audit events for each violation in the namespace of the violating object and attach them to it, so they show up in `kubectl describe`. Events for cluster-scoped resources are still emitted in the gatekeeper namespace. Requires --emit-audit-events")
+	auditEventsQPS            = flag.Float64("audit-events-qps", 1.0/300, "(alpha) steady-state rate of audit events per violating object, events above this rate are dropped. Only used with --audit-events-involved-namespace")
+	auditEventsBurst          = flag.Int("audit-events-burst", 25, "(alpha) number of audit events per violating object which may be emitted before --audit-events-qps is enforced. Only used with --audit-events-involved-namespace")
+	auditComplianceReports    = flag.Bool("audit-compliance-reports", false, "(alpha) publish a ComplianceReport named \"gatekeeper\" in each namespace with violations, summarizing the violations found by audit in it")
 	auditMatchKindOnly        = flag.Bool("audit-match-kind-only", false, "only use kinds specified in all constraints for auditing cluster resources. if kind is not specified in any of the constraints, it will audit all resources (same as setting this flag to false)")
 	emptyAuditResults         []auditResult
 )
@@ -67,8 +86,41 @@ type Manager struct {
 	reporter        *reporter
 	log             logr.Logger
 	processExcluder *process.Excluder
+	watchManager    *watch.Manager
+	statusCtx       context.Context
 	eventRecorder   record.EventRecorder
 	gkNamespace     string
+	// emittedEvents deduplicates violation events within a single audit run,
+	// as the same object may be listed under more than one API group.
+	emittedEvents map[string]bool
+	// exemptions holds the violations exempted by each Exception during a
+	// single audit run, keyed by the name of the Exception.
+	exemptions map[string]*exemptions
+	// suppressed holds the violations suppressed by each Suppression during a
+	// single audit run, keyed by the name of the Suppression.
+	suppressed map[string]*suppressions
+	// downgraded counts the violations downgraded to warn by each
+	// NamespacePolicyOverride during a single audit run.
+	downgraded map[types.NamespacedName]int64
+	// tickets files tickets for persistent violations, if set.
+	tickets *ticketing.Notifier
+	// violations holds the violations found by the current audit, and
+	// previousViolations those of the last complete audit, or nil if no audit
+	// has completed since the pod started.
+	violations         violationSet
+	previousViolations violationSet
+	// severities tallies the violations found by the current audit by
+	// severity, and previousSeverities those of the last complete audit.
+	severities         *severities
+	previousSeverities *severities
+	// compliance tallies the violations found by the current audit in each
+	// namespace, if --audit-compliance-reports is set.
+	compliance *compliance
+	// queryStats totals the statistics of the queries of the current audit,
+	// if --query-stats is set.
+	queryStats querystats.Stats
+	// coverage tallies what the current audit evaluated.
+	coverage *coverage
 }
 
 type auditResult struct {
@@ -76,12 +128,19 @@ type auditResult struct {
 	cnamespace        string
 	cgvk              schema.GroupVersionKind
 	capiversion       string
+	rgroup            string
 	rkind             string
 	rname             string
 	rnamespace        string
 	message           string
 	enforcementAction string
+	severity          severity.Level
 	constraint        *unstructured.Unstructured
+	remediation       remediation.Patch
+	// violationID and ticket are only set when tickets are filed for
+	// persistent violations.
+	violationID string
+	ticket      string
 }
 
 // StatusViolation represents each violation under status.
@@ -91,6 +150,12 @@ type StatusViolation struct {
 	Namespace         string `json:"namespace,omitempty"`
 	Message           string `json:"message"`
 	EnforcementAction string `json:"enforcementAction"`
+	Severity          string `json:"severity,omitempty"`
+	ID                string `json:"id,omitempty"`
+	Ticket            string `json:"ticket,omitempty"`
+	// Remediation is the JSON patch the template returned to fix the
+	// violation, if any.
+	Remediation remediation.Patch `json:"remediation,omitempty"`
 }
 
 // nsCache is used for caching namespaces and their labels.
@@ -115,14 +180,24 @@ func (c *nsCache) Get(ctx context.Context, client client.Client, namespace strin
 	return c.cache[namespace], nil
 }
 
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
 // New creates a new manager for audit.
-func New(mgr manager.Manager, opa *opa.Client, processExcluder *process.Excluder) (*Manager, error) {
+func New(mgr manager.Manager, opa *opa.Client, processExcluder *process.Excluder, wm *watch.Manager) (*Manager, error) {
 	reporter, err := newStatsReporter()
 	if err != nil {
 		log.Error(err, "StatsReporter could not start")
 		return nil, err
 	}
 	eventBroadcaster := record.NewBroadcaster()
+	if *auditEventsInvolvedNs {
+		// Events are attached to the violating objects, so the spam filter keyed
+		// on the involved object rate-limits each object individually.
+		eventBroadcaster = record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+			QPS:       float32(*auditEventsQPS),
+			BurstSize: *auditEventsBurst,
+		})
+	}
 	kubeClient := kubernetes.NewForConfigOrDie(mgr.GetConfig())
 	eventBroadcaster.StartRecordingToSink(&clientcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
 	recorder := eventBroadcaster.NewRecorder(
@@ -136,20 +211,82 @@ func New(mgr manager.Manager, opa *opa.Client, processExcluder *process.Excluder
 		mgr:             mgr,
 		reporter:        reporter,
 		processExcluder: processExcluder,
+		watchManager:    wm,
 		eventRecorder:   recorder,
 		gkNamespace:     util.GetNamespace(),
+		tickets:         ticketing.New(ticketing.NewConfigMapStore(mgr.GetClient(), mgr.GetAPIReader(), util.GetNamespace())),
 	}
 	return am, nil
 }
 
+// runTimes is when audits last started and finished, and what the last
+// complete audit evaluated.
+type runTimes struct {
+	mux      sync.RWMutex
+	start    time.Time
+	end      time.Time
+	coverage *v1beta1.AuditCoverage
+}
+
+var lastRun = &runTimes{}
+
+func (r *runTimes) started(t time.Time) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.start = t
+}
+
+func (r *runTimes) finished(t time.Time) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.end = t
+}
+
+func (r *runTimes) covered(c *v1beta1.AuditCoverage) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.coverage = c
+}
+
+// LastCoverage returns what the most recent complete audit evaluated, or nil
+// if this process has not completed an audit.
+func LastCoverage() *v1beta1.AuditCoverage {
+	lastRun.mux.RLock()
+	defer lastRun.mux.RUnlock()
+	return lastRun.coverage.DeepCopy()
+}
+
+// LastRun returns when the most recent audit started, and when the most recent
+// audit to complete finished. end is before start while an audit is running.
+// Both are zero if this process has not audited.
+func LastRun() (start, end time.Time) {
+	lastRun.mux.RLock()
+	defer lastRun.mux.RUnlock()
+	return lastRun.start, lastRun.end
+}
+
 // audit performs an audit then updates the status of all constraint resources with the results.
 func (am *Manager) audit(ctx context.Context) error {
 	startTime := time.Now()
 	timestamp := startTime.UTC().Format(time.RFC3339)
 	am.log = log.WithValues(logging.AuditID, timestamp)
+	am.emittedEvents = make(map[string]bool)
+	am.exemptions = make(map[string]*exemptions)
+	am.suppressed = make(map[string]*suppressions)
+	am.downgraded = make(map[types.NamespacedName]int64)
+	am.violations = make(violationSet)
+	am.severities = newSeverities()
+	am.compliance = nil
+	if *auditComplianceReports {
+		am.compliance = newCompliance()
+	}
+	am.queryStats = querystats.Stats{}
+	am.coverage = newCoverage(*querystats.Enabled)
 	logStart(am.log)
+	lastRun.started(startTime)
 	// record audit latency
 	defer func() {
+		lastRun.finished(time.Now())
 		logFinish(am.log)
 		latency := time.Since(startTime)
 		if err := am.reporter.reportLatency(latency); err != nil {
@@ -181,9 +318,6 @@ func (am *Manager) audit(ctx context.Context) error {
 		return nil
 	}
 
-	var resp *constraintTypes.Responses
-	var res []*constraintTypes.Result
-
 	updateLists := make(map[util.KindVersionResource][]auditResult)
 	totalViolationsPerConstraint := make(map[util.KindVersionResource]int64)
 	totalViolationsPerEnforcementAction := make(map[util.EnforcementAction]int64)
@@ -192,16 +326,11 @@ func (am *Manager) audit(ctx context.Context) error {
 		totalViolationsPerEnforcementAction[action] = 0
 	}
 
+	am.checkInventory(ctx)
+
 	if *auditFromCache {
 		am.log.Info("Auditing from cache")
-		resp, err = am.opa.Audit(ctx)
-		if err != nil {
-			return err
-		}
-		res = resp.Results()
-		am.log.Info("Audit opa.Audit() results", "violations", len(res))
-
-		err := am.addAuditResponsesToUpdateLists(updateLists, res, totalViolationsPerConstraint, totalViolationsPerEnforcementAction, timestamp)
+		err := am.auditCache(ctx, updateLists, totalViolationsPerConstraint, totalViolationsPerEnforcementAction, timestamp)
 		if err != nil {
 			return err
 		}
@@ -216,7 +345,11 @@ func (am *Manager) audit(ctx context.Context) error {
 	// log constraints with violations
 	for link := range updateLists {
 		ar := updateLists[link][0]
-		logConstraint(am.log, ar.constraint, ar.enforcementAction, totalViolationsPerConstraint[link])
+		logConstraint(am.log, ar.constraint, ar.enforcementAction, ar.severity, totalViolationsPerConstraint[link])
+	}
+
+	if *querystats.Enabled {
+		logQueryStats(am.log, am.queryStats)
 	}
 
 	for k, v := range totalViolationsPerEnforcementAction {
@@ -225,8 +358,42 @@ func (am *Manager) audit(ctx context.Context) error {
 		}
 	}
 
+	// An audit interrupted by shutdown has partial results, which must not
+	// replace those of the last complete audit.
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	am.reportCoverage()
+
+	if am.tickets != nil {
+		am.fileTickets(ctx, updateLists, timestamp)
+	}
+
+	// Changes since the previous audit are only known once an audit has
+	// completed.
+	var deltas map[util.KindVersionResource]violationDelta
+	if am.previousViolations != nil {
+		deltas = diffViolations(am.previousViolations, am.violations)
+		am.reportDeltas(deltas)
+	}
+	am.previousViolations = am.violations
+	am.reportSeverities()
+
 	// update constraints for each kind
-	am.writeAuditResults(ctx, constraintsGVKs, updateLists, timestamp, totalViolationsPerConstraint)
+	am.writeAuditResults(am.statusCtx, constraintsGVKs, updateLists, timestamp, totalViolationsPerConstraint, deltas)
+	if *exception.Enabled {
+		am.writeExceptionStatuses(am.statusCtx, timestamp)
+	}
+	if *suppression.Enabled {
+		am.writeSuppressionStatuses(am.statusCtx, timestamp)
+	}
+	if *override.Enabled {
+		am.writeOverrideStatuses(am.statusCtx, timestamp)
+	}
+	if am.compliance != nil {
+		am.writeComplianceReports(am.statusCtx, timestamp)
+	}
 
 	return nil
 }
@@ -278,9 +445,6 @@ func (am *Manager) auditResources(
 		}
 	}
 
-	var errs opa.Errors
-	nsCache := newNSCache()
-
 	matchedKinds := make(map[string]bool)
 	if *auditMatchKindOnly {
 		constraintList := &unstructured.UnstructuredList{}
@@ -332,82 +496,126 @@ func (am *Manager) auditResources(
 		matchedKinds["*"] = true
 	}
 
+	var discovered, kinds []schema.GroupVersionKind
 	for gv, gvKinds := range clusterAPIResources {
-	kindsLoop:
 		for kind := range gvKinds {
+			discovered = append(discovered, schema.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: kind})
 			_, matchAll := matchedKinds["*"]
 			if _, found := matchedKinds[kind]; !found && !matchAll {
 				continue
 			}
+			kinds = append(kinds, schema.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: kind})
+		}
+	}
 
-			objList := &unstructured.UnstructuredList{}
-			opts := &client.ListOptions{
-				Limit: int64(*auditChunkSize),
-			}
-			resourceVersion := ""
-
-			for {
-				objList.SetGroupVersionKind(schema.GroupVersionKind{
-					Group:   gv.Group,
-					Version: gv.Version,
-					Kind:    kind + "List",
-				})
-				objList.SetResourceVersion(resourceVersion)
+	am.coverage.discover(discovered)
 
-				err := am.client.List(ctx, objList, opts)
-				if err != nil {
-					am.log.Error(err, "Unable to list objects for gvk", "group", gv.Group, "version", gv.Version, "kind", kind)
-					continue kindsLoop
-				}
+	objects := am.newObjectIterator(am.client, kinds, int64(*auditChunkSize), process.Audit)
+	return am.reviewObjects(ctx, objects, updateLists, totalViolationsPerConstraint, totalViolationsPerEnforcementAction, timestamp)
+}
 
-				for index := range objList.Items {
-					objNamespace := objList.Items[index].GetNamespace()
-					isExcludedNamespace, err := am.skipExcludedNamespace(&objList.Items[index])
-					if err != nil {
-						log.Error(err, "error while excluding namespaces")
-					}
+// auditCache audits the objects replicated into the constraint framework's
+// cache, reading them from the informers which replicate them.
+func (am *Manager) auditCache(
+	ctx context.Context,
+	updateLists map[util.KindVersionResource][]auditResult,
+	totalViolationsPerConstraint map[util.KindVersionResource]int64,
+	totalViolationsPerEnforcementAction map[util.EnforcementAction]int64,
+	timestamp string) error {
+	kinds, err := am.syncedKinds(ctx)
+	if err != nil {
+		return err
+	}
 
-					if isExcludedNamespace {
-						continue
-					}
+	am.coverage.discover(kinds)
 
-					ns := corev1.Namespace{}
-					if objNamespace != "" {
-						ns, err = nsCache.Get(ctx, am.client, objNamespace)
-						if err != nil {
-							am.log.Error(err, "Unable to look up object namespace", "group", gv.Group, "version", gv.Version, "kind", kind)
-							continue
-						}
-					}
+	// Objects excluded from sync were never replicated, so are not audited.
+	objects := am.newObjectIterator(am.mgr.GetCache(), kinds, 0, process.Sync, process.Audit)
+	return am.reviewObjects(ctx, objects, updateLists, totalViolationsPerConstraint, totalViolationsPerEnforcementAction, timestamp)
+}
 
-					augmentedObj := target.AugmentedUnstructured{
-						Object:    objList.Items[index],
-						Namespace: &ns,
-					}
-					resp, err := am.opa.Review(ctx, augmentedObj)
-					if err != nil {
-						errs = append(errs, err)
-					} else if len(resp.Results()) > 0 {
-						err = am.addAuditResponsesToUpdateLists(updateLists, resp.Results(), totalViolationsPerConstraint, totalViolationsPerEnforcementAction, timestamp)
-						if err != nil {
-							return err
-						}
-					}
-				}
+// syncedKinds returns the kinds the Config replicates which are currently
+// watched. Kinds without an informer are skipped rather than listed, as
+// listing them from the cache would start an informer which nothing stops.
+func (am *Manager) syncedKinds(ctx context.Context) ([]schema.GroupVersionKind, error) {
+	cfg := &configv1alpha1.Config{}
+	if err := am.client.Get(ctx, keys.Config, cfg); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
 
-				resourceVersion = objList.GetResourceVersion()
-				opts.Continue = objList.GetContinue()
-				if opts.Continue == "" {
-					break
-				}
-			}
+	watched := make(map[schema.GroupVersionKind]bool)
+	for _, gvk := range am.watchManager.GetManagedGVK() {
+		watched[gvk] = true
+	}
+	var kinds []schema.GroupVersionKind
+	for _, entry := range cfg.Spec.Sync.SyncOnly {
+		gvk := schema.GroupVersionKind{Group: entry.Group, Version: entry.Version, Kind: entry.Kind}
+		if !watched[gvk] {
+			am.log.Info("Skipping kind which is not yet synced", "group", gvk.Group, "version", gvk.Version, "kind", gvk.Kind)
+			continue
 		}
+		kinds = append(kinds, gvk)
 	}
+	return kinds, nil
+}
 
-	if len(errs) > 0 {
-		return errs
+func (am *Manager) newObjectIterator(reader client.Reader, kinds []schema.GroupVersionKind, limit int64, excluded ...process.Process) *objectIterator {
+	return &objectIterator{
+		reader:          reader,
+		kinds:           kinds,
+		limit:           limit,
+		excluded:        excluded,
+		processExcluder: am.processExcluder,
+		namespaces:      newNSCache(),
+		nsClient:        am.client,
+		log:             am.log,
+		coverage:        am.coverage,
 	}
-	return nil
+}
+
+// reviewObjects reviews each object yielded by objects, up to --audit-workers
+// at once, adding its violations to updateLists as it goes. Only the results
+// for a single object per worker are held at once, so memory does not grow
+// with the number of violations.
+func (am *Manager) reviewObjects(
+	ctx context.Context,
+	objects review.Iterator,
+	updateLists map[util.KindVersionResource][]auditResult,
+	totalViolationsPerConstraint map[util.KindVersionResource]int64,
+	totalViolationsPerEnforcementAction map[util.EnforcementAction]int64,
+	timestamp string) error {
+	var limiter review.Limiter
+	if l := am.newTemplateLimiter(ctx); l != nil {
+		limiter = l
+	}
+	return review.StreamParallel(ctx, am.opa, objects, *auditWorkers, limiter, func(r review.Result) error {
+		am.queryStats.Add(r.Stats)
+		am.coverage.review(r.Stats.ConstraintsMatched)
+		am.reportTemplateLatency(r.Constraints)
+		if len(r.Results) == 0 {
+			return nil
+		}
+		results := r.Results
+		if *schedule.Enabled {
+			results, _ = schedule.Get().Filter(results)
+		}
+		if *exception.Enabled {
+			results = am.exempt(r.Object, results)
+		}
+		if *override.Enabled {
+			am.downgrade(results)
+		}
+		if *suppression.Enabled {
+			// Suppressed after downgrading, so that the suppressed violations
+			// record the enforcement action they would have been reported
+			// with.
+			results = am.suppress(r.Object, results)
+		}
+		return am.addAuditResponsesToUpdateLists(updateLists, results, totalViolationsPerConstraint, totalViolationsPerEnforcementAction, timestamp)
+	})
 }
 
 func (am *Manager) auditManagerLoop(ctx context.Context) {
@@ -430,12 +638,32 @@ func (am *Manager) auditManagerLoop(ctx context.Context) {
 // Start implements controller.Controller.
 func (am *Manager) Start(ctx context.Context) error {
 	log.Info("Starting Audit Manager")
+	// Status updates outlive ctx, so that the results of the last audit are
+	// still written during shutdown.
+	statusCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	am.statusCtx = statusCtx
 	go am.auditManagerLoop(ctx)
 	<-ctx.Done()
 	log.Info("Stopping audit manager workers")
+	<-am.stopper
+	am.waitForStatusUpdate(*shutdown.GracePeriod)
 	return nil
 }
 
+// waitForStatusUpdate waits up to timeout for the status of the last audit to
+// be written. It must only be called once the audit loop has stopped.
+func (am *Manager) waitForStatusUpdate(timeout time.Duration) {
+	if am.ucloop == nil {
+		return
+	}
+	select {
+	case <-am.ucloop.stopped:
+	case <-time.After(timeout):
+		am.log.Info("timeout waiting for audit status update to finish")
+	}
+}
+
 func (am *Manager) ensureCRDExists(ctx context.Context) error {
 	crd := &apiextensionsv1.CustomResourceDefinition{}
 	return am.client.Get(ctx, types.NamespacedName{Name: crdName}, crd)
@@ -485,14 +713,29 @@ func (am *Manager) addAuditResponsesToUpdateLists(
 		if !ok {
 			return errors.Errorf("could not cast resource as reviewResource: %v", r.Resource)
 		}
+		if am.violations != nil {
+			am.violations.add(r.Constraint, resource)
+		}
+		if am.severities != nil {
+			am.severities.add(r.Constraint, resource)
+		}
+		if am.compliance != nil {
+			am.compliance.add(r.Constraint, resource)
+		}
+		level, _ := severity.Of(r.Constraint)
 		rname := resource.GetName()
 		rkind := resource.GetKind()
 		rnamespace := resource.GetNamespace()
 		// append audit results only if it is below violations limit
 		if uint(len(updateLists[key])) < *constraintViolationsLimit {
+			patch, err := remediation.Get(r)
+			if err != nil {
+				am.log.Error(err, "ignoring invalid remediation", logging.ConstraintName, name, logging.ConstraintKind, gvk.Kind)
+			}
 			result := auditResult{
 				cgvk:              gvk,
 				capiversion:       apiVersion,
+				rgroup:            resource.GroupVersionKind().Group,
 				cname:             name,
 				cnamespace:        namespace,
 				rkind:             rkind,
@@ -500,21 +743,51 @@ func (am *Manager) addAuditResponsesToUpdateLists(
 				rnamespace:        rnamespace,
 				message:           message,
 				enforcementAction: enforcementAction,
+				severity:          level,
 				constraint:        r.Constraint,
+				remediation:       patch,
 			}
 			updateLists[key] = append(updateLists[key], result)
 		}
 		ea := util.EnforcementAction(enforcementAction)
 		totalViolationsPerEnforcementAction[ea]++
-		logViolation(am.log, r.Constraint, r.EnforcementAction, resource.GroupVersionKind(), rnamespace, rname, message, details)
-		if *emitAuditEvents {
-			emitEvent(r.Constraint, timestamp, enforcementAction, resource.GroupVersionKind(), rnamespace, rname, message, am.gkNamespace, am.eventRecorder)
+		logViolation(am.log, r.Constraint, r.EnforcementAction, level, resource.GroupVersionKind(), rnamespace, rname, message, details)
+		if *emitAuditEvents && am.firstEventForViolation(resource, r.Constraint) {
+			emitEvent(r.Constraint, timestamp, enforcementAction, level, resource.GroupVersionKind(), rnamespace, rname, string(resource.GetUID()), message, am.gkNamespace, am.eventRecorder)
 		}
 	}
 	return nil
 }
 
-func (am *Manager) writeAuditResults(ctx context.Context, constraintsGVKs []schema.GroupVersionKind, updateLists map[util.KindVersionResource][]auditResult, timestamp string, totalViolations map[util.KindVersionResource]int64) {
+// reportTemplateLatency records the time spent evaluating each constraint
+// matching an object, whether or not the object violates it.
+func (am *Manager) reportTemplateLatency(constraints []querystats.ConstraintDuration) {
+	for _, c := range constraints {
+		if err := am.reporter.reportTemplateLatency(c.Kind, c.Name, c.Duration); err != nil {
+			am.log.Error(err, "failed to report template latency")
+		}
+	}
+}
+
+// firstEventForViolation returns true if no event has been emitted yet for the
+// violation of constraint by resource during the current audit run.
+func (am *Manager) firstEventForViolation(resource, constraint *unstructured.Unstructured) bool {
+	if am.emittedEvents == nil {
+		am.emittedEvents = make(map[string]bool)
+	}
+	resourceKey := string(resource.GetUID())
+	if resourceKey == "" {
+		resourceKey = resource.GetNamespace() + "/" + resource.GetName()
+	}
+	key := resource.GroupVersionKind().Kind + "/" + resourceKey + "/" + constraint.GetKind() + "/" + constraint.GetName()
+	if am.emittedEvents[key] {
+		return false
+	}
+	am.emittedEvents[key] = true
+	return true
+}
+
+func (am *Manager) writeAuditResults(ctx context.Context, constraintsGVKs []schema.GroupVersionKind, updateLists map[util.KindVersionResource][]auditResult, timestamp string, totalViolations map[util.KindVersionResource]int64, deltas map[util.KindVersionResource]violationDelta) {
 	// if there is a previous reporting thread, close it before starting a new one
 	if am.ucloop != nil {
 		// this is closing the previous audit reporting thread
@@ -536,7 +809,9 @@ func (am *Manager) writeAuditResults(ctx context.Context, constraintsGVKs []sche
 		ul:      updateLists,
 		ts:      timestamp,
 		tv:      totalViolations,
+		deltas:  deltas,
 		log:     am.log,
+		tickets: am.tickets,
 	}
 
 	go am.ucloop.update(ctx, constraintsGVKs)
@@ -554,22 +829,38 @@ func (am *Manager) skipExcludedNamespace(obj *unstructured.Unstructured) (bool,
 func (ucloop *updateConstraintLoop) updateConstraintStatus(ctx context.Context, instance *unstructured.Unstructured, auditResults []auditResult, timestamp string, totalViolations int64) error {
 	constraintName := instance.GetName()
 	ucloop.log.Info("updating constraint status", "constraintName", constraintName)
+	tickets := ucloop.previousTickets(instance)
 	// create constraint status violations
 	var statusViolations []interface{}
 	for i := range auditResults {
 		ar := &auditResults[i] // avoid large shallow copy in range loop
+		if ar.violationID != "" && ar.ticket == "" {
+			if ticket, ok := tickets[ar.violationID]; ok {
+				ar.ticket = ticket
+				ucloop.tickets.Record(ar.violationID, ticket)
+			}
+		}
 		// append statusViolations for this constraint until constraintViolationsLimit has reached
 		if uint(len(statusViolations)) < *constraintViolationsLimit {
 			msg := ar.message
 			if len(msg) > msgSize {
 				msg = truncateString(msg, msgSize)
 			}
+			patch := ar.remediation
+			if len(patch.String()) > remediationSize {
+				// Unlike messages, patches cannot be truncated.
+				patch = nil
+			}
 			statusViolations = append(statusViolations, StatusViolation{
 				Kind:              ar.rkind,
 				Name:              ar.rname,
 				Namespace:         ar.rnamespace,
 				Message:           msg,
 				EnforcementAction: ar.enforcementAction,
+				Severity:          string(ar.severity),
+				ID:                ar.violationID,
+				Ticket:            ar.ticket,
+				Remediation:       patch,
 			})
 		}
 	}
@@ -591,6 +882,21 @@ func (ucloop *updateConstraintLoop) updateConstraintStatus(ctx context.Context,
 	if err = unstructured.SetNestedField(instance.Object, totalViolations, "status", "totalViolations"); err != nil {
 		return err
 	}
+	// update constraint status auditDelta, or remove that of a previous pod
+	// if changes are not known
+	if ucloop.deltas != nil {
+		d := ucloop.deltas[util.GetUniqueKey(*instance)]
+		delta := map[string]interface{}{
+			"newViolations":       d.New,
+			"resolvedViolations":  d.Resolved,
+			"unchangedViolations": d.Unchanged,
+		}
+		if err = unstructured.SetNestedMap(instance.Object, delta, "status", "auditDelta"); err != nil {
+			return err
+		}
+	} else {
+		unstructured.RemoveNestedField(instance.Object, "status", "auditDelta")
+	}
 	// update constraint status violations
 	if len(violations) == 0 {
 		_, found, err := unstructured.NestedSlice(instance.Object, "status", "violations")
@@ -638,7 +944,11 @@ type updateConstraintLoop struct {
 	ul      map[util.KindVersionResource][]auditResult
 	ts      string
 	tv      map[util.KindVersionResource]int64
+	// deltas are the changes to the violations of each constraint since the
+	// previous audit, or nil if they are not known.
+	deltas  map[util.KindVersionResource]violationDelta
 	log     logr.Logger
+	tickets *ticketing.Notifier
 }
 
 func (ucloop *updateConstraintLoop) update(ctx context.Context, constraintsGVKs []schema.GroupVersionKind) {
@@ -752,7 +1062,14 @@ func logFinish(l logr.Logger) {
 	)
 }
 
-func logConstraint(l logr.Logger, constraint *unstructured.Unstructured, enforcementAction string, totalViolations int64) {
+func logQueryStats(l logr.Logger, stats querystats.Stats) {
+	l.Info(
+		"audit query stats",
+		append([]interface{}{logging.EventType, "audit_query_stats"}, stats.KeysAndValues()...)...,
+	)
+}
+
+func logConstraint(l logr.Logger, constraint *unstructured.Unstructured, enforcementAction string, level severity.Level, totalViolations int64) {
 	l.Info(
 		"audit results for constraint",
 		logging.EventType, "constraint_audited",
@@ -762,6 +1079,7 @@ func logConstraint(l logr.Logger, constraint *unstructured.Unstructured, enforce
 		logging.ConstraintName, constraint.GetName(),
 		logging.ConstraintNamespace, constraint.GetNamespace(),
 		logging.ConstraintAction, enforcementAction,
+		logging.ConstraintSeverity, level,
 		logging.ConstraintStatus, "enforced",
 		logging.ConstraintViolations, strconv.FormatInt(totalViolations, 10),
 	)
@@ -769,7 +1087,7 @@ func logConstraint(l logr.Logger, constraint *unstructured.Unstructured, enforce
 
 func logViolation(l logr.Logger,
 	constraint *unstructured.Unstructured,
-	enforcementAction string, resourceGroupVersionKind schema.GroupVersionKind, rnamespace, rname, message string, details interface{}) {
+	enforcementAction string, level severity.Level, resourceGroupVersionKind schema.GroupVersionKind, rnamespace, rname, message string, details interface{}) {
 	l.Info(
 		message,
 		logging.Details, details,
@@ -780,6 +1098,7 @@ func logViolation(l logr.Logger,
 		logging.ConstraintName, constraint.GetName(),
 		logging.ConstraintNamespace, constraint.GetNamespace(),
 		logging.ConstraintAction, enforcementAction,
+		logging.ConstraintSeverity, level,
 		logging.ResourceGroup, resourceGroupVersionKind.Group,
 		logging.ResourceAPIVersion, resourceGroupVersionKind.Version,
 		logging.ResourceKind, resourceGroupVersionKind.Kind,
@@ -789,7 +1108,7 @@ func logViolation(l logr.Logger,
 }
 
 func emitEvent(constraint *unstructured.Unstructured,
-	timestamp, enforcementAction string, resourceGroupVersionKind schema.GroupVersionKind, rnamespace, rname, message, gkNamespace string,
+	timestamp, enforcementAction string, level severity.Level, resourceGroupVersionKind schema.GroupVersionKind, rnamespace, rname, ruid, message, gkNamespace string,
 	eventRecorder record.EventRecorder) {
 	annotations := map[string]string{
 		"process":                    "audit",
@@ -801,6 +1120,7 @@ func emitEvent(constraint *unstructured.Unstructured,
 		logging.ConstraintName:       constraint.GetName(),
 		logging.ConstraintNamespace:  constraint.GetNamespace(),
 		logging.ConstraintAction:     enforcementAction,
+		logging.ConstraintSeverity:   string(level),
 		logging.ResourceGroup:        resourceGroupVersionKind.Group,
 		logging.ResourceAPIVersion:   resourceGroupVersionKind.Version,
 		logging.ResourceKind:         resourceGroupVersionKind.Kind,
@@ -808,7 +1128,12 @@ func emitEvent(constraint *unstructured.Unstructured,
 		logging.ResourceName:         rname,
 	}
 	reason := "AuditViolation"
-	ref := getViolationRef(gkNamespace, resourceGroupVersionKind.Kind, rname, rnamespace, constraint.GetKind(), constraint.GetName(), constraint.GetNamespace())
+	var ref *corev1.ObjectReference
+	if *auditEventsInvolvedNs && rnamespace != "" {
+		ref = getViolatingObjectRef(resourceGroupVersionKind, rname, rnamespace, ruid)
+	} else {
+		ref = getViolationRef(gkNamespace, resourceGroupVersionKind.Kind, rname, rnamespace, constraint.GetKind(), constraint.GetName(), constraint.GetNamespace())
+	}
 
 	eventRecorder.AnnotatedEventf(ref, annotations, corev1.EventTypeWarning, reason, "Timestamp: %s, Resource Namespace: %s, Constraint: %s, Message: %s", timestamp, rnamespace, constraint.GetName(), message)
 }
@@ -821,3 +1146,15 @@ func getViolationRef(gkNamespace, rkind, rname, rnamespace, ckind, cname, cnames
 		Namespace: gkNamespace,
 	}
 }
+
+// getViolatingObjectRef references the violating object itself, so the event is
+// created in its namespace and listed when describing it.
+func getViolatingObjectRef(rgvk schema.GroupVersionKind, rname, rnamespace, ruid string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: rgvk.GroupVersion().String(),
+		Kind:       rgvk.Kind,
+		Name:       rname,
+		Namespace:  rnamespace,
+		UID:        types.UID(ruid),
+	}
+}
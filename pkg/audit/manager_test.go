@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFirstEventForViolation(t *testing.T) {
+	am := &Manager{}
+
+	resource := &unstructured.Unstructured{}
+	resource.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Pod"})
+	resource.SetName("foo")
+	resource.SetNamespace("bar")
+	resource.SetUID("abc")
+
+	constraint := &unstructured.Unstructured{}
+	constraint.SetKind("K8sRequiredLabels")
+	constraint.SetName("must-have-owner")
+
+	if !am.firstEventForViolation(resource, constraint) {
+		t.Error("first violation should emit an event")
+	}
+	if am.firstEventForViolation(resource, constraint) {
+		t.Error("repeated violation should not emit an event")
+	}
+
+	other := constraint.DeepCopy()
+	other.SetName("must-have-team")
+	if !am.firstEventForViolation(resource, other) {
+		t.Error("violation of a different constraint should emit an event")
+	}
+}
+
+func TestGetViolatingObjectRef(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	got := getViolatingObjectRef(gvk, "foo", "bar", "abc")
+	want := &corev1.ObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "foo",
+		Namespace:  "bar",
+		UID:        types.UID("abc"),
+	}
+	if *got != *want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+
+	constraintTypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/override"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// downgrade downgrades the results which a NamespacePolicyOverride downgrades
+// from deny to warn, counting them against the overrides.
+func (am *Manager) downgrade(results []*constraintTypes.Result) {
+	for r, names := range override.Get().Apply(results) {
+		// Only results for namespaced objects are downgraded.
+		ns := r.Resource.(*unstructured.Unstructured).GetNamespace()
+		for _, name := range names {
+			am.downgraded[types.NamespacedName{Namespace: ns, Name: name}]++
+		}
+	}
+}
+
+// writeOverrideStatuses records which constraints every
+// NamespacePolicyOverride downgrades, and the violations downgraded by the
+// audit started at timestamp, in its status.
+func (am *Manager) writeOverrideStatuses(ctx context.Context, timestamp string) {
+	list := &exceptionsv1alpha1.NamespacePolicyOverrideList{}
+	if err := am.client.List(ctx, list); err != nil {
+		am.log.Error(err, "unable to list namespace policy overrides")
+		return
+	}
+	for i := range list.Items {
+		o := &list.Items[i]
+		active, denied := override.Get().Partition(o)
+		o.Status = exceptionsv1alpha1.NamespacePolicyOverrideStatus{
+			AuditTimestamp:  timestamp,
+			Active:          active,
+			Denied:          denied,
+			TotalDowngraded: am.downgraded[types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}],
+		}
+		if err := am.client.Status().Update(ctx, o); err != nil {
+			am.log.Error(err, "unable to update namespace policy override status", "namespace", o.GetNamespace(), "overrideName", o.GetName())
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// severities tallies the violations found by an audit by severity, and the
+// compliance score of each namespace: the sum of the severity weights of the
+// violations of the objects in it.
+type severities struct {
+	violations map[severity.Level]int64
+	scores     map[string]float64
+}
+
+func newSeverities() *severities {
+	s := &severities{violations: make(map[severity.Level]int64), scores: make(map[string]float64)}
+	for _, l := range severity.KnownLevels {
+		s.violations[l] = 0
+	}
+	return s
+}
+
+// add records that constraint is violated by resource.
+func (s *severities) add(constraint, resource *unstructured.Unstructured) {
+	level, weight := severity.Of(constraint)
+	s.violations[level]++
+	if ns := resource.GetNamespace(); ns != "" {
+		s.scores[ns] += weight
+	}
+}
+
+// reportSeverities reports the violations of the current audit by severity,
+// and the compliance score of each namespace. Namespaces scored by the
+// previous complete audit but not the current one are reported as compliant.
+func (am *Manager) reportSeverities() {
+	for level, v := range am.severities.violations {
+		if err := am.reporter.reportSeverityViolations(level, v); err != nil {
+			am.log.Error(err, "failed to report violations by severity")
+		}
+	}
+	if am.previousSeverities != nil {
+		for ns := range am.previousSeverities.scores {
+			if _, ok := am.severities.scores[ns]; !ok {
+				if err := am.reporter.reportComplianceScore(ns, 0); err != nil {
+					am.log.Error(err, "failed to report compliance score")
+				}
+			}
+		}
+	}
+	for ns, score := range am.severities.scores {
+		if err := am.reporter.reportComplianceScore(ns, score); err != nil {
+			am.log.Error(err, "failed to report compliance score")
+		}
+	}
+	am.previousSeverities = am.severities
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
+	"go.opencensus.io/stats/view"
+)
+
+func TestReportSeverities(t *testing.T) {
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := &Manager{reporter: r, log: logr.Discard()}
+
+	critical := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sRequiredLabels", "", "critical")
+	critical.Object["spec"] = map[string]interface{}{"severity": "critical"}
+	weighted := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sRequiredLabels", "", "weighted")
+	weighted.Object["spec"] = map[string]interface{}{"severity": "low", "severityWeight": 2.5}
+	unset := deltaObject("constraints.gatekeeper.sh/v1beta1", "K8sRequiredLabels", "", "unset")
+
+	am.severities = newSeverities()
+	am.severities.add(critical, deltaObject("v1", "Pod", "a", "pod"))
+	am.severities.add(weighted, deltaObject("v1", "Pod", "a", "pod"))
+	am.severities.add(unset, deltaObject("v1", "Pod", "b", "pod"))
+	am.severities.add(critical, deltaObject("v1", "Namespace", "", "a"))
+	am.reportSeverities()
+
+	wantViolations := map[string]float64{"none": 1, "low": 1, "medium": 0, "high": 0, "critical": 2}
+	if diff := cmp.Diff(wantViolations, retrieveByTag(t, severityViolationsMetricName, "severity")); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(map[string]float64{"a": 12.5, "b": 1}, retrieveByTag(t, complianceScoreMetricName, "namespace")); diff != "" {
+		t.Error(diff)
+	}
+
+	// Namespaces without violations in the next audit are compliant.
+	am.severities = newSeverities()
+	am.severities.add(unset, deltaObject("v1", "Pod", "b", "pod"))
+	am.reportSeverities()
+	if diff := cmp.Diff(map[string]float64{"a": 0, "b": 1}, retrieveByTag(t, complianceScoreMetricName, "namespace")); diff != "" {
+		t.Error(diff)
+	}
+	if got := am.previousSeverities.violations[severity.None]; got != 1 {
+		t.Errorf("got %d previous violations without severity, want 1", got)
+	}
+}
+
+// retrieveByTag returns the last values of the metric name, keyed by the
+// value of the tag key.
+func retrieveByTag(t *testing.T, name, key string) map[string]float64 {
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == key {
+				got[tag.Value] = row.Data.(*view.LastValueData).Value
+			}
+		}
+	}
+	return got
+}
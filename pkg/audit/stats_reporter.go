@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
@@ -15,6 +16,18 @@ const (
 	violationsMetricName    = "violations"
 	auditDurationMetricName = "audit_duration_seconds"
 	lastRunTimeMetricName   = "audit_last_run_time"
+
+	templateDurationMetricName = "audit_template_duration_seconds"
+	violationChangesMetricName = "audit_violation_changes"
+
+	severityViolationsMetricName = "audit_violations_by_severity"
+	complianceScoreMetricName    = "audit_namespace_compliance_score"
+
+	coverageKindsMetricName              = "audit_coverage_kinds"
+	coverageObjectsMetricName            = "audit_coverage_objects"
+	coverageExcludedNamespacesMetricName = "audit_coverage_excluded_namespaces"
+
+	suppressedViolationsMetricName = "audit_suppressed_violations"
 )
 
 var (
@@ -22,7 +35,43 @@ var (
 	auditDurationM = stats.Float64(auditDurationMetricName, "Latency of audit operation in seconds", stats.UnitSeconds)
 	lastRunTimeM   = stats.Float64(lastRunTimeMetricName, "Timestamp of last audit run time", stats.UnitSeconds)
 
+	templateDurationM = stats.Float64(templateDurationMetricName, "Latency of evaluating a constraint of a template for an object in seconds", stats.UnitSeconds)
+	violationChangesM = stats.Int64(violationChangesMetricName, "Number of violations of constraints which are new, resolved or unchanged since the previous audit", stats.UnitDimensionless)
+
+	severityViolationsM = stats.Int64(severityViolationsMetricName, "Total number of audited violations of constraints of each severity", stats.UnitDimensionless)
+	complianceScoreM    = stats.Float64(complianceScoreMetricName, "Sum of the severity weights of the audited violations of the objects in a namespace, 0 if the namespace is compliant", stats.UnitDimensionless)
+
+	coverageKindsM              = stats.Int64(coverageKindsMetricName, "Number of kinds the last audit discovered, audited, or failed to list", stats.UnitDimensionless)
+	coverageObjectsM            = stats.Int64(coverageObjectsMetricName, "Number of objects the last audit reviewed, skipped in excluded namespaces, or reviewed without any constraint matching them", stats.UnitDimensionless)
+	coverageExcludedNamespacesM = stats.Int64(coverageExcludedNamespacesMetricName, "Number of namespaces whose objects the last audit skipped because they are excluded", stats.UnitDimensionless)
+
+	suppressedViolationsM = stats.Int64(suppressedViolationsMetricName, "Number of violations the last audit suppressed by each Suppression", stats.UnitDimensionless)
+
 	enforcementActionKey = tag.MustNewKey("enforcement_action")
+	templateKindKey      = tag.MustNewKey("template_kind")
+	constraintNameKey    = tag.MustNewKey("constraint_name")
+	changeKey            = tag.MustNewKey("change")
+	severityKey          = tag.MustNewKey("severity")
+	namespaceKey         = tag.MustNewKey("namespace")
+	coverageKey          = tag.MustNewKey("coverage")
+	suppressionNameKey   = tag.MustNewKey("suppression_name")
+)
+
+// Values of the change tag.
+const (
+	changeNew       = "new"
+	changeResolved  = "resolved"
+	changeUnchanged = "unchanged"
+)
+
+// Values of the coverage tag.
+const (
+	coverageDiscovered = "discovered"
+	coverageAudited    = "audited"
+	coverageFailed     = "failed"
+	coverageReviewed   = "reviewed"
+	coverageExcluded   = "excluded"
+	coverageUnmatched  = "unmatched"
 )
 
 func init() {
@@ -50,6 +99,61 @@ func register() error {
 			Description: "Timestamp of last audit run time",
 			Aggregation: view.LastValue(),
 		},
+		{
+			Name:        templateDurationMetricName,
+			Measure:     templateDurationM,
+			Description: templateDurationM.Description(),
+			Aggregation: view.Distribution(0.001, 0.002, 0.003, 0.004, 0.005, 0.006, 0.007, 0.008, 0.009, 0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.07, 0.08, 0.09, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.5, 2, 2.5, 3),
+			TagKeys:     []tag.Key{templateKindKey, constraintNameKey},
+		},
+		{
+			Name:        violationChangesMetricName,
+			Measure:     violationChangesM,
+			Description: violationChangesM.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{templateKindKey, constraintNameKey, changeKey},
+		},
+		{
+			Name:        severityViolationsMetricName,
+			Measure:     severityViolationsM,
+			Description: severityViolationsM.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{severityKey},
+		},
+		{
+			Name:        complianceScoreMetricName,
+			Measure:     complianceScoreM,
+			Description: complianceScoreM.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{namespaceKey},
+		},
+		{
+			Name:        coverageKindsMetricName,
+			Measure:     coverageKindsM,
+			Description: coverageKindsM.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{coverageKey},
+		},
+		{
+			Name:        coverageObjectsMetricName,
+			Measure:     coverageObjectsM,
+			Description: coverageObjectsM.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{coverageKey},
+		},
+		{
+			Name:        coverageExcludedNamespacesMetricName,
+			Measure:     coverageExcludedNamespacesM,
+			Description: coverageExcludedNamespacesM.Description(),
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        suppressedViolationsMetricName,
+			Measure:     suppressedViolationsM,
+			Description: suppressedViolationsM.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{suppressionNameKey},
+		},
 	}
 	return view.Register(views...)
 }
@@ -74,6 +178,97 @@ func (r *reporter) reportLatency(d time.Duration) error {
 	return r.report(ctx, auditDurationM.M(d.Seconds()))
 }
 
+func (r *reporter) reportTemplateLatency(templateKind, constraintName string, d time.Duration) error {
+	mutators := []tag.Mutator{tag.Insert(templateKindKey, templateKind)}
+	if name, ok := metrics.ConstraintNameTagValue(constraintName); ok {
+		mutators = append(mutators, tag.Insert(constraintNameKey, name))
+	}
+	ctx, err := tag.New(context.Background(), mutators...)
+	if err != nil {
+		return err
+	}
+
+	return r.report(ctx, templateDurationM.M(d.Seconds()))
+}
+
+// reportViolationDelta reports d for the constraints of templateKind, or only
+// the constraint named constraintName if it is not empty.
+func (r *reporter) reportViolationDelta(templateKind, constraintName string, d violationDelta) error {
+	for change, v := range map[string]int64{changeNew: d.New, changeResolved: d.Resolved, changeUnchanged: d.Unchanged} {
+		mutators := []tag.Mutator{tag.Insert(templateKindKey, templateKind), tag.Insert(changeKey, change)}
+		if constraintName != "" {
+			mutators = append(mutators, tag.Insert(constraintNameKey, constraintName))
+		}
+		ctx, err := tag.New(context.Background(), mutators...)
+		if err != nil {
+			return err
+		}
+		if err := r.report(ctx, violationChangesM.M(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reporter) reportSeverityViolations(level severity.Level, v int64) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(severityKey, string(level)))
+	if err != nil {
+		return err
+	}
+
+	return r.report(ctx, severityViolationsM.M(v))
+}
+
+func (r *reporter) reportComplianceScore(namespace string, score float64) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(namespaceKey, namespace))
+	if err != nil {
+		return err
+	}
+
+	return r.report(ctx, complianceScoreM.M(score))
+}
+
+func (r *reporter) reportCoverageKinds(coverage string, v int64) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(coverageKey, coverage))
+	if err != nil {
+		return err
+	}
+
+	return r.report(ctx, coverageKindsM.M(v))
+}
+
+func (r *reporter) reportCoverageObjects(coverage string, v int64) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(coverageKey, coverage))
+	if err != nil {
+		return err
+	}
+
+	return r.report(ctx, coverageObjectsM.M(v))
+}
+
+func (r *reporter) reportExcludedNamespaces(v int64) error {
+	return r.report(context.Background(), coverageExcludedNamespacesM.M(v))
+}
+
+func (r *reporter) reportSuppressedViolations(suppressionName string, v int64) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(suppressionNameKey, suppressionName))
+	if err != nil {
+		return err
+	}
+
+	return r.report(ctx, suppressedViolationsM.M(v))
+}
+
 func (r *reporter) reportRunStart(t time.Time) error {
 	ctx, err := tag.New(context.Background())
 	if err != nil {
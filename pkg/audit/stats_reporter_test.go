@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
 	"go.opencensus.io/stats/view"
 )
 
@@ -112,3 +113,83 @@ func TestLastRestartCheck(t *testing.T) {
 		t.Errorf("Metric: %v - Expected %v, got %v", lastRunTimeMetricName, expectedTs, value.Value)
 	}
 }
+
+func TestReportTemplateLatency(t *testing.T) {
+	const expectedLatency = time.Duration(2 * time.Second)
+	const expectedRowLength = 1
+	expectedTags := map[string]string{
+		"template_kind": "K8sRequiredLabels",
+	}
+
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Errorf("newStatsReporter() error %v", err)
+	}
+	err = r.reportTemplateLatency("K8sRequiredLabels", "must-have-owner", expectedLatency)
+	if err != nil {
+		t.Errorf("reportTemplateLatency error %v", err)
+	}
+	row := checkData(t, templateDurationMetricName, expectedRowLength)
+	value, ok := row.Data.(*view.DistributionData)
+	if !ok {
+		t.Error("reportTemplateLatency should have aggregation type Distribution")
+	}
+	for _, tag := range row.Tags {
+		if tag.Value != expectedTags[tag.Key.Name()] {
+			t.Errorf("reportTemplateLatency tags does not match for %v", tag.Key.Name())
+		}
+	}
+	if value.Max != expectedLatency.Seconds() {
+		t.Errorf("Metric: %v - Expected %v, got %v", templateDurationMetricName, expectedLatency.Seconds(), value.Max)
+	}
+}
+
+func TestReportCoverage(t *testing.T) {
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Fatalf("newStatsReporter() error %v", err)
+	}
+	err = r.reportCoverage(&v1beta1.AuditCoverage{
+		KindsDiscovered:    10,
+		KindsAudited:       9,
+		FailedKinds:        []string{"metrics.k8s.io/v1beta1, Kind=PodMetrics"},
+		ExcludedNamespaces: []string{"kube-system", "gatekeeper-system"},
+		ObjectsReviewed:    100,
+		ObjectsExcluded:    20,
+	})
+	if err != nil {
+		t.Fatalf("reportCoverage error %v", err)
+	}
+
+	// unmatched is not recorded without query statistics.
+	rows, err := view.RetrieveData(coverageObjectsMetricName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("got %d rows of %s, want 2", len(rows), coverageObjectsMetricName)
+	}
+
+	row := checkData(t, coverageExcludedNamespacesMetricName, 1)
+	if value, ok := row.Data.(*view.LastValueData); !ok || value.Value != 2 {
+		t.Errorf("got %v excluded namespaces, want 2", row.Data)
+	}
+}
+
+func TestReportSuppressedViolations(t *testing.T) {
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Fatalf("newStatsReporter() error %v", err)
+	}
+	if err := r.reportSuppressedViolations("accepted-risks", 3); err != nil {
+		t.Fatalf("reportSuppressedViolations error %v", err)
+	}
+
+	row := checkData(t, suppressedViolationsMetricName, 1)
+	if value, ok := row.Data.(*view.LastValueData); !ok || value.Value != 3 {
+		t.Errorf("got %v suppressed violations, want 3", row.Data)
+	}
+	if len(row.Tags) != 1 || row.Tags[0].Value != "accepted-risks" {
+		t.Errorf("got tags %v, want suppression_name accepted-risks", row.Tags)
+	}
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+
+	constraintTypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/suppression"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// suppressions are the violations suppressed by a Suppression during an
+// audit.
+type suppressions struct {
+	total int64
+	items []exceptionsv1alpha1.SuppressedViolation
+}
+
+// suppress returns the results for obj which are not suppressed by a
+// Suppression, recording the others against the Suppressions suppressing
+// them.
+func (am *Manager) suppress(obj interface{}, results []*constraintTypes.Result) []*constraintTypes.Result {
+	var ns *corev1.Namespace
+	if au, ok := obj.(target.AugmentedUnstructured); ok {
+		ns = au.Namespace
+	}
+	kept, suppressed := suppression.Get().Filter(results, ns)
+	for r, names := range suppressed {
+		resource, ok := r.Resource.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			am.recordSuppression(name, exceptionsv1alpha1.SuppressedViolation{
+				ConstraintKind:    r.Constraint.GetKind(),
+				ConstraintName:    r.Constraint.GetName(),
+				Kind:              resource.GetKind(),
+				Name:              resource.GetName(),
+				Namespace:         resource.GetNamespace(),
+				Message:           truncateString(r.Msg, msgSize),
+				EnforcementAction: r.EnforcementAction,
+			})
+		}
+	}
+	return kept
+}
+
+func (am *Manager) recordSuppression(name string, v exceptionsv1alpha1.SuppressedViolation) {
+	if am.suppressed == nil {
+		am.suppressed = make(map[string]*suppressions)
+	}
+	s, ok := am.suppressed[name]
+	if !ok {
+		s = &suppressions{}
+		am.suppressed[name] = s
+	}
+	s.total++
+	if uint(len(s.items)) < *constraintViolationsLimit {
+		s.items = append(s.items, v)
+	}
+}
+
+// writeSuppressionStatuses records the violations suppressed by the audit
+// started at timestamp in the status of every Suppression, and reports how
+// many each suppressed.
+func (am *Manager) writeSuppressionStatuses(ctx context.Context, timestamp string) {
+	list := &exceptionsv1alpha1.SuppressionList{}
+	if err := am.client.List(ctx, list); err != nil {
+		am.log.Error(err, "unable to list suppressions")
+		return
+	}
+	for i := range list.Items {
+		s := &list.Items[i]
+		s.Status = exceptionsv1alpha1.SuppressionStatus{AuditTimestamp: timestamp}
+		if sv, ok := am.suppressed[s.GetName()]; ok {
+			s.Status.TotalSuppressed = sv.total
+			s.Status.Suppressed = sv.items
+		}
+		if err := am.reporter.reportSuppressedViolations(s.GetName(), s.Status.TotalSuppressed); err != nil {
+			am.log.Error(err, "failed to report suppressed violations", "suppressionName", s.GetName())
+		}
+		if err := am.client.Status().Update(ctx, s); err != nil {
+			am.log.Error(err, "unable to update suppression status", "suppressionName", s.GetName())
+		}
+	}
+}
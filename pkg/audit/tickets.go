@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/ticketing"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// fileTickets reports the violations recorded by an audit to the ticket
+// notifier, and sets the ID and ticket of each of them.
+func (am *Manager) fileTickets(ctx context.Context, updateLists map[util.KindVersionResource][]auditResult, timestamp string) {
+	var violations []*ticketing.Violation
+	for key := range updateLists {
+		results := updateLists[key]
+		for i := range results {
+			ar := &results[i]
+			constraint := ticketing.Reference{Kind: ar.cgvk.Kind, Namespace: ar.cnamespace, Name: ar.cname}
+			resource := ticketing.Reference{Group: ar.rgroup, Kind: ar.rkind, Namespace: ar.rnamespace, Name: ar.rname}
+			ar.violationID = ticketing.ID(constraint, resource, ar.message)
+			violations = append(violations, &ticketing.Violation{
+				ID:                ar.violationID,
+				Constraint:        constraint,
+				Resource:          resource,
+				EnforcementAction: ar.enforcementAction,
+				Severity:          string(ar.severity),
+				Message:           ar.message,
+				AuditTimestamp:    timestamp,
+			})
+		}
+	}
+
+	am.tickets.Observe(ctx, violations)
+
+	for key := range updateLists {
+		results := updateLists[key]
+		for i := range results {
+			results[i].ticket = am.tickets.Ticket(results[i].violationID)
+		}
+	}
+}
+
+// previousTickets returns the tickets recorded on the violations in the
+// status of instance, by violation ID. Tickets filed by a previous Gatekeeper
+// pod are carried over so they are not filed again.
+func (ucloop *updateConstraintLoop) previousTickets(instance *unstructured.Unstructured) map[string]string {
+	if ucloop.tickets == nil {
+		return nil
+	}
+	violations, _, err := unstructured.NestedSlice(instance.Object, "status", "violations")
+	if err != nil {
+		return nil
+	}
+	tickets := make(map[string]string)
+	for _, v := range violations {
+		violation, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := violation["id"].(string)
+		ticket, _ := violation["ticket"].(string)
+		if id != "" && ticket != "" {
+			tickets[id] = ticket
+		}
+	}
+	return tickets
+}
@@ -0,0 +1,257 @@
+// Package builtins lets a build of Gatekeeper add Rego builtins, such as CIDR
+// math extensions or verifiers specific to an organization, and controls
+// which builtins ConstraintTemplates may call.
+//
+// Custom builtins are registered with Register, usually from the init
+// function of a package compiled into a fork. Each call is sandboxed: it is
+// bounded by a timeout, and a panic is returned as an error rather than
+// crashing the pod. A template may only call the custom builtins named by its
+// Annotation. A capability manifest in the format of `opa capabilities`,
+// given with --rego-capabilities, further limits the builtins templates may
+// call. Templates are checked when they are admitted and when they are
+// ingested. The results of builtins which set a CacheTTL, or are given one
+// with --builtin-cache-ttl, may be persisted with --builtin-cache-dir. The health of each custom builtin, as observed by
+// its calls, is reported by Healths.
+package builtins
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/gatekeeper/pkg/faultinject"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// Annotation is set on a ConstraintTemplate to the comma-separated names of
+// the custom builtins it may call.
+const Annotation = "builtins.gatekeeper.sh/allowed"
+
+// DefaultTimeout bounds each call of a custom builtin which sets no Timeout.
+const DefaultTimeout = time.Second
+
+var capabilitiesFile = flag.String("rego-capabilities", "", "(alpha) path of a capability manifest, in the format output by `opa capabilities`, listing the builtins ConstraintTemplates may call. Custom builtins must also be allowed by the "+Annotation+" annotation of each template. If empty, every builtin of this build may be called")
+
+// Builtin is a custom Rego builtin.
+type Builtin struct {
+	// Function declares the name and type of the builtin.
+	Function *rego.Function
+	// Impl implements the builtin. It must return once the context of its
+	// BuiltinContext is done, and must not use the BuiltinContext after.
+	Impl rego.BuiltinDyn
+	// Timeout bounds each call. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// CacheTTL, if set, is how long the results of the builtin are kept in
+	// the cache of --builtin-cache-dir, which survives restarts of the pod.
+	// It is overridden by --builtin-cache-ttl.
+	// Results are cached by the arguments of the call, so only builtins
+	// whose results depend on nothing else, such as verifiers of image
+	// signatures, should set it.
+	CacheTTL time.Duration
+}
+
+var (
+	registryMux sync.RWMutex
+	registry    = make(map[string]Builtin)
+
+	capabilitiesMux sync.RWMutex
+	// capabilities are the builtins templates may call, or nil if they may
+	// call any builtin.
+	capabilities map[string]bool
+)
+
+// Register adds b to the builtins of Rego. It panics if a builtin of the same
+// name already exists.
+func Register(b Builtin) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	name := b.Function.Name
+	if _, ok := ast.BuiltinMap[name]; ok {
+		panic(fmt.Sprintf("builtin %q already exists", name))
+	}
+	if b.Timeout <= 0 {
+		b.Timeout = DefaultTimeout
+	}
+	registry[name] = b
+	rego.RegisterBuiltinDyn(b.Function, cached(name, sandbox(name, b.Timeout, b.Impl)))
+}
+
+// Registered returns the sorted names of the custom builtins.
+func Registered() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isCustom(name string) bool {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// sandbox returns impl, bounded by timeout and returning an error rather
+// than panicking.
+func sandbox(name string, timeout time.Duration, impl rego.BuiltinDyn) rego.BuiltinDyn {
+	type result struct {
+		term *ast.Term
+		err  error
+	}
+	return func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		parent := bctx.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		querystats.CountExternalDataCall(parent)
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		bctx.Context = ctx
+		if err := faultinject.Get().CallProvider(ctx); err != nil {
+			err = fmt.Errorf("builtin %s: %w", name, err)
+			recordCall(name, err)
+			return nil, err
+		}
+
+		done := make(chan result, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- result{err: fmt.Errorf("builtin %s panicked: %v", name, r)}
+				}
+			}()
+			term, err := impl(bctx, terms)
+			done <- result{term: term, err: err}
+		}()
+
+		var r result
+		select {
+		case r = <-done:
+		case <-ctx.Done():
+			r.err = fmt.Errorf("builtin %s: %w", name, ctx.Err())
+		}
+		recordCall(name, r.err)
+		return r.term, r.err
+	}
+}
+
+// LoadCapabilities loads the capability manifest of --rego-capabilities, if
+// set.
+func LoadCapabilities() error {
+	if *capabilitiesFile == "" {
+		return nil
+	}
+	f, err := os.Open(*capabilitiesFile)
+	if err != nil {
+		return fmt.Errorf("opening --rego-capabilities: %w", err)
+	}
+	defer f.Close()
+	caps, err := ast.LoadCapabilitiesJSON(f)
+	if err != nil {
+		return fmt.Errorf("parsing --rego-capabilities: %w", err)
+	}
+	SetCapabilities(caps)
+	return nil
+}
+
+// SetCapabilities limits the builtins templates may call to those of caps.
+// If caps is nil, templates may call any builtin.
+func SetCapabilities(caps *ast.Capabilities) {
+	capabilitiesMux.Lock()
+	defer capabilitiesMux.Unlock()
+	if caps == nil {
+		capabilities = nil
+		return
+	}
+	capabilities = make(map[string]bool, len(caps.Builtins))
+	for _, b := range caps.Builtins {
+		capabilities[b.Name] = true
+	}
+}
+
+func inCapabilities(name string) bool {
+	capabilitiesMux.RLock()
+	defer capabilitiesMux.RUnlock()
+	return capabilities == nil || capabilities[name]
+}
+
+// Validate returns an error if the Rego or libraries of templ call a builtin
+// which is not in the capability manifest, or a custom builtin which templ
+// does not allow. Rego which does not parse is left to the constraint
+// framework to report.
+func Validate(templ *templates.ConstraintTemplate) error {
+	allowed := make(map[string]bool)
+	for _, name := range strings.Split(templ.GetAnnotations()[Annotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+
+	var denied []string
+	seen := make(map[string]bool)
+	for _, target := range templ.Spec.Targets {
+		for _, src := range append([]string{target.Rego}, target.Libs...) {
+			m, err := ast.ParseModule(target.Target, src)
+			if err != nil || m == nil {
+				continue
+			}
+			for _, name := range calledBuiltins(m) {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				switch {
+				case !inCapabilities(name):
+					denied = append(denied, fmt.Sprintf("%s is not in the capability manifest", name))
+				case isCustom(name) && !allowed[name]:
+					denied = append(denied, fmt.Sprintf("custom builtin %s is not allowed by the %s annotation", name, Annotation))
+				}
+			}
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return fmt.Errorf("template calls builtins it may not: %s", strings.Join(denied, "; "))
+	}
+	return nil
+}
+
+// calledBuiltins returns the names of the builtins called by m. Calls of
+// functions which are not builtins, such as those defined by m, are skipped.
+func calledBuiltins(m *ast.Module) []string {
+	var names []string
+	add := func(op ast.Ref) {
+		name := op.String()
+		if _, ok := ast.BuiltinMap[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for _, rule := range m.Rules {
+		ast.NewGenericVisitor(func(x interface{}) bool {
+			switch v := x.(type) {
+			case *ast.Expr:
+				if v.IsCall() {
+					add(v.Operator())
+				}
+			case ast.Call:
+				if op, ok := v[0].Value.(ast.Ref); ok {
+					add(op)
+				}
+			}
+			return false
+		}).Walk(rule)
+	}
+	return names
+}
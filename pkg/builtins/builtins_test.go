@@ -0,0 +1,150 @@
+package builtins
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	Register(Builtin{
+		Function: &rego.Function{Name: "test.double", Decl: types.NewFunction(types.Args(types.N), types.N)},
+		Impl: func(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+			n, _ := terms[0].Value.(ast.Number).Int()
+			return ast.IntNumberTerm(2 * n), nil
+		},
+	})
+	Register(Builtin{
+		Function: &rego.Function{Name: "test.panic", Decl: types.NewFunction(types.Args(types.N), types.N)},
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			panic("boom")
+		},
+	})
+	Register(Builtin{
+		Function: &rego.Function{Name: "test.slow", Decl: types.NewFunction(types.Args(types.N), types.N)},
+		Impl: func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+			<-bctx.Context.Done()
+			return terms[0], nil
+		},
+		Timeout: 10 * time.Millisecond,
+	})
+}
+
+func eval(t *testing.T, query string) (interface{}, error) {
+	t.Helper()
+	rs, err := rego.New(rego.Query(query), rego.StrictBuiltinErrors(true)).Eval(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, nil
+	}
+	return rs[0].Expressions[0].Value, nil
+}
+
+func TestRegister(t *testing.T) {
+	got, err := eval(t, "test.double(21)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.(interface{ String() string }).String() != "42" {
+		t.Errorf("got %v, want 42", got)
+	}
+
+	for _, query := range []string{"test.panic(1)", "test.slow(1)"} {
+		if _, err := eval(t, query); err == nil {
+			t.Errorf("got no error evaluating %s", query)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("got no panic registering a builtin twice")
+		}
+	}()
+	Register(Builtin{Function: &rego.Function{Name: "count"}})
+}
+
+func TestRegistered(t *testing.T) {
+	if diff := cmp.Diff([]string{"test.double", "test.panic", "test.slow"}, Registered()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func template(annotation, rego string) *templates.ConstraintTemplate {
+	ct := &templates.ConstraintTemplate{
+		Spec: templates.ConstraintTemplateSpec{
+			Targets: []templates.Target{{Target: "admission.k8s.gatekeeper.sh", Rego: rego}},
+		},
+	}
+	if annotation != "" {
+		ct.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{Annotation: annotation}}
+	}
+	return ct
+}
+
+func TestValidate(t *testing.T) {
+	const custom = `package foo
+violation[{"msg": "denied"}] {
+  test.double(input.review.object.spec.replicas) > 10
+}`
+	const standard = `package foo
+violation[{"msg": msg}] {
+  double(1) > 1
+  msg := sprintf("%v", [count(input.review.object.spec)])
+}
+double(x) = y { y := x * 2 }`
+
+	tcs := []struct {
+		name         string
+		annotation   string
+		rego         string
+		capabilities []string
+		wantErr      string
+	}{
+		{name: "standard builtins", rego: standard},
+		{name: "custom builtin not allowed", rego: custom, wantErr: "custom builtin test.double"},
+		{name: "custom builtin allowed", annotation: "test.slow, test.double", rego: custom},
+		{
+			name:         "builtins in the manifest",
+			rego:         standard,
+			capabilities: []string{"assign", "gt", "mul", "eq", "sprintf", "count"},
+		},
+		{
+			name:         "builtin missing from the manifest",
+			rego:         standard,
+			capabilities: []string{"assign", "gt", "mul", "eq", "count"},
+			wantErr:      "sprintf is not in the capability manifest",
+		},
+		{name: "invalid rego", rego: "package foo violation["},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.capabilities != nil {
+				caps := &ast.Capabilities{}
+				for _, name := range tc.capabilities {
+					caps.Builtins = append(caps.Builtins, &ast.Builtin{Name: name})
+				}
+				SetCapabilities(caps)
+				defer SetCapabilities(nil)
+			}
+
+			err := Validate(template(tc.annotation, tc.rego))
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
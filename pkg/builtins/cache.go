@@ -0,0 +1,422 @@
+package builtins
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var (
+	cacheDir        = flag.String("builtin-cache-dir", "", "(alpha) directory persisting the results of custom builtins which set a CacheTTL, such as image signature verifiers, so that they survive restarts of the pod. It should be on a volume which outlives the pod. If empty, results are not cached")
+	cacheMaxEntries = flag.Int("builtin-cache-max-entries", 10000, "(alpha) the number of results --builtin-cache-dir holds, beyond which the results which expire first are evicted")
+	cacheTTLFlag    = flag.String("builtin-cache-ttl", "", "(alpha) custom builtins whose results --builtin-cache-dir persists, such as those verifying image signatures, as a comma-separated list of builtin=ttl, for example acme.verify_signature=24h. Overrides the CacheTTL set by the build, and a ttl of 0 disables caching the builtin")
+)
+
+var log = logf.Log.WithName("builtins")
+
+// cacheFileSuffix is the suffix of the files of cached results.
+const cacheFileSuffix = ".json"
+
+var (
+	cacheMux sync.RWMutex
+	// cache persists the results of custom builtins, or is nil if they are
+	// not cached.
+	cache *diskCache
+	// cacheTTLs are the TTLs of --builtin-cache-ttl, which override those
+	// set by the build, keyed by builtin name.
+	cacheTTLs map[string]time.Duration
+)
+
+// LoadCache opens the cache of --builtin-cache-dir, if set, dropping the
+// results which expired while the pod was down.
+func LoadCache() error {
+	if *cacheDir == "" {
+		if *cacheTTLFlag != "" {
+			return fmt.Errorf("--builtin-cache-ttl requires --builtin-cache-dir")
+		}
+		return nil
+	}
+	if *cacheMaxEntries <= 0 {
+		return fmt.Errorf("--builtin-cache-max-entries must be positive, got %d", *cacheMaxEntries)
+	}
+	ttls, err := parseCacheTTLs(*cacheTTLFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --builtin-cache-ttl: %w", err)
+	}
+	c, err := newDiskCache(*cacheDir, *cacheMaxEntries, time.Now)
+	if err != nil {
+		return err
+	}
+	setCache(c)
+	setCacheTTLs(ttls)
+	return nil
+}
+
+// parseCacheTTLs returns the TTLs of a comma-separated list of builtin=ttl,
+// each of which must name a custom builtin.
+func parseCacheTTLs(s string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q must be of the form builtin=ttl", entry)
+		}
+		name := strings.TrimSpace(parts[0])
+		if !isCustom(name) {
+			return nil, fmt.Errorf("%q is not a custom builtin, must be one of %s", name, strings.Join(Registered(), ", "))
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("builtin %s: %w", name, err)
+		}
+		if ttl < 0 {
+			return nil, fmt.Errorf("builtin %s: ttl must not be negative", name)
+		}
+		ttls[name] = ttl
+	}
+	return ttls, nil
+}
+
+func setCacheTTLs(ttls map[string]time.Duration) {
+	cacheMux.Lock()
+	defer cacheMux.Unlock()
+	cacheTTLs = ttls
+}
+
+// cacheFor returns the cache of the results of the builtin name and how long
+// they are kept, or a nil cache if they are not cached.
+func cacheFor(name string) (*diskCache, time.Duration) {
+	cacheMux.RLock()
+	c := cache
+	ttl, ok := cacheTTLs[name]
+	cacheMux.RUnlock()
+	if !ok {
+		registryMux.RLock()
+		ttl = registry[name].CacheTTL
+		registryMux.RUnlock()
+	}
+	if c == nil || ttl <= 0 {
+		return nil, 0
+	}
+	return c, ttl
+}
+
+func setCache(c *diskCache) {
+	cacheMux.Lock()
+	defer cacheMux.Unlock()
+	cache = c
+}
+
+// cached returns impl, returning the results it returned for the same
+// arguments within the TTL of name from the cache, if they are cached.
+// Concurrent calls with the same arguments which are not cached share a
+// single call of impl, so that the reviews of a redeployed workload do not
+// all look its images up at once. Errors and undefined results are not
+// cached.
+func cached(name string, impl rego.BuiltinDyn) rego.BuiltinDyn {
+	return func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		c, ttl := cacheFor(name)
+		if c == nil {
+			return impl(bctx, terms)
+		}
+		key := cacheKey(name, terms)
+		if term, ok := c.get(key); ok {
+			return term, nil
+		}
+		ctx := bctx.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return c.do(ctx, key, func() (*ast.Term, error) {
+			term, err := impl(bctx, terms)
+			if err == nil && term != nil {
+				if err := c.put(key, term, ttl); err != nil {
+					log.Error(err, "unable to cache builtin result", "builtin", name)
+				}
+			}
+			return term, err
+		})
+	}
+}
+
+// cacheKey identifies a call of the builtin name with terms. The string form
+// of terms is canonical, as objects and sets are written sorted.
+func cacheKey(name string, terms []*ast.Term) string {
+	args := make([]string, len(terms))
+	for i, t := range terms {
+		args[i] = t.String()
+	}
+	sum := sha256.Sum256([]byte(name + "(" + strings.Join(args, ", ") + ")"))
+	return hex.EncodeToString(sum[:])
+}
+
+// diskCache is a bounded store of the results of builtins, with a file for
+// each result. The index of the files is kept in memory, ordered by when
+// they expire.
+type diskCache struct {
+	dir string
+	max int
+	now func() time.Time
+
+	mux     sync.Mutex
+	entries map[string]*list.Element
+	// order holds the *cacheEntry of every result, soonest to expire first.
+	order *list.List
+
+	inflightMux sync.Mutex
+	// inflight are the calls being made for results which are not cached,
+	// keyed like them.
+	inflight map[string]*inflightCall
+}
+
+// inflightCall is a call shared by the concurrent calls with the same
+// arguments. Its result is set once done is closed.
+type inflightCall struct {
+	done chan struct{}
+	term *ast.Term
+	err  error
+}
+
+type cacheEntry struct {
+	key     string
+	expires time.Time
+}
+
+// cacheFile is the content of the file of a result.
+type cacheFile struct {
+	Expires time.Time `json:"expires"`
+	Result  *ast.Term `json:"result"`
+}
+
+func newDiskCache(dir string, max int, now func() time.Time) (*diskCache, error) {
+	c := &diskCache{
+		dir:      dir,
+		max:      max,
+		now:      now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		inflight: make(map[string]*inflightCall),
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating --builtin-cache-dir: %w", err)
+	}
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("loading --builtin-cache-dir: %w", err)
+	}
+	return c, nil
+}
+
+// load indexes the results in the directory of c. Expired results and files
+// which cannot be read are removed.
+func (c *diskCache) load() error {
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	now := c.now()
+	var entries []*cacheEntry
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if strings.Contains(f.Name(), cacheFileSuffix+".tmp") {
+			// A result the pod was writing when it stopped.
+			if err := os.Remove(filepath.Join(c.dir, f.Name())); err != nil {
+				log.Error(err, "unable to remove partially written builtin result", "file", f.Name())
+			}
+			continue
+		}
+		if !strings.HasSuffix(f.Name(), cacheFileSuffix) {
+			continue
+		}
+		key := strings.TrimSuffix(f.Name(), cacheFileSuffix)
+		cf, err := c.read(key)
+		if err != nil || !now.Before(cf.Expires) {
+			c.remove(key)
+			continue
+		}
+		entries = append(entries, &cacheEntry{key: key, expires: cf.Expires})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].expires.Before(entries[j].expires)
+	})
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, e := range entries {
+		c.entries[e.key] = c.order.PushBack(e)
+	}
+	c.evict()
+	return nil
+}
+
+// get returns the result of key, if it is cached and has not expired.
+func (c *diskCache) get(key string) (*ast.Term, bool) {
+	c.mux.Lock()
+	e, ok := c.entries[key]
+	if ok && !c.now().Before(e.Value.(*cacheEntry).expires) {
+		c.drop(e)
+		ok = false
+	}
+	c.mux.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	cf, err := c.read(key)
+	if err != nil {
+		c.mux.Lock()
+		if e, ok := c.entries[key]; ok {
+			c.drop(e)
+		}
+		c.mux.Unlock()
+		return nil, false
+	}
+	return cf.Result, true
+}
+
+// do returns the result of fn, unless a call of fn for key is already in
+// flight, in which case it waits for and returns the result of that call
+// until ctx is done. A call which fails because its own caller's context was
+// done is not shared: its waiters make the call again instead.
+func (c *diskCache) do(ctx context.Context, key string, fn func() (*ast.Term, error)) (*ast.Term, error) {
+	c.inflightMux.Lock()
+	for {
+		call, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+		c.inflightMux.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !errors.Is(call.err, context.Canceled) && !errors.Is(call.err, context.DeadlineExceeded) {
+			return call.term, call.err
+		}
+		c.inflightMux.Lock()
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.inflightMux.Unlock()
+
+	defer func() {
+		c.inflightMux.Lock()
+		delete(c.inflight, key)
+		c.inflightMux.Unlock()
+		close(call.done)
+	}()
+	call.term, call.err = fn()
+	return call.term, call.err
+}
+
+// put caches result as that of key until ttl elapses, evicting the results
+// which expire first if the cache is full.
+func (c *diskCache) put(key string, result *ast.Term, ttl time.Duration) error {
+	expires := c.now().Add(ttl)
+	bytes, err := json.Marshal(cacheFile{Expires: expires, Result: result})
+	if err != nil {
+		return err
+	}
+	// Results are written to a temporary file which is then renamed, so a
+	// restart never finds a partially written result.
+	tmp, err := ioutil.TempFile(c.dir, key+cacheFileSuffix+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+	}
+	entry := &cacheEntry{key: key, expires: expires}
+	// Results usually share the TTL of their builtin, so they are inserted
+	// from the back.
+	mark := c.order.Back()
+	for mark != nil && mark.Value.(*cacheEntry).expires.After(expires) {
+		mark = mark.Prev()
+	}
+	if mark == nil {
+		c.entries[key] = c.order.PushFront(entry)
+	} else {
+		c.entries[key] = c.order.InsertAfter(entry, mark)
+	}
+	c.evict()
+	return nil
+}
+
+// evict drops the results which expire first until at most max are cached.
+// c.mux must be held.
+func (c *diskCache) evict() {
+	for c.order.Len() > c.max {
+		c.drop(c.order.Front())
+	}
+}
+
+// drop removes the result of e. c.mux must be held.
+func (c *diskCache) drop(e *list.Element) {
+	key := e.Value.(*cacheEntry).key
+	c.order.Remove(e)
+	delete(c.entries, key)
+	c.remove(key)
+}
+
+func (c *diskCache) read(key string) (*cacheFile, error) {
+	bytes, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	cf := &cacheFile{}
+	if err := json.Unmarshal(bytes, cf); err != nil {
+		return nil, err
+	}
+	if cf.Result == nil {
+		return nil, fmt.Errorf("cached result %s has no result", key)
+	}
+	return cf, nil
+}
+
+func (c *diskCache) remove(key string) {
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		log.Error(err, "unable to remove cached builtin result", "file", c.path(key))
+	}
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheFileSuffix)
+}
@@ -0,0 +1,310 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+
+	c, err := newDiskCache(dir, 2, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.put("a", ast.StringTerm("verified"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.put("b", ast.BooleanTerm(true), 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.get("a")
+	if !ok || !got.Equal(ast.StringTerm("verified")) {
+		t.Errorf("got %v, %v, want the cached result of a", got, ok)
+	}
+
+	// Results survive restarts.
+	c, err = newDiskCache(dir, 2, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.get("b"); !ok || !got.Equal(ast.BooleanTerm(true)) {
+		t.Errorf("got %v, %v, want the cached result of b after restarting", got, ok)
+	}
+
+	// The result which expires first is evicted when the cache is full.
+	if err := c.put("c", ast.IntNumberTerm(1), 3*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.get("a"); ok {
+		t.Error("got a cached after it was evicted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a"+cacheFileSuffix)); !os.IsNotExist(err) {
+		t.Errorf("got the file of a evicted result: %v", err)
+	}
+
+	// Expired results are dropped.
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.get("b"); ok {
+		t.Error("got b cached after it expired")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Error("got c not cached before it expired")
+	}
+
+	// Partially written and expired results are removed when loading.
+	if err := ioutil.WriteFile(filepath.Join(dir, "d"+cacheFileSuffix+".tmp123"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := newDiskCache(dir, 2, clock); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("got %d files, want every result removed", len(files))
+	}
+}
+
+func TestCached(t *testing.T) {
+	c, err := newDiskCache(t.TempDir(), 10, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCache(c)
+	defer setCache(nil)
+	setCacheTTLs(map[string]time.Duration{"test.verify": time.Hour})
+	defer setCacheTTLs(nil)
+
+	calls := 0
+	fail := false
+	impl := cached("test.verify", func(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		calls++
+		if fail {
+			return nil, errors.New("registry unavailable")
+		}
+		return ast.BooleanTerm(true), nil
+	})
+
+	image := ast.StringTerm("registry.example.com/app:v1")
+	for i := 0; i < 2; i++ {
+		got, err := impl(rego.BuiltinContext{}, []*ast.Term{image})
+		if err != nil || !got.Equal(ast.BooleanTerm(true)) {
+			t.Fatalf("got %v, %v, want true", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want the second served from the cache", calls)
+	}
+
+	// Errors are not cached.
+	fail = true
+	other := ast.StringTerm("registry.example.com/app:v2")
+	for i := 0; i < 2; i++ {
+		if _, err := impl(rego.BuiltinContext{}, []*ast.Term{other}); err == nil {
+			t.Error("got no error")
+		}
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want failed calls not to be cached", calls)
+	}
+}
+
+func TestCached_Concurrent(t *testing.T) {
+	c, err := newDiskCache(t.TempDir(), 10, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCache(c)
+	defer setCache(nil)
+	setCacheTTLs(map[string]time.Duration{"test.verify": time.Hour})
+	defer setCacheTTLs(nil)
+
+	var calls int32
+	release := make(chan struct{})
+	impl := cached("test.verify", func(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return ast.BooleanTerm(true), nil
+	})
+
+	// The reviews of a redeployed workload look its image up once.
+	image := ast.StringTerm("registry.example.com/app:v1")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := impl(rego.BuiltinContext{}, []*ast.Term{image}); err != nil || !got.Equal(ast.BooleanTerm(true)) {
+				t.Errorf("got %v, %v, want true", got, err)
+			}
+		}()
+	}
+	for {
+		c.inflightMux.Lock()
+		n := len(c.inflight)
+		c.inflightMux.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("got %d calls, want the concurrent calls to share one", calls)
+	}
+}
+
+func TestCached_Cancelled(t *testing.T) {
+	c, err := newDiskCache(t.TempDir(), 10, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCache(c)
+	defer setCache(nil)
+	setCacheTTLs(map[string]time.Duration{"test.verify": time.Hour})
+	defer setCacheTTLs(nil)
+
+	var calls int32
+	release := make(chan struct{})
+	impl := cached("test.verify", func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// The first call lasts until its caller gives up on it.
+			<-bctx.Context.Done()
+			return nil, bctx.Context.Err()
+		}
+		<-release
+		return ast.BooleanTerm(true), nil
+	})
+	image := []*ast.Term{ast.StringTerm("registry.example.com/app:v1")}
+	waitInflight := func() {
+		for {
+			c.inflightMux.Lock()
+			n := len(c.inflight)
+			c.inflightMux.Unlock()
+			if n == 1 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := impl(rego.BuiltinContext{Context: first}, image)
+		firstErr <- err
+	}()
+	waitInflight()
+
+	type result struct {
+		term *ast.Term
+		err  error
+	}
+	waited := make(chan result, 1)
+	go func() {
+		term, err := impl(rego.BuiltinContext{Context: context.Background()}, image)
+		waited <- result{term: term, err: err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v for the cancelled call, want %v", err, context.Canceled)
+	}
+
+	// The waiter makes the call again rather than failing with the error of
+	// the cancelled caller, and gives up on it once its own deadline passes.
+	waitInflight()
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelDeadline()
+	if _, err := impl(rego.BuiltinContext{Context: deadline}, image); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v waiting past the deadline, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if got := <-waited; got.err != nil || !got.term.Equal(ast.BooleanTerm(true)) {
+		t.Errorf("got %v, %v for the waiting call, want true", got.term, got.err)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want the waiting call to be made again once", calls)
+	}
+}
+
+func TestCached_TTL(t *testing.T) {
+	c, err := newDiskCache(t.TempDir(), 10, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCache(c)
+	defer setCache(nil)
+
+	calls := 0
+	impl := cached("test.double", func(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		calls++
+		return terms[0], nil
+	})
+	call := func() {
+		if _, err := impl(rego.BuiltinContext{}, []*ast.Term{ast.IntNumberTerm(1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// test.double sets no CacheTTL, so it is only cached once given one.
+	call()
+	call()
+	if calls != 2 {
+		t.Errorf("got %d calls, want results not cached without a TTL", calls)
+	}
+	setCacheTTLs(map[string]time.Duration{"test.double": time.Hour})
+	defer setCacheTTLs(nil)
+	call()
+	call()
+	if calls != 3 {
+		t.Errorf("got %d calls, want results cached with --builtin-cache-ttl", calls)
+	}
+}
+
+func TestParseCacheTTLs(t *testing.T) {
+	got, err := parseCacheTTLs("test.double=24h, test.slow=0s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]time.Duration{"test.double": 24 * time.Hour, "test.slow": 0}, got); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, in := range []string{"test.double", "test.unknown=1h", "test.double=soon", "test.double=-1h"} {
+		if _, err := parseCacheTTLs(in); err == nil {
+			t.Errorf("got no error parsing %q", in)
+		}
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	a := ast.MustParseTerm(`{"image": "app", "keys": {"a", "b"}}`)
+	b := ast.MustParseTerm(`{"keys": {"b", "a"}, "image": "app"}`)
+	if cacheKey("test.verify", []*ast.Term{a}) != cacheKey("test.verify", []*ast.Term{b}) {
+		t.Error("got different keys for equal arguments")
+	}
+	if cacheKey("test.verify", []*ast.Term{a}) == cacheKey("test.other", []*ast.Term{a}) {
+		t.Error("got the same key for different builtins")
+	}
+}
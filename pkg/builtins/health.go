@@ -0,0 +1,70 @@
+package builtins
+
+import (
+	"sync"
+	"time"
+)
+
+// Health is the health of a custom builtin, such as one fetching data from an
+// external provider, as observed by its calls in this process. Calls answered
+// by the cache of --builtin-cache-dir, or sharing a concurrent call with the
+// same arguments, are not counted.
+type Health struct {
+	Name string
+	// Calls is the number of times the builtin was called.
+	Calls int64
+	// Errors is the number of calls which returned an error, timed out or
+	// panicked.
+	Errors int64
+	// LastError is the error of the last failed call.
+	LastError       string
+	LastErrorTime   time.Time
+	LastSuccessTime time.Time
+}
+
+// Healthy returns whether the last call of the builtin succeeded, or it was
+// never called.
+func (h Health) Healthy() bool {
+	return h.LastErrorTime.IsZero() || h.LastSuccessTime.After(h.LastErrorTime)
+}
+
+var (
+	healthMux sync.Mutex
+	health    = make(map[string]*Health)
+	now       = time.Now
+)
+
+// recordCall records that the builtin name returned err.
+func recordCall(name string, err error) {
+	healthMux.Lock()
+	defer healthMux.Unlock()
+	h, ok := health[name]
+	if !ok {
+		h = &Health{Name: name}
+		health[name] = h
+	}
+	h.Calls++
+	if err != nil {
+		h.Errors++
+		h.LastError = err.Error()
+		h.LastErrorTime = now()
+		return
+	}
+	h.LastSuccessTime = now()
+}
+
+// Healths returns the health of every custom builtin, sorted by name.
+func Healths() []Health {
+	names := Registered()
+	healthMux.Lock()
+	defer healthMux.Unlock()
+	healths := make([]Health, len(names))
+	for i, name := range names {
+		if h, ok := health[name]; ok {
+			healths[i] = *h
+		} else {
+			healths[i] = Health{Name: name}
+		}
+	}
+	return healths
+}
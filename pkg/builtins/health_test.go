@@ -0,0 +1,69 @@
+package builtins
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/pkg/faultinject"
+)
+
+func TestHealths(t *testing.T) {
+	healthMux.Lock()
+	health = make(map[string]*Health)
+	healthMux.Unlock()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	defer func() { now = time.Now }()
+
+	for _, query := range []string{"test.double(1)", "test.panic(1)", "test.panic(1)", "test.slow(1)", "test.double(2)"} {
+		_, _ = eval(t, query)
+	}
+
+	want := []Health{{
+		Name:            "test.double",
+		Calls:           2,
+		LastSuccessTime: start.Add(5 * time.Second),
+	}, {
+		Name:          "test.panic",
+		Calls:         2,
+		Errors:        2,
+		LastError:     "builtin test.panic panicked: boom",
+		LastErrorTime: start.Add(3 * time.Second),
+	}, {
+		Name:          "test.slow",
+		Calls:         1,
+		Errors:        1,
+		LastError:     "builtin test.slow: context deadline exceeded",
+		LastErrorTime: start.Add(4 * time.Second),
+	}}
+	got := Healths()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+	for i, healthy := range []bool{true, false, false} {
+		if got[i].Healthy() != healthy {
+			t.Errorf("got Healthy() %t for %s, want %t", got[i].Healthy(), got[i].Name, healthy)
+		}
+	}
+}
+
+func TestHealths_ProviderTimeout(t *testing.T) {
+	healthMux.Lock()
+	health = make(map[string]*Health)
+	healthMux.Unlock()
+	old := *faultinject.Get()
+	*faultinject.Get() = faultinject.Faults{ProviderTimeout: time.Millisecond}
+	defer func() { *faultinject.Get() = old }()
+
+	if _, err := eval(t, "test.double(1)"); err == nil {
+		t.Error("got no error calling a builtin with an injected provider timeout")
+	}
+	if got := Healths()[0]; got.Name != "test.double" || got.Errors != 1 || got.Healthy() {
+		t.Errorf("got %+v, want the injected timeout recorded", got)
+	}
+}
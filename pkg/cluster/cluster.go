@@ -0,0 +1,101 @@
+// Package cluster holds the labels identifying the cluster Gatekeeper runs in.
+//
+// Constraints, mutators and exceptions may set match.clusterSelector to select
+// clusters by these labels, so that a single set of policies can be
+// distributed across a fleet of clusters and scoped to some of them.
+package cluster
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var (
+	mux      sync.RWMutex
+	identity = labels.Set{}
+)
+
+func init() {
+	flag.Var(labelsFlag{}, "cluster-label", "(alpha) a label identifying the cluster, as key=value, which match.clusterSelector of constraints and mutators is matched against. This flag can be declared more than once.")
+}
+
+// labelsFlag adds the labels of each declaration of --cluster-label to the
+// identity of the cluster.
+type labelsFlag struct{}
+
+var _ flag.Value = labelsFlag{}
+
+func (labelsFlag) String() string {
+	return Labels().String()
+}
+
+func (labelsFlag) Set(s string) error {
+	parsed, err := labels.ConvertSelectorToLabelsMap(s)
+	if err != nil {
+		return fmt.Errorf("invalid cluster label %q: %w", s, err)
+	}
+	for k, v := range parsed {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return fmt.Errorf("invalid cluster label key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return fmt.Errorf("invalid cluster label value %q: %s", v, strings.Join(errs, "; "))
+		}
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	identity = labels.Merge(identity, parsed)
+	return nil
+}
+
+// Labels returns the labels identifying the cluster.
+func Labels() labels.Set {
+	mux.RLock()
+	defer mux.RUnlock()
+	return labels.Merge(labels.Set{}, identity)
+}
+
+// SetLabels replaces the labels identifying the cluster.
+func SetLabels(l labels.Set) {
+	mux.Lock()
+	defer mux.Unlock()
+	identity = labels.Merge(labels.Set{}, l)
+}
+
+// Selects returns whether selector selects the cluster. A nil selector selects
+// every cluster.
+func Selects(selector *metav1.LabelSelector) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(Labels()), nil
+}
+
+// SelectsConstraint returns whether spec.match.clusterSelector of constraint
+// selects the cluster.
+func SelectsConstraint(constraint *unstructured.Unstructured) (bool, error) {
+	raw, found, err := unstructured.NestedMap(constraint.Object, "spec", "match", "clusterSelector")
+	if err != nil {
+		return false, err
+	}
+	if !found || raw == nil {
+		return true, nil
+	}
+	selector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, selector); err != nil {
+		return false, fmt.Errorf("invalid spec.match.clusterSelector: %w", err)
+	}
+	return Selects(selector)
+}
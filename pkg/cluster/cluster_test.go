@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestLabelsFlag(t *testing.T) {
+	defer SetLabels(nil)
+	SetLabels(nil)
+
+	f := labelsFlag{}
+	if err := f.Set("env=prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("region=eu,tier=1"); err != nil {
+		t.Fatal(err)
+	}
+	want := labels.Set{"env": "prod", "region": "eu", "tier": "1"}
+	if got := Labels(); !labels.Equals(got, want) {
+		t.Errorf("got labels %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{"env", "env=a b", "-env=prod"} {
+		if err := f.Set(invalid); err == nil {
+			t.Errorf("got no error setting %q, want error", invalid)
+		}
+	}
+}
+
+func TestSelectsConstraint(t *testing.T) {
+	defer SetLabels(nil)
+	SetLabels(labels.Set{"env": "prod", "region": "eu"})
+
+	tcs := []struct {
+		name     string
+		selector interface{}
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name: "no selector",
+			want: true,
+		},
+		{
+			name:     "empty selector",
+			selector: map[string]interface{}{},
+			want:     true,
+		},
+		{
+			name:     "matching labels",
+			selector: map[string]interface{}{"matchLabels": map[string]interface{}{"env": "prod"}},
+			want:     true,
+		},
+		{
+			name:     "other labels",
+			selector: map[string]interface{}{"matchLabels": map[string]interface{}{"env": "dev"}},
+			want:     false,
+		},
+		{
+			name: "matching expression",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "region", "operator": "In", "values": []interface{}{"eu", "us"}},
+			}},
+			want: true,
+		},
+		{
+			name: "absent label",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "zone", "operator": "Exists"},
+			}},
+			want: false,
+		},
+		{
+			name: "invalid operator",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "zone", "operator": "Near"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:     "not an object",
+			selector: "env=prod",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			constraint := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"match": map[string]interface{}{}},
+			}}
+			if tc.selector != nil {
+				if err := unstructured.SetNestedField(constraint.Object, tc.selector, "spec", "match", "clusterSelector"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := SelectsConstraint(constraint)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
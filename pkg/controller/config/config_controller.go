@@ -23,9 +23,11 @@ import (
 	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
 	syncc "github.com/open-policy-agent/gatekeeper/pkg/controller/sync"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
 	"github.com/open-policy-agent/gatekeeper/pkg/keys"
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/override"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
 	"github.com/open-policy-agent/gatekeeper/pkg/target"
 	"github.com/open-policy-agent/gatekeeper/pkg/watch"
@@ -102,6 +104,7 @@ func newReconciler(mgr manager.Manager, opa syncc.OpaDataClient, wm *watch.Manag
 	watchSet := watch.NewSet()
 	filteredOpa := syncc.NewFilteredOpaDataClient(opa, watchSet)
 	syncMetricsCache := syncc.NewMetricsCache()
+	introspection.Get().SetSyncSource(syncMetricsCache.SyncedKinds)
 
 	syncAdder := syncc.Adder{
 		Opa:             filteredOpa,
@@ -223,6 +226,7 @@ func (r *ReconcileConfig) Reconcile(ctx context.Context, request reconcile.Reque
 	newSyncOnly := watch.NewSet()
 	newExcluder := process.New()
 	var statsEnabled bool
+	var overridable []configv1alpha1.OverridableConstraint
 	// If the config is being deleted the user is saying they don't want to
 	// sync anything
 	if exists && instance.GetDeletionTimestamp().IsZero() {
@@ -233,8 +237,12 @@ func (r *ReconcileConfig) Reconcile(ctx context.Context, request reconcile.Reque
 
 		newExcluder.Add(instance.Spec.Match)
 		statsEnabled = instance.Spec.Readiness.StatsEnabled
+		overridable = instance.Spec.Overrides.Overridable
 	}
 
+	// Constraints which are no longer overridable are enforced again at once.
+	override.Get().SetOverridable(overridable)
+
 	// Enable verbose readiness stats if requested.
 	if statsEnabled {
 		log.Info("enabling readiness stats")
@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // Process indicates the Gatekeeper component from which the resource will be excluded.
@@ -26,6 +27,9 @@ const (
 type Excluder struct {
 	mux                sync.RWMutex
 	excludedNamespaces map[Process]map[util.PrefixWildcard]bool
+	// indexes are the excludedNamespaces of each process, indexed so a
+	// namespace is matched without scanning them.
+	indexes map[Process]*util.NamespaceIndex
 }
 
 var allProcesses = []Process{
@@ -73,12 +77,28 @@ func (s *Excluder) Add(entry []configv1alpha1.MatchEntry) {
 			}
 		}
 	}
+	s.indexes = indexNamespaces(s.excludedNamespaces)
+}
+
+// indexNamespaces returns the NamespaceIndex of the excluded namespaces of
+// each process.
+func indexNamespaces(excluded map[Process]map[util.PrefixWildcard]bool) map[Process]*util.NamespaceIndex {
+	indexes := make(map[Process]*util.NamespaceIndex, len(excluded))
+	for p, namespaces := range excluded {
+		patterns := make([]string, 0, len(namespaces))
+		for ns := range namespaces {
+			patterns = append(patterns, string(ns))
+		}
+		indexes[p] = util.NewNamespaceIndex(patterns)
+	}
+	return indexes
 }
 
 func (s *Excluder) Replace(new *Excluder) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 	s.excludedNamespaces = new.excludedNamespaces
+	s.indexes = indexNamespaces(new.excludedNamespaces)
 }
 
 func (s *Excluder) Equals(new *Excluder) bool {
@@ -96,19 +116,28 @@ func (s *Excluder) IsNamespaceExcluded(process Process, obj runtime.Object) (boo
 		return false, errors.Wrapf(err, "Failed to get accessor for %s - %s", obj.GetObjectKind().GroupVersionKind().Group, obj.GetObjectKind().GroupVersionKind().Kind)
 	}
 
+	// Gatekeeper's own resources are excluded along with its namespace.
+	if IsSelf(process, obj.GetObjectKind().GroupVersionKind().GroupKind(), meta.GetNamespace(), meta.GetName()) {
+		return true, nil
+	}
+
 	if obj.GetObjectKind().GroupVersionKind().Kind == "Namespace" && obj.GetObjectKind().GroupVersionKind().Group == "" {
-		return exactOrPrefixMatch(s.excludedNamespaces[process], meta.GetName()), nil
+		return s.indexes[process].Matches(meta.GetName()), nil
 	}
 
-	return exactOrPrefixMatch(s.excludedNamespaces[process], meta.GetNamespace()), nil
+	return s.indexes[process].Matches(meta.GetNamespace()), nil
 }
 
-func exactOrPrefixMatch(boolMap map[util.PrefixWildcard]bool, ns string) bool {
-	for k := range boolMap {
-		if k.Matches(ns) {
-			return true
-		}
+// IsExcluded returns true if namespace is excluded from process. Unlike
+// IsNamespaceExcluded, it needs only the name of the namespace, so callers
+// which know it need not decode the object.
+func (s *Excluder) IsExcluded(process Process, namespace string) bool {
+	if IsSelf(process, schema.GroupKind{Kind: "Namespace"}, "", namespace) {
+		return true
 	}
 
-	return false
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.indexes[process].Matches(namespace)
 }
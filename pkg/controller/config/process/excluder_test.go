@@ -3,7 +3,12 @@ package process
 import (
 	"testing"
 
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
 )
 
 func TestExactOrPrefixMatch(t *testing.T) {
@@ -43,7 +48,11 @@ func TestExactOrPrefixMatch(t *testing.T) {
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			if exactOrPrefixMatch(tc.nsMap, tc.ns) != tc.excluded {
+			excluder := New()
+			for ns := range tc.nsMap {
+				excluder.Add([]configv1alpha1.MatchEntry{{ExcludedNamespaces: []util.PrefixWildcard{ns}, Processes: []string{"*"}}})
+			}
+			if excluder.IsExcluded(Webhook, tc.ns) != tc.excluded {
 				if tc.excluded {
 					t.Errorf("Expected ns '%v' to match map: %v", tc.ns, tc.nsMap)
 				} else {
@@ -53,3 +62,112 @@ func TestExactOrPrefixMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestExcluder_IsNamespaceExcluded(t *testing.T) {
+	excluder := New()
+	excluder.Add([]configv1alpha1.MatchEntry{
+		{ExcludedNamespaces: []util.PrefixWildcard{"kube-*"}, Processes: []string{"webhook"}},
+		{ExcludedNamespaces: []util.PrefixWildcard{"audit-excluded"}, Processes: []string{"audit"}},
+	})
+
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "kube-system"},
+	}
+	namespace := &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-public"},
+	}
+	for _, tc := range []struct {
+		name     string
+		process  Process
+		obj      *corev1.Pod
+		excluded bool
+	}{
+		{name: "excluded namespace", process: Webhook, obj: pod, excluded: true},
+		{name: "other process", process: Audit, obj: pod, excluded: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := excluder.IsNamespaceExcluded(tc.process, tc.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.excluded {
+				t.Errorf("got excluded %v, want %v", got, tc.excluded)
+			}
+		})
+	}
+
+	// Namespaces are matched by their name.
+	got, err := excluder.IsNamespaceExcluded(Webhook, namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Error("got Namespace not excluded, want it excluded")
+	}
+
+	// Replacing the exclusions replaces their index.
+	excluder.Replace(New())
+	if excluder.IsExcluded(Webhook, "kube-system") {
+		t.Error("got namespace excluded after replacing the exclusions")
+	}
+}
+
+func TestIsSelf(t *testing.T) {
+	namespace := schema.GroupKind{Kind: "Namespace"}
+	pod := schema.GroupKind{Kind: "Pod"}
+	crd := schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+	gkNamespace := util.GetNamespace()
+
+	tcs := []struct {
+		name string
+		// flag sets --exclude-gatekeeper-resources if not nil.
+		flag      *string
+		process   Process
+		kind      schema.GroupKind
+		namespace string
+		objName   string
+		want      bool
+	}{
+		{name: "pod in gatekeeper's namespace", process: Mutation, kind: pod, namespace: gkNamespace, objName: "gatekeeper-audit", want: true},
+		{name: "pod elsewhere", process: Mutation, kind: pod, namespace: "default", objName: "nginx"},
+		{name: "gatekeeper's namespace", process: Audit, kind: namespace, objName: gkNamespace, want: true},
+		{name: "other namespace", process: Audit, kind: namespace, objName: "default"},
+		{name: "gatekeeper crd", process: Webhook, kind: crd, objName: "k8srequiredlabels.constraints.gatekeeper.sh", want: true},
+		{name: "other crd", process: Webhook, kind: crd, objName: "certificates.cert-manager.io"},
+		{name: "sync is not excluded by default", process: Sync, kind: pod, namespace: gkNamespace},
+		{name: "every process", flag: pointer.StringPtr("*"), process: Sync, kind: pod, namespace: gkNamespace, want: true},
+		{name: "disabled", flag: pointer.StringPtr(""), process: Audit, kind: pod, namespace: gkNamespace},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.flag != nil {
+				defer func(old string) {
+					if err := selfExcluded.Set(old); err != nil {
+						t.Fatal(err)
+					}
+				}(selfExcluded.String())
+				if err := selfExcluded.Set(*tc.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := IsSelf(tc.process, tc.kind, tc.namespace, tc.objName); got != tc.want {
+				t.Errorf("got IsSelf() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelfExcludedFlag(t *testing.T) {
+	s := newProcessSet()
+	if err := s.Set("audit, webhook"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.String(); got != "audit,webhook" {
+		t.Errorf("got %q, want audit,webhook", got)
+	}
+	if err := s.Set("audit,validation"); err == nil {
+		t.Error("got no error setting an unknown process")
+	}
+}
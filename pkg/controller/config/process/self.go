@@ -0,0 +1,103 @@
+package process
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatekeeperGroupSuffix is the suffix of the API groups of Gatekeeper's
+// resources, including constraints.
+const gatekeeperGroupSuffix = ".gatekeeper.sh"
+
+// processSet is a flag holding a comma-separated list of processes.
+type processSet struct {
+	mux       sync.RWMutex
+	processes map[Process]bool
+}
+
+var _ flag.Value = &processSet{}
+
+func newProcessSet(processes ...Process) *processSet {
+	s := &processSet{processes: make(map[Process]bool)}
+	for _, p := range processes {
+		s.processes[p] = true
+	}
+	return s
+}
+
+func (s *processSet) String() string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var names []string
+	for p := range s.processes {
+		names = append(names, string(p))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (s *processSet) Set(value string) error {
+	processes := make(map[Process]bool)
+	for _, name := range strings.Split(value, ",") {
+		p := Process(strings.TrimSpace(name))
+		switch {
+		case p == "":
+		case p == Star:
+			for _, o := range allProcesses {
+				processes[o] = true
+			}
+		case isProcess(p):
+			processes[p] = true
+		default:
+			return fmt.Errorf("unknown process %q", p)
+		}
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.processes = processes
+	return nil
+}
+
+func (s *processSet) has(p Process) bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.processes[p]
+}
+
+func isProcess(p Process) bool {
+	for _, o := range allProcesses {
+		if p == o {
+			return true
+		}
+	}
+	return false
+}
+
+// selfExcluded are the processes which skip Gatekeeper's own resources.
+var selfExcluded = newProcessSet(Audit, Webhook, Mutation)
+
+func init() {
+	flag.Var(selfExcluded, "exclude-gatekeeper-resources", "(alpha) comma-separated processes, of audit, webhook, mutation-webhook and sync or * for all of them, which skip Gatekeeper's own resources: its namespace, the objects in it and its CustomResourceDefinitions. This keeps policies matching every object, such as mutators adding a sidecar to every pod, from restarting Gatekeeper. Set to an empty string to review them like any other object")
+}
+
+// IsSelf returns whether process skips the object of kind with namespace and
+// name as one of Gatekeeper's own resources.
+func IsSelf(process Process, kind schema.GroupKind, namespace, name string) bool {
+	if !selfExcluded.has(process) {
+		return false
+	}
+	gkNamespace := util.GetNamespace()
+	switch {
+	case kind.Group == "" && kind.Kind == "Namespace":
+		return name == gkNamespace
+	case kind.Group == "apiextensions.k8s.io" && kind.Kind == "CustomResourceDefinition":
+		return strings.HasSuffix(name, gatekeeperGroupSuffix)
+	}
+	return namespace != "" && namespace == gkNamespace
+}
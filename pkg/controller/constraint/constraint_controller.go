@@ -24,12 +24,20 @@ import (
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/constraints"
 	constraintstatusv1beta1 "github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/cluster"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/constraintstatus"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/matchedkinds"
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/operations"
+	"github.com/open-policy-agent/gatekeeper/pkg/paramlint"
+	"github.com/open-policy-agent/gatekeeper/pkg/policyfreeze"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/schedule"
+	"github.com/open-policy-agent/gatekeeper/pkg/statusaggregation"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"github.com/open-policy-agent/gatekeeper/pkg/watch"
 	corev1 "k8s.io/api/core/v1"
@@ -136,6 +144,9 @@ func newReconciler(
 		constraintsCache: constraintsCache,
 		tracker:          tracker,
 	}
+	if *statusaggregation.Enabled {
+		r.reports = statusaggregation.Get()
+	}
 	r.getPod = r.defaultGetPod
 	// default
 	r.assumeDeleted = func(schema.GroupVersionKind) bool { return false }
@@ -162,6 +173,11 @@ func add(mgr manager.Manager, r reconcile.Reconciler, events <-chan event.Generi
 		return err
 	}
 
+	if *statusaggregation.Enabled {
+		// Status is held in memory rather than in ConstraintPodStatus objects.
+		return nil
+	}
+
 	// Watch for changes to ConstraintStatus
 	err = c.Watch(
 		&source.Kind{Type: &constraintstatusv1beta1.ConstraintPodStatus{}},
@@ -192,6 +208,9 @@ type ReconcileConstraint struct {
 	// assumeDeleted allows us to short-circuit get requests
 	// that would otherwise trigger a watch
 	assumeDeleted func(schema.GroupVersionKind) bool
+	// reports holds the status of constraints in memory if set, instead of
+	// ConstraintPodStatus objects.
+	reports *statusaggregation.Reports
 }
 
 // +kubebuilder:rbac:groups=constraints.gatekeeper.sh,resources=*,verbs=get;list;watch;create;update;patch;delete
@@ -223,6 +242,12 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, nil
 	}
 
+	// Keep the loaded constraint while the policy is frozen.
+	if freeze := policyfreeze.Get(); freeze.Frozen() {
+		freeze.Defer(gvk.Kind + "/" + unpackedRequest.Name)
+		return reconcile.Result{RequeueAfter: policyfreeze.RetryInterval}, nil
+	}
+
 	deleted := false
 	instance := &unstructured.Unstructured{}
 	instance.SetGroupVersionKind(gvk)
@@ -273,6 +298,35 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 		status.Status.ConstraintUID = instance.GetUID()
 		status.Status.ObservedGeneration = instance.GetGeneration()
 		status.Status.Errors = nil
+		status.Status.Warnings = nil
+		if *paramlint.Enabled {
+			status.Status.Warnings = paramlint.Lint(instance)
+		}
+
+		status.Status.Frozen = policyfreeze.Annotated(instance)
+		if status.Status.Frozen {
+			// Keep the loaded version of the constraint, if there is one.
+			if _, err := r.opa.GetConstraint(ctx, instance); err == nil {
+				r.log.Info("constraint is frozen, keeping the loaded version", "kind", instance.GetKind(), "name", instance.GetName())
+				status.Status.Enforced = true
+				if err := r.writePodStatus(ctx, instance, status); err != nil {
+					return reconcile.Result{Requeue: true}, nil
+				}
+				return reconcile.Result{}, nil
+			}
+		}
+
+		// The labels of the cluster do not change while Gatekeeper runs, so
+		// constraints selecting other clusters are never added to OPA.
+		selected, err := cluster.SelectsConstraint(instance)
+		if err != nil {
+			status.Status.Errors = append(status.Status.Errors, constraintstatusv1beta1.Error{Message: err.Error()})
+		}
+		if !selected {
+			reportMetrics = true
+			return r.unselect(ctx, instance, status, constraintKey)
+		}
+
 		if c, err := r.opa.GetConstraint(ctx, instance); err != nil || !constraints.SemanticEqual(instance, c) {
 			if err := r.cacheConstraint(ctx, instance); err != nil {
 				r.constraintsCache.addConstraintKey(constraintKey, tags{
@@ -280,7 +334,7 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 					status:            metrics.ErrorStatus,
 				})
 				status.Status.Errors = append(status.Status.Errors, constraintstatusv1beta1.Error{Message: err.Error()})
-				if err2 := r.writer.Update(ctx, status); err2 != nil {
+				if err2 := r.writePodStatus(ctx, instance, status); err2 != nil {
 					log.Error(err2, "could not report constraint error status")
 				}
 				reportMetrics = true
@@ -290,7 +344,7 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 		}
 
 		status.Status.Enforced = true
-		if err = r.writer.Update(ctx, status); err != nil {
+		if err = r.writePodStatus(ctx, instance, status); err != nil {
 			return reconcile.Result{Requeue: true}, nil
 		}
 
@@ -300,6 +354,15 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 			status:            metrics.ActiveStatus,
 		})
 		reportMetrics = true
+		if err := matchedkinds.Get().Set(instance); err != nil {
+			log.Error(err, "could not record the kinds matched by constraint", "kind", instance.GetKind(), "name", instance.GetName())
+		}
+		introspection.Get().SetConstraint(introspection.Constraint{
+			Kind:              instance.GetKind(),
+			Name:              instance.GetName(),
+			Generation:        instance.GetGeneration(),
+			EnforcementAction: string(enforcementAction),
+		})
 	} else {
 		r.log.Info("handling constraint delete", "instance", instance)
 		if _, err := r.opa.RemoveConstraint(ctx, instance); err != nil {
@@ -315,7 +378,14 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 
 		r.constraintsCache.deleteConstraintKey(constraintKey)
 		reportMetrics = true
+		matchedkinds.Get().Remove(instance)
+		introspection.Get().RemoveConstraint(instance.GetKind(), instance.GetName())
+		schedule.Get().Remove(instance.GetKind(), instance.GetName())
 
+		if r.reports != nil {
+			r.reports.Remove(instance.GetKind(), instance.GetName())
+			return reconcile.Result{}, nil
+		}
 		sName, err := constraintstatusv1beta1.KeyForConstraint(util.GetPodName(), instance)
 		if err != nil {
 			return reconcile.Result{}, err
@@ -332,6 +402,30 @@ func (r *ReconcileConstraint) Reconcile(ctx context.Context, request reconcile.R
 	return reconcile.Result{}, nil
 }
 
+// unselect removes constraint, which does not select the cluster, from OPA
+// and reports it as not enforced.
+func (r *ReconcileConstraint) unselect(ctx context.Context, constraint *unstructured.Unstructured, status *constraintstatusv1beta1.ConstraintPodStatus, constraintKey string) (reconcile.Result, error) {
+	if _, err := r.opa.RemoveConstraint(ctx, constraint); err != nil {
+		if _, ok := err.(*opa.UnrecognizedConstraintError); !ok {
+			return reconcile.Result{}, err
+		}
+	}
+	r.log.Info("constraint does not select this cluster", "kind", constraint.GetKind(), "name", constraint.GetName())
+
+	// The constraint will never be added to OPA, so it is not waited for.
+	r.tracker.For(constraint.GroupVersionKind()).CancelExpect(constraint)
+
+	r.constraintsCache.deleteConstraintKey(constraintKey)
+	matchedkinds.Get().Remove(constraint)
+	introspection.Get().RemoveConstraint(constraint.GetKind(), constraint.GetName())
+
+	status.Status.Enforced = false
+	if err := r.writePodStatus(ctx, constraint, status); err != nil {
+		return reconcile.Result{Requeue: true}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
 func (r *ReconcileConstraint) defaultGetPod(_ context.Context) (*corev1.Pod, error) {
 	// require injection of GetPod in order to control what client we use to
 	// guarantee we don't inadvertently create a watch
@@ -339,6 +433,13 @@ func (r *ReconcileConstraint) defaultGetPod(_ context.Context) (*corev1.Pod, err
 }
 
 func (r *ReconcileConstraint) getOrCreatePodStatus(ctx context.Context, constraint *unstructured.Unstructured) (*constraintstatusv1beta1.ConstraintPodStatus, error) {
+	if r.reports != nil {
+		// The status is reported in full whenever it is written.
+		statusObj := &constraintstatusv1beta1.ConstraintPodStatus{}
+		statusObj.Status.ID = util.GetPodName()
+		statusObj.Status.Operations = operations.AssignedStringList()
+		return statusObj, nil
+	}
 	statusObj := &constraintstatusv1beta1.ConstraintPodStatus{}
 	sName, err := constraintstatusv1beta1.KeyForConstraint(util.GetPodName(), constraint)
 	if err != nil {
@@ -366,6 +467,15 @@ func (r *ReconcileConstraint) getOrCreatePodStatus(ctx context.Context, constrai
 	return statusObj, nil
 }
 
+// writePodStatus records the status of this pod for constraint.
+func (r *ReconcileConstraint) writePodStatus(ctx context.Context, constraint *unstructured.Unstructured, status *constraintstatusv1beta1.ConstraintPodStatus) error {
+	if r.reports != nil {
+		r.reports.Set(constraint.GetKind(), constraint.GetName(), status.Status)
+		return nil
+	}
+	return r.writer.Update(ctx, status)
+}
+
 func logAddition(l logr.Logger, constraint *unstructured.Unstructured, enforcementAction util.EnforcementAction) {
 	l.Info(
 		"constraint added to OPA",
@@ -25,14 +25,25 @@ import (
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	statusv1beta1 "github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/builtins"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/constraint"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/constraintstatus"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/constrainttemplatestatus"
+	"github.com/open-policy-agent/gatekeeper/pkg/election"
+	"github.com/open-policy-agent/gatekeeper/pkg/engine"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
 	"github.com/open-policy-agent/gatekeeper/pkg/operations"
+	"github.com/open-policy-agent/gatekeeper/pkg/policyfreeze"
+	"github.com/open-policy-agent/gatekeeper/pkg/prune"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/regocost"
+	"github.com/open-policy-agent/gatekeeper/pkg/schedule"
+	"github.com/open-policy-agent/gatekeeper/pkg/selftest"
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
+	"github.com/open-policy-agent/gatekeeper/pkg/statusaggregation"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"github.com/open-policy-agent/gatekeeper/pkg/watch"
 	"github.com/open-policy-agent/opa/ast"
@@ -40,6 +51,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -73,17 +85,22 @@ type Adder struct {
 	ControllerSwitch *watch.ControllerSwitch
 	Tracker          *readiness.Tracker
 	GetPod           func(context.Context) (*corev1.Pod, error)
+	EngineSelector   engine.Selector
 }
 
 // Add creates a new ConstraintTemplate Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func (a *Adder) Add(mgr manager.Manager) error {
+	if err := validateOrphanPolicy(*orphanPolicy); err != nil {
+		return err
+	}
 	// events will be used to receive events from dynamic watches registered
 	events := make(chan event.GenericEvent, 1024)
 	r, err := newReconciler(mgr, a.Opa, a.WatchManager, a.ControllerSwitch, a.Tracker, events, events, a.GetPod)
 	if err != nil {
 		return err
 	}
+	r.engines = a.EngineSelector
 	return add(mgr, r)
 }
 
@@ -109,6 +126,10 @@ func (a *Adder) InjectGetPod(getPod func(context.Context) (*corev1.Pod, error))
 
 func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {}
 
+func (a *Adder) InjectEngineSelector(s engine.Selector) {
+	a.EngineSelector = s
+}
+
 // newReconciler returns a new reconcile.Reconciler
 // cstrEvents is the channel from which constraint controller will receive the events
 // regEvents is the channel registered by Registrar to put the events in
@@ -133,25 +154,39 @@ func newReconciler(mgr manager.Manager, opa *opa.Client, wm *watch.Manager, cs *
 	}
 
 	if operations.IsAssigned(operations.Status) {
-		// statusEvents will be used to receive events from dynamic watches registered
-		// via the registrar below.
-		statusEvents := make(chan event.GenericEvent, 1024)
-		csAdder := constraintstatus.Adder{
-			Opa:              opa,
-			WatchManager:     wm,
-			ControllerSwitch: cs,
-			Events:           statusEvents,
-		}
-		if err := csAdder.Add(mgr); err != nil {
+		statusMgr, err := election.Manager(mgr, election.Status)
+		if err != nil {
 			return nil, err
 		}
+		if *statusaggregation.Enabled {
+			aggregator, err := statusaggregation.New(mgr)
+			if err != nil {
+				return nil, err
+			}
+			if err := statusMgr.Add(aggregator); err != nil {
+				return nil, err
+			}
+		} else {
+			// statusEvents will be used to receive events from dynamic watches registered
+			// via the registrar below.
+			statusEvents := make(chan event.GenericEvent, 1024)
+			csAdder := constraintstatus.Adder{
+				Opa:              opa,
+				WatchManager:     wm,
+				ControllerSwitch: cs,
+				Events:           statusEvents,
+			}
+			if err := csAdder.Add(statusMgr); err != nil {
+				return nil, err
+			}
+		}
 
 		ctsAdder := constrainttemplatestatus.Adder{
 			Opa:              opa,
 			WatchManager:     wm,
 			ControllerSwitch: cs,
 		}
-		if err := ctsAdder.Add(mgr); err != nil {
+		if err := ctsAdder.Add(statusMgr); err != nil {
 			return nil, err
 		}
 	}
@@ -233,6 +268,7 @@ type ReconcileConstraintTemplate struct {
 	metrics       *reporter
 	tracker       *readiness.Tracker
 	getPod        func(context.Context) (*corev1.Pod, error)
+	engines       engine.Selector
 }
 
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;create;update;patch;delete
@@ -253,6 +289,12 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 		}
 	}
 
+	// Keep the loaded template while the policy is frozen.
+	if freeze := policyfreeze.Get(); freeze.Frozen() {
+		freeze.Defer("ConstraintTemplate/" + request.Name)
+		return reconcile.Result{RequeueAfter: policyfreeze.RetryInterval}, nil
+	}
+
 	defer r.metrics.registry.report(ctx, r.metrics)
 
 	// Fetch the ConstraintTemplate instance
@@ -279,6 +321,23 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 		}
 	}
 
+	if !deleted {
+		if err := r.syncOrphansFinalizer(ctx, ct); err != nil {
+			log.Error(err, "update error")
+			return reconcile.Result{Requeue: true}, nil
+		}
+	} else if containsString(orphansFinalizerName, ct.GetFinalizers()) {
+		// The constraints of the template are handled before it is removed.
+		proceed, err := r.handleOrphans(ctx, ct)
+		if err != nil {
+			log.Error(err, "orphan handling error")
+			return reconcile.Result{}, err
+		}
+		if !proceed {
+			return reconcile.Result{RequeueAfter: orphansRequeueInterval}, nil
+		}
+	}
+
 	if deleted {
 		ctRef := &templates.ConstraintTemplate{}
 		ctRef.SetNamespace(request.Namespace)
@@ -289,6 +348,7 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 			log.Info("missing constraint template in OPA cache, no deletion necessary")
 			logAction(ctRef, deletedAction)
 			r.metrics.registry.remove(request.NamespacedName)
+			introspection.Get().RemoveTemplate(request.Name)
 		} else {
 			result, err = r.handleDelete(ctx, ctUnversioned)
 			if err != nil {
@@ -299,6 +359,7 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 			} else if !result.Requeue {
 				logAction(ct, deletedAction)
 				r.metrics.registry.remove(request.NamespacedName)
+				introspection.Get().RemoveTemplate(request.Name)
 			}
 		}
 		err = r.deleteAllStatus(ctx, request.Name)
@@ -314,6 +375,21 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 	status.Status.ObservedGeneration = ct.GetGeneration()
 	status.Status.Errors = nil
 
+	status.Status.Frozen = policyfreeze.Annotated(ct)
+	if status.Status.Frozen {
+		// Keep the loaded version of the template, if there is one.
+		ctRef := &templates.ConstraintTemplate{}
+		ctRef.SetName(ct.GetName())
+		if _, err := r.opa.GetTemplate(ctx, ctRef); err == nil {
+			log.Info("constraint template is frozen, keeping the loaded version")
+			if err := r.Update(ctx, status); err != nil {
+				log.Error(err, "update error")
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, nil
+		}
+	}
+
 	unversionedCT := &templates.ConstraintTemplate{}
 	if err := r.scheme.Convert(ct, unversionedCT, nil); err != nil {
 		log.Error(err, "conversion error")
@@ -321,12 +397,14 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 		logError(request.NamespacedName.Name)
 		return reconcile.Result{}, err
 	}
+	status.Status.Cost = estimateCost(unversionedCT)
 
 	unversionedProposedCRD, err := r.opa.CreateCRD(ctx, unversionedCT)
 	if err != nil {
 		log.Error(err, "CRD creation error")
 		r.tracker.TryCancelTemplate(unversionedCT) // Don't track templates that failed compilation
 		r.metrics.registry.add(request.NamespacedName, metrics.ErrorStatus)
+		setIntrospectionState(ct, err)
 		var createErr *v1beta1.CreateCRDError
 		if parseErrs, ok := err.(ast.Errors); ok {
 			for i := 0; i < len(parseErrs); i++ {
@@ -351,13 +429,23 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 		log.Error(err, "CRD conversion error")
 		r.tracker.TryCancelTemplate(unversionedCT) // Don't track templates that failed compilation
 		r.metrics.registry.add(request.NamespacedName, metrics.ErrorStatus)
+		setIntrospectionState(ct, err)
 		logError(request.NamespacedName.Name)
 		err := r.reportErrorOnCTStatus(ctx, "conversion_error", "Could not convert from unversioned resource", status, err)
 		return reconcile.Result{}, err
 	}
+	if err := addSpecFields(proposedCRD); err != nil {
+		log.Error(err, "CRD schema error")
+		r.tracker.TryCancelTemplate(unversionedCT)
+		r.metrics.registry.add(request.NamespacedName, metrics.ErrorStatus)
+		setIntrospectionState(ct, err)
+		logError(request.NamespacedName.Name)
+		err := r.reportErrorOnCTStatus(ctx, "schema_error", "Could not add Gatekeeper fields to the constraint schema", status, err)
+		return reconcile.Result{}, err
+	}
 
-	name := unversionedProposedCRD.GetName()
-	namespace := unversionedProposedCRD.GetNamespace()
+	name := proposedCRD.GetName()
+	namespace := proposedCRD.GetNamespace()
 	// Check if the constraint CRD already exists
 	action := updatedAction
 	currentCRD := &apiextensionsv1.CustomResourceDefinition{}
@@ -382,13 +470,49 @@ func (r *ReconcileConstraintTemplate) Reconcile(ctx context.Context, request rec
 		log.Error(err, "update error")
 		logError(request.NamespacedName.Name)
 		r.metrics.registry.add(request.NamespacedName, metrics.ErrorStatus)
+		setIntrospectionState(ct, err)
 	} else if !result.Requeue {
 		logAction(ct, action)
 		r.metrics.registry.add(request.NamespacedName, metrics.ActiveStatus)
+		setIntrospectionState(ct, nil)
 	}
 	return result, err
 }
 
+// setIntrospectionState records the outcome of ingesting ct, which failed if err is non-nil.
+func setIntrospectionState(ct *v1beta1.ConstraintTemplate, err error) {
+	t := introspection.Template{
+		Name:       ct.GetName(),
+		Kind:       ct.Spec.CRD.Spec.Names.Kind,
+		Generation: ct.GetGeneration(),
+		Status:     introspection.TemplateActive,
+	}
+	if err != nil {
+		t.Status = introspection.TemplateError
+		t.Error = err.Error()
+	}
+	introspection.Get().SetTemplate(t)
+}
+
+// estimateCost returns the estimated cost of evaluating the Rego of ct, or nil
+// if its Rego does not parse, which is reported when creating its CRD.
+func estimateCost(ct *templates.ConstraintTemplate) *statusv1beta1.CostEstimate {
+	e, err := regocost.EstimateTemplate(ct)
+	if err != nil {
+		return nil
+	}
+	cost := &statusv1beta1.CostEstimate{Class: string(e.Class)}
+	for _, f := range e.Findings {
+		cost.Findings = append(cost.Findings, statusv1beta1.CostFinding{
+			Code:     f.Code,
+			Class:    string(f.Class),
+			Message:  f.Message,
+			Location: f.Location,
+		})
+	}
+	return cost
+}
+
 func (r *ReconcileConstraintTemplate) reportErrorOnCTStatus(ctx context.Context, code, message string, status *statusv1beta1.ConstraintTemplatePodStatus, err error) error {
 	status.Status.Errors = []*v1beta1.CreateCRDError{}
 	createErr := &v1beta1.CreateCRDError{
@@ -415,6 +539,36 @@ func (r *ReconcileConstraintTemplate) handleUpdate(
 	log.Info("loading code into OPA")
 	beginCompile := time.Now()
 
+	if err := builtins.Validate(unversionedCT); err != nil {
+		err := r.reportErrorOnCTStatus(ctx, "ingest_error", "Template calls builtins it may not", status, err)
+		r.tracker.TryCancelTemplate(unversionedCT)
+		return reconcile.Result{}, err
+	}
+
+	if r.engines != nil {
+		// Engines are selected before the template is compiled, so that it is
+		// compiled by the engine it prefers.
+		if err := r.engines.Select(ctx, unversionedCT); err != nil {
+			err := r.reportErrorOnCTStatus(ctx, "ingest_error", "Could not select evaluation engine", status, err)
+			r.tracker.TryCancelTemplate(unversionedCT)
+			return reconcile.Result{}, err
+		}
+	}
+
+	if *selftest.Enabled {
+		// Templates are tested before they are activated, so that a template
+		// whose tests fail never enforces its constraints.
+		if err := r.runSelfTests(ctx, ct, unversionedCT); err != nil {
+			err := r.reportErrorOnCTStatus(ctx, "self_test_error", "Template failed its tests", status, err)
+			r.tracker.TryCancelTemplate(unversionedCT)
+			return reconcile.Result{}, err
+		}
+	}
+
+	// The paths templates declare they read are recorded before their Rego
+	// is compiled, so that objects are never pruned of fields they read.
+	prune.Get().Declare(unversionedCT)
+
 	// It's important that opa.AddTemplate() is called first. That way we can
 	// rely on a template's existence in OPA to know whether a watch needs
 	// to be removed
@@ -474,6 +628,21 @@ func (r *ReconcileConstraintTemplate) handleUpdate(
 	return reconcile.Result{}, nil
 }
 
+// runSelfTests runs the tests embedded in ct. The typed template does not hold
+// them, so ct is read again as unstructured.
+func (r *ReconcileConstraintTemplate) runSelfTests(ctx context.Context, ct *v1beta1.ConstraintTemplate, unversionedCT *templates.ConstraintTemplate) error {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind("ConstraintTemplate"))
+	if err := r.Get(ctx, types.NamespacedName{Name: ct.GetName()}, u); err != nil {
+		return err
+	}
+	tests, err := selftest.Read(u)
+	if err != nil {
+		return err
+	}
+	return selftest.Run(ctx, unversionedCT, tests)
+}
+
 func (r *ReconcileConstraintTemplate) handleDelete(
 	ctx context.Context,
 	ct *templates.ConstraintTemplate) (reconcile.Result, error) {
@@ -631,3 +800,12 @@ func removeString(s string, items []string) []string {
 	}
 	return rval
 }
+
+// addSpecFields adds the schemas of the constraint spec fields Gatekeeper
+// reads, which the framework does not know, to crd.
+func addSpecFields(crd *apiextensionsv1.CustomResourceDefinition) error {
+	if err := schedule.AddToCRD(crd); err != nil {
+		return err
+	}
+	return severity.AddToCRD(crd)
+}
@@ -0,0 +1,220 @@
+package constrainttemplate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/controller/constraint"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// OrphanPolicy is what happens to the constraints of a ConstraintTemplate when
+// the template is deleted.
+type OrphanPolicy string
+
+const (
+	// Block keeps the template, and enforces its constraints, until its
+	// constraints are deleted.
+	Block OrphanPolicy = "block"
+	// Cascade deletes the constraints of the template before the template.
+	Cascade OrphanPolicy = "cascade"
+	// Abandon keeps the constraint CRD and its constraints when the template is
+	// deleted. They are no longer enforced.
+	Abandon OrphanPolicy = "abandon"
+)
+
+// OrphanPolicies are the valid values of --constraint-template-orphan-policy.
+var OrphanPolicies = []OrphanPolicy{Block, Cascade, Abandon}
+
+const (
+	// orphansFinalizerName holds the deletion of a template until its
+	// constraints are handled according to the orphan policy.
+	orphansFinalizerName = "templates.gatekeeper.sh/constraints"
+
+	// orphansRequeueInterval is how often the constraints of a template whose
+	// deletion is held are listed again.
+	orphansRequeueInterval = 5 * time.Second
+
+	// maxListedOrphans is the number of remaining constraints named in the
+	// status of a template.
+	maxListedOrphans = 10
+)
+
+var orphanPolicy = flag.String("constraint-template-orphan-policy", "", fmt.Sprintf("(alpha) what happens to the constraints of a ConstraintTemplate when it is deleted. One of %v. If set, templates are given a finalizer which holds their deletion until their constraints are handled. Disabled if empty", OrphanPolicies))
+
+// validateOrphanPolicy returns an error if p is neither empty nor one of
+// OrphanPolicies.
+func validateOrphanPolicy(p string) error {
+	if p == "" {
+		return nil
+	}
+	for _, policy := range OrphanPolicies {
+		if OrphanPolicy(p) == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --constraint-template-orphan-policy %q, must be one of %v", p, OrphanPolicies)
+}
+
+// syncOrphansFinalizer adds the orphans finalizer to ct if an orphan policy is
+// set, and removes it otherwise so that templates are not held once the
+// policy is unset.
+func (r *ReconcileConstraintTemplate) syncOrphansFinalizer(ctx context.Context, ct *v1beta1.ConstraintTemplate) error {
+	has := containsString(orphansFinalizerName, ct.GetFinalizers())
+	switch {
+	case *orphanPolicy != "" && !has:
+		ct.SetFinalizers(append(ct.GetFinalizers(), orphansFinalizerName))
+	case *orphanPolicy == "" && has:
+		ct.SetFinalizers(removeString(orphansFinalizerName, ct.GetFinalizers()))
+	default:
+		return nil
+	}
+	return r.Update(ctx, ct)
+}
+
+// handleOrphans handles the constraints of ct, which is being deleted,
+// according to the orphan policy. It returns whether the deletion of ct may
+// proceed, in which case the orphans finalizer has been removed.
+func (r *ReconcileConstraintTemplate) handleOrphans(ctx context.Context, ct *v1beta1.ConstraintTemplate) (bool, error) {
+	log := log.WithValues("name", ct.GetName(), "orphan_policy", *orphanPolicy)
+
+	switch OrphanPolicy(*orphanPolicy) {
+	case Block, Cascade:
+		remaining, err := r.listConstraints(ctx, ct)
+		if err != nil {
+			return false, err
+		}
+		if len(remaining) != 0 {
+			if OrphanPolicy(*orphanPolicy) == Cascade {
+				log.Info("deleting the constraints of the template", "count", len(remaining))
+				if err := r.deleteConstraints(ctx, remaining); err != nil {
+					return false, err
+				}
+			} else {
+				log.Info("template deletion blocked by its constraints", "count", len(remaining))
+			}
+			return false, r.reportOrphans(ctx, ct, remaining)
+		}
+	case Abandon:
+		log.Info("abandoning the constraint CRD of the template")
+		if err := r.abandonCRD(ctx, ct); err != nil {
+			return false, err
+		}
+	}
+
+	ct.SetFinalizers(removeString(orphansFinalizerName, ct.GetFinalizers()))
+	if err := r.Update(ctx, ct); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// listConstraints returns the constraints of ct.
+func (r *ReconcileConstraintTemplate) listConstraints(ctx context.Context, ct *v1beta1.ConstraintTemplate) ([]unstructured.Unstructured, error) {
+	gvk := makeGvk(ct.Spec.CRD.Spec.Names.Kind)
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// deleteConstraints deletes constraints, first removing the finalizer which
+// older versions of Gatekeeper added to them so they are not held.
+func (r *ReconcileConstraintTemplate) deleteConstraints(ctx context.Context, constraints []unstructured.Unstructured) error {
+	for i := range constraints {
+		c := &constraints[i]
+		if constraint.HasFinalizer(c) {
+			constraint.RemoveFinalizer(c)
+			if err := r.Update(ctx, c); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		if !c.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if err := r.Delete(ctx, c); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportOrphans records the constraints which hold the deletion of ct in the
+// status of this pod for ct.
+func (r *ReconcileConstraintTemplate) reportOrphans(ctx context.Context, ct *v1beta1.ConstraintTemplate, remaining []unstructured.Unstructured) error {
+	status, err := r.getOrCreatePodStatus(ctx, ct.GetName())
+	if err != nil {
+		return err
+	}
+	status.Status.Errors = []*v1beta1.CreateCRDError{{
+		Code:    "constraints_remain",
+		Message: orphansMessage(OrphanPolicy(*orphanPolicy), ct.Spec.CRD.Spec.Names.Kind, remaining),
+	}}
+	return r.Update(ctx, status)
+}
+
+// orphansMessage describes the constraints of kind remaining under policy.
+func orphansMessage(policy OrphanPolicy, kind string, remaining []unstructured.Unstructured) string {
+	var names []string
+	for _, c := range remaining {
+		names = append(names, c.GetName())
+	}
+	sort.Strings(names)
+	if len(names) > maxListedOrphans {
+		names = append(names[:maxListedOrphans], fmt.Sprintf("and %d more", len(remaining)-maxListedOrphans))
+	}
+
+	action := "deleted"
+	if policy == Cascade {
+		action = "deleted by Gatekeeper"
+	}
+	return fmt.Sprintf("deletion is waiting for %d %s constraints to be %s: %s",
+		len(remaining), kind, action, strings.Join(names, ", "))
+}
+
+// abandonCRD removes the reference from the constraint CRD of ct to ct, so
+// that the CRD and its constraints are not garbage collected with ct.
+func (r *ReconcileConstraintTemplate) abandonCRD(ctx context.Context, ct *v1beta1.ConstraintTemplate) error {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	gvk := makeGvk(ct.Spec.CRD.Spec.Names.Kind)
+	name := strings.ToLower(gvk.Kind) + "." + gvk.Group
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, crd); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	refs, removed := withoutOwner(crd.GetOwnerReferences(), ct.GetUID())
+	if !removed {
+		return nil
+	}
+	crd.SetOwnerReferences(refs)
+	return r.Update(ctx, crd)
+}
+
+// withoutOwner returns refs without references to the owner with uid, and
+// whether there were any.
+func withoutOwner(refs []metav1.OwnerReference, uid types.UID) ([]metav1.OwnerReference, bool) {
+	var kept []metav1.OwnerReference
+	for _, ref := range refs {
+		if ref.UID != uid {
+			kept = append(kept, ref)
+		}
+	}
+	return kept, len(kept) != len(refs)
+}
@@ -0,0 +1,84 @@
+package constrainttemplate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateOrphanPolicy(t *testing.T) {
+	for _, p := range []string{"", "block", "cascade", "abandon"} {
+		if err := validateOrphanPolicy(p); err != nil {
+			t.Errorf("got error %v for %q, want nil", err, p)
+		}
+	}
+	for _, p := range []string{"Block", "orphan", "delete"} {
+		if err := validateOrphanPolicy(p); err == nil {
+			t.Errorf("got no error for %q, want error", p)
+		}
+	}
+}
+
+func TestOrphansMessage(t *testing.T) {
+	constraints := func(n int) []unstructured.Unstructured {
+		var result []unstructured.Unstructured
+		for i := n - 1; i >= 0; i-- {
+			u := unstructured.Unstructured{}
+			u.SetName(fmt.Sprintf("c%02d", i))
+			result = append(result, u)
+		}
+		return result
+	}
+
+	tcs := []struct {
+		name      string
+		policy    OrphanPolicy
+		remaining []unstructured.Unstructured
+		want      string
+	}{
+		{
+			name:      "block",
+			policy:    Block,
+			remaining: constraints(2),
+			want:      "deletion is waiting for 2 K8sRequiredLabels constraints to be deleted: c00, c01",
+		},
+		{
+			name:      "cascade",
+			policy:    Cascade,
+			remaining: constraints(1),
+			want:      "deletion is waiting for 1 K8sRequiredLabels constraints to be deleted by Gatekeeper: c00",
+		},
+		{
+			name:      "truncated",
+			policy:    Block,
+			remaining: constraints(12),
+			want:      "deletion is waiting for 12 K8sRequiredLabels constraints to be deleted: c00, c01, c02, c03, c04, c05, c06, c07, c08, c09, and 2 more",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := orphansMessage(tc.policy, "K8sRequiredLabels", tc.remaining); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithoutOwner(t *testing.T) {
+	refs := []metav1.OwnerReference{{Name: "template", UID: "a"}, {Name: "other", UID: "b"}}
+
+	got, removed := withoutOwner(refs, "a")
+	if !removed {
+		t.Error("got not removed, want removed")
+	}
+	if diff := cmp.Diff([]metav1.OwnerReference{{Name: "other", UID: "b"}}, got); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, removed := withoutOwner(refs, "c"); removed {
+		t.Error("got removed for an absent owner, want not removed")
+	}
+}
@@ -24,7 +24,10 @@ import (
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	podstatus "github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/engine"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/openapi"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"github.com/open-policy-agent/gatekeeper/pkg/watch"
@@ -56,6 +59,18 @@ type GetProcessExcluderInjector interface {
 	InjectProcessExcluder(processExcluder *process.Excluder)
 }
 
+type ExpansionSystemInjector interface {
+	InjectExpansionSystem(expansionSystem *expansion.System)
+}
+
+type EngineSelectorInjector interface {
+	InjectEngineSelector(s engine.Selector)
+}
+
+type SchemaValidatorInjector interface {
+	InjectSchemaValidator(v *openapi.Validator)
+}
+
 // Injectors is a list of adder structs that need injection. We can convert this
 // to an interface once we create controllers for things like data sync.
 var Injectors []Injector
@@ -72,6 +87,14 @@ type Dependencies struct {
 	GetPod           func(context.Context) (*corev1.Pod, error)
 	ProcessExcluder  *process.Excluder
 	MutationSystem   *mutation.System
+	ExpansionSystem  *expansion.System
+	EngineSelector   engine.Selector
+	// SchemaValidator, if set, validates mutators against the schemas of the
+	// kinds they mutate before they are ingested.
+	SchemaValidator *openapi.Validator
+	// Restore, if set, is called once Opa has been reset and before any
+	// controller is added, to restore the policy of another pod.
+	Restore func(context.Context) error
 }
 
 type defaultPodGetter struct {
@@ -124,6 +147,11 @@ func AddToManager(ctx context.Context, m manager.Manager, deps Dependencies) err
 	if err := deps.Opa.Reset(ctx); err != nil {
 		return err
 	}
+	if deps.Restore != nil {
+		if err := deps.Restore(ctx); err != nil {
+			return err
+		}
+	}
 	if deps.GetPod == nil {
 		podGetter := &defaultPodGetter{
 			scheme: m.GetScheme(),
@@ -156,6 +184,15 @@ func AddToManager(ctx context.Context, m manager.Manager, deps Dependencies) err
 		if a2, ok := a.(GetProcessExcluderInjector); ok {
 			a2.InjectProcessExcluder(deps.ProcessExcluder)
 		}
+		if a2, ok := a.(ExpansionSystemInjector); ok {
+			a2.InjectExpansionSystem(deps.ExpansionSystem)
+		}
+		if a2, ok := a.(EngineSelectorInjector); ok && deps.EngineSelector != nil {
+			a2.InjectEngineSelector(deps.EngineSelector)
+		}
+		if a2, ok := a.(SchemaValidatorInjector); ok && deps.SchemaValidator != nil {
+			a2.InjectSchemaValidator(deps.SchemaValidator)
+		}
 		if err := a.Add(m); err != nil {
 			return err
 		}
@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package exception
+
+import (
+	"context"
+
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	log = logf.Log.WithName("controller").WithValues(logging.Process, "exception_controller")
+	gvk = exceptionsv1alpha1.GroupVersion.WithKind("Exception")
+)
+
+type Adder struct {
+	// Tracker accepts a handle for the readiness tracker
+	Tracker *readiness.Tracker
+}
+
+// Add creates a new Exception Controller and adds it to the Manager. The
+// Manager will set fields on the Controller and Start it when the Manager is
+// Started.
+func (a *Adder) Add(mgr manager.Manager) error {
+	if !*exception.Enabled {
+		return nil
+	}
+
+	r := &Reconciler{
+		reader:  mgr.GetCache(),
+		system:  exception.Get(),
+		tracker: a.Tracker.For(gvk),
+	}
+	c, err := controller.New("exception-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &exceptionsv1alpha1.Exception{}},
+		&handler.EnqueueRequestForObject{})
+}
+
+func (a *Adder) InjectOpa(o *opa.Client) {}
+
+func (a *Adder) InjectWatchManager(w *watch.Manager) {}
+
+func (a *Adder) InjectControllerSwitch(cs *watch.ControllerSwitch) {}
+
+func (a *Adder) InjectTracker(t *readiness.Tracker) {
+	a.Tracker = t
+}
+
+func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {}
+
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler keeps the Exceptions of an exception System in sync with the
+// cluster, and reports them to the readiness tracker once ingested.
+type Reconciler struct {
+	reader  client.Reader
+	system  *exception.System
+	tracker readiness.Expectations
+}
+
+// +kubebuilder:rbac:groups=exceptions.gatekeeper.sh,resources=*,verbs=get;list;watch;update;patch
+
+// Reconcile upserts the Exception into the exception System, or removes it if
+// it was deleted.
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	e := &exceptionsv1alpha1.Exception{}
+	if err := r.reader.Get(ctx, request.NamespacedName, e); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		log.Info("removing Exception", "name", request.Name)
+		r.system.Remove(request.Name)
+		r.cancelExpect(request.Name)
+		return reconcile.Result{}, nil
+	}
+	// Readiness is keyed by kind, which typed objects read from the cache lack.
+	e.SetGroupVersionKind(gvk)
+	if !e.GetDeletionTimestamp().IsZero() {
+		log.Info("removing Exception", "name", request.Name)
+		r.system.Remove(request.Name)
+		r.tracker.CancelExpect(e)
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.system.Upsert(e); err != nil {
+		// The Exception is invalid, so retrying will not help. Stop exempting
+		// with the previous version, failing closed.
+		log.Error(err, "invalid Exception", "name", request.Name)
+		r.system.Remove(request.Name)
+		r.tracker.CancelExpect(e)
+		return reconcile.Result{}, nil
+	}
+	log.Info("upserted Exception", "name", request.Name, "expiresAt", e.Spec.ExpiresAt)
+	r.tracker.Observe(e)
+	return reconcile.Result{}, nil
+}
+
+// cancelExpect stops the readiness tracker expecting the deleted Exception
+// name.
+func (r *Reconciler) cancelExpect(name string) {
+	e := &exceptionsv1alpha1.Exception{}
+	e.SetName(name)
+	e.SetGroupVersionKind(gvk)
+	r.tracker.CancelExpect(e)
+}
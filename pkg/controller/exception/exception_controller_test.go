@@ -0,0 +1,117 @@
+package exception
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeExceptions serves Exceptions to the readiness tracker and the
+// Reconciler, and nothing else.
+type fakeExceptions struct {
+	exceptions map[string]*exceptionsv1alpha1.Exception
+}
+
+func (f *fakeExceptions) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	e, ok := f.exceptions[key.Name]
+	if !ok {
+		return apierrors.NewNotFound(exceptionsv1alpha1.GroupVersion.WithResource("exceptions").GroupResource(), key.Name)
+	}
+	e.DeepCopyInto(obj.(*exceptionsv1alpha1.Exception))
+	return nil
+}
+
+func (f *fakeExceptions) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	if l, ok := list.(*exceptionsv1alpha1.ExceptionList); ok {
+		for _, e := range f.exceptions {
+			l.Items = append(l.Items, *e.DeepCopy())
+		}
+	}
+	return nil
+}
+
+func newException(name, reason string) *exceptionsv1alpha1.Exception {
+	return &exceptionsv1alpha1.Exception{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: exceptionsv1alpha1.ExceptionSpec{
+			Constraints:   []exceptionsv1alpha1.ConstraintReference{{Kind: "K8sRequiredLabels"}},
+			ExpiresAt:     metav1.NewTime(time.Now().Add(time.Hour)),
+			Justification: exceptionsv1alpha1.Justification{Reason: reason},
+		},
+	}
+}
+
+func TestReconcile_Readiness(t *testing.T) {
+	tcs := []struct {
+		name      string
+		exception *exceptionsv1alpha1.Exception
+		// deleted is whether the Exception is deleted once expected.
+		deleted bool
+	}{
+		{
+			name:      "ingested",
+			exception: newException("legacy-apps", "migrating"),
+		},
+		{
+			name:      "invalid",
+			exception: newException("legacy-apps", ""),
+		},
+		{
+			name:      "deleted",
+			exception: newException("legacy-apps", "migrating"),
+			deleted:   true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			old := *exception.Enabled
+			*exception.Enabled = true
+			defer func() { *exception.Enabled = old }()
+
+			cluster := &fakeExceptions{exceptions: map[string]*exceptionsv1alpha1.Exception{tc.exception.Name: tc.exception}}
+			tracker := readiness.NewTracker(cluster, false)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				_ = tracker.Run(ctx)
+			}()
+
+			deadline := time.Now().Add(10 * time.Second)
+			for !tracker.Populated() {
+				if time.Now().After(deadline) {
+					t.Fatal("tracker was not populated")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if tracker.Satisfied() {
+				t.Fatal("got a satisfied tracker before the Exception was reconciled")
+			}
+
+			if tc.deleted {
+				delete(cluster.exceptions, tc.exception.Name)
+			}
+			r := &Reconciler{
+				reader:  cluster,
+				system:  exception.NewSystem(),
+				tracker: tracker.For(gvk),
+			}
+			if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: tc.exception.Name}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tracker.Satisfied() {
+				t.Error("got an unsatisfied tracker once the Exception was reconciled")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package expansion
+
+import (
+	"context"
+
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	log = logf.Log.WithName("controller").WithValues(logging.Process, "expansion_template_controller")
+	gvk = expansionv1alpha1.GroupVersion.WithKind("ExpansionTemplate")
+)
+
+type Adder struct {
+	ExpansionSystem *expansion.System
+	// Tracker accepts a handle for the readiness tracker
+	Tracker *readiness.Tracker
+}
+
+// Add creates a new ExpansionTemplate Controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the Manager
+// is Started.
+func (a *Adder) Add(mgr manager.Manager) error {
+	if !*expansion.ExpansionEnabled {
+		return nil
+	}
+
+	r := &Reconciler{
+		reader:  mgr.GetCache(),
+		system:  a.ExpansionSystem,
+		tracker: a.Tracker.For(gvk),
+	}
+	c, err := controller.New("expansion-template-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &expansionv1alpha1.ExpansionTemplate{}},
+		&handler.EnqueueRequestForObject{})
+}
+
+func (a *Adder) InjectOpa(o *opa.Client) {}
+
+func (a *Adder) InjectWatchManager(w *watch.Manager) {}
+
+func (a *Adder) InjectControllerSwitch(cs *watch.ControllerSwitch) {}
+
+func (a *Adder) InjectTracker(t *readiness.Tracker) {
+	a.Tracker = t
+}
+
+func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {}
+
+func (a *Adder) InjectExpansionSystem(expansionSystem *expansion.System) {
+	a.ExpansionSystem = expansionSystem
+}
+
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler keeps the ExpansionTemplates of an expansion System in sync
+// with the cluster, and reports them to the readiness tracker once ingested.
+type Reconciler struct {
+	reader  client.Reader
+	system  *expansion.System
+	tracker readiness.Expectations
+}
+
+// +kubebuilder:rbac:groups=expansion.gatekeeper.sh,resources=*,verbs=get;list;watch
+
+// Reconcile upserts the ExpansionTemplate into the expansion System, or
+// removes it if it was deleted.
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	t := &expansionv1alpha1.ExpansionTemplate{}
+	if err := r.reader.Get(ctx, request.NamespacedName, t); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		log.Info("removing ExpansionTemplate", "name", request.Name)
+		r.system.Remove(request.Name)
+		r.cancelExpect(request.Name)
+		return reconcile.Result{}, nil
+	}
+	// Readiness is keyed by kind, which typed objects read from the cache lack.
+	t.SetGroupVersionKind(gvk)
+	if !t.GetDeletionTimestamp().IsZero() {
+		log.Info("removing ExpansionTemplate", "name", request.Name)
+		r.system.Remove(request.Name)
+		r.tracker.CancelExpect(t)
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.system.Upsert(t); err != nil {
+		// The template is invalid, so retrying will not help. Stop expanding
+		// with the previous version rather than enforcing stale policy.
+		log.Error(err, "invalid ExpansionTemplate", "name", request.Name)
+		r.system.Remove(request.Name)
+		r.tracker.CancelExpect(t)
+		return reconcile.Result{}, nil
+	}
+	log.Info("upserted ExpansionTemplate", "name", request.Name)
+	r.tracker.Observe(t)
+	return reconcile.Result{}, nil
+}
+
+// cancelExpect stops the readiness tracker expecting the deleted
+// ExpansionTemplate name.
+func (r *Reconciler) cancelExpect(name string) {
+	t := &expansionv1alpha1.ExpansionTemplate{}
+	t.SetName(name)
+	t.SetGroupVersionKind(gvk)
+	r.tracker.CancelExpect(t)
+}
@@ -0,0 +1,119 @@
+package expansion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeTemplates serves ExpansionTemplates to the readiness tracker and the
+// Reconciler, and nothing else.
+type fakeTemplates struct {
+	templates map[string]*expansionv1alpha1.ExpansionTemplate
+}
+
+func (f *fakeTemplates) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	t, ok := f.templates[key.Name]
+	if !ok {
+		return apierrors.NewNotFound(expansionv1alpha1.GroupVersion.WithResource("expansiontemplates").GroupResource(), key.Name)
+	}
+	t.DeepCopyInto(obj.(*expansionv1alpha1.ExpansionTemplate))
+	return nil
+}
+
+func (f *fakeTemplates) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	if l, ok := list.(*expansionv1alpha1.ExpansionTemplateList); ok {
+		for _, t := range f.templates {
+			l.Items = append(l.Items, *t.DeepCopy())
+		}
+	}
+	return nil
+}
+
+func newTemplate(name, source string) *expansionv1alpha1.ExpansionTemplate {
+	return &expansionv1alpha1.ExpansionTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: expansionv1alpha1.ExpansionTemplateSpec{
+			ApplyTo:        []match.ApplyTo{{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{"Deployment"}}},
+			TemplateSource: source,
+			GeneratedGVK:   expansionv1alpha1.GeneratedGVK{Version: "v1", Kind: "Pod"},
+		},
+	}
+}
+
+func TestReconcile_Readiness(t *testing.T) {
+	tcs := []struct {
+		name     string
+		template *expansionv1alpha1.ExpansionTemplate
+		// deleted is whether the template is deleted once expected.
+		deleted bool
+	}{
+		{
+			name:     "ingested",
+			template: newTemplate("expand-deployments", "spec.template"),
+		},
+		{
+			name:     "invalid",
+			template: newTemplate("expand-deployments", ""),
+		},
+		{
+			name:     "deleted",
+			template: newTemplate("expand-deployments", "spec.template"),
+			deleted:  true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			old := *expansion.ExpansionEnabled
+			*expansion.ExpansionEnabled = true
+			defer func() { *expansion.ExpansionEnabled = old }()
+
+			cluster := &fakeTemplates{templates: map[string]*expansionv1alpha1.ExpansionTemplate{tc.template.Name: tc.template}}
+			tracker := readiness.NewTracker(cluster, false)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				_ = tracker.Run(ctx)
+			}()
+
+			deadline := time.Now().Add(10 * time.Second)
+			for !tracker.Populated() {
+				if time.Now().After(deadline) {
+					t.Fatal("tracker was not populated")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if tracker.Satisfied() {
+				t.Fatal("got a satisfied tracker before the ExpansionTemplate was reconciled")
+			}
+
+			if tc.deleted {
+				delete(cluster.templates, tc.template.Name)
+			}
+			r := &Reconciler{
+				reader:  cluster,
+				system:  expansion.NewSystem(mutation.NewSystem(mutation.SystemOpts{})),
+				tracker: tracker.For(gvk),
+			}
+			if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: tc.template.Name}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tracker.Satisfied() {
+				t.Error("got an unsatisfied tracker once the ExpansionTemplate was reconciled")
+			}
+		})
+	}
+}
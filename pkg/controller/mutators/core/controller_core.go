@@ -29,6 +29,7 @@ import (
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/mutatorstatus"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/openapi"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
@@ -63,12 +64,16 @@ type Adder struct {
 	// turns it into a mutator. The contents of the mutation object
 	// are set by the API server.
 	MutatorFor func(client.Object) (types.Mutator, error)
+	// SchemaValidator, if set, validates mutators against the schemas of the
+	// kinds they mutate before they are ingested.
+	SchemaValidator *openapi.Validator
 }
 
 // Add creates a new Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func (a *Adder) Add(mgr manager.Manager) error {
 	r := newReconciler(mgr, a.MutationSystem, a.Tracker, a.GetPod, a.Kind, a.NewMutationObj, a.MutatorFor)
+	r.schemaValidator = a.SchemaValidator
 	return add(mgr, r)
 }
 
@@ -137,6 +142,9 @@ type Reconciler struct {
 	kind           string
 	newMutationObj func() client.Object
 	mutatorFor     func(client.Object) (types.Mutator, error)
+	// schemaValidator, if not nil, validates mutators before they are
+	// upserted.
+	schemaValidator *openapi.Validator
 
 	system   *mutation.System
 	tracker  *readiness.Tracker
@@ -181,6 +189,16 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 		return reconcile.Result{}, err
 	}
 
+	if r.schemaValidator != nil {
+		if err = r.schemaValidator.Validate(mutator); err != nil {
+			r.log.Error(err, "Mutator does not match the schema of the kinds it mutates", "resource", request.NamespacedName)
+			r.getTracker().TryCancelExpect(mutationObj)
+
+			err = r.updateStatus(ctx, mutationObj, false, err)
+			return reconcile.Result{}, err
+		}
+	}
+
 	if err = r.system.Upsert(mutator); err != nil {
 		r.log.Error(err, "Insert failed", "resource", request.NamespacedName)
 		r.getTracker().TryCancelExpect(mutationObj)
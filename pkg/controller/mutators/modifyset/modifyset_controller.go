@@ -24,6 +24,7 @@ import (
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/mutators/core"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/openapi"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
 	"github.com/open-policy-agent/gatekeeper/pkg/watch"
@@ -36,17 +37,21 @@ type Adder struct {
 	MutationSystem *mutation.System
 	Tracker        *readiness.Tracker
 	GetPod         func(context.Context) (*corev1.Pod, error)
+	// SchemaValidator, if set, validates mutators against the schemas of the
+	// kinds they mutate.
+	SchemaValidator *openapi.Validator
 }
 
 // Add creates a new ModifySet Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func (a *Adder) Add(mgr manager.Manager) error {
 	adder := core.Adder{
-		Tracker:        a.Tracker,
-		GetPod:         a.GetPod,
-		MutationSystem: a.MutationSystem,
-		Kind:           "ModifySet",
-		NewMutationObj: func() client.Object { return &mutationsv1alpha1.ModifySet{} },
+		Tracker:         a.Tracker,
+		GetPod:          a.GetPod,
+		MutationSystem:  a.MutationSystem,
+		SchemaValidator: a.SchemaValidator,
+		Kind:            "ModifySet",
+		NewMutationObj:  func() client.Object { return &mutationsv1alpha1.ModifySet{} },
 		MutatorFor: func(obj client.Object) (types.Mutator, error) {
 			// The type is provided by the `NewObj` function above. If we
 			// are fed the wrong type, this is a non-recoverable error and we
@@ -75,3 +80,7 @@ func (a *Adder) InjectGetPod(getPod func(context.Context) (*corev1.Pod, error))
 func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {
 	a.MutationSystem = mutationSystem
 }
+
+func (a *Adder) InjectSchemaValidator(v *openapi.Validator) {
+	a.SchemaValidator = v
+}
@@ -0,0 +1,179 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package namespacedefaults
+
+import (
+	"context"
+	"sync"
+
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/namespacedefaults"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller").WithValues(logging.Process, "namespacedefaults_controller")
+
+type Adder struct {
+	MutationSystem *mutation.System
+}
+
+// Add creates a new NamespaceDefaults Controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the
+// Manager is Started.
+func (a *Adder) Add(mgr manager.Manager) error {
+	if !*mutation.MutationEnabled || !*namespacedefaults.Enabled {
+		return nil
+	}
+
+	r := &Reconciler{
+		reader:       mgr.GetCache(),
+		statusClient: mgr.GetClient(),
+		system:       a.MutationSystem,
+		ids:          make(map[k8stypes.NamespacedName][]types.ID),
+	}
+	c, err := controller.New("namespacedefaults-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &mutationsv1alpha1.NamespaceDefaults{}},
+		&handler.EnqueueRequestForObject{})
+}
+
+func (a *Adder) InjectOpa(o *opa.Client) {}
+
+func (a *Adder) InjectWatchManager(w *watch.Manager) {}
+
+func (a *Adder) InjectControllerSwitch(cs *watch.ControllerSwitch) {}
+
+func (a *Adder) InjectTracker(t *readiness.Tracker) {}
+
+func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {
+	a.MutationSystem = mutationSystem
+}
+
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler keeps the mutators compiled from NamespaceDefaults in sync with
+// the cluster.
+type Reconciler struct {
+	reader       client.Reader
+	statusClient client.StatusClient
+	system       *mutation.System
+
+	// mux guards ids.
+	mux sync.Mutex
+	// ids are the IDs of the mutators upserted for each NamespaceDefaults.
+	ids map[k8stypes.NamespacedName][]types.ID
+}
+
+// +kubebuilder:rbac:groups=mutations.gatekeeper.sh,resources=*,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile upserts the mutators compiled from the NamespaceDefaults into the
+// mutation System, removing those of its previous version, or removes them
+// all if it was deleted.
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	nd := &mutationsv1alpha1.NamespaceDefaults{}
+	if err := r.reader.Get(ctx, request.NamespacedName, nd); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		log.Info("removing NamespaceDefaults", "namespace", request.Namespace, "name", request.Name)
+		return reconcile.Result{}, r.replace(request.NamespacedName, nil)
+	}
+	if !nd.GetDeletionTimestamp().IsZero() {
+		log.Info("removing NamespaceDefaults", "namespace", request.Namespace, "name", request.Name)
+		return reconcile.Result{}, r.replace(request.NamespacedName, nil)
+	}
+
+	status := mutationsv1alpha1.NamespaceDefaultsStatus{ObservedGeneration: nd.GetGeneration()}
+	mutators, err := namespacedefaults.MutatorsFor(nd)
+	if err == nil {
+		err = r.replace(request.NamespacedName, mutators)
+	}
+	if err != nil {
+		// Stop applying the previous version too, so the defaults in effect
+		// are never those the namespace owner replaced.
+		log.Error(err, "unable to apply NamespaceDefaults", "namespace", request.Namespace, "name", request.Name)
+		if rmErr := r.replace(request.NamespacedName, nil); rmErr != nil {
+			return reconcile.Result{}, rmErr
+		}
+		status.Errors = []string{err.Error()}
+	} else {
+		log.Info("upserted NamespaceDefaults", "namespace", request.Namespace, "name", request.Name)
+		status.Enforced = true
+	}
+
+	if equality.Semantic.DeepEqual(nd.Status, status) {
+		return reconcile.Result{}, nil
+	}
+	nd.Status = status
+	return reconcile.Result{}, r.statusClient.Status().Update(ctx, nd)
+}
+
+// replace upserts mutators into the mutation System in place of those
+// upserted for the NamespaceDefaults nn.
+func (r *Reconciler) replace(nn k8stypes.NamespacedName, mutators []types.Mutator) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	keep := make(map[types.ID]bool, len(mutators))
+	for _, m := range mutators {
+		keep[m.ID()] = true
+	}
+	var remaining []types.ID
+	var firstErr error
+	for _, id := range r.ids[nn] {
+		if keep[id] {
+			continue
+		}
+		if err := r.system.Remove(id); err != nil {
+			// The mutator must be removed on the next attempt.
+			remaining = append(remaining, id)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	for _, m := range mutators {
+		// The mutator may have been upserted even if an error is returned.
+		remaining = append(remaining, m.ID())
+		if err := r.system.Upsert(m); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(r.ids, nn)
+	} else {
+		r.ids[nn] = remaining
+	}
+	return firstErr
+}
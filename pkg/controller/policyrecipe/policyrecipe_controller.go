@@ -0,0 +1,224 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package policyrecipe
+
+import (
+	"context"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	recipesv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/recipes/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/recipe"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller").WithValues(logging.Process, "policyrecipe_controller")
+
+// crdWait is how long to wait for the CRD of a generated template to be
+// created before creating its constraint again.
+const crdWait = 5 * time.Second
+
+type Adder struct{}
+
+// Add creates a new PolicyRecipe Controller and adds it to the Manager. The
+// Manager will set fields on the Controller and Start it when the Manager is
+// Started.
+func (a *Adder) Add(mgr manager.Manager) error {
+	if !*recipe.Enabled {
+		return nil
+	}
+
+	r := &Reconciler{
+		reader: mgr.GetCache(),
+		client: mgr.GetClient(),
+		scheme: mgr.GetScheme(),
+	}
+	c, err := controller.New("policyrecipe-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	if err := c.Watch(
+		&source.Kind{Type: &recipesv1alpha1.PolicyRecipe{}},
+		&handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	// Restore generated templates which are changed or deleted.
+	return c.Watch(
+		&source.Kind{Type: &v1beta1.ConstraintTemplate{}},
+		handler.EnqueueRequestsFromMapFunc(r.recipesUsing))
+}
+
+func (a *Adder) InjectOpa(o *opa.Client) {}
+
+func (a *Adder) InjectWatchManager(w *watch.Manager) {}
+
+func (a *Adder) InjectControllerSwitch(cs *watch.ControllerSwitch) {}
+
+func (a *Adder) InjectTracker(t *readiness.Tracker) {}
+
+func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {}
+
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler keeps the ConstraintTemplates and constraints compiled from
+// PolicyRecipes in sync with them.
+type Reconciler struct {
+	reader client.Reader
+	// client writes generated objects. Constraints are unstructured, so it
+	// reads them from the API server rather than the cache.
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=recipes.gatekeeper.sh,resources=*,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=templates.gatekeeper.sh,resources=constrainttemplates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=constraints.gatekeeper.sh,resources=*,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile creates or updates the ConstraintTemplate and the constraint the
+// PolicyRecipe compiles to. The constraint is owned by the PolicyRecipe, so
+// it is garbage collected when the PolicyRecipe is deleted. The template is
+// shared with other PolicyRecipes, so it is kept.
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	pr := &recipesv1alpha1.PolicyRecipe{}
+	if err := r.reader.Get(ctx, request.NamespacedName, pr); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if !pr.GetDeletionTimestamp().IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	status := recipesv1alpha1.PolicyRecipeStatus{ObservedGeneration: pr.GetGeneration()}
+	templ, constraint, err := recipe.Compile(pr)
+	if err != nil {
+		// The PolicyRecipe is invalid, so retrying will not help.
+		log.Error(err, "invalid PolicyRecipe", "name", request.Name)
+		status.Errors = []string{err.Error()}
+		return reconcile.Result{}, r.updateStatus(ctx, pr, status)
+	}
+	status.Template = templ.GetName()
+	status.Constraint = &recipesv1alpha1.ConstraintReference{Kind: constraint.GetKind(), Name: constraint.GetName()}
+
+	result := reconcile.Result{}
+	err = controllerutil.SetControllerReference(pr, constraint, r.scheme)
+	if err == nil {
+		err = r.apply(ctx, templ)
+	}
+	if err == nil {
+		err = r.apply(ctx, constraint)
+		if meta.IsNoMatchError(err) {
+			// The CRD of the template has not been created yet.
+			log.Info("waiting for the constraint CRD of PolicyRecipe", "name", request.Name, "kind", constraint.GetKind())
+			result.RequeueAfter = crdWait
+			err = nil
+		} else if err == nil {
+			status.Created = true
+		}
+	}
+	if err != nil {
+		status.Errors = []string{err.Error()}
+		if statusErr := r.updateStatus(ctx, pr, status); statusErr != nil {
+			log.Error(statusErr, "unable to update the status of PolicyRecipe", "name", request.Name)
+		}
+		return reconcile.Result{}, err
+	}
+	if status.Created {
+		log.Info("applied PolicyRecipe", "name", request.Name, "template", templ.GetName(), "constraint", constraint.GetName())
+	}
+	return result, r.updateStatus(ctx, pr, status)
+}
+
+// apply creates desired, or updates it if its spec, labels or owner
+// references are not as desired. Fields the API server defaults are left as
+// they are.
+func (r *Reconciler) apply(ctx context.Context, desired *unstructured.Unstructured) error {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.client.Get(ctx, client.ObjectKeyFromObject(desired), current)
+	if errors.IsNotFound(err) {
+		return r.client.Create(ctx, desired)
+	}
+	if err != nil {
+		return err
+	}
+
+	labels := current.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range desired.GetLabels() {
+		labels[k] = v
+	}
+	if equality.Semantic.DeepDerivative(desired.Object["spec"], current.Object["spec"]) &&
+		equality.Semantic.DeepEqual(labels, current.GetLabels()) &&
+		equality.Semantic.DeepDerivative(desired.GetOwnerReferences(), current.GetOwnerReferences()) {
+		return nil
+	}
+
+	current.Object["spec"] = desired.Object["spec"]
+	current.SetLabels(labels)
+	if refs := desired.GetOwnerReferences(); len(refs) != 0 {
+		current.SetOwnerReferences(refs)
+	}
+	return r.client.Update(ctx, current)
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, pr *recipesv1alpha1.PolicyRecipe, status recipesv1alpha1.PolicyRecipeStatus) error {
+	if equality.Semantic.DeepEqual(pr.Status, status) {
+		return nil
+	}
+	pr.Status = status
+	return r.client.Status().Update(ctx, pr)
+}
+
+// recipesUsing returns the requests to reconcile the PolicyRecipes using the
+// recipe the ConstraintTemplate obj implements, if it was generated.
+func (r *Reconciler) recipesUsing(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[recipe.RecipeLabel]
+	if !ok {
+		return nil
+	}
+	list := &recipesv1alpha1.PolicyRecipeList{}
+	if err := r.reader.List(context.Background(), list); err != nil {
+		log.Error(err, "unable to list PolicyRecipes")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		if recipe.Of(&list.Items[i]) == name {
+			requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: list.Items[i].GetName()}})
+		}
+	}
+	return requests
+}
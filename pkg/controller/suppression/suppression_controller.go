@@ -0,0 +1,110 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package suppression
+
+import (
+	"context"
+
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
+	"github.com/open-policy-agent/gatekeeper/pkg/suppression"
+	"github.com/open-policy-agent/gatekeeper/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller").WithValues(logging.Process, "suppression_controller")
+
+type Adder struct{}
+
+// Add creates a new Suppression Controller and adds it to the Manager. The
+// Manager will set fields on the Controller and Start it when the Manager is
+// Started.
+func (a *Adder) Add(mgr manager.Manager) error {
+	if !*suppression.Enabled {
+		return nil
+	}
+
+	r := &Reconciler{
+		reader: mgr.GetCache(),
+		system: suppression.Get(),
+	}
+	c, err := controller.New("suppression-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &exceptionsv1alpha1.Suppression{}},
+		&handler.EnqueueRequestForObject{})
+}
+
+func (a *Adder) InjectOpa(o *opa.Client) {}
+
+func (a *Adder) InjectWatchManager(w *watch.Manager) {}
+
+func (a *Adder) InjectControllerSwitch(cs *watch.ControllerSwitch) {}
+
+func (a *Adder) InjectTracker(t *readiness.Tracker) {}
+
+func (a *Adder) InjectMutationSystem(mutationSystem *mutation.System) {}
+
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler keeps the Suppressions of a suppression System in sync with the
+// cluster.
+type Reconciler struct {
+	reader client.Reader
+	system *suppression.System
+}
+
+// +kubebuilder:rbac:groups=exceptions.gatekeeper.sh,resources=*,verbs=get;list;watch;update;patch
+
+// Reconcile upserts the Suppression into the suppression System, or removes it if
+// it was deleted.
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	s := &exceptionsv1alpha1.Suppression{}
+	if err := r.reader.Get(ctx, request.NamespacedName, s); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		log.Info("removing Suppression", "name", request.Name)
+		r.system.Remove(request.Name)
+		return reconcile.Result{}, nil
+	}
+	if !s.GetDeletionTimestamp().IsZero() {
+		log.Info("removing Suppression", "name", request.Name)
+		r.system.Remove(request.Name)
+		return reconcile.Result{}, nil
+	}
+
+	if err := r.system.Upsert(s); err != nil {
+		// The Suppression is invalid, so retrying will not help. Stop
+		// suppressing with the previous version, so the violations are
+		// reported again.
+		log.Error(err, "invalid Suppression", "name", request.Name)
+		r.system.Remove(request.Name)
+		return reconcile.Result{}, nil
+	}
+	log.Info("upserted Suppression", "name", request.Name)
+	return reconcile.Result{}, nil
+}
@@ -23,6 +23,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/faultinject"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
 	"github.com/open-policy-agent/gatekeeper/pkg/readiness"
@@ -97,7 +99,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler, events <-chan event.Generi
 			Source:         events,
 			DestBufferSize: 1024,
 		},
-		handler.EnqueueRequestsFromMapFunc(util.EventPackerMapFunc()),
+		faultinject.Get().Lag(handler.EnqueueRequestsFromMapFunc(util.EventPackerMapFunc())),
 	)
 }
 
@@ -295,6 +297,32 @@ func (c *MetricsCache) DeleteObject(key string) {
 	delete(c.Cache, key)
 }
 
+// SyncedKinds returns the number of replicated objects of each kind.
+func (c *MetricsCache) SyncedKinds() []introspection.SyncedKind {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	kinds := make(map[string]*introspection.SyncedKind)
+	for _, v := range c.Cache {
+		k, ok := kinds[v.Kind]
+		if !ok {
+			k = &introspection.SyncedKind{Kind: v.Kind}
+			kinds[v.Kind] = k
+		}
+		if v.Status == metrics.ErrorStatus {
+			k.Errors++
+		} else {
+			k.Objects++
+		}
+	}
+
+	synced := make([]introspection.SyncedKind, 0, len(kinds))
+	for _, k := range kinds {
+		synced = append(synced, *k)
+	}
+	return synced
+}
+
 func (c *MetricsCache) ReportSync(reporter *Reporter) {
 	c.mux.RLock()
 	defer c.mux.RUnlock()
@@ -0,0 +1,150 @@
+package debug
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+)
+
+// FrameworkStats describes what is held by the constraint framework.
+type FrameworkStats struct {
+	// Modules is the number of compiled Rego modules.
+	Modules int `json:"modules"`
+	// ModuleSets is the number of module sets, one per ConstraintTemplate.
+	ModuleSets int `json:"moduleSets"`
+	// DataEntries is the number of documents put into the data cache, such as
+	// replicated objects and constraints.
+	DataEntries int `json:"dataEntries"`
+}
+
+// DriverStats counts the modules and data put into constraint framework
+// drivers wrapped by it.
+type DriverStats struct {
+	mux        sync.RWMutex
+	modules    map[string]bool
+	moduleSets map[string]int
+	data       map[string]bool
+}
+
+// NewDriverStats returns an empty DriverStats.
+func NewDriverStats() *DriverStats {
+	return &DriverStats{
+		modules:    make(map[string]bool),
+		moduleSets: make(map[string]int),
+		data:       make(map[string]bool),
+	}
+}
+
+// Stats returns the current statistics.
+func (s *DriverStats) Stats() FrameworkStats {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	stats := FrameworkStats{
+		Modules:     len(s.modules),
+		ModuleSets:  len(s.moduleSets),
+		DataEntries: len(s.data),
+	}
+	for _, n := range s.moduleSets {
+		stats.Modules += n
+	}
+	return stats
+}
+
+// Wrap returns d, recording successful changes to its modules and data in s.
+func (s *DriverStats) Wrap(d drivers.Driver) drivers.Driver {
+	return &statsDriver{Driver: d, stats: s}
+}
+
+type statsDriver struct {
+	drivers.Driver
+	stats *DriverStats
+}
+
+var _ drivers.Driver = &statsDriver{}
+
+func (d *statsDriver) PutModule(ctx context.Context, name string, src string) error {
+	if err := d.Driver.PutModule(ctx, name, src); err != nil {
+		return err
+	}
+	d.stats.mux.Lock()
+	defer d.stats.mux.Unlock()
+	d.stats.modules[name] = true
+	return nil
+}
+
+func (d *statsDriver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+		return err
+	}
+	d.stats.mux.Lock()
+	defer d.stats.mux.Unlock()
+	if len(srcs) == 0 {
+		delete(d.stats.moduleSets, namePrefix)
+	} else {
+		d.stats.moduleSets[namePrefix] = len(srcs)
+	}
+	return nil
+}
+
+func (d *statsDriver) DeleteModule(ctx context.Context, name string) (bool, error) {
+	deleted, err := d.Driver.DeleteModule(ctx, name)
+	if err != nil {
+		return deleted, err
+	}
+	d.stats.mux.Lock()
+	defer d.stats.mux.Unlock()
+	delete(d.stats.modules, name)
+	return deleted, nil
+}
+
+func (d *statsDriver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	n, err := d.Driver.DeleteModules(ctx, namePrefix)
+	if err != nil {
+		return n, err
+	}
+	d.stats.mux.Lock()
+	defer d.stats.mux.Unlock()
+	delete(d.stats.moduleSets, namePrefix)
+	return n, nil
+}
+
+func (d *statsDriver) PutData(ctx context.Context, path string, data interface{}) error {
+	if err := d.Driver.PutData(ctx, path, data); err != nil {
+		return err
+	}
+	d.stats.mux.Lock()
+	defer d.stats.mux.Unlock()
+	d.stats.data[path] = true
+	return nil
+}
+
+func (d *statsDriver) DeleteData(ctx context.Context, path string) (bool, error) {
+	deleted, err := d.Driver.DeleteData(ctx, path)
+	if err != nil {
+		return deleted, err
+	}
+	d.stats.mux.Lock()
+	defer d.stats.mux.Unlock()
+	d.stats.deleteData(path)
+	return deleted, nil
+}
+
+// deleteData forgets path and every path below it. Must be called while
+// holding s.mux.
+func (s *DriverStats) deleteData(path string) {
+	if s.data[path] {
+		// Data is put at the paths of individual documents, so only deleting
+		// a parent, such as when resetting the client, requires a scan.
+		delete(s.data, path)
+		return
+	}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for p := range s.data {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(s.data, p)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package debug
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+)
+
+func TestDriverStats(t *testing.T) {
+	ctx := context.Background()
+	stats := NewDriverStats()
+	d := stats.Wrap(local.New())
+	if err := d.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.PutModule(ctx, "single", "package single\nallow = true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, "template", []string{"package a\nallow = true", "package b\nallow = true"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModule(ctx, "invalid", "package"); err == nil {
+		t.Fatal("got no error putting an invalid module")
+	}
+	for _, path := range []string{"/cluster/v1/Namespace/foo", "/cluster/v1/Namespace/bar", "/namespace/foo/v1/Pod/baz"} {
+		if err := d.PutData(ctx, path, map[string]interface{}{"a": "b"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := FrameworkStats{Modules: 3, ModuleSets: 1, DataEntries: 3}
+	if diff := cmp.Diff(want, stats.Stats()); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := d.DeleteData(ctx, "/cluster/v1/Namespace/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeleteData(ctx, "/namespace"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeleteModules(ctx, "template"); err != nil {
+		t.Fatal(err)
+	}
+
+	want = FrameworkStats{Modules: 1, ModuleSets: 0, DataEntries: 1}
+	if diff := cmp.Diff(want, stats.Stats()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,38 @@
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+)
+
+// frameworkVar is the name of the expvar publishing FrameworkStats.
+const frameworkVar = "constraint_framework"
+
+var publishOnce sync.Once
+
+// Handler serves pprof profiles under /debug/pprof/ and expvar variables,
+// including runtime memory statistics, under /debug/vars. If stats is not nil,
+// its statistics are published as the "constraint_framework" variable.
+//
+// The handler exposes process internals, so must only be served on a local
+// address.
+func Handler(stats *DriverStats) http.Handler {
+	if stats != nil {
+		publishOnce.Do(func() {
+			expvar.Publish(frameworkVar, expvar.Func(func() interface{} {
+				return stats.Stats()
+			}))
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
@@ -0,0 +1,39 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	stats := NewDriverStats()
+	stats.data["/cluster/v1/Namespace/foo"] = true
+	h := Handler(stats)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d from /debug/vars", rec.Code)
+	}
+	vars := struct {
+		Framework FrameworkStats         `json:"constraint_framework"`
+		MemStats  map[string]interface{} `json:"memstats"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Framework.DataEntries != 1 {
+		t.Errorf("got %+v, want 1 data entry", vars.Framework)
+	}
+	if vars.MemStats["HeapAlloc"] == nil {
+		t.Error("memstats not published")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d from /debug/pprof/", rec.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package decisionlog
+
+import (
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Verdict is the outcome of an admission review.
+type Verdict string
+
+const (
+	// Allow means the request was admitted without warnings.
+	Allow Verdict = "allow"
+	// Warn means the request was admitted with warnings.
+	Warn Verdict = "warn"
+	// Deny means the request was rejected by at least one constraint.
+	Deny Verdict = "deny"
+	// Error means the request could not be reviewed.
+	Error Verdict = "error"
+)
+
+// RedactedValue replaces redacted values in Decisions.
+const RedactedValue = "[REDACTED]"
+
+// Decision records a single admission verdict.
+type Decision struct {
+	Timestamp time.Time `json:"timestamp"`
+	// RequestUID is the UID of the AdmissionRequest.
+	RequestUID string `json:"request_uid"`
+	Operation  string `json:"operation"`
+
+	ResourceGroup     string `json:"resource_group"`
+	ResourceVersion   string `json:"resource_api_version"`
+	ResourceKind      string `json:"resource_kind"`
+	ResourceNamespace string `json:"resource_namespace,omitempty"`
+	ResourceName      string `json:"resource_name,omitempty"`
+	SubResource       string `json:"request_subresource,omitempty"`
+	Username          string `json:"request_username,omitempty"`
+
+	Verdict Verdict `json:"verdict"`
+	// Results are the constraints which produced a result for the request.
+	Results []Result `json:"results,omitempty"`
+	// Error is set if the request could not be reviewed.
+	Error string `json:"error,omitempty"`
+	// LatencySeconds is the time it took to reach the verdict.
+	LatencySeconds float64 `json:"latency_seconds"`
+
+	// Object is the reviewed object, only recorded if enabled.
+	Object map[string]interface{} `json:"object,omitempty"`
+}
+
+// Result is the outcome of evaluating a single constraint.
+type Result struct {
+	ConstraintKind    string `json:"constraint_kind"`
+	ConstraintName    string `json:"constraint_name"`
+	EnforcementAction string `json:"enforcement_action"`
+	// Severity is the severity of the constraint's violations.
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Redactor removes sensitive data from Decisions before they are written.
+type Redactor struct {
+	// Username, if true, removes the requesting user.
+	Username bool
+	// Fields are dot-separated paths into the reviewed object to redact, for
+	// example "spec.containers".
+	Fields []string
+}
+
+// secretFields are always redacted from Secrets.
+var secretFields = []string{"data", "stringData"}
+
+// Redact removes sensitive data from d in place. The contents of Secrets are
+// always redacted.
+func (r *Redactor) Redact(d *Decision) {
+	if r.Username && d.Username != "" {
+		d.Username = RedactedValue
+	}
+	if d.Object == nil {
+		return
+	}
+
+	if d.ResourceGroup == "" && d.ResourceKind == "Secret" {
+		for _, f := range secretFields {
+			redactField(d.Object, f)
+		}
+	}
+	for _, f := range r.Fields {
+		redactField(d.Object, strings.Split(f, ".")...)
+	}
+}
+
+func redactField(obj map[string]interface{}, path ...string) {
+	if _, found, err := unstructured.NestedFieldNoCopy(obj, path...); err != nil || !found {
+		return
+	}
+	// The path was found, so every parent is a map and setting cannot fail.
+	_ = unstructured.SetNestedField(obj, RedactedValue, path...)
+}
@@ -0,0 +1,59 @@
+package decisionlog
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRedact(t *testing.T) {
+	tcs := []struct {
+		name     string
+		redactor Redactor
+		decision Decision
+		want     Decision
+	}{
+		{
+			name:     "nothing to redact",
+			decision: Decision{ResourceKind: "Pod", Username: "alice"},
+			want:     Decision{ResourceKind: "Pod", Username: "alice"},
+		},
+		{
+			name:     "username",
+			redactor: Redactor{Username: true},
+			decision: Decision{ResourceKind: "Pod", Username: "alice"},
+			want:     Decision{ResourceKind: "Pod", Username: RedactedValue},
+		},
+		{
+			name:     "secret data is always redacted",
+			decision: Decision{ResourceKind: "Secret", Object: map[string]interface{}{"data": map[string]interface{}{"password": "aHVudGVyMg=="}}},
+			want:     Decision{ResourceKind: "Secret", Object: map[string]interface{}{"data": RedactedValue}},
+		},
+		{
+			name:     "secret-like kind in another group",
+			decision: Decision{ResourceGroup: "example.com", ResourceKind: "Secret", Object: map[string]interface{}{"data": "foo"}},
+			want:     Decision{ResourceGroup: "example.com", ResourceKind: "Secret", Object: map[string]interface{}{"data": "foo"}},
+		},
+		{
+			name:     "fields",
+			redactor: Redactor{Fields: []string{"spec.env", "spec.missing", "metadata.name.invalid"}},
+			decision: Decision{ResourceKind: "Pod", Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "foo"},
+				"spec":     map[string]interface{}{"env": []interface{}{"TOKEN=bar"}, "image": "nginx"},
+			}},
+			want: Decision{ResourceKind: "Pod", Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "foo"},
+				"spec":     map[string]interface{}{"env": RedactedValue, "image": "nginx"},
+			}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.redactor.Redact(&tc.decision)
+			if diff := cmp.Diff(tc.want, tc.decision); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
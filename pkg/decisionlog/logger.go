@@ -0,0 +1,164 @@
+package decisionlog
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	shutdownTimeout      = 10 * time.Second
+)
+
+var (
+	sinkName       = flag.String("decision-log-sink", "", "(alpha) record every admission verdict to a decision log sink. One of [`file`, `http`, `kafka-rest`]. Disabled if empty")
+	sinkTarget     = flag.String("decision-log-target", "", "(alpha) where the decision log sink writes to: a file path for `file`, a URL for `http`, and the topic URL of a Kafka REST Proxy for `kafka-rest`")
+	includeObject  = flag.Bool("decision-log-include-object", false, "(alpha) include the reviewed object in decision logs. The data of Secrets is always redacted")
+	redactUsername = flag.Bool("decision-log-redact-username", false, "(alpha) redact the requesting user from decision logs")
+	redactFields   = util.NewFlagSet()
+	bufferSize     = flag.Int("decision-log-buffer-size", defaultBufferSize, "(alpha) number of decisions buffered for the decision log sink. Decisions are dropped rather than delaying admission when the buffer is full")
+
+	log = logf.Log.WithName("decision-log")
+)
+
+func init() {
+	flag.Var(redactFields, "decision-log-redact-field", "(alpha) dot-separated path of a field of the reviewed object to redact from decision logs, for example `spec.template`. This flag can be declared more than once")
+}
+
+var _ manager.Runnable = &Logger{}
+
+// Logger buffers Decisions and writes them to a Sink in batches. Logging
+// never blocks; if the buffer is full the Decision is dropped.
+type Logger struct {
+	sink          Sink
+	redactor      Redactor
+	includeObject bool
+	decisions     chan Decision
+	batchSize     int
+	flushInterval time.Duration
+	reporter      *reporter
+}
+
+// NewFromFlags creates a Logger configured by the decision log flags. Returns
+// nil if decision logging is disabled.
+func NewFromFlags() (*Logger, error) {
+	if *sinkName == "" {
+		return nil, nil
+	}
+	sink, err := NewSink(*sinkName, *sinkTarget)
+	if err != nil {
+		return nil, err
+	}
+	l := New(sink, Redactor{Username: *redactUsername, Fields: redactFields.ToSlice()}, *bufferSize)
+	l.includeObject = *includeObject
+	return l, nil
+}
+
+// New creates a Logger writing to sink.
+func New(sink Sink, redactor Redactor, bufferSize int) *Logger {
+	return &Logger{
+		sink:          sink,
+		redactor:      redactor,
+		decisions:     make(chan Decision, bufferSize),
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		reporter:      newStatsReporter(),
+	}
+}
+
+// IncludeObject returns true if Decisions should record the reviewed object.
+func (l *Logger) IncludeObject() bool {
+	return l.includeObject
+}
+
+// Log redacts and enqueues d. Returns false if d was dropped.
+func (l *Logger) Log(d Decision) bool {
+	l.redactor.Redact(&d)
+	select {
+	case l.decisions <- d:
+		return true
+	default:
+		if err := l.reporter.reportDropped(); err != nil {
+			log.Error(err, "failed to report dropped decision")
+		}
+		return false
+	}
+}
+
+// Start implements manager.Runnable. Buffered decisions are flushed when ctx
+// is canceled.
+func (l *Logger) Start(ctx context.Context) error {
+	log.Info("starting decision logger")
+	defer func() {
+		if err := l.sink.Close(); err != nil {
+			log.Error(err, "closing decision log sink")
+		}
+	}()
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Decision, 0, l.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			l.drain(batch)
+			return nil
+		case d := <-l.decisions:
+			batch = append(batch, d)
+			if len(batch) >= l.batchSize {
+				batch = l.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = l.flush(ctx, batch)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every webhook
+// replica logs the decisions it makes.
+func (l *Logger) NeedLeaderElection() bool {
+	return false
+}
+
+// drain writes batch and everything still buffered, bounded by
+// shutdownTimeout.
+func (l *Logger) drain(batch []Decision) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for {
+		select {
+		case d := <-l.decisions:
+			batch = append(batch, d)
+			if len(batch) >= l.batchSize {
+				batch = l.flush(ctx, batch)
+			}
+		default:
+			l.flush(ctx, batch)
+			return
+		}
+	}
+}
+
+// flush writes batch to the sink and returns it emptied.
+func (l *Logger) flush(ctx context.Context, batch []Decision) []Decision {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := l.sink.Write(ctx, batch); err != nil {
+		log.Error(err, "failed to write decisions", "count", len(batch))
+		if err := l.reporter.reportWrite(writeError, len(batch)); err != nil {
+			log.Error(err, "failed to report decision log write")
+		}
+	} else if err := l.reporter.reportWrite(writeSuccess, len(batch)); err != nil {
+		log.Error(err, "failed to report decision log write")
+	}
+	return batch[:0]
+}
@@ -0,0 +1,166 @@
+package decisionlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mux     sync.Mutex
+	batches [][]Decision
+	closed  bool
+}
+
+func (s *fakeSink) Write(_ context.Context, decisions []Decision) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.batches = append(s.batches, append([]Decision(nil), decisions...))
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	sink := &fakeSink{}
+	l := New(sink, Redactor{Username: true}, 10)
+	l.batchSize = 2
+	l.flushInterval = time.Hour
+
+	for _, uid := range []string{"a", "b", "c"} {
+		if !l.Log(Decision{RequestUID: uid, Username: "alice"}) {
+			t.Fatalf("decision %q dropped", uid)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Start(ctx) }()
+
+	// Wait for the first full batch before shutting down, so the remainder is
+	// written by the final flush.
+	for i := 0; ; i++ {
+		sink.mux.Lock()
+		n := len(sink.batches)
+		sink.mux.Unlock()
+		if n > 0 {
+			break
+		}
+		if i > 100 {
+			t.Fatal("timed out waiting for the first batch")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if !sink.closed {
+		t.Error("sink not closed")
+	}
+	var uids []string
+	for _, batch := range sink.batches {
+		if len(batch) > 2 {
+			t.Errorf("got batch of %d, want at most 2", len(batch))
+		}
+		for _, d := range batch {
+			uids = append(uids, d.RequestUID)
+			if d.Username != RedactedValue {
+				t.Errorf("got username %q, want redacted", d.Username)
+			}
+		}
+	}
+	if len(uids) != 3 {
+		t.Errorf("got decisions %v, want [a b c]", uids)
+	}
+}
+
+func TestLoggerDropsWhenFull(t *testing.T) {
+	l := New(&fakeSink{}, Redactor{}, 1)
+	if !l.Log(Decision{RequestUID: "a"}) {
+		t.Error("first decision dropped")
+	}
+	if l.Log(Decision{RequestUID: "b"}) {
+		t.Error("got second decision buffered, want dropped")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	sink, err := NewSink(FileSink, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(context.Background(), []Decision{{RequestUID: "a", Verdict: Allow}, {RequestUID: "b", Verdict: Deny}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Decision
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var d Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, d)
+	}
+	if len(got) != 2 || got[0].RequestUID != "a" || got[1].Verdict != Deny {
+		t.Errorf("got %+v, want decisions a and b", got)
+	}
+}
+
+func TestKafkaRESTSink(t *testing.T) {
+	var got kafkaRecords
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(KafkaRESTSink, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(context.Background(), []Decision{{RequestUID: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("got content type %q", contentType)
+	}
+	if len(got.Records) != 1 || got.Records[0].Value.RequestUID != "a" {
+		t.Errorf("got %+v, want one record for decision a", got)
+	}
+}
+
+func TestNewSinkErrors(t *testing.T) {
+	if _, err := NewSink(HTTPSink, ""); err == nil {
+		t.Error("got no error for missing target")
+	}
+	if _, err := NewSink("syslog", "foo"); err == nil {
+		t.Error("got no error for unknown sink")
+	}
+}
@@ -0,0 +1,137 @@
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// FileSink appends decisions to a file as JSON lines.
+	FileSink = "file"
+	// HTTPSink POSTs batches of decisions to a URL as a JSON array.
+	HTTPSink = "http"
+	// KafkaRESTSink produces decisions to a Kafka topic through a Kafka REST
+	// Proxy, with the URL being the proxy's topic endpoint.
+	KafkaRESTSink = "kafka-rest"
+
+	httpSinkTimeout = 10 * time.Second
+)
+
+// Sink is a destination for decisions.
+type Sink interface {
+	// Write persists a batch of decisions.
+	Write(ctx context.Context, decisions []Decision) error
+	// Close releases the Sink's resources. Write is not called after Close.
+	Close() error
+}
+
+// NewSink creates the Sink called name, writing to target. target is a file
+// path for FileSink, and a URL otherwise.
+func NewSink(name, target string) (Sink, error) {
+	if target == "" {
+		return nil, fmt.Errorf("decision log sink %q requires a target", name)
+	}
+	switch name {
+	case FileSink:
+		return newFileSink(target)
+	case HTTPSink:
+		return &httpSink{url: target, client: &http.Client{Timeout: httpSinkTimeout}, encode: encodeJSONArray, contentType: "application/json"}, nil
+	case KafkaRESTSink:
+		return &httpSink{url: target, client: &http.Client{Timeout: httpSinkTimeout}, encode: encodeKafkaRecords, contentType: "application/vnd.kafka.json.v2+json"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported decision log sink %q", name)
+	}
+}
+
+type fileSink struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: f}, nil
+}
+
+func (s *fileSink) Write(_ context.Context, decisions []Decision) error {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for i := range decisions {
+		if err := enc.Encode(&decisions[i]); err != nil {
+			return err
+		}
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.file.Close()
+}
+
+type httpSink struct {
+	url         string
+	client      *http.Client
+	contentType string
+	encode      func([]Decision) ([]byte, error)
+}
+
+func (s *httpSink) Write(ctx context.Context, decisions []Decision) error {
+	body, err := s.encode(decisions)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("decision log sink %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func encodeJSONArray(decisions []Decision) ([]byte, error) {
+	return json.Marshal(decisions)
+}
+
+type kafkaRecord struct {
+	Value *Decision `json:"value"`
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+func encodeKafkaRecords(decisions []Decision) ([]byte, error) {
+	records := kafkaRecords{Records: make([]kafkaRecord, len(decisions))}
+	for i := range decisions {
+		records.Records[i].Value = &decisions[i]
+	}
+	return json.Marshal(records)
+}
@@ -0,0 +1,80 @@
+package decisionlog
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	decisionsWrittenMetricName = "decision_log_decisions"
+	decisionsDroppedMetricName = "decision_log_dropped_decisions"
+)
+
+type writeStatus string
+
+const (
+	writeSuccess writeStatus = "success"
+	writeError   writeStatus = "error"
+)
+
+var (
+	statusKey = tag.MustNewKey("status")
+
+	decisionsWrittenM = stats.Int64(
+		decisionsWrittenMetricName,
+		"Total number of decisions written to the decision log sink",
+		stats.UnitDimensionless)
+
+	decisionsDroppedM = stats.Int64(
+		decisionsDroppedMetricName,
+		"Total number of decisions dropped because the decision log buffer was full",
+		stats.UnitDimensionless)
+)
+
+func init() {
+	views := []*view.View{
+		{
+			Name:        decisionsWrittenMetricName,
+			Description: decisionsWrittenM.Description(),
+			Measure:     decisionsWrittenM,
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{statusKey},
+		},
+		{
+			Name:        decisionsDroppedMetricName,
+			Description: decisionsDroppedM.Description(),
+			Measure:     decisionsDroppedM,
+			Aggregation: view.Count(),
+		},
+	}
+
+	if err := view.Register(views...); err != nil {
+		panic(err)
+	}
+}
+
+type reporter struct{}
+
+func newStatsReporter() *reporter {
+	return &reporter{}
+}
+
+func (r *reporter) reportWrite(status writeStatus, count int) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(statusKey, string(status)),
+	)
+	if err != nil {
+		return err
+	}
+
+	return metrics.Record(ctx, decisionsWrittenM.M(int64(count)))
+}
+
+func (r *reporter) reportDropped() error {
+	return metrics.Record(context.Background(), decisionsDroppedM.M(1))
+}
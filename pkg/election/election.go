@@ -0,0 +1,298 @@
+// Package election elects a leader for each of Gatekeeper's singleton
+// subsystems independently.
+//
+// Without election, singleton work such as audit is kept to one pod by running
+// it only in pods assigned the matching --operation. Electing a leader per
+// subsystem instead lets every replica be assigned the operation, with one of
+// them at a time doing the work. Each subsystem has its own Lease, so the
+// leaders of different subsystems may be different pods.
+package election
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// +kubebuilder:rbac:groups=coordination.k8s.io,namespace=gatekeeper-system,resources=leases,verbs=get;create;update
+
+var log = logf.Log.WithName("leader-election").WithValues(logging.Process, "leader_election")
+
+// Subsystem is singleton work for which a leader may be elected.
+type Subsystem string
+
+const (
+	Audit          = Subsystem("audit")
+	CertRotation   = Subsystem("cert-rotation")
+	MutationStatus = Subsystem("mutation-status")
+	PolicySnapshot = Subsystem("policy-snapshot")
+	Status         = Subsystem("status")
+)
+
+var allSubsystems = []Subsystem{Audit, CertRotation, MutationStatus, PolicySnapshot, Status}
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+var (
+	elected = subsystemSet{}
+
+	mux      sync.Mutex
+	managers = make(map[Subsystem]*electedManager)
+)
+
+func init() {
+	flag.Var(elected, "leader-elect", fmt.Sprintf("(alpha) a subsystem which only the elected leader among pods running it performs, using a Lease of its own. One of %v. This flag can be declared more than once.", allSubsystems))
+}
+
+type subsystemSet map[Subsystem]bool
+
+var _ flag.Value = subsystemSet{}
+
+func (s subsystemSet) String() string {
+	var names []string
+	for k := range s {
+		names = append(names, string(k))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (s subsystemSet) Set(v string) error {
+	for _, name := range strings.Split(v, ",") {
+		valid := false
+		for _, sub := range allSubsystems {
+			if Subsystem(name) == sub {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%s is not a subsystem which can elect a leader: %v", name, allSubsystems)
+		}
+		s[Subsystem(name)] = true
+	}
+	return nil
+}
+
+// IsElected returns whether a leader is elected for s.
+func IsElected(s Subsystem) bool {
+	return elected[s]
+}
+
+// Manager returns mgr if no leader is elected for s. Otherwise it returns a
+// Manager whose Runnables only run in the pod currently leading s. Every call
+// for the same Subsystem shares a single Lease.
+//
+// Runnables which cannot be restarted, such as controllers, are stopped by
+// exiting the manager with an error if leadership is lost.
+func Manager(mgr manager.Manager, s Subsystem) (manager.Manager, error) {
+	if !IsElected(s) {
+		return mgr, nil
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	if m, ok := managers[s]; ok {
+		return m, nil
+	}
+
+	client, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		util.GetNamespace(),
+		LeaseName(s),
+		client.CoreV1(),
+		client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: util.GetPodName() + "_" + uuid.New().String()},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &electedManager{Manager: mgr, subsystem: s, lock: lock}
+	if err := mgr.Add(m.leader()); err != nil {
+		return nil, err
+	}
+	managers[s] = m
+	return m, nil
+}
+
+// LeaseName is the name of the Lease held by the leader of s.
+func LeaseName(s Subsystem) string {
+	return fmt.Sprintf("gatekeeper-%s-leader", s)
+}
+
+// Leader returns the name of the pod which last held the Lease of s, or "" if
+// it has never been held. The pod may no longer exist.
+func Leader(ctx context.Context, client kubernetes.Interface, s Subsystem) (string, error) {
+	lease, err := client.CoordinationV1().Leases(util.GetNamespace()).Get(ctx, LeaseName(s), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if lease.Spec.HolderIdentity == nil {
+		return "", nil
+	}
+	// Identities are the name of the pod followed by a unique suffix.
+	id := *lease.Spec.HolderIdentity
+	if i := strings.LastIndex(id, "_"); i >= 0 {
+		id = id[:i]
+	}
+	return id, nil
+}
+
+// electedManager holds Runnables until the pod is elected leader.
+type electedManager struct {
+	manager.Manager
+	subsystem Subsystem
+	lock      resourcelock.Interface
+
+	mux       sync.Mutex
+	runnables []manager.Runnable
+	// ctx is the context of the manager, set once it starts.
+	ctx context.Context
+	// leading is the context of the current term, or nil before election.
+	leading context.Context
+	running sync.WaitGroup
+	errs    chan error
+}
+
+var _ manager.Manager = &electedManager{}
+
+// Add injects the manager's dependencies into r, and runs r once the pod is
+// elected leader.
+func (m *electedManager) Add(r manager.Runnable) error {
+	if err := m.Manager.SetFields(r); err != nil {
+		return err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.runnables = append(m.runnables, r)
+	if m.leading != nil {
+		m.start(m.leading, r)
+	}
+	return nil
+}
+
+// start runs r until ctx ends. m.mux must be held.
+func (m *electedManager) start(ctx context.Context, r manager.Runnable) {
+	errs := m.errs
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+		if err := r.Start(ctx); err != nil {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// lead runs every Runnable until either the manager stops or term ends.
+func (m *electedManager) lead(term context.Context) {
+	log.Info("elected leader", "subsystem", m.subsystem)
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	go func() {
+		<-term.Done()
+		cancel()
+	}()
+	m.leading = ctx
+	for _, r := range m.runnables {
+		m.start(ctx, r)
+	}
+}
+
+func (m *electedManager) leader() manager.Runnable {
+	return &leader{m: m}
+}
+
+// leader campaigns for the Lease of a subsystem, running its Runnables while
+// it holds the Lease.
+type leader struct {
+	m *electedManager
+}
+
+var _ manager.LeaderElectionRunnable = &leader{}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every pod
+// campaigns for the Lease.
+func (l *leader) NeedLeaderElection() bool {
+	return false
+}
+
+func (l *leader) Start(ctx context.Context) error {
+	m := l.m
+	m.mux.Lock()
+	m.ctx = ctx
+	m.errs = make(chan error, 1)
+	errs := m.errs
+	m.mux.Unlock()
+
+	lost := make(chan struct{})
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            m.lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            string(m.subsystem),
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: m.lead,
+			OnStoppedLeading: func() { close(lost) },
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The elector has a context of its own, so the Lease is only released
+	// once the Runnables have stopped.
+	electorCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(electorCtx)
+
+	select {
+	case <-ctx.Done():
+		// Release the Lease as soon as the subsystem has stopped, rather than
+		// once the rest of the pod has, so another pod takes over promptly
+		// instead of waiting for the Lease to expire.
+		m.running.Wait()
+		cancel()
+		<-lost
+		log.Info("released leadership", "subsystem", m.subsystem)
+		return nil
+	case <-lost:
+		return fmt.Errorf("lost leadership of %s", m.subsystem)
+	case err := <-errs:
+		return fmt.Errorf("running %s: %w", m.subsystem, err)
+	}
+}
@@ -0,0 +1,112 @@
+package election
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// fakeManager records the Runnables it sets fields on.
+type fakeManager struct {
+	manager.Manager
+	injected int
+}
+
+func (m *fakeManager) SetFields(interface{}) error {
+	m.injected++
+	return nil
+}
+
+type runnable struct {
+	started chan struct{}
+}
+
+func (r *runnable) Start(ctx context.Context) error {
+	close(r.started)
+	<-ctx.Done()
+	return nil
+}
+
+func started(r *runnable) bool {
+	select {
+	case <-r.started:
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
+
+func TestSubsystemSet(t *testing.T) {
+	s := subsystemSet{}
+	if err := s.Set("audit,status"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("cert-rotation"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.String(), "audit,cert-rotation,status"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := s.Set("webhook"); err == nil {
+		t.Error("got no error for an unknown subsystem")
+	}
+}
+
+func TestManagerNotElected(t *testing.T) {
+	mgr := &fakeManager{}
+	got, err := Manager(mgr, Audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != mgr {
+		t.Error("got a wrapped manager for a subsystem without leader election")
+	}
+}
+
+func TestElectedManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgr := &fakeManager{}
+	m := &electedManager{Manager: mgr, subsystem: Audit, ctx: ctx, errs: make(chan error, 1)}
+
+	before := &runnable{started: make(chan struct{})}
+	if err := m.Add(before); err != nil {
+		t.Fatal(err)
+	}
+	if started(before) {
+		t.Fatal("runnable started before election")
+	}
+
+	term, end := context.WithCancel(context.Background())
+	m.lead(term)
+	if !started(before) {
+		t.Error("runnable added before election was not started once elected")
+	}
+
+	after := &runnable{started: make(chan struct{})}
+	if err := m.Add(after); err != nil {
+		t.Fatal(err)
+	}
+	if !started(after) {
+		t.Error("runnable added after election was not started")
+	}
+
+	if mgr.injected != 2 {
+		t.Errorf("got fields set on %d runnables, want 2", mgr.injected)
+	}
+
+	// Runnables stop when the term ends.
+	end()
+	stopped := make(chan struct{})
+	go func() {
+		m.running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("runnables did not stop when the term ended")
+	}
+}
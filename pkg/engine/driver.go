@@ -0,0 +1,317 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("evaluation-engine")
+
+var (
+	// templatePath matches the prefix the constraint framework puts the
+	// modules of a template under.
+	templatePath = regexp.MustCompile(`^templates\["([^"]+)"\]\["([^"]+)"\]$`)
+	// hookPath matches the paths the constraint framework queries to review
+	// an object or audit the cluster.
+	hookPath = regexp.MustCompile(`^hooks\["([^"]+)"\]\.(violation|audit)$`)
+)
+
+func templatePrefix(target, kind string) string {
+	return fmt.Sprintf(`templates["%s"]["%s"]`, target, kind)
+}
+
+// Selector records the engines a ConstraintTemplate prefers.
+type Selector interface {
+	// Select records the engines templ prefers, and recompiles it if it was
+	// already compiled by an engine it no longer prefers.
+	Select(ctx context.Context, templ *templates.ConstraintTemplate) error
+}
+
+// Driver is a constraint framework driver which compiles templates with the
+// engines they prefer, and everything else with the wrapped Rego driver.
+type Driver struct {
+	drivers.Driver
+
+	// names are the enabled engines, in their default order of preference.
+	names   []string
+	engines map[string]Engine
+
+	mux sync.RWMutex
+	// preferences are the engines preferred by templates with the
+	// Annotation, keyed by the prefix of their modules.
+	preferences map[string][]string
+	// sources are the selected ConstraintTemplates, keyed by the prefix of
+	// their modules.
+	sources map[string]*templates.ConstraintTemplate
+	// modules are the compiled modules, keyed by prefix.
+	modules map[string][]string
+	// compiledBy holds the templates compiled by engines other than the Rego
+	// driver, keyed by the prefix of their modules.
+	compiledBy map[string]compiled
+}
+
+// compiled is a template compiled by an engine.
+type compiled struct {
+	engine string
+	target string
+	kind   string
+}
+
+var (
+	_ drivers.Driver = &Driver{}
+	_ Selector       = &Driver{}
+)
+
+// NewDriver returns a Driver compiling templates with the named engines,
+// falling back to d.
+func NewDriver(d drivers.Driver, names []string) (*Driver, error) {
+	driver := &Driver{
+		Driver:      d,
+		engines:     make(map[string]Engine),
+		preferences: make(map[string][]string),
+		sources:     make(map[string]*templates.ConstraintTemplate),
+		modules:     make(map[string][]string),
+		compiledBy:  make(map[string]compiled),
+	}
+	for _, name := range names {
+		if name == Rego {
+			return nil, fmt.Errorf("the %q engine is always enabled", Rego)
+		}
+		if _, ok := driver.engines[name]; ok {
+			return nil, fmt.Errorf("evaluation engine %q enabled twice", name)
+		}
+		e, err := newEngine(name)
+		if err != nil {
+			return nil, err
+		}
+		driver.engines[name] = e
+		driver.names = append(driver.names, name)
+	}
+	return driver, nil
+}
+
+// Select implements Selector.
+func (d *Driver) Select(ctx context.Context, templ *templates.ConstraintTemplate) error {
+	// The wrapped Rego driver may also be configured per template.
+	if s, ok := d.Driver.(Selector); ok {
+		if err := s.Select(ctx, templ); err != nil {
+			return err
+		}
+	}
+	if len(d.engines) == 0 {
+		return nil
+	}
+	kind := templ.Spec.CRD.Spec.Names.Kind
+	var recompile []string
+	func() {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		for _, t := range templ.Spec.Targets {
+			prefix := templatePrefix(t.Target, kind)
+			before := d.orderLocked(prefix)
+			if names, ok := templ.GetAnnotations()[Annotation]; ok {
+				d.preferences[prefix] = parseNames(names)
+			} else {
+				delete(d.preferences, prefix)
+			}
+			d.sources[prefix] = templ.DeepCopy()
+			if _, ok := d.modules[prefix]; ok && !equal(before, d.orderLocked(prefix)) {
+				recompile = append(recompile, prefix)
+			}
+		}
+	}()
+
+	for _, prefix := range recompile {
+		d.mux.RLock()
+		srcs := d.modules[prefix]
+		d.mux.RUnlock()
+		if err := d.PutModules(ctx, prefix, srcs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderLocked returns the engines to try for the template whose modules are
+// under prefix. Must be called while holding d.mux.
+func (d *Driver) orderLocked(prefix string) []string {
+	if names, ok := d.preferences[prefix]; ok {
+		return names
+	}
+	return d.names
+}
+
+func (d *Driver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	if len(d.engines) == 0 {
+		return d.Driver.PutModules(ctx, namePrefix, srcs)
+	}
+	match := templatePath.FindStringSubmatch(namePrefix)
+	if match == nil {
+		return d.Driver.PutModules(ctx, namePrefix, srcs)
+	}
+	if len(srcs) == 0 {
+		// Putting no modules deletes those under namePrefix.
+		if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+			return err
+		}
+		_, err := d.DeleteModules(ctx, namePrefix)
+		return err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	t := Template{Target: match[1], Kind: match[2], Modules: srcs, Source: d.sources[namePrefix]}
+	engine := Rego
+	for _, name := range d.orderLocked(namePrefix) {
+		if name == Rego {
+			break
+		}
+		e, ok := d.engines[name]
+		if !ok {
+			log.V(1).Info("skipping evaluation engine which is not enabled", "engine", name, "kind", t.Kind)
+			continue
+		}
+		err := e.Compile(ctx, t)
+		if err == nil {
+			engine = name
+			break
+		}
+		if !errors.Is(err, ErrUnsupported) {
+			log.Error(err, "unable to compile template, falling back to the next engine", "engine", name, "kind", t.Kind)
+		}
+	}
+
+	if engine == Rego {
+		if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+			return err
+		}
+	} else if _, err := d.Driver.DeleteModules(ctx, namePrefix); err != nil {
+		return err
+	}
+
+	if previous, ok := d.compiledBy[namePrefix]; ok && previous.engine != engine {
+		if err := d.engines[previous.engine].Remove(ctx, t.Target, t.Kind); err != nil {
+			return fmt.Errorf("removing template %s from evaluation engine %q: %w", t.Kind, previous.engine, err)
+		}
+	}
+	if engine == Rego {
+		delete(d.compiledBy, namePrefix)
+	} else {
+		d.compiledBy[namePrefix] = compiled{engine: engine, target: t.Target, kind: t.Kind}
+	}
+	d.modules[namePrefix] = append([]string(nil), srcs...)
+	return nil
+}
+
+func (d *Driver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	n, err := d.Driver.DeleteModules(ctx, namePrefix)
+	if err != nil || len(d.engines) == 0 {
+		return n, err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if c, ok := d.compiledBy[namePrefix]; ok {
+		if err := d.engines[c.engine].Remove(ctx, c.target, c.kind); err != nil {
+			return n, fmt.Errorf("removing template %s from evaluation engine %q: %w", c.kind, c.engine, err)
+		}
+		n += len(d.modules[namePrefix])
+	}
+	delete(d.compiledBy, namePrefix)
+	delete(d.modules, namePrefix)
+	delete(d.preferences, namePrefix)
+	delete(d.sources, namePrefix)
+	return n, nil
+}
+
+func (d *Driver) PutData(ctx context.Context, path string, data interface{}) error {
+	if err := d.Driver.PutData(ctx, path, data); err != nil {
+		return err
+	}
+	for _, name := range d.names {
+		if err := d.engines[name].PutData(ctx, path, data); err != nil {
+			return fmt.Errorf("putting data into evaluation engine %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (d *Driver) DeleteData(ctx context.Context, path string) (bool, error) {
+	deleted, err := d.Driver.DeleteData(ctx, path)
+	if err != nil {
+		return deleted, err
+	}
+	for _, name := range d.names {
+		if err := d.engines[name].DeleteData(ctx, path); err != nil {
+			return deleted, fmt.Errorf("deleting data from evaluation engine %q: %w", name, err)
+		}
+	}
+	return deleted, nil
+}
+
+// Query queries the Rego driver, adding the results of every engine which
+// compiled a template for the queried target.
+func (d *Driver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	resp, err := d.Driver.Query(ctx, path, input, opts...)
+	if err != nil {
+		return resp, err
+	}
+	if len(d.engines) == 0 {
+		return resp, nil
+	}
+	match := hookPath.FindStringSubmatch(path)
+	if match == nil {
+		return resp, nil
+	}
+	target, query := match[1], match[2]
+
+	for _, name := range d.enginesFor(target) {
+		results, err := d.engines[name].Query(ctx, target, query, input)
+		if err != nil {
+			return resp, fmt.Errorf("querying evaluation engine %q: %w", name, err)
+		}
+		resp.Results = append(resp.Results, results...)
+	}
+	return resp, nil
+}
+
+// enginesFor returns the engines which compiled a template for target, in
+// their default order of preference.
+func (d *Driver) enginesFor(target string) []string {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	used := make(map[string]bool)
+	for _, c := range d.compiledBy {
+		if c.target == target {
+			used[c.engine] = true
+		}
+	}
+	var names []string
+	for _, name := range d.names {
+		if used[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
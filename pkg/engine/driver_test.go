@@ -0,0 +1,267 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const target = "admission.k8s.gatekeeper.sh"
+
+// regoDriver holds the module sets and data put into it.
+type regoDriver struct {
+	drivers.Driver
+	moduleSets map[string][]string
+	data       map[string]interface{}
+}
+
+func (d *regoDriver) PutModules(_ context.Context, prefix string, srcs []string) error {
+	d.moduleSets[prefix] = srcs
+	return nil
+}
+
+func (d *regoDriver) DeleteModules(_ context.Context, prefix string) (int, error) {
+	n := len(d.moduleSets[prefix])
+	delete(d.moduleSets, prefix)
+	return n, nil
+}
+
+func (d *regoDriver) PutData(_ context.Context, path string, data interface{}) error {
+	d.data[path] = data
+	return nil
+}
+
+func (d *regoDriver) DeleteData(_ context.Context, path string) (bool, error) {
+	delete(d.data, path)
+	return true, nil
+}
+
+func (d *regoDriver) Query(_ context.Context, path string, _ interface{}, _ ...drivers.QueryOpt) (*types.Response, error) {
+	return &types.Response{Results: []*types.Result{{Msg: "rego"}}}, nil
+}
+
+// fakeEngine compiles the templates of the kinds it supports.
+type fakeEngine struct {
+	name      string
+	supported map[string]bool
+	compiled  map[string]Template
+	data      map[string]interface{}
+}
+
+func (e *fakeEngine) Compile(_ context.Context, t Template) error {
+	if !e.supported[t.Kind] {
+		return ErrUnsupported
+	}
+	e.compiled[t.Kind] = t
+	return nil
+}
+
+func (e *fakeEngine) Remove(_ context.Context, _, kind string) error {
+	delete(e.compiled, kind)
+	return nil
+}
+
+func (e *fakeEngine) PutData(_ context.Context, path string, data interface{}) error {
+	e.data[path] = data
+	return nil
+}
+
+func (e *fakeEngine) DeleteData(_ context.Context, path string) error {
+	delete(e.data, path)
+	return nil
+}
+
+func (e *fakeEngine) Query(_ context.Context, _, query string, _ interface{}) ([]*types.Result, error) {
+	var results []*types.Result
+	for kind := range e.compiled {
+		results = append(results, &types.Result{Msg: e.name + " " + query + " " + kind})
+	}
+	return results, nil
+}
+
+var engines = map[string]*fakeEngine{}
+
+func init() {
+	for name, kinds := range map[string][]string{"first": {"OnlyFirst", "Both"}, "second": {"Both", "OnlySecond"}} {
+		e := &fakeEngine{name: name, supported: make(map[string]bool)}
+		for _, kind := range kinds {
+			e.supported[kind] = true
+		}
+		engines[name] = e
+		Register(name, func() (Engine, error) {
+			e.compiled = make(map[string]Template)
+			e.data = make(map[string]interface{})
+			return e, nil
+		})
+	}
+}
+
+func template(kind string, annotations map[string]string) *templates.ConstraintTemplate {
+	return &templates.ConstraintTemplate{
+		ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+		Spec: templates.ConstraintTemplateSpec{
+			CRD:     templates.CRD{Spec: templates.CRDSpec{Names: templates.Names{Kind: kind}}},
+			Targets: []templates.Target{{Target: target}},
+		},
+	}
+}
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+	rego := &regoDriver{moduleSets: make(map[string][]string), data: make(map[string]interface{})}
+	d, err := NewDriver(rego, []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := engines["first"], engines["second"]
+
+	for _, kind := range []string{"OnlyFirst", "Both", "OnlySecond", "Neither"} {
+		if err := d.Select(ctx, template(kind, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.PutModules(ctx, templatePrefix(target, kind), []string{"package " + kind}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.PutModules(ctx, "hooks", []string{"package hooks"}); err != nil {
+		t.Fatal(err)
+	}
+
+	compiledKinds := func(e *fakeEngine) []string {
+		var kinds []string
+		for _, kind := range []string{"OnlyFirst", "Both", "OnlySecond", "Neither"} {
+			if _, ok := e.compiled[kind]; ok {
+				kinds = append(kinds, kind)
+			}
+		}
+		return kinds
+	}
+	if diff := cmp.Diff([]string{"OnlyFirst", "Both"}, compiledKinds(first)); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"OnlySecond"}, compiledKinds(second)); diff != "" {
+		t.Error(diff)
+	}
+	wantRego := map[string][]string{
+		templatePrefix(target, "Neither"): {"package Neither"},
+		"hooks":                           {"package hooks"},
+	}
+	if diff := cmp.Diff(wantRego, rego.moduleSets); diff != "" {
+		t.Error(diff)
+	}
+	if first.compiled["Both"].Source == nil {
+		t.Error("got no source for a selected template")
+	}
+
+	// A template may prefer a different engine, which recompiles it.
+	if err := d.Select(ctx, template("Both", map[string]string{Annotation: "second, rego"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := first.compiled["Both"]; ok {
+		t.Error("got template still compiled by the engine it no longer prefers")
+	}
+	if _, ok := second.compiled["Both"]; !ok {
+		t.Error("got template not compiled by the engine it prefers")
+	}
+	// Or only the Rego driver.
+	if err := d.Select(ctx, template("OnlyFirst", map[string]string{Annotation: "rego"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rego.moduleSets[templatePrefix(target, "OnlyFirst")]; !ok {
+		t.Error("got template preferring rego not compiled by the Rego driver")
+	}
+
+	if err := d.PutData(ctx, "/external/ns", "data"); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []map[string]interface{}{rego.data, first.data, second.data} {
+		if data["/external/ns"] != "data" {
+			t.Errorf("got data %v, want it put into every engine", data)
+		}
+	}
+
+	resp, err := d.Query(ctx, `hooks["`+target+`"].violation`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, r := range resp.Results {
+		msgs = append(msgs, r.Msg)
+	}
+	want := []string{"rego", "second violation Both", "second violation OnlySecond"}
+	if diff := cmp.Diff(want, sortedAfterFirst(msgs)); diff != "" {
+		t.Error(diff)
+	}
+
+	if n, err := d.DeleteModules(ctx, templatePrefix(target, "OnlySecond")); err != nil || n != 1 {
+		t.Errorf("got %d, %v deleting a template compiled by an engine, want 1 module deleted", n, err)
+	}
+	if _, ok := second.compiled["OnlySecond"]; ok {
+		t.Error("got deleted template still compiled")
+	}
+}
+
+// selectingDriver is a Rego driver which records the templates selected.
+type selectingDriver struct {
+	regoDriver
+	selected []string
+}
+
+func (d *selectingDriver) Select(_ context.Context, templ *templates.ConstraintTemplate) error {
+	d.selected = append(d.selected, templ.Spec.CRD.Spec.Names.Kind)
+	return nil
+}
+
+func TestDriver_SelectRego(t *testing.T) {
+	rego := &selectingDriver{}
+	for _, names := range [][]string{nil, {"first"}} {
+		d, err := NewDriver(rego, names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Select(context.Background(), template("Kind", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]string{"Kind", "Kind"}, rego.selected); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNewDriver(t *testing.T) {
+	for _, names := range [][]string{{"unknown"}, {Rego}, {"first", "first"}} {
+		if _, err := NewDriver(&regoDriver{}, names); err == nil {
+			t.Errorf("got no error enabling engines %v", names)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, name := range []string{Rego, "first"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("got no panic registering %q", name)
+				}
+			}()
+			Register(name, func() (Engine, error) { return nil, errors.New("unused") })
+		}()
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, Registered()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// sortedAfterFirst sorts all but the first of s, since the results of an
+// engine are in no particular order.
+func sortedAfterFirst(s []string) []string {
+	sort.Strings(s[1:])
+	return s
+}
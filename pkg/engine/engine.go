@@ -0,0 +1,139 @@
+// Package engine lets ConstraintTemplates be evaluated by engines other than
+// the local Rego driver of the constraint framework, such as CEL, WASM or a
+// remote OPA.
+//
+// Engines are registered by name, usually from the init function of the
+// package implementing them, and enabled with --evaluation-engines. A Driver
+// sits below the constraint framework client and compiles each template with
+// the first enabled engine it prefers which accepts it, falling back to the
+// Rego driver. Replicated data and constraints are put into every engine, and
+// the results of every engine are merged into those of the Rego driver.
+package engine
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+)
+
+// Rego is the name of the built-in engine, the Rego driver of the constraint
+// framework. Every template falls back to it.
+const Rego = "rego"
+
+// Annotation is set on a ConstraintTemplate to the comma-separated names of
+// the engines to compile it with, in order of preference, for example
+// "cel,rego". Engines which are not enabled are skipped. If unset, the
+// engines of --evaluation-engines are preferred in the order given.
+const Annotation = "engine.gatekeeper.sh/engines"
+
+var enabled = flag.String("evaluation-engines", "", "(alpha) comma-separated names of the registered evaluation engines to compile ConstraintTemplates with, in order of preference, before falling back to the built-in Rego driver. Templates may choose among them with the "+Annotation+" annotation")
+
+// ErrUnsupported is returned by Engine.Compile if the engine cannot evaluate
+// a template, in which case the next engine is tried.
+var ErrUnsupported = errors.New("template not supported by engine")
+
+// Template is a ConstraintTemplate compiled for a single target.
+type Template struct {
+	// Target is the name of the target the template is compiled for.
+	Target string
+	// Kind is the kind of the template's constraints.
+	Kind string
+	// Modules are the Rego modules the constraint framework generated from the
+	// template, with their packages rewritten.
+	Modules []string
+	// Source is the ConstraintTemplate the modules were generated from. It is
+	// nil if the template was not selected before it was compiled, for example
+	// when its modules were restored from another pod.
+	Source *templates.ConstraintTemplate
+}
+
+// Engine evaluates ConstraintTemplates. Its methods may be called
+// concurrently.
+type Engine interface {
+	// Compile compiles t, replacing whatever was compiled for the same target
+	// and kind. Returns ErrUnsupported if the engine cannot evaluate t.
+	Compile(ctx context.Context, t Template) error
+	// Remove forgets the template compiled for kind in target.
+	Remove(ctx context.Context, target, kind string) error
+
+	// PutData and DeleteData replicate constraints and data at the paths the
+	// constraint framework puts them at in its Rego driver.
+	PutData(ctx context.Context, path string, data interface{}) error
+	DeleteData(ctx context.Context, path string) error
+
+	// Query returns the results of query, either "violation" or "audit", for
+	// the templates compiled by the engine for target. Results must be those
+	// the Rego hooks of the constraint framework would return, including the
+	// Constraint and the Review.
+	Query(ctx context.Context, target, query string, input interface{}) ([]*types.Result, error)
+}
+
+// Factory creates an Engine.
+type Factory func() (Engine, error)
+
+var (
+	registryMux sync.RWMutex
+	registry    = make(map[string]Factory)
+)
+
+// Register makes an Engine available by name. It panics if Register is called
+// twice with the same name, or with the name of the Rego engine.
+func Register(name string, f Factory) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	if name == Rego {
+		panic(fmt.Sprintf("engine name %q is reserved", Rego))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("engine %q registered twice", name))
+	}
+	registry[name] = f
+}
+
+// Registered returns the sorted names of the registered engines.
+func Registered() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled returns the names of the engines enabled by --evaluation-engines.
+func Enabled() []string {
+	return parseNames(*enabled)
+}
+
+func parseNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func newEngine(name string) (Engine, error) {
+	registryMux.RLock()
+	f, ok := registry[name]
+	registryMux.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown evaluation engine %q, registered engines are %v", name, Registered())
+	}
+	e, err := f()
+	if err != nil {
+		return nil, fmt.Errorf("creating evaluation engine %q: %w", name, err)
+	}
+	return e, nil
+}
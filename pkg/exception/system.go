@@ -0,0 +1,185 @@
+// Package exception exempts objects from constraints according to
+// Exceptions, rather than excludes baked into the Rego of every template.
+//
+// An Exception refers to constraints by kind and optionally name, selects the
+// exempted objects with the same match criteria as mutators, and stops
+// exempting them once it expires. The webhook drops the violations exempted
+// by an Exception, and audit records them in the status of the Exception.
+package exception
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var (
+	// Enabled is whether Exceptions are loaded and exempt objects.
+	Enabled = flag.Bool("enable-exceptions", false, "(alpha) exempt objects from constraints according to Exceptions, and record the violations they exempt in their status during audit")
+
+	log = logf.Log.WithName("exception")
+)
+
+// System holds the Exceptions of the cluster.
+type System struct {
+	mux        sync.RWMutex
+	exceptions map[string]*entry
+	now        func() time.Time
+}
+
+// entry is an Exception, with the Matcher of its match.
+type entry struct {
+	exception *exceptionsv1alpha1.Exception
+	matcher   *match.Matcher
+}
+
+var system = NewSystem()
+
+// Get returns the System of this process.
+func Get() *System {
+	return system
+}
+
+// NewSystem returns a System without Exceptions.
+func NewSystem() *System {
+	return &System{
+		exceptions: make(map[string]*entry),
+		now:        time.Now,
+	}
+}
+
+// Validate returns an error if e cannot be used to exempt objects.
+func Validate(e *exceptionsv1alpha1.Exception) error {
+	if len(e.Spec.Constraints) == 0 {
+		return errors.New("spec.constraints must not be empty")
+	}
+	for i, c := range e.Spec.Constraints {
+		if c.Kind == "" {
+			return fmt.Errorf("spec.constraints[%d].kind must be set", i)
+		}
+	}
+	if e.Spec.ExpiresAt.IsZero() {
+		return errors.New("spec.expiresAt must be set")
+	}
+	if e.Spec.Justification.Reason == "" {
+		return errors.New("spec.justification.reason must be set")
+	}
+	for field, s := range map[string]*metav1.LabelSelector{
+		"labelSelector":     e.Spec.Match.LabelSelector,
+		"namespaceSelector": e.Spec.Match.NamespaceSelector,
+	} {
+		if _, err := metav1.LabelSelectorAsSelector(s); err != nil {
+			return fmt.Errorf("invalid spec.match.%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// Upsert adds e, or replaces the Exception of the same name.
+func (s *System) Upsert(e *exceptionsv1alpha1.Exception) error {
+	if err := Validate(e); err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e = e.DeepCopy()
+	s.exceptions[e.GetName()] = &entry{exception: e, matcher: match.NewMatcher(&e.Spec.Match)}
+	return nil
+}
+
+// Remove removes the Exception named name.
+func (s *System) Remove(name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.exceptions, name)
+}
+
+// Exempting returns the sorted names of the unexpired Exceptions which
+// exempt obj from constraint. ns is the namespace of obj, or nil if obj is
+// cluster-scoped.
+func (s *System) Exempting(constraint, obj *unstructured.Unstructured, ns *corev1.Namespace) ([]string, error) {
+	if isNamespace(obj) {
+		// Namespaces are matched against themselves, as for mutators.
+		ns = &corev1.Namespace{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ns); err != nil {
+			return nil, err
+		}
+	} else if obj.GetNamespace() == "" && (ns == nil || ns.GetName() == "") {
+		ns = nil
+	}
+
+	now := s.now()
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var names []string
+	for name, en := range s.exceptions {
+		if !now.Before(en.exception.Spec.ExpiresAt.Time) || !refersTo(en.exception, constraint) {
+			continue
+		}
+		matches, err := en.matcher.Matches(obj, ns)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Filter returns the results which are not exempted by an Exception, and the
+// names of the Exceptions exempting the others. ns is the namespace of the
+// reviewed objects.
+func (s *System) Filter(results []*types.Result, ns *corev1.Namespace) ([]*types.Result, map[*types.Result][]string) {
+	var kept []*types.Result
+	exempted := make(map[*types.Result][]string)
+	for _, r := range results {
+		obj, ok := r.Resource.(*unstructured.Unstructured)
+		if !ok || r.Constraint == nil {
+			kept = append(kept, r)
+			continue
+		}
+		names, err := s.Exempting(r.Constraint, obj, ns)
+		if err != nil {
+			log.Error(err, "unable to match exceptions", "kind", obj.GetKind(), "name", obj.GetName())
+		}
+		if len(names) == 0 {
+			kept = append(kept, r)
+			continue
+		}
+		exempted[r] = names
+	}
+	return kept, exempted
+}
+
+// Expired returns whether e has expired.
+func (s *System) Expired(e *exceptionsv1alpha1.Exception) bool {
+	return !s.now().Before(e.Spec.ExpiresAt.Time)
+}
+
+func refersTo(e *exceptionsv1alpha1.Exception, constraint *unstructured.Unstructured) bool {
+	for _, c := range e.Spec.Constraints {
+		if c.Kind == constraint.GetKind() && (c.Name == "" || c.Name == constraint.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNamespace(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group == "" && gvk.Kind == "Namespace"
+}
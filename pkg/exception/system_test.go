@@ -0,0 +1,245 @@
+package exception
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var now = time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+func newException(name string, expiresAt time.Time, m match.Match, refs ...exceptionsv1alpha1.ConstraintReference) *exceptionsv1alpha1.Exception {
+	return &exceptionsv1alpha1.Exception{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: exceptionsv1alpha1.ExceptionSpec{
+			Constraints:   refs,
+			Match:         m,
+			ExpiresAt:     metav1.NewTime(expiresAt),
+			Justification: exceptionsv1alpha1.Justification{Reason: "migration in progress"},
+		},
+	}
+}
+
+func newConstraint(kind, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	u.SetKind(kind)
+	u.SetName(name)
+	return u
+}
+
+func newObject(kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newSystem(t *testing.T, exceptions ...*exceptionsv1alpha1.Exception) *System {
+	s := NewSystem()
+	s.now = func() time.Time { return now }
+	for _, e := range exceptions {
+		if err := s.Upsert(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *exceptionsv1alpha1.Exception {
+		return newException("e", now, match.Match{}, exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels"})
+	}
+
+	tcs := []struct {
+		name    string
+		mutate  func(e *exceptionsv1alpha1.Exception)
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			mutate: func(e *exceptionsv1alpha1.Exception) {},
+		},
+		{
+			name:    "no constraints",
+			mutate:  func(e *exceptionsv1alpha1.Exception) { e.Spec.Constraints = nil },
+			wantErr: true,
+		},
+		{
+			name:    "constraint without kind",
+			mutate:  func(e *exceptionsv1alpha1.Exception) { e.Spec.Constraints[0].Kind = "" },
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			mutate:  func(e *exceptionsv1alpha1.Exception) { e.Spec.ExpiresAt = metav1.Time{} },
+			wantErr: true,
+		},
+		{
+			name:    "no reason",
+			mutate:  func(e *exceptionsv1alpha1.Exception) { e.Spec.Justification.Reason = "" },
+			wantErr: true,
+		},
+		{
+			name: "invalid label selector",
+			mutate: func(e *exceptionsv1alpha1.Exception) {
+				e.Spec.Match.LabelSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: "Bogus"}},
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			e := valid()
+			tc.mutate(e)
+			err := Validate(e)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("got Validate() error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestExempting(t *testing.T) {
+	labels := exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels"}
+	onlyFoo := exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels", Name: "foo"}
+	inLegacy := match.Match{Namespaces: []string{"legacy"}}
+	legacy := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	tcs := []struct {
+		name       string
+		exceptions []*exceptionsv1alpha1.Exception
+		constraint *unstructured.Unstructured
+		obj        *unstructured.Unstructured
+		ns         *corev1.Namespace
+		want       []string
+	}{
+		{
+			name:       "no exceptions",
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+		},
+		{
+			name:       "exempted from every constraint of a kind",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), inLegacy, labels)},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+			want:       []string{"e"},
+		},
+		{
+			name:       "exempted from a named constraint",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), inLegacy, onlyFoo)},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+			want:       []string{"e"},
+		},
+		{
+			name:       "other constraint of the kind",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), inLegacy, onlyFoo)},
+			constraint: newConstraint("K8sRequiredLabels", "bar"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+		},
+		{
+			name:       "other constraint kind",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), inLegacy, labels)},
+			constraint: newConstraint("K8sAllowedRepos", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+		},
+		{
+			name:       "unmatched object",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), inLegacy, labels)},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "other", "a"),
+			ns:         other,
+		},
+		{
+			name:       "expired",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now, inLegacy, labels)},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+		},
+		{
+			name: "several exceptions",
+			exceptions: []*exceptionsv1alpha1.Exception{
+				newException("b", now.Add(time.Hour), match.Match{}, labels),
+				newException("a", now.Add(time.Hour), inLegacy, onlyFoo),
+				newException("c", now.Add(-time.Hour), match.Match{}, labels),
+			},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "namespace matched against itself",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), inLegacy, labels)},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Namespace", "", "legacy"),
+			ns:         &corev1.Namespace{},
+			want:       []string{"e"},
+		},
+		{
+			name:       "cluster-scoped object",
+			exceptions: []*exceptionsv1alpha1.Exception{newException("e", now.Add(time.Hour), match.Match{Scope: "Cluster"}, labels)},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Node", "", "n"),
+			ns:         &corev1.Namespace{},
+			want:       []string{"e"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSystem(t, tc.exceptions...)
+			got, err := s.Exempting(tc.constraint, tc.obj, tc.ns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := newSystem(t, newException("e", now.Add(time.Hour), match.Match{Namespaces: []string{"legacy"}},
+		exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels", Name: "foo"}))
+	legacy := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}}
+	obj := newObject("Pod", "legacy", "a")
+
+	exempted := &types.Result{Constraint: newConstraint("K8sRequiredLabels", "foo"), Resource: obj}
+	kept := &types.Result{Constraint: newConstraint("K8sRequiredLabels", "bar"), Resource: obj}
+
+	gotKept, gotExempted := s.Filter([]*types.Result{exempted, kept}, legacy)
+	if len(gotKept) != 1 || gotKept[0] != kept {
+		t.Errorf("got kept %v, want only the result of constraint bar", gotKept)
+	}
+	if diff := cmp.Diff(map[*types.Result][]string{exempted: {"e"}}, gotExempted); diff != "" {
+		t.Error(diff)
+	}
+
+	s.Remove("e")
+	if gotKept, _ := s.Filter([]*types.Result{exempted, kept}, legacy); len(gotKept) != 2 {
+		t.Errorf("got %d results kept after removing the exception, want 2", len(gotKept))
+	}
+}
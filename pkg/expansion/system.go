@@ -0,0 +1,386 @@
+// Package expansion generates the resources which the controllers of
+// workload resources create, so policy can be enforced on them when the
+// workload is admitted.
+//
+// A Deployment which creates Pods violating a constraint is admitted, and only
+// the creation of its Pods is denied, by which time the user who submitted it
+// may no longer be watching. ExpansionTemplates describe how a parent resource
+// generates a child, such as the Pod templated in `spec.template` of a
+// Deployment. The children are reviewed along with their parent, and the
+// violations they would cause are reported when the parent is admitted.
+package expansion
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/path/parser"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ExpansionEnabled is whether resources are expanded using ExpansionTemplates.
+var ExpansionEnabled = flag.Bool("enable-generator-resource-expansion", false, "(alpha) review the resources generated from admitted resources, as described by ExpansionTemplates")
+
+// MaxDepth is the number of times a resource is expanded transitively, for
+// example twice from a Deployment to its ReplicaSet and then its Pod.
+const MaxDepth = 5
+
+// Resultant is a resource generated from a parent.
+type Resultant struct {
+	Obj *unstructured.Unstructured
+	// TemplateName is the name of the ExpansionTemplate which generated Obj.
+	TemplateName string
+	// EnforcementAction overrides the enforcement action of violations of Obj
+	// if set.
+	EnforcementAction string
+	// Parent is the Resultant Obj was generated from, or nil if it was
+	// generated from the expanded resource.
+	Parent *Resultant
+	// SourcePath is the path of the field of the expanded resource which Obj
+	// was generated from, for example `spec.template`. It is empty if Obj was
+	// not copied from a single field of the expanded resource.
+	SourcePath string
+
+	template *template
+}
+
+// TemplateNames returns the names of the ExpansionTemplates which generated
+// r, starting from the expanded resource.
+func (r *Resultant) TemplateNames() []string {
+	var names []string
+	for cur := r; cur != nil; cur = cur.Parent {
+		names = append([]string{cur.TemplateName}, names...)
+	}
+	return names
+}
+
+// rootPath returns the path of the field of the expanded resource which path
+// in r.Obj was copied from, or false if it was not copied from the expanded
+// resource.
+func (r *Resultant) rootPath(path []string) ([]string, bool) {
+	for cur := r; cur != nil; cur = cur.Parent {
+		var ok bool
+		if path, ok = cur.template.parentPath(path); !ok {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// template is an ExpansionTemplate with its paths parsed.
+type template struct {
+	name              string
+	applyTo           []match.ApplyTo
+	source            []string
+	generated         v1alpha1.GeneratedGVK
+	mappings          []mapping
+	enforcementAction string
+	skipMutation      bool
+}
+
+type mapping struct {
+	from, to []string
+}
+
+// System holds the ExpansionTemplates, and expands resources with them.
+type System struct {
+	mux            sync.RWMutex
+	templates      map[string]*template
+	mutationSystem *mutation.System
+}
+
+// NewSystem returns a System without ExpansionTemplates. Generated resources
+// are mutated by mutationSystem, unless it is nil.
+func NewSystem(mutationSystem *mutation.System) *System {
+	return &System{
+		templates:      make(map[string]*template),
+		mutationSystem: mutationSystem,
+	}
+}
+
+// Upsert adds t to the System, replacing the ExpansionTemplate of the same
+// name. An invalid t is not added.
+func (s *System) Upsert(t *v1alpha1.ExpansionTemplate) error {
+	parsed, err := newTemplate(t)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.templates[t.GetName()] = parsed
+	return nil
+}
+
+// Remove removes the ExpansionTemplate called name.
+func (s *System) Remove(name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.templates, name)
+}
+
+// Validate returns an error if t is not a valid ExpansionTemplate.
+func Validate(t *v1alpha1.ExpansionTemplate) error {
+	_, err := newTemplate(t)
+	return err
+}
+
+func newTemplate(t *v1alpha1.ExpansionTemplate) (*template, error) {
+	spec := t.Spec
+	if len(spec.ApplyTo) == 0 {
+		return nil, fmt.Errorf("ExpansionTemplate %s must set applyTo", t.GetName())
+	}
+	if spec.TemplateSource == "" && len(spec.FieldMappings) == 0 {
+		return nil, fmt.Errorf("ExpansionTemplate %s must set templateSource or fieldMappings", t.GetName())
+	}
+	if spec.GeneratedGVK.Version == "" || spec.GeneratedGVK.Kind == "" {
+		return nil, fmt.Errorf("ExpansionTemplate %s must set the version and kind of generatedGVK", t.GetName())
+	}
+	if spec.EnforcementAction != "" {
+		if err := util.ValidateEnforcementAction(util.EnforcementAction(spec.EnforcementAction)); err != nil {
+			return nil, fmt.Errorf("ExpansionTemplate %s: %w", t.GetName(), err)
+		}
+	}
+
+	parsed := &template{
+		name:              t.GetName(),
+		applyTo:           spec.ApplyTo,
+		generated:         spec.GeneratedGVK,
+		enforcementAction: spec.EnforcementAction,
+		skipMutation:      spec.SkipMutation,
+	}
+	if spec.TemplateSource != "" {
+		source, err := parseFieldPath(spec.TemplateSource)
+		if err != nil {
+			return nil, fmt.Errorf("ExpansionTemplate %s has an invalid templateSource: %w", t.GetName(), err)
+		}
+		parsed.source = source
+	}
+	for _, m := range spec.FieldMappings {
+		from, err := parseFieldPath(m.From)
+		if err != nil {
+			return nil, fmt.Errorf("ExpansionTemplate %s has an invalid field mapping from %q: %w", t.GetName(), m.From, err)
+		}
+		to, err := parseFieldPath(m.To)
+		if err != nil {
+			return nil, fmt.Errorf("ExpansionTemplate %s has an invalid field mapping to %q: %w", t.GetName(), m.To, err)
+		}
+		parsed.mappings = append(parsed.mappings, mapping{from: from, to: to})
+	}
+	return parsed, nil
+}
+
+// parseFieldPath parses a path of object fields, such as `spec.template`.
+func parseFieldPath(p string) ([]string, error) {
+	if p == "" {
+		return nil, fmt.Errorf("path must not be empty")
+	}
+	parsed, err := parser.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(parsed.Nodes))
+	for i, n := range parsed.Nodes {
+		obj, ok := n.(*parser.Object)
+		if !ok {
+			return nil, fmt.Errorf("%s: only object fields are supported, not %s", p, n)
+		}
+		fields[i] = obj.Reference
+	}
+	return fields, nil
+}
+
+// Expand returns the resources generated from parent by every
+// ExpansionTemplate which applies to it, ordered by template name. Templates
+// whose source is missing from parent generate nothing. ns is the namespace
+// of parent, or nil if it is cluster-scoped.
+//
+// Generated resources are mutated as they would be when created, unless
+// their ExpansionTemplate skips mutation.
+//
+// Generated resources are expanded in turn, each followed by its own children,
+// so a Deployment may generate a ReplicaSet which generates a Pod. An error is
+// returned if a resource would generate a kind it was itself generated from,
+// or if expansion is nested more than MaxDepth times.
+func (s *System) Expand(parent *unstructured.Unstructured, ns *corev1.Namespace) ([]*Resultant, error) {
+	s.mux.RLock()
+	templates := make([]*template, 0, len(s.templates))
+	for _, t := range s.templates {
+		templates = append(templates, t)
+	}
+	s.mux.RUnlock()
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].name < templates[j].name
+	})
+	return s.expand(templates, parent, ns, nil, []schema.GroupVersionKind{parent.GroupVersionKind()})
+}
+
+// expand returns the descendants of obj, which was generated as from, or
+// nil if obj is the expanded resource. chain holds the kinds of obj and the
+// resources it was generated from.
+func (s *System) expand(templates []*template, obj *unstructured.Unstructured, ns *corev1.Namespace, from *Resultant, chain []schema.GroupVersionKind) ([]*Resultant, error) {
+	var resultants []*Resultant
+	for _, t := range templates {
+		if !match.AppliesTo(t.applyTo, obj) {
+			continue
+		}
+		child, err := t.generate(obj)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			continue
+		}
+
+		gvk := child.GroupVersionKind()
+		for _, ancestor := range chain {
+			if ancestor == gvk {
+				return nil, fmt.Errorf("expanding %s with ExpansionTemplate %s: cycle generating %s", objectKey(obj), t.name, kindChain(append(chain, gvk)))
+			}
+		}
+		if len(chain) > MaxDepth {
+			return nil, fmt.Errorf("expanding %s with ExpansionTemplate %s: more than %d levels of expansion generating %s", objectKey(obj), t.name, MaxDepth, kindChain(append(chain, gvk)))
+		}
+
+		r := &Resultant{
+			Obj:               child,
+			TemplateName:      t.name,
+			EnforcementAction: t.enforcementAction,
+			Parent:            from,
+			template:          t,
+		}
+		if r.EnforcementAction == "" && from != nil {
+			r.EnforcementAction = from.EnforcementAction
+		}
+		if src, ok := r.rootPath(nil); ok {
+			r.SourcePath = fieldPath(src)
+		}
+		if s.mutationSystem != nil && !t.skipMutation {
+			// Descendants are generated from the mutated resource, as its
+			// controller would create them.
+			if _, err := s.mutationSystem.Mutate(child, ns); err != nil {
+				return nil, fmt.Errorf("mutating %s generated by ExpansionTemplate %s: %w", objectKey(child), t.name, err)
+			}
+		}
+		resultants = append(resultants, r)
+
+		descendants, err := s.expand(templates, child, ns, r, append(chain[:len(chain):len(chain)], gvk))
+		if err != nil {
+			return nil, err
+		}
+		resultants = append(resultants, descendants...)
+	}
+	return resultants, nil
+}
+
+// parentPath returns the path of the field of the parent which path in the
+// generated resource was copied from, or false if it was not copied.
+func (t *template) parentPath(path []string) ([]string, bool) {
+	// Field mappings are applied after the source is copied, so the last
+	// mapping to an enclosing field wins.
+	for i := len(t.mappings) - 1; i >= 0; i-- {
+		m := t.mappings[i]
+		if hasPrefix(path, m.to) {
+			return join(m.from, path[len(m.to):]), true
+		}
+	}
+	if t.source == nil {
+		return nil, false
+	}
+	return join(t.source, path), true
+}
+
+// fieldPath renders fields as a path which parseFieldPath accepts.
+func fieldPath(fields []string) string {
+	p := parser.Path{}
+	for _, f := range fields {
+		p.Nodes = append(p.Nodes, &parser.Object{Reference: f})
+	}
+	return p.String()
+}
+
+func hasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func join(a, b []string) []string {
+	joined := make([]string, 0, len(a)+len(b))
+	return append(append(joined, a...), b...)
+}
+
+func kindChain(chain []schema.GroupVersionKind) string {
+	kinds := make([]string, len(chain))
+	for i, gvk := range chain {
+		kinds[i] = gvk.Kind
+	}
+	return strings.Join(kinds, " -> ")
+}
+
+// generate returns the child of parent, or nil if parent holds no template.
+// A template without a source generates a child from its field mappings
+// alone.
+func (t *template) generate(parent *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	child := &unstructured.Unstructured{Object: make(map[string]interface{})}
+	if t.source != nil {
+		src, found, err := unstructured.NestedFieldNoCopy(parent.Object, t.source...)
+		if err != nil {
+			return nil, fmt.Errorf("expanding %s with ExpansionTemplate %s: %w", objectKey(parent), t.name, err)
+		}
+		if !found {
+			return nil, nil
+		}
+		srcMap, ok := src.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expanding %s with ExpansionTemplate %s: %s is not an object", objectKey(parent), t.name, strings.Join(t.source, "."))
+		}
+		child.Object = runtime.DeepCopyJSON(srcMap)
+	}
+
+	child.SetGroupVersionKind(t.generated.ToGroupVersionKind())
+	child.SetNamespace(parent.GetNamespace())
+	if child.GetName() == "" && child.GetGenerateName() == "" {
+		// The name of generated resources is not known until they are
+		// created, so they are named after their parent.
+		child.SetName(parent.GetName())
+	}
+
+	for _, m := range t.mappings {
+		v, found, err := unstructured.NestedFieldNoCopy(parent.Object, m.from...)
+		if err != nil {
+			return nil, fmt.Errorf("expanding %s with ExpansionTemplate %s: %w", objectKey(parent), t.name, err)
+		}
+		if !found {
+			continue
+		}
+		if err := unstructured.SetNestedField(child.Object, runtime.DeepCopyJSONValue(v), m.to...); err != nil {
+			return nil, fmt.Errorf("expanding %s with ExpansionTemplate %s: %w", objectKey(parent), t.name, err)
+		}
+	}
+	return child, nil
+}
+
+func objectKey(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
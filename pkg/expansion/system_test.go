@@ -0,0 +1,500 @@
+package expansion
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTemplateObj(name string, applyTo match.ApplyTo, source string, gvk v1alpha1.GeneratedGVK, mappings ...v1alpha1.FieldMapping) *v1alpha1.ExpansionTemplate {
+	return &v1alpha1.ExpansionTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.ExpansionTemplateSpec{
+			ApplyTo:        []match.ApplyTo{applyTo},
+			TemplateSource: source,
+			GeneratedGVK:   gvk,
+			FieldMappings:  mappings,
+		},
+	}
+}
+
+var (
+	deployments = match.ApplyTo{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{"Deployment"}}
+	cronJobs    = match.ApplyTo{Groups: []string{"batch"}, Versions: []string{"v1"}, Kinds: []string{"CronJob"}}
+	webApps     = match.ApplyTo{Groups: []string{"example.com"}, Versions: []string{"v1"}, Kinds: []string{"WebApp"}}
+	pods        = v1alpha1.GeneratedGVK{Version: "v1", Kind: "Pod"}
+	jobs        = v1alpha1.GeneratedGVK{Group: "batch", Version: "v1", Kind: "Job"}
+)
+
+func deployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "nginx",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"team": "web"},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "nginx"},
+				},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func cronJob() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"metadata":   map[string]interface{}{"name": "backup", "namespace": "ops"},
+		"spec": map[string]interface{}{
+			"jobTemplate": map[string]interface{}{
+				"spec": map[string]interface{}{"backoffLimit": int64(2)},
+			},
+		},
+	}}
+}
+
+// webApp is a custom resource whose operator creates a frontend and a worker
+// Pod.
+func webApp() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "WebApp",
+		"metadata":   map[string]interface{}{"name": "shop", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"frontend": map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "shop-frontend"},
+				"spec":     map[string]interface{}{"hostNetwork": true},
+			},
+			"worker": map[string]interface{}{
+				"spec": map[string]interface{}{"hostPID": true},
+			},
+		},
+	}}
+}
+
+func TestExpand(t *testing.T) {
+	tcs := []struct {
+		name      string
+		templates []*v1alpha1.ExpansionTemplate
+		parent    *unstructured.Unstructured
+		want      []*Resultant
+	}{
+		{
+			name:      "no templates",
+			parent:    deployment(),
+			templates: nil,
+			want:      nil,
+		},
+		{
+			name:      "pod from deployment",
+			parent:    deployment(),
+			templates: []*v1alpha1.ExpansionTemplate{newTemplateObj("expand-deployments", deployments, "spec.template", pods)},
+			want: []*Resultant{{
+				TemplateName: "expand-deployments",
+				SourcePath:   "spec.template",
+				Obj: &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name":      "nginx",
+						"namespace": "default",
+						"labels":    map[string]interface{}{"app": "nginx"},
+					},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "nginx", "image": "nginx"},
+						},
+					},
+				}},
+			}},
+		},
+		{
+			name:   "job from custom source with field mapping",
+			parent: cronJob(),
+			templates: []*v1alpha1.ExpansionTemplate{newTemplateObj("expand-cronjobs", cronJobs, "spec.jobTemplate", jobs,
+				v1alpha1.FieldMapping{From: "metadata.name", To: "metadata.labels.cronjob"})},
+			want: []*Resultant{{
+				TemplateName: "expand-cronjobs",
+				SourcePath:   "spec.jobTemplate",
+				Obj: &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "batch/v1",
+					"kind":       "Job",
+					"metadata": map[string]interface{}{
+						"name":      "backup",
+						"namespace": "ops",
+						"labels":    map[string]interface{}{"cronjob": "backup"},
+					},
+					"spec": map[string]interface{}{"backoffLimit": int64(2)},
+				}},
+			}},
+		},
+		{
+			name:   "multiple children",
+			parent: webApp(),
+			templates: []*v1alpha1.ExpansionTemplate{
+				newTemplateObj("webapp-worker", webApps, "spec.worker", pods),
+				newTemplateObj("webapp-frontend", webApps, "spec.frontend", pods),
+			},
+			want: []*Resultant{
+				{
+					TemplateName: "webapp-frontend",
+					SourcePath:   "spec.frontend",
+					Obj: &unstructured.Unstructured{Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Pod",
+						"metadata":   map[string]interface{}{"name": "shop-frontend", "namespace": "default"},
+						"spec":       map[string]interface{}{"hostNetwork": true},
+					}},
+				},
+				{
+					TemplateName: "webapp-worker",
+					SourcePath:   "spec.worker",
+					Obj: &unstructured.Unstructured{Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Pod",
+						"metadata":   map[string]interface{}{"name": "shop", "namespace": "default"},
+						"spec":       map[string]interface{}{"hostPID": true},
+					}},
+				},
+			},
+		},
+		{
+			name:      "template does not apply",
+			parent:    cronJob(),
+			templates: []*v1alpha1.ExpansionTemplate{newTemplateObj("expand-deployments", deployments, "spec.template", pods)},
+			want:      nil,
+		},
+		{
+			name:      "source missing",
+			parent:    deployment(),
+			templates: []*v1alpha1.ExpansionTemplate{newTemplateObj("expand-deployments", deployments, "spec.jobTemplate", pods)},
+			want:      nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSystem(nil)
+			for _, tmpl := range tc.templates {
+				if err := s.Upsert(tmpl); err != nil {
+					t.Fatal(err)
+				}
+			}
+			parent := tc.parent.DeepCopy()
+			got, err := s.Expand(parent, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(Resultant{})); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.parent, parent); diff != "" {
+				t.Errorf("parent was modified: %s", diff)
+			}
+		})
+	}
+}
+
+func TestExpandSourceNotObject(t *testing.T) {
+	s := NewSystem(nil)
+	if err := s.Upsert(newTemplateObj("bad", deployments, "metadata.name", pods)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Expand(deployment(), nil); err == nil {
+		t.Error("got no error for a template source which is not an object")
+	}
+}
+
+var (
+	replicaSets    = match.ApplyTo{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{"ReplicaSet"}}
+	replicaSetsGVK = v1alpha1.GeneratedGVK{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	deploymentsGVK = v1alpha1.GeneratedGVK{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+func TestExpandRecursive(t *testing.T) {
+	// A Deployment creates a ReplicaSet with its spec, which creates the Pods.
+	deploymentToReplicaSet := newTemplateObj("expand-deployments", deployments, "", replicaSetsGVK,
+		v1alpha1.FieldMapping{From: "spec", To: "spec"})
+	deploymentToReplicaSet.Spec.EnforcementAction = "warn"
+	replicaSetToPod := newTemplateObj("expand-replicasets", replicaSets, "spec.template", pods)
+
+	s := NewSystem(nil)
+	for _, tmpl := range []*v1alpha1.ExpansionTemplate{deploymentToReplicaSet, replicaSetToPod} {
+		if err := s.Upsert(tmpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.Expand(deployment(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d resultants, want a ReplicaSet and a Pod", len(got))
+	}
+	rs, pod := got[0], got[1]
+	if rs.Obj.GetKind() != "ReplicaSet" || rs.Parent != nil {
+		t.Errorf("got first resultant %s with parent %v, want a ReplicaSet generated from the Deployment", rs.Obj.GetKind(), rs.Parent)
+	}
+	if pod.Obj.GetKind() != "Pod" || pod.Parent != rs {
+		t.Errorf("got second resultant %s with parent %v, want a Pod generated from the ReplicaSet", pod.Obj.GetKind(), pod.Parent)
+	}
+	if diff := cmp.Diff([]string{"expand-deployments", "expand-replicasets"}, pod.TemplateNames()); diff != "" {
+		t.Error(diff)
+	}
+	if rs.SourcePath != "" || pod.SourcePath != "spec.template" {
+		t.Errorf("got source paths %q and %q, want the ReplicaSet built from field mappings and the Pod from spec.template", rs.SourcePath, pod.SourcePath)
+	}
+	if pod.EnforcementAction != "warn" {
+		t.Errorf("got enforcement action %q for the Pod, want it inherited from the ReplicaSet", pod.EnforcementAction)
+	}
+	if diff := cmp.Diff(map[string]string{"app": "nginx"}, pod.Obj.GetLabels()); diff != "" {
+		t.Error(diff)
+	}
+	containers, _, _ := unstructured.NestedSlice(pod.Obj.Object, "spec", "containers")
+	if len(containers) != 1 {
+		t.Errorf("got containers %v, want those of the Deployment", containers)
+	}
+}
+
+func TestExpandSourcePath(t *testing.T) {
+	// The Pod template of the ReplicaSet is mapped from a field of the
+	// Deployment whose name must be quoted.
+	parent := deployment()
+	if err := unstructured.SetNestedField(parent.Object, map[string]interface{}{"spec": map[string]interface{}{}}, "spec", "pod.template"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSystem(nil)
+	for _, tmpl := range []*v1alpha1.ExpansionTemplate{
+		newTemplateObj("expand-deployments", deployments, "metadata", replicaSetsGVK,
+			v1alpha1.FieldMapping{From: `spec."pod.template"`, To: "spec.template"}),
+		newTemplateObj("expand-replicasets", replicaSets, "spec.template.spec", pods),
+	} {
+		if err := s.Upsert(tmpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.Expand(parent, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, r := range got {
+		paths = append(paths, r.SourcePath)
+	}
+	if diff := cmp.Diff([]string{"metadata", `spec."pod.template".spec`}, paths); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestExpandCycle(t *testing.T) {
+	s := NewSystem(nil)
+	for _, tmpl := range []*v1alpha1.ExpansionTemplate{
+		newTemplateObj("expand-deployments", deployments, "", replicaSetsGVK, v1alpha1.FieldMapping{From: "spec", To: "spec"}),
+		newTemplateObj("expand-replicasets", replicaSets, "", deploymentsGVK, v1alpha1.FieldMapping{From: "spec", To: "spec"}),
+	} {
+		if err := s.Upsert(tmpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Expand(deployment(), nil); err == nil {
+		t.Error("got no error for ExpansionTemplates generating a cycle")
+	}
+}
+
+func TestExpandMaxDepth(t *testing.T) {
+	// Each level generates a new kind from the same template, so only the
+	// depth limit stops expansion.
+	s := NewSystem(nil)
+	kinds := []string{"Deployment"}
+	for i := 0; i <= MaxDepth; i++ {
+		kinds = append(kinds, fmt.Sprintf("Level%d", i))
+	}
+	for i := 0; i < len(kinds)-1; i++ {
+		applyTo := match.ApplyTo{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{kinds[i]}}
+		gvk := v1alpha1.GeneratedGVK{Group: "apps", Version: "v1", Kind: kinds[i+1]}
+		if err := s.Upsert(newTemplateObj(kinds[i], applyTo, "spec", gvk, v1alpha1.FieldMapping{From: "spec", To: "spec"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Expand(deployment(), nil); err == nil {
+		t.Errorf("got no error expanding %d levels, more than MaxDepth", len(kinds)-1)
+	}
+
+	s.Remove(kinds[len(kinds)-2])
+	got, err := s.Expand(deployment(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != MaxDepth {
+		t.Errorf("got %d resultants, want %d", len(got), MaxDepth)
+	}
+}
+
+func newAssign(t *testing.T, applyTo match.ApplyTo, location, value string) types.Mutator {
+	t.Helper()
+	a := &mutationsv1alpha1.Assign{
+		ObjectMeta: metav1.ObjectMeta{Name: location},
+		Spec: mutationsv1alpha1.AssignSpec{
+			ApplyTo:  []match.ApplyTo{applyTo},
+			Location: location,
+			Parameters: mutationsv1alpha1.Parameters{
+				Assign: runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"value": %q}`, value))},
+			},
+		},
+	}
+	m, err := mutators.MutatorForAssign(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestExpandMutation(t *testing.T) {
+	podsApplyTo := match.ApplyTo{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}
+	tcs := []struct {
+		name          string
+		viaReplicaSet bool
+		skipMutation  bool
+		want          map[string]interface{}
+	}{
+		{
+			name: "generated resource is mutated",
+			want: map[string]interface{}{"dnsPolicy": "Default"},
+		},
+		{
+			name:         "template skips mutation",
+			skipMutation: true,
+			want:         map[string]interface{}{},
+		},
+		{
+			name:          "resource is generated from its mutated parent",
+			viaReplicaSet: true,
+			want:          map[string]interface{}{"dnsPolicy": "Default", "priorityClassName": "low"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			mutationSystem := mutation.NewSystem(mutation.SystemOpts{})
+			for _, m := range []types.Mutator{
+				newAssign(t, podsApplyTo, "spec.dnsPolicy", "Default"),
+				newAssign(t, replicaSets, "spec.template.spec.priorityClassName", "low"),
+			} {
+				if err := mutationSystem.Upsert(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s := NewSystem(mutationSystem)
+			tmpls := []*v1alpha1.ExpansionTemplate{newTemplateObj("expand-deployments", deployments, "spec.template", pods)}
+			if tc.viaReplicaSet {
+				tmpls = []*v1alpha1.ExpansionTemplate{
+					newTemplateObj("expand-deployments", deployments, "", replicaSetsGVK, v1alpha1.FieldMapping{From: "spec", To: "spec"}),
+					newTemplateObj("expand-replicasets", replicaSets, "spec.template", pods),
+				}
+			}
+			for _, tmpl := range tmpls {
+				tmpl.Spec.SkipMutation = tc.skipMutation
+				if err := s.Upsert(tmpl); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := s.Expand(deployment(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pod := got[len(got)-1].Obj
+			spec, _, err := unstructured.NestedMap(pod.Object, "spec")
+			if err != nil {
+				t.Fatal(err)
+			}
+			delete(spec, "containers")
+			if diff := cmp.Diff(tc.want, spec); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestUpsertRemove(t *testing.T) {
+	s := NewSystem(nil)
+	if err := s.Upsert(newTemplateObj("expand-deployments", deployments, "spec.template", pods)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Expand(deployment(), nil); len(got) != 1 {
+		t.Fatalf("got %d resultants, want 1", len(got))
+	}
+	s.Remove("expand-deployments")
+	if got, _ := s.Expand(deployment(), nil); len(got) != 0 {
+		t.Errorf("got %d resultants after removal, want 0", len(got))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *v1alpha1.ExpansionTemplate {
+		return newTemplateObj("t", deployments, "spec.template", pods)
+	}
+	tcs := []struct {
+		name    string
+		mutate  func(*v1alpha1.ExpansionTemplate)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(*v1alpha1.ExpansionTemplate) {}},
+		{name: "no applyTo", mutate: func(t *v1alpha1.ExpansionTemplate) { t.Spec.ApplyTo = nil }, wantErr: true},
+		{name: "no generated kind", mutate: func(t *v1alpha1.ExpansionTemplate) { t.Spec.GeneratedGVK.Kind = "" }, wantErr: true},
+		{name: "no template source", mutate: func(t *v1alpha1.ExpansionTemplate) { t.Spec.TemplateSource = "" }, wantErr: true},
+		{
+			name: "field mappings without template source",
+			mutate: func(t *v1alpha1.ExpansionTemplate) {
+				t.Spec.TemplateSource = ""
+				t.Spec.FieldMappings = []v1alpha1.FieldMapping{{From: "spec", To: "spec"}}
+			},
+		},
+		{name: "list in template source", mutate: func(t *v1alpha1.ExpansionTemplate) { t.Spec.TemplateSource = "spec.containers[name: foo]" }, wantErr: true},
+		{name: "unparseable template source", mutate: func(t *v1alpha1.ExpansionTemplate) { t.Spec.TemplateSource = "spec..template" }, wantErr: true},
+		{name: "invalid enforcement action", mutate: func(t *v1alpha1.ExpansionTemplate) { t.Spec.EnforcementAction = "block" }, wantErr: true},
+		{
+			name: "quoted field mapping",
+			mutate: func(t *v1alpha1.ExpansionTemplate) {
+				t.Spec.FieldMappings = []v1alpha1.FieldMapping{{From: `metadata.labels."app.kubernetes.io/name"`, To: "metadata.labels.app"}}
+			},
+		},
+		{
+			name: "empty field mapping",
+			mutate: func(t *v1alpha1.ExpansionTemplate) {
+				t.Spec.FieldMappings = []v1alpha1.FieldMapping{{From: "metadata.labels"}}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl := valid()
+			tc.mutate(tmpl)
+			if err := Validate(tmpl); (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
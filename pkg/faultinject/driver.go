@@ -0,0 +1,62 @@
+package faultinject
+
+import (
+	"context"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+)
+
+// Wrap returns d, with the evaluation faults of f injected into its queries,
+// or d itself if f injects none.
+func (f *Faults) Wrap(d drivers.Driver) drivers.Driver {
+	if f.SlowEvaluation == 0 {
+		return d
+	}
+	return &faultDriver{Driver: d, faults: f}
+}
+
+type faultDriver struct {
+	drivers.Driver
+	faults *Faults
+}
+
+var _ drivers.Driver = &faultDriver{}
+
+func (d *faultDriver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	if err := sleep(ctx, d.faults.SlowEvaluation); err != nil {
+		return nil, err
+	}
+	return d.Driver.Query(ctx, path, input, opts...)
+}
+
+// CallProvider injects ProviderTimeout into a call of a custom builtin with
+// ctx: it returns ErrProviderTimeout once the duration has passed, or the
+// error of ctx if it is done first, as when the builtin times out before the
+// provider. It returns nil at once if ProviderTimeout is not injected.
+func (f *Faults) CallProvider(ctx context.Context) error {
+	if f.ProviderTimeout == 0 {
+		return nil
+	}
+	if err := sleep(ctx, f.ProviderTimeout); err != nil {
+		return err
+	}
+	return ErrProviderTimeout
+}
+
+// sleep waits for d, returning early with the error of ctx if it is done
+// first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d == 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,149 @@
+// Package faultinject injects faults into Gatekeeper, so that operators can
+// rehearse how their clusters behave when it is degraded, such as whether
+// the failurePolicy of the webhook is the one they want and whether their
+// alerts fire, before a real incident.
+//
+// Faults are injected only when they are set with --fault-injection and the
+// environment of the pod sets EnvVar to "true", so that they cannot be
+// enabled by a change of arguments alone.
+package faultinject
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// EnvVar is the environment variable which must be "true" for faults to be
+// injected.
+const EnvVar = "GATEKEEPER_FAULT_INJECTION"
+
+// The faults which can be injected.
+const (
+	// SlowEvaluation delays every policy query by the duration.
+	SlowEvaluation = "slow-evaluation"
+	// ProviderTimeout fails every call of a custom builtin, such as one
+	// fetching data from an external provider, after the duration, as if the
+	// provider timed out. The query calling it fails as it would for a real
+	// timeout, while queries calling no custom builtin are unaffected.
+	ProviderTimeout = "provider-timeout"
+	// InformerLag delays ingesting changes to synced resources by the
+	// duration, as if the informers watching them lagged behind.
+	InformerLag = "informer-lag"
+)
+
+var (
+	flagFaults = flag.String("fault-injection", "", "(alpha) faults to inject for chaos testing, as a comma-separated list of fault=duration. "+SlowEvaluation+" delays every policy query, "+ProviderTimeout+" fails every call of a custom builtin, such as one fetching external data, after the duration and "+InformerLag+" delays ingesting changes to synced resources. Requires "+EnvVar+"=true in the environment. Never set it in production")
+
+	log = logf.Log.WithName("fault-injection")
+
+	faults = &Faults{}
+)
+
+// ErrProviderTimeout is the error of the builtin calls failed by
+// ProviderTimeout.
+var ErrProviderTimeout = errors.New("injected fault: provider timed out")
+
+// Faults are the faults injected. The zero value injects none.
+type Faults struct {
+	SlowEvaluation  time.Duration
+	ProviderTimeout time.Duration
+	InformerLag     time.Duration
+}
+
+// Get returns the faults injected into this pod, which are only set once
+// Validate has returned.
+func Get() *Faults {
+	return faults
+}
+
+// Validate parses --fault-injection into the faults returned by Get,
+// returning an error if it is invalid or faults are not enabled in the
+// environment.
+func Validate() error {
+	if *flagFaults == "" {
+		return nil
+	}
+	if os.Getenv(EnvVar) != "true" {
+		return fmt.Errorf("--fault-injection requires %s=true in the environment", EnvVar)
+	}
+	f, err := Parse(*flagFaults)
+	if err != nil {
+		return fmt.Errorf("invalid --fault-injection: %w", err)
+	}
+	faults = f
+	log.Info("WARNING: injecting faults, this pod is degraded on purpose", "faults", f.String())
+	return nil
+}
+
+// Parse returns the Faults of a comma-separated list of fault=duration.
+func Parse(s string) (*Faults, error) {
+	f := &Faults{}
+	fields := map[string]*time.Duration{
+		SlowEvaluation:  &f.SlowEvaluation,
+		ProviderTimeout: &f.ProviderTimeout,
+		InformerLag:     &f.InformerLag,
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("fault %q must be of the form fault=duration", entry)
+		}
+		name := strings.TrimSpace(parts[0])
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown fault %q, must be one of %s", name, strings.Join(names(fields), ", "))
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("fault %s: %w", name, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("fault %s: duration must not be negative", name)
+		}
+		*field = d
+	}
+	return f, nil
+}
+
+func names(fields map[string]*time.Duration) []string {
+	out := make([]string, 0, len(fields))
+	for name := range fields {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Enabled returns whether any fault is injected.
+func (f *Faults) Enabled() bool {
+	return f.SlowEvaluation != 0 || f.ProviderTimeout != 0 || f.InformerLag != 0
+}
+
+// String returns the faults as they are set with --fault-injection.
+func (f *Faults) String() string {
+	var out []string
+	for _, fault := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{name: SlowEvaluation, d: f.SlowEvaluation},
+		{name: ProviderTimeout, d: f.ProviderTimeout},
+		{name: InformerLag, d: f.InformerLag},
+	} {
+		if fault.d != 0 {
+			out = append(out, fault.name+"="+fault.d.String())
+		}
+	}
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,187 @@
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		name    string
+		in      string
+		want    *Faults
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: &Faults{}},
+		{
+			name: "all",
+			in:   "slow-evaluation=2s, provider-timeout=10s,informer-lag=1m",
+			want: &Faults{SlowEvaluation: 2 * time.Second, ProviderTimeout: 10 * time.Second, InformerLag: time.Minute},
+		},
+		{name: "unknown fault", in: "slow-audit=2s", wantErr: true},
+		{name: "no duration", in: "slow-evaluation", wantErr: true},
+		{name: "invalid duration", in: "slow-evaluation=soon", wantErr: true},
+		{name: "negative duration", in: "informer-lag=-1s", wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	f, err := Parse("informer-lag=30s,slow-evaluation=1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.String(), "slow-evaluation=1s,informer-lag=30s"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	defer func(f string) {
+		*flagFaults = f
+		faults = &Faults{}
+	}(*flagFaults)
+	defer os.Unsetenv(EnvVar)
+
+	*flagFaults = "slow-evaluation=1s"
+	if err := Validate(); err == nil {
+		t.Errorf("got no error injecting faults without %s", EnvVar)
+	}
+	if Get().Enabled() {
+		t.Error("got faults injected without the environment enabling them")
+	}
+
+	os.Setenv(EnvVar, "true")
+	if err := Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := Get().SlowEvaluation; got != time.Second {
+		t.Errorf("got slow evaluation of %v, want 1s", got)
+	}
+}
+
+// fakeDriver answers every query with an empty response.
+type fakeDriver struct {
+	drivers.Driver
+	queries int
+}
+
+func (d *fakeDriver) Query(context.Context, string, interface{}, ...drivers.QueryOpt) (*types.Response, error) {
+	d.queries++
+	return &types.Response{}, nil
+}
+
+func TestWrap(t *testing.T) {
+	ctx := context.Background()
+	inner := &fakeDriver{}
+	if got := (&Faults{InformerLag: time.Second}).Wrap(inner); got != inner {
+		t.Error("got the driver wrapped without evaluation faults")
+	}
+
+	d := (&Faults{SlowEvaluation: 20 * time.Millisecond}).Wrap(inner)
+	start := time.Now()
+	if _, err := d.Query(ctx, "hooks", nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("got a query answered after %v, want it delayed", elapsed)
+	}
+	if inner.queries != 1 {
+		t.Errorf("got %d queries evaluated, want 1", inner.queries)
+	}
+
+	// Provider timeouts are injected into builtin calls, not queries.
+	if got := (&Faults{ProviderTimeout: time.Millisecond}).Wrap(inner); got != inner {
+		t.Error("got the driver wrapped with only a provider timeout")
+	}
+
+	// Delays end with the context of the query.
+	d = (&Faults{SlowEvaluation: time.Hour}).Wrap(inner)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := d.Query(canceled, "hooks", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCallProvider(t *testing.T) {
+	ctx := context.Background()
+	if err := (&Faults{SlowEvaluation: time.Hour}).CallProvider(ctx); err != nil {
+		t.Errorf("got error %v without a provider timeout", err)
+	}
+
+	start := time.Now()
+	if err := (&Faults{ProviderTimeout: 20 * time.Millisecond}).CallProvider(ctx); !errors.Is(err, ErrProviderTimeout) {
+		t.Errorf("got error %v, want %v", err, ErrProviderTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("got a call failed after %v, want it delayed", elapsed)
+	}
+
+	// A builtin timing out before the provider fails with its own timeout.
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if err := (&Faults{ProviderTimeout: time.Hour}).CallProvider(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// fakeQueue records the items added to it and their delays.
+type fakeQueue struct {
+	workqueue.RateLimitingInterface
+	added []interface{}
+	after []time.Duration
+}
+
+func (q *fakeQueue) Add(item interface{}) {
+	q.added = append(q.added, item)
+}
+
+func (q *fakeQueue) AddAfter(item interface{}, d time.Duration) {
+	q.added = append(q.added, item)
+	q.after = append(q.after, d)
+}
+
+func TestLag(t *testing.T) {
+	h := handler.Funcs{
+		GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			q.Add("request")
+		},
+	}
+	if _, ok := (&Faults{}).Lag(h).(handler.Funcs); !ok {
+		t.Error("got the handler wrapped without informer lag")
+	}
+
+	q := &fakeQueue{}
+	(&Faults{InformerLag: time.Minute}).Lag(h).Generic(event.GenericEvent{}, q)
+	if diff := cmp.Diff([]interface{}{"request"}, q.added); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]time.Duration{time.Minute}, q.after); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,52 @@
+package faultinject
+
+import (
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// Lag returns h, enqueuing requests after the InformerLag of f, or h itself
+// if f injects no lag. Reconcilers read objects when requests are dequeued,
+// so changes are ingested as late as if the informers had lagged behind.
+func (f *Faults) Lag(h handler.EventHandler) handler.EventHandler {
+	if f.InformerLag == 0 {
+		return h
+	}
+	return &lagHandler{EventHandler: h, lag: f.InformerLag}
+}
+
+type lagHandler struct {
+	handler.EventHandler
+	lag time.Duration
+}
+
+var _ handler.EventHandler = &lagHandler{}
+
+func (h *lagHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Create(e, &lagQueue{RateLimitingInterface: q, lag: h.lag})
+}
+
+func (h *lagHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Update(e, &lagQueue{RateLimitingInterface: q, lag: h.lag})
+}
+
+func (h *lagHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Delete(e, &lagQueue{RateLimitingInterface: q, lag: h.lag})
+}
+
+func (h *lagHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.EventHandler.Generic(e, &lagQueue{RateLimitingInterface: q, lag: h.lag})
+}
+
+// lagQueue adds items after a lag.
+type lagQueue struct {
+	workqueue.RateLimitingInterface
+	lag time.Duration
+}
+
+func (q *lagQueue) Add(item interface{}) {
+	q.AddAfter(item, q.lag)
+}
@@ -0,0 +1,117 @@
+package gatekeeperstatus
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/audit"
+	"github.com/open-policy-agent/gatekeeper/pkg/builtins"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const certFile = "tls.crt"
+
+// CertificateProbe reports on the certificate served by the webhook from
+// certDir.
+func CertificateProbe(certDir string) func() *v1beta1.CertificateStatus {
+	return func() *v1beta1.CertificateStatus {
+		return certificateStatus(filepath.Join(certDir, certFile), time.Now())
+	}
+}
+
+func certificateStatus(path string, now time.Time) *v1beta1.CertificateStatus {
+	cert, err := readCertificate(path)
+	if err != nil {
+		return &v1beta1.CertificateStatus{Error: err.Error()}
+	}
+	notAfter := metav1.NewTime(cert.NotAfter)
+	return &v1beta1.CertificateStatus{
+		Valid:    !now.Before(cert.NotBefore) && now.Before(cert.NotAfter),
+		NotAfter: &notAfter,
+	}
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found in " + path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// SyncProbe reports the data replicated into state.
+func SyncProbe(state *introspection.State) func() *v1beta1.SyncStatus {
+	return func() *v1beta1.SyncStatus {
+		kinds := state.SyncedKinds()
+		s := &v1beta1.SyncStatus{Kinds: len(kinds)}
+		for _, k := range kinds {
+			s.Objects += k.Objects
+			s.Errors += k.Errors
+		}
+		return s
+	}
+}
+
+// AuditProbe reports when audit last ran in this process, and what the last
+// complete audit evaluated.
+func AuditProbe() func() *v1beta1.AuditStatus {
+	return func() *v1beta1.AuditStatus {
+		s := auditStatus(audit.LastRun())
+		s.Coverage = audit.LastCoverage()
+		return s
+	}
+}
+
+func auditStatus(start, end time.Time) *v1beta1.AuditStatus {
+	s := &v1beta1.AuditStatus{}
+	if !start.IsZero() {
+		t := metav1.NewTime(start)
+		s.LastRunStartTime = &t
+	}
+	if !end.IsZero() {
+		t := metav1.NewTime(end)
+		s.LastRunEndTime = &t
+	}
+	return s
+}
+
+// ProviderProbe reports the health of the custom builtins, which may fetch
+// data from external providers.
+func ProviderProbe() func() []v1beta1.ProviderStatus {
+	return func() []v1beta1.ProviderStatus {
+		return providerStatuses(builtins.Healths())
+	}
+}
+
+func providerStatuses(healths []builtins.Health) []v1beta1.ProviderStatus {
+	var statuses []v1beta1.ProviderStatus
+	for _, h := range healths {
+		s := v1beta1.ProviderStatus{
+			Name:      h.Name,
+			Healthy:   h.Healthy(),
+			Calls:     h.Calls,
+			Errors:    h.Errors,
+			LastError: h.LastError,
+		}
+		if !h.LastErrorTime.IsZero() {
+			t := metav1.NewTime(h.LastErrorTime)
+			s.LastErrorTime = &t
+		}
+		if !h.LastSuccessTime.IsZero() {
+			t := metav1.NewTime(h.LastSuccessTime)
+			s.LastSuccessTime = &t
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
@@ -0,0 +1,120 @@
+package gatekeeperstatus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/builtins"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+)
+
+func writeCertificate(t *testing.T, path string, notBefore, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gatekeeper-webhook-service.gatekeeper-system.svc"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificateStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gatekeeper-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now().Truncate(time.Second)
+	path := filepath.Join(dir, certFile)
+	writeCertificate(t, path, now.Add(-time.Hour), now.Add(time.Hour))
+
+	s := certificateStatus(path, now)
+	if !s.Valid || s.Error != "" {
+		t.Errorf("got %+v, want a valid certificate", s)
+	}
+	if !s.NotAfter.Time.Equal(now.Add(time.Hour)) {
+		t.Errorf("got notAfter %v, want %v", s.NotAfter.Time, now.Add(time.Hour))
+	}
+
+	if s := certificateStatus(path, now.Add(2*time.Hour)); s.Valid {
+		t.Errorf("got %+v, want an expired certificate", s)
+	}
+
+	if s := certificateStatus(filepath.Join(dir, "missing.crt"), now); s.Valid || s.Error == "" {
+		t.Errorf("got %+v, want an error for a missing certificate", s)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if s := certificateStatus(path, now); s.Valid || s.Error == "" {
+		t.Errorf("got %+v, want an error for an invalid certificate", s)
+	}
+}
+
+func TestSyncProbe(t *testing.T) {
+	state := introspection.New()
+	probe := SyncProbe(state)
+	if s := probe(); s.Kinds != 0 || s.Objects != 0 {
+		t.Errorf("got %+v, want nothing synced", s)
+	}
+
+	state.SetSyncSource(func() []introspection.SyncedKind {
+		return []introspection.SyncedKind{{Kind: "Pod", Objects: 3}, {Kind: "Namespace", Objects: 2, Errors: 1}}
+	})
+	s := probe()
+	if s.Kinds != 2 || s.Objects != 5 || s.Errors != 1 {
+		t.Errorf("got %+v, want 2 kinds with 5 objects and 1 error", s)
+	}
+}
+
+func TestAuditStatus(t *testing.T) {
+	if s := auditStatus(time.Time{}, time.Time{}); s.LastRunStartTime != nil || s.LastRunEndTime != nil {
+		t.Errorf("got %+v, want no audit run", s)
+	}
+
+	start := time.Now()
+	s := auditStatus(start, time.Time{})
+	if s.LastRunStartTime == nil || !s.LastRunStartTime.Time.Equal(start) || s.LastRunEndTime != nil {
+		t.Errorf("got %+v, want a running audit", s)
+	}
+}
+
+func TestProviderStatuses(t *testing.T) {
+	failed := time.Now()
+	got := providerStatuses([]builtins.Health{
+		{Name: "example.never_called"},
+		{Name: "example.failing", Calls: 2, Errors: 1, LastError: "timed out", LastErrorTime: failed, LastSuccessTime: failed.Add(-time.Minute)},
+	})
+	if len(got) != 2 {
+		t.Fatalf("got %d statuses, want 2", len(got))
+	}
+	if s := got[0]; !s.Healthy || s.LastErrorTime != nil || s.LastSuccessTime != nil {
+		t.Errorf("got %+v, want a healthy provider never called", s)
+	}
+	if s := got[1]; s.Healthy || s.Calls != 2 || s.Errors != 1 || s.LastError != "timed out" || s.LastErrorTime == nil || !s.LastErrorTime.Time.Equal(failed) {
+		t.Errorf("got %+v, want an unhealthy provider", s)
+	}
+}
@@ -0,0 +1,186 @@
+package gatekeeperstatus
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/operations"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	defaultInterval = 30 * time.Second
+	// expiryIntervals is how many intervals a pod may miss reporting before
+	// its entry is removed by other pods.
+	expiryIntervals = 4
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	// Enabled is whether each pod reports its health to the GatekeeperStatus.
+	Enabled = flag.Bool("enable-gatekeeper-status", false, "(alpha) report the health of this pod to the cluster-scoped GatekeeperStatus resource named `gatekeeper`")
+
+	log = logf.Log.WithName("gatekeeper-status")
+)
+
+// Probes observe the health of this pod. Health with a nil probe is not
+// reported.
+type Probes struct {
+	Ready              func() bool
+	WebhookCertificate func() *v1beta1.CertificateStatus
+	Sync               func() *v1beta1.SyncStatus
+	Audit              func() *v1beta1.AuditStatus
+	PolicyFreeze       func() *v1beta1.PolicyFreezeStatus
+	Providers          func() []v1beta1.ProviderStatus
+}
+
+var _ manager.Runnable = &Reporter{}
+
+// Reporter periodically records the health of this pod in the
+// GatekeeperStatus singleton, creating it if needed. Entries of pods which
+// stopped reporting are removed.
+type Reporter struct {
+	client     client.Client
+	reader     client.Reader
+	id         string
+	operations []string
+	probes     Probes
+	interval   time.Duration
+	now        func() time.Time
+}
+
+// New creates a Reporter for this pod. Reads are made with reader so the
+// GatekeeperStatus does not need to be cached.
+func New(c client.Client, reader client.Reader, probes Probes) *Reporter {
+	return &Reporter{
+		client:     c,
+		reader:     reader,
+		id:         util.GetID(),
+		operations: operations.AssignedStringList(),
+		probes:     probes,
+		interval:   defaultInterval,
+		now:        time.Now,
+	}
+}
+
+// Start implements manager.Runnable. The entry of this pod is removed when
+// ctx is canceled.
+func (r *Reporter) Start(ctx context.Context) error {
+	log.Info("reporting pod health", "id", r.id)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.report(ctx); err != nil {
+			log.Error(err, "failed to report pod health")
+		}
+		select {
+		case <-ctx.Done():
+			removeCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := r.remove(removeCtx); err != nil {
+				log.Error(err, "failed to remove pod health")
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every pod
+// reports its own health.
+func (r *Reporter) NeedLeaderElection() bool {
+	return false
+}
+
+// health returns the current health of this pod.
+func (r *Reporter) health() v1beta1.GatekeeperPodHealth {
+	h := v1beta1.GatekeeperPodHealth{
+		ID:                r.id,
+		Operations:        r.operations,
+		LastHeartbeatTime: metav1.NewTime(r.now()),
+	}
+	if r.probes.Ready != nil {
+		h.Ready = r.probes.Ready()
+	}
+	if r.probes.WebhookCertificate != nil {
+		h.WebhookCertificate = r.probes.WebhookCertificate()
+	}
+	if r.probes.Sync != nil {
+		h.Sync = r.probes.Sync()
+	}
+	if r.probes.Audit != nil {
+		h.Audit = r.probes.Audit()
+	}
+	if r.probes.PolicyFreeze != nil {
+		h.PolicyFreeze = r.probes.PolicyFreeze()
+	}
+	if r.probes.Providers != nil {
+		h.Providers = r.probes.Providers()
+	}
+	return h
+}
+
+func (r *Reporter) report(ctx context.Context) error {
+	h := r.health()
+	return r.update(ctx, func(pods []v1beta1.GatekeeperPodHealth) []v1beta1.GatekeeperPodHealth {
+		return append(pods, h)
+	})
+}
+
+func (r *Reporter) remove(ctx context.Context) error {
+	return r.update(ctx, func(pods []v1beta1.GatekeeperPodHealth) []v1beta1.GatekeeperPodHealth {
+		return pods
+	})
+}
+
+// update replaces the entry of this pod with the result of mutate, which is
+// passed every other unexpired entry. Conflicting writes by other pods are
+// retried.
+func (r *Reporter) update(ctx context.Context, mutate func([]v1beta1.GatekeeperPodHealth) []v1beta1.GatekeeperPodHealth) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		status := &v1beta1.GatekeeperStatus{}
+		err := r.reader.Get(ctx, types.NamespacedName{Name: v1beta1.GatekeeperStatusName}, status)
+		create := apierrors.IsNotFound(err)
+		if err != nil && !create {
+			return err
+		}
+		if create {
+			status = &v1beta1.GatekeeperStatus{}
+			status.SetName(v1beta1.GatekeeperStatusName)
+		}
+
+		expiry := r.now().Add(-expiryIntervals * r.interval)
+		var pods []v1beta1.GatekeeperPodHealth
+		for _, p := range status.Status.Pods {
+			if p.ID == r.id || p.LastHeartbeatTime.Time.Before(expiry) {
+				continue
+			}
+			pods = append(pods, p)
+		}
+		pods = mutate(pods)
+		sort.Slice(pods, func(i, j int) bool {
+			return pods[i].ID < pods[j].ID
+		})
+		status.Status.Pods = pods
+
+		if create {
+			err := r.client.Create(ctx, status)
+			if apierrors.IsAlreadyExists(err) {
+				// Another pod created the status first, retry as an update.
+				return apierrors.NewConflict(v1beta1.GroupVersion.WithResource("gatekeeperstatuses").GroupResource(), v1beta1.GatekeeperStatusName, err)
+			}
+			return err
+		}
+		return r.client.Update(ctx, status)
+	})
+}
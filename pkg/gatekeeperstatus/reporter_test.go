@@ -0,0 +1,147 @@
+package gatekeeperstatus
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var gatekeeperStatusResource = schema.GroupResource{Group: v1beta1.GroupVersion.Group, Resource: "gatekeeperstatuses"}
+
+// fakeClient stores a single GatekeeperStatus, rejecting writes made with a
+// stale resourceVersion.
+type fakeClient struct {
+	client.Client
+	status    *v1beta1.GatekeeperStatus
+	version   int
+	conflicts int
+}
+
+func (f *fakeClient) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	if f.status == nil {
+		return apierrors.NewNotFound(gatekeeperStatusResource, key.Name)
+	}
+	f.status.DeepCopyInto(obj.(*v1beta1.GatekeeperStatus))
+	return nil
+}
+
+func (f *fakeClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	if f.status != nil {
+		return apierrors.NewAlreadyExists(gatekeeperStatusResource, obj.GetName())
+	}
+	f.store(obj.(*v1beta1.GatekeeperStatus))
+	return nil
+}
+
+func (f *fakeClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	if f.conflicts > 0 {
+		f.conflicts--
+		return apierrors.NewConflict(gatekeeperStatusResource, obj.GetName(), nil)
+	}
+	if f.status == nil {
+		return apierrors.NewNotFound(gatekeeperStatusResource, obj.GetName())
+	}
+	if obj.GetResourceVersion() != f.status.GetResourceVersion() {
+		return apierrors.NewConflict(gatekeeperStatusResource, obj.GetName(), nil)
+	}
+	f.store(obj.(*v1beta1.GatekeeperStatus))
+	return nil
+}
+
+func (f *fakeClient) store(status *v1beta1.GatekeeperStatus) {
+	f.version++
+	f.status = status.DeepCopy()
+	f.status.SetResourceVersion(strconv.Itoa(f.version))
+}
+
+func newTestReporter(c *fakeClient, id string, now time.Time) *Reporter {
+	r := New(c, c, Probes{
+		Ready: func() bool { return true },
+		Sync:  func() *v1beta1.SyncStatus { return &v1beta1.SyncStatus{Kinds: 1, Objects: 2} },
+	})
+	r.id = id
+	r.operations = []string{"webhook"}
+	r.now = func() time.Time { return now }
+	return r
+}
+
+func podIDs(c *fakeClient) []string {
+	var ids []string
+	for _, p := range c.status.Status.Pods {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestReporter(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &fakeClient{}
+
+	b := newTestReporter(c, "b", start)
+	if err := b.report(ctx); err != nil {
+		t.Fatal(err)
+	}
+	a := newTestReporter(c, "a", start)
+	if err := a.report(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.status.GetName() != v1beta1.GatekeeperStatusName {
+		t.Errorf("got name %q, want %q", c.status.GetName(), v1beta1.GatekeeperStatusName)
+	}
+	want := v1beta1.GatekeeperPodHealth{
+		ID:                "a",
+		Operations:        []string{"webhook"},
+		Ready:             true,
+		Sync:              &v1beta1.SyncStatus{Kinds: 1, Objects: 2},
+		LastHeartbeatTime: metav1.NewTime(start),
+	}
+	if diff := cmp.Diff(want, c.status.Status.Pods[0]); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, podIDs(c)); diff != "" {
+		t.Error(diff)
+	}
+
+	// a reports again after b has missed enough reports to expire.
+	a.now = func() time.Time { return start.Add(expiryIntervals*a.interval + time.Second) }
+	c.conflicts = 1
+	if err := a.report(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a"}, podIDs(c)); diff != "" {
+		t.Error(diff)
+	}
+
+	if err := a.remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string(nil), podIDs(c)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestReporterStopsOnCancel(t *testing.T) {
+	c := &fakeClient{}
+	r := newTestReporter(c, "a", time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.status == nil {
+		t.Fatal("status was not created")
+	}
+	if diff := cmp.Diff([]string(nil), podIDs(c)); diff != "" {
+		t.Errorf("pod was not removed on shutdown: %s", diff)
+	}
+}
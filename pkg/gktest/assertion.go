@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -24,8 +25,16 @@ type Assertion struct {
 	// Defaults to "yes".
 	Violations *intstr.IntOrString `json:"violations,omitempty"`
 
+	// Warnings, if set, indicates either whether there are warnings, or how many
+	// warnings match this assertion, in the same way as Violations. Warnings are
+	// the violations of Constraints with the "warn" enforcement action, which
+	// are returned to the user without denying the object.
+	//
+	// If Warnings is set and Violations is not, violations are not counted.
+	Warnings *intstr.IntOrString `json:"warnings,omitempty"`
+
 	// Message is a regular expression which matches the Msg field of individual
-	// violations.
+	// violations and warnings.
 	//
 	// If unset, has no effect and all violations match this Assertion.
 	Message *string `json:"message,omitempty"`
@@ -36,10 +45,15 @@ type Assertion struct {
 
 func (a *Assertion) Run(results []*types.Result) error {
 	matching := int32(0)
-	var messages []string
+	matchingWarnings := int32(0)
+	var messages, warnings []string
 
 	for _, r := range results {
 		messages = append(messages, r.Msg)
+		isWarning := r.EnforcementAction == string(util.Warn)
+		if isWarning {
+			warnings = append(warnings, r.Msg)
+		}
 
 		matches, err := a.matches(r)
 		if err != nil {
@@ -48,6 +62,19 @@ func (a *Assertion) Run(results []*types.Result) error {
 
 		if matches {
 			matching++
+			if isWarning {
+				matchingWarnings++
+			}
+		}
+	}
+
+	if a.Warnings != nil {
+		err := matchesCount(a.Warnings, matchingWarnings, "warnings", ErrNumWarnings)
+		if err != nil {
+			return fmt.Errorf("%w: got warnings %v", err, warnings)
+		}
+		if a.Violations == nil {
+			return nil
 		}
 	}
 
@@ -56,7 +83,7 @@ func (a *Assertion) Run(results []*types.Result) error {
 		a.Violations = intStrFromStr("yes")
 	}
 
-	err := a.matchesCount(matching)
+	err := matchesCount(a.Violations, matching, "violations", ErrNumViolations)
 	if err != nil {
 		return fmt.Errorf("%w: got messages %v", err, messages)
 	}
@@ -64,49 +91,51 @@ func (a *Assertion) Run(results []*types.Result) error {
 	return nil
 }
 
-func (a *Assertion) matchesCount(matching int32) error {
-	switch a.Violations.Type {
+// matchesCount returns an error wrapping errNum unless matching of the field
+// want is wanted.
+func matchesCount(want *intstr.IntOrString, matching int32, field string, errNum error) error {
+	switch want.Type {
 	case intstr.Int:
-		return a.matchesCountInt(matching)
+		return matchesCountInt(want, matching, field, errNum)
 	case intstr.String:
-		return a.matchesCountStr(matching)
+		return matchesCountStr(want, matching, field, errNum)
 	default:
 		// Requires a bug in intstr unmarshalling code, or a misuse of the IntOrStr
 		// type in Go code.
-		return fmt.Errorf("%w: assertion.violations improperly parsed to type %d",
-			ErrInvalidYAML, a.Violations.Type)
+		return fmt.Errorf("%w: assertion.%s improperly parsed to type %d",
+			ErrInvalidYAML, field, want.Type)
 	}
 }
 
-func (a *Assertion) matchesCountInt(matching int32) error {
-	wantMatching := a.Violations.IntVal
+func matchesCountInt(want *intstr.IntOrString, matching int32, field string, errNum error) error {
+	wantMatching := want.IntVal
 	if wantMatching != matching {
-		return fmt.Errorf("%w: got %d violations but want exactly %d",
-			ErrNumViolations, matching, wantMatching)
+		return fmt.Errorf("%w: got %d %s but want exactly %d",
+			errNum, matching, field, wantMatching)
 	}
 
 	return nil
 }
 
-func (a *Assertion) matchesCountStr(matching int32) error {
-	switch a.Violations.StrVal {
+func matchesCountStr(want *intstr.IntOrString, matching int32, field string, errNum error) error {
+	switch want.StrVal {
 	case "yes":
 		if matching == 0 {
-			return fmt.Errorf("%w: got %d violations but want at least %d",
-				ErrNumViolations, matching, 1)
+			return fmt.Errorf("%w: got %d %s but want at least %d",
+				errNum, matching, field, 1)
 		}
 
 		return nil
 	case "no":
 		if matching > 0 {
-			return fmt.Errorf("%w: got %d violations but want none",
-				ErrNumViolations, matching)
+			return fmt.Errorf("%w: got %d %s but want none",
+				errNum, matching, field)
 		}
 
 		return nil
 	default:
-		return fmt.Errorf(`%w: assertion.violation, if set, must be an integer, "yes", or "no"`,
-			ErrInvalidYAML)
+		return fmt.Errorf(`%w: assertion.%s, if set, must be an integer, "yes", or "no"`,
+			ErrInvalidYAML, field)
 	}
 }
 
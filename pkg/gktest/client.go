@@ -24,6 +24,9 @@ type Client interface {
 	// AddData adds the state of the cluster. For use in referential Constraints.
 	AddData(ctx context.Context, data interface{}) (*types.Responses, error)
 
+	// RemoveData removes state of the cluster added with AddData.
+	RemoveData(ctx context.Context, data interface{}) (*types.Responses, error)
+
 	// Review runs all Constraints against obj.
 	Review(ctx context.Context, obj interface{}, opts ...client.QueryOpt) (*types.Responses, error)
 }
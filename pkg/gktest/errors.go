@@ -23,7 +23,38 @@ var (
 	// ErrNumViolations indicates an Object did not get the expected number of
 	// violations.
 	ErrNumViolations = errors.New("unexpected number of violations")
+	// ErrNumWarnings indicates an Object did not get the expected number of
+	// warnings.
+	ErrNumWarnings = errors.New("unexpected number of warnings")
 	// ErrInvalidRegex indicates a Case specified a Violation regex that could not
 	// be compiled.
 	ErrInvalidRegex = errors.New("message contains invalid regular expression")
+	// ErrAddingExpansionTemplate indicates an ExpansionTemplate is not valid.
+	ErrAddingExpansionTemplate = errors.New("adding ExpansionTemplate")
+	// ErrAddingMutator indicates a mutator could not be added, for example
+	// because it conflicts with another mutator.
+	ErrAddingMutator = errors.New("adding mutator")
+	// ErrAddingException indicates an Exception could not be added, for
+	// example because it is missing an expiry.
+	ErrAddingException = errors.New("adding Exception")
+	// ErrExpanding indicates the resources generated from an object could not be
+	// expanded or mutated.
+	ErrExpanding = errors.New("expanding object")
+	// ErrRendering indicates a Case's object could not be rendered with the
+	// Suite's values.
+	ErrRendering = errors.New("rendering object")
+	// ErrMutated indicates a Case asserted its object is not mutated, but a
+	// mutator changed it.
+	ErrMutated = errors.New("object was mutated")
+	// ErrDenyMessage indicates a Case's object was not denied with a message
+	// matching AssertDenyMessage.
+	ErrDenyMessage = errors.New("unexpected deny message")
+	// ErrRegoTests indicates the Rego unit tests of a Template could not be
+	// parsed or compiled.
+	ErrRegoTests = errors.New("running Rego tests")
+	// ErrRegoTestFailed indicates a Rego unit test of a Template failed.
+	ErrRegoTestFailed = errors.New("rego test failed")
+	// ErrAddingInventory indicates an object of a Test's or Case's Inventory
+	// could not be added to data.inventory.
+	ErrAddingInventory = errors.New("adding inventory")
 )
@@ -0,0 +1,64 @@
+package gktest
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// readException reads the contents of path and returns the Exception it
+// defines.
+func readException(f fs.FS, path string) (*exceptionsv1alpha1.Exception, error) {
+	bytes, err := fs.ReadFile(f, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading Exception from %q: %w", path, err)
+	}
+
+	u, err := readUnstructured(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing Exception YAML from %q: %v", ErrAddingException, path, err)
+	}
+	if u.GroupVersionKind() != exceptionsv1alpha1.GroupVersion.WithKind("Exception") {
+		return nil, fmt.Errorf("%w: %q does not define an Exception", ErrAddingException, path)
+	}
+
+	e := &exceptionsv1alpha1.Exception{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, e); err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrAddingException, path, err)
+	}
+	return e, nil
+}
+
+// makeExceptionSystem returns an exception System with the Test's Exceptions,
+// or nil if the Test has none.
+func (r *Runner) makeExceptionSystem(suiteDir string, t Test) (*exception.System, error) {
+	if len(t.Exceptions) == 0 {
+		return nil, nil
+	}
+
+	system := exception.NewSystem()
+	for _, path := range t.Exceptions {
+		e, err := readException(r.FS, filepath.Join(suiteDir, path))
+		if err != nil {
+			return nil, err
+		}
+		if err := system.Upsert(e); err != nil {
+			return nil, fmt.Errorf("%w %s: %v", ErrAddingException, e.GetName(), err)
+		}
+	}
+	return system, nil
+}
+
+// exempt returns the results of the review of u which no Exception of system
+// exempts, as the webhook would. Objects in a namespace are matched as if
+// their namespace had no labels.
+func exempt(system *exception.System, u *unstructured.Unstructured, results []*types.Result) []*types.Result {
+	kept, _ := system.Filter(results, namespaceOf(u, nil))
+	return kept
+}
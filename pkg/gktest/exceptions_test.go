@@ -0,0 +1,116 @@
+package gktest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	exceptionExemptNamespace = `
+apiVersion: exceptions.gatekeeper.sh/v1alpha1
+kind: Exception
+metadata:
+  name: exempt-namespace
+spec:
+  constraints:
+  - kind: NeverValidate
+  match:
+    namespaces: ["exempt"]
+  expiresAt: "2999-01-01T00:00:00Z"
+  justification:
+    reason: migrating
+`
+
+	exceptionExpired = `
+apiVersion: exceptions.gatekeeper.sh/v1alpha1
+kind: Exception
+metadata:
+  name: expired
+spec:
+  constraints:
+  - kind: NeverValidate
+  expiresAt: "2000-01-01T00:00:00Z"
+  justification:
+    reason: migrated
+`
+
+	exceptionWithoutReason = `
+apiVersion: exceptions.gatekeeper.sh/v1alpha1
+kind: Exception
+metadata:
+  name: without-reason
+spec:
+  constraints:
+  - kind: NeverValidate
+  expiresAt: "2999-01-01T00:00:00Z"
+`
+)
+
+func TestRunner_Run_Exceptions(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"template.yaml":       &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"constraint.yaml":     &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+		"exempt-ns.yaml":      &fstest.MapFile{Data: []byte(exceptionExemptNamespace)},
+		"expired.yaml":        &fstest.MapFile{Data: []byte(exceptionExpired)},
+		"without-reason.yaml": &fstest.MapFile{Data: []byte(exceptionWithoutReason)},
+		"default.yaml":        &fstest.MapFile{Data: []byte(objectInDefault)},
+		"exempt.yaml":         &fstest.MapFile{Data: []byte(objectInExempt)},
+	}
+
+	violations := []Assertion{{Violations: intStrFromStr("yes")}}
+	suite := &Suite{
+		Tests: []Test{{
+			Name:       "exceptions",
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Exceptions: []string{"exempt-ns.yaml", "expired.yaml"},
+			Cases: []Case{{
+				Name:   "exempted",
+				Object: "exempt.yaml",
+			}, {
+				Name:       "not exempted",
+				Object:     "default.yaml",
+				Assertions: violations,
+			}},
+		}, {
+			Name:       "invalid exception",
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Exceptions: []string{"without-reason.yaml"},
+		}, {
+			Name:       "not an exception",
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Exceptions: []string{"constraint.yaml"},
+		}},
+	}
+
+	runner := Runner{FS: fileSystem, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "suite.yaml", suite)
+
+	want := SuiteResult{
+		Path: "suite.yaml",
+		TestResults: []TestResult{{
+			Name: "exceptions",
+			CaseResults: []CaseResult{
+				{Name: "exempted"},
+				{Name: "not exempted"},
+			},
+		}, {
+			Name:  "invalid exception",
+			Error: ErrAddingException,
+		}, {
+			Name:  "not an exception",
+			Error: ErrAddingException,
+		}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+}
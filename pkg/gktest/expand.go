@@ -0,0 +1,93 @@
+package gktest
+
+import (
+	"fmt"
+	"strings"
+
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// AnnotationParent is set on expanded resources to the kind and name of
+	// the resource they were generated from, for example
+	// "Deployment.apps/nginx".
+	AnnotationParent = "expansion.gatekeeper.sh/parent"
+	// AnnotationTemplates is set on expanded resources to the names of the
+	// ExpansionTemplates which generated them, starting from the parent.
+	AnnotationTemplates = "expansion.gatekeeper.sh/templates"
+	// AnnotationSourcePath is set on expanded resources to the field of the
+	// parent they were generated from, if they were copied from a single field.
+	AnnotationSourcePath = "expansion.gatekeeper.sh/source-path"
+)
+
+// Expand returns the resources Gatekeeper generates from resources with
+// expansionTemplates and reviews at admission, after applying mutators to
+// them. Each is annotated with the resource it was generated from.
+//
+// Namespaces in resources are used to match mutators against the resources in
+// them. Resources in other namespaces are matched as if their namespace had
+// no labels.
+func Expand(resources []*unstructured.Unstructured, expansionTemplates []*expansionv1alpha1.ExpansionTemplate, mutators []types.Mutator) ([]*unstructured.Unstructured, error) {
+	mutationSystem := mutation.NewSystem(mutation.SystemOpts{})
+	for _, m := range mutators {
+		if err := mutationSystem.Upsert(m); err != nil {
+			return nil, fmt.Errorf("%w %v: %v", ErrAddingMutator, m.ID(), err)
+		}
+	}
+	system := expansion.NewSystem(mutationSystem)
+	for _, t := range expansionTemplates {
+		if err := system.Upsert(t); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAddingExpansionTemplate, err)
+		}
+	}
+
+	namespaces := make(map[string]*corev1.Namespace)
+	for _, r := range resources {
+		if r.GroupVersionKind() != corev1.SchemeGroupVersion.WithKind("Namespace") {
+			continue
+		}
+		ns := &corev1.Namespace{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(r.Object, ns); err != nil {
+			return nil, fmt.Errorf("%w: reading Namespace %s: %v", ErrExpanding, r.GetName(), err)
+		}
+		namespaces[ns.Name] = ns
+	}
+
+	var expanded []*unstructured.Unstructured
+	for _, r := range resources {
+		var ns *corev1.Namespace
+		if r.GetNamespace() != "" {
+			ns = namespaces[r.GetNamespace()]
+			if ns == nil {
+				ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: r.GetNamespace()}}
+			}
+		}
+
+		resultants, err := system.Expand(r, ns)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrExpanding, err)
+		}
+		parent := r.GroupVersionKind().GroupKind().String() + "/" + r.GetName()
+		for _, res := range resultants {
+			annotations := res.Obj.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[AnnotationParent] = parent
+			annotations[AnnotationTemplates] = strings.Join(res.TemplateNames(), ",")
+			if res.SourcePath != "" {
+				annotations[AnnotationSourcePath] = res.SourcePath
+			}
+			res.Obj.SetAnnotations(annotations)
+			expanded = append(expanded, res.Obj)
+		}
+	}
+	return expanded, nil
+}
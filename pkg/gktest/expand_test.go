@@ -0,0 +1,113 @@
+package gktest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const expandDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: prod
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx
+`
+
+const expandNamespace = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+  labels:
+    env: prod
+`
+
+func TestExpand(t *testing.T) {
+	deployment, err := readUnstructured([]byte(expandDeployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespace, err := readUnstructured([]byte(expandNamespace))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &expansionv1alpha1.ExpansionTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "expand-deployments"},
+		Spec: expansionv1alpha1.ExpansionTemplateSpec{
+			ApplyTo:        []match.ApplyTo{{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{"Deployment"}}},
+			TemplateSource: "spec.template",
+			GeneratedGVK:   expansionv1alpha1.GeneratedGVK{Version: "v1", Kind: "Pod"},
+		},
+	}
+	// Only Pods in namespaces labeled env: prod are mutated, so the labels of
+	// the Namespace must be used.
+	assign := &mutationsv1alpha1.Assign{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-priority"},
+		Spec: mutationsv1alpha1.AssignSpec{
+			ApplyTo: []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}},
+			Match: match.Match{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+			Location: "spec.priorityClassName",
+			Parameters: mutationsv1alpha1.Parameters{
+				Assign: runtime.RawExtension{Raw: []byte(`{"value": "high"}`)},
+			},
+		},
+	}
+	m, err := mutators.MutatorForAssign(assign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Expand([]*unstructured.Unstructured{namespace, deployment}, []*expansionv1alpha1.ExpansionTemplate{tmpl}, []types.Mutator{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*unstructured.Unstructured{{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "nginx",
+			"namespace": "prod",
+			"annotations": map[string]interface{}{
+				AnnotationParent:     "Deployment.apps/nginx",
+				AnnotationTemplates:  "expand-deployments",
+				AnnotationSourcePath: "spec.template",
+			},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx"},
+			},
+			"priorityClassName": "high",
+		},
+	}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestExpandInvalidTemplate(t *testing.T) {
+	tmpl := &expansionv1alpha1.ExpansionTemplate{ObjectMeta: metav1.ObjectMeta{Name: "no-apply-to"}}
+	_, err := Expand(nil, []*expansionv1alpha1.ExpansionTemplate{tmpl}, nil)
+	if !errors.Is(err, ErrAddingExpansionTemplate) {
+		t.Errorf("got error %v, want %v", err, ErrAddingExpansionTemplate)
+	}
+}
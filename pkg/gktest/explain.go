@@ -0,0 +1,336 @@
+package gktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Rule is a Constraint or mutator which applies to an object.
+type Rule struct {
+	// Path is the file the Rule was read from.
+	Path string `json:"path"`
+	// Kind and Name identify the Rule.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// EnforcementAction is the enforcement action of a Constraint, and empty
+	// for mutators.
+	EnforcementAction string `json:"enforcementAction,omitempty"`
+	// Criteria are the match criteria the object satisfied, such as
+	// "namespaces: [prod]". Empty if the Rule applies to every object.
+	Criteria []string `json:"criteria,omitempty"`
+}
+
+// Explanation is what Gatekeeper would do with an object.
+type Explanation struct {
+	// Constraints are the Constraints which would review the object.
+	Constraints []Rule `json:"constraints,omitempty"`
+	// Mutators are the mutators which would mutate the object.
+	Mutators []Rule `json:"mutators,omitempty"`
+}
+
+// Explain returns the Constraints and mutators which would apply to obj when
+// it is created, read from the files selected by target in the same way as
+// ReadSuites finds Suites. Files which define neither are skipped. ns is the
+// Namespace of obj; if it is nil, the Namespace of a namespaced obj is assumed
+// to have no labels. clusterSelectors are matched against cluster.Labels.
+func Explain(f fs.FS, target string, recursive bool, obj *unstructured.Unstructured, ns *corev1.Namespace) (*Explanation, error) {
+	if f == nil {
+		return nil, ErrNoFileSystem
+	}
+	if target == "" {
+		return nil, ErrNoTarget
+	}
+
+	files, err := listFiles(f, target, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	ns = namespaceOf(obj, ns)
+	explanation := &Explanation{}
+	for _, file := range files {
+		bytes, err := fs.ReadFile(f, file)
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", file, err)
+		}
+		u, err := readUnstructured(bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", file, err)
+		}
+
+		switch u.GroupVersionKind().Group {
+		case "constraints.gatekeeper.sh":
+			rule, ok, err := explainConstraint(u, obj, ns)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %v", ErrAddingConstraint, file, err)
+			}
+			if ok {
+				rule.Path = file
+				explanation.Constraints = append(explanation.Constraints, rule)
+			}
+		case mutationsv1alpha1.GroupVersion.Group:
+			m, err := readMutator(f, file)
+			if err != nil {
+				return nil, err
+			}
+			if !m.Matches(obj, ns) {
+				continue
+			}
+			criteria, err := matchCriteria(u)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %v", ErrAddingMutator, file, err)
+			}
+			applyTo, _, _ := unstructured.NestedSlice(u.Object, "spec", "applyTo")
+			if len(applyTo) != 0 {
+				criteria = append([]string{fmt.Sprintf("applyTo: %v", applyTo)}, criteria...)
+			}
+			explanation.Mutators = append(explanation.Mutators, Rule{
+				Path:     file,
+				Kind:     u.GetKind(),
+				Name:     u.GetName(),
+				Criteria: criteria,
+			})
+		}
+	}
+
+	sortRules(explanation.Constraints)
+	sortRules(explanation.Mutators)
+	return explanation, nil
+}
+
+// namespaceOf returns the Namespace matched against for obj: obj itself if it
+// is a Namespace, ns if set, a Namespace without labels if obj is namespaced,
+// and otherwise nil.
+func namespaceOf(obj *unstructured.Unstructured, ns *corev1.Namespace) *corev1.Namespace {
+	gvk := obj.GroupVersionKind()
+	switch {
+	case gvk.Group == "" && gvk.Kind == "Namespace":
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: obj.GetName(), Labels: obj.GetLabels()}}
+	case ns != nil:
+		return ns
+	case obj.GetNamespace() != "":
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: obj.GetNamespace()}}
+	}
+	return nil
+}
+
+// explainConstraint returns the Rule of constraint, and whether it would
+// review obj when it is created.
+func explainConstraint(constraint, obj *unstructured.Unstructured, ns *corev1.Namespace) (Rule, bool, error) {
+	rule := Rule{Kind: constraint.GetKind(), Name: constraint.GetName()}
+
+	action, err := util.GetEnforcementAction(constraint.Object)
+	if err != nil {
+		return rule, false, err
+	}
+	rule.EnforcementAction = string(action)
+
+	operations, _, err := unstructured.NestedStringSlice(constraint.Object, "spec", "match", "operations")
+	if err != nil {
+		return rule, false, err
+	}
+	if len(operations) != 0 && !containsAny(operations, "CREATE", "*") {
+		return rule, false, nil
+	}
+
+	thresholds, err := readThresholds(constraint)
+	if err != nil {
+		return rule, false, err
+	}
+	for _, t := range thresholds {
+		if !t.met(obj) {
+			return rule, false, nil
+		}
+	}
+
+	m, err := readMatch(constraint)
+	if err != nil {
+		return rule, false, err
+	}
+	ok, err := match.Matches(m, obj, ns)
+	if err != nil || !ok {
+		return rule, false, err
+	}
+
+	rule.Criteria, err = matchCriteria(constraint)
+	return rule, true, err
+}
+
+// readMatch returns spec.match of u.
+func readMatch(u *unstructured.Unstructured) (*match.Match, error) {
+	m := &match.Match{}
+	raw, _, err := unstructured.NestedMap(u.Object, "spec", "match")
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// matchCriteria describes each criterion set in spec.match of u, in the order
+// they are documented.
+func matchCriteria(u *unstructured.Unstructured) ([]string, error) {
+	m, err := readMatch(u)
+	if err != nil {
+		return nil, err
+	}
+
+	var criteria []string
+	if len(m.Kinds) != 0 {
+		criteria = append(criteria, fmt.Sprintf("kinds: [%s]", strings.Join(groupKinds(m.Kinds), ", ")))
+	}
+	if m.Scope != "" {
+		criteria = append(criteria, fmt.Sprintf("scope: %s", m.Scope))
+	}
+	if len(m.Namespaces) != 0 {
+		criteria = append(criteria, fmt.Sprintf("namespaces: %v", m.Namespaces))
+	}
+	if len(m.ExcludedNamespaces) != 0 {
+		criteria = append(criteria, fmt.Sprintf("excludedNamespaces: %v", m.ExcludedNamespaces))
+	}
+	if m.LabelSelector != nil {
+		criteria = append(criteria, fmt.Sprintf("labelSelector: %s", metav1.FormatLabelSelector(m.LabelSelector)))
+	}
+	if m.NamespaceSelector != nil {
+		criteria = append(criteria, fmt.Sprintf("namespaceSelector: %s", metav1.FormatLabelSelector(m.NamespaceSelector)))
+	}
+	if m.Name != "" {
+		criteria = append(criteria, fmt.Sprintf("name: %s", m.Name))
+	}
+	operations, _, err := unstructured.NestedStringSlice(u.Object, "spec", "match", "operations")
+	if err != nil {
+		return nil, err
+	}
+	if len(operations) != 0 {
+		criteria = append(criteria, fmt.Sprintf("operations: %v", operations))
+	}
+	if m.ClusterSelector != nil {
+		criteria = append(criteria, fmt.Sprintf("clusterSelector: %s", metav1.FormatLabelSelector(m.ClusterSelector)))
+	}
+	thresholds, err := readThresholds(u)
+	if err != nil {
+		return nil, err
+	}
+	if len(thresholds) != 0 {
+		criteria = append(criteria, fmt.Sprintf("thresholds: %v", thresholds))
+	}
+	return criteria, nil
+}
+
+// threshold is a criterion of spec.match.thresholds of a Constraint.
+type threshold struct {
+	Field    string  `json:"field"`
+	Operator string  `json:"operator"`
+	Value    float64 `json:"value"`
+}
+
+func (t threshold) String() string {
+	return fmt.Sprintf("%s %s %v", t.Field, t.Operator, t.Value)
+}
+
+// readThresholds returns spec.match.thresholds of u.
+func readThresholds(u *unstructured.Unstructured) ([]threshold, error) {
+	raw, _, err := unstructured.NestedSlice(u.Object, "spec", "match", "thresholds")
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+	bytes, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var thresholds []threshold
+	if err := json.Unmarshal(bytes, &thresholds); err != nil {
+		return nil, fmt.Errorf("invalid spec.match.thresholds: %w", err)
+	}
+	return thresholds, nil
+}
+
+// met returns whether obj meets t, as the target's Rego does: the quantity of
+// a number is its value, and that of an array or object its number of items.
+func (t threshold) met(obj *unstructured.Unstructured) bool {
+	value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(t.Field, ".")...)
+	if err != nil || !found {
+		return false
+	}
+	var quantity float64
+	switch v := value.(type) {
+	case int64:
+		quantity = float64(v)
+	case float64:
+		quantity = v
+	case []interface{}:
+		quantity = float64(len(v))
+	case map[string]interface{}:
+		quantity = float64(len(v))
+	default:
+		return false
+	}
+	switch t.Operator {
+	case "Gt":
+		return quantity > t.Value
+	case "Gte":
+		return quantity >= t.Value
+	case "Lt":
+		return quantity < t.Value
+	case "Lte":
+		return quantity <= t.Value
+	case "Eq":
+		return quantity == t.Value
+	}
+	return false
+}
+
+// groupKinds returns each group and kind selected by kinds, such as
+// "Deployment.apps". Omitted groups or kinds are written as "*".
+func groupKinds(kinds []match.Kinds) []string {
+	var result []string
+	for _, k := range kinds {
+		groups, names := k.APIGroups, k.Kinds
+		if len(groups) == 0 {
+			groups = []string{"*"}
+		}
+		if len(names) == 0 {
+			names = []string{"*"}
+		}
+		for _, g := range groups {
+			for _, n := range names {
+				result = append(result, schema.GroupKind{Group: g, Kind: n}.String())
+			}
+		}
+	}
+	return result
+}
+
+func containsAny(items []string, values ...string) bool {
+	for _, item := range items {
+		for _, v := range values {
+			if item == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortRules(rules []Rule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Kind != rules[j].Kind {
+			return rules[i].Kind < rules[j].Kind
+		}
+		return rules[i].Name < rules[j].Name
+	})
+}
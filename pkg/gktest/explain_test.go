@@ -0,0 +1,170 @@
+package gktest
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/pkg/cluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestExplain(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"policies/template.yaml": &fstest.MapFile{Data: []byte(templateAlwaysValidate)},
+		"policies/pods.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: pods
+spec:
+  enforcementAction: dryrun
+  match:
+    kinds:
+    - apiGroups: [""]
+      kinds: ["Pod"]
+    namespaceSelector:
+      matchLabels:
+        env: prod
+`)},
+		"policies/deployments.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: deployments
+spec:
+  match:
+    kinds:
+    - apiGroups: ["apps"]
+      kinds: ["Deployment"]
+`)},
+		"policies/nested/all.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: all
+`)},
+		"policies/nested/deletes.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: deletes
+spec:
+  match:
+    operations: ["DELETE"]
+`)},
+		"policies/nested/prod-clusters.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: prod-clusters
+spec:
+  match:
+    clusterSelector:
+      matchLabels:
+        env: prod
+`)},
+		"policies/nested/single-container.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: single-container
+spec:
+  match:
+    thresholds:
+    - field: spec.containers
+      operator: Eq
+      value: 1
+`)},
+		"policies/nested/many-containers.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: AlwaysValidate
+metadata:
+  name: many-containers
+spec:
+  match:
+    thresholds:
+    - field: spec.containers
+      operator: Gt
+      value: 1
+`)},
+		"policies/nested/mutator.yaml": &fstest.MapFile{Data: []byte(`
+apiVersion: mutations.gatekeeper.sh/v1alpha1
+kind: AssignMetadata
+metadata:
+  name: add-owner
+spec:
+  match:
+    namespaces: ["prod-*"]
+  location: metadata.labels.owner
+  parameters:
+    assign:
+      value: admin
+`)},
+	}
+
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace("prod-a")
+	pod.SetName("pod")
+	if err := unstructured.SetNestedSlice(pod.Object, []interface{}{map[string]interface{}{"name": "app"}}, "spec", "containers"); err != nil {
+		t.Fatal(err)
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod-a", Labels: map[string]string{"env": "prod"}}}
+
+	cluster.SetLabels(labels.Set{"env": "prod"})
+	defer cluster.SetLabels(nil)
+
+	got, err := Explain(fileSystem, "policies", true, pod, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Explanation{
+		Constraints: []Rule{
+			{Path: "policies/nested/all.yaml", Kind: "AlwaysValidate", Name: "all", EnforcementAction: "deny"},
+			{
+				Path: "policies/pods.yaml", Kind: "AlwaysValidate", Name: "pods", EnforcementAction: "dryrun",
+				Criteria: []string{"kinds: [Pod]", "namespaceSelector: env=prod"},
+			},
+			{
+				Path: "policies/nested/prod-clusters.yaml", Kind: "AlwaysValidate", Name: "prod-clusters", EnforcementAction: "deny",
+				Criteria: []string{"clusterSelector: env=prod"},
+			},
+			{
+				Path: "policies/nested/single-container.yaml", Kind: "AlwaysValidate", Name: "single-container", EnforcementAction: "deny",
+				Criteria: []string{"thresholds: [spec.containers Eq 1]"},
+			},
+		},
+		Mutators: []Rule{
+			{Path: "policies/nested/mutator.yaml", Kind: "AssignMetadata", Name: "add-owner", Criteria: []string{"namespaces: [prod-*]"}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Constraints selecting other clusters do not apply.
+	cluster.SetLabels(labels.Set{"env": "dev"})
+	got, err = Explain(fileSystem, "policies/nested", false, pod, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range got.Constraints {
+		if r.Name == "prod-clusters" {
+			t.Errorf("got constraint %q selecting other clusters, want it not to apply", r.Name)
+		}
+	}
+
+	// The Namespace is assumed to have no labels if it is not given.
+	got, err = Explain(fileSystem, "policies", false, pod, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Constraints) != 0 || len(got.Mutators) != 0 {
+		t.Errorf("got %+v, want no rules outside of policies/nested or matching the namespace labels", got)
+	}
+}
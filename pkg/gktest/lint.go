@@ -0,0 +1,62 @@
+package gktest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/regocost"
+)
+
+// LintResult is the estimated cost of evaluating a ConstraintTemplate.
+type LintResult struct {
+	// Path is the file the ConstraintTemplate was read from.
+	Path string
+	// Template is the name of the ConstraintTemplate.
+	Template string
+	// Estimate is the estimated cost of the ConstraintTemplate's Rego.
+	Estimate *regocost.Estimate
+	// Error is the error reading or analyzing the ConstraintTemplate, if any.
+	Error error
+}
+
+// Lint estimates the cost of evaluating the ConstraintTemplates selected by
+// target, which are found in the same way as ReadSuites finds Suites. Files
+// which do not define a ConstraintTemplate are skipped.
+func Lint(f fs.FS, target string, recursive bool) ([]LintResult, error) {
+	if f == nil {
+		return nil, ErrNoFileSystem
+	}
+	if target == "" {
+		return nil, ErrNoTarget
+	}
+
+	files, err := listFiles(f, target, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []LintResult
+	for _, file := range files {
+		template, err := readTemplate(f, file)
+		if errors.Is(err, ErrNotATemplate) {
+			continue
+		}
+		if err != nil {
+			results = append(results, LintResult{Path: file, Error: err})
+			continue
+		}
+
+		estimate, err := regocost.EstimateTemplate(template)
+		if err != nil {
+			err = fmt.Errorf("%w: parsing Rego of %q: %v", ErrAddingTemplate, template.Name, err)
+		}
+		results = append(results, LintResult{
+			Path:     file,
+			Template: template.Name,
+			Estimate: estimate,
+			Error:    err,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,72 @@
+package gktest
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/regocost"
+)
+
+const templateRegexInLoop = `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: allowedrepos
+spec:
+  crd:
+    spec:
+      names:
+        kind: AllowedRepos
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8sallowedrepos
+        violation[{"msg": "bad image"}] {
+          container := input.review.object.spec.containers[_]
+          not re_match("^gcr.io/", container.image)
+        }
+`
+
+func TestLint(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"templates/always.yaml":       &fstest.MapFile{Data: []byte(templateAlwaysValidate)},
+		"templates/nested/repos.yaml": &fstest.MapFile{Data: []byte(templateRegexInLoop)},
+		"templates/nested/suite.yaml": &fstest.MapFile{Data: []byte(`
+kind: Suite
+apiVersion: test.gatekeeper.sh/v1alpha1
+`)},
+		"templates/nested/invalid.yaml": &fstest.MapFile{Data: []byte(templateInvalidYAML)},
+	}
+
+	results, err := Lint(fileSystem, "templates", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want one per ConstraintTemplate file: %+v", len(results), results)
+	}
+
+	byPath := make(map[string]LintResult)
+	for _, r := range results {
+		byPath[r.Path] = r
+	}
+	if got := byPath["templates/always.yaml"]; got.Error != nil || got.Estimate.Class != regocost.Low {
+		t.Errorf("got %+v, want a Low estimate for alwaysvalidate", got)
+	}
+	if got := byPath["templates/nested/repos.yaml"]; got.Error != nil || got.Template != "allowedrepos" || got.Estimate.Class != regocost.Medium {
+		t.Errorf("got %+v, want a Medium estimate for allowedrepos", got)
+	}
+	if got := byPath["templates/nested/invalid.yaml"]; !errors.Is(got.Error, ErrAddingTemplate) {
+		t.Errorf("got error %v, want %v", got.Error, ErrAddingTemplate)
+	}
+
+	// Without recursion only the top-level directory is linted.
+	results, err = Lint(fileSystem, "templates", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("got %d results, want 1: %+v", len(results), results)
+	}
+}
@@ -0,0 +1,86 @@
+package gktest
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Matrix expands a Case into one Case for every combination of an object and
+// a set of Constraint parameters. Every expanded Case has the Assertions and
+// Values of the Case it was expanded from.
+type Matrix struct {
+	// Objects are the paths to the files containing the objects to test,
+	// relative to the file defining the Suite.
+	Objects []string `json:"objects"`
+
+	// Parameters are sets of parameters which replace the parameters of the
+	// Test's Constraint. If empty, objects are tested against the Constraint
+	// as it is defined.
+	Parameters []map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// expandedCase is a Case to run, which may have been expanded from a Matrix.
+type expandedCase struct {
+	Case
+
+	// parameters replace the parameters of the Test's Constraint when running
+	// Case, if not nil.
+	parameters map[string]interface{}
+
+	// document is the index of the document of Object to review.
+	document int
+
+	// err is why the Case could not be expanded.
+	err error
+}
+
+// expandCase returns the Cases to run for c. Cases without a Matrix are run as
+// they are. Each Case expanded from a Matrix is named after c, its object and
+// the index of its parameters, for example "with-label/pod.yaml/parameters[1]".
+func expandCase(c Case) []expandedCase {
+	if c.Matrix == nil {
+		return []expandedCase{{Case: c}}
+	}
+	if c.Object != "" {
+		return []expandedCase{{Case: c, err: fmt.Errorf("%w: must not define both object and matrix", ErrInvalidCase)}}
+	}
+	if len(c.Matrix.Objects) == 0 {
+		return []expandedCase{{Case: c, err: fmt.Errorf("%w: matrix must define objects", ErrInvalidCase)}}
+	}
+
+	var cases []expandedCase
+	for _, object := range c.Matrix.Objects {
+		expanded := c
+		expanded.Matrix = nil
+		expanded.Object = object
+		expanded.Name = fmt.Sprintf("%s/%s", c.Name, object)
+
+		if len(c.Matrix.Parameters) == 0 {
+			cases = append(cases, expandedCase{Case: expanded})
+			continue
+		}
+		for i, parameters := range c.Matrix.Parameters {
+			withParameters := expanded
+			withParameters.Name = fmt.Sprintf("%s/parameters[%d]", expanded.Name, i)
+			if parameters == nil {
+				// Explicitly empty parameters still replace those of the Constraint.
+				parameters = map[string]interface{}{}
+			}
+			cases = append(cases, expandedCase{Case: withParameters, parameters: parameters})
+		}
+	}
+	return cases
+}
+
+// withParameters returns a copy of constraint with its parameters replaced.
+func withParameters(constraint *unstructured.Unstructured, parameters map[string]interface{}) *unstructured.Unstructured {
+	c := constraint.DeepCopy()
+	spec, ok := c.Object["spec"].(map[string]interface{})
+	if !ok {
+		spec = make(map[string]interface{})
+		c.Object["spec"] = spec
+	}
+	spec["parameters"] = parameters
+	return c
+}
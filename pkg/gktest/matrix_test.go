@@ -0,0 +1,179 @@
+package gktest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	templateForbidNames = `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: forbidnames
+spec:
+  crd:
+    spec:
+      names:
+        kind: ForbidNames
+      validation:
+        openAPIV3Schema:
+          properties:
+            names:
+              type: array
+              items:
+                type: string
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8sforbidnames
+        violation[{"msg": msg}] {
+          name := input.review.object.metadata.name
+          name == input.parameters.names[_]
+          msg := sprintf("name %v is forbidden", [name])
+        }
+`
+
+	constraintForbidNames = `
+kind: ForbidNames
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: forbid-names
+spec:
+  parameters:
+    names: ["foo"]
+`
+
+	objectFoo = `
+kind: Object
+apiVersion: v1
+metadata:
+  name: foo`
+
+	objectBar = `
+kind: Object
+apiVersion: v1
+metadata:
+  name: bar`
+)
+
+func TestExpandCase(t *testing.T) {
+	testCases := []struct {
+		name    string
+		c       Case
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "no matrix",
+			c:    Case{Name: "case", Object: "foo.yaml"},
+			want: []string{"case"},
+		},
+		{
+			name: "objects",
+			c:    Case{Name: "case", Matrix: &Matrix{Objects: []string{"foo.yaml", "bar.yaml"}}},
+			want: []string{"case/foo.yaml", "case/bar.yaml"},
+		},
+		{
+			name: "objects and parameters",
+			c: Case{Name: "case", Matrix: &Matrix{
+				Objects:    []string{"foo.yaml", "bar.yaml"},
+				Parameters: []map[string]interface{}{{"names": []interface{}{"foo"}}, nil},
+			}},
+			want: []string{
+				"case/foo.yaml/parameters[0]",
+				"case/foo.yaml/parameters[1]",
+				"case/bar.yaml/parameters[0]",
+				"case/bar.yaml/parameters[1]",
+			},
+		},
+		{
+			name:    "object and matrix",
+			c:       Case{Name: "case", Object: "foo.yaml", Matrix: &Matrix{Objects: []string{"bar.yaml"}}},
+			want:    []string{"case"},
+			wantErr: ErrInvalidCase,
+		},
+		{
+			name:    "matrix without objects",
+			c:       Case{Name: "case", Matrix: &Matrix{}},
+			want:    []string{"case"},
+			wantErr: ErrInvalidCase,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := expandCase(tc.c)
+
+			var names []string
+			for _, c := range got {
+				names = append(names, c.Name)
+				if diff := cmp.Diff(tc.wantErr, c.err, cmpopts.EquateErrors()); diff != "" {
+					t.Error(diff)
+				}
+				if tc.wantErr == nil && c.Matrix != nil {
+					t.Errorf("got expanded case %q with a matrix", c.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.want, names); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_Matrix(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"template.yaml":   &fstest.MapFile{Data: []byte(templateForbidNames)},
+		"constraint.yaml": &fstest.MapFile{Data: []byte(constraintForbidNames)},
+		"foo.yaml":        &fstest.MapFile{Data: []byte(objectFoo)},
+		"bar.yaml":        &fstest.MapFile{Data: []byte(objectBar)},
+	}
+
+	suite := &Suite{
+		Tests: []Test{{
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Cases: []Case{{
+				Name: "forbidden",
+				Matrix: &Matrix{
+					Objects: []string{"foo.yaml", "bar.yaml"},
+					Parameters: []map[string]interface{}{
+						{"names": []interface{}{"foo", "bar"}},
+						{"names": []interface{}{"bar", "baz"}},
+					},
+				},
+				Assertions: []Assertion{{Violations: intStrFromStr("yes")}},
+			}, {
+				// Run against the Constraint as defined once parameters are restored.
+				Name:   "allowed",
+				Object: "bar.yaml",
+			}},
+		}},
+	}
+
+	runner := Runner{FS: fileSystem, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "suite.yaml", suite)
+
+	want := SuiteResult{
+		Path: "suite.yaml",
+		TestResults: []TestResult{{
+			CaseResults: []CaseResult{
+				{Name: "forbidden/foo.yaml/parameters[0]"},
+				{Name: "forbidden/foo.yaml/parameters[1]", Error: ErrNumViolations},
+				{Name: "forbidden/bar.yaml/parameters[0]"},
+				{Name: "forbidden/bar.yaml/parameters[1]"},
+				{Name: "allowed"},
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,126 @@
+package gktest
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// readMutator reads the contents of path and returns the mutator it defines.
+// Returns an error if the file does not define an Assign, AssignMetadata or
+// ModifySet.
+func readMutator(f fs.FS, path string) (types.Mutator, error) {
+	bytes, err := fs.ReadFile(f, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mutator from %q: %w", path, err)
+	}
+
+	u, err := readUnstructured(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing mutator YAML from %q: %v", ErrAddingMutator, path, err)
+	}
+	if u.GroupVersionKind().Group != mutationsv1alpha1.GroupVersion.Group {
+		return nil, fmt.Errorf("%w: %q does not define a mutator", ErrAddingMutator, path)
+	}
+
+	var m types.Mutator
+	switch kind := u.GetKind(); kind {
+	case "Assign":
+		a := &mutationsv1alpha1.Assign{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, a); err == nil {
+			m, err = mutators.MutatorForAssign(a)
+		}
+	case "AssignMetadata":
+		a := &mutationsv1alpha1.AssignMetadata{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, a); err == nil {
+			m, err = mutators.MutatorForAssignMetadata(a)
+		}
+	case "ModifySet":
+		s := &mutationsv1alpha1.ModifySet{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, s); err == nil {
+			m, err = mutators.MutatorForModifySet(s)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown mutator kind %q in %q", ErrAddingMutator, kind, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrAddingMutator, path, err)
+	}
+	return m, nil
+}
+
+// makeMutationSystem returns a mutation System with the Test's mutators, or
+// nil if the Test has none.
+func (r *Runner) makeMutationSystem(suiteDir string, t Test) (*mutation.System, error) {
+	if len(t.Mutators) == 0 {
+		return nil, nil
+	}
+
+	system := mutation.NewSystem(mutation.SystemOpts{})
+	for _, path := range t.Mutators {
+		m, err := readMutator(r.FS, filepath.Join(suiteDir, path))
+		if err != nil {
+			return nil, err
+		}
+		if err := system.Upsert(m); err != nil {
+			return nil, fmt.Errorf("%w %v: %v", ErrAddingMutator, m.ID(), err)
+		}
+	}
+	return system, nil
+}
+
+// mutate applies the mutators of system to u as Gatekeeper would at admission,
+// returning whether u was changed. Objects in a namespace are mutated as if
+// their namespace had no labels.
+func mutate(system *mutation.System, u *unstructured.Unstructured) (bool, error) {
+	var ns *corev1.Namespace
+	if u.GetNamespace() != "" {
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: u.GetNamespace()}}
+	}
+
+	original := u.DeepCopy()
+	mutated, err := system.Mutate(u, ns)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrExpanding, err)
+	}
+	return mutated && !reflect.DeepEqual(original.Object, u.Object), nil
+}
+
+// mutatedBy returns the kinds and names of the mutators of system which
+// change original when applied on their own.
+func mutatedBy(system *mutation.System, original *unstructured.Unstructured) string {
+	var ns *corev1.Namespace
+	if original.GetNamespace() != "" {
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: original.GetNamespace()}}
+	}
+
+	var ids []string
+	for _, state := range system.MutatorStates() {
+		m := system.Get(state.ID)
+		u := original.DeepCopy()
+		if m == nil || !m.Matches(u, ns) {
+			continue
+		}
+		var mutated bool
+		if nm, ok := m.(types.NamespaceMutator); ok {
+			mutated, _ = nm.MutateInNamespace(u, ns)
+		} else {
+			mutated, _ = m.Mutate(u)
+		}
+		if mutated && !reflect.DeepEqual(original.Object, u.Object) {
+			ids = append(ids, state.ID.Kind+"/"+state.ID.Name)
+		}
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,126 @@
+package gktest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	mutatorOwnerLabel = `
+apiVersion: mutations.gatekeeper.sh/v1alpha1
+kind: AssignMetadata
+metadata:
+  name: owner-label
+spec:
+  match:
+    scope: Namespaced
+    excludedNamespaces: ["exempt"]
+  location: "metadata.labels.owner"
+  parameters:
+    assign:
+      value: "admin"
+`
+
+	objectInDefault = `
+kind: Pod
+apiVersion: v1
+metadata:
+  name: pod
+  namespace: default`
+
+	objectInExempt = `
+kind: Pod
+apiVersion: v1
+metadata:
+  name: pod
+  namespace: exempt`
+
+	objectWithOwner = `
+kind: Pod
+apiVersion: v1
+metadata:
+  name: pod
+  namespace: default
+  labels:
+    owner: someone`
+)
+
+func TestRunner_Run_AssertNoMutation(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"mutator.yaml":     &fstest.MapFile{Data: []byte(mutatorOwnerLabel)},
+		"not-mutator.yaml": &fstest.MapFile{Data: []byte(constraintAlwaysValidate)},
+		"template.yaml":    &fstest.MapFile{Data: []byte(templateAlwaysValidate)},
+		"default.yaml":     &fstest.MapFile{Data: []byte(objectInDefault)},
+		"exempt.yaml":      &fstest.MapFile{Data: []byte(objectInExempt)},
+		"owner.yaml":       &fstest.MapFile{Data: []byte(objectWithOwner)},
+	}
+
+	suite := &Suite{
+		Tests: []Test{{
+			Name:     "mutation",
+			Mutators: []string{"mutator.yaml"},
+			Cases: []Case{{
+				Name:             "exempt namespace",
+				Object:           "exempt.yaml",
+				AssertNoMutation: true,
+			}, {
+				Name:             "already compliant",
+				Object:           "owner.yaml",
+				AssertNoMutation: true,
+			}, {
+				Name:             "mutated",
+				Object:           "default.yaml",
+				AssertNoMutation: true,
+			}, {
+				Name:   "mutated without assertion",
+				Object: "default.yaml",
+			}},
+		}, {
+			Name:     "invalid mutator",
+			Mutators: []string{"not-mutator.yaml"},
+		}, {
+			Name:       "no mutators",
+			Template:   "template.yaml",
+			Constraint: "not-mutator.yaml",
+			Cases: []Case{{
+				Object:           "default.yaml",
+				AssertNoMutation: true,
+			}},
+		}},
+	}
+
+	runner := Runner{FS: fileSystem, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "suite.yaml", suite)
+
+	want := SuiteResult{
+		Path: "suite.yaml",
+		TestResults: []TestResult{{
+			Name: "mutation",
+			CaseResults: []CaseResult{
+				{Name: "exempt namespace"},
+				{Name: "already compliant"},
+				{Name: "mutated", Error: ErrMutated},
+				{Name: "mutated without assertion"},
+			},
+		}, {
+			Name:  "invalid mutator",
+			Error: ErrAddingMutator,
+		}, {
+			Name:        "no mutators",
+			CaseResults: []CaseResult{{Error: ErrInvalidCase}},
+		}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+
+	if msg := got.TestResults[0].CaseResults[2].Error.Error(); msg != "object was mutated by: AssignMetadata/owner-label" {
+		t.Errorf("got error %q, want it to name the mutator", msg)
+	}
+}
@@ -2,12 +2,30 @@ package gktest
 
 import (
 	opaclient "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"github.com/open-policy-agent/gatekeeper/pkg/engine"
+	"github.com/open-policy-agent/gatekeeper/pkg/inventory"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
 	"github.com/open-policy-agent/gatekeeper/pkg/target"
 )
 
 func NewOPAClient() (Client, error) {
-	driver := local.New(local.Tracing(false))
+	return newOPAClient(nil)
+}
+
+// newOPAClient returns a Client compiling templates with the named evaluation
+// engines, in order of preference, before falling back to Rego.
+func newOPAClient(engines []string) (Client, error) {
+	var driver drivers.Driver = local.New(local.Tracing(false))
+	if len(engines) != 0 {
+		d, err := engine.NewDriver(driver, engines)
+		if err != nil {
+			return nil, err
+		}
+		driver = d
+	}
+	driver = querystats.Wrap(inventory.NewIndex().Wrap(driver))
 	backend, err := opaclient.NewBackend(opaclient.Driver(driver))
 	if err != nil {
 		return nil, err
@@ -3,6 +3,7 @@ package gktest
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 type PrinterGo struct{}
@@ -95,6 +96,11 @@ func (p PrinterGo) PrintTest(w StringWriter, r *TestResult, verbose bool) error
 				return fmt.Errorf("%w: %v", ErrWritingString, err)
 			}
 		}
+	} else if verbose && r.Skipped {
+		_, err := w.WriteString(fmt.Sprintf("--- SKIP: %s\t(%v)\n", r.Name, r.Runtime))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWritingString, err)
+		}
 	} else if verbose {
 		_, err := w.WriteString(fmt.Sprintf("--- PASS: %s\t(%v)\n", r.Name, r.Runtime))
 		if err != nil {
@@ -117,6 +123,25 @@ func (p PrinterGo) PrintCase(w StringWriter, r *CaseResult, verbose bool) error
 		if err != nil {
 			return fmt.Errorf("%w: %v", ErrWritingString, err)
 		}
+		if verbose && r.Rendered != "" {
+			rendered := indent(12, strings.TrimSuffix(r.Rendered, "\n"))
+			_, err = w.WriteString(fmt.Sprintf("        rendered object:\n%s\n", rendered))
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrWritingString, err)
+			}
+		}
+		if r.Trace != "" {
+			trace := indent(12, strings.TrimSuffix(r.Trace, "\n"))
+			_, err = w.WriteString(fmt.Sprintf("        trace:\n%s\n", trace))
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrWritingString, err)
+			}
+		}
+	} else if verbose && r.Skipped {
+		_, err := w.WriteString(fmt.Sprintf("    --- SKIP: %s\t(%v)\n", r.Name, r.Runtime))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWritingString, err)
+		}
 	} else if verbose {
 		_, err := w.WriteString(fmt.Sprintf("    --- PASS: %s\t(%v)\n", r.Name, r.Runtime))
 		if err != nil {
@@ -124,5 +149,13 @@ func (p PrinterGo) PrintCase(w StringWriter, r *CaseResult, verbose bool) error
 		}
 	}
 
+	if r.Stats != nil && (verbose || r.Error != nil) {
+		_, err := w.WriteString(fmt.Sprintf("        stats: queries=%d eval=%v constraints-matched=%d external-data-calls=%d cache-hits=%d\n",
+			r.Stats.Queries, Duration(r.Stats.EvalDuration), r.Stats.ConstraintsMatched, r.Stats.ExternalDataCalls, r.Stats.CacheHits))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWritingString, err)
+		}
+	}
+
 	return nil
 }
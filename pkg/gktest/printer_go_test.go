@@ -56,6 +56,44 @@ PASS
 --- PASS: forbid-labels	(0.330s)
 ok	tests.go	0.330s
 PASS
+`,
+		},
+		{
+			name: "skipped tests",
+			result: []SuiteResult{{
+				Path:    "tests.go",
+				Runtime: Duration(100 * time.Millisecond),
+				TestResults: []TestResult{{
+					Name:    "forbid-labels",
+					Runtime: Duration(100 * time.Millisecond),
+					CaseResults: []CaseResult{{
+						Name:    "forbid-labels/with label",
+						Runtime: Duration(100 * time.Millisecond),
+					}, {
+						Name:    "forbid-labels/pending",
+						Skipped: true,
+					}},
+				}, {
+					Name:        "require-labels",
+					Skipped:     true,
+					CaseResults: []CaseResult{{Name: "require-labels/with label", Skipped: true}},
+				}},
+			}},
+			want: `ok	tests.go	0.100s
+PASS
+`,
+			wantVerbose: `=== RUN   forbid-labels
+    === RUN   forbid-labels/with label
+    --- PASS: forbid-labels/with label	(0.100s)
+    === RUN   forbid-labels/pending
+    --- SKIP: forbid-labels/pending	(0.000s)
+--- PASS: forbid-labels	(0.100s)
+=== RUN   require-labels
+    === RUN   require-labels/with label
+    --- SKIP: require-labels/with label	(0.000s)
+--- SKIP: require-labels	(0.000s)
+ok	tests.go	0.100s
+PASS
 `,
 		},
 		{
@@ -121,6 +159,41 @@ FAIL
 --- FAIL: forbid-labels	(0.330s)
 FAIL	tests.go	0.330s
 FAIL
+`,
+		},
+		{
+			name: "rendered object failure",
+			result: []SuiteResult{{
+				Path:    "tests.go",
+				Runtime: Duration(230 * time.Millisecond),
+				TestResults: []TestResult{{
+					Name:    "forbid-labels",
+					Runtime: Duration(230 * time.Millisecond),
+					CaseResults: []CaseResult{{
+						Name:     "forbid-labels/without label",
+						Error:    errors.New("got violation but want allow"),
+						Runtime:  Duration(230 * time.Millisecond),
+						Rendered: "kind: Namespace\nmetadata:\n  name: foo\n",
+					}},
+				}},
+			}},
+			want: `    --- FAIL: forbid-labels/without label	(0.230s)
+        got violation but want allow
+--- FAIL: forbid-labels	(0.230s)
+FAIL	tests.go	0.230s
+FAIL
+`,
+			wantVerbose: `=== RUN   forbid-labels
+    === RUN   forbid-labels/without label
+    --- FAIL: forbid-labels/without label	(0.230s)
+        got violation but want allow
+        rendered object:
+            kind: Namespace
+            metadata:
+              name: foo
+--- FAIL: forbid-labels	(0.230s)
+FAIL	tests.go	0.230s
+FAIL
 `,
 		},
 		{
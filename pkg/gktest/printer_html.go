@@ -0,0 +1,299 @@
+package gktest
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// PrinterHTML prints a self-contained HTML report of the results of Suites,
+// with a summary, the coverage of each Suite, and every Test and Case. Failed
+// Cases list the violations of their object next to what they expected of it
+// if the Runner recorded Details.
+//
+// The report embeds its styles and loads nothing, so it can be attached to
+// change tickets as evidence of policy testing.
+type PrinterHTML struct {
+	// Title is the title of the report. Defaults to "Gatekeeper policy test
+	// report".
+	Title string
+	// Now returns the time the report is generated at. Defaults to time.Now.
+	Now func() time.Time
+}
+
+var _ Printer = PrinterHTML{}
+
+// defaultHTMLTitle is the title of reports without one.
+const defaultHTMLTitle = "Gatekeeper policy test report"
+
+// Print writes the report of r to w. If verbose, the rendered objects of
+// failed Cases are included.
+func (p PrinterHTML) Print(w StringWriter, r []SuiteResult, verbose bool) error {
+	report := p.report(r, verbose)
+	b := &strings.Builder{}
+	if err := htmlReportTemplate.Execute(b, report); err != nil {
+		return fmt.Errorf("rendering HTML report: %w", err)
+	}
+	_, err := w.WriteString(b.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+	return nil
+}
+
+// htmlReport is the data the HTML report is rendered from.
+type htmlReport struct {
+	Title     string
+	Generated string
+	Failed    bool
+	Suites    int
+	Tests     int
+	Cases     int
+	Failures  int
+	Skipped   int
+	Runtime   Duration
+	// Details is whether the Runner recorded which objects were reviewed and
+	// their violations, without which coverage is unknown.
+	Details  bool
+	Coverage []htmlCoverage
+	Results  []htmlSuite
+}
+
+// htmlCoverage is what the Tests of a Suite exercise.
+type htmlCoverage struct {
+	Path string
+	// Tests is the number of Tests of the Suite, and Complete the number of
+	// those whose Cases reviewed both an allowed and a denied object.
+	Tests    int
+	Complete int
+	// CasesRun is the number of Cases run, and Cases the number defined.
+	CasesRun int
+	Cases    int
+	// Incomplete describes what each incomplete Test is missing.
+	Incomplete []string
+}
+
+type htmlSuite struct {
+	Path    string
+	Failed  bool
+	Runtime Duration
+	Error   string
+	Tests   []htmlTest
+}
+
+type htmlTest struct {
+	Name    string
+	Failed  bool
+	Skipped bool
+	Runtime Duration
+	Error   string
+	Cases   []htmlCase
+}
+
+type htmlCase struct {
+	Name       string
+	Failed     bool
+	Skipped    bool
+	Runtime    Duration
+	Error      string
+	Assertions []string
+	Violations []string
+	Reviewed   bool
+	Stats      string
+	Rendered   string
+}
+
+func (p PrinterHTML) report(results []SuiteResult, verbose bool) htmlReport {
+	title := p.Title
+	if title == "" {
+		title = defaultHTMLTitle
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+
+	report := htmlReport{
+		Title:     title,
+		Generated: now().UTC().Format(time.RFC3339),
+		Suites:    len(results),
+	}
+	for i := range results {
+		s := &results[i]
+		report.Runtime += s.Runtime
+		if s.IsFailure() {
+			report.Failed = true
+		}
+		if s.Error != nil {
+			report.Failures++
+		}
+
+		suite := htmlSuite{Path: s.Path, Failed: s.IsFailure(), Runtime: s.Runtime, Error: errorString(s.Error)}
+		coverage := htmlCoverage{Path: s.Path, Tests: len(s.TestResults)}
+		for j := range s.TestResults {
+			t := &s.TestResults[j]
+			report.Tests++
+			if t.Error != nil {
+				report.Failures++
+			}
+
+			test := htmlTest{Name: t.Name, Failed: t.IsFailure(), Skipped: t.Skipped, Runtime: t.Runtime, Error: errorString(t.Error)}
+			allowed, denied := false, false
+			for k := range t.CaseResults {
+				c := &t.CaseResults[k]
+				report.Cases++
+				coverage.Cases++
+				if c.IsFailure() {
+					report.Failures++
+				}
+				if c.Skipped {
+					report.Skipped++
+				}
+				if c.Assertions != nil {
+					report.Details = true
+				}
+				if c.Runtime != 0 || c.Error != nil || c.Assertions != nil {
+					// Cases filtered out have empty results.
+					coverage.CasesRun++
+				}
+				if c.Reviewed {
+					if len(c.Violations) == 0 {
+						allowed = true
+					} else {
+						denied = true
+					}
+				}
+				test.Cases = append(test.Cases, htmlCaseOf(c, verbose))
+			}
+
+			switch {
+			case t.Skipped:
+				coverage.Incomplete = append(coverage.Incomplete, fmt.Sprintf("%s: skipped", t.Name))
+			case allowed && denied:
+				coverage.Complete++
+			case allowed:
+				coverage.Incomplete = append(coverage.Incomplete, fmt.Sprintf("%s: no denied object", t.Name))
+			case denied:
+				coverage.Incomplete = append(coverage.Incomplete, fmt.Sprintf("%s: no allowed object", t.Name))
+			default:
+				coverage.Incomplete = append(coverage.Incomplete, fmt.Sprintf("%s: no reviewed object", t.Name))
+			}
+			suite.Tests = append(suite.Tests, test)
+		}
+		report.Coverage = append(report.Coverage, coverage)
+		report.Results = append(report.Results, suite)
+	}
+	return report
+}
+
+func htmlCaseOf(c *CaseResult, verbose bool) htmlCase {
+	result := htmlCase{
+		Name:       c.Name,
+		Failed:     c.IsFailure(),
+		Skipped:    c.Skipped,
+		Runtime:    c.Runtime,
+		Error:      errorString(c.Error),
+		Assertions: c.Assertions,
+		Violations: c.Violations,
+		Reviewed:   c.Reviewed,
+	}
+	if c.Stats != nil {
+		result.Stats = fmt.Sprintf("queries=%d eval=%v constraints-matched=%d external-data-calls=%d cache-hits=%d",
+			c.Stats.Queries, Duration(c.Stats.EvalDuration), c.Stats.ConstraintsMatched, c.Stats.ExternalDataCalls, c.Stats.CacheHits)
+	}
+	if verbose && c.IsFailure() {
+		result.Rendered = c.Rendered
+	}
+	return result
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { margin-bottom: 0.2em; }
+table { border-collapse: collapse; margin: 0.5em 0 1.5em; }
+th, td { border: 1px solid #d0d7de; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+ul { margin: 0; padding-left: 1.2em; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; font-weight: bold; }
+.pass { color: #1a7f37; }
+.fail { color: #cf222e; }
+.skip { color: #9a6700; }
+.meta { color: #656d76; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Generated {{.Generated}}</p>
+<p>Result: {{if .Failed}}<span class="fail">FAIL</span>{{else}}<span class="pass">PASS</span>{{end}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Suites</th><th>Tests</th><th>Cases</th><th>Failures</th><th>Skipped</th><th>Runtime</th></tr>
+<tr><td>{{.Suites}}</td><td>{{.Tests}}</td><td>{{.Cases}}</td><td>{{.Failures}}</td><td>{{.Skipped}}</td><td>{{.Runtime}}</td></tr>
+</table>
+
+<h2>Coverage</h2>
+<table>
+<tr><th>Suite</th><th>Cases run</th><th>Tests with allowed and denied objects</th><th>Gaps</th></tr>
+{{- $details := .Details}}
+{{- range .Coverage}}
+<tr><td>{{.Path}}</td><td>{{.CasesRun}} / {{.Cases}}</td>
+{{- if $details}}<td>{{.Complete}} / {{.Tests}}</td><td>{{if .Incomplete}}<ul>{{range .Incomplete}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+{{- else}}<td colspan="2" class="meta">not recorded</td>{{end}}</tr>
+{{- end}}
+</table>
+
+<h2>Suites</h2>
+{{- range .Results}}
+<details{{if .Failed}} open{{end}}>
+<summary>{{if .Failed}}<span class="fail">FAIL</span>{{else}}<span class="pass">ok</span>{{end}} {{.Path}} <span class="meta">({{.Runtime}})</span></summary>
+{{- if .Error}}
+<pre class="fail">{{.Error}}</pre>
+{{- end}}
+{{- range .Tests}}
+<h3>{{if .Failed}}<span class="fail">FAIL</span>{{else if .Skipped}}<span class="skip">SKIP</span>{{else}}<span class="pass">PASS</span>{{end}} {{.Name}} <span class="meta">({{.Runtime}})</span></h3>
+{{- if .Error}}
+<pre class="fail">{{.Error}}</pre>
+{{- end}}
+{{- if .Cases}}
+<table>
+<tr><th>Case</th><th>Result</th><th>Runtime</th><th>Want</th><th>Got</th></tr>
+{{- range .Cases}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{if .Failed}}<span class="fail">FAIL</span>{{else if .Skipped}}<span class="skip">SKIP</span>{{else}}<span class="pass">PASS</span>{{end}}</td>
+<td>{{.Runtime}}</td>
+<td>{{if .Assertions}}<ul>{{range .Assertions}}<li>{{.}}</li>{{end}}</ul>{{end}}</td>
+<td>{{if .Violations}}<ul>{{range .Violations}}<li>{{.}}</li>{{end}}</ul>{{else if .Reviewed}}no violations{{end}}</td>
+</tr>
+{{- if or .Error .Stats .Rendered}}
+<tr><td colspan="5">
+{{- if .Error}}<pre class="fail">{{.Error}}</pre>{{end}}
+{{- if .Stats}}<p class="meta">{{.Stats}}</p>{{end}}
+{{- if .Rendered}}<pre>{{.Rendered}}</pre>{{end}}
+</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</details>
+{{- end}}
+</body>
+</html>
+`))
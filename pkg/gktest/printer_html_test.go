@@ -0,0 +1,104 @@
+package gktest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrinterHTML_Print(t *testing.T) {
+	results := []SuiteResult{{
+		Path:    "tests/labels.yaml",
+		Runtime: Duration(2 * time.Second),
+		TestResults: []TestResult{{
+			Name:    "required-labels",
+			Runtime: Duration(time.Second),
+			CaseResults: []CaseResult{{
+				Name:       "allowed",
+				Runtime:    Duration(time.Millisecond),
+				Reviewed:   true,
+				Violations: []string{},
+				Assertions: []string{"violations: no"},
+			}, {
+				Name:       "missing-owner",
+				Runtime:    Duration(time.Millisecond),
+				Error:      errors.New("got 0 violations but want at least 1"),
+				Reviewed:   true,
+				Violations: []string{},
+				Assertions: []string{`violations: yes, message: "<owner>"`},
+				Rendered:   "kind: Pod",
+			}, {
+				// Filtered out.
+			}},
+		}, {
+			Name:  "bad-template",
+			Error: errors.New("compiling template"),
+		}},
+	}}
+
+	w := &strings.Builder{}
+	p := PrinterHTML{Now: func() time.Time { return time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC) }}
+	err := p.Print(w, results, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := w.String()
+
+	for _, want := range []string{
+		"<title>Gatekeeper policy test report</title>",
+		"Generated 2021-09-01T00:00:00Z",
+		`Result: <span class="fail">FAIL</span>`,
+		"<td>1</td><td>2</td><td>3</td><td>2</td><td>0</td><td>2.000s</td>",
+		"<td>tests/labels.yaml</td><td>2 / 3</td><td>0 / 2</td>",
+		"<li>required-labels: no denied object</li>",
+		"<li>bad-template: no reviewed object</li>",
+		"<details open>",
+		`<pre class="fail">compiling template</pre>`,
+		`<pre class="fail">got 0 violations but want at least 1</pre>`,
+		// Messages are escaped.
+		"<li>violations: yes, message: &#34;&lt;owner&gt;&#34;</li>",
+		"<td>no violations</td>",
+		"<pre>kind: Pod</pre>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report does not contain %q:\n%s", want, got)
+		}
+	}
+
+	w.Reset()
+	err = p.Print(w, results, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(w.String(), "kind: Pod") {
+		t.Errorf("report contains rendered object when not verbose")
+	}
+}
+
+func TestPrinterHTML_Print_NoDetails(t *testing.T) {
+	results := []SuiteResult{{
+		Path: "tests/labels.yaml",
+		TestResults: []TestResult{{
+			Name:        "required-labels",
+			CaseResults: []CaseResult{{Name: "allowed", Runtime: Duration(time.Millisecond)}},
+		}},
+	}}
+
+	w := &strings.Builder{}
+	err := PrinterHTML{Title: "Labels"}.Print(w, results, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := w.String()
+
+	for _, want := range []string{
+		"<title>Labels</title>",
+		`Result: <span class="pass">PASS</span>`,
+		`<td colspan="2" class="meta">not recorded</td>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report does not contain %q:\n%s", want, got)
+		}
+	}
+}
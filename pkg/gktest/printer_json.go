@@ -0,0 +1,139 @@
+package gktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+)
+
+// PrinterJSON prints the results of Suites as JSON, for tools which
+// post-process them. Every Suite, Test and Case is printed with its error,
+// runtime and whatever else the Runner recorded, followed by a Summary of the
+// results.
+type PrinterJSON struct{}
+
+var _ Printer = PrinterJSON{}
+
+type jsonReport struct {
+	Failed  bool        `json:"failed"`
+	Summary jsonSummary `json:"summary"`
+	Suites  []jsonSuite `json:"suites"`
+}
+
+type jsonSummary struct {
+	Passed               int `json:"passed"`
+	Skipped              int `json:"skipped"`
+	AssertionFailures    int `json:"assertionFailures"`
+	SuiteErrors          int `json:"suiteErrors"`
+	InfrastructureErrors int `json:"infrastructureErrors"`
+	ExitCode             int `json:"exitCode"`
+}
+
+type jsonSuite struct {
+	Path           string     `json:"path"`
+	Failed         bool       `json:"failed"`
+	Error          string     `json:"error,omitempty"`
+	RuntimeSeconds float64    `json:"runtimeSeconds"`
+	Tests          []jsonTest `json:"tests,omitempty"`
+}
+
+type jsonTest struct {
+	Name           string     `json:"name"`
+	Failed         bool       `json:"failed"`
+	Skipped        bool       `json:"skipped,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	RuntimeSeconds float64    `json:"runtimeSeconds"`
+	Cases          []jsonCase `json:"cases,omitempty"`
+}
+
+type jsonCase struct {
+	Name           string            `json:"name"`
+	Failed         bool              `json:"failed"`
+	Skipped        bool              `json:"skipped,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	RuntimeSeconds float64           `json:"runtimeSeconds"`
+	Rendered       string            `json:"rendered,omitempty"`
+	Stats          *querystats.Stats `json:"stats,omitempty"`
+	Trace          string            `json:"trace,omitempty"`
+	Reviewed       bool              `json:"reviewed,omitempty"`
+	Violations     []string          `json:"violations,omitempty"`
+	Assertions     []string          `json:"assertions,omitempty"`
+	Object         string            `json:"object,omitempty"`
+	ObjectLine     int               `json:"objectLine,omitempty"`
+}
+
+// Print writes r to w as JSON. Everything the Runner recorded is printed, so
+// verbose is ignored.
+func (p PrinterJSON) Print(w StringWriter, r []SuiteResult, verbose bool) error {
+	summary := Summarize(r)
+	report := jsonReport{
+		Failed: summary.IsFailure(),
+		Summary: jsonSummary{
+			Passed:               summary.Passed,
+			Skipped:              summary.Skipped,
+			AssertionFailures:    summary.AssertionFailures,
+			SuiteErrors:          summary.SuiteErrors,
+			InfrastructureErrors: summary.InfrastructureErrors,
+			ExitCode:             summary.ExitCode(),
+		},
+		Suites: make([]jsonSuite, 0, len(r)),
+	}
+	for i := range r {
+		report.Suites = append(report.Suites, jsonSuiteOf(&r[i]))
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("rendering JSON report: %w", err)
+	}
+	_, err = w.WriteString(string(b) + "\n")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+	return nil
+}
+
+func jsonSuiteOf(s *SuiteResult) jsonSuite {
+	suite := jsonSuite{
+		Path:           s.Path,
+		Failed:         s.IsFailure(),
+		Error:          errorString(s.Error),
+		RuntimeSeconds: seconds(s.Runtime),
+	}
+	for i := range s.TestResults {
+		t := &s.TestResults[i]
+		test := jsonTest{
+			Name:           t.Name,
+			Failed:         t.IsFailure(),
+			Skipped:        t.Skipped,
+			Error:          errorString(t.Error),
+			RuntimeSeconds: seconds(t.Runtime),
+		}
+		for j := range t.CaseResults {
+			c := &t.CaseResults[j]
+			test.Cases = append(test.Cases, jsonCase{
+				Name:           c.Name,
+				Failed:         c.IsFailure(),
+				Skipped:        c.Skipped,
+				Error:          errorString(c.Error),
+				RuntimeSeconds: seconds(c.Runtime),
+				Rendered:       c.Rendered,
+				Stats:          c.Stats,
+				Trace:          c.Trace,
+				Reviewed:       c.Reviewed,
+				Violations:     c.Violations,
+				Assertions:     c.Assertions,
+				Object:         c.Object,
+				ObjectLine:     c.ObjectLine,
+			})
+		}
+		suite.Tests = append(suite.Tests, test)
+	}
+	return suite
+}
+
+func seconds(d Duration) float64 {
+	return time.Duration(d).Seconds()
+}
@@ -0,0 +1,94 @@
+package gktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrinterJSON_Print(t *testing.T) {
+	results := []SuiteResult{{
+		Path:    "tests/labels.yaml",
+		Runtime: Duration(2 * time.Second),
+		TestResults: []TestResult{{
+			Name:    "required-labels",
+			Runtime: Duration(time.Second),
+			CaseResults: []CaseResult{{
+				Name:    "allowed",
+				Runtime: Duration(time.Millisecond),
+			}, {
+				Name:       "missing-owner",
+				Runtime:    Duration(2 * time.Millisecond),
+				Error:      fmt.Errorf("%w: got 0 violations but want at least 1", ErrNumViolations),
+				Reviewed:   true,
+				Assertions: []string{"violations: yes"},
+				Rendered:   "kind: Pod",
+			}},
+		}},
+	}, {
+		Path:  "tests/missing.yaml",
+		Error: fmt.Errorf("%w: template.yaml", ErrInvalidSuite),
+	}}
+
+	w := &strings.Builder{}
+	if err := (PrinterJSON{}).Print(w, results, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var got jsonReport
+	if err := json.Unmarshal([]byte(w.String()), &got); err != nil {
+		t.Fatalf("got invalid JSON: %v\n%s", err, w.String())
+	}
+	want := jsonReport{
+		Failed: true,
+		Summary: jsonSummary{
+			Passed:            1,
+			AssertionFailures: 1,
+			SuiteErrors:       1,
+			ExitCode:          ExitSuite,
+		},
+		Suites: []jsonSuite{{
+			Path:           "tests/labels.yaml",
+			Failed:         true,
+			RuntimeSeconds: 2,
+			Tests: []jsonTest{{
+				Name:           "required-labels",
+				Failed:         true,
+				RuntimeSeconds: 1,
+				Cases: []jsonCase{{
+					Name:           "allowed",
+					RuntimeSeconds: 0.001,
+				}, {
+					Name:           "missing-owner",
+					Failed:         true,
+					Error:          "unexpected number of violations: got 0 violations but want at least 1",
+					RuntimeSeconds: 0.002,
+					Rendered:       "kind: Pod",
+					Reviewed:       true,
+					Assertions:     []string{"violations: yes"},
+				}},
+			}},
+		}, {
+			Path:   "tests/missing.yaml",
+			Failed: true,
+			Error:  "invalid Suite: template.yaml",
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestPrinterJSON_Print_Empty(t *testing.T) {
+	w := &strings.Builder{}
+	if err := (PrinterJSON{}).Print(w, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), `"suites": []`) {
+		t.Errorf("got %s, want an empty list of suites", w.String())
+	}
+}
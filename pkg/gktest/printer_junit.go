@@ -0,0 +1,193 @@
+package gktest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PrinterJUnit prints the results of Suites as JUnit XML, which CI systems
+// such as Jenkins and GitLab display as test reports. Each Suite is a
+// testsuite, and each Case a testcase named after its Test. Failed Cases are
+// failures, skipped Cases are skipped, and Suites and Tests which could not be
+// run are errors.
+type PrinterJUnit struct {
+	// Now returns the time the report is generated at. Defaults to time.Now.
+	Now func() time.Time
+}
+
+var _ Printer = PrinterJUnit{}
+
+// junitName is the name of the testsuites of reports.
+const junitName = "gator test"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr,omitempty"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct{}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Print writes the JUnit XML report of r to w. If verbose, the rendered
+// objects of failed Cases are included as their output.
+func (p PrinterJUnit) Print(w StringWriter, r []SuiteResult, verbose bool) error {
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+
+	report := p.report(r, now(), verbose)
+	b, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("rendering JUnit report: %w", err)
+	}
+	_, err = w.WriteString(xml.Header + string(b) + "\n")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+	return nil
+}
+
+func (p PrinterJUnit) report(results []SuiteResult, now time.Time, verbose bool) junitTestSuites {
+	report := junitTestSuites{Name: junitName}
+	var runtime Duration
+	for i := range results {
+		s := &results[i]
+		runtime += s.Runtime
+
+		suite := junitTestSuite{
+			Name:      s.Path,
+			Time:      junitTime(s.Runtime),
+			Timestamp: now.UTC().Format("2006-01-02T15:04:05"),
+		}
+		if s.Error != nil {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      s.Path,
+				ClassName: s.Path,
+				Time:      junitTime(s.Runtime),
+				Error:     junitFailureOf(s.Error, ""),
+			})
+			suite.Errors++
+		}
+		for j := range s.TestResults {
+			t := &s.TestResults[j]
+			if t.Name == "" && t.Runtime == 0 && t.Error == nil {
+				// Tests filtered out have empty results.
+				continue
+			}
+			if t.Error != nil {
+				suite.Cases = append(suite.Cases, junitTestCase{
+					Name:      t.Name,
+					ClassName: t.Name,
+					Time:      junitTime(t.Runtime),
+					Error:     junitFailureOf(t.Error, ""),
+				})
+				suite.Errors++
+			}
+			for k := range t.CaseResults {
+				c := &t.CaseResults[k]
+				if c.Runtime == 0 && c.Error == nil && c.Assertions == nil && !c.Skipped {
+					// Cases filtered out have empty results.
+					continue
+				}
+				tc := junitTestCase{Name: c.Name, ClassName: t.Name, Time: junitTime(c.Runtime)}
+				if c.Skipped {
+					tc.Skipped = &junitSkipped{}
+					suite.Skipped++
+				}
+				if c.IsFailure() {
+					tc.Failure = junitFailureOf(c.Error, junitDetails(c))
+					suite.Failures++
+					if verbose {
+						tc.SystemOut = c.Rendered
+					}
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		}
+		suite.Tests = len(suite.Cases)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = junitTime(runtime)
+	return report
+}
+
+// junitFailureOf returns the failure for err, with the first line of err as
+// its message followed by details.
+func junitFailureOf(err error, details string) *junitFailure {
+	msg := err.Error()
+	text := msg
+	if details != "" {
+		text += "\n\n" + details
+	}
+	if i := strings.Index(msg, "\n"); i >= 0 {
+		msg = msg[:i]
+	}
+	return &junitFailure{Message: msg, Text: text}
+}
+
+// junitDetails describes what c expected of its object and its violations,
+// if the Runner recorded Details.
+func junitDetails(c *CaseResult) string {
+	if c.Assertions == nil {
+		return ""
+	}
+	b := &strings.Builder{}
+	b.WriteString("want:\n")
+	for _, a := range c.Assertions {
+		fmt.Fprintf(b, "  %s\n", a)
+	}
+	switch {
+	case len(c.Violations) > 0:
+		b.WriteString("got:\n")
+		for _, v := range c.Violations {
+			fmt.Fprintf(b, "  %s\n", v)
+		}
+	case c.Reviewed:
+		b.WriteString("got: no violations\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// junitTime formats d in seconds, as JUnit reports durations.
+func junitTime(d Duration) string {
+	return fmt.Sprintf("%.3f", time.Duration(d).Seconds())
+}
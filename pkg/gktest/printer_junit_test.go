@@ -0,0 +1,98 @@
+package gktest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrinterJUnit_Print(t *testing.T) {
+	results := []SuiteResult{{
+		Path:    "tests/labels.yaml",
+		Runtime: Duration(2 * time.Second),
+		TestResults: []TestResult{{
+			Name:    "required-labels",
+			Runtime: Duration(time.Second),
+			CaseResults: []CaseResult{{
+				Name:    "allowed",
+				Runtime: Duration(time.Millisecond),
+			}, {
+				Name:       "missing-owner",
+				Runtime:    Duration(2 * time.Millisecond),
+				Error:      errors.New("got 0 violations but want at least 1"),
+				Reviewed:   true,
+				Violations: []string{},
+				Assertions: []string{`violations: yes, message: "<owner>"`},
+				Rendered:   "kind: Pod",
+			}, {
+				// Filtered out.
+			}},
+		}, {
+			Name:    "bad-template",
+			Runtime: Duration(3 * time.Millisecond),
+			Error:   errors.New("compiling template"),
+		}, {
+			// Filtered out.
+		}},
+	}, {
+		Path:    "tests/missing.yaml",
+		Runtime: Duration(time.Millisecond),
+		Error:   errors.New("reading suite"),
+	}}
+
+	w := &strings.Builder{}
+	p := PrinterJUnit{Now: func() time.Time { return time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC) }}
+	err := p.Print(w, results, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="gator test" tests="4" failures="1" errors="2" time="2.001">
+  <testsuite name="tests/labels.yaml" tests="3" failures="1" errors="1" time="2.000" timestamp="2021-09-01T00:00:00">
+    <testcase name="allowed" classname="required-labels" time="0.001"></testcase>
+    <testcase name="missing-owner" classname="required-labels" time="0.002">
+      <failure message="got 0 violations but want at least 1">got 0 violations but want at least 1&#xA;&#xA;want:&#xA;  violations: yes, message: &#34;&lt;owner&gt;&#34;&#xA;got: no violations</failure>
+      <system-out>kind: Pod</system-out>
+    </testcase>
+    <testcase name="bad-template" classname="bad-template" time="0.003">
+      <error message="compiling template">compiling template</error>
+    </testcase>
+  </testsuite>
+  <testsuite name="tests/missing.yaml" tests="1" failures="0" errors="1" time="0.001" timestamp="2021-09-01T00:00:00">
+    <testcase name="tests/missing.yaml" classname="tests/missing.yaml" time="0.001">
+      <error message="reading suite">reading suite</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if diff := cmp.Diff(want, w.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestPrinterJUnit_Print_NotVerbose(t *testing.T) {
+	results := []SuiteResult{{
+		Path: "tests/labels.yaml",
+		TestResults: []TestResult{{
+			Name: "required-labels",
+			CaseResults: []CaseResult{{
+				Name:     "missing-owner",
+				Error:    errors.New("got 0 violations but want at least 1"),
+				Rendered: "kind: Pod",
+			}},
+		}},
+	}}
+
+	w := &strings.Builder{}
+	err := PrinterJUnit{}.Print(w, results, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(w.String(), "system-out") {
+		t.Errorf("got the rendered object of a failed case without verbose:\n%s", w.String())
+	}
+}
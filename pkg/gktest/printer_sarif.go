@@ -0,0 +1,175 @@
+package gktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// PrinterSARIF prints the failures of Suites as a SARIF log, which code
+// scanning tools such as that of GitHub use to annotate pull requests. Each
+// Test is a rule, and each failed Case a result located at the object under
+// test, if the Runner recorded Details. Suites and Tests which could not be run
+// are results located at their Suite.
+type PrinterSARIF struct {
+	// Root is the directory paths are made relative to, usually that of the
+	// repository the Suites are in. Paths outside Root are printed as they are.
+	Root string
+}
+
+var _ Printer = PrinterSARIF{}
+
+const (
+	sarifSchema     = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion    = "2.1.0"
+	sarifDriverName = "gator"
+	// sarifSuiteRule is the rule of results for Suites which could not be run.
+	sarifSuiteRule = "suite"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// Print writes the SARIF log of the failures in r to w. Passing Cases are not
+// results, so verbose is ignored.
+func (p PrinterSARIF) Print(w StringWriter, r []SuiteResult, verbose bool) error {
+	b, err := json.MarshalIndent(p.log(r), "", "  ")
+	if err != nil {
+		return fmt.Errorf("rendering SARIF log: %w", err)
+	}
+	_, err = w.WriteString(string(b) + "\n")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+	return nil
+}
+
+func (p PrinterSARIF) log(results []SuiteResult) sarifLog {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: sarifDriverName}},
+		Results: []sarifResult{},
+	}
+	rules := make(map[string]bool)
+	addRule := func(id, description string) {
+		if rules[id] {
+			return
+		}
+		rules[id] = true
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: description}})
+	}
+
+	for i := range results {
+		s := &results[i]
+		if s.Error != nil {
+			addRule(sarifSuiteRule, "Suites of tests must be valid")
+			run.Results = append(run.Results, p.result(sarifSuiteRule, s.Error.Error(), s.Path, 0))
+		}
+		for j := range s.TestResults {
+			t := &s.TestResults[j]
+			if !t.IsFailure() {
+				continue
+			}
+			addRule(t.Name, fmt.Sprintf("Cases of test %q must pass", t.Name))
+			if t.Error != nil {
+				run.Results = append(run.Results, p.result(t.Name, t.Error.Error(), s.Path, 0))
+			}
+			for k := range t.CaseResults {
+				c := &t.CaseResults[k]
+				if !c.IsFailure() {
+					continue
+				}
+				msg := fmt.Sprintf("case %q failed: %v", c.Name, c.Error)
+				if details := junitDetails(c); details != "" {
+					msg += "\n" + details
+				}
+				if c.Object == "" {
+					// Without Details, the object is not known.
+					run.Results = append(run.Results, p.result(t.Name, msg, s.Path, 0))
+					continue
+				}
+				run.Results = append(run.Results, p.result(t.Name, msg, c.Object, c.ObjectLine))
+			}
+		}
+	}
+
+	return sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+// result returns the error result of rule located at line of the file at
+// path, or at the file itself if line is 0.
+func (p PrinterSARIF) result(rule, msg, path string, line int) sarifResult {
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: p.uri(path)},
+	}}
+	if line > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+	}
+	return sarifResult{
+		RuleID:    rule,
+		Level:     "error",
+		Message:   sarifMessage{Text: msg},
+		Locations: []sarifLocation{location},
+	}
+}
+
+// uri returns path relative to Root, if it is within Root.
+func (p PrinterSARIF) uri(path string) string {
+	if p.Root != "" {
+		rel, err := filepath.Rel(p.Root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(filepath.ToSlash(rel), "../") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,105 @@
+package gktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrinterSARIF_Print(t *testing.T) {
+	results := []SuiteResult{{
+		Path: "repo/tests/labels.yaml",
+		TestResults: []TestResult{{
+			Name: "required-labels",
+			CaseResults: []CaseResult{{
+				Name: "allowed",
+			}, {
+				Name:       "missing-owner",
+				Error:      fmt.Errorf("%w: got 0 violations but want at least 1", ErrNumViolations),
+				Reviewed:   true,
+				Assertions: []string{"violations: yes"},
+				Object:     "repo/tests/pod.yaml",
+				ObjectLine: 3,
+			}},
+		}, {
+			Name:  "broken",
+			Error: fmt.Errorf("%w: template.yaml", ErrInvalidYAML),
+		}},
+	}, {
+		Path:  "elsewhere/missing.yaml",
+		Error: fmt.Errorf("%w: template.yaml", ErrInvalidSuite),
+	}}
+
+	w := &strings.Builder{}
+	if err := (PrinterSARIF{Root: "repo"}).Print(w, results, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var got sarifLog
+	if err := json.Unmarshal([]byte(w.String()), &got); err != nil {
+		t.Fatalf("got invalid JSON: %v\n%s", err, w.String())
+	}
+	location := func(uri string, line int) []sarifLocation {
+		l := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}}
+		if line > 0 {
+			l.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+		}
+		return []sarifLocation{l}
+	}
+	want := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name: "gator",
+				Rules: []sarifRule{{
+					ID:               "required-labels",
+					ShortDescription: sarifMessage{Text: `Cases of test "required-labels" must pass`},
+				}, {
+					ID:               "broken",
+					ShortDescription: sarifMessage{Text: `Cases of test "broken" must pass`},
+				}, {
+					ID:               "suite",
+					ShortDescription: sarifMessage{Text: "Suites of tests must be valid"},
+				}},
+			}},
+			Results: []sarifResult{{
+				RuleID:    "required-labels",
+				Level:     "error",
+				Message:   sarifMessage{Text: "case \"missing-owner\" failed: unexpected number of violations: got 0 violations but want at least 1\nwant:\n  violations: yes\ngot: no violations"},
+				Locations: location("tests/pod.yaml", 3),
+			}, {
+				RuleID:    "broken",
+				Level:     "error",
+				Message:   sarifMessage{Text: "invalid yaml: template.yaml"},
+				Locations: location("tests/labels.yaml", 0),
+			}, {
+				RuleID:    "suite",
+				Level:     "error",
+				Message:   sarifMessage{Text: "invalid Suite: template.yaml"},
+				Locations: location("elsewhere/missing.yaml", 0),
+			}},
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestPrinterSARIF_Print_Passing(t *testing.T) {
+	results := []SuiteResult{{
+		Path:        "tests/labels.yaml",
+		TestResults: []TestResult{{Name: "required-labels", CaseResults: []CaseResult{{Name: "allowed"}}}},
+	}}
+
+	w := &strings.Builder{}
+	if err := (PrinterSARIF{}).Print(w, results, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), `"results": []`) {
+		t.Errorf("got %s, want no results", w.String())
+	}
+}
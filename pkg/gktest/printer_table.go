@@ -0,0 +1,100 @@
+package gktest
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// PrinterTable prints a kubectl-style table summarizing each Suite, followed
+// by the failures of the Suites which failed in the format of PrinterGo.
+type PrinterTable struct {
+	// Wide adds columns with the number of Template/Constraint tests, the
+	// number of skipped Cases and the error which stopped each Suite from
+	// executing.
+	Wide bool
+}
+
+var _ Printer = PrinterTable{}
+
+func (p PrinterTable) Print(w StringWriter, r []SuiteResult, verbose bool) error {
+	table := &strings.Builder{}
+	tw := tabwriter.NewWriter(table, 0, 8, 3, ' ', 0)
+
+	header := "NAME\tCASES\tFAILURES\tRUNTIME"
+	if p.Wide {
+		header = "NAME\tTESTS\tCASES\tFAILURES\tSKIPPED\tRUNTIME\tERROR"
+	}
+	fmt.Fprintln(tw, header)
+
+	fail := false
+	for i := range r {
+		fmt.Fprintln(tw, p.row(&r[i]))
+		if r[i].IsFailure() {
+			fail = true
+		}
+	}
+	// Flushing writes to a strings.Builder, which never returns an error.
+	_ = tw.Flush()
+
+	_, err := w.WriteString(table.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+
+	if fail {
+		_, err = w.WriteString("\n")
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWritingString, err)
+		}
+		for i := range r {
+			if !r[i].IsFailure() {
+				continue
+			}
+			err = PrinterGo{}.PrintSuite(w, &r[i], verbose)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = w.WriteString("FAIL\n")
+	} else {
+		_, err = w.WriteString("PASS\n")
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+	return nil
+}
+
+// row returns the tab-separated columns of the table for r.
+func (p PrinterTable) row(r *SuiteResult) string {
+	cases, failures, skipped := 0, 0, 0
+	for _, t := range r.TestResults {
+		if t.Error != nil {
+			failures++
+		}
+		for _, c := range t.CaseResults {
+			cases++
+			if c.IsFailure() {
+				failures++
+			}
+			if c.Skipped {
+				skipped++
+			}
+		}
+	}
+	if r.Error != nil {
+		failures++
+	}
+
+	if !p.Wide {
+		return fmt.Sprintf("%s\t%d\t%d\t%v", r.Path, cases, failures, r.Runtime)
+	}
+
+	errMsg := "<none>"
+	if r.Error != nil {
+		// Errors may span lines, which would break up the table.
+		errMsg = strings.ReplaceAll(r.Error.Error(), "\n", " ")
+	}
+	return fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%v\t%s", r.Path, len(r.TestResults), cases, failures, skipped, r.Runtime, errMsg)
+}
@@ -0,0 +1,139 @@
+package gktest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrinterTable_Print(t *testing.T) {
+	passing := SuiteResult{
+		Path:    "tests.go",
+		Runtime: Duration(330 * time.Millisecond),
+		TestResults: []TestResult{{
+			Name:    "forbid-labels",
+			Runtime: Duration(330 * time.Millisecond),
+			CaseResults: []CaseResult{{
+				Name:    "forbid-labels/with label",
+				Runtime: Duration(100 * time.Millisecond),
+			}, {
+				Name:    "forbid-labels/without label",
+				Runtime: Duration(230 * time.Millisecond),
+			}},
+		}},
+	}
+	failing := SuiteResult{
+		Path:    "tests-2.go",
+		Runtime: Duration(400 * time.Millisecond),
+		TestResults: []TestResult{{
+			Name:    "require-labels",
+			Runtime: Duration(400 * time.Millisecond),
+			CaseResults: []CaseResult{{
+				Name:    "require-labels/with label",
+				Runtime: Duration(170 * time.Millisecond),
+			}, {
+				Name:    "require-labels/without label",
+				Error:   errors.New("got allow but want violation"),
+				Runtime: Duration(230 * time.Millisecond),
+			}},
+		}},
+	}
+	broken := SuiteResult{
+		Path:  "broken.go",
+		Error: errors.New("invalid\nsuite"),
+	}
+
+	testCases := []struct {
+		name    string
+		wide    bool
+		verbose bool
+		result  []SuiteResult
+		want    string
+	}{
+		{
+			name:   "no suites",
+			result: []SuiteResult{},
+			want: `NAME   CASES   FAILURES   RUNTIME
+PASS
+`,
+		},
+		{
+			name:   "passing suite",
+			result: []SuiteResult{passing},
+			want: `NAME       CASES   FAILURES   RUNTIME
+tests.go   2       0          0.330s
+PASS
+`,
+		},
+		{
+			name:   "failing suites",
+			result: []SuiteResult{passing, failing, broken},
+			want: `NAME         CASES   FAILURES   RUNTIME
+tests.go     2       0          0.330s
+tests-2.go   2       1          0.400s
+broken.go    0       1          0.000s
+
+    --- FAIL: require-labels/without label	(0.230s)
+        got allow but want violation
+--- FAIL: require-labels	(0.400s)
+FAIL	tests-2.go	0.400s
+FAIL	broken.go	0.000s
+  invalid
+suite
+FAIL
+`,
+		},
+		{
+			name:    "failing suite verbose",
+			verbose: true,
+			result:  []SuiteResult{passing, failing},
+			want: `NAME         CASES   FAILURES   RUNTIME
+tests.go     2       0          0.330s
+tests-2.go   2       1          0.400s
+
+=== RUN   require-labels
+    === RUN   require-labels/with label
+    --- PASS: require-labels/with label	(0.170s)
+    === RUN   require-labels/without label
+    --- FAIL: require-labels/without label	(0.230s)
+        got allow but want violation
+--- FAIL: require-labels	(0.400s)
+FAIL	tests-2.go	0.400s
+FAIL
+`,
+		},
+		{
+			name:   "wide",
+			wide:   true,
+			result: []SuiteResult{passing, broken},
+			want: `NAME        TESTS   CASES   FAILURES   SKIPPED   RUNTIME   ERROR
+tests.go    1       2       0          0         0.330s    <none>
+broken.go   0       0       1          0         0.000s    invalid suite
+
+FAIL	broken.go	0.000s
+  invalid
+suite
+FAIL
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &strings.Builder{}
+			gotErr := PrinterTable{Wide: tc.wide}.Print(w, tc.result, tc.verbose)
+
+			if gotErr != nil {
+				t.Fatal(gotErr)
+			}
+			wantLines := strings.Split(tc.want, "\n")
+			gotLines := strings.Split(w.String(), "\n")
+			if diff := cmp.Diff(wantLines, gotLines); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
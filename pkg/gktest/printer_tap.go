@@ -0,0 +1,133 @@
+package gktest
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PrinterTAP prints the results of Suites in the Test Anything Protocol,
+// version 13, for harnesses such as prove. Each Case is a test point, as are
+// Suites and Tests which could not be run. Failed test points are followed by
+// a YAML block describing the failure, and skipped Cases have the SKIP
+// directive.
+type PrinterTAP struct{}
+
+var _ Printer = PrinterTAP{}
+
+// tapPoint is a test point of a TAP report.
+type tapPoint struct {
+	description string
+	skipped     bool
+	failure     *tapFailure
+}
+
+// tapFailure is the YAML block describing a failed test point.
+type tapFailure struct {
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+	// Want and Got are what a Case expected of its object and the violations
+	// of the object, if the Runner recorded Details.
+	Want     []string `json:"want,omitempty"`
+	Got      []string `json:"got,omitempty"`
+	Runtime  string   `json:"runtime,omitempty"`
+	Rendered string   `json:"rendered,omitempty"`
+}
+
+// Print writes the TAP report of r to w. If verbose, the rendered objects of
+// failed Cases are included in their YAML blocks.
+func (p PrinterTAP) Print(w StringWriter, r []SuiteResult, verbose bool) error {
+	points := p.points(r, verbose)
+
+	b := &strings.Builder{}
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(b, "1..%d\n", len(points))
+	for i, point := range points {
+		if point.skipped {
+			fmt.Fprintf(b, "ok %d - %s # SKIP\n", i+1, point.description)
+			continue
+		}
+		if point.failure == nil {
+			fmt.Fprintf(b, "ok %d - %s\n", i+1, point.description)
+			continue
+		}
+		fmt.Fprintf(b, "not ok %d - %s\n", i+1, point.description)
+		block, err := yaml.Marshal(point.failure)
+		if err != nil {
+			return fmt.Errorf("rendering TAP report: %w", err)
+		}
+		b.WriteString("  ---\n")
+		for _, line := range strings.Split(strings.TrimSuffix(string(block), "\n"), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+		b.WriteString("  ...\n")
+	}
+
+	_, err := w.WriteString(b.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWritingString, err)
+	}
+	return nil
+}
+
+func (p PrinterTAP) points(results []SuiteResult, verbose bool) []tapPoint {
+	var points []tapPoint
+	for i := range results {
+		s := &results[i]
+		if s.Error != nil {
+			points = append(points, tapPoint{
+				description: tapDescription(s.Path),
+				failure:     &tapFailure{Message: s.Error.Error(), Severity: "fail"},
+			})
+		}
+		for j := range s.TestResults {
+			t := &s.TestResults[j]
+			if t.Name == "" && t.Runtime == 0 && t.Error == nil {
+				// Tests filtered out have empty results.
+				continue
+			}
+			if t.Error != nil {
+				points = append(points, tapPoint{
+					description: tapDescription(s.Path, t.Name),
+					failure:     &tapFailure{Message: t.Error.Error(), Severity: "fail", Runtime: t.Runtime.String()},
+				})
+			}
+			for k := range t.CaseResults {
+				c := &t.CaseResults[k]
+				if c.Runtime == 0 && c.Error == nil && c.Assertions == nil && !c.Skipped {
+					// Cases filtered out have empty results.
+					continue
+				}
+				point := tapPoint{description: tapDescription(s.Path, t.Name, c.Name), skipped: c.Skipped}
+				if c.IsFailure() {
+					point.failure = &tapFailure{
+						Message:  c.Error.Error(),
+						Severity: "fail",
+						Want:     c.Assertions,
+						Got:      c.Violations,
+						Runtime:  c.Runtime.String(),
+					}
+					if c.Reviewed && len(c.Violations) == 0 {
+						point.failure.Got = []string{"no violations"}
+					}
+					if verbose {
+						point.failure.Rendered = c.Rendered
+					}
+				}
+				points = append(points, point)
+			}
+		}
+	}
+	return points
+}
+
+// tapDescription joins the path of a Suite with the names of a Test and Case.
+// TAP treats "#" as the start of a directive, so it is escaped.
+func tapDescription(path string, names ...string) string {
+	description := path
+	if len(names) > 0 {
+		description += " " + strings.Join(names, "/")
+	}
+	return strings.ReplaceAll(description, "#", `\#`)
+}
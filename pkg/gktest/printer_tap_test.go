@@ -0,0 +1,108 @@
+package gktest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrinterTAP_Print(t *testing.T) {
+	results := []SuiteResult{{
+		Path: "tests/labels.yaml",
+		TestResults: []TestResult{{
+			Name: "required-labels",
+			CaseResults: []CaseResult{{
+				Name:    "allowed",
+				Runtime: Duration(time.Millisecond),
+			}, {
+				Name:       "missing #owner",
+				Runtime:    Duration(2 * time.Millisecond),
+				Error:      fmt.Errorf("%w: got 0 violations but want at least 1", ErrNumViolations),
+				Reviewed:   true,
+				Assertions: []string{"violations: yes"},
+				Rendered:   "kind: Pod\n",
+			}, {
+				Name:    "pending",
+				Skipped: true,
+			}, {
+				// Filtered out.
+			}},
+		}, {
+			// Filtered out.
+		}},
+	}, {
+		Path:  "tests/missing.yaml",
+		Error: fmt.Errorf("%w: template.yaml", ErrInvalidSuite),
+	}}
+
+	testCases := []struct {
+		name    string
+		verbose bool
+		want    string
+	}{
+		{
+			name: "default",
+			want: `TAP version 13
+1..4
+ok 1 - tests/labels.yaml required-labels/allowed
+not ok 2 - tests/labels.yaml required-labels/missing \#owner
+  ---
+  got:
+  - no violations
+  message: 'unexpected number of violations: got 0 violations but want at least 1'
+  runtime: 0.002s
+  severity: fail
+  want:
+  - 'violations: yes'
+  ...
+ok 3 - tests/labels.yaml required-labels/pending # SKIP
+not ok 4 - tests/missing.yaml
+  ---
+  message: 'invalid Suite: template.yaml'
+  severity: fail
+  ...
+`,
+		},
+		{
+			name:    "verbose",
+			verbose: true,
+			want: `TAP version 13
+1..4
+ok 1 - tests/labels.yaml required-labels/allowed
+not ok 2 - tests/labels.yaml required-labels/missing \#owner
+  ---
+  got:
+  - no violations
+  message: 'unexpected number of violations: got 0 violations but want at least 1'
+  rendered: |
+    kind: Pod
+  runtime: 0.002s
+  severity: fail
+  want:
+  - 'violations: yes'
+  ...
+ok 3 - tests/labels.yaml required-labels/pending # SKIP
+not ok 4 - tests/missing.yaml
+  ---
+  message: 'invalid Suite: template.yaml'
+  severity: fail
+  ...
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &strings.Builder{}
+			if err := (PrinterTAP{}).Print(w, results, tc.verbose); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, w.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -1,13 +1,17 @@
 package gktest
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 
 	templatesv1 "github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	"github.com/open-policy-agent/gatekeeper/apis"
+	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -34,6 +38,36 @@ func readUnstructured(bytes []byte) (*unstructured.Unstructured, error) {
 	return u, nil
 }
 
+// readDocuments returns the objects defined by the YAML documents in b, which
+// are separated by "---". Empty documents are skipped.
+func readDocuments(b []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	var objs []*unstructured.Unstructured
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		docBytes, err := yaml.Marshal(&doc)
+		if err != nil {
+			return nil, err
+		}
+		u, err := readUnstructured(docBytes)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, u)
+	}
+}
+
 // readTemplate reads the contents of the path and returns the
 // ConstraintTemplate it defines. Returns an error if the file does not define
 // a ConstraintTemplate.
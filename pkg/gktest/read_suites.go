@@ -55,6 +55,17 @@ func ReadSuites(f fs.FS, target string, recursive bool) (map[string]*Suite, erro
 		return nil, ErrNoTarget
 	}
 
+	files, err := listFiles(f, target, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	return readSuites(f, files)
+}
+
+// listFiles returns the YAML files selected by target, traversing
+// subdirectories if recursive is true.
+func listFiles(f fs.FS, target string, recursive bool) (fileList, error) {
 	stat, err := fs.Stat(f, target)
 	if err != nil {
 		return nil, err
@@ -83,7 +94,7 @@ func ReadSuites(f fs.FS, target string, recursive bool) (map[string]*Suite, erro
 		return nil, err
 	}
 
-	return readSuites(f, files)
+	return files, nil
 }
 
 // readSuites reads the passed set of files into Suites on the given filesystem.
@@ -158,7 +169,7 @@ func readSuite(f fs.FS, path string) (*Suite, error) {
 	u := unstructured.Unstructured{
 		Object: make(map[string]interface{}),
 	}
-	err = yaml.Unmarshal(bytes, u.Object)
+	err = unmarshalYAML(bytes, u.Object)
 	if err != nil {
 		return nil, fmt.Errorf("%w: parsing yaml file %q: %v", ErrInvalidYAML, path, err)
 	}
@@ -184,7 +195,7 @@ func parseYAML(yamlBytes []byte, v interface{}) error {
 	// panics when handed scalar types it doesn't recognize.
 	obj := make(map[string]interface{})
 
-	err := yaml.Unmarshal(yamlBytes, obj)
+	err := unmarshalYAML(yamlBytes, obj)
 	if err != nil {
 		return err
 	}
@@ -197,6 +208,54 @@ func parseYAML(yamlBytes []byte, v interface{}) error {
 	return parseJSON(jsonBytes, v)
 }
 
+// unmarshalYAML unmarshals yamlBytes into v. Anchors, aliases and merge keys
+// are resolved, and aliases which refer to a node containing them are reported
+// with their lines.
+func unmarshalYAML(yamlBytes []byte, v interface{}) error {
+	if err := checkAliases(yamlBytes); err != nil {
+		return err
+	}
+	return yaml.Unmarshal(yamlBytes, v)
+}
+
+// checkAliases returns an error if an alias in yamlBytes refers to an anchored
+// node which contains it, which would expand forever. yaml.Unmarshal detects
+// this too, but without saying where. Syntax errors are left to the decoder.
+func checkAliases(yamlBytes []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(yamlBytes, &doc); err != nil {
+		return nil
+	}
+	return checkNodeAliases(&doc, make(map[*yaml.Node]bool), make(map[*yaml.Node]bool))
+}
+
+// checkNodeAliases checks the aliases within n. open holds the nodes being
+// checked which contain n, and done those already checked.
+func checkNodeAliases(n *yaml.Node, open, done map[*yaml.Node]bool) error {
+	if done[n] {
+		return nil
+	}
+	open[n] = true
+	defer delete(open, n)
+
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		if open[n.Alias] {
+			return fmt.Errorf("alias *%s on line %d refers to anchor &%s on line %d, which contains the alias",
+				n.Value, n.Line, n.Alias.Anchor, n.Alias.Line)
+		}
+		if err := checkNodeAliases(n.Alias, open, done); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.Content {
+		if err := checkNodeAliases(child, open, done); err != nil {
+			return err
+		}
+	}
+	done[n] = true
+	return nil
+}
+
 func parseJSON(jsonBytes []byte, v interface{}) error {
 	return json.Unmarshal(jsonBytes, v)
 }
@@ -380,3 +380,87 @@ tests: {}
 		})
 	}
 }
+
+func TestReadSuites_Anchors(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"test.yaml": &fstest.MapFile{
+			Data: []byte(`
+kind: Suite
+apiVersion: test.gatekeeper.sh/v1alpha1
+tests:
+- name: labels
+  template: template.yaml
+  constraint: constraint.yaml
+  cases:
+  - &allowed
+    name: allowed
+    object: allowed.yaml
+    assertions:
+    - violations: no
+  - <<: *allowed
+    name: also-allowed
+    object: also-allowed.yaml
+`),
+		},
+	}
+
+	got, err := ReadSuites(fileSystem, "test.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := got["test.yaml"].Tests[0].Cases
+	if len(cases) != 2 {
+		t.Fatalf("got %d cases, want 2", len(cases))
+	}
+	merged := cases[1]
+	if merged.Name != "also-allowed" || merged.Object != "also-allowed.yaml" {
+		t.Errorf("got case %q of %q, want the keys of the case to override the merged ones", merged.Name, merged.Object)
+	}
+	if len(merged.Assertions) != 1 || merged.Assertions[0].Violations.String() != "no" {
+		t.Errorf("got assertions %v, want those of the merged case", describeAssertions(merged))
+	}
+}
+
+func TestCheckAliases(t *testing.T) {
+	testCases := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "alias",
+			yaml: "a: &a {x: 1}\nb: *a\n",
+		},
+		{
+			name: "merge keys",
+			yaml: "a: &a {x: 1}\nb: &b {y: 2}\nc:\n  <<: [*a, *b]\n",
+		},
+		{
+			name:    "alias within its anchor",
+			yaml:    "a: &a\n  b:\n  - *a\n",
+			wantErr: "alias *a on line 3 refers to anchor &a on line 1, which contains the alias",
+		},
+		{
+			name:    "merge within its anchor",
+			yaml:    "a: &a\n  x: 1\n  <<: *a\n",
+			wantErr: "alias *a on line 3 refers to anchor &a on line 1, which contains the alias",
+		},
+		{
+			name: "syntax error",
+			yaml: "a: [",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkAliases([]byte(tc.yaml))
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tc.wantErr {
+				t.Errorf("got error %q, want %q", gotErr, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package gktest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/tester"
+)
+
+// regoTestSuffix is the suffix of the files holding Rego unit tests.
+const regoTestSuffix = "_test.rego"
+
+// runRegoTests runs the Rego unit tests of the Template of t, as `opa test`
+// would. They are the test rules of the *_test.rego files in the directory of
+// the Template which are in the package of its Rego, or import it. Returns a
+// CaseResult for each test which is not skipped. They are run one Test at a
+// time, as they are compiled and evaluated together.
+func (r *Runner) runRegoTests(ctx context.Context, suiteDir string, filter Filter, t Test) ([]CaseResult, error) {
+	if t.Template == "" {
+		return nil, nil
+	}
+	ingestMux.Lock()
+	defer ingestMux.Unlock()
+
+	templatePath := filepath.Join(suiteDir, t.Template)
+	template, err := readTemplate(r.FS, templatePath)
+	if err != nil {
+		return nil, err
+	}
+	modules, pkg, err := templateModules(templatePath, template)
+	if err != nil {
+		return nil, err
+	}
+
+	testPaths, err := fs.Glob(r.FS, path.Join(filepath.Dir(templatePath), "*"+regoTestSuffix))
+	if err != nil {
+		return nil, err
+	}
+	testFiles := make(map[string]bool)
+	for _, p := range testPaths {
+		src, err := fs.ReadFile(r.FS, p)
+		if err != nil {
+			return nil, fmt.Errorf("reading Rego tests from %q: %w", p, err)
+		}
+		m, err := ast.ParseModule(p, string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRegoTests, err)
+		}
+		if m == nil || !testsPackage(m, pkg) {
+			continue
+		}
+		modules[p] = m
+		testFiles[p] = true
+	}
+	if len(testFiles) == 0 {
+		return nil, nil
+	}
+
+	store := inmem.New()
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Abort(ctx, txn)
+
+	ch, err := tester.NewRunner().SetStore(store).SetModules(modules).EnableFailureLine(true).RunTests(ctx, txn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRegoTests, err)
+	}
+
+	var results []CaseResult
+	for tr := range ch {
+		// Only the results of the tests in the test files are reported, not
+		// of those which may be in the Template's own Rego.
+		if tr.Skip || tr.Location == nil || !testFiles[tr.Location.File] {
+			continue
+		}
+		name := tr.Package + "." + tr.Name
+		if !filter.MatchesCase(Case{Name: name}) {
+			continue
+		}
+		result := CaseResult{Name: name, Runtime: Duration(tr.Duration)}
+		switch {
+		case tr.Error != nil:
+			result.Error = fmt.Errorf("%w: %s: %v", ErrRegoTestFailed, tr.Location, tr.Error)
+		case tr.Fail && tr.FailedAt != nil && tr.FailedAt.Location != nil:
+			result.Error = fmt.Errorf("%w: %s: %s", ErrRegoTestFailed, tr.FailedAt.Location, tr.FailedAt)
+		case tr.Fail:
+			result.Error = fmt.Errorf("%w: %s", ErrRegoTestFailed, tr.Location)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// templateModules returns the modules of the Rego of template, keyed by file
+// name, and the package of its Rego. Libraries are named after the template.
+func templateModules(templatePath string, template *templates.ConstraintTemplate) (map[string]*ast.Module, ast.Ref, error) {
+	if len(template.Spec.Targets) != 1 {
+		return nil, nil, fmt.Errorf("%w: template %q must have exactly one target", ErrAddingTemplate, templatePath)
+	}
+	target := template.Spec.Targets[0]
+	main, err := ast.ParseModule(templatePath, target.Rego)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrAddingTemplate, err)
+	}
+	if main == nil {
+		return nil, nil, fmt.Errorf("%w: template %q has no Rego", ErrAddingTemplate, templatePath)
+	}
+	modules := map[string]*ast.Module{templatePath: main}
+	for i, lib := range target.Libs {
+		name := fmt.Sprintf("%s:lib%d", templatePath, i)
+		m, err := ast.ParseModule(name, lib)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrAddingTemplate, err)
+		}
+		if m != nil {
+			modules[name] = m
+		}
+	}
+	return modules, main.Package.Path, nil
+}
+
+// testsPackage returns whether m is in package pkg, or imports it.
+func testsPackage(m *ast.Module, pkg ast.Ref) bool {
+	if m.Package.Path.Equal(pkg) {
+		return true
+	}
+	for _, imp := range m.Imports {
+		if ref, ok := imp.Path.Value.(ast.Ref); ok && ref.HasPrefix(pkg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package gktest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	regoTestsNeverValidate = `
+package k8snevervalidate
+
+test_violation {
+  count(violation) == 1
+}
+
+test_no_violation {
+  count(violation) == 0
+}
+
+todo_test_skipped {
+  false
+}
+`
+
+	regoTestsImport = `
+package k8snevervalidate_test
+
+import data.k8snevervalidate
+
+test_message {
+  k8snevervalidate.violation[{"msg": "never validate"}]
+}
+`
+
+	regoTestsOtherPackage = `
+package other
+
+test_other {
+  false
+}
+`
+)
+
+func TestRunner_Run_RegoTests(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"tests/template.yaml":        &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"tests/constraint.yaml":      &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+		"tests/object.yaml":          &fstest.MapFile{Data: []byte(object)},
+		"tests/template_test.rego":   &fstest.MapFile{Data: []byte(regoTestsNeverValidate)},
+		"tests/import_test.rego":     &fstest.MapFile{Data: []byte(regoTestsImport)},
+		"tests/other_test.rego":      &fstest.MapFile{Data: []byte(regoTestsOtherPackage)},
+		"tests/not-a-test-file.rego": &fstest.MapFile{Data: []byte(regoTestsOtherPackage)},
+	}
+
+	suite := &Suite{
+		RegoTests: true,
+		Tests: []Test{{
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Cases: []Case{{
+				Name:       "object",
+				Object:     "object.yaml",
+				Assertions: []Assertion{{Violations: intStrFromStr("yes")}},
+			}},
+		}},
+	}
+
+	runner := Runner{FS: fileSystem, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "tests/suite.yaml", suite)
+
+	want := SuiteResult{
+		Path: "tests/suite.yaml",
+		TestResults: []TestResult{{
+			CaseResults: []CaseResult{
+				{Name: "object"},
+				{Name: "data.k8snevervalidate_test.test_message"},
+				{Name: "data.k8snevervalidate.test_violation"},
+				{Name: "data.k8snevervalidate.test_no_violation", Error: ErrRegoTestFailed},
+			},
+		}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+
+	// Without RegoTests only the Cases are run.
+	suite.RegoTests = false
+	got = runner.Run(context.Background(), Filter{}, "tests/suite.yaml", suite)
+	if n := len(got.TestResults[0].CaseResults); n != 1 {
+		t.Errorf("got %d case results, want 1", n)
+	}
+}
+
+func TestRunner_Run_RegoTestsCompileError(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"template.yaml":      &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"constraint.yaml":    &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+		"template_test.rego": &fstest.MapFile{Data: []byte("package k8snevervalidate\n\ntest_undefined { undefined_function(1) }\n")},
+	}
+
+	suite := &Suite{
+		RegoTests: true,
+		Tests:     []Test{{Template: "template.yaml", Constraint: "constraint.yaml"}},
+	}
+
+	runner := Runner{FS: fileSystem, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "suite.yaml", suite)
+
+	want := SuiteResult{
+		Path:        "suite.yaml",
+		TestResults: []TestResult{{Error: ErrRegoTests}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+}
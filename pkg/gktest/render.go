@@ -0,0 +1,157 @@
+package gktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// renderFuncs are the functions available to object templates in addition to
+// those built into text/template. They are a small subset of the Sprig
+// functions Helm charts use, with the same names and argument order.
+var renderFuncs = template.FuncMap{
+	"default": func(d, v interface{}) interface{} {
+		if isEmpty(v) {
+			return d
+		}
+		return v
+	},
+	"quote": func(v interface{}) string {
+		return fmt.Sprintf("%q", fmt.Sprint(v))
+	},
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"indent":     indent,
+	"nindent": func(n int, s string) string {
+		return "\n" + indent(n, s)
+	},
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"toYaml": func(v interface{}) (string, error) {
+		b, err := yaml.Marshal(v)
+		return strings.TrimSuffix(string(b), "\n"), err
+	},
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case int:
+		return v == 0
+	case int64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// readValues reads the values Case objects are rendered with from the file at
+// path.
+func readValues(f fs.FS, path string) (map[string]interface{}, error) {
+	b, err := fs.ReadFile(f, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading values: %v", ErrInvalidSuite, err)
+	}
+	if err := checkAliases(b); err != nil {
+		return nil, fmt.Errorf("%w: parsing values from %q: %v", ErrInvalidYAML, path, err)
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("%w: parsing values from %q: %v", ErrInvalidYAML, path, err)
+	}
+	return values, nil
+}
+
+// mergeValues returns the values of the Suite overridden by those of a Case.
+// Only top-level keys are overridden.
+func mergeValues(suite, c map[string]interface{}) map[string]interface{} {
+	if len(c) == 0 {
+		return suite
+	}
+	merged := make(map[string]interface{}, len(suite)+len(c))
+	for k, v := range suite {
+		merged[k] = v
+	}
+	for k, v := range c {
+		merged[k] = v
+	}
+	return merged
+}
+
+// render executes the template in the file at path with values, which are
+// available as .Values. Referencing a value which is not set is an error.
+func render(f fs.FS, path string, values map[string]interface{}) ([]byte, error) {
+	b, err := fs.ReadFile(f, path)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := template.New(filepath.Base(path)).
+		Option("missingkey=error").
+		Funcs(renderFuncs).
+		Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing %q: %v", ErrRendering, path, err)
+	}
+
+	out := &bytes.Buffer{}
+	err = t.Execute(out, map[string]interface{}{"Values": values})
+	if err != nil {
+		return nil, fmt.Errorf("%w: executing %q: %v", ErrRendering, path, err)
+	}
+	return out.Bytes(), nil
+}
+
+// renderCached returns the rendered object at path, rendering it only the
+// first time the Runner sees path with the same values.
+func (r *Runner) renderCached(path string, values map[string]interface{}) ([]byte, error) {
+	// encoding/json sorts map keys, so equal values have equal keys.
+	key, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding values: %v", ErrRendering, err)
+	}
+	cacheKey := path + "\x00" + string(key)
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if rendered, ok := r.rendered[cacheKey]; ok {
+		return rendered, nil
+	}
+
+	rendered, err := render(r.FS, path, values)
+	if err != nil {
+		return nil, err
+	}
+	if r.rendered == nil {
+		r.rendered = make(map[string][]byte)
+	}
+	r.rendered[cacheKey] = rendered
+	return rendered, nil
+}
@@ -0,0 +1,115 @@
+package gktest
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	objectTemplate = `
+kind: Namespace
+apiVersion: v1
+metadata:
+  name: {{ .Values.name | lower }}
+  labels:
+    team: {{ .Values.team | default "platform" | quote }}`
+
+	objectMissingValue = `
+kind: Namespace
+apiVersion: v1
+metadata:
+  name: {{ .Values.missing }}`
+)
+
+func TestRunner_Run_Values(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"tests/template.yaml":   &fstest.MapFile{Data: []byte(templateAlwaysValidate)},
+		"tests/constraint.yaml": &fstest.MapFile{Data: []byte(constraintAlwaysValidate)},
+		"tests/values.yaml":     &fstest.MapFile{Data: []byte("name: Default\nteam: \"\"\n")},
+		"tests/namespace.yaml":  &fstest.MapFile{Data: []byte(objectTemplate)},
+		"tests/missing.yaml":    &fstest.MapFile{Data: []byte(objectMissingValue)},
+	}
+
+	suite := &Suite{
+		Values: "values.yaml",
+		Tests: []Test{{
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Cases: []Case{{
+				Name:   "suite values",
+				Object: "namespace.yaml",
+			}, {
+				Name:   "case values",
+				Object: "namespace.yaml",
+				Values: map[string]interface{}{"name": "Other", "team": "web"},
+			}, {
+				Name:       "suite values again",
+				Object:     "namespace.yaml",
+				Assertions: []Assertion{{Violations: intStrFromStr("yes")}},
+			}, {
+				Name:   "missing value",
+				Object: "missing.yaml",
+			}},
+		}},
+	}
+
+	runner := Runner{FS: fileSystem, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "tests/suite.yaml", suite)
+
+	renderedDefault := `
+kind: Namespace
+apiVersion: v1
+metadata:
+  name: default
+  labels:
+    team: "platform"`
+	want := SuiteResult{
+		Path: "tests/suite.yaml",
+		TestResults: []TestResult{{
+			CaseResults: []CaseResult{{
+				Name:     "suite values",
+				Rendered: renderedDefault,
+			}, {
+				Name: "case values",
+				Rendered: `
+kind: Namespace
+apiVersion: v1
+metadata:
+  name: other
+  labels:
+    team: "web"`,
+			}, {
+				Name:     "suite values again",
+				Error:    ErrNumViolations,
+				Rendered: renderedDefault,
+			}, {
+				Name:  "missing value",
+				Error: ErrRendering,
+			}},
+		}},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+
+	// Cases with the same object and values share a rendering.
+	if len(runner.rendered) != 2 {
+		t.Errorf("got %d cached renderings, want 2", len(runner.rendered))
+	}
+}
+
+func TestRunner_Run_MissingValues(t *testing.T) {
+	runner := Runner{FS: fstest.MapFS{}, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "suite.yaml", &Suite{Values: "values.yaml"})
+
+	want := SuiteResult{Path: "suite.yaml", Error: ErrInvalidSuite}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.IgnoreFields(SuiteResult{}, "Runtime")); diff != "" {
+		t.Error(diff)
+	}
+}
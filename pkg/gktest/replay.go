@@ -0,0 +1,304 @@
+package gktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrReadingAuditLog indicates an audit log could not be parsed.
+var ErrReadingAuditLog = errors.New("reading audit log")
+
+// AuditEvent is the part of a Kubernetes audit.k8s.io/v1 Event read by Replay.
+type AuditEvent struct {
+	AuditID                  string                    `json:"auditID"`
+	Stage                    string                    `json:"stage"`
+	Verb                     string                    `json:"verb"`
+	User                     authenticationv1.UserInfo `json:"user"`
+	ObjectRef                *AuditObjectReference     `json:"objectRef,omitempty"`
+	ResponseStatus           *metav1.Status            `json:"responseStatus,omitempty"`
+	RequestObject            *runtime.Unknown          `json:"requestObject,omitempty"`
+	RequestReceivedTimestamp metav1.MicroTime          `json:"requestReceivedTimestamp"`
+}
+
+// AuditObjectReference is the object of the request an AuditEvent records.
+type AuditObjectReference struct {
+	Resource    string `json:"resource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+	APIGroup    string `json:"apiGroup,omitempty"`
+	APIVersion  string `json:"apiVersion,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+// ReplayedRequest is a request from an audit log which Constraints deny or
+// warn about.
+type ReplayedRequest struct {
+	AuditID   string           `json:"auditID"`
+	Timestamp metav1.MicroTime `json:"timestamp"`
+	User      string           `json:"user"`
+	Operation string           `json:"operation"`
+	// Kind is the kind and group of the object, such as "Deployment.apps".
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// ResponseCode is the status code the API server returned for the request,
+	// if the audit log records it.
+	ResponseCode int32 `json:"responseCode,omitempty"`
+	// Denials are the messages of the Constraints which deny the request.
+	Denials []string `json:"denials,omitempty"`
+	// Warnings are the messages of the Constraints with the warn enforcement
+	// action which the request violates.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Denied returns whether the request would be denied.
+func (r *ReplayedRequest) Denied() bool {
+	return len(r.Denials) != 0
+}
+
+// ReplayResult is the outcome of replaying an audit log.
+type ReplayResult struct {
+	// Replayed is the number of requests reviewed.
+	Replayed int `json:"replayed"`
+	// Skipped is the number of creations, updates and patches which could not
+	// be reviewed, because their audit events do not hold the object of the
+	// request, they are patches, or they are requests for subresources.
+	Skipped int `json:"skipped"`
+	// Requests are the replayed requests which would be denied or warned
+	// about, in the order of the audit log.
+	Requests []ReplayedRequest `json:"requests,omitempty"`
+}
+
+// Replay reviews the requests recorded in the audit log read from log against
+// the ConstraintTemplates, Constraints and mutators selected by target, which
+// are found in the same way as ReadSuites finds Suites. Objects are mutated
+// before they are reviewed, as they are at admission.
+//
+// The audit log holds audit.k8s.io/v1 Events or EventLists, as written by the
+// log and webhook backends of the API server. Only creations and updates
+// logged at the Request level or above can be replayed. Objects in a namespace
+// are reviewed as if their Namespace had no labels, unless the Namespace was
+// created or updated earlier in the log.
+func Replay(ctx context.Context, f fs.FS, target string, recursive bool, log io.Reader) (*ReplayResult, error) {
+	client, system, err := ReadPolicies(ctx, f, target, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replayer{
+		client:     client,
+		system:     system,
+		result:     &ReplayResult{},
+		writes:     make(map[string]bool),
+		replayed:   make(map[string]int),
+		namespaces: make(map[string]*corev1.Namespace),
+	}
+
+	decoder := json.NewDecoder(log)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("%w: %v", ErrReadingAuditLog, err)
+		}
+		events, err := parseAuditEvents(raw)
+		if err != nil {
+			return nil, err
+		}
+		for i := range events {
+			if err := r.replay(ctx, &events[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	r.result.Skipped = len(r.writes) - r.result.Replayed
+	return r.result, nil
+}
+
+// parseAuditEvents returns the Event or the items of the EventList raw holds.
+func parseAuditEvents(raw json.RawMessage) ([]AuditEvent, error) {
+	var list struct {
+		Kind  string       `json:"kind"`
+		Items []AuditEvent `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadingAuditLog, err)
+	}
+	if list.Kind == "EventList" {
+		return list.Items, nil
+	}
+
+	event := AuditEvent{}
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadingAuditLog, err)
+	}
+	return []AuditEvent{event}, nil
+}
+
+type replayer struct {
+	client Client
+	system *mutation.System
+	result *ReplayResult
+	// writes are the audit IDs of the creations, updates and patches logged.
+	writes map[string]bool
+	// replayed are the audit IDs of the requests already replayed, and the
+	// index of their ReplayedRequest in result, or -1 if they have none.
+	replayed map[string]int
+	// namespaces are the Namespaces created or updated by replayed requests.
+	namespaces map[string]*corev1.Namespace
+}
+
+// replay reviews the request event records, unless an event of the same
+// request was already replayed.
+func (r *replayer) replay(ctx context.Context, event *AuditEvent) error {
+	switch event.Verb {
+	case "create", "update", "patch":
+		r.writes[event.AuditID] = true
+	default:
+		return nil
+	}
+	if i, ok := r.replayed[event.AuditID]; ok {
+		// The API server logs several stages of each request. Later stages
+		// record how the request was answered.
+		if i >= 0 && event.ResponseStatus != nil {
+			r.result.Requests[i].ResponseCode = event.ResponseStatus.Code
+		}
+		return nil
+	}
+
+	obj, operation, ok := replayable(event)
+	if !ok {
+		return nil
+	}
+	r.replayed[event.AuditID] = -1
+	r.result.Replayed++
+
+	ns := r.namespaceOf(obj)
+	if r.system != nil {
+		if _, err := r.system.Mutate(obj, ns); err != nil {
+			return fmt.Errorf("%w: request %q: %v", ErrExpanding, event.AuditID, err)
+		}
+	}
+	if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" {
+		r.namespaces[obj.GetName()] = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: obj.GetName(), Labels: obj.GetLabels()}}
+	}
+
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	gvk := obj.GroupVersionKind()
+	review := &target.AugmentedReview{
+		AdmissionRequest: &admissionv1.AdmissionRequest{
+			UID:       types.UID(event.AuditID),
+			Kind:      metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+			Resource:  metav1.GroupVersionResource{Group: event.ObjectRef.APIGroup, Version: event.ObjectRef.APIVersion, Resource: event.ObjectRef.Resource},
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Operation: operation,
+			UserInfo:  event.User,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+		Namespace: ns,
+	}
+	resp, err := r.client.Review(ctx, review)
+	if err != nil {
+		return fmt.Errorf("reviewing request %q: %w", event.AuditID, err)
+	}
+
+	request := ReplayedRequest{
+		AuditID:   event.AuditID,
+		Timestamp: event.RequestReceivedTimestamp,
+		User:      event.User.Username,
+		Operation: string(operation),
+		Kind:      gvk.GroupKind().String(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	if event.ResponseStatus != nil {
+		request.ResponseCode = event.ResponseStatus.Code
+	}
+	for _, result := range resp.Results() {
+		msg := fmt.Sprintf("[%s] %s", result.Constraint.GetName(), result.Msg)
+		switch result.EnforcementAction {
+		case string(util.Deny):
+			request.Denials = append(request.Denials, msg)
+		case string(util.Warn):
+			request.Warnings = append(request.Warnings, msg)
+		}
+	}
+	if len(request.Denials) != 0 || len(request.Warnings) != 0 {
+		r.replayed[event.AuditID] = len(r.result.Requests)
+		r.result.Requests = append(r.result.Requests, request)
+	}
+	return nil
+}
+
+// replayable returns the object and operation of the request event records,
+// and whether it can be replayed.
+func replayable(event *AuditEvent) (*unstructured.Unstructured, admissionv1.Operation, bool) {
+	var operation admissionv1.Operation
+	switch event.Verb {
+	case "create":
+		operation = admissionv1.Create
+	case "update":
+		operation = admissionv1.Update
+	default:
+		// The bodies of patches are not the objects they result in.
+		return nil, "", false
+	}
+	if event.RequestObject == nil || event.ObjectRef == nil || event.ObjectRef.Subresource != "" {
+		return nil, "", false
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(event.RequestObject.Raw); err != nil {
+		return nil, "", false
+	}
+	// The namespace and name of an object may be omitted from its body and
+	// taken from the path of the request.
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(event.ObjectRef.Namespace)
+	}
+	if obj.GetName() == "" {
+		obj.SetName(event.ObjectRef.Name)
+	}
+	return obj, operation, true
+}
+
+// namespaceOf returns the Namespace of obj: obj itself if it is a Namespace,
+// the Namespace last replayed if there is one, a Namespace without labels if
+// obj is namespaced, and otherwise nil.
+func (r *replayer) namespaceOf(obj *unstructured.Unstructured) *corev1.Namespace {
+	return namespaceOf(obj, r.namespaces[obj.GetNamespace()])
+}
+
+// String summarizes the result.
+func (r *ReplayResult) String() string {
+	denied, warned := 0, 0
+	for i := range r.Requests {
+		if r.Requests[i].Denied() {
+			denied++
+		} else {
+			warned++
+		}
+	}
+	return fmt.Sprintf("replayed %d requests, skipped %d: %d would be denied, %d warned", r.Replayed, r.Skipped, denied, warned)
+}
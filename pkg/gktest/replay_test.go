@@ -0,0 +1,95 @@
+package gktest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const replayLog = `
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"ns","stage":"RequestReceived","verb":"create","user":{"username":"admin"},"objectRef":{"resource":"namespaces","name":"prod","apiVersion":"v1"},"requestObject":{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"prod","labels":{"env":"prod"}}}}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"prod-pod","stage":"RequestReceived","requestReceivedTimestamp":"2021-09-01T10:00:00.000000Z","verb":"create","user":{"username":"alice"},"objectRef":{"resource":"pods","namespace":"prod","apiVersion":"v1"},"requestObject":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web"}}}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"prod-pod","stage":"ResponseComplete","verb":"create","user":{"username":"alice"},"objectRef":{"resource":"pods","namespace":"prod","name":"web","apiVersion":"v1"},"responseStatus":{"code":201},"requestObject":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web"}}}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"dev-pod","stage":"RequestReceived","verb":"create","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"dev","apiVersion":"v1"},"requestObject":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web","namespace":"dev"}}}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Request","auditID":"patch","stage":"RequestReceived","verb":"patch","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"prod","name":"web","apiVersion":"v1"},"requestObject":{"metadata":{"labels":{"a":"b"}}}}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"get","stage":"ResponseComplete","verb":"get","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"prod","name":"web","apiVersion":"v1"}}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"metadata","stage":"ResponseComplete","verb":"create","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"prod","apiVersion":"v1"}}
+{"kind":"EventList","apiVersion":"audit.k8s.io/v1","items":[
+  {"auditID":"update","stage":"RequestReceived","verb":"update","user":{"username":"carol"},"objectRef":{"resource":"pods","namespace":"prod","name":"web","apiVersion":"v1"},"requestObject":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web","namespace":"prod"}}}
+]}
+`
+
+func TestReplay(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"policies/template.yaml": &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"policies/constraint.yaml": &fstest.MapFile{Data: []byte(`
+kind: NeverValidate
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: prod-pods
+spec:
+  match:
+    kinds:
+    - apiGroups: [""]
+      kinds: ["Pod"]
+    namespaceSelector:
+      matchLabels:
+        env: prod
+`)},
+	}
+
+	got, err := Replay(context.Background(), fileSystem, "policies", false, strings.NewReader(replayLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ReplayResult{
+		Replayed: 4,
+		Skipped:  2,
+		Requests: []ReplayedRequest{
+			{
+				AuditID:      "prod-pod",
+				User:         "alice",
+				Operation:    "CREATE",
+				Kind:         "Pod",
+				Namespace:    "prod",
+				Name:         "web",
+				ResponseCode: 201,
+				Denials:      []string{"[prod-pods] never validate"},
+			},
+			{
+				AuditID:   "update",
+				User:      "carol",
+				Operation: "UPDATE",
+				Kind:      "Pod",
+				Namespace: "prod",
+				Name:      "web",
+				Denials:   []string{"[prod-pods] never validate"},
+			},
+		},
+	}
+	want.Requests[0].Timestamp = metav1.NewMicroTime(time.Date(2021, 9, 1, 10, 0, 0, 0, time.UTC))
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b metav1.MicroTime) bool { return a.Equal(&b) })); diff != "" {
+		t.Error(diff)
+	}
+	if s := got.String(); s != "replayed 4 requests, skipped 2: 2 would be denied, 0 warned" {
+		t.Errorf("got summary %q", s)
+	}
+}
+
+func TestReplay_InvalidLog(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"policies/template.yaml": &fstest.MapFile{Data: []byte(templateNeverValidate)},
+	}
+
+	_, err := Replay(context.Background(), fileSystem, "policies", false, strings.NewReader(`{"auditID": "a"} not json`))
+	if !errors.Is(err, ErrReadingAuditLog) {
+		t.Errorf("got error %v, want %v", err, ErrReadingAuditLog)
+	}
+}
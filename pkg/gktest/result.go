@@ -3,6 +3,8 @@ package gktest
 import (
 	"fmt"
 	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
 )
 
 // Duration is an alias of time.Duration to allow for custom formatting.
@@ -60,6 +62,10 @@ type TestResult struct {
 	// the test Cases to run.
 	Runtime Duration
 
+	// Skipped is whether the Test, or its Suite, was skipped. If so, it was
+	// not run and every one of its CaseResults is skipped.
+	Skipped bool
+
 	// CaseResults are individual results for all tests defined for this Constraint.
 	CaseResults []CaseResult
 }
@@ -93,6 +99,34 @@ type CaseResult struct {
 
 	// Runtime is the time it took for this Case to run.
 	Runtime Duration
+
+	// Skipped is whether the Case, or its Test or Suite, was skipped. Skipped
+	// Cases are not run, so they neither pass nor fail.
+	Skipped bool
+
+	// Rendered is the object under test after rendering it with the Suite's
+	// values, if it was rendered.
+	Rendered string
+	// Stats are the statistics of the queries reviewing the object under
+	// test, if the Runner collected them.
+	Stats *querystats.Stats
+	// Trace is the trace of the queries reviewing the object under test, if
+	// the Runner traced them and the Case failed.
+	Trace string
+
+	// The following are only recorded if the Runner records Details.
+
+	// Reviewed is whether the object under test was reviewed, rather than the
+	// Case failing before it was.
+	Reviewed bool
+	// Violations are the messages of the violations of the object under test.
+	Violations []string
+	// Assertions describe what the Case expected of the object under test.
+	Assertions []string
+	// Object is the path to the file containing the object under test, and
+	// ObjectLine the line of the file the object starts on, or 0 if unknown.
+	Object     string
+	ObjectLine int
 }
 
 // IsFailure returns true if the test failed to execute or produced an
@@ -0,0 +1,210 @@
+package gktest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"sync"
+
+	templatesv1 "github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ReadPolicies returns a Client with the ConstraintTemplates and Constraints
+// selected by target, which are found in the same way as ReadSuites finds
+// Suites, and a mutation System with its mutators, or nil if there are none.
+// Other files are skipped.
+func ReadPolicies(ctx context.Context, f fs.FS, target string, recursive bool) (Client, *mutation.System, error) {
+	if f == nil {
+		return nil, nil, ErrNoFileSystem
+	}
+	if target == "" {
+		return nil, nil, ErrNoTarget
+	}
+
+	files, err := listFiles(f, target, recursive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := NewOPAClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCreatingClient, err)
+	}
+	var system *mutation.System
+	var constraints []string
+	for _, file := range files {
+		bytes, err := fs.ReadFile(f, file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %q: %w", file, err)
+		}
+		u, err := readUnstructured(bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing %q: %w", file, err)
+		}
+
+		switch gvk := u.GroupVersionKind(); {
+		case gvk.Group == templatesv1.SchemeGroupVersion.Group && gvk.Kind == "ConstraintTemplate":
+			template, err := readTemplate(f, file)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := client.AddTemplate(ctx, template); err != nil {
+				return nil, nil, fmt.Errorf("%w: %q: %v", ErrAddingTemplate, file, err)
+			}
+		case gvk.Group == "constraints.gatekeeper.sh":
+			// Constraints are added once every template is.
+			constraints = append(constraints, file)
+		case gvk.Group == mutationsv1alpha1.GroupVersion.Group:
+			m, err := readMutator(f, file)
+			if err != nil {
+				return nil, nil, err
+			}
+			if system == nil {
+				system = mutation.NewSystem(mutation.SystemOpts{})
+			}
+			if err := system.Upsert(m); err != nil {
+				return nil, nil, fmt.Errorf("%w %v: %v", ErrAddingMutator, m.ID(), err)
+			}
+		}
+	}
+
+	for _, file := range constraints {
+		constraint, err := readConstraint(f, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := client.AddConstraint(ctx, constraint); err != nil {
+			return nil, nil, fmt.Errorf("%w: %q: %v", ErrAddingConstraint, file, err)
+		}
+	}
+	return client, system, nil
+}
+
+// ReviewOpts configures ReviewAll.
+type ReviewOpts struct {
+	// Workers is the number of objects reviewed in parallel. Defaults to
+	// GOMAXPROCS.
+	Workers int
+	// System mutates each object before it is reviewed, as at admission, if
+	// set.
+	System *mutation.System
+	// Namespaces are the Namespaces objects are matched against, keyed by
+	// name. Objects in other namespaces are reviewed as if their Namespace had
+	// no labels.
+	Namespaces map[string]*corev1.Namespace
+	// Stats is whether to collect the statistics of the queries reviewing each
+	// object. The driver of the Client must record them, as that of
+	// NewOPAClient does.
+	Stats bool
+}
+
+// ObjectReview is the outcome of reviewing an object.
+type ObjectReview struct {
+	// Object is the reviewed object, after it was mutated.
+	Object *unstructured.Unstructured
+	// Results are the violations of the Constraints the object violates, of
+	// every enforcement action.
+	Results []*types.Result
+	// Stats are the statistics of the queries reviewing the object, if
+	// ReviewOpts.Stats is set.
+	Stats *querystats.Stats
+}
+
+// ReviewAll reviews objects against the Constraints of client, returning the
+// violations of each object in the order of objects. Objects are reviewed by
+// opts.Workers parallel workers sharing client, so policy is compiled once
+// however many objects are reviewed. objects are not modified.
+//
+// The first error reviewing an object stops the review of the others and is
+// returned.
+func ReviewAll(ctx context.Context, client Client, objects []*unstructured.Unstructured, opts ReviewOpts) ([]ObjectReview, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(objects) {
+		workers = len(objects)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reviews := make([]ObjectReview, len(objects))
+	indices := make(chan int)
+	var errOnce sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if ctx.Err() != nil {
+					// Drain the objects fed before the review was stopped.
+					continue
+				}
+				review, err := reviewObject(ctx, client, objects[i], opts)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("reviewing object %d %s %q: %w", i, objects[i].GroupVersionKind(), objects[i].GetName(), err)
+						cancel()
+					})
+					continue
+				}
+				reviews[i] = review
+			}
+		}()
+	}
+
+feed:
+	for i := range objects {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// reviewObject mutates a copy of obj with opts.System, if set, and reviews it.
+func reviewObject(ctx context.Context, client Client, obj *unstructured.Unstructured, opts ReviewOpts) (ObjectReview, error) {
+	u := obj.DeepCopy()
+	ns := namespaceOf(u, opts.Namespaces[u.GetNamespace()])
+	if opts.System != nil {
+		if _, err := opts.System.Mutate(u, ns); err != nil {
+			return ObjectReview{}, fmt.Errorf("%w: %v", ErrExpanding, err)
+		}
+	}
+	var recorder *querystats.Recorder
+	if opts.Stats {
+		ctx, recorder = querystats.NewContext(ctx)
+	}
+	resp, err := client.Review(ctx, &target.AugmentedUnstructured{Object: *u, Namespace: ns})
+	if err != nil {
+		return ObjectReview{}, err
+	}
+	review := ObjectReview{Object: u, Results: resp.Results()}
+	if recorder != nil {
+		stats := recorder.Stats()
+		review.Stats = &stats
+	}
+	return review, nil
+}
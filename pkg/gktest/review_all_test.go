@@ -0,0 +1,120 @@
+package gktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newReviewPod(namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind("Pod")
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestReviewAll(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"policies/template.yaml": &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"policies/constraint.yaml": &fstest.MapFile{Data: []byte(`
+kind: NeverValidate
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: prod-pods
+spec:
+  match:
+    kinds:
+    - apiGroups: [""]
+      kinds: ["Pod"]
+    namespaceSelector:
+      matchLabels:
+        env: prod
+`)},
+		"policies/mutator.yaml": &fstest.MapFile{Data: []byte(mutatorOwnerLabel)},
+	}
+
+	ctx := context.Background()
+	client, system, err := ReadPolicies(ctx, fileSystem, "policies", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []*unstructured.Unstructured
+	for i := 0; i < 50; i++ {
+		namespace := "dev"
+		if i%2 == 0 {
+			namespace = "prod"
+		}
+		objects = append(objects, newReviewPod(namespace, fmt.Sprintf("pod-%d", i)))
+	}
+	opts := ReviewOpts{
+		Workers: 4,
+		System:  system,
+		Namespaces: map[string]*corev1.Namespace{
+			"prod": {ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"env": "prod"}}},
+		},
+	}
+	got, err := ReviewAll(ctx, client, objects, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(objects) {
+		t.Fatalf("got %d reviews, want %d", len(got), len(objects))
+	}
+	for i, review := range got {
+		if review.Object.GetName() != objects[i].GetName() {
+			t.Errorf("got review of %q at %d, want %q", review.Object.GetName(), i, objects[i].GetName())
+		}
+		if diff := cmp.Diff(map[string]string{"owner": "admin"}, review.Object.GetLabels()); diff != "" {
+			t.Errorf("object %d not mutated: %s", i, diff)
+		}
+		if objects[i].GetLabels() != nil {
+			t.Errorf("object %d was modified", i)
+		}
+
+		want := 0
+		if objects[i].GetNamespace() == "prod" {
+			want = 1
+		}
+		if len(review.Results) != want {
+			t.Errorf("got %d results for %s/%s, want %d", len(review.Results), objects[i].GetNamespace(), objects[i].GetName(), want)
+		}
+	}
+}
+
+func TestReviewAll_Empty(t *testing.T) {
+	client, err := NewOPAClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReviewAll(context.Background(), client, nil, ReviewOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d reviews, want none", len(got))
+	}
+}
+
+func TestReviewAll_Canceled(t *testing.T) {
+	client, err := NewOPAClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ReviewAll(ctx, client, []*unstructured.Unstructured{newReviewPod("dev", "pod")}, ReviewOpts{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
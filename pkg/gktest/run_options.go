@@ -0,0 +1,243 @@
+package gktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/engine"
+)
+
+// ErrInvalidRunOptions means a RunOption was given an invalid value.
+var ErrInvalidRunOptions = errors.New("invalid run options")
+
+// Outputs are the formats results can be printed in, by the name RunOptions
+// and gator know them by. The empty format is that of go test.
+var Outputs = []string{"table", "wide", "html", "junit", "json", "sarif", "tap"}
+
+// RunOptions configure how RunSuites runs Suites and how their results are
+// printed. They are created with NewRunOptions, so that tools embedding gktest
+// keep building as options are added.
+type RunOptions struct {
+	filter          Filter
+	parallelism     int
+	testParallelism int
+	trace           bool
+	stats           bool
+	engines         []string
+	output          string
+	sourceRoot      string
+}
+
+// RunOption sets an option of RunOptions, returning an error if it is given an
+// invalid value.
+type RunOption func(*RunOptions) error
+
+// NewRunOptions returns the RunOptions set by opts. By default, every Test and
+// Case is run, one Suite and Test at a time, with the built-in Rego engine and
+// without tracing or statistics, and results are printed in the format of go
+// test.
+func NewRunOptions(opts ...RunOption) (*RunOptions, error) {
+	o := &RunOptions{parallelism: 1, testParallelism: 1}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// WithFilter runs only the Tests and Cases matching run, as parsed by
+// NewFilter.
+func WithFilter(run string) RunOption {
+	return func(o *RunOptions) error {
+		filter, err := NewFilter(run)
+		if err != nil {
+			return fmt.Errorf("%w: compiling filter: %v", ErrInvalidRunOptions, err)
+		}
+		o.filter = filter
+		return nil
+	}
+}
+
+// WithParallelism runs up to n Suites at once.
+func WithParallelism(n int) RunOption {
+	return func(o *RunOptions) error {
+		if n < 1 {
+			return fmt.Errorf("%w: parallelism must be at least 1, got %d", ErrInvalidRunOptions, n)
+		}
+		o.parallelism = n
+		return nil
+	}
+}
+
+// WithTestParallelism runs up to n Tests of each Suite at once, each with its
+// own Client.
+func WithTestParallelism(n int) RunOption {
+	return func(o *RunOptions) error {
+		if n < 1 {
+			return fmt.Errorf("%w: test parallelism must be at least 1, got %d", ErrInvalidRunOptions, n)
+		}
+		o.testParallelism = n
+		return nil
+	}
+}
+
+// WithTrace sets whether to trace the queries reviewing the object of each
+// Case, recording the trace of those which fail.
+func WithTrace(trace bool) RunOption {
+	return func(o *RunOptions) error {
+		o.trace = trace
+		return nil
+	}
+}
+
+// WithStats sets whether to collect the statistics of the queries reviewing
+// the object of each Case.
+func WithStats(stats bool) RunOption {
+	return func(o *RunOptions) error {
+		o.stats = stats
+		return nil
+	}
+}
+
+// WithEngines compiles templates with the named registered evaluation
+// engines, in order of preference, before falling back to Rego.
+func WithEngines(names ...string) RunOption {
+	return func(o *RunOptions) error {
+		registered := make(map[string]bool)
+		for _, name := range engine.Registered() {
+			registered[name] = true
+		}
+		seen := make(map[string]bool)
+		for _, name := range names {
+			switch {
+			case name == engine.Rego:
+				return fmt.Errorf("%w: the %q engine is always enabled", ErrInvalidRunOptions, engine.Rego)
+			case !registered[name]:
+				return fmt.Errorf("%w: unknown evaluation engine %q, must be one of: %v", ErrInvalidRunOptions, name, engine.Registered())
+			case seen[name]:
+				return fmt.Errorf("%w: evaluation engine %q given twice", ErrInvalidRunOptions, name)
+			}
+			seen[name] = true
+		}
+		o.engines = names
+		return nil
+	}
+}
+
+// WithOutput prints results in format, one of Outputs, or in the format of go
+// test if empty.
+func WithOutput(format string) RunOption {
+	return func(o *RunOptions) error {
+		if format != "" && !containsString(Outputs, format) {
+			return fmt.Errorf("%w: unknown output format %q, must be one of: %v", ErrInvalidRunOptions, format, Outputs)
+		}
+		o.output = format
+		return nil
+	}
+}
+
+// WithSourceRoot prints the paths of files within root relative to it, in
+// formats such as SARIF which locate results in a repository.
+func WithSourceRoot(root string) RunOption {
+	return func(o *RunOptions) error {
+		o.sourceRoot = root
+		return nil
+	}
+}
+
+// Filter returns the Filter selecting the Tests and Cases to run.
+func (o *RunOptions) Filter() Filter {
+	return o.filter
+}
+
+// Printer returns the Printer of the output format.
+func (o *RunOptions) Printer() Printer {
+	switch o.output {
+	case "table":
+		return PrinterTable{}
+	case "wide":
+		return PrinterTable{Wide: true}
+	case "html":
+		return PrinterHTML{}
+	case "junit":
+		return PrinterJUnit{}
+	case "json":
+		return PrinterJSON{}
+	case "sarif":
+		return PrinterSARIF{Root: o.sourceRoot}
+	case "tap":
+		return PrinterTAP{}
+	default:
+		return PrinterGo{}
+	}
+}
+
+// Verbose returns whether results must be printed verbosely for what the
+// options record to be printed.
+func (o *RunOptions) Verbose() bool {
+	return o.stats || o.trace
+}
+
+// Runner returns a Runner reading Suites and objects from fsys as set by the
+// options.
+func (o *RunOptions) Runner(fsys fs.FS) *Runner {
+	engines := o.engines
+	return &Runner{
+		FS: fsys,
+		NewClient: func() (Client, error) {
+			return newOPAClient(engines)
+		},
+		Stats: o.stats,
+		// Reports other than those of go test and tables list what each case
+		// expected next to the violations of its object.
+		Details:     o.output != "" && o.output != "table" && o.output != "wide",
+		Trace:       o.trace,
+		Parallelism: o.testParallelism,
+	}
+}
+
+// RunSuites runs suites, keyed by their path in fsys, as set by opts. Returns
+// the results of the Suites sorted by path.
+func RunSuites(ctx context.Context, fsys fs.FS, suites map[string]*Suite, opts *RunOptions) []SuiteResult {
+	runner := opts.Runner(fsys)
+
+	paths := make([]string, 0, len(suites))
+	for path := range suites {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	results := make([]SuiteResult, len(paths))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.parallelism && w < len(paths); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = runner.Run(ctx, opts.filter, paths[i], suites[paths[i]])
+			}
+		}()
+	}
+	for i := range paths {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return results
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
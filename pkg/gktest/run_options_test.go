@@ -0,0 +1,139 @@
+package gktest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewRunOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        []RunOption
+		wantErr     error
+		wantPrinter Printer
+	}{
+		{
+			name:        "defaults",
+			wantPrinter: PrinterGo{},
+		},
+		{
+			name:        "junit",
+			opts:        []RunOption{WithOutput("junit"), WithParallelism(4), WithTrace(true), WithStats(true)},
+			wantPrinter: PrinterJUnit{},
+		},
+		{
+			name:        "json",
+			opts:        []RunOption{WithOutput("json")},
+			wantPrinter: PrinterJSON{},
+		},
+		{
+			name:        "sarif",
+			opts:        []RunOption{WithOutput("sarif"), WithSourceRoot("repo")},
+			wantPrinter: PrinterSARIF{Root: "repo"},
+		},
+		{
+			name:        "tap",
+			opts:        []RunOption{WithOutput("tap")},
+			wantPrinter: PrinterTAP{},
+		},
+		{
+			name:        "wide",
+			opts:        []RunOption{WithOutput("wide")},
+			wantPrinter: PrinterTable{Wide: true},
+		},
+		{
+			name:    "unknown output",
+			opts:    []RunOption{WithOutput("xml")},
+			wantErr: ErrInvalidRunOptions,
+		},
+		{
+			name:    "no parallelism",
+			opts:    []RunOption{WithParallelism(0)},
+			wantErr: ErrInvalidRunOptions,
+		},
+		{
+			name:    "no test parallelism",
+			opts:    []RunOption{WithTestParallelism(0)},
+			wantErr: ErrInvalidRunOptions,
+		},
+		{
+			name:    "unknown engine",
+			opts:    []RunOption{WithEngines("unregistered")},
+			wantErr: ErrInvalidRunOptions,
+		},
+		{
+			name:    "rego engine",
+			opts:    []RunOption{WithEngines("rego")},
+			wantErr: ErrInvalidRunOptions,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewRunOptions(tc.opts...)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantPrinter, got.Printer()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRunSuites(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml":   &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"constraint.yaml": &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+		"object.yaml":     &fstest.MapFile{Data: []byte(object)},
+	}
+	suite := func(violations string) *Suite {
+		return &Suite{Tests: []Test{{
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Cases: []Case{{
+				Name:       "object",
+				Object:     "object.yaml",
+				Assertions: []Assertion{{Violations: intStrFromStr(violations)}},
+			}},
+		}}}
+	}
+	suites := map[string]*Suite{
+		"c.yaml": suite("yes"),
+		"a.yaml": suite("no"),
+		"b.yaml": suite("yes"),
+	}
+
+	opts, err := NewRunOptions(WithParallelism(2), WithTrace(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := RunSuites(context.Background(), fsys, suites, opts)
+
+	var paths []string
+	for _, r := range results {
+		paths = append(paths, r.Path)
+	}
+	if diff := cmp.Diff([]string{"a.yaml", "b.yaml", "c.yaml"}, paths); diff != "" {
+		t.Error(diff)
+	}
+
+	failed := results[0].TestResults[0].CaseResults[0]
+	if !failed.IsFailure() {
+		t.Fatal("got a.yaml passing, want it to fail")
+	}
+	if !strings.Contains(failed.Trace, "Trace:") || strings.Contains(failed.Trace, "TRACING DISABLED") {
+		t.Errorf("got trace %q of the failed case, want the trace of its review", failed.Trace)
+	}
+	if passed := results[1].TestResults[0].CaseResults[0]; passed.IsFailure() || passed.Trace != "" {
+		t.Errorf("got error %v and trace %q of b.yaml, want it to pass without a trace", passed.Error, passed.Trace)
+	}
+}
@@ -5,12 +5,30 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	opaclient "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	admissionv1 "k8s.io/api/admission/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// ingestMux serializes creating Clients and adding Templates and Constraints
+// to them, which is not safe to do concurrently even with separate Clients:
+// compiling Rego sorts the types of builtins OPA shares between compilers, and
+// converting Templates registers their types with a shared scheme. Objects are
+// still reviewed in parallel.
+var ingestMux sync.Mutex
+
 // Runner defines logic independent of how tests are run and the results are
 // printed.
 type Runner struct {
@@ -20,13 +38,48 @@ type Runner struct {
 	// NewClient instantiates a Client for compiling Templates/Constraints, and
 	// validating objects against them.
 	NewClient func() (Client, error)
+
+	// Stats is whether to collect the statistics of the queries reviewing the
+	// object of each Case. The driver of the Client must record them, as that
+	// of NewOPAClient does.
+	Stats bool
+
+	// Details is whether to record the violations of the object of each Case
+	// and the Assertions it was checked against, for reports such as those of
+	// PrinterHTML.
+	Details bool
+
+	// Trace is whether to trace the queries reviewing the object of each Case,
+	// recording the trace of those which fail.
+	Trace bool
+
+	// Parallelism is the number of Tests of a Suite run at once, each with its
+	// own Client. Tests are run one at a time if it is less than 2. Results
+	// are in the order of the Tests regardless. Clients are created and their
+	// Templates and Constraints added one at a time, across every Runner.
+	Parallelism int
+
+	// mux guards rendered.
+	mux sync.Mutex
+	// rendered caches objects rendered with values, keyed by path and values.
+	rendered map[string][]byte
 }
 
 // Run executes all Tests in the Suite and returns the results.
 func (r *Runner) Run(ctx context.Context, filter Filter, suitePath string, s *Suite) SuiteResult {
 	start := time.Now()
 
-	results, err := r.runTests(ctx, filter, suitePath, s.Tests)
+	var results []TestResult
+	var err error
+	if s.Skip {
+		results = skipTests(filter, s.Tests)
+	} else {
+		var values map[string]interface{}
+		values, err = r.suiteValues(suitePath, s)
+		if err == nil {
+			results, err = r.runTests(ctx, filter, suitePath, values, s.RegoTests, s.Tests)
+		}
+	}
 
 	return SuiteResult{
 		Path:        suitePath,
@@ -36,25 +89,86 @@ func (r *Runner) Run(ctx context.Context, filter Filter, suitePath string, s *Su
 	}
 }
 
+// suiteValues returns the values the Suite's Case objects are rendered with,
+// or nil if the Suite has none.
+func (r *Runner) suiteValues(suitePath string, s *Suite) (map[string]interface{}, error) {
+	if s.Values == "" {
+		return nil, nil
+	}
+	return readValues(r.FS, filepath.Join(filepath.Dir(suitePath), s.Values))
+}
+
 // runTests runs every Test in Suite.
-func (r *Runner) runTests(ctx context.Context, filter Filter, suitePath string, tests []Test) ([]TestResult, error) {
+func (r *Runner) runTests(ctx context.Context, filter Filter, suitePath string, values map[string]interface{}, regoTests bool, tests []Test) ([]TestResult, error) {
 	suiteDir := filepath.Dir(suitePath)
 
 	results := make([]TestResult, len(tests))
+	indices := make(chan int)
+	workers := r.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(tests); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = r.runTest(ctx, suiteDir, filter, values, regoTests, tests[i])
+			}
+		}()
+	}
 	for i, t := range tests {
-		if filter.MatchesTest(t) {
-			results[i] = r.runTest(ctx, suiteDir, filter, t)
+		switch {
+		case !filter.MatchesTest(t):
+		case t.Skip:
+			results[i] = skipTest(filter, t)
+		default:
+			indices <- i
 		}
 	}
+	close(indices)
+	wg.Wait()
 
 	return results, nil
 }
 
-// runTest runs an individual Test.
-func (r *Runner) runTest(ctx context.Context, suiteDir string, filter Filter, t Test) TestResult {
+// skipTests returns the results of tests when their Suite is skipped.
+func skipTests(filter Filter, tests []Test) []TestResult {
+	results := make([]TestResult, len(tests))
+	for i, t := range tests {
+		if filter.MatchesTest(t) {
+			results[i] = skipTest(filter, t)
+		}
+	}
+	return results
+}
+
+// skipTest returns the result of t when it is skipped, with every Case it
+// would have run skipped.
+func skipTest(filter Filter, t Test) TestResult {
+	result := TestResult{Name: t.Name, Skipped: true}
+	for _, c := range t.Cases {
+		for _, expanded := range expandCase(c) {
+			if filter.MatchesCase(expanded.Case) {
+				result.CaseResults = append(result.CaseResults, CaseResult{Name: expanded.Name, Skipped: true})
+			}
+		}
+	}
+	return result
+}
+
+// runTest runs an individual Test, followed by the Rego unit tests of its
+// Template if regoTests is set.
+func (r *Runner) runTest(ctx context.Context, suiteDir string, filter Filter, values map[string]interface{}, regoTests bool, t Test) TestResult {
 	start := time.Now()
 
-	results, err := r.runCases(ctx, suiteDir, filter, t)
+	results, err := r.runCases(ctx, suiteDir, filter, values, t)
+	if err == nil && regoTests {
+		var regoResults []CaseResult
+		regoResults, err = r.runRegoTests(ctx, suiteDir, filter, t)
+		results = append(results, regoResults...)
+	}
 
 	return TestResult{
 		Name:        t.Name,
@@ -66,60 +180,252 @@ func (r *Runner) runTest(ctx context.Context, suiteDir string, filter Filter, t
 
 // runCases executes every Case in the Test. Returns the results for every Case,
 // or an error if there was a problem executing the Test.
-func (r *Runner) runCases(ctx context.Context, suiteDir string, filter Filter, t Test) ([]CaseResult, error) {
-	client, err := r.makeTestClient(ctx, suiteDir, t)
+func (r *Runner) runCases(ctx context.Context, suiteDir string, filter Filter, values map[string]interface{}, t Test) ([]CaseResult, error) {
+	client, constraint, err := r.makeTestClient(ctx, suiteDir, t)
+	if err != nil {
+		return nil, err
+	}
+	mutationSystem, err := r.makeMutationSystem(suiteDir, t)
 	if err != nil {
 		return nil, err
 	}
+	exceptionSystem, err := r.makeExceptionSystem(suiteDir, t)
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := r.readInventory(suiteDir, t.Inventory)
+	if err != nil {
+		return nil, err
+	}
+	err = r.setInventory(ctx, client, inventory)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []expandedCase
+	for _, c := range t.Cases {
+		for _, expanded := range expandCase(c) {
+			cases = append(cases, r.expandDocuments(suiteDir, values, expanded)...)
+		}
+	}
 
-	results := make([]CaseResult, len(t.Cases))
-	for i, c := range t.Cases {
-		if !filter.MatchesCase(c) {
+	// replaced is whether the Constraint in client has had its parameters
+	// replaced by those of a Case.
+	replaced := false
+	// extended is whether the Test's inventory in client has been extended by
+	// that of a Case.
+	extended := false
+	results := make([]CaseResult, len(cases))
+	for i, c := range cases {
+		if !filter.MatchesCase(c.Case) {
+			continue
+		}
+		if c.Skip {
+			results[i] = CaseResult{Name: c.Name, Skipped: true}
+			continue
+		}
+		if c.err != nil {
+			results[i] = CaseResult{Name: c.Name, Error: c.err}
 			continue
 		}
 
-		results[i] = r.runCase(ctx, client, suiteDir, c)
+		switch {
+		case c.parameters != nil && constraint == nil:
+			err = fmt.Errorf("%w: matrix parameters require a constraint", ErrInvalidCase)
+		case c.parameters != nil:
+			err = r.setConstraint(ctx, client, withParameters(constraint, c.parameters))
+			replaced = true
+		case replaced:
+			err = r.setConstraint(ctx, client, constraint)
+			replaced = false
+		}
+		if err != nil {
+			results[i] = CaseResult{Name: c.Name, Error: err}
+			continue
+		}
+
+		var caseInventory []*unstructured.Unstructured
+		caseInventory, err = r.readCaseInventory(suiteDir, values, c)
+		switch {
+		case err == nil && len(caseInventory) != 0:
+			// The Case's objects are added last to replace those of the Test
+			// with the same name.
+			err = r.setInventory(ctx, client, append(append([]*unstructured.Unstructured{}, inventory...), caseInventory...))
+			extended = true
+		case err == nil && extended:
+			err = r.setInventory(ctx, client, inventory)
+			extended = false
+		}
+		if err != nil {
+			results[i] = CaseResult{Name: c.Name, Error: err}
+			continue
+		}
+
+		results[i] = r.runCase(ctx, client, mutationSystem, exceptionSystem, suiteDir, values, c)
 	}
 
 	return results, nil
 }
 
-func (r *Runner) makeTestClient(ctx context.Context, suiteDir string, t Test) (Client, error) {
-	client, err := r.NewClient()
+// expandDocuments returns the Cases to run for c. If c reviews each document
+// of its object, it is expanded into a Case for each of them, named after c
+// and the index of the document, for example "deployments/documents[1]".
+func (r *Runner) expandDocuments(suiteDir string, values map[string]interface{}, c expandedCase) []expandedCase {
+	if !c.EachDocument || c.Skip || c.err != nil {
+		return []expandedCase{c}
+	}
+	if c.Object == "" {
+		c.err = fmt.Errorf("%w: eachDocument requires an object", ErrInvalidCase)
+		return []expandedCase{c}
+	}
+
+	objs, _, err := r.readObject(filepath.Join(suiteDir, c.Object), values, c.Values)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCreatingClient, err)
+		c.err = err
+		return []expandedCase{c}
+	}
+	cases := make([]expandedCase, len(objs))
+	for i := range objs {
+		cases[i] = c
+		cases[i].Name = fmt.Sprintf("%s/documents[%d]", c.Name, i)
+		cases[i].document = i
 	}
+	return cases
+}
 
-	err = r.addTemplate(ctx, suiteDir, t.Template, client)
+// readCaseInventory returns the objects added to data.inventory while c is
+// run: those of its Inventory, followed by the documents of its object after
+// the first, unless each of them is reviewed as a Case of its own.
+func (r *Runner) readCaseInventory(suiteDir string, values map[string]interface{}, c expandedCase) ([]*unstructured.Unstructured, error) {
+	objs, err := r.readInventory(suiteDir, c.Inventory)
 	if err != nil {
 		return nil, err
 	}
+	if c.EachDocument || c.Object == "" {
+		return objs, nil
+	}
+
+	documents, _, err := r.readObject(filepath.Join(suiteDir, c.Object), values, c.Values)
+	if err != nil || len(documents) < 2 {
+		// Errors reading the object are reported when the Case is run.
+		return objs, nil
+	}
+	return append(objs, documents[1:]...), nil
+}
 
-	err = r.addConstraint(ctx, suiteDir, t.Constraint, client)
+func (r *Runner) makeTestClient(ctx context.Context, suiteDir string, t Test) (Client, *unstructured.Unstructured, error) {
+	mutatorsOnly := t.Template == "" && t.Constraint == "" && len(t.Mutators) != 0
+	client, err := r.newTemplateClient(ctx, suiteDir, t.Template, !mutatorsOnly)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if mutatorsOnly {
+		// The Test only checks how its mutators change objects.
+		return client, nil, nil
+	}
+
+	constraint, err := r.addConstraint(ctx, suiteDir, t.Constraint, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, constraint, nil
+}
+
+// newTemplateClient returns a new Client, with the Template at templatePath
+// added if addTemplate is set.
+func (r *Runner) newTemplateClient(ctx context.Context, suiteDir, templatePath string, addTemplate bool) (Client, error) {
+	ingestMux.Lock()
+	defer ingestMux.Unlock()
+
+	client, err := r.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCreatingClient, err)
+	}
+	if !addTemplate {
+		return client, nil
 	}
 
+	err = r.addTemplate(ctx, suiteDir, templatePath, client)
+	if err != nil {
+		return nil, err
+	}
 	return client, nil
 }
 
-func (r *Runner) addConstraint(ctx context.Context, suiteDir, constraintPath string, client Client) error {
+func (r *Runner) addConstraint(ctx context.Context, suiteDir, constraintPath string, client Client) (*unstructured.Unstructured, error) {
 	if constraintPath == "" {
-		return fmt.Errorf("%w: missing constraint", ErrInvalidSuite)
+		return nil, fmt.Errorf("%w: missing constraint", ErrInvalidSuite)
 	}
 
 	cObj, err := readConstraint(r.FS, filepath.Join(suiteDir, constraintPath))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = client.AddConstraint(ctx, cObj)
+	err = r.setConstraint(ctx, client, cObj)
+	if err != nil {
+		return nil, err
+	}
+	return cObj, nil
+}
+
+// setConstraint adds constraint to client, replacing any Constraint with the
+// same kind and name.
+func (r *Runner) setConstraint(ctx context.Context, client Client, constraint *unstructured.Unstructured) error {
+	ingestMux.Lock()
+	defer ingestMux.Unlock()
+
+	_, err := client.AddConstraint(ctx, constraint)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrAddingConstraint, err)
 	}
 	return nil
 }
 
+// readInventory reads the objects in every document of the files at paths,
+// relative to suiteDir, expanding Lists into their items.
+func (r *Runner) readInventory(suiteDir string, paths []string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, path := range paths {
+		documents, err := readObjects(r.FS, filepath.Join(suiteDir, path))
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range documents {
+			if !u.IsList() {
+				objs = append(objs, u)
+				continue
+			}
+			list, err := u.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrAddingInventory, path, err)
+			}
+			for i := range list.Items {
+				objs = append(objs, &list.Items[i])
+			}
+		}
+	}
+	return objs, nil
+}
+
+// setInventory replaces data.inventory in client with objs. Objects are added
+// in order, so an object replaces those before it of the same kind, namespace
+// and name.
+func (r *Runner) setInventory(ctx context.Context, client Client, objs []*unstructured.Unstructured) error {
+	_, err := client.RemoveData(ctx, target.WipeData{})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAddingInventory, err)
+	}
+	for _, obj := range objs {
+		_, err = client.AddData(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("%w: %s %s: %v", ErrAddingInventory, obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
 func (r *Runner) addTemplate(ctx context.Context, suiteDir, templatePath string, client Client) error {
 	if templatePath == "" {
 		return fmt.Errorf("%w: missing template", ErrInvalidSuite)
@@ -139,30 +445,93 @@ func (r *Runner) addTemplate(ctx context.Context, suiteDir, templatePath string,
 }
 
 // RunCase executes a Case and returns the result of the run.
-func (r *Runner) runCase(ctx context.Context, client Client, suiteDir string, c Case) CaseResult {
+func (r *Runner) runCase(ctx context.Context, client Client, mutationSystem *mutation.System, exceptionSystem *exception.System, suiteDir string, values map[string]interface{}, c expandedCase) CaseResult {
 	start := time.Now()
 
-	err := r.checkCase(ctx, client, suiteDir, c)
+	var recorder *querystats.Recorder
+	if r.Stats {
+		ctx, recorder = querystats.NewContext(ctx)
+	}
+	var details *CaseResult
+	if r.Details || r.Trace {
+		details = &CaseResult{}
+	}
+	rendered, err := r.checkCase(ctx, client, mutationSystem, exceptionSystem, suiteDir, values, c, details)
 
-	return CaseResult{
-		Name:    c.Name,
-		Error:   err,
-		Runtime: Duration(time.Since(start)),
+	result := CaseResult{
+		Name:     c.Name,
+		Error:    err,
+		Runtime:  Duration(time.Since(start)),
+		Rendered: rendered,
 	}
+	if r.Details {
+		result.Reviewed = details.Reviewed
+		result.Violations = details.Violations
+		result.Assertions = describeAssertions(c.Case)
+		if c.Object != "" {
+			result.Object = filepath.Join(suiteDir, c.Object)
+			result.ObjectLine = objectLine(r.FS, result.Object, c.document)
+		}
+	}
+	if r.Trace && err != nil {
+		result.Trace = details.Trace
+	}
+	if recorder != nil {
+		stats := recorder.Stats()
+		result.Stats = &stats
+	}
+	return result
 }
 
-func (r *Runner) checkCase(ctx context.Context, client Client, suiteDir string, c Case) error {
+// checkCase runs the Case, returning the rendered object if it was rendered
+// with values. If details is not nil, the violations of the object which no
+// Exception exempts, and their trace if the Runner traces queries, are
+// recorded in it once it is reviewed.
+func (r *Runner) checkCase(ctx context.Context, client Client, mutationSystem *mutation.System, exceptionSystem *exception.System, suiteDir string, values map[string]interface{}, c expandedCase, details *CaseResult) (string, error) {
 	if c.Object == "" {
-		return fmt.Errorf("%w: must define object", ErrInvalidCase)
+		return "", fmt.Errorf("%w: must define object", ErrInvalidCase)
+	}
+	if c.AssertNoMutation && mutationSystem == nil {
+		return "", fmt.Errorf("%w: assertNoMutation requires the test to define mutators", ErrInvalidCase)
 	}
 
 	objectPath := filepath.Join(suiteDir, c.Object)
-	review, err := r.runReview(ctx, client, objectPath)
+	objs, rendered, err := r.readObject(objectPath, values, c.Values)
 	if err != nil {
-		return err
+		return rendered, err
+	}
+	if c.document >= len(objs) {
+		return rendered, fmt.Errorf("%w: %s does not define an object", ErrInvalidCase, c.Object)
+	}
+	u := objs[c.document]
+
+	if mutationSystem != nil {
+		original := u.DeepCopy()
+		mutated, err := mutate(mutationSystem, u)
+		if err != nil {
+			return rendered, err
+		}
+		if mutated && c.AssertNoMutation {
+			return rendered, fmt.Errorf("%w by: %s", ErrMutated, mutatedBy(mutationSystem, original))
+		}
+	}
+
+	review, err := reviewCase(ctx, client, u, c.Operation, c.SubResource, opaclient.Tracing(r.Trace))
+	if err != nil {
+		return rendered, err
 	}
 
 	results := review.Results()
+	if exceptionSystem != nil {
+		results = exempt(exceptionSystem, u, results)
+	}
+	if details != nil {
+		details.Reviewed = true
+		details.Violations = describeViolations(results)
+		if r.Trace {
+			details.Trace = review.TraceDump()
+		}
+	}
 
 	if len(c.Assertions) == 0 {
 		// Default to assuming the object passes validation if no Assertions are
@@ -173,27 +542,156 @@ func (r *Runner) checkCase(ctx context.Context, client Client, suiteDir string,
 	for i := range c.Assertions {
 		err = c.Assertions[i].Run(results)
 		if err != nil {
-			return err
+			return rendered, err
 		}
 	}
 
-	return nil
+	if c.AssertDenyMessage != nil {
+		return rendered, checkDenyMessage(*c.AssertDenyMessage, results)
+	}
+
+	return rendered, nil
 }
 
-func (r *Runner) runReview(ctx context.Context, client Client, path string) (*types.Responses, error) {
-	u, err := readCase(r.FS, path)
+// reviewCase reviews u as the object of an admission request performing
+// operation on subResource, if set, or as an existing object if operation is
+// empty.
+func reviewCase(ctx context.Context, client Client, u *unstructured.Unstructured, operation, subResource string, opts ...opaclient.QueryOpt) (*types.Responses, error) {
+	if operation == "" {
+		if subResource != "" {
+			return nil, fmt.Errorf("%w: subResource %q requires an operation", ErrInvalidCase, subResource)
+		}
+		return client.Review(ctx, u, opts...)
+	}
+	op := admissionv1.Operation(operation)
+	switch op {
+	case admissionv1.Create, admissionv1.Update, admissionv1.Delete:
+	default:
+		return nil, fmt.Errorf("%w: operation must be one of CREATE, UPDATE or DELETE, got %q", ErrInvalidCase, operation)
+	}
+	review, err := target.OperationReview(u, op, namespaceOf(u, nil))
 	if err != nil {
 		return nil, err
 	}
+	review.AdmissionRequest.SubResource = subResource
+	return client.Review(ctx, review, opts...)
+}
 
-	return client.Review(ctx, u)
+// checkDenyMessage returns an error unless results deny the object with a
+// message matching pattern.
+func checkDenyMessage(pattern string, results []*types.Result) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("%w: assertDenyMessage: %v", ErrInvalidRegex, err)
+	}
+	msg := util.DenyMessage(results)
+	if msg == "" {
+		return fmt.Errorf("%w: object was not denied, want a message matching %q", ErrDenyMessage, pattern)
+	}
+	if !re.MatchString(msg) {
+		return fmt.Errorf("%w: got %q, want a message matching %q", ErrDenyMessage, msg, pattern)
+	}
+	return nil
 }
 
-func readCase(f fs.FS, path string) (*unstructured.Unstructured, error) {
+// readObject reads the objects in the documents of the file at path, rendering
+// it first if either the Suite or the Case defines values.
+func (r *Runner) readObject(path string, suiteValues, caseValues map[string]interface{}) ([]*unstructured.Unstructured, string, error) {
+	if suiteValues == nil && len(caseValues) == 0 {
+		objs, err := readObjects(r.FS, path)
+		return objs, "", err
+	}
+
+	rendered, err := r.renderCached(path, mergeValues(suiteValues, caseValues))
+	if err != nil {
+		return nil, "", err
+	}
+	objs, err := readDocuments(rendered)
+	return objs, string(rendered), err
+}
+
+func readObjects(f fs.FS, path string) ([]*unstructured.Unstructured, error) {
 	bytes, err := fs.ReadFile(f, path)
 	if err != nil {
 		return nil, err
 	}
 
-	return readUnstructured(bytes)
+	return readDocuments(bytes)
+}
+
+// objectLine returns the line the object of the given document in the file at
+// path starts on, after any blank lines, comments and document separators, or
+// 0 if the file cannot be read. Empty documents are not counted.
+func objectLine(f fs.FS, path string, document int) int {
+	bytes, err := fs.ReadFile(f, path)
+	if err != nil {
+		return 0
+	}
+	// inDocument is whether the lines so far hold an object since the last
+	// document separator.
+	inDocument := false
+	for i, line := range strings.Split(string(bytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "---" {
+			if inDocument {
+				document--
+				inDocument = false
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || inDocument {
+			continue
+		}
+		if document == 0 {
+			return i + 1
+		}
+		inDocument = true
+	}
+	return 0
+}
+
+// describeAssertions returns a description of each Assertion the object of c
+// is checked against.
+func describeAssertions(c Case) []string {
+	if len(c.Assertions) == 0 {
+		return []string{"violations: no"}
+	}
+	var descriptions []string
+	for i := range c.Assertions {
+		a := &c.Assertions[i]
+		var parts []string
+		if a.Violations != nil || a.Warnings == nil {
+			violations := "yes"
+			if a.Violations != nil {
+				violations = a.Violations.String()
+			}
+			parts = append(parts, "violations: "+violations)
+		}
+		if a.Warnings != nil {
+			parts = append(parts, "warnings: "+a.Warnings.String())
+		}
+		if a.Message != nil {
+			parts = append(parts, fmt.Sprintf("message: %q", *a.Message))
+		}
+		descriptions = append(descriptions, strings.Join(parts, ", "))
+	}
+	if c.AssertDenyMessage != nil {
+		descriptions = append(descriptions, fmt.Sprintf("denyMessage: %q", *c.AssertDenyMessage))
+	}
+	return descriptions
+}
+
+// describeViolations returns the message of each of results, marking
+// warnings, sorted so they can be compared across runs.
+func describeViolations(results []*types.Result) []string {
+	violations := []string{}
+	for _, r := range results {
+		msg := r.Msg
+		if r.EnforcementAction == string(util.Warn) {
+			msg = "(warning) " + msg
+		}
+		violations = append(violations, msg)
+	}
+	sort.Strings(violations)
+	return violations
 }
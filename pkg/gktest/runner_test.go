@@ -3,7 +3,9 @@ package gktest
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/fs"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -162,6 +164,15 @@ metadata:
   name: always-fail
 `
 
+	constraintNeverValidateWarn = `
+kind: NeverValidate
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: always-warn
+spec:
+  enforcementAction: warn
+`
+
 	constraintNeverValidateTwice = `
 kind: NeverValidateTwice
 apiVersion: constraints.gatekeeper.sh/v1beta1
@@ -184,6 +195,40 @@ metadata:
   name: other
 `
 
+	templateUniqueHost = `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: uniqueingresshost
+spec:
+  crd:
+    spec:
+      names:
+        kind: UniqueIngressHost
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package uniqueingresshost
+        violation[{"msg": msg}] {
+          host := input.review.object.spec.rules[_].host
+          other := data.inventory.namespace[ns][_]["Ingress"][name]
+          not identical(other, input.review)
+          other.spec.rules[_].host == host
+          msg := sprintf("host %v is used by %v/%v", [host, ns, name])
+        }
+        identical(obj, review) {
+          obj.metadata.namespace == review.object.metadata.namespace
+          obj.metadata.name == review.object.metadata.name
+        }
+`
+
+	constraintUniqueHost = `
+kind: UniqueIngressHost
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: unique-ingress-host
+`
+
 	object = `
 kind: Object
 apiVersion: v1
@@ -877,6 +922,86 @@ func TestRunner_RunCase(t *testing.T) {
 				Error: ErrNumViolations,
 			},
 		},
+		// Warnings
+		{
+			name:       "expect warning",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidateWarn,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings: intStrFromStr("yes"),
+			}},
+			want: CaseResult{},
+		},
+		{
+			name:       "expect warning message",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidateWarn,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings: intStrFromInt(1),
+				Message:  pointer.StringPtr("never validate"),
+			}},
+			want: CaseResult{},
+		},
+		{
+			name:       "expect warning message fail",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidateWarn,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings: intStrFromStr("yes"),
+				Message:  pointer.StringPtr("other guidance"),
+			}},
+			want: CaseResult{
+				Error: ErrNumWarnings,
+			},
+		},
+		{
+			name:       "expect warning from denying constraint fail",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidate,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings: intStrFromStr("yes"),
+			}},
+			want: CaseResult{
+				Error: ErrNumWarnings,
+			},
+		},
+		{
+			name:       "expect no warnings and a violation",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidate,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings:   intStrFromStr("no"),
+				Violations: intStrFromInt(1),
+			}},
+			want: CaseResult{},
+		},
+		{
+			name:       "expect no warnings",
+			template:   templateAlwaysValidate,
+			constraint: constraintAlwaysValidate,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings: intStrFromInt(0),
+			}},
+			want: CaseResult{},
+		},
+		{
+			name:       "invalid warnings IntOrStr string value",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidateWarn,
+			object:     object,
+			assertions: []Assertion{{
+				Warnings: &intstr.IntOrString{Type: intstr.String, StrVal: "maybe"},
+			}},
+			want: CaseResult{
+				Error: ErrInvalidYAML,
+			},
+		},
 		// Invalid assertions
 		{
 			name:       "invalid IntOrStr",
@@ -950,3 +1075,710 @@ func TestRunner_RunCase(t *testing.T) {
 		})
 	}
 }
+
+func TestRunner_RunCase_AssertDenyMessage(t *testing.T) {
+	testCases := []struct {
+		name       string
+		template   string
+		constraint string
+		violations string
+		message    string
+		want       CaseResult
+	}{
+		{
+			name:       "matching message",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidate,
+			violations: "yes",
+			message:    `^\[always-fail\] never validate$`,
+			want:       CaseResult{},
+		},
+		{
+			name:       "composed message",
+			template:   templateNeverValidateTwice,
+			constraint: constraintNeverValidateTwice,
+			violations: "yes",
+			message:    `^\[always-fail-twice\] (first|second) message\n\[always-fail-twice\] (first|second) message$`,
+			want:       CaseResult{},
+		},
+		{
+			name:       "violation message alone",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidate,
+			violations: "yes",
+			message:    `^never validate$`,
+			want: CaseResult{
+				Error: ErrDenyMessage,
+			},
+		},
+		{
+			name:       "not denied",
+			template:   templateAlwaysValidate,
+			constraint: constraintAlwaysValidate,
+			violations: "no",
+			message:    `.*`,
+			want: CaseResult{
+				Error: ErrDenyMessage,
+			},
+		},
+		{
+			name:       "invalid regex",
+			template:   templateNeverValidate,
+			constraint: constraintNeverValidate,
+			violations: "yes",
+			message:    `never validate [(`,
+			want: CaseResult{
+				Error: ErrInvalidRegex,
+			},
+		},
+	}
+
+	const (
+		templateFile   = "template.yaml"
+		constraintFile = "constraint.yaml"
+		objectFile     = "object.yaml"
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			suite := &Suite{
+				Tests: []Test{{
+					Template:   templateFile,
+					Constraint: constraintFile,
+					Cases: []Case{{
+						Object:            objectFile,
+						Assertions:        []Assertion{{Violations: intStrFromStr(tc.violations)}},
+						AssertDenyMessage: pointer.StringPtr(tc.message),
+					}},
+				}},
+			}
+			runner := Runner{
+				FS: fstest.MapFS{
+					templateFile:   &fstest.MapFile{Data: []byte(tc.template)},
+					constraintFile: &fstest.MapFile{Data: []byte(tc.constraint)},
+					objectFile:     &fstest.MapFile{Data: []byte(object)},
+				},
+				NewClient: NewOPAClient,
+			}
+
+			got := runner.Run(context.Background(), Filter{}, "", suite)
+
+			want := SuiteResult{
+				TestResults: []TestResult{{
+					CaseResults: []CaseResult{tc.want},
+				}},
+			}
+
+			if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+				cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+			); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
+
+func TestRunner_RunCase_Operation(t *testing.T) {
+	const (
+		templateFile   = "template.yaml"
+		constraintFile = "constraint.yaml"
+		protectedFile  = "protected.yaml"
+		otherFile      = "other.yaml"
+	)
+
+	// Namespaces listed in the Constraint may not be deleted.
+	template := `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: k8sprotectednamespaces
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sProtectedNamespaces
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8sprotectednamespaces
+        violation[{"msg": msg}] {
+          input.review.operation == "DELETE"
+          name := input.review.oldObject.metadata.name
+          name == input.parameters.namespaces[_]
+          msg := sprintf("namespace %v is protected", [name])
+        }
+`
+	constraint := `
+kind: K8sProtectedNamespaces
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: protected-namespaces
+spec:
+  match:
+    kinds:
+    - apiGroups: [""]
+      kinds: ["Namespace"]
+    operations: ["DELETE"]
+  parameters:
+    namespaces: ["kube-system"]
+`
+	namespace := `
+kind: Namespace
+apiVersion: v1
+metadata:
+  name: %s
+`
+
+	testCases := []struct {
+		name       string
+		object     string
+		operation  string
+		violations string
+		want       CaseResult
+	}{
+		{
+			name:       "deleting a protected namespace",
+			object:     protectedFile,
+			operation:  "DELETE",
+			violations: "yes",
+		},
+		{
+			name:       "deleting another namespace",
+			object:     otherFile,
+			operation:  "DELETE",
+			violations: "no",
+		},
+		{
+			name:       "creating a protected namespace",
+			object:     protectedFile,
+			operation:  "CREATE",
+			violations: "no",
+		},
+		{
+			name:       "existing protected namespace",
+			object:     protectedFile,
+			violations: "no",
+		},
+		{
+			name:       "invalid operation",
+			object:     protectedFile,
+			operation:  "delete",
+			violations: "no",
+			want:       CaseResult{Error: ErrInvalidCase},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			suite := &Suite{
+				Tests: []Test{{
+					Template:   templateFile,
+					Constraint: constraintFile,
+					Cases: []Case{{
+						Object:     tc.object,
+						Operation:  tc.operation,
+						Assertions: []Assertion{{Violations: intStrFromStr(tc.violations)}},
+					}},
+				}},
+			}
+			runner := Runner{
+				FS: fstest.MapFS{
+					templateFile:   &fstest.MapFile{Data: []byte(template)},
+					constraintFile: &fstest.MapFile{Data: []byte(constraint)},
+					protectedFile:  &fstest.MapFile{Data: []byte(fmt.Sprintf(namespace, "kube-system"))},
+					otherFile:      &fstest.MapFile{Data: []byte(fmt.Sprintf(namespace, "team-a"))},
+				},
+				NewClient: NewOPAClient,
+			}
+
+			got := runner.Run(context.Background(), Filter{}, "", suite)
+
+			want := SuiteResult{
+				TestResults: []TestResult{{
+					CaseResults: []CaseResult{tc.want},
+				}},
+			}
+
+			if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+				cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+			); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
+
+func TestRunner_RunCase_SubResource(t *testing.T) {
+	const (
+		templateFile   = "template.yaml"
+		constraintFile = "constraint.yaml"
+		evictionFile   = "eviction.yaml"
+	)
+
+	// Pods listed in the Constraint may not be evicted.
+	template := `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: k8sprotectedpods
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sProtectedPods
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8sprotectedpods
+        violation[{"msg": msg}] {
+          input.review.subResource == "eviction"
+          name := input.review.object.metadata.name
+          name == input.parameters.pods[_]
+          msg := sprintf("pod %v may not be evicted", [name])
+        }
+`
+	constraint := `
+kind: K8sProtectedPods
+apiVersion: constraints.gatekeeper.sh/v1beta1
+metadata:
+  name: protected-pods
+spec:
+  match:
+    kinds:
+    - apiGroups: ["policy"]
+      kinds: ["Eviction"]
+  parameters:
+    pods: ["db-0"]
+`
+	eviction := `
+kind: Eviction
+apiVersion: policy/v1
+metadata:
+  name: db-0
+  namespace: prod
+`
+
+	testCases := []struct {
+		name        string
+		operation   string
+		subResource string
+		violations  string
+		want        CaseResult
+	}{
+		{
+			name:        "evicting a protected pod",
+			operation:   "CREATE",
+			subResource: "eviction",
+			violations:  "yes",
+		},
+		{
+			name:       "without subresource",
+			operation:  "CREATE",
+			violations: "no",
+		},
+		{
+			name:        "subresource without operation",
+			subResource: "eviction",
+			violations:  "yes",
+			want:        CaseResult{Error: ErrInvalidCase},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			suite := &Suite{
+				Tests: []Test{{
+					Template:   templateFile,
+					Constraint: constraintFile,
+					Cases: []Case{{
+						Object:      evictionFile,
+						Operation:   tc.operation,
+						SubResource: tc.subResource,
+						Assertions:  []Assertion{{Violations: intStrFromStr(tc.violations)}},
+					}},
+				}},
+			}
+			runner := Runner{
+				FS: fstest.MapFS{
+					templateFile:   &fstest.MapFile{Data: []byte(template)},
+					constraintFile: &fstest.MapFile{Data: []byte(constraint)},
+					evictionFile:   &fstest.MapFile{Data: []byte(eviction)},
+				},
+				NewClient: NewOPAClient,
+			}
+
+			got := runner.Run(context.Background(), Filter{}, "", suite)
+
+			want := SuiteResult{
+				TestResults: []TestResult{{
+					CaseResults: []CaseResult{tc.want},
+				}},
+			}
+
+			if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+				cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+			); diff != "" {
+				t.Errorf(diff)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_Stats(t *testing.T) {
+	const (
+		templateFile   = "template.yaml"
+		constraintFile = "constraint.yaml"
+		objectFile     = "object.yaml"
+	)
+	suite := &Suite{
+		Tests: []Test{{
+			Template:   templateFile,
+			Constraint: constraintFile,
+			Cases: []Case{{
+				Object:     objectFile,
+				Assertions: []Assertion{{Violations: intStrFromStr("yes")}},
+			}},
+		}},
+	}
+	runner := Runner{
+		FS: fstest.MapFS{
+			templateFile:   &fstest.MapFile{Data: []byte(templateNeverValidate)},
+			constraintFile: &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+			objectFile:     &fstest.MapFile{Data: []byte(object)},
+		},
+		NewClient: NewOPAClient,
+		Stats:     true,
+	}
+
+	got := runner.Run(context.Background(), Filter{}, "", suite)
+	if got.IsFailure() {
+		t.Fatalf("got failed suite: %+v", got)
+	}
+	stats := got.TestResults[0].CaseResults[0].Stats
+	if stats == nil {
+		t.Fatal("got no stats, want the stats of the review")
+	}
+	if stats.Queries != 1 || stats.ConstraintsMatched != 1 {
+		t.Errorf("got %+v, want one query matching one constraint", *stats)
+	}
+}
+
+func TestRunner_Run_Details(t *testing.T) {
+	const (
+		templateFile   = "template.yaml"
+		constraintFile = "constraint.yaml"
+		objectFile     = "object.yaml"
+	)
+	suite := &Suite{
+		Tests: []Test{{
+			Template:   templateFile,
+			Constraint: constraintFile,
+			Cases: []Case{{
+				Object:     objectFile,
+				Assertions: []Assertion{{Violations: intStrFromStr("yes")}},
+			}},
+		}},
+	}
+	runner := Runner{
+		FS: fstest.MapFS{
+			templateFile:   &fstest.MapFile{Data: []byte(templateNeverValidate)},
+			constraintFile: &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+			objectFile:     &fstest.MapFile{Data: []byte(object)},
+		},
+		NewClient: NewOPAClient,
+		Details:   true,
+	}
+
+	got := runner.Run(context.Background(), Filter{}, "", suite)
+	if got.IsFailure() {
+		t.Fatalf("got failed suite: %+v", got)
+	}
+	result := got.TestResults[0].CaseResults[0]
+	if !result.Reviewed {
+		t.Error("got object not reviewed, want reviewed")
+	}
+	if diff := cmp.Diff([]string{"violations: yes"}, result.Assertions); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"never validate"}, result.Violations); diff != "" {
+		t.Error(diff)
+	}
+	// The object starts after a blank line.
+	if result.Object != objectFile || result.ObjectLine != 2 {
+		t.Errorf("got object at %s:%d, want %s:2", result.Object, result.ObjectLine, objectFile)
+	}
+}
+
+func TestObjectLine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"object.yaml":    &fstest.MapFile{Data: []byte("kind: Pod\n")},
+		"commented.yaml": &fstest.MapFile{Data: []byte("# A pod.\n\n---\nkind: Pod\n")},
+		"empty.yaml":     &fstest.MapFile{Data: []byte("---\n")},
+		"documents.yaml": &fstest.MapFile{Data: []byte("kind: Pod\nmetadata: {}\n---\n---\n# A service.\nkind: Service\n")},
+	}
+	testCases := []struct {
+		path     string
+		document int
+		want     int
+	}{
+		{path: "object.yaml", want: 1},
+		{path: "commented.yaml", want: 4},
+		{path: "empty.yaml", want: 0},
+		{path: "missing.yaml", want: 0},
+		{path: "documents.yaml", want: 1},
+		{path: "documents.yaml", document: 1, want: 6},
+		{path: "documents.yaml", document: 2, want: 0},
+	}
+	for _, tc := range testCases {
+		if got := objectLine(fsys, tc.path, tc.document); got != tc.want {
+			t.Errorf("got line %d of document %d of %s, want %d", got, tc.document, tc.path, tc.want)
+		}
+	}
+}
+
+func TestRunner_Run_Parallelism(t *testing.T) {
+	fsys := fstest.MapFS{
+		"allow-template.yaml":   &fstest.MapFile{Data: []byte(templateAlwaysValidate)},
+		"allow-constraint.yaml": &fstest.MapFile{Data: []byte(constraintAlwaysValidate)},
+		"deny-template.yaml":    &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"deny-constraint.yaml":  &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+		"object.yaml":           &fstest.MapFile{Data: []byte(object)},
+	}
+	suite := &Suite{}
+	var wantNames []string
+	var wantFailed []bool
+	for i := 0; i < 8; i++ {
+		policy := "allow"
+		if i%2 == 1 {
+			policy = "deny"
+		}
+		name := fmt.Sprintf("%s-%d", policy, i)
+		suite.Tests = append(suite.Tests, Test{
+			Name:       name,
+			Template:   policy + "-template.yaml",
+			Constraint: policy + "-constraint.yaml",
+			Cases:      []Case{{Name: "object", Object: "object.yaml"}},
+		})
+		wantNames = append(wantNames, name)
+		wantFailed = append(wantFailed, policy == "deny")
+	}
+
+	runner := Runner{FS: fsys, NewClient: NewOPAClient, Parallelism: 3}
+	got := runner.Run(context.Background(), Filter{}, "", suite)
+
+	var gotNames []string
+	var gotFailed []bool
+	for _, r := range got.TestResults {
+		gotNames = append(gotNames, r.Name)
+		gotFailed = append(gotFailed, r.IsFailure())
+	}
+	if diff := cmp.Diff(wantNames, gotNames); diff != "" {
+		t.Errorf("got results out of order: %s", diff)
+	}
+	if diff := cmp.Diff(wantFailed, gotFailed); diff != "" {
+		t.Errorf("got tests failing which each test's own client should not fail: %s", diff)
+	}
+}
+
+func TestRunner_Run_Skip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml":   &fstest.MapFile{Data: []byte(templateNeverValidate)},
+		"constraint.yaml": &fstest.MapFile{Data: []byte(constraintNeverValidate)},
+		"object.yaml":     &fstest.MapFile{Data: []byte(object)},
+	}
+	denied := Case{Name: "denied", Object: "object.yaml", Assertions: []Assertion{{Violations: intStrFromStr("yes")}}}
+	allowed := Case{Name: "allowed", Object: "object.yaml", Skip: true}
+	newSuite := func(skip bool) *Suite {
+		return &Suite{Skip: skip, Tests: []Test{{
+			Name:       "run",
+			Template:   "template.yaml",
+			Constraint: "constraint.yaml",
+			Cases:      []Case{denied, allowed},
+		}, {
+			Name: "pending",
+			Skip: true,
+			// The Test is not run, so its missing Template is not an error.
+			Constraint: "constraint.yaml",
+			Cases: []Case{{
+				Name:   "matrix",
+				Matrix: &Matrix{Objects: []string{"a.yaml", "b.yaml"}},
+			}},
+		}}}
+	}
+
+	type result struct {
+		Name    string
+		Skipped bool
+		Failed  bool
+	}
+	flatten := func(r SuiteResult) []result {
+		var got []result
+		for _, test := range r.TestResults {
+			got = append(got, result{Name: test.Name, Skipped: test.Skipped, Failed: test.IsFailure()})
+			for _, c := range test.CaseResults {
+				got = append(got, result{Name: test.Name + "/" + c.Name, Skipped: c.Skipped, Failed: c.IsFailure()})
+			}
+		}
+		return got
+	}
+
+	runner := Runner{FS: fsys, NewClient: NewOPAClient}
+
+	got := runner.Run(context.Background(), Filter{}, "", newSuite(false))
+	want := []result{
+		{Name: "run"},
+		{Name: "run/denied"},
+		{Name: "run/allowed", Skipped: true},
+		{Name: "pending", Skipped: true},
+		{Name: "pending/matrix/a.yaml", Skipped: true},
+		{Name: "pending/matrix/b.yaml", Skipped: true},
+	}
+	if diff := cmp.Diff(want, flatten(got)); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(Summary{Passed: 1, Skipped: 3}, got.Summary()); diff != "" {
+		t.Error(diff)
+	}
+
+	got = runner.Run(context.Background(), Filter{}, "", newSuite(true))
+	for _, r := range flatten(got) {
+		if !r.Skipped || r.Failed {
+			t.Errorf("got %+v in a skipped suite, want it skipped", r)
+		}
+	}
+}
+
+func TestRunner_Run_Inventory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml":      &fstest.MapFile{Data: []byte(templateUniqueHost)},
+		"constraint.yaml":    &fstest.MapFile{Data: []byte(constraintUniqueHost)},
+		"ingress.yaml":       &fstest.MapFile{Data: []byte(ingress("web", "a.example.com"))},
+		"inventory-a.yaml":   &fstest.MapFile{Data: []byte(ingress("other", "a.example.com"))},
+		"inventory-b.yaml":   &fstest.MapFile{Data: []byte(ingress("other", "b.example.com"))},
+		"inventory-web.yaml": &fstest.MapFile{Data: []byte(ingress("web", "a.example.com"))},
+		"inventory-list.yaml": &fstest.MapFile{Data: []byte(`
+kind: List
+apiVersion: v1
+items:
+- ` + strings.ReplaceAll(strings.TrimPrefix(ingress("first", "c.example.com"), "\n"), "\n", "\n  ") + `
+- ` + strings.ReplaceAll(strings.TrimPrefix(ingress("second", "a.example.com"), "\n"), "\n", "\n  ") + `
+`)},
+	}
+	violates := []Assertion{{Violations: intStrFromStr("yes")}}
+	allows := []Assertion{{Violations: intStrFromStr("no")}}
+
+	suite := &Suite{Tests: []Test{{
+		Name:       "unique-ingress-host",
+		Template:   "template.yaml",
+		Constraint: "constraint.yaml",
+		Inventory:  []string{"inventory-b.yaml", "inventory-web.yaml"},
+		Cases: []Case{{
+			Name:       "test inventory",
+			Object:     "ingress.yaml",
+			Assertions: allows,
+		}, {
+			Name:       "case inventory",
+			Object:     "ingress.yaml",
+			Inventory:  []string{"inventory-a.yaml"},
+			Assertions: violates,
+		}, {
+			Name:       "case inventory removed",
+			Object:     "ingress.yaml",
+			Assertions: allows,
+		}, {
+			Name:       "list",
+			Object:     "ingress.yaml",
+			Inventory:  []string{"inventory-list.yaml"},
+			Assertions: violates,
+		}, {
+			Name:       "missing",
+			Object:     "ingress.yaml",
+			Inventory:  []string{"missing.yaml"},
+			Assertions: allows,
+		}},
+	}}}
+
+	runner := Runner{FS: fsys, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "", suite)
+	if len(got.TestResults) != 1 {
+		t.Fatalf("got %d TestResults, want 1", len(got.TestResults))
+	}
+	if err := got.TestResults[0].Error; err != nil {
+		t.Fatalf("got Test error %v", err)
+	}
+
+	wantErrs := map[string]error{"missing": fs.ErrNotExist}
+	for _, c := range got.TestResults[0].CaseResults {
+		if !errors.Is(c.Error, wantErrs[c.Name]) {
+			t.Errorf("%s: got error %v, want %v", c.Name, c.Error, wantErrs[c.Name])
+		}
+	}
+}
+
+func ingress(name, host string) string {
+	return `
+kind: Ingress
+apiVersion: networking.k8s.io/v1
+metadata:
+  name: ` + name + `
+  namespace: default
+spec:
+  rules:
+  - host: ` + host + `
+`
+}
+
+func TestRunner_Run_Documents(t *testing.T) {
+	documents := ingress("web", "a.example.com") + "---\n" + ingress("other", "a.example.com") + "---\n" + ingress("api", "b.example.com")
+	fsys := fstest.MapFS{
+		"template.yaml":   &fstest.MapFile{Data: []byte(templateUniqueHost)},
+		"constraint.yaml": &fstest.MapFile{Data: []byte(constraintUniqueHost)},
+		"ingresses.yaml":  &fstest.MapFile{Data: []byte(documents)},
+		"empty.yaml":      &fstest.MapFile{Data: []byte("---\n")},
+	}
+
+	suite := &Suite{Tests: []Test{{
+		Name:       "unique-ingress-host",
+		Template:   "template.yaml",
+		Constraint: "constraint.yaml",
+		Cases: []Case{{
+			// The other documents are added to data.inventory, so the host of
+			// the first is used twice.
+			Name:       "inventory",
+			Object:     "ingresses.yaml",
+			Assertions: []Assertion{{Violations: intStrFromStr("yes")}},
+		}, {
+			// Without an inventory, every host is unique.
+			Name:         "each",
+			Object:       "ingresses.yaml",
+			EachDocument: true,
+			Assertions:   []Assertion{{Violations: intStrFromStr("no")}},
+		}, {
+			Name:         "empty",
+			Object:       "empty.yaml",
+			EachDocument: true,
+		}, {
+			Name:   "empty object",
+			Object: "empty.yaml",
+		}},
+	}}}
+
+	runner := Runner{FS: fsys, NewClient: NewOPAClient}
+	got := runner.Run(context.Background(), Filter{}, "", suite)
+
+	want := SuiteResult{TestResults: []TestResult{{
+		Name: "unique-ingress-host",
+		CaseResults: []CaseResult{
+			{Name: "inventory"},
+			{Name: "each/documents[0]"},
+			{Name: "each/documents[1]"},
+			{Name: "each/documents[2]"},
+			{Name: "empty object", Error: ErrInvalidCase},
+		},
+	}}}
+	if diff := cmp.Diff(want, got, cmpopts.EquateErrors(), cmpopts.EquateEmpty(),
+		cmpopts.IgnoreFields(SuiteResult{}, "Runtime"), cmpopts.IgnoreFields(TestResult{}, "Runtime"), cmpopts.IgnoreFields(CaseResult{}, "Runtime"),
+	); diff != "" {
+		t.Error(diff)
+	}
+}
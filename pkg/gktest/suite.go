@@ -8,6 +8,22 @@ import (
 type Suite struct {
 	metav1.ObjectMeta
 
+	// Values is the path to a YAML file, relative to the file defining the
+	// Suite, of values to render Case objects with. If set, every object is
+	// a text/template with the values available as .Values.
+	Values string `json:"values,omitempty"`
+
+	// RegoTests is whether to also run the Rego unit tests of each Test's
+	// Template, as `opa test` would. They are the tests in the *_test.rego
+	// files in the directory of the Template which are in, or import, the
+	// package of its Rego. Each is reported as a Case named after its package
+	// and rule, such as data.k8srequiredlabels.test_missing_label.
+	RegoTests bool `json:"regoTests,omitempty"`
+
+	// Skip skips every Test of the Suite, reporting their Cases as skipped
+	// rather than running them.
+	Skip bool `json:"skip,omitempty"`
+
 	// Tests is a list of Template&Constraint pairs, with tests to run on
 	// each.
 	Tests []Test `json:"tests"`
@@ -26,6 +42,28 @@ type Test struct {
 	// the Suite. Must be an instance of Template.
 	Constraint string `json:"constraint"`
 
+	// Mutators are the paths to Assign, AssignMetadata and ModifySet mutators,
+	// relative to the file defining the Suite. Case objects are mutated before
+	// they are reviewed, as they would be at admission. A Test which defines
+	// Mutators may omit Template and Constraint to only test mutation.
+	Mutators []string `json:"mutators,omitempty"`
+
+	// Exceptions are the paths to Exceptions, relative to the file defining
+	// the Suite. Violations they exempt are dropped before a Case's
+	// Assertions are checked, as the webhook would with --enable-exceptions.
+	// Objects in a namespace are matched as if their namespace had no labels.
+	Exceptions []string `json:"exceptions,omitempty"`
+
+	// Inventory are the paths to objects, relative to the file defining the
+	// Suite, which are added to data.inventory as though they were replicated
+	// from the cluster, for referential Constraints such as one requiring
+	// unique Ingress hosts. A file defining a List adds each of its items.
+	Inventory []string `json:"inventory,omitempty"`
+
+	// Skip skips the Test, reporting its Cases as skipped rather than running
+	// them, for example while its policy is pending or flaky.
+	Skip bool `json:"skip,omitempty"`
+
 	// Cases are the test cases to run on the instantiated Constraint.
 	Cases []Case `json:"cases,omitempty"`
 }
@@ -35,8 +73,56 @@ type Case struct {
 	Name string `json:"name"`
 
 	// Object is the path to the file containing a Kubernetes object to test.
+	// If the file holds several YAML documents, separated by "---", the first
+	// is tested and the others are added to data.inventory, unless
+	// EachDocument is set.
 	Object string `json:"object"`
 
+	// EachDocument expands this Case into a Case testing each document of
+	// Object, named after the index of the document, for example
+	// "deployments/documents[1]".
+	EachDocument bool `json:"eachDocument,omitempty"`
+
+	// Inventory are the paths to objects added to data.inventory while Object
+	// is reviewed, along with those of the Test's Inventory. Objects with the
+	// same kind, namespace and name as one of the Test's replace it.
+	Inventory []string `json:"inventory,omitempty"`
+
+	// Values override the top-level keys of the Suite's values when rendering
+	// Object. If set, Object is rendered even if the Suite has no values, so
+	// that a single file may define objects which differ only in these values.
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// Matrix expands this Case into a Case for every combination of its
+	// objects and Constraint parameters. Object must not be set if Matrix is.
+	Matrix *Matrix `json:"matrix,omitempty"`
+
+	// Operation is the operation of the admission request reviewing Object:
+	// CREATE, UPDATE or DELETE. A DELETE carries Object as the existing object,
+	// in oldObject. If empty, Object is reviewed as an existing object, as in
+	// audit, which matches Constraints as though it were created or updated.
+	Operation string `json:"operation,omitempty"`
+
+	// SubResource is the subresource of the admission request reviewing
+	// Object, such as scale or eviction, in which case Object is the
+	// subresource's object, such as a Scale or an Eviction. It is available to
+	// templates as input.review.subResource. Requires Operation.
+	SubResource string `json:"subResource,omitempty"`
+
+	// AssertNoMutation fails the Case if the Test's mutators change Object,
+	// for example to check that objects in exempt namespaces or which already
+	// comply are left untouched. Requires the Test to define Mutators.
+	AssertNoMutation bool `json:"assertNoMutation,omitempty"`
+
+	// AssertDenyMessage is a regular expression which must match the message
+	// the webhook would deny Object with, composed from the messages of every
+	// violation of a Constraint with the deny enforcement action. Fails the
+	// Case if Object is not denied.
+	AssertDenyMessage *string `json:"assertDenyMessage,omitempty"`
+
+	// Skip skips the Case, reporting it as skipped rather than running it.
+	Skip bool `json:"skip,omitempty"`
+
 	// Assertions are statements which must be true about the result of running
 	// Review with the Test's Constraint on the Case's Object.
 	//
@@ -0,0 +1,165 @@
+package gktest
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Category is the kind of error which made a test fail.
+type Category string
+
+const (
+	// CategoryAssertion indicates a policy did not behave as a Case asserted.
+	CategoryAssertion Category = "assertion"
+	// CategorySuite indicates a Suite, or a file it refers to, is invalid.
+	CategorySuite Category = "suite"
+	// CategoryInfrastructure indicates an error unrelated to the Suite or the
+	// policies under test prevented running tests.
+	CategoryInfrastructure Category = "infrastructure"
+)
+
+// assertionErrors are the errors of Cases whose assertions failed.
+var assertionErrors = []error{ErrNumViolations, ErrDenyMessage, ErrMutated}
+
+// suiteErrors are the errors of Suites which are invalid, or refer to files
+// which are missing or invalid.
+var suiteErrors = []error{
+	ErrInvalidSuite,
+	ErrInvalidCase,
+	ErrInvalidRegex,
+	ErrInvalidYAML,
+	ErrUnsupportedExtension,
+	ErrNotATemplate,
+	ErrNotAConstraint,
+	ErrAddingTemplate,
+	ErrAddingConstraint,
+	ErrAddingExpansionTemplate,
+	ErrAddingMutator,
+	ErrAddingInventory,
+	ErrRendering,
+	fs.ErrNotExist,
+}
+
+// Categorize returns the Category of err. Errors not known to be caused by
+// failed assertions or invalid Suites are infrastructure errors.
+func Categorize(err error) Category {
+	for _, target := range assertionErrors {
+		if errors.Is(err, target) {
+			return CategoryAssertion
+		}
+	}
+	for _, target := range suiteErrors {
+		if errors.Is(err, target) {
+			return CategorySuite
+		}
+	}
+	return CategoryInfrastructure
+}
+
+// Summary counts the Cases which passed or were skipped, and the errors of each Category which
+// made Suites, Tests and Cases fail. An error which stopped a Suite or Test
+// from executing is counted once, rather than once per Case it stopped.
+type Summary struct {
+	// Passed is the number of Cases which passed.
+	Passed int
+	// Skipped is the number of Cases which were skipped. Skipped Cases do not
+	// make the Summary a failure.
+	Skipped int
+	// AssertionFailures is the number of Cases whose assertions failed.
+	AssertionFailures int
+	// SuiteErrors is the number of errors caused by invalid Suites.
+	SuiteErrors int
+	// InfrastructureErrors is the number of other errors.
+	InfrastructureErrors int
+}
+
+// Exit codes of Summary.ExitCode.
+const (
+	ExitPass           = 0
+	ExitAssertion      = 1
+	ExitSuite          = 2
+	ExitInfrastructure = 3
+)
+
+// Summary counts the results of the Suite.
+func (r *SuiteResult) Summary() Summary {
+	var s Summary
+	if r.Error != nil {
+		s.add(r.Error)
+		return s
+	}
+	for i := range r.TestResults {
+		s.Add(r.TestResults[i].Summary())
+	}
+	return s
+}
+
+// Summary counts the results of the Test.
+func (r *TestResult) Summary() Summary {
+	var s Summary
+	if r.Error != nil {
+		s.add(r.Error)
+		return s
+	}
+	for _, c := range r.CaseResults {
+		if c.Skipped {
+			s.Skipped++
+		} else if c.Error != nil {
+			s.add(c.Error)
+		} else if c.Name != "" {
+			// Cases excluded by the Filter are left empty.
+			s.Passed++
+		}
+	}
+	return s
+}
+
+// Summarize counts the results of every Suite.
+func Summarize(results []SuiteResult) Summary {
+	var s Summary
+	for i := range results {
+		s.Add(results[i].Summary())
+	}
+	return s
+}
+
+// Add adds the counts of o to s.
+func (s *Summary) Add(o Summary) {
+	s.Passed += o.Passed
+	s.Skipped += o.Skipped
+	s.AssertionFailures += o.AssertionFailures
+	s.SuiteErrors += o.SuiteErrors
+	s.InfrastructureErrors += o.InfrastructureErrors
+}
+
+func (s *Summary) add(err error) {
+	switch Categorize(err) {
+	case CategoryAssertion:
+		s.AssertionFailures++
+	case CategorySuite:
+		s.SuiteErrors++
+	default:
+		s.InfrastructureErrors++
+	}
+}
+
+// IsFailure returns true if any error was counted.
+func (s Summary) IsFailure() bool {
+	return s.AssertionFailures+s.SuiteErrors+s.InfrastructureErrors > 0
+}
+
+// ExitCode returns the exit code of the most severe Category counted:
+// infrastructure errors over invalid Suites over failed assertions, so CI can
+// tell failing policies from broken Suites.
+func (s Summary) ExitCode() int {
+	switch {
+	case s.InfrastructureErrors > 0:
+		return ExitInfrastructure
+	case s.SuiteErrors > 0:
+		return ExitSuite
+	case s.AssertionFailures > 0:
+		return ExitAssertion
+	default:
+		return ExitPass
+	}
+}
@@ -0,0 +1,74 @@
+package gktest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCategorize(t *testing.T) {
+	tcs := []struct {
+		err  error
+		want Category
+	}{
+		{err: fmt.Errorf("%w: got 1 violations but want none", ErrNumViolations), want: CategoryAssertion},
+		{err: fmt.Errorf("%w: object was not denied", ErrDenyMessage), want: CategoryAssertion},
+		{err: fmt.Errorf("%w: must define object", ErrInvalidCase), want: CategorySuite},
+		{err: fmt.Errorf("%w: rego_parse_error", ErrAddingTemplate), want: CategorySuite},
+		{err: &fs.PathError{Op: "open", Path: "object.yaml", Err: fs.ErrNotExist}, want: CategorySuite},
+		{err: fmt.Errorf("%w: out of memory", ErrCreatingClient), want: CategoryInfrastructure},
+		{err: errors.New("review failed"), want: CategoryInfrastructure},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			if got := Categorize(tc.err); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []SuiteResult{{
+		TestResults: []TestResult{{
+			Name: "labels",
+			CaseResults: []CaseResult{
+				{Name: "allowed"},
+				{Name: "denied", Error: ErrNumViolations},
+				// Excluded by the Filter.
+				{},
+			},
+		}, {
+			Name:  "limits",
+			Error: fmt.Errorf("%w: missing constraint", ErrInvalidSuite),
+		}},
+	}, {
+		Error: fmt.Errorf("%w: reading values", fs.ErrNotExist),
+	}}
+
+	got := Summarize(results)
+	want := Summary{Passed: 1, AssertionFailures: 1, SuiteErrors: 2}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+	if got.ExitCode() != ExitSuite {
+		t.Errorf("got exit code %d, want %d", got.ExitCode(), ExitSuite)
+	}
+
+	tcs := []struct {
+		summary Summary
+		want    int
+	}{
+		{summary: Summary{Passed: 2}, want: ExitPass},
+		{summary: Summary{AssertionFailures: 1}, want: ExitAssertion},
+		{summary: Summary{AssertionFailures: 1, SuiteErrors: 1, InfrastructureErrors: 1}, want: ExitInfrastructure},
+	}
+	for _, tc := range tcs {
+		if got := tc.summary.ExitCode(); got != tc.want {
+			t.Errorf("%+v: got exit code %d, want %d", tc.summary, got, tc.want)
+		}
+	}
+}
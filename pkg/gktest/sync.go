@@ -0,0 +1,138 @@
+package gktest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/inventory"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ErrNotAConfig indicates a file does not contain a Config.
+var ErrNotAConfig = errors.New("not a Config")
+
+// SyncCheck is the result of checking that the data ConstraintTemplates read
+// from data.inventory is replicated by Configs.
+type SyncCheck struct {
+	// Configs are the files Configs were read from.
+	Configs []string
+	// Results hold the templates which read data.inventory.
+	Results []SyncResult
+}
+
+// SyncResult is whether the kinds a ConstraintTemplate reads from
+// data.inventory are replicated.
+type SyncResult struct {
+	// Path is the file the ConstraintTemplate was read from.
+	Path string
+	// Template is the name of the ConstraintTemplate.
+	Template string
+	// Required are the kinds the template reads from data.inventory.
+	Required []schema.GroupVersionKind
+	// Missing are the Required kinds which no Config replicates.
+	Missing []schema.GroupVersionKind
+	// Dynamic are the locations of references to data.inventory whose kind
+	// cannot be determined without evaluating the Rego.
+	Dynamic []string
+	// Error is the error reading or parsing the ConstraintTemplate, if any.
+	Error error
+}
+
+// CheckSync reads the ConstraintTemplates and Configs selected by target,
+// which are found in the same way as ReadSuites finds Suites, and reports the
+// kinds each template reads from data.inventory which no Config replicates.
+// Templates which do not read data.inventory are omitted.
+func CheckSync(f fs.FS, target string, recursive bool) (*SyncCheck, error) {
+	if f == nil {
+		return nil, ErrNoFileSystem
+	}
+	if target == "" {
+		return nil, ErrNoTarget
+	}
+
+	files, err := listFiles(f, target, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &SyncCheck{}
+	synced := make(map[schema.GroupVersionKind]bool)
+	var templateFiles []string
+	for _, file := range files {
+		config, err := readConfig(f, file)
+		switch {
+		case errors.Is(err, ErrNotAConfig), errors.Is(err, ErrInvalidYAML):
+			// Unparseable files are reported if they are meant to be templates.
+			templateFiles = append(templateFiles, file)
+			continue
+		case err != nil:
+			return nil, err
+		}
+		check.Configs = append(check.Configs, file)
+		for _, e := range config.Spec.Sync.SyncOnly {
+			synced[schema.GroupVersionKind{Group: e.Group, Version: e.Version, Kind: e.Kind}] = true
+		}
+	}
+
+	for _, file := range templateFiles {
+		template, err := readTemplate(f, file)
+		if errors.Is(err, ErrNotATemplate) {
+			continue
+		}
+		if err != nil {
+			check.Results = append(check.Results, SyncResult{Path: file, Error: err})
+			continue
+		}
+
+		usage, err := inventory.Extract(template)
+		if err != nil {
+			check.Results = append(check.Results, SyncResult{
+				Path:     file,
+				Template: template.Name,
+				Error:    fmt.Errorf("%w: parsing Rego of %q: %v", ErrAddingTemplate, template.Name, err),
+			})
+			continue
+		}
+		if usage.Empty() {
+			continue
+		}
+
+		check.Results = append(check.Results, SyncResult{
+			Path:     file,
+			Template: template.Name,
+			Required: usage.GVKs,
+			Missing:  usage.Missing(synced),
+			Dynamic:  usage.Dynamic,
+		})
+	}
+	return check, nil
+}
+
+func readConfig(f fs.FS, path string) (*configv1alpha1.Config, error) {
+	bytes, err := fs.ReadFile(f, path)
+	if err != nil {
+		return nil, fmt.Errorf("reading Config from %q: %w", path, err)
+	}
+
+	u, err := readUnstructured(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing YAML from %q: %v", ErrInvalidYAML, path, err)
+	}
+	gvk := u.GroupVersionKind()
+	if gvk.Group != configv1alpha1.GroupVersion.Group || gvk.Kind != "Config" {
+		return nil, fmt.Errorf("%w: %q", ErrNotAConfig, path)
+	}
+
+	jsonBytes, err := u.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("calling unstructured.MarshalJSON(): %w", err)
+	}
+	config := &configv1alpha1.Config{}
+	if err := json.Unmarshal(jsonBytes, config); err != nil {
+		return nil, fmt.Errorf("parsing Config from %q: %w", path, err)
+	}
+	return config, nil
+}
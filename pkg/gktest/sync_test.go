@@ -0,0 +1,124 @@
+package gktest
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	templateUniqueIngressHost = `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: uniqueingresshost
+spec:
+  crd:
+    spec:
+      names:
+        kind: UniqueIngressHost
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8suniqueingresshost
+        import data.lib.namespaces
+        violation[{"msg": "duplicate host"}] {
+          host := input.review.object.spec.rules[_].host
+          other := data.inventory.namespace[ns]["networking.k8s.io/v1"]["Ingress"][name]
+          other.spec.rules[_].host == host
+          namespaces.exists(ns)
+        }
+      libs:
+        - |
+          package lib.namespaces
+          import data.inventory.cluster as cluster
+          exists(ns) {
+            cluster["v1"].Namespace[ns]
+          }
+`
+
+	templateDynamicInventory = `
+kind: ConstraintTemplate
+apiVersion: templates.gatekeeper.sh/v1beta1
+metadata:
+  name: uniquename
+spec:
+  crd:
+    spec:
+      names:
+        kind: UniqueName
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8suniquename
+        violation[{"msg": "duplicate name"}] {
+          other := data.inventory.cluster[_][input.parameters.kind][input.review.object.metadata.name]
+        }
+`
+
+	configSyncNamespaces = `
+kind: Config
+apiVersion: config.gatekeeper.sh/v1alpha1
+metadata:
+  name: config
+  namespace: gatekeeper-system
+spec:
+  sync:
+    syncOnly:
+      - group: ""
+        version: v1
+        kind: Namespace
+`
+)
+
+func TestCheckSync(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"policy/config.yaml":    &fstest.MapFile{Data: []byte(configSyncNamespaces)},
+		"policy/always.yaml":    &fstest.MapFile{Data: []byte(templateAlwaysValidate)},
+		"policy/ingress.yaml":   &fstest.MapFile{Data: []byte(templateUniqueIngressHost)},
+		"policy/name.yaml":      &fstest.MapFile{Data: []byte(templateDynamicInventory)},
+		"policy/suite.yaml":     &fstest.MapFile{Data: []byte("kind: Suite\napiVersion: test.gatekeeper.sh/v1alpha1\n")},
+		"policy/invalid.yaml":   &fstest.MapFile{Data: []byte(templateInvalidYAML)},
+		"policy/unrelated.yaml": &fstest.MapFile{Data: []byte("kind: Namespace\napiVersion: v1\n")},
+	}
+
+	got, err := CheckSync(fileSystem, "policy", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"policy/config.yaml"}, got.Configs); diff != "" {
+		t.Error(diff)
+	}
+
+	byPath := make(map[string]SyncResult)
+	for _, r := range got.Results {
+		byPath[r.Path] = r
+	}
+	if len(byPath) != 3 {
+		t.Errorf("got results for %d files, want only templates reading data.inventory and invalid templates: %+v", len(byPath), got.Results)
+	}
+
+	namespace := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	ingress := schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+	ingressResult := byPath["policy/ingress.yaml"]
+	if diff := cmp.Diff([]schema.GroupVersionKind{namespace, ingress}, ingressResult.Required); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]schema.GroupVersionKind{ingress}, ingressResult.Missing); diff != "" {
+		t.Error(diff)
+	}
+	if len(ingressResult.Dynamic) != 0 {
+		t.Errorf("got dynamic references %v, want none", ingressResult.Dynamic)
+	}
+
+	nameResult := byPath["policy/name.yaml"]
+	if len(nameResult.Required) != 0 || len(nameResult.Dynamic) != 1 {
+		t.Errorf("got %+v, want a single dynamic reference", nameResult)
+	}
+
+	if byPath["policy/invalid.yaml"].Error == nil {
+		t.Error("got no error for an invalid template")
+	}
+}
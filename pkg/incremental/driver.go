@@ -0,0 +1,113 @@
+// Package incremental avoids reloading unchanged Rego into the constraint
+// framework.
+//
+// The local driver recompiles every module whenever any module changes, and
+// blocks queries while it does so. Reloading a template whose Rego did not
+// change, such as after an update to only its CRD schema, would stall every
+// review for the duration of a full recompilation without changing the
+// compiled policy.
+//
+// Rego which did change is still recompiled together with every other module:
+// OPA compiles a set of modules as a whole, and cannot recompile only the
+// modules depending on a change. What is derived from the results of
+// templates, such as reviews cached by inventory.ResultCache, is invalidated
+// only for the changed template.
+package incremental
+
+import (
+	"context"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("incremental-driver")
+
+// NewDriver returns d, skipping puts of modules which are identical to those
+// already held by d. Only module sets which changed are recompiled into d.
+func NewDriver(d drivers.Driver) drivers.Driver {
+	return &driver{
+		Driver:     d,
+		modules:    make(map[string]string),
+		moduleSets: make(map[string][]string),
+	}
+}
+
+type driver struct {
+	drivers.Driver
+
+	// mux serializes changes to modules, so the sources recorded are always
+	// those held by the wrapped driver.
+	mux        sync.Mutex
+	modules    map[string]string
+	moduleSets map[string][]string
+}
+
+var _ drivers.Driver = &driver{}
+
+func (d *driver) PutModule(ctx context.Context, name string, src string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if cur, ok := d.modules[name]; ok && cur == src {
+		log.V(1).Info("skipping unchanged module", "name", name)
+		return nil
+	}
+	if err := d.Driver.PutModule(ctx, name, src); err != nil {
+		return err
+	}
+	d.modules[name] = src
+	return nil
+}
+
+func (d *driver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if cur, ok := d.moduleSets[namePrefix]; ok && equal(cur, srcs) {
+		log.V(1).Info("skipping unchanged module set", "prefix", namePrefix)
+		return nil
+	}
+	if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+		return err
+	}
+	d.moduleSets[namePrefix] = append([]string(nil), srcs...)
+	return nil
+}
+
+func (d *driver) DeleteModule(ctx context.Context, name string) (bool, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	deleted, err := d.Driver.DeleteModule(ctx, name)
+	if err != nil {
+		return deleted, err
+	}
+	delete(d.modules, name)
+	return deleted, nil
+}
+
+func (d *driver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	n, err := d.Driver.DeleteModules(ctx, namePrefix)
+	if err != nil {
+		return n, err
+	}
+	delete(d.moduleSets, namePrefix)
+	return n, nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
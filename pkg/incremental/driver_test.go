@@ -0,0 +1,131 @@
+package incremental
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+)
+
+// countingDriver counts the changes which reach it, failing them if err is set.
+type countingDriver struct {
+	drivers.Driver
+	puts    int
+	deletes int
+	err     error
+}
+
+func (d *countingDriver) PutModule(_ context.Context, _ string, _ string) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.puts++
+	return nil
+}
+
+func (d *countingDriver) PutModules(_ context.Context, _ string, _ []string) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.puts++
+	return nil
+}
+
+func (d *countingDriver) DeleteModule(_ context.Context, _ string) (bool, error) {
+	d.deletes++
+	return true, nil
+}
+
+func (d *countingDriver) DeleteModules(_ context.Context, _ string) (int, error) {
+	d.deletes++
+	return 1, nil
+}
+
+func TestPutModules(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingDriver{}
+	d := NewDriver(inner)
+
+	steps := []struct {
+		name     string
+		prefix   string
+		srcs     []string
+		wantPuts int
+	}{
+		{name: "new module set", prefix: "a", srcs: []string{"package a"}, wantPuts: 1},
+		{name: "unchanged module set", prefix: "a", srcs: []string{"package a"}, wantPuts: 1},
+		{name: "other module set", prefix: "b", srcs: []string{"package b"}, wantPuts: 2},
+		{name: "changed module", prefix: "a", srcs: []string{"package a\nx = 1"}, wantPuts: 3},
+		{name: "added module", prefix: "a", srcs: []string{"package a\nx = 1", "package lib"}, wantPuts: 4},
+		{name: "removed module", prefix: "a", srcs: []string{"package a\nx = 1"}, wantPuts: 5},
+		{name: "unchanged after change", prefix: "a", srcs: []string{"package a\nx = 1"}, wantPuts: 5},
+	}
+	for _, s := range steps {
+		if err := d.PutModules(ctx, s.prefix, s.srcs); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if inner.puts != s.wantPuts {
+			t.Errorf("%s: got %d puts, want %d", s.name, inner.puts, s.wantPuts)
+		}
+	}
+
+	if _, err := d.DeleteModules(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, "a", []string{"package a\nx = 1"}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.puts != 6 {
+		t.Errorf("got %d puts, want a deleted module set to be put again", inner.puts)
+	}
+}
+
+func TestPutModulesFailure(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingDriver{}
+	d := NewDriver(inner)
+
+	if err := d.PutModules(ctx, "a", []string{"package a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	inner.err = errors.New("compilation failed")
+	if err := d.PutModules(ctx, "a", []string{"package a\nbroken"}); err == nil {
+		t.Fatal("got no error from a failed put")
+	}
+
+	// The failed sources were not recorded, so retrying them reaches the driver.
+	inner.err = nil
+	if err := d.PutModules(ctx, "a", []string{"package a\nbroken"}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.puts != 2 {
+		t.Errorf("got %d puts, want 2", inner.puts)
+	}
+}
+
+func TestPutModule(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingDriver{}
+	d := NewDriver(inner)
+
+	for i := 0; i < 2; i++ {
+		if err := d.PutModule(ctx, "m", "package m"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.puts != 1 {
+		t.Errorf("got %d puts, want an unchanged module to be put once", inner.puts)
+	}
+
+	if _, err := d.DeleteModule(ctx, "m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModule(ctx, "m", "package m"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.puts != 2 {
+		t.Errorf("got %d puts, want a deleted module to be put again", inner.puts)
+	}
+}
@@ -0,0 +1,200 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// StatePath is the path serving the Snapshot.
+const StatePath = "/debug/state"
+
+const (
+	shutdownTimeout = 5 * time.Second
+	reviewTimeout   = 10 * time.Second
+)
+
+var (
+	// Addr is the address the introspection server binds to.
+	Addr = flag.String("introspection-addr", "", "(alpha) the address the introspection endpoint binds to, for example `127.0.0.1:8888`. Requests must carry a bearer token authorized to get the non-resource URL "+StatePath+". Served over TLS with the webhook certificates unless bound to a loopback address. Disabled if empty")
+
+	log = logf.Log.WithName("introspection")
+)
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+var _ manager.Runnable = &Server{}
+
+// Server serves the Snapshot of a State as JSON to authorized users. Callers
+// are authenticated with a TokenReview of their bearer token, and authorized
+// with a SubjectAccessReview for getting the request path. Unless it only
+// binds to a loopback address, the Server is served over TLS, so that bearer
+// tokens are never sent in cleartext over the pod network.
+type Server struct {
+	addr  string
+	certs Certs
+	state *State
+	mux   *http.ServeMux
+
+	tokenReviews   authenticationv1client.TokenReviewInterface
+	accessReviews  authorizationv1client.SubjectAccessReviewInterface
+	reviewDeadline time.Duration
+}
+
+// NewServer creates a Server for state, which binds to addr and serves the
+// Certs set with SetCerts.
+func NewServer(addr string, state *State, client kubernetes.Interface) *Server {
+	s := newServer(addr, state, client.AuthenticationV1().TokenReviews(), client.AuthorizationV1().SubjectAccessReviews())
+	s.certs = GetCerts()
+	return s
+}
+
+func newServer(addr string, state *State, tokenReviews authenticationv1client.TokenReviewInterface, accessReviews authorizationv1client.SubjectAccessReviewInterface) *Server {
+	s := &Server{
+		addr:           addr,
+		state:          state,
+		tokenReviews:   tokenReviews,
+		accessReviews:  accessReviews,
+		reviewDeadline: reviewTimeout,
+	}
+	s.mux = http.NewServeMux()
+	s.Handle(StatePath, http.HandlerFunc(s.serveState))
+	return s
+}
+
+// Handle serves h at path to authorized users, who must be allowed to get
+// path. It must be called before the Server is started.
+func (s *Server) Handle(path string, h http.Handler) {
+	s.mux.Handle(path, s.authorize(h))
+}
+
+// Start implements manager.Runnable.
+func (s *Server) Start(ctx context.Context) error {
+	loopback, err := isLoopback(s.addr)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s.mux}
+	if !loopback {
+		srv.TLSConfig = s.certs.serverConfig()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if loopback {
+			log.Info("serving introspection endpoint", "addr", ln.Addr().String(), "path", StatePath)
+			errCh <- srv.Serve(ln)
+			return
+		}
+		log.Info("serving introspection endpoint over TLS", "addr", ln.Addr().String(), "path", StatePath, "certDir", s.certs.Dir)
+		errCh <- srv.ServeTLS(ln, "", "")
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every pod
+// serves its own state.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) serveState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.state.Snapshot()); err != nil {
+		log.Error(err, "writing state")
+	}
+}
+
+// authorize only passes requests made with a bearer token which may get the
+// requested path through to next.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || token == r.Header.Get("Authorization") {
+			http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.reviewDeadline)
+		defer cancel()
+
+		tr, err := s.tokenReviews.Create(ctx, &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{Token: token},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "reviewing token")
+			http.Error(w, "unable to authenticate request", http.StatusInternalServerError)
+			return
+		}
+		if !tr.Status.Authenticated {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user := tr.Status.User
+		extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			extra[k] = authorizationv1.ExtraValue(v)
+		}
+		sar, err := s.accessReviews.Create(ctx, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   user.Username,
+				UID:    user.UID,
+				Groups: user.Groups,
+				Extra:  extra,
+				NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+					Path: r.URL.Path,
+					Verb: strings.ToLower(r.Method),
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			log.Error(err, "reviewing access")
+			http.Error(w, "unable to authorize request", http.StatusInternalServerError)
+			return
+		}
+		if !sar.Status.Allowed {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,148 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeTokenReviews struct {
+	users map[string]string
+	err   error
+}
+
+func (f *fakeTokenReviews) Create(_ context.Context, tr *authenticationv1.TokenReview, _ metav1.CreateOptions) (*authenticationv1.TokenReview, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	user, ok := f.users[tr.Spec.Token]
+	tr.Status.Authenticated = ok
+	tr.Status.User.Username = user
+	return tr, nil
+}
+
+type fakeAccessReviews struct {
+	allowed map[string]bool
+	gotPath string
+	gotVerb string
+}
+
+func (f *fakeAccessReviews) Create(_ context.Context, sar *authorizationv1.SubjectAccessReview, _ metav1.CreateOptions) (*authorizationv1.SubjectAccessReview, error) {
+	f.gotPath = sar.Spec.NonResourceAttributes.Path
+	f.gotVerb = sar.Spec.NonResourceAttributes.Verb
+	sar.Status.Allowed = f.allowed[sar.Spec.User]
+	return sar, nil
+}
+
+func TestServer(t *testing.T) {
+	state := New()
+	state.SetTemplate(Template{Name: "foo", Kind: "Foo", Status: TemplateActive})
+
+	tcs := []struct {
+		name         string
+		header       string
+		reviewErr    error
+		wantCode     int
+		wantSnapshot bool
+	}{
+		{
+			name:     "no token",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "not a bearer token",
+			header:   "Basic YWRtaW46YWRtaW4=",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "unknown token",
+			header:   "Bearer unknown",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "token review fails",
+			header:    "Bearer admin-token",
+			reviewErr: errors.New("connection refused"),
+			wantCode:  http.StatusInternalServerError,
+		},
+		{
+			name:     "forbidden",
+			header:   "Bearer viewer-token",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "allowed",
+			header:       "Bearer admin-token",
+			wantCode:     http.StatusOK,
+			wantSnapshot: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			accessReviews := &fakeAccessReviews{allowed: map[string]bool{"admin": true}}
+			tokenReviews := &fakeTokenReviews{
+				users: map[string]string{"admin-token": "admin", "viewer-token": "viewer"},
+				err:   tc.reviewErr,
+			}
+			s := newServer("", state, tokenReviews, accessReviews)
+
+			req := httptest.NewRequest(http.MethodGet, StatePath, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			s.mux.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tc.wantCode, rec.Body.String())
+			}
+			if !tc.wantSnapshot {
+				return
+			}
+			if accessReviews.gotPath != StatePath || accessReviews.gotVerb != "get" {
+				t.Errorf("got access review for %s %s, want get %s", accessReviews.gotVerb, accessReviews.gotPath, StatePath)
+			}
+			snap := &Snapshot{}
+			if err := json.Unmarshal(rec.Body.Bytes(), snap); err != nil {
+				t.Fatal(err)
+			}
+			if len(snap.Templates) != 1 || snap.Templates[0].Name != "foo" {
+				t.Errorf("got templates %v, want foo", snap.Templates)
+			}
+		})
+	}
+}
+
+func TestServerHandle(t *testing.T) {
+	accessReviews := &fakeAccessReviews{allowed: map[string]bool{"admin": true}}
+	tokenReviews := &fakeTokenReviews{users: map[string]string{"admin-token": "admin"}}
+	s := newServer("", New(), tokenReviews, accessReviews)
+	s.Handle("/debug/other", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/other", nil)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d without a token, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if accessReviews.gotPath != "/debug/other" {
+		t.Errorf("got access review for %s, want /debug/other", accessReviews.gotPath)
+	}
+}
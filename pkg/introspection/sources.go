@@ -0,0 +1,20 @@
+package introspection
+
+import "github.com/open-policy-agent/gatekeeper/pkg/mutation"
+
+// MutatorSource lists the Mutators held by system.
+func MutatorSource(system *mutation.System) func() []Mutator {
+	return func() []Mutator {
+		states := system.MutatorStates()
+		mutators := make([]Mutator, len(states))
+		for i, st := range states {
+			mutators[i] = Mutator{
+				Kind:      st.ID.Kind,
+				Namespace: st.ID.Namespace,
+				Name:      st.ID.Name,
+				Conflicts: st.Conflicts,
+			}
+		}
+		return mutators
+	}
+}
@@ -0,0 +1,192 @@
+package introspection
+
+import (
+	"sort"
+	"sync"
+)
+
+// TemplateStatus is whether a ConstraintTemplate was ingested.
+type TemplateStatus string
+
+const (
+	// TemplateActive denotes a template compiled into the constraint framework.
+	TemplateActive TemplateStatus = "active"
+	// TemplateError denotes a template which failed to be ingested.
+	TemplateError TemplateStatus = "error"
+)
+
+// Template is a ConstraintTemplate as last handled by this pod.
+type Template struct {
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	Generation int64          `json:"generation"`
+	Status     TemplateStatus `json:"status"`
+	// Error is why the template failed to be ingested.
+	Error string `json:"error,omitempty"`
+}
+
+// Constraint is a Constraint as last ingested by this pod.
+type Constraint struct {
+	Kind              string `json:"kind"`
+	Name              string `json:"name"`
+	Generation        int64  `json:"generation"`
+	EnforcementAction string `json:"enforcementAction"`
+}
+
+// Mutator is a Mutator held by the mutation System.
+type Mutator struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Conflicts is true if the Mutator is not applied because its schema
+	// conflicts with another Mutator.
+	Conflicts bool `json:"conflicts"`
+}
+
+// SyncedKind is the number of objects of a kind replicated into the
+// constraint framework's cache.
+type SyncedKind struct {
+	Kind    string `json:"kind"`
+	Objects int    `json:"objects"`
+	Errors  int    `json:"errors"`
+}
+
+// Snapshot is the policy state held in memory by a pod.
+type Snapshot struct {
+	Templates   []Template   `json:"templates"`
+	Constraints []Constraint `json:"constraints"`
+	Mutators    []Mutator    `json:"mutators"`
+	Sync        []SyncedKind `json:"sync"`
+}
+
+// State records the policy state of a pod as it is ingested, so it can be
+// compared with what is stored in the API server.
+type State struct {
+	mux         sync.RWMutex
+	templates   map[string]Template
+	constraints map[string]Constraint
+	mutators    func() []Mutator
+	sync        func() []SyncedKind
+}
+
+var state = New()
+
+// Get returns the State of this process.
+func Get() *State {
+	return state
+}
+
+// New returns an empty State.
+func New() *State {
+	return &State{
+		templates:   make(map[string]Template),
+		constraints: make(map[string]Constraint),
+	}
+}
+
+// SetTemplate records the outcome of ingesting a ConstraintTemplate.
+func (s *State) SetTemplate(t Template) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.templates[t.Name] = t
+}
+
+// RemoveTemplate forgets the ConstraintTemplate called name.
+func (s *State) RemoveTemplate(name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.templates, name)
+}
+
+// SetConstraint records that a Constraint was ingested.
+func (s *State) SetConstraint(c Constraint) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.constraints[constraintKey(c.Kind, c.Name)] = c
+}
+
+// RemoveConstraint forgets a Constraint.
+func (s *State) RemoveConstraint(kind, name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.constraints, constraintKey(kind, name))
+}
+
+// SetMutatorSource sets the function listing the Mutators held by the
+// mutation System.
+func (s *State) SetMutatorSource(f func() []Mutator) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.mutators = f
+}
+
+// SetSyncSource sets the function listing the kinds of replicated data.
+func (s *State) SetSyncSource(f func() []SyncedKind) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.sync = f
+}
+
+// SyncedKinds returns the kinds of replicated data, or nil if no sync source
+// is set.
+func (s *State) SyncedKinds() []SyncedKind {
+	s.mux.RLock()
+	sync := s.sync
+	s.mux.RUnlock()
+	if sync == nil {
+		return nil
+	}
+	return sync()
+}
+
+// Snapshot returns the current State, sorted so it can be easily diffed.
+func (s *State) Snapshot() *Snapshot {
+	s.mux.RLock()
+	snap := &Snapshot{
+		Templates:   make([]Template, 0, len(s.templates)),
+		Constraints: make([]Constraint, 0, len(s.constraints)),
+		Mutators:    []Mutator{},
+		Sync:        []SyncedKind{},
+	}
+	for _, t := range s.templates {
+		snap.Templates = append(snap.Templates, t)
+	}
+	for _, c := range s.constraints {
+		snap.Constraints = append(snap.Constraints, c)
+	}
+	mutators, sync := s.mutators, s.sync
+	s.mux.RUnlock()
+
+	// The sources have their own locking, so are called without holding s.mux.
+	if mutators != nil {
+		snap.Mutators = append(snap.Mutators, mutators()...)
+	}
+	if sync != nil {
+		snap.Sync = append(snap.Sync, sync()...)
+	}
+
+	sort.Slice(snap.Templates, func(i, j int) bool {
+		return snap.Templates[i].Name < snap.Templates[j].Name
+	})
+	sort.Slice(snap.Constraints, func(i, j int) bool {
+		return constraintKey(snap.Constraints[i].Kind, snap.Constraints[i].Name) < constraintKey(snap.Constraints[j].Kind, snap.Constraints[j].Name)
+	})
+	sort.Slice(snap.Mutators, func(i, j int) bool {
+		a, b := snap.Mutators[i], snap.Mutators[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	sort.Slice(snap.Sync, func(i, j int) bool {
+		return snap.Sync[i].Kind < snap.Sync[j].Kind
+	})
+	return snap
+}
+
+func constraintKey(kind, name string) string {
+	return kind + "/" + name
+}
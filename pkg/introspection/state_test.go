@@ -0,0 +1,51 @@
+package introspection
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := New()
+	s.SetTemplate(Template{Name: "k8srequiredlabels", Kind: "K8sRequiredLabels", Generation: 2, Status: TemplateActive})
+	s.SetTemplate(Template{Name: "broken", Kind: "Broken", Generation: 1, Status: TemplateError, Error: "rego_parse_error"})
+	s.SetTemplate(Template{Name: "removed", Kind: "Removed"})
+	s.RemoveTemplate("removed")
+
+	s.SetConstraint(Constraint{Kind: "K8sRequiredLabels", Name: "b", Generation: 1, EnforcementAction: "deny"})
+	s.SetConstraint(Constraint{Kind: "K8sRequiredLabels", Name: "a", Generation: 1, EnforcementAction: "dryrun"})
+	s.SetConstraint(Constraint{Kind: "K8sRequiredLabels", Name: "a", Generation: 3, EnforcementAction: "deny"})
+	s.SetConstraint(Constraint{Kind: "K8sRequiredLabels", Name: "c"})
+	s.RemoveConstraint("K8sRequiredLabels", "c")
+
+	s.SetMutatorSource(func() []Mutator {
+		return []Mutator{{Kind: "ModifySet", Name: "b"}, {Kind: "Assign", Name: "a", Conflicts: true}}
+	})
+	s.SetSyncSource(func() []SyncedKind {
+		return []SyncedKind{{Kind: "Pod", Objects: 3}, {Kind: "Namespace", Objects: 2, Errors: 1}}
+	})
+
+	want := &Snapshot{
+		Templates: []Template{
+			{Name: "broken", Kind: "Broken", Generation: 1, Status: TemplateError, Error: "rego_parse_error"},
+			{Name: "k8srequiredlabels", Kind: "K8sRequiredLabels", Generation: 2, Status: TemplateActive},
+		},
+		Constraints: []Constraint{
+			{Kind: "K8sRequiredLabels", Name: "a", Generation: 3, EnforcementAction: "deny"},
+			{Kind: "K8sRequiredLabels", Name: "b", Generation: 1, EnforcementAction: "deny"},
+		},
+		Mutators: []Mutator{{Kind: "Assign", Name: "a", Conflicts: true}, {Kind: "ModifySet", Name: "b"}},
+		Sync:     []SyncedKind{{Kind: "Namespace", Objects: 2, Errors: 1}, {Kind: "Pod", Objects: 3}},
+	}
+	if diff := cmp.Diff(want, s.Snapshot()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestSnapshotEmpty(t *testing.T) {
+	want := &Snapshot{Templates: []Template{}, Constraints: []Constraint{}, Mutators: []Mutator{}, Sync: []SyncedKind{}}
+	if diff := cmp.Diff(want, New().Snapshot()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,279 @@
+package introspection
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// Certs locates the certificates the introspection endpoint is served with:
+// those of the webhook, as written by the certificate rotator.
+type Certs struct {
+	// Dir holds the tls.crt and tls.key the endpoint is served with, and the
+	// ca.crt they are issued by.
+	Dir string
+	// ServerName is the DNS name the serving certificate is issued for.
+	ServerName string
+
+	keyPair *keyPair
+	roots   *roots
+}
+
+// NewCerts returns the Certs in dir, issued for serverName.
+func NewCerts(dir, serverName string) Certs {
+	return Certs{Dir: dir, ServerName: serverName, keyPair: &keyPair{dir: dir}, roots: &roots{dir: dir}}
+}
+
+var (
+	certsMux sync.RWMutex
+	certs    Certs
+)
+
+// SetCerts sets the Certs every pod serves its introspection endpoint with.
+// It must be called before the endpoint is served or fetched from.
+func SetCerts(c Certs) {
+	certsMux.Lock()
+	defer certsMux.Unlock()
+	certs = c
+}
+
+// GetCerts returns the Certs set with SetCerts.
+func GetCerts() Certs {
+	certsMux.RLock()
+	defer certsMux.RUnlock()
+	return certs
+}
+
+// Validate returns an error if the introspection endpoint is served to other
+// pods, which requires certificates, and dir does not exist.
+func Validate(dir string) error {
+	if *Addr == "" {
+		return nil
+	}
+	loopback, err := isLoopback(*Addr)
+	if err != nil {
+		return fmt.Errorf("parsing --introspection-addr: %w", err)
+	}
+	if loopback {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("--introspection-addr %s is served over TLS with the webhook certificates: %w", *Addr, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("--introspection-addr %s is served over TLS with the webhook certificates, but %s is not a directory", *Addr, dir)
+	}
+	return nil
+}
+
+// isLoopback returns whether addr only binds to the loopback interface.
+func isLoopback(addr string) (bool, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false, err
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback()), nil
+}
+
+// serverConfig returns the TLS configuration serving the certificate in Dir,
+// which is reloaded whenever it is rotated.
+func (c Certs) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.keyPair.get()
+		},
+	}
+}
+
+// PeerClient returns a client for the introspection endpoint of other pods,
+// authenticated with the bearer token of cfg. The client refuses to send
+// requests other than over https, and only to peers serving a certificate
+// issued for ServerName by the CA in Dir. Peers are reached by pod IP, so
+// ServerName is verified rather than the host.
+func (c Certs) PeerClient(cfg *rest.Config, timeout time.Duration) (*http.Client, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = c.clientConfig()
+	rt, err := transport.NewBearerAuthWithRefreshRoundTripper(cfg.BearerToken, cfg.BearerTokenFile, base)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: &httpsOnly{next: rt}, Timeout: timeout}, nil
+}
+
+// clientConfig returns the TLS configuration verifying peers against the CA
+// in Dir. The CA is verified against in VerifyConnection rather than set as
+// RootCAs, so that it is reloaded whenever it is rotated.
+func (c Certs) clientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: true, // #nosec G402 verified by VerifyConnection
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("peer presented no certificate")
+			}
+			pool, err := c.roots.get()
+			if err != nil {
+				return err
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         pool,
+				Intermediates: intermediates,
+				DNSName:       c.ServerName,
+			})
+			return err
+		},
+	}
+}
+
+// httpsOnly refuses to send requests, and the credentials added to them by
+// next, other than over https.
+type httpsOnly struct {
+	next http.RoundTripper
+}
+
+func (t *httpsOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("refusing to send credentials to %s over %s", req.URL.Host, req.URL.Scheme)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Sign signs data with the serving key in Dir, returning the signature and
+// the DER-encoded serving certificate to verify it with.
+func (c Certs) Sign(data []byte) (signature, cert []byte, err error) {
+	keyPair, err := c.keyPair.get()
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported serving key %T", keyPair.PrivateKey)
+	}
+	digest := sha256.Sum256(data)
+	signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signature, keyPair.Certificate[0], nil
+}
+
+// Verify returns an error unless signature is the signature of data by the
+// key of cert, as returned by Sign, and cert is a serving certificate for
+// ServerName issued by the CA in Dir.
+func (c Certs) Verify(data, signature, cert []byte) error {
+	parsed, err := x509.ParseCertificate(cert)
+	if err != nil {
+		return err
+	}
+	pool, err := c.roots.get()
+	if err != nil {
+		return err
+	}
+	if _, err := parsed.Verify(x509.VerifyOptions{Roots: pool, DNSName: c.ServerName}); err != nil {
+		return err
+	}
+	var algorithm x509.SignatureAlgorithm
+	switch parsed.PublicKey.(type) {
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	default:
+		return fmt.Errorf("unsupported serving key %T", parsed.PublicKey)
+	}
+	return parsed.CheckSignature(algorithm, data, signature)
+}
+
+// keyPair loads the serving certificate in a directory, and loads it again
+// once it is rotated.
+type keyPair struct {
+	dir string
+
+	mux     sync.Mutex
+	modTime time.Time
+	cert    *tls.Certificate
+}
+
+func (k *keyPair) get() (*tls.Certificate, error) {
+	if k == nil {
+		return nil, errors.New("no serving certificate configured")
+	}
+	certFile := filepath.Join(k.dir, "tls.crt")
+	info, err := os.Stat(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if k.cert != nil && info.ModTime().Equal(k.modTime) {
+		return k.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, filepath.Join(k.dir, "tls.key"))
+	if err != nil {
+		return nil, err
+	}
+	k.cert = &cert
+	k.modTime = info.ModTime()
+	return k.cert, nil
+}
+
+// roots loads the CA in a directory, and loads it again once it is rotated.
+type roots struct {
+	dir string
+
+	mux     sync.Mutex
+	modTime time.Time
+	pool    *x509.CertPool
+}
+
+func (r *roots) get() (*x509.CertPool, error) {
+	if r == nil {
+		return nil, errors.New("no CA configured")
+	}
+	caFile := filepath.Join(r.dir, "ca.crt")
+	info, err := os.Stat(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.pool != nil && info.ModTime().Equal(r.modTime) {
+		return r.pool, nil
+	}
+	b, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	r.pool = pool
+	r.modTime = info.ModTime()
+	return r.pool, nil
+}
@@ -0,0 +1,304 @@
+package introspection
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+const testServerName = "gatekeeper-webhook-service.gatekeeper-system.svc"
+
+// testCA issues serving certificates, as the certificate rotator does.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gatekeeper-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// write writes ca.crt, and a tls.crt and tls.key issued for dnsName with
+// serial, to dir.
+func (ca *testCA) write(t *testing.T, dir, dnsName string, serial int64) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"ca.crt":  ca.pem,
+		"tls.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"tls.key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Rotated certificates must be told apart by their modification time.
+	mod := time.Now().Add(time.Duration(serial) * time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "tls.crt"), mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveTLS serves h with the certificate of certs on a loopback port, and
+// returns its address.
+func serveTLS(t *testing.T, certs Certs, h http.Handler) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", certs.serverConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: h}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		srv.Close()
+	})
+	return ln.Addr().String()
+}
+
+func TestServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	ca.write(t, dir, testServerName, 2)
+	certs := NewCerts(dir, testServerName)
+
+	addr := serveTLS(t, certs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	serial := func() int64 {
+		t.Helper()
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots, ServerName: testServerName, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if got := serial(); got != 2 {
+		t.Errorf("got certificate %d, want 2", got)
+	}
+	ca.write(t, dir, testServerName, 3)
+	if got := serial(); got != 3 {
+		t.Errorf("got certificate %d once rotated, want 3", got)
+	}
+}
+
+func TestServerConfigWithoutCerts(t *testing.T) {
+	addr := serveTLS(t, NewCerts(t.TempDir(), testServerName), http.NotFoundHandler())
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true}) // #nosec G402
+	if err == nil {
+		conn.Close()
+		t.Fatal("got a handshake without a certificate to serve")
+	}
+}
+
+func TestPeerClient(t *testing.T) {
+	ca := newTestCA(t)
+	clientDir := t.TempDir()
+	ca.write(t, clientDir, testServerName, 2)
+	client, err := NewCerts(clientDir, testServerName).PeerClient(&rest.Config{BearerToken: "token"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotToken string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// serve returns the address of a peer serving a certificate for dnsName
+	// issued by ca.
+	serve := func(ca *testCA, dnsName string) string {
+		dir := t.TempDir()
+		ca.write(t, dir, dnsName, 2)
+		return serveTLS(t, NewCerts(dir, dnsName), h)
+	}
+	plain := httptest.NewServer(h)
+	defer plain.Close()
+
+	tcs := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name: "peer",
+			url:  "https://" + serve(ca, testServerName),
+		},
+		{
+			name:    "peer issued by another CA",
+			url:     "https://" + serve(newTestCA(t), testServerName),
+			wantErr: true,
+		},
+		{
+			name:    "peer issued for another name",
+			url:     "https://" + serve(ca, "other.gatekeeper-system.svc"),
+			wantErr: true,
+		},
+		{
+			name:    "plain http",
+			url:     plain.URL,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			gotToken = ""
+			resp, err := client.Get(tc.url + StatePath)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tc.wantErr)
+			}
+			wantToken := "Bearer token"
+			if tc.wantErr {
+				wantToken = ""
+			}
+			if gotToken != wantToken {
+				t.Errorf("peer got Authorization %q, want %q", gotToken, wantToken)
+			}
+		})
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	ca.write(t, dir, testServerName, 2)
+	certs := NewCerts(dir, testServerName)
+	data := []byte(`{"version":"v1"}`)
+
+	signature, cert, err := certs.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.Verify(data, signature, cert); err != nil {
+		t.Errorf("got error verifying signed data: %v", err)
+	}
+	if err := certs.Verify([]byte(`{"version":"v2"}`), signature, cert); err == nil {
+		t.Error("got no error verifying changed data")
+	}
+
+	otherDir := t.TempDir()
+	newTestCA(t).write(t, otherDir, testServerName, 2)
+	otherSignature, otherCert, err := NewCerts(otherDir, testServerName).Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.Verify(data, otherSignature, otherCert); err == nil {
+		t.Error("got no error verifying data signed by a key issued by another CA")
+	}
+	if err := certs.Verify(data, otherSignature, cert); err == nil {
+		t.Error("got no error verifying data signed by another key")
+	}
+
+	nameDir := t.TempDir()
+	ca.write(t, nameDir, "other.gatekeeper-system.svc", 2)
+	nameSignature, nameCert, err := NewCerts(nameDir, "other.gatekeeper-system.svc").Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.Verify(data, nameSignature, nameCert); err == nil {
+		t.Error("got no error verifying data signed by a key issued for another name")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name    string
+		addr    string
+		dir     string
+		wantErr bool
+	}{
+		{name: "disabled", dir: filepath.Join(dir, "missing")},
+		{name: "loopback without certs", addr: "127.0.0.1:8888", dir: filepath.Join(dir, "missing")},
+		{name: "localhost without certs", addr: "localhost:8888", dir: filepath.Join(dir, "missing")},
+		{name: "pod network with certs", addr: ":8888", dir: dir},
+		{name: "pod network without certs", addr: ":8888", dir: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "certs not a directory", addr: ":8888", dir: file, wantErr: true},
+		{name: "invalid address", addr: "8888", dir: dir, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			old := *Addr
+			*Addr = tc.addr
+			defer func() { *Addr = old }()
+
+			if err := Validate(tc.dir); (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// LookupBuiltin is the name of the Rego builtin returning the replicated
+// objects of a kind whose field holds a value, for example
+//
+//	gatekeeper.inventory.lookup("networking.k8s.io/v1", "Ingress", "spec.rules[_].host", host)
+//
+// It is answered from an Index rather than by scanning data.inventory.
+const LookupBuiltin = "gatekeeper.inventory.lookup"
+
+func init() {
+	rego.RegisterBuiltin4(&rego.Function{
+		Name: LookupBuiltin,
+		Decl: types.NewFunction(
+			types.Args(types.S, types.S, types.S, types.A),
+			types.NewArray(nil, types.A),
+		),
+	}, lookup)
+}
+
+func lookup(bctx rego.BuiltinContext, apiVersion, kind, field, value *ast.Term) (*ast.Term, error) {
+	idx := fromContext(bctx.Context)
+	if idx == nil {
+		return nil, errors.New("replicated objects are not indexed by this driver")
+	}
+	gvStr, ok := apiVersion.Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("apiVersion must be a string, not %v", apiVersion)
+	}
+	kindStr, ok := kind.Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("kind must be a string, not %v", kind)
+	}
+	fieldStr, ok := field.Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("field must be a string, not %v", field)
+	}
+	gv, err := schema.ParseGroupVersion(string(gvStr))
+	if err != nil {
+		return nil, err
+	}
+	v, err := ast.JSON(value.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, hit, err := idx.lookup(gv.WithKind(string(kindStr)), string(fieldStr), v)
+	if err != nil {
+		return nil, err
+	}
+	if hit {
+		querystats.CountCacheHit(bctx.Context)
+	}
+	result, err := ast.InterfaceToValue(objects)
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewTerm(result), nil
+}
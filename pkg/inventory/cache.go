@@ -0,0 +1,410 @@
+package inventory
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/builtins"
+	"github.com/open-policy-agent/opa/ast"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("review-cache")
+
+// CacheSize is the number of reviews a ResultCache holds.
+var CacheSize = flag.Int("review-cache-size", 0, "(alpha) the number of reviews whose results are cached, keyed by the reviewed object, so that reviewing an unchanged object again does not evaluate Rego. A change to the objects replicated into data.inventory only invalidates the reviews of constraints whose templates read the changed kind. Reviews of constraints whose templates call nondeterministic or custom builtins are not cached. Disabled if 0")
+
+var (
+	// reviewPath matches the path the constraint framework queries to review
+	// an object.
+	reviewPath = regexp.MustCompile(`^hooks\["([^"]+)"\]\.violation$`)
+	// templatePrefix matches the name prefix of the modules the constraint
+	// framework puts for a template.
+	templatePrefix = regexp.MustCompile(`^templates\["([^"]+)"\]\["([^"]+)"\]$`)
+)
+
+// namespaceGVK is the kind of the Namespaces the target reads from
+// data.inventory to match constraints with a namespaceSelector.
+var namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+
+// nondeterministic are the OPA builtins which may return different results
+// for the same arguments. Custom builtins, which may call external data
+// providers, are also treated as nondeterministic.
+var nondeterministic = map[string]bool{
+	"http.send":          true,
+	"net.lookup_ip_addr": true,
+	"opa.runtime":        true,
+	"rand.intn":          true,
+	"time.now_ns":        true,
+	"uuid.rfc4122":       true,
+}
+
+// ResultCache caches the responses of reviews, keyed by the reviewed input.
+// A response is invalidated when a constraint changes, and when the template
+// of a constraint matching the input changes or objects of a kind it reads are
+// replicated or deleted. Changes to other templates and kinds leave it
+// cached.
+type ResultCache struct {
+	max        int
+	dependents *Dependents
+
+	mux sync.Mutex
+	// generation changes whenever responses are invalidated, so that a
+	// response evaluated across an invalidation is not cached.
+	generation uint64
+	entries    map[string]*list.Element
+	// order holds the *resultEntry of every response, most recently used
+	// first.
+	order *list.List
+	// uncacheable are the kinds of the templates calling nondeterministic
+	// builtins.
+	uncacheable map[string]bool
+}
+
+type resultEntry struct {
+	key string
+	// response is the JSON encoding of the response, decoded for every hit
+	// so that callers may change it.
+	response []byte
+	// templates are the kinds of the templates of the constraints matching
+	// the input.
+	templates []string
+}
+
+// NewResultCache returns a ResultCache holding max responses, which are
+// invalidated by the changes idx is notified of.
+func NewResultCache(idx *Index, max int) *ResultCache {
+	c := &ResultCache{
+		max:         max,
+		dependents:  NewDependents(),
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+		uncacheable: make(map[string]bool),
+	}
+	invalidate := c.dependents.Hook(func(_ schema.GroupVersionKind, templates []string) {
+		c.invalidate(templates)
+	})
+	idx.OnChange(func(gvk schema.GroupVersionKind) {
+		if gvk == namespaceGVK {
+			// Namespaces are read to match constraints, whatever their
+			// template.
+			c.flush()
+			return
+		}
+		invalidate(gvk)
+	})
+	return c
+}
+
+// Wrap returns d, answering reviews from c. d must be wrapped by the Index of
+// c, so that c is notified of changes to the inventory.
+func (c *ResultCache) Wrap(d drivers.Driver) drivers.Driver {
+	return &cachingDriver{Driver: d, cache: c}
+}
+
+func (c *ResultCache) get(key string) (*types.Response, bool) {
+	c.mux.Lock()
+	e, ok := c.entries[key]
+	var b []byte
+	if ok {
+		c.order.MoveToFront(e)
+		b = e.Value.(*resultEntry).response
+	}
+	c.mux.Unlock()
+	if !ok {
+		return nil, false
+	}
+	resp := &types.Response{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, false
+	}
+	return resp, true
+}
+
+// put caches resp for key, unless responses were invalidated since
+// generation or a template in templates is uncacheable.
+func (c *ResultCache) put(key string, generation uint64, resp *types.Response, templates []string) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if generation != c.generation {
+		return
+	}
+	for _, t := range templates {
+		if c.uncacheable[t] {
+			return
+		}
+	}
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&resultEntry{key: key, response: b, templates: templates})
+	for c.order.Len() > c.max {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.entries, e.Value.(*resultEntry).key)
+	}
+}
+
+// currentGeneration returns the generation responses evaluated now are
+// cached with.
+func (c *ResultCache) currentGeneration() uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.generation
+}
+
+// invalidate drops the responses of constraints of templates.
+func (c *ResultCache) invalidate(templates []string) {
+	invalid := make(map[string]bool, len(templates))
+	for _, t := range templates {
+		invalid[t] = true
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation++
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*resultEntry)
+		for _, t := range entry.templates {
+			if invalid[t] {
+				c.order.Remove(e)
+				delete(c.entries, entry.key)
+				break
+			}
+		}
+		e = next
+	}
+}
+
+// flush drops every response.
+func (c *ResultCache) flush() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation++
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// putTemplate records what the modules srcs of the template of kind read,
+// and whether they call nondeterministic builtins. A template without modules
+// is forgotten.
+func (c *ResultCache) putTemplate(target, kind string, srcs []string) {
+	if len(srcs) == 0 {
+		c.dependents.Remove(kind)
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		delete(c.uncacheable, kind)
+		return
+	}
+	usage, err := ExtractTargets([]templates.Target{{Target: target, Rego: srcs[0], Libs: srcs[1:]}})
+	if err != nil {
+		// Reads nothing can be known about may read any kind.
+		usage = &Usage{Dynamic: []string{fmt.Sprintf("%s: %v", kind, err)}}
+	}
+	c.dependents.Set(kind, usage)
+	deterministic := isDeterministic(srcs)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if deterministic {
+		delete(c.uncacheable, kind)
+	} else {
+		c.uncacheable[kind] = true
+	}
+}
+
+// isDeterministic returns whether the modules srcs call no nondeterministic
+// or custom builtins.
+func isDeterministic(srcs []string) bool {
+	calls := make(map[string]bool, len(nondeterministic))
+	for name := range nondeterministic {
+		calls[name] = true
+	}
+	for _, name := range builtins.Registered() {
+		calls[name] = true
+	}
+	for _, src := range srcs {
+		m, err := ast.ParseModule("", src)
+		if err != nil {
+			return false
+		}
+		if m == nil {
+			continue
+		}
+		deterministic := true
+		ast.NewGenericVisitor(func(x interface{}) bool {
+			switch v := x.(type) {
+			case *ast.Expr:
+				if v.IsCall() && calls[v.Operator().String()] {
+					deterministic = false
+				}
+			case ast.Call:
+				if calls[v[0].String()] {
+					deterministic = false
+				}
+			}
+			return !deterministic
+		}).Walk(m)
+		if !deterministic {
+			return false
+		}
+	}
+	return true
+}
+
+// kindsModule returns the kinds of the constraints of a target matching the
+// input as the messages of results, as constraints are decoded into results
+// which only keep their metadata.
+const kindsModule = `package gatekeeper.review_cache[%[1]q]
+
+matching_kinds[{"msg": constraint.kind}] {
+	constraint := data.hooks[%[1]q].library.matching_constraints[_]
+}`
+
+// cachingDriver answers reviews from a ResultCache.
+type cachingDriver struct {
+	drivers.Driver
+	cache *ResultCache
+
+	kindsMux sync.Mutex
+	// kindsTargets are the targets whose kindsModule is put.
+	kindsTargets map[string]bool
+}
+
+func (d *cachingDriver) PutModule(ctx context.Context, name string, src string) error {
+	defer d.cache.flush()
+	return d.Driver.PutModule(ctx, name, src)
+}
+
+func (d *cachingDriver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	match := templatePrefix.FindStringSubmatch(namePrefix)
+	if match == nil {
+		defer d.cache.flush()
+		return d.Driver.PutModules(ctx, namePrefix, srcs)
+	}
+	// Only the reviews matched by constraints of the template change.
+	defer d.cache.invalidate([]string{match[2]})
+	if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+		return err
+	}
+	d.cache.putTemplate(match[1], match[2], srcs)
+	return nil
+}
+
+func (d *cachingDriver) DeleteModule(ctx context.Context, name string) (bool, error) {
+	defer d.cache.flush()
+	return d.Driver.DeleteModule(ctx, name)
+}
+
+func (d *cachingDriver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	match := templatePrefix.FindStringSubmatch(namePrefix)
+	if match == nil {
+		defer d.cache.flush()
+		return d.Driver.DeleteModules(ctx, namePrefix)
+	}
+	defer d.cache.invalidate([]string{match[2]})
+	n, err := d.Driver.DeleteModules(ctx, namePrefix)
+	if err != nil {
+		return n, err
+	}
+	d.cache.putTemplate(match[1], match[2], nil)
+	return n, nil
+}
+
+func (d *cachingDriver) PutData(ctx context.Context, path string, data interface{}) error {
+	// Changes to the inventory are invalidated by the Index, other data,
+	// such as constraints, may change the result of any review.
+	if _, ok := inventoryPath(path); !ok {
+		defer d.cache.flush()
+	}
+	return d.Driver.PutData(ctx, path, data)
+}
+
+func (d *cachingDriver) DeleteData(ctx context.Context, path string) (bool, error) {
+	if _, ok := inventoryPath(path); !ok {
+		defer d.cache.flush()
+	}
+	return d.Driver.DeleteData(ctx, path)
+}
+
+func (d *cachingDriver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	cfg := &drivers.QueryCfg{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	match := reviewPath.FindStringSubmatch(path)
+	if match == nil || cfg.TracingEnabled {
+		return d.Driver.Query(ctx, path, input, opts...)
+	}
+	b, err := json.Marshal(input)
+	if err != nil {
+		return d.Driver.Query(ctx, path, input, opts...)
+	}
+	sum := sha256.Sum256(append([]byte(path), b...))
+	key := hex.EncodeToString(sum[:])
+	if resp, ok := d.cache.get(key); ok {
+		return resp, nil
+	}
+
+	generation := d.cache.currentGeneration()
+	resp, err := d.Driver.Query(ctx, path, input, opts...)
+	if err != nil {
+		return resp, err
+	}
+	kinds, err := d.matchingKinds(ctx, match[1], input)
+	if err != nil {
+		log.V(1).Info("unable to find the constraints matching a review, it is not cached", "error", err.Error())
+		return resp, nil
+	}
+	d.cache.put(key, generation, resp, kinds)
+	return resp, nil
+}
+
+// matchingKinds returns the kinds of the constraints of target matching
+// input, which name the templates whose changes invalidate its review.
+func (d *cachingDriver) matchingKinds(ctx context.Context, target string, input interface{}) ([]string, error) {
+	if err := d.putKindsModule(ctx, target); err != nil {
+		return nil, err
+	}
+	resp, err := d.Driver.Query(ctx, fmt.Sprintf(`gatekeeper.review_cache[%q].matching_kinds`, target), input)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make([]string, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		kinds = append(kinds, r.Msg)
+	}
+	return kinds, nil
+}
+
+// putKindsModule puts the kindsModule of target, unless it is already put.
+func (d *cachingDriver) putKindsModule(ctx context.Context, target string) error {
+	d.kindsMux.Lock()
+	defer d.kindsMux.Unlock()
+	if d.kindsTargets[target] {
+		return nil
+	}
+	if err := d.Driver.PutModule(ctx, fmt.Sprintf("gatekeeper.review_cache.%s", target), fmt.Sprintf(kindsModule, target)); err != nil {
+		return err
+	}
+	if d.kindsTargets == nil {
+		d.kindsTargets = make(map[string]bool)
+	}
+	d.kindsTargets[target] = true
+	return nil
+}
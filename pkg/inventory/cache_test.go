@@ -0,0 +1,194 @@
+package inventory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// countingDriver counts the reviews it evaluates.
+type countingDriver struct {
+	drivers.Driver
+	reviews int
+}
+
+func (d *countingDriver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	if reviewPath.MatchString(path) {
+		d.reviews++
+	}
+	return d.Driver.Query(ctx, path, input, opts...)
+}
+
+func newCacheTemplate(kind, rego string) *templates.ConstraintTemplate {
+	ct := &templates.ConstraintTemplate{}
+	ct.SetName(strings.ToLower(kind))
+	ct.Spec.CRD.Spec.Names.Kind = kind
+	ct.Spec.Targets = []templates.Target{{Target: (&target.K8sValidationTarget{}).GetName(), Rego: rego}}
+	return ct
+}
+
+func newCacheConstraint(kind, name, group, matchKind string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"match": map[string]interface{}{
+				"kinds": []interface{}{map[string]interface{}{
+					"apiGroups": []interface{}{group},
+					"kinds":     []interface{}{matchKind},
+				}},
+			},
+		},
+	}}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: kind})
+	u.SetName(name)
+	return u
+}
+
+func TestResultCache(t *testing.T) {
+	ctx := context.Background()
+	counting := &countingDriver{Driver: local.New()}
+	idx := NewIndex()
+	d := NewResultCache(idx, 10).Wrap(idx.Wrap(counting))
+	backend, err := client.NewBackend(client.Driver(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := backend.NewClient(client.Targets(&target.K8sValidationTarget{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ct := range []*templates.ConstraintTemplate{
+		newCacheTemplate("K8sUniqueHost", `package k8suniquehost
+violation[{"msg": "host taken"}] {
+  host := input.review.object.spec.rules[_].host
+  other := data.inventory.namespace[_]["networking.k8s.io/v1"]["Ingress"][_]
+  other.spec.rules[_].host == host
+  other.metadata.name != input.review.object.metadata.name
+}`),
+		newCacheTemplate("K8sDeniedName", `package k8sdeniedname
+violation[{"msg": "name denied"}] {
+  input.review.object.metadata.name == "denied"
+}`),
+	} {
+		if _, err := c.AddTemplate(ctx, ct); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, constraint := range []*unstructured.Unstructured{
+		newCacheConstraint("K8sUniqueHost", "unique-host", "networking.k8s.io", "Ingress"),
+		newCacheConstraint("K8sDeniedName", "denied-name", "", "ConfigMap"),
+	} {
+		if _, err := c.AddConstraint(ctx, constraint); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ingress := &unstructured.Unstructured{Object: ingressObject("default", "second", "example.com")}
+	configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "denied"},
+	}}
+	review := func(obj *unstructured.Unstructured) int {
+		t.Helper()
+		resp, err := c.Review(ctx, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(resp.Results())
+	}
+	reviewBoth := func(wantIngress, wantConfigMap, wantReviews int) {
+		t.Helper()
+		if got := review(ingress); got != wantIngress {
+			t.Errorf("got %d results for the Ingress, want %d", got, wantIngress)
+		}
+		if got := review(configMap); got != wantConfigMap {
+			t.Errorf("got %d results for the ConfigMap, want %d", got, wantConfigMap)
+		}
+		if counting.reviews != wantReviews {
+			t.Errorf("got %d reviews evaluated, want %d", counting.reviews, wantReviews)
+		}
+	}
+
+	reviewBoth(0, 1, 2)
+	// Reviewing the same objects again is answered from the cache.
+	reviewBoth(0, 1, 2)
+
+	// Replicating an Ingress invalidates the review of the Ingress, whose
+	// constraint reads Ingresses, but not that of the ConfigMap.
+	if _, err := c.AddData(ctx, &unstructured.Unstructured{Object: ingressObject("default", "first", "example.com")}); err != nil {
+		t.Fatal(err)
+	}
+	reviewBoth(1, 1, 3)
+
+	// Replicating a kind no template reads invalidates nothing.
+	if _, err := c.AddData(ctx, &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "secret"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	reviewBoth(1, 1, 3)
+
+	// Changing a template only invalidates the reviews matched by its
+	// constraints.
+	if _, err := c.AddTemplate(ctx, newCacheTemplate("K8sDeniedName", `package k8sdeniedname
+violation[{"msg": "name denied"}] {
+  input.review.object.metadata.name == "denied"
+}
+
+violation[{"msg": "name still denied"}] {
+  input.review.object.metadata.name == "denied"
+}`)); err != nil {
+		t.Fatal(err)
+	}
+	reviewBoth(1, 2, 4)
+
+	// Changing constraints invalidates every review.
+	if _, err := c.RemoveConstraint(ctx, newCacheConstraint("K8sDeniedName", "denied-name", "", "ConfigMap")); err != nil {
+		t.Fatal(err)
+	}
+	reviewBoth(1, 0, 6)
+}
+
+func TestIsDeterministic(t *testing.T) {
+	tcs := []struct {
+		name string
+		rego string
+		want bool
+	}{
+		{
+			name: "deterministic",
+			rego: `package foo
+violation[{"msg": msg}] { msg := sprintf("%v", [input.review]) }`,
+			want: true,
+		},
+		{
+			name: "time",
+			rego: `package foo
+violation[{"msg": "late"}] { time.now_ns() > 0 }`,
+		},
+		{
+			name: "nested call",
+			rego: `package foo
+violation[{"msg": msg}] { msg := sprintf("%v", [http.send({"method": "get", "url": "https://example.com"})]) }`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isDeterministic([]string{tc.rego}); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
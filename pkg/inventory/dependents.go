@@ -0,0 +1,67 @@
+package inventory
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Dependents tracks what each ConstraintTemplate reads from data.inventory, so
+// that what is derived from the inventory, such as cached results, can be
+// invalidated for only the templates a change to the inventory affects.
+type Dependents struct {
+	mux sync.RWMutex
+	// usages are keyed by the name of the template.
+	usages map[string]*Usage
+}
+
+// NewDependents returns Dependents without templates.
+func NewDependents() *Dependents {
+	return &Dependents{usages: make(map[string]*Usage)}
+}
+
+// Set records that the template named template reads u.
+func (d *Dependents) Set(template string, u *Usage) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if u == nil || u.Empty() {
+		delete(d.usages, template)
+		return
+	}
+	d.usages[template] = u
+}
+
+// Remove forgets the template named template.
+func (d *Dependents) Remove(template string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.usages, template)
+}
+
+// Readers returns the sorted names of the templates which may read objects of
+// gvk. Templates which read kinds that cannot be determined without
+// evaluating their Rego may read any kind.
+func (d *Dependents) Readers(gvk schema.GroupVersionKind) []string {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	var readers []string
+	for name, u := range d.usages {
+		if u.Reads(gvk) {
+			readers = append(readers, name)
+		}
+	}
+	sort.Strings(readers)
+	return readers
+}
+
+// Hook returns a hook for Index.OnChange which calls invalidate with the
+// changed kind and the templates which may read it. Changes to kinds no
+// template reads are ignored.
+func (d *Dependents) Hook(invalidate func(gvk schema.GroupVersionKind, templates []string)) func(schema.GroupVersionKind) {
+	return func(gvk schema.GroupVersionKind) {
+		if readers := d.Readers(gvk); len(readers) != 0 {
+			invalidate(gvk, readers)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIndex_OnChange(t *testing.T) {
+	ctx := context.Background()
+	idx := NewIndex()
+	d := idx.Wrap(local.New())
+	var changed []string
+	idx.OnChange(func(gvk schema.GroupVersionKind) {
+		changed = append(changed, gvk.Kind)
+	})
+
+	if err := d.PutData(ctx, ingressPath("a", "first"), ingressObject("a", "first", "example.com")); err != nil {
+		t.Fatal(err)
+	}
+	// Data outside of the inventory, and objects which are not held, are not
+	// changes.
+	if err := d.PutData(ctx, "/external/other.target/cluster/v1/Namespace/a", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeleteData(ctx, ingressPath("b", "missing")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DeleteData(ctx, "/external/admission.k8s.gatekeeper.sh"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"Ingress", "Ingress"}, changed); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDependents(t *testing.T) {
+	ingress := schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+	namespace := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+
+	d := NewDependents()
+	d.Set("uniqueingresshost", &Usage{GVKs: []schema.GroupVersionKind{ingress}})
+	d.Set("namespacelabels", &Usage{GVKs: []schema.GroupVersionKind{namespace}})
+	d.Set("dynamic", &Usage{Dynamic: []string{"dynamic: data.inventory.cluster[_][input.parameters.kind]"}})
+	d.Set("norefs", &Usage{})
+
+	var invalidated map[string][]string
+	hook := d.Hook(func(gvk schema.GroupVersionKind, templates []string) {
+		invalidated[gvk.Kind] = templates
+	})
+
+	invalidated = make(map[string][]string)
+	hook(ingress)
+	hook(service)
+	want := map[string][]string{
+		"Ingress": {"dynamic", "uniqueingresshost"},
+		"Service": {"dynamic"},
+	}
+	if diff := cmp.Diff(want, invalidated); diff != "" {
+		t.Error(diff)
+	}
+
+	d.Remove("dynamic")
+	invalidated = make(map[string][]string)
+	hook(service)
+	hook(namespace)
+	if diff := cmp.Diff(map[string][]string{"Namespace": {"namespacelabels"}}, invalidated); diff != "" {
+		t.Error(diff)
+	}
+}
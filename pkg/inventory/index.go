@@ -0,0 +1,399 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Index keeps the objects replicated into data.inventory partitioned by kind,
+// and indexes them by the values of their fields so they can be looked up
+// without scanning the inventory. The index of a field is built the first
+// time it is looked up, and kept up to date from then on.
+type Index struct {
+	mux sync.RWMutex
+	// objects are the replicated objects, keyed by kind and then by
+	// "namespace/name". Cluster-scoped objects have an empty namespace.
+	objects map[schema.GroupVersionKind]map[string]interface{}
+	// fields are the indexes of the fields looked up, keyed by kind and then
+	// by field.
+	fields map[schema.GroupVersionKind]map[string]*fieldIndex
+
+	hookMux sync.RWMutex
+	// hooks are called with the kinds of the objects put or deleted.
+	hooks []func(schema.GroupVersionKind)
+}
+
+// fieldIndex maps the values of a field to the objects holding them.
+type fieldIndex struct {
+	path ast.Ref
+	// keys are the keys of the objects holding each value, keyed by the JSON
+	// encoding of the value.
+	keys map[string]map[string]bool
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{
+		objects: make(map[schema.GroupVersionKind]map[string]interface{}),
+		fields:  make(map[schema.GroupVersionKind]map[string]*fieldIndex),
+	}
+}
+
+// indexKey is the context key of the Index of a query.
+type indexKey struct{}
+
+// fromContext returns the Index of the query evaluated with ctx.
+func fromContext(ctx context.Context) *Index {
+	idx, _ := ctx.Value(indexKey{}).(*Index)
+	return idx
+}
+
+// Wrap returns d, keeping the objects it replicates into data.inventory in
+// idx, and making idx available to the lookup builtins of the queries it
+// evaluates.
+func (idx *Index) Wrap(d drivers.Driver) drivers.Driver {
+	return &indexingDriver{Driver: d, index: idx}
+}
+
+type indexingDriver struct {
+	drivers.Driver
+	index *Index
+}
+
+func (d *indexingDriver) PutData(ctx context.Context, path string, data interface{}) error {
+	if err := d.Driver.PutData(ctx, path, data); err != nil {
+		return err
+	}
+	d.index.put(path, data)
+	return nil
+}
+
+func (d *indexingDriver) DeleteData(ctx context.Context, path string) (bool, error) {
+	deleted, err := d.Driver.DeleteData(ctx, path)
+	if err != nil {
+		return deleted, err
+	}
+	d.index.delete(path)
+	return deleted, nil
+}
+
+func (d *indexingDriver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	return d.Driver.Query(context.WithValue(ctx, indexKey{}, d.index), path, input, opts...)
+}
+
+// inventoryPath returns the path below data.inventory of a path the
+// constraint framework puts data at, or false if path is outside of it.
+func inventoryPath(path string) (storage.Path, bool) {
+	p, ok := storage.ParsePathEscaped(path)
+	if !ok || len(p) < 2 || p[0] != "external" || p[1] != (&target.K8sValidationTarget{}).GetName() {
+		return nil, false
+	}
+	return p[2:], true
+}
+
+// objectKind returns the kind and key of the object at p, a path below
+// data.inventory, or false if p is not the path of an object.
+func objectKind(p storage.Path) (schema.GroupVersionKind, string, bool) {
+	var namespace, gv, kind, name string
+	switch {
+	case len(p) == 4 && p[0] == "cluster":
+		gv, kind, name = p[1], p[2], p[3]
+	case len(p) == 5 && p[0] == "namespace":
+		namespace, gv, kind, name = p[1], p[2], p[3], p[4]
+	default:
+		return schema.GroupVersionKind{}, "", false
+	}
+	parsed, err := schema.ParseGroupVersion(gv)
+	if err != nil {
+		return schema.GroupVersionKind{}, "", false
+	}
+	return parsed.WithKind(kind), namespace + "/" + name, true
+}
+
+// OnChange registers hook to be called with the kind of every object put into
+// or deleted from the inventory, after idx holds the change. Deleting a
+// partition of the inventory calls hook once for each kind it held. hook must
+// not block, as it is called while the driver replicates data.
+func (idx *Index) OnChange(hook func(schema.GroupVersionKind)) {
+	idx.hookMux.Lock()
+	defer idx.hookMux.Unlock()
+	idx.hooks = append(idx.hooks, hook)
+}
+
+// changed calls the hooks of idx with each of gvks.
+func (idx *Index) changed(gvks ...schema.GroupVersionKind) {
+	idx.hookMux.RLock()
+	defer idx.hookMux.RUnlock()
+	for _, gvk := range gvks {
+		for _, hook := range idx.hooks {
+			hook(gvk)
+		}
+	}
+}
+
+func (idx *Index) put(path string, data interface{}) {
+	p, ok := inventoryPath(path)
+	if !ok {
+		return
+	}
+	gvk, key, ok := objectKind(p)
+	if !ok {
+		return
+	}
+
+	idx.putObject(gvk, key, data)
+	idx.changed(gvk)
+}
+
+func (idx *Index) putObject(gvk schema.GroupVersionKind, key string, data interface{}) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	objects, ok := idx.objects[gvk]
+	if !ok {
+		objects = make(map[string]interface{})
+		idx.objects[gvk] = objects
+	}
+	if old, ok := objects[key]; ok {
+		for _, f := range idx.fields[gvk] {
+			f.remove(key, old)
+		}
+	}
+	objects[key] = data
+	for _, f := range idx.fields[gvk] {
+		f.add(key, data)
+	}
+}
+
+// delete forgets the objects at or below path.
+func (idx *Index) delete(path string) {
+	p, ok := inventoryPath(path)
+	if !ok {
+		return
+	}
+	idx.changed(idx.deleteObjects(p)...)
+}
+
+// deleteObjects forgets the objects at or below p, returning their kinds.
+func (idx *Index) deleteObjects(p storage.Path) []schema.GroupVersionKind {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	if gvk, key, ok := objectKind(p); ok {
+		old, ok := idx.objects[gvk][key]
+		if !ok {
+			return nil
+		}
+		for _, f := range idx.fields[gvk] {
+			f.remove(key, old)
+		}
+		delete(idx.objects[gvk], key)
+		return []schema.GroupVersionKind{gvk}
+	}
+
+	// Anything else deleted holds whole partitions, such as all the data of
+	// the target when the Config changes, so matching objects are forgotten.
+	var deleted []schema.GroupVersionKind
+	for gvk, objects := range idx.objects {
+		found := false
+		for key, obj := range objects {
+			if !hasPrefix(objectPath(gvk, key), p) {
+				continue
+			}
+			for _, f := range idx.fields[gvk] {
+				f.remove(key, obj)
+			}
+			delete(objects, key)
+			found = true
+		}
+		if found {
+			deleted = append(deleted, gvk)
+		}
+	}
+	return deleted
+}
+
+// objectPath returns the path below data.inventory of the object of gvk with
+// key.
+func objectPath(gvk schema.GroupVersionKind, key string) storage.Path {
+	namespace, name := splitKey(key)
+	gv := gvk.GroupVersion().String()
+	if namespace == "" {
+		return storage.Path{"cluster", gv, gvk.Kind, name}
+	}
+	return storage.Path{"namespace", namespace, gv, gvk.Kind, name}
+}
+
+func splitKey(key string) (string, string) {
+	i := strings.Index(key, "/")
+	return key[:i], key[i+1:]
+}
+
+func hasPrefix(p, prefix storage.Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i := range prefix {
+		if p[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup returns the objects of gvk whose field holds value, sorted by
+// namespace and name. field is a reference into the object such as
+// `spec.rules[_].host`, in which variables iterate over arrays and objects.
+func (idx *Index) Lookup(gvk schema.GroupVersionKind, field string, value interface{}) ([]interface{}, error) {
+	objects, _, err := idx.lookup(gvk, field, value)
+	return objects, err
+}
+
+// lookup is Lookup, also returning whether the index of field was already
+// built.
+func (idx *Index) lookup(gvk schema.GroupVersionKind, field string, value interface{}) ([]interface{}, bool, error) {
+	path, err := parseField(field)
+	if err != nil {
+		return nil, false, err
+	}
+	v, err := valueKey(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	idx.mux.RLock()
+	f, ok := idx.fields[gvk][field]
+	if ok {
+		defer idx.mux.RUnlock()
+		return idx.objectsLocked(gvk, f.keys[v]), true, nil
+	}
+	idx.mux.RUnlock()
+
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	if f, ok = idx.fields[gvk][field]; !ok {
+		f = &fieldIndex{path: path, keys: make(map[string]map[string]bool)}
+		for key, obj := range idx.objects[gvk] {
+			f.add(key, obj)
+		}
+		if idx.fields[gvk] == nil {
+			idx.fields[gvk] = make(map[string]*fieldIndex)
+		}
+		idx.fields[gvk][field] = f
+	}
+	return idx.objectsLocked(gvk, f.keys[v]), ok, nil
+}
+
+// objectsLocked returns the objects of gvk with keys, sorted by key. Must be
+// called while holding idx.mux.
+func (idx *Index) objectsLocked(gvk schema.GroupVersionKind, keys map[string]bool) []interface{} {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	objects := make([]interface{}, 0, len(sorted))
+	for _, key := range sorted {
+		objects = append(objects, idx.objects[gvk][key])
+	}
+	return objects
+}
+
+// parseField parses a field reference, such as `metadata.labels["app"]`.
+func parseField(field string) (ast.Ref, error) {
+	ref, err := ast.ParseRef(field)
+	if err != nil {
+		return nil, err
+	}
+	head, ok := ref[0].Value.(ast.Var)
+	if !ok {
+		return nil, fmt.Errorf("field %s must reference object keys, array indexes or variables", field)
+	}
+	path := ast.Ref{ast.StringTerm(string(head))}
+	for _, term := range ref[1:] {
+		switch term.Value.(type) {
+		case ast.String, ast.Var, ast.Number:
+			path = append(path, term)
+		default:
+			return nil, fmt.Errorf("field %s must reference object keys, array indexes or variables", field)
+		}
+	}
+	return path, nil
+}
+
+func valueKey(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (f *fieldIndex) add(key string, obj interface{}) {
+	for _, v := range fieldValues(f.path, obj) {
+		keys, ok := f.keys[v]
+		if !ok {
+			keys = make(map[string]bool)
+			f.keys[v] = keys
+		}
+		keys[key] = true
+	}
+}
+
+func (f *fieldIndex) remove(key string, obj interface{}) {
+	for _, v := range fieldValues(f.path, obj) {
+		delete(f.keys[v], key)
+		if len(f.keys[v]) == 0 {
+			delete(f.keys, v)
+		}
+	}
+}
+
+// fieldValues returns the JSON encodings of the values at path in obj.
+func fieldValues(path ast.Ref, obj interface{}) []string {
+	var values []string
+	var walk func(path ast.Ref, v interface{})
+	walk = func(path ast.Ref, v interface{}) {
+		if len(path) == 0 {
+			if key, err := valueKey(v); err == nil {
+				values = append(values, key)
+			}
+			return
+		}
+		switch term := path[0].Value.(type) {
+		case ast.String:
+			if m, ok := v.(map[string]interface{}); ok {
+				if child, ok := m[string(term)]; ok {
+					walk(path[1:], child)
+				}
+			}
+		case ast.Number:
+			i, ok := term.Int()
+			if s, isSlice := v.([]interface{}); ok && isSlice && i >= 0 && i < len(s) {
+				walk(path[1:], s[i])
+			}
+		case ast.Var:
+			switch c := v.(type) {
+			case []interface{}:
+				for _, child := range c {
+					walk(path[1:], child)
+				}
+			case map[string]interface{}:
+				for _, child := range c {
+					walk(path[1:], child)
+				}
+			}
+		}
+	}
+	walk(path, obj)
+	return values
+}
@@ -0,0 +1,142 @@
+package inventory
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+)
+
+const lookupModule = `package test
+
+found[{"msg": msg}] {
+  obj := gatekeeper.inventory.lookup("networking.k8s.io/v1", "Ingress", "spec.rules[_].host", input.host)[_]
+  msg := sprintf("%s/%s", [obj.metadata.namespace, obj.metadata.name])
+}`
+
+func ingressObject(namespace, name string, hosts ...string) map[string]interface{} {
+	var rules []interface{}
+	for _, host := range hosts {
+		rules = append(rules, map[string]interface{}{"host": host})
+	}
+	return map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   map[string]interface{}{"namespace": namespace, "name": name},
+		"spec":       map[string]interface{}{"rules": rules},
+	}
+}
+
+func ingressPath(namespace, name string) string {
+	return "/external/admission.k8s.gatekeeper.sh/namespace/" + namespace + "/networking.k8s.io%2Fv1/Ingress/" + name
+}
+
+func found(ctx context.Context, t *testing.T, d drivers.Driver, host string) []string {
+	t.Helper()
+	resp, err := d.Query(ctx, "test.found", map[string]interface{}{"host": host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []string{}
+	for _, r := range resp.Results {
+		msgs = append(msgs, r.Msg)
+	}
+	sort.Strings(msgs)
+	return msgs
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	ctx := context.Background()
+	d := NewIndex().Wrap(local.New())
+	if err := d.PutModule(ctx, "test", lookupModule); err != nil {
+		t.Fatal(err)
+	}
+	put := func(namespace, name string, hosts ...string) {
+		t.Helper()
+		if err := d.PutData(ctx, ingressPath(namespace, name), ingressObject(namespace, name, hosts...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a", "first", "example.com", "other.com")
+	put("b", "second", "example.com")
+
+	if diff := cmp.Diff([]string{"a/first", "b/second"}, found(ctx, t, d, "example.com")); diff != "" {
+		t.Error(diff)
+	}
+
+	// The index is kept up to date once built.
+	put("a", "first", "other.com")
+	put("c", "third", "example.com")
+	if diff := cmp.Diff([]string{"b/second", "c/third"}, found(ctx, t, d, "example.com")); diff != "" {
+		t.Error(diff)
+	}
+	if _, err := d.DeleteData(ctx, ingressPath("b", "second")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"c/third"}, found(ctx, t, d, "example.com")); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"a/first"}, found(ctx, t, d, "other.com")); diff != "" {
+		t.Error(diff)
+	}
+
+	// Wiping the data of the target forgets every object.
+	if _, err := d.DeleteData(ctx, "/external/admission.k8s.gatekeeper.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{}, found(ctx, t, d, "other.com")); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestIndex_Lookup_NotIndexed(t *testing.T) {
+	ctx := context.Background()
+	d := local.New()
+	if err := d.PutModule(ctx, "test", lookupModule); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutData(ctx, ingressPath("a", "first"), ingressObject("a", "first", "example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{}, found(ctx, t, d, "example.com")); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestIndex_Lookup_CacheHits(t *testing.T) {
+	d := NewIndex().Wrap(local.New())
+	if err := d.PutModule(context.Background(), "test", lookupModule); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutData(context.Background(), ingressPath("a", "first"), ingressObject("a", "first", "example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only lookups answered by an index which was already built are hits.
+	for _, want := range []int{0, 1} {
+		ctx, stats := querystats.NewContext(context.Background())
+		found(ctx, t, d, "example.com")
+		if got := stats.Stats().CacheHits; got != want {
+			t.Errorf("got %d cache hits, want %d", got, want)
+		}
+	}
+}
+
+func TestParseField(t *testing.T) {
+	for field, wantErr := range map[string]bool{
+		"spec.rules[_].host":                   false,
+		`metadata.labels["app.kubernetes.io"]`: false,
+		"spec.ports[0].port":                   false,
+		"spec[{1}]":                            true,
+		`"spec".rules`:                         true,
+		"spec.rules[":                          true,
+	} {
+		if _, err := parseField(field); (err != nil) != wantErr {
+			t.Errorf("got error %v parsing %q, want error %v", err, field, wantErr)
+		}
+	}
+}
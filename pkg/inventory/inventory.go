@@ -0,0 +1,234 @@
+// Package inventory statically extracts the kinds the Rego of
+// ConstraintTemplates reads from data.inventory, where Gatekeeper replicates
+// the objects selected by the Config. Replication is configured separately
+// from templates, so a template may read kinds which are never replicated and
+// silently find no objects of them.
+//
+// It also indexes the replicated objects by the values of their fields, for
+// the gatekeeper.inventory.lookup builtin to find objects without scanning
+// data.inventory, and caches the results of reviews, which a change to the
+// inventory only invalidates for the templates reading the changed kind.
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/opa/ast"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Root is the ref of data.inventory, which is laid out as
+//
+//	data.inventory.cluster[groupVersion][kind][name]
+//	data.inventory.namespace[namespace][groupVersion][kind][name]
+var Root = ast.DefaultRootRef.Append(ast.StringTerm("inventory"))
+
+// Usage is what Rego reads from data.inventory.
+type Usage struct {
+	// GVKs are the kinds read from data.inventory, sorted.
+	GVKs []schema.GroupVersionKind
+	// Dynamic are the locations of references to data.inventory whose kind
+	// cannot be determined without evaluating the Rego, sorted.
+	Dynamic []string
+}
+
+// Reads returns whether u may read objects of gvk from data.inventory.
+func (u *Usage) Reads(gvk schema.GroupVersionKind) bool {
+	if len(u.Dynamic) > 0 {
+		return true
+	}
+	for _, g := range u.GVKs {
+		if g == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty returns whether u does not read data.inventory.
+func (u *Usage) Empty() bool {
+	return len(u.GVKs) == 0 && len(u.Dynamic) == 0
+}
+
+// Missing returns the kinds u reads which synced does not hold.
+func (u *Usage) Missing(synced map[schema.GroupVersionKind]bool) []schema.GroupVersionKind {
+	var missing []schema.GroupVersionKind
+	for _, gvk := range u.GVKs {
+		if !synced[gvk] {
+			missing = append(missing, gvk)
+		}
+	}
+	return missing
+}
+
+// Extract returns what the Rego and libraries of the targets of ct read from
+// data.inventory.
+func Extract(ct *templates.ConstraintTemplate) (*Usage, error) {
+	return ExtractTargets(ct.Spec.Targets)
+}
+
+// ExtractV1Beta1 is Extract for a v1beta1 ConstraintTemplate, as listed from
+// the API server.
+func ExtractV1Beta1(ct *v1beta1.ConstraintTemplate) (*Usage, error) {
+	targets := make([]templates.Target, len(ct.Spec.Targets))
+	for i, target := range ct.Spec.Targets {
+		targets[i] = templates.Target{Target: target.Target, Rego: target.Rego, Libs: target.Libs}
+	}
+	return ExtractTargets(targets)
+}
+
+// ExtractTargets returns what the Rego and libraries of targets read from
+// data.inventory. Modules are named after their target, and libraries
+// "<target>/libs[<index>]".
+func ExtractTargets(targets []templates.Target) (*Usage, error) {
+	modules := make(map[string]string)
+	for _, target := range targets {
+		modules[target.Target] = target.Rego
+		for i, lib := range target.Libs {
+			modules[fmt.Sprintf("%s/libs[%d]", target.Target, i)] = lib
+		}
+	}
+
+	gvks := make(map[schema.GroupVersionKind]bool)
+	usage := &Usage{}
+	for name, src := range modules {
+		m, err := ast.ParseModule(name, src)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			continue
+		}
+
+		imports := make(map[ast.Var]ast.Ref)
+		for _, imp := range m.Imports {
+			path, ok := imp.Path.Value.(ast.Ref)
+			if !ok || !path.HasPrefix(Root) {
+				continue
+			}
+			alias := imp.Alias
+			if alias == "" {
+				alias = ast.Var(strings.Trim(path[len(path)-1].String(), `"`))
+			}
+			imports[alias] = path
+		}
+
+		// Only rules are walked, as the paths of imports are not reads.
+		for _, rule := range m.Rules {
+			ast.WalkRefs(rule, func(ref ast.Ref) bool {
+				if v, ok := ref[0].Value.(ast.Var); ok {
+					if path, ok := imports[v]; ok {
+						ref = path.Concat(ref[1:])
+					}
+				}
+				if !ref.HasPrefix(Root) {
+					return false
+				}
+				gvk, ok := refGVK(ref)
+				if !ok {
+					usage.Dynamic = append(usage.Dynamic, location(name, ref))
+					return false
+				}
+				gvks[gvk] = true
+				return false
+			})
+			ast.NewGenericVisitor(func(x interface{}) bool {
+				var op ast.Ref
+				var operands []*ast.Term
+				switch v := x.(type) {
+				case *ast.Expr:
+					if !v.IsCall() {
+						return false
+					}
+					op, operands = v.Operator(), v.Operands()
+				case ast.Call:
+					ref, ok := v[0].Value.(ast.Ref)
+					if !ok {
+						return false
+					}
+					op, operands = ref, v[1:]
+				default:
+					return false
+				}
+				if op.String() != LookupBuiltin {
+					return false
+				}
+				gvk, ok := lookupGVK(operands)
+				if !ok {
+					usage.Dynamic = append(usage.Dynamic, location(name, op))
+					return false
+				}
+				gvks[gvk] = true
+				return false
+			}).Walk(rule)
+		}
+	}
+
+	for gvk := range gvks {
+		usage.GVKs = append(usage.GVKs, gvk)
+	}
+	sort.Slice(usage.GVKs, func(i, j int) bool {
+		return usage.GVKs[i].String() < usage.GVKs[j].String()
+	})
+	sort.Strings(usage.Dynamic)
+	return usage, nil
+}
+
+// refGVK returns the kind ref reads from data.inventory, or false if it is not
+// a constant.
+func refGVK(ref ast.Ref) (schema.GroupVersionKind, bool) {
+	var gv, kind *ast.Term
+	switch scope := ref[len(Root):]; {
+	case len(scope) >= 3 && scope[0].Equal(ast.StringTerm("cluster")):
+		gv, kind = scope[1], scope[2]
+	case len(scope) >= 4 && scope[0].Equal(ast.StringTerm("namespace")):
+		gv, kind = scope[2], scope[3]
+	default:
+		return schema.GroupVersionKind{}, false
+	}
+	gvStr, ok := gv.Value.(ast.String)
+	if !ok {
+		return schema.GroupVersionKind{}, false
+	}
+	kindStr, ok := kind.Value.(ast.String)
+	if !ok {
+		return schema.GroupVersionKind{}, false
+	}
+	parsed, err := schema.ParseGroupVersion(string(gvStr))
+	if err != nil {
+		return schema.GroupVersionKind{}, false
+	}
+	return parsed.WithKind(string(kindStr)), true
+}
+
+// lookupGVK returns the kind looked up by a call of LookupBuiltin with
+// operands, or false if it is not a constant.
+func lookupGVK(operands []*ast.Term) (schema.GroupVersionKind, bool) {
+	if len(operands) < 2 {
+		return schema.GroupVersionKind{}, false
+	}
+	gvStr, ok := operands[0].Value.(ast.String)
+	if !ok {
+		return schema.GroupVersionKind{}, false
+	}
+	kindStr, ok := operands[1].Value.(ast.String)
+	if !ok {
+		return schema.GroupVersionKind{}, false
+	}
+	parsed, err := schema.ParseGroupVersion(string(gvStr))
+	if err != nil {
+		return schema.GroupVersionKind{}, false
+	}
+	return parsed.WithKind(string(kindStr)), true
+}
+
+func location(name string, ref ast.Ref) string {
+	if loc := ref[0].Location; loc != nil {
+		return fmt.Sprintf("%s:%d: %s", name, loc.Row, ref)
+	}
+	return fmt.Sprintf("%s: %s", name, ref)
+}
@@ -0,0 +1,122 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	namespace = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	ingress   = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+	pod       = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+)
+
+func TestExtractTargets(t *testing.T) {
+	tcs := []struct {
+		name        string
+		rego        string
+		libs        []string
+		wantGVKs    []schema.GroupVersionKind
+		wantDynamic int
+		wantErr     bool
+	}{
+		{
+			name: "no inventory",
+			rego: `package foo
+violation[{"msg": "denied"}] {
+  input.review.object.metadata.name == "foo"
+}`,
+		},
+		{
+			name: "cluster and namespace scoped",
+			rego: `package foo
+violation[{"msg": "denied"}] {
+  data.inventory.cluster["v1"].Namespace[_]
+  data.inventory.namespace[_]["networking.k8s.io/v1"]["Ingress"][_]
+}`,
+			wantGVKs: []schema.GroupVersionKind{namespace, ingress},
+		},
+		{
+			name: "imported in a library",
+			rego: `package foo
+import data.lib.pods
+violation[{"msg": "denied"}] {
+  pods.exists
+}`,
+			libs: []string{`package lib.pods
+import data.inventory.namespace as ns
+import data.inventory.cluster
+exists {
+  ns[_].v1.Pod[_]
+  cluster.v1.Namespace[_]
+}`},
+			wantGVKs: []schema.GroupVersionKind{namespace, pod},
+		},
+		{
+			name: "dynamic kind",
+			rego: `package foo
+violation[{"msg": "denied"}] {
+  data.inventory.cluster["v1"][input.parameters.kind][_]
+  inv := data.inventory
+}`,
+			wantDynamic: 2,
+		},
+		{
+			name: "looked up",
+			rego: `package foo
+violation[{"msg": "denied"}] {
+  gatekeeper.inventory.lookup("networking.k8s.io/v1", "Ingress", "spec.rules[_].host", "example.com", objs)
+  pods := gatekeeper.inventory.lookup("v1", input.parameters.kind, "metadata.name", "foo")
+}`,
+			wantGVKs:    []schema.GroupVersionKind{ingress},
+			wantDynamic: 1,
+		},
+		{
+			name:    "invalid rego",
+			rego:    `package foo violation[`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExtractTargets([]templates.Target{{Target: "admission.k8s.gatekeeper.sh", Rego: tc.rego, Libs: tc.libs}})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantGVKs, got.GVKs); diff != "" {
+				t.Error(diff)
+			}
+			if len(got.Dynamic) != tc.wantDynamic {
+				t.Errorf("got dynamic references %v, want %d", got.Dynamic, tc.wantDynamic)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	u := &Usage{GVKs: []schema.GroupVersionKind{namespace, ingress}}
+	if !u.Reads(namespace) || u.Reads(pod) {
+		t.Errorf("got Reads(%v) = %v and Reads(%v) = %v, want true and false", namespace, u.Reads(namespace), pod, u.Reads(pod))
+	}
+	if diff := cmp.Diff([]schema.GroupVersionKind{ingress}, u.Missing(map[schema.GroupVersionKind]bool{namespace: true})); diff != "" {
+		t.Error(diff)
+	}
+
+	dynamic := &Usage{Dynamic: []string{"admission.k8s.gatekeeper.sh:3: data.inventory"}}
+	if !dynamic.Reads(pod) {
+		t.Error("got a dynamic reference not reading every kind")
+	}
+	if !(&Usage{}).Empty() || dynamic.Empty() {
+		t.Error("got Empty() = false without references, or true with a dynamic reference")
+	}
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const configMapResync = 10 * time.Minute
+
+var _ manager.Runnable = &ConfigMapWatcher{}
+
+// ConfigMapWatcher applies the logging configuration stored in a ConfigMap to
+// a DynamicConfig whenever the ConfigMap changes. Deleting the ConfigMap
+// restores the DynamicConfig's defaults.
+type ConfigMapWatcher struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	config    *DynamicConfig
+	log       logr.Logger
+}
+
+// NewConfigMapWatcher creates a watcher for the ConfigMap namespace/name.
+func NewConfigMapWatcher(client kubernetes.Interface, namespace, name string, config *DynamicConfig, log logr.Logger) *ConfigMapWatcher {
+	return &ConfigMapWatcher{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		config:    config,
+		log:       log.WithValues("configmap", namespace+"/"+name),
+	}
+}
+
+// Start implements manager.Runnable.
+func (w *ConfigMapWatcher) Start(ctx context.Context) error {
+	lw := cache.NewListWatchFromClient(
+		w.client.CoreV1().RESTClient(),
+		"configmaps",
+		w.namespace,
+		fields.OneTermEqualSelector("metadata.name", w.name))
+
+	_, informer := cache.NewInformer(lw, &corev1.ConfigMap{}, configMapResync, cache.ResourceEventHandlerFuncs{
+		AddFunc: w.apply,
+		UpdateFunc: func(_, obj interface{}) {
+			w.apply(obj)
+		},
+		DeleteFunc: func(interface{}) {
+			w.log.Info("logging configuration removed, restoring defaults")
+			w.config.Set(w.config.Defaults())
+		},
+	})
+	informer.Run(ctx.Done())
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica
+// configures its own logging.
+func (w *ConfigMapWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *ConfigMapWatcher) apply(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	settings, err := ParseSettings(cm.Data, w.config.Defaults())
+	if err != nil {
+		// Keep logging with the last valid configuration.
+		w.log.Error(err, "invalid logging configuration, ignoring")
+		return
+	}
+	w.config.Set(settings)
+	w.log.Info("applied logging configuration", "resourceVersion", cm.GetResourceVersion())
+}
@@ -0,0 +1,431 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// LevelKey is the ConfigMap key for the default minimum log level.
+	LevelKey = "level"
+	// LevelPrefix prefixes ConfigMap keys setting the minimum log level of a
+	// subsystem, for example "level.webhook".
+	LevelPrefix = "level."
+	// SamplingPrefix prefixes ConfigMap keys setting the sampling rate of a
+	// subsystem, for example "sampling.webhook". Values have the form
+	// "<initial>/<thereafter>".
+	SamplingPrefix = "sampling."
+	// RedactKey is the ConfigMap key listing, comma separated, the log fields
+	// whose values are never written, including fields nested in the values
+	// of other fields.
+	RedactKey = "redact"
+
+	// RedactedValue replaces the value of redacted fields.
+	RedactedValue = "[REDACTED]"
+
+	samplingTick    = time.Second
+	samplingBuckets = 4096
+)
+
+// AllLevels enables every level. Cores wrapped by a DynamicConfig must accept
+// all levels, as filtering is done by the DynamicConfig.
+var AllLevels = zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+
+// Sampling limits how many identical messages a subsystem logs per second. The
+// first Initial messages are logged, then every Thereafter-th message.
+type Sampling struct {
+	Initial    uint64
+	Thereafter uint64
+}
+
+// Settings is the logging configuration at a point in time.
+//
+// Subsystems are identified by logger name, for example "webhook" or
+// "controller". Settings for a subsystem also apply to loggers named below
+// it; the most specific subsystem wins.
+type Settings struct {
+	// Level is the minimum level for subsystems without a level of their own.
+	Level zapcore.Level
+	// Levels are the minimum levels of individual subsystems.
+	Levels map[string]zapcore.Level
+	// Sampling is the sampling applied to individual subsystems. The empty
+	// subsystem applies to all loggers.
+	Sampling map[string]Sampling
+	// Redact are the field keys whose values are replaced with RedactedValue,
+	// at any depth.
+	Redact map[string]bool
+}
+
+// ParseSettings overlays the configuration in data on top of defaults.
+func ParseSettings(data map[string]string, defaults Settings) (Settings, error) {
+	s := Settings{
+		Level:    defaults.Level,
+		Levels:   make(map[string]zapcore.Level),
+		Sampling: make(map[string]Sampling),
+		Redact:   make(map[string]bool),
+	}
+	for k, v := range defaults.Levels {
+		s.Levels[k] = v
+	}
+	for k, v := range defaults.Sampling {
+		s.Sampling[k] = v
+	}
+	for k, v := range defaults.Redact {
+		s.Redact[k] = v
+	}
+
+	for key, value := range data {
+		value = strings.TrimSpace(value)
+		switch {
+		case key == LevelKey:
+			lvl, err := ParseLevel(value)
+			if err != nil {
+				return Settings{}, fmt.Errorf("%s: %w", key, err)
+			}
+			s.Level = lvl
+		case strings.HasPrefix(key, LevelPrefix):
+			lvl, err := ParseLevel(value)
+			if err != nil {
+				return Settings{}, fmt.Errorf("%s: %w", key, err)
+			}
+			s.Levels[strings.TrimPrefix(key, LevelPrefix)] = lvl
+		case strings.HasPrefix(key, SamplingPrefix):
+			sampling, err := parseSampling(value)
+			if err != nil {
+				return Settings{}, fmt.Errorf("%s: %w", key, err)
+			}
+			s.Sampling[strings.TrimPrefix(key, SamplingPrefix)] = sampling
+		case key == RedactKey:
+			for _, field := range strings.Split(value, ",") {
+				if field = strings.TrimSpace(field); field != "" {
+					s.Redact[field] = true
+				}
+			}
+		default:
+			return Settings{}, fmt.Errorf("unknown logging configuration key %q", key)
+		}
+	}
+
+	return s, nil
+}
+
+// ParseLevel parses one of "debug", "info", "warning" or "error", or a
+// non-negative integer verbosity as used by logr's V().
+func ParseLevel(s string) (zapcore.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn", "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return zapcore.Level(-v), nil
+}
+
+func parseSampling(s string) (Sampling, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Sampling{}, fmt.Errorf("invalid sampling %q, must be <initial>/<thereafter>", s)
+	}
+	initial, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil {
+		return Sampling{}, fmt.Errorf("invalid sampling %q: %v", s, err)
+	}
+	thereafter, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		return Sampling{}, fmt.Errorf("invalid sampling %q: %v", s, err)
+	}
+	return Sampling{Initial: initial, Thereafter: thereafter}, nil
+}
+
+// DynamicConfig holds logging Settings which may be replaced while the
+// process is running.
+type DynamicConfig struct {
+	defaults Settings
+	state    atomic.Value // *dynamicState
+}
+
+type dynamicState struct {
+	settings Settings
+	minLevel zapcore.Level
+	samplers map[string]*sampler
+}
+
+// NewDynamicConfig returns a DynamicConfig initialized with defaults.
+func NewDynamicConfig(defaults Settings) *DynamicConfig {
+	c := &DynamicConfig{defaults: defaults}
+	c.Set(defaults)
+	return c
+}
+
+// Defaults returns the Settings the DynamicConfig was created with.
+func (c *DynamicConfig) Defaults() Settings {
+	return c.defaults
+}
+
+// Set replaces the current Settings. Sampling counters are reset.
+func (c *DynamicConfig) Set(s Settings) {
+	state := &dynamicState{
+		settings: s,
+		minLevel: s.Level,
+		samplers: make(map[string]*sampler, len(s.Sampling)),
+	}
+	for _, lvl := range s.Levels {
+		if lvl < state.minLevel {
+			state.minLevel = lvl
+		}
+	}
+	for name, sampling := range s.Sampling {
+		state.samplers[name] = &sampler{first: sampling.Initial, thereafter: sampling.Thereafter}
+	}
+	c.state.Store(state)
+}
+
+// WrapCore applies the DynamicConfig to core. Suitable for use with
+// zap.WrapCore.
+func (c *DynamicConfig) WrapCore(core zapcore.Core) zapcore.Core {
+	return &dynamicCore{inner: core, config: c}
+}
+
+func (c *DynamicConfig) load() *dynamicState {
+	return c.state.Load().(*dynamicState)
+}
+
+// levelFor returns the minimum level for the logger with the given name.
+func (s *dynamicState) levelFor(name string) zapcore.Level {
+	key, ok := subsystem(name, func(k string) bool {
+		_, found := s.settings.Levels[k]
+		return found
+	})
+	if !ok {
+		return s.settings.Level
+	}
+	return s.settings.Levels[key]
+}
+
+func (s *dynamicState) samplerFor(name string) *sampler {
+	key, ok := subsystem(name, func(k string) bool {
+		_, found := s.samplers[k]
+		return found
+	})
+	if !ok {
+		return nil
+	}
+	return s.samplers[key]
+}
+
+// redact returns fields with the values of the keys to redact replaced with
+// RedactedValue, including the keys of objects, maps and structs nested in
+// the values of fields.
+func (s *dynamicState) redact(fields []zapcore.Field) []zapcore.Field {
+	if len(s.settings.Redact) == 0 {
+		return fields
+	}
+	var redacted []zapcore.Field
+	set := func(i int, f zapcore.Field) {
+		if redacted == nil {
+			redacted = make([]zapcore.Field, len(fields))
+			copy(redacted, fields)
+		}
+		redacted[i] = f
+	}
+	for i, f := range fields {
+		if s.settings.Redact[f.Key] {
+			set(i, zap.String(f.Key, RedactedValue))
+			continue
+		}
+		switch f.Type {
+		case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+			if v, ok := s.redactNested(f); ok {
+				set(i, zap.Any(f.Key, v))
+			}
+		case zapcore.InlineMarshalerType:
+			// The keys of inlined objects are written alongside those of
+			// fields, so they are redacted as nested keys of an object.
+			if v, ok := s.redactNested(zap.Object("inline", f.Interface.(zapcore.ObjectMarshaler))); ok {
+				set(i, zap.Inline(redactedObject(v.(map[string]interface{}))))
+			}
+		}
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// redactNested returns the value of f as decoded from JSON, with the values of
+// nested keys to redact replaced, and whether any were. Values which cannot
+// be encoded are left to the encoder to report.
+func (s *dynamicState) redactNested(f zapcore.Field) (interface{}, bool) {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	b, err := json.Marshal(enc.Fields[f.Key])
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return s.redactValue(v)
+}
+
+// redactValue replaces the values of the keys to redact in v, a value decoded
+// from JSON, and returns whether any were.
+func (s *dynamicState) redactValue(v interface{}) (interface{}, bool) {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			if s.settings.Redact[k] {
+				v[k] = RedactedValue
+				redacted = true
+				continue
+			}
+			if nested, ok := s.redactValue(nested); ok {
+				v[k] = nested
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for i, nested := range v {
+			if nested, ok := s.redactValue(nested); ok {
+				v[i] = nested
+				redacted = true
+			}
+		}
+	}
+	return v, redacted
+}
+
+// redactedObject is an inlined object whose keys were redacted.
+type redactedObject map[string]interface{}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range o {
+		zap.Any(k, v).AddTo(enc)
+	}
+	return nil
+}
+
+// subsystem returns the most specific configured subsystem covering the logger
+// name. Logger names are dot separated, so "webhook" covers
+// "webhook.validation", and the empty subsystem covers every logger.
+func subsystem(name string, configured func(string) bool) (string, bool) {
+	for {
+		if configured(name) {
+			return name, true
+		}
+		if name == "" {
+			return "", false
+		}
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[:i]
+		} else {
+			name = ""
+		}
+	}
+}
+
+// dynamicCore filters, samples and redacts entries according to the current
+// state of a DynamicConfig before passing them to inner.
+type dynamicCore struct {
+	inner  zapcore.Core
+	config *DynamicConfig
+	// fields are the fields added by With. They are kept rather than added to
+	// inner, which would encode them at once, so that they are redacted
+	// according to the configuration when each entry is written.
+	fields []zapcore.Field
+}
+
+var _ zapcore.Core = &dynamicCore{}
+
+func (c *dynamicCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.config.load().minLevel
+}
+
+func (c *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dynamicCore{
+		inner:  c.inner,
+		config: c.config,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *dynamicCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	state := c.config.load()
+	if ent.Level < state.levelFor(ent.LoggerName) {
+		return ce
+	}
+	if s := state.samplerFor(ent.LoggerName); s != nil && !s.allow(ent) {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+func (c *dynamicCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.fields) != 0 {
+		fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	}
+	return c.inner.Write(ent, c.config.load().redact(fields))
+}
+
+func (c *dynamicCore) Sync() error {
+	return c.inner.Sync()
+}
+
+// sampler counts identical entries per tick. Unlike zapcore's sampler it
+// supports logr verbosity levels below zapcore.DebugLevel.
+type sampler struct {
+	first      uint64
+	thereafter uint64
+	counts     [samplingBuckets]counter
+}
+
+func (s *sampler) allow(ent zapcore.Entry) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte{byte(ent.Level)})
+	_, _ = h.Write([]byte(ent.Message))
+	n := s.counts[h.Sum32()%samplingBuckets].incCheckReset(ent.Time)
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+type counter struct {
+	mux     sync.Mutex
+	resetAt time.Time
+	n       uint64
+}
+
+func (c *counter) incCheckReset(t time.Time) uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if !t.Before(c.resetAt) {
+		c.resetAt = t.Add(samplingTick)
+		c.n = 0
+	}
+	c.n++
+	return c.n
+}
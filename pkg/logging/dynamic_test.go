@@ -0,0 +1,217 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseSettings(t *testing.T) {
+	defaults := Settings{
+		Level:    zapcore.InfoLevel,
+		Sampling: map[string]Sampling{"": {Initial: 100, Thereafter: 100}},
+	}
+
+	tcs := []struct {
+		name    string
+		data    map[string]string
+		want    Settings
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			want: Settings{
+				Level:    zapcore.InfoLevel,
+				Levels:   map[string]zapcore.Level{},
+				Sampling: map[string]Sampling{"": {Initial: 100, Thereafter: 100}},
+				Redact:   map[string]bool{},
+			},
+		},
+		{
+			name: "everything",
+			data: map[string]string{
+				"level":            "warning",
+				"level.webhook":    "debug",
+				"level.controller": "2",
+				"sampling.webhook": "10/50",
+				"redact":           "object, oldObject",
+			},
+			want: Settings{
+				Level: zapcore.WarnLevel,
+				Levels: map[string]zapcore.Level{
+					"webhook":    zapcore.DebugLevel,
+					"controller": zapcore.Level(-2),
+				},
+				Sampling: map[string]Sampling{
+					"":        {Initial: 100, Thereafter: 100},
+					"webhook": {Initial: 10, Thereafter: 50},
+				},
+				Redact: map[string]bool{"object": true, "oldObject": true},
+			},
+		},
+		{
+			name:    "invalid level",
+			data:    map[string]string{"level.webhook": "loud"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid sampling",
+			data:    map[string]string{"sampling.webhook": "10"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			data:    map[string]string{"verbosity": "2"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseSettings(tc.data, defaults)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDynamicCore(t *testing.T) {
+	logs := &bytes.Buffer{}
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	config := NewDynamicConfig(Settings{Level: zapcore.InfoLevel})
+	logger := zap.New(zapcore.NewCore(enc, zapcore.AddSync(logs), AllLevels), zap.WrapCore(config.WrapCore))
+
+	logger.Named("webhook").Debug("dropped")
+	logger.Named("webhook").Info("kept", zap.String("object", "secret"))
+	if got := takeAll(t, logs); len(got) != 1 || got[0]["msg"] != "kept" {
+		t.Fatalf("got %v, want only the info message", got)
+	}
+
+	settings, err := ParseSettings(map[string]string{
+		"level.webhook":    "debug",
+		"sampling.webhook": "1/0",
+		"redact":           "object",
+	}, config.Defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set(settings)
+
+	logger.Named("webhook").Named("validation").Debug("kept", zap.String("object", "secret"))
+	logger.Named("webhook").Debug("kept")
+	logger.Named("webhook").Debug("kept")
+	logger.Named("controller").Debug("dropped")
+
+	got := takeAll(t, logs)
+	if len(got) != 1 {
+		t.Fatalf("got %d entries, want 1: %v", len(got), got)
+	}
+	if v := got[0]["object"]; v != RedactedValue {
+		t.Errorf("got object %v, want %q", v, RedactedValue)
+	}
+
+	config.Set(config.Defaults())
+	logger.Named("webhook").Debug("dropped")
+	if got := takeAll(t, logs); len(got) != 0 {
+		t.Errorf("got %v, want no entries after restoring defaults", got)
+	}
+}
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("password", c.Password)
+	return nil
+}
+
+func TestDynamicCore_Redact(t *testing.T) {
+	logs := &bytes.Buffer{}
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	config := NewDynamicConfig(Settings{Level: zapcore.InfoLevel})
+	logger := zap.New(zapcore.NewCore(enc, zapcore.AddSync(logs), AllLevels), zap.WrapCore(config.WrapCore))
+
+	// Fields attached before redaction is configured are redacted once it is.
+	withContext := logger.With(zap.String("password", "secret-context"))
+	settings, err := ParseSettings(map[string]string{"redact": "password"}, config.Defaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set(settings)
+
+	withContext.Info("context")
+	logger.Info("nested",
+		zap.Any("request", map[string]interface{}{"users": []interface{}{map[string]interface{}{"name": "a", "password": "secret-map"}}}),
+		zap.Any("struct", credentials{User: "b", Password: "secret-struct"}),
+		zap.Object("object", credentials{User: "c", Password: "secret-object"}),
+		zap.Inline(credentials{User: "d", Password: "secret-inline"}),
+		zap.Int("count", 3),
+	)
+	got := takeAll(t, logs)
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2: %v", len(got), got)
+	}
+	if v := got[0]["password"]; v != RedactedValue {
+		t.Errorf("got context password %v, want %q", v, RedactedValue)
+	}
+	for _, leak := range []string{"secret-context", "secret-map", "secret-struct", "secret-object", "secret-inline"} {
+		for _, entry := range got {
+			if b, _ := json.Marshal(entry); strings.Contains(string(b), `"`+leak+`"`) {
+				t.Errorf("got password %q written in %s", leak, b)
+			}
+		}
+	}
+	nested := got[1]
+	if v := nested["request"].(map[string]interface{})["users"].([]interface{})[0].(map[string]interface{})["name"]; v != "a" {
+		t.Errorf("got name %v, want other nested fields kept", v)
+	}
+	if v := nested["object"].(map[string]interface{})["user"]; v != "c" {
+		t.Errorf("got user %v, want other object fields kept", v)
+	}
+	if v := nested["user"]; v != "d" {
+		t.Errorf("got inline user %v, want other inline fields kept", v)
+	}
+	if v := nested["count"]; v != float64(3) {
+		t.Errorf("got count %v, want 3", v)
+	}
+
+	// Removing redaction writes the values of fields attached before.
+	config.Set(config.Defaults())
+	withContext.Info("context")
+	if got := takeAll(t, logs); len(got) != 1 || got[0]["password"] != "secret-context" {
+		t.Errorf("got %v, want the context password written", got)
+	}
+}
+
+// takeAll returns the JSON log entries written to buf, and resets it.
+func takeAll(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	buf.Reset()
+	return entries
+}
@@ -13,6 +13,7 @@ const (
 	ConstraintAPIVersion = "constraint_api_version"
 	ConstraintStatus     = "constraint_status"
 	ConstraintAction     = "constraint_action"
+	ConstraintSeverity   = "constraint_severity"
 	AuditID              = "audit_id"
 	ConstraintViolations = "constraint_violations"
 	ResourceGroup        = "resource_group"
@@ -20,6 +21,7 @@ const (
 	ResourceAPIVersion   = "resource_api_version"
 	ResourceNamespace    = "resource_namespace"
 	ResourceName         = "resource_name"
+	RequestSubResource   = "request_subresource"
 	RequestUsername      = "request_username"
 	MutationApplied      = "mutation_applied"
 	Mutator              = "mutator"
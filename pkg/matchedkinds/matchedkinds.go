@@ -0,0 +1,148 @@
+// Package matchedkinds tracks the kinds selected by the match of each
+// constraint, so the validation webhook can deny requests for kinds which no
+// constraint matches. This lets high-security clusters require an explicit
+// policy for every kind the webhook is registered for, rather than allowing
+// unmatched kinds implicitly.
+package matchedkinds
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// DenyUnmatched is whether requests for kinds which no constraint matches
+	// are denied.
+	DenyUnmatched = flag.Bool("deny-unmatched-kinds", false, "(alpha) deny admission requests for kinds which are not selected by the match of any constraint. Requests for Gatekeeper resources are always reviewed as usual")
+
+	// DenyMessage is the message of requests denied because no constraint
+	// matches their kind.
+	DenyMessage = flag.String("deny-unmatched-kinds-message", "no constraint matches this kind, and unmatched kinds are denied", "(alpha) the message returned for requests denied by --deny-unmatched-kinds")
+)
+
+// selector selects kinds as an entry of the kinds of a constraint match.
+type selector struct {
+	groups []string
+	kinds  []string
+}
+
+// selectAll is the selector of a constraint without kinds in its match.
+var selectAll = []selector{{groups: []string{"*"}, kinds: []string{"*"}}}
+
+// Kinds holds the kinds selected by each constraint.
+type Kinds struct {
+	mux         sync.RWMutex
+	constraints map[string][]selector
+}
+
+var kinds = NewKinds()
+
+// Get returns the Kinds of this process.
+func Get() *Kinds {
+	return kinds
+}
+
+// NewKinds returns Kinds without constraints.
+func NewKinds() *Kinds {
+	return &Kinds{constraints: make(map[string][]selector)}
+}
+
+// Set records the kinds selected by constraint.
+func (k *Kinds) Set(constraint *unstructured.Unstructured) error {
+	selectors, err := selectorsFor(constraint)
+	if err != nil {
+		return err
+	}
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	k.constraints[key(constraint)] = selectors
+	return nil
+}
+
+// Remove forgets the kinds selected by constraint.
+func (k *Kinds) Remove(constraint *unstructured.Unstructured) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	delete(k.constraints, key(constraint))
+}
+
+// Matched returns whether any constraint selects gvk.
+func (k *Kinds) Matched(gvk schema.GroupVersionKind) bool {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+	for _, selectors := range k.constraints {
+		for _, s := range selectors {
+			if contains(s.groups, gvk.Group) && contains(s.kinds, gvk.Kind) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Exempt returns whether requests for gvk are reviewed even if no constraint
+// matches it. Gatekeeper resources are exempt so that constraints can be
+// created in the first place.
+func Exempt(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == "gatekeeper.sh" || strings.HasSuffix(gvk.Group, ".gatekeeper.sh")
+}
+
+// selectorsFor returns the selectors of the kinds of a constraint match, in
+// the same way as the validation target: a constraint without kinds selects
+// every kind, while a selector without apiGroups or kinds selects none.
+func selectorsFor(constraint *unstructured.Unstructured) ([]selector, error) {
+	field, found, err := unstructured.NestedFieldNoCopy(constraint.Object, "spec", "match", "kinds")
+	if err != nil {
+		return nil, err
+	}
+	if !found || field == nil {
+		return selectAll, nil
+	}
+	entries, ok := field.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.match.kinds of %s is %T, not a list", key(constraint), field)
+	}
+	var selectors []selector
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		selectors = append(selectors, selector{
+			groups: stringsIn(entry["apiGroups"]),
+			kinds:  stringsIn(entry["kinds"]),
+		})
+	}
+	return selectors, nil
+}
+
+// stringsIn returns the strings in a list of a constraint match, which may
+// contain nulls.
+func stringsIn(list interface{}) []string {
+	items, _ := list.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func key(constraint *unstructured.Unstructured) string {
+	return constraint.GetKind() + "/" + constraint.GetName()
+}
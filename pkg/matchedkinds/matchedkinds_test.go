@@ -0,0 +1,140 @@
+package matchedkinds
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func constraint(name string, match map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{},
+	}}
+	u.SetKind("K8sRequiredLabels")
+	u.SetName(name)
+	if match != nil {
+		u.Object["spec"] = map[string]interface{}{"match": match}
+	}
+	return u
+}
+
+func kindsMatch(groups, kinds []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"kinds": []interface{}{map[string]interface{}{"apiGroups": groups, "kinds": kinds}},
+	}
+}
+
+var (
+	pod        = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	deployment = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+func TestMatched(t *testing.T) {
+	tcs := []struct {
+		name        string
+		constraints []*unstructured.Unstructured
+		gvk         schema.GroupVersionKind
+		want        bool
+	}{
+		{
+			name: "no constraints",
+			gvk:  pod,
+			want: false,
+		},
+		{
+			name:        "constraint without match",
+			constraints: []*unstructured.Unstructured{constraint("all", nil)},
+			gvk:         deployment,
+			want:        true,
+		},
+		{
+			name:        "constraint with null kinds",
+			constraints: []*unstructured.Unstructured{constraint("all", map[string]interface{}{"kinds": nil})},
+			gvk:         deployment,
+			want:        true,
+		},
+		{
+			name:        "kind selected",
+			constraints: []*unstructured.Unstructured{constraint("pods", kindsMatch([]interface{}{""}, []interface{}{"Pod"}))},
+			gvk:         pod,
+			want:        true,
+		},
+		{
+			name:        "kind not selected",
+			constraints: []*unstructured.Unstructured{constraint("pods", kindsMatch([]interface{}{""}, []interface{}{"Pod"}))},
+			gvk:         deployment,
+			want:        false,
+		},
+		{
+			name:        "wildcard group",
+			constraints: []*unstructured.Unstructured{constraint("deployments", kindsMatch([]interface{}{"*"}, []interface{}{nil, "Deployment"}))},
+			gvk:         deployment,
+			want:        true,
+		},
+		{
+			name:        "selector without groups selects nothing",
+			constraints: []*unstructured.Unstructured{constraint("pods", kindsMatch(nil, []interface{}{"Pod"}))},
+			gvk:         pod,
+			want:        false,
+		},
+		{
+			name: "any constraint selects",
+			constraints: []*unstructured.Unstructured{
+				constraint("pods", kindsMatch([]interface{}{""}, []interface{}{"Pod"})),
+				constraint("deployments", kindsMatch([]interface{}{"apps"}, []interface{}{"*"})),
+			},
+			gvk:  deployment,
+			want: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			k := NewKinds()
+			for _, c := range tc.constraints {
+				if err := k.Set(c); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := k.Matched(tc.gvk); got != tc.want {
+				t.Errorf("got Matched(%v) = %v, want %v", tc.gvk, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	k := NewKinds()
+	c := constraint("pods", kindsMatch([]interface{}{""}, []interface{}{"Pod"}))
+	if err := k.Set(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Set(constraint("pods", kindsMatch([]interface{}{"apps"}, []interface{}{"Deployment"}))); err != nil {
+		t.Fatal(err)
+	}
+	if k.Matched(pod) {
+		t.Error("got Pod matched by an updated constraint which no longer selects it")
+	}
+	k.Remove(c)
+	if k.Matched(deployment) {
+		t.Error("got Deployment matched by a removed constraint")
+	}
+}
+
+func TestExempt(t *testing.T) {
+	tcs := []struct {
+		group string
+		want  bool
+	}{
+		{group: "constraints.gatekeeper.sh", want: true},
+		{group: "templates.gatekeeper.sh", want: true},
+		{group: "gatekeeper.sh", want: true},
+		{group: "notgatekeeper.sh", want: false},
+		{group: "apps", want: false},
+	}
+	for _, tc := range tcs {
+		if got := Exempt(schema.GroupVersionKind{Group: tc.group}); got != tc.want {
+			t.Errorf("got Exempt(%q) = %v, want %v", tc.group, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"flag"
+	"sync"
+)
+
+// OverflowTagValue is recorded in place of a tag value once a LabelLimiter has
+// seen its limit of distinct values.
+const OverflowTagValue = "other"
+
+var (
+	constraintNameLabel = flag.Bool("metrics-constraint-name-label", false, "(alpha) label per-template evaluation metrics with the constraint name. The number of distinct constraint names is capped by --metrics-constraint-name-limit")
+	constraintNameLimit = flag.Int("metrics-constraint-name-limit", 100, "(alpha) maximum number of distinct constraint names recorded in per-template evaluation metrics, further constraints are reported as \"other\"")
+
+	constraintNamesOnce sync.Once
+	constraintNames     *LabelLimiter
+)
+
+// LabelLimiter guards a metric tag against unbounded cardinality. The first
+// limit distinct values are passed through, every other value is collapsed
+// into OverflowTagValue.
+type LabelLimiter struct {
+	mux   sync.Mutex
+	limit int
+	seen  map[string]struct{}
+}
+
+// NewLabelLimiter creates a LabelLimiter which passes through at most limit
+// distinct values.
+func NewLabelLimiter(limit int) *LabelLimiter {
+	return &LabelLimiter{
+		limit: limit,
+		seen:  make(map[string]struct{}),
+	}
+}
+
+// Value returns v if it has been seen before or the limit has not yet been
+// reached, otherwise OverflowTagValue.
+func (l *LabelLimiter) Value(v string) string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if _, ok := l.seen[v]; ok {
+		return v
+	}
+	if len(l.seen) >= l.limit {
+		return OverflowTagValue
+	}
+	l.seen[v] = struct{}{}
+	return v
+}
+
+// ConstraintNameTagValue returns the value to record for a constraint name tag,
+// and whether the tag should be recorded at all. Constraint names are only
+// recorded when enabled with --metrics-constraint-name-label.
+func ConstraintNameTagValue(name string) (string, bool) {
+	if !*constraintNameLabel {
+		return "", false
+	}
+	constraintNamesOnce.Do(func() {
+		constraintNames = NewLabelLimiter(*constraintNameLimit)
+	})
+	return constraintNames.Value(name), true
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import "testing"
+
+func TestLabelLimiter(t *testing.T) {
+	l := NewLabelLimiter(2)
+
+	tcs := []struct {
+		value string
+		want  string
+	}{
+		{value: "a", want: "a"},
+		{value: "b", want: "b"},
+		{value: "c", want: OverflowTagValue},
+		{value: "a", want: "a"},
+		{value: "d", want: OverflowTagValue},
+		{value: "b", want: "b"},
+	}
+
+	for _, tc := range tcs {
+		if got := l.Value(tc.value); got != tc.want {
+			t.Errorf("Value(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestConstraintNameTagValue(t *testing.T) {
+	if _, ok := ConstraintNameTagValue("foo"); ok {
+		t.Error("constraint name tag should be disabled by default")
+	}
+
+	*constraintNameLabel = true
+	defer func() { *constraintNameLabel = false }()
+
+	got, ok := ConstraintNameTagValue("foo")
+	if !ok {
+		t.Fatal("constraint name tag should be enabled")
+	}
+	if got != "foo" {
+		t.Errorf("got %q, want %q", got, "foo")
+	}
+}
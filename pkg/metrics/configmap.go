@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const configMapResync = 10 * time.Minute
+
+// Keys of the metrics ConfigMap. Each overrides the flag of the same name.
+const (
+	backendKey        = "backend"
+	prometheusPortKey = "prometheus-port"
+	otlpEndpointKey   = "otlp-endpoint"
+	exportIntervalKey = "export-interval"
+)
+
+// ParseSettings returns defaults overridden by the data of a metrics
+// ConfigMap.
+func ParseSettings(data map[string]string, defaults Settings) (Settings, error) {
+	s := defaults
+	for k, v := range data {
+		v = strings.TrimSpace(v)
+		switch k {
+		case backendKey:
+			s.Backend = strings.ToLower(v)
+			switch s.Backend {
+			case prometheusBackend, otlpBackend, stdoutBackend:
+			default:
+				return Settings{}, fmt.Errorf("unsupported metrics backend %q", v)
+			}
+		case prometheusPortKey:
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return Settings{}, fmt.Errorf("invalid %s: %w", k, err)
+			}
+			s.PrometheusPort = port
+		case otlpEndpointKey:
+			s.OTLPEndpoint = v
+		case exportIntervalKey:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return Settings{}, fmt.Errorf("invalid %s: %w", k, err)
+			}
+			s.ExportInterval = d
+		default:
+			return Settings{}, fmt.Errorf("unknown metrics configuration key %q", k)
+		}
+	}
+	return s, nil
+}
+
+var _ manager.Runnable = &configMapWatcher{}
+
+// configMapWatcher switches the metrics backend whenever the metrics
+// ConfigMap changes. Deleting the ConfigMap restores the backend selected by
+// flags.
+type configMapWatcher struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	runner    *runner
+}
+
+func newConfigMapWatcher(client kubernetes.Interface, namespace, name string, r *runner) *configMapWatcher {
+	return &configMapWatcher{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		runner:    r,
+	}
+}
+
+// Start implements manager.Runnable.
+func (w *configMapWatcher) Start(ctx context.Context) error {
+	lw := cache.NewListWatchFromClient(
+		w.client.CoreV1().RESTClient(),
+		"configmaps",
+		w.namespace,
+		fields.OneTermEqualSelector("metadata.name", w.name))
+
+	_, informer := cache.NewInformer(lw, &corev1.ConfigMap{}, configMapResync, cache.ResourceEventHandlerFuncs{
+		AddFunc: w.apply,
+		UpdateFunc: func(_, obj interface{}) {
+			w.apply(obj)
+		},
+		DeleteFunc: func(interface{}) {
+			log.Info("metrics configuration removed, restoring defaults", "configmap", w.namespace+"/"+w.name)
+			if err := w.runner.apply(defaultSettings()); err != nil {
+				log.Error(err, "unable to restore the default metrics backend")
+			}
+		},
+	})
+	informer.Run(ctx.Done())
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica
+// exports its own metrics.
+func (w *configMapWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *configMapWatcher) apply(obj interface{}) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	settings, err := ParseSettings(cm.Data, defaultSettings())
+	if err != nil {
+		// Keep exporting with the last valid configuration.
+		log.Error(err, "invalid metrics configuration, ignoring", "configmap", w.namespace+"/"+w.name)
+		return
+	}
+	if err := w.runner.apply(settings); err != nil {
+		log.Error(err, "unable to switch metrics backend", "backend", settings.Backend)
+		return
+	}
+	log.Info("applied metrics configuration", "resourceVersion", cm.GetResourceVersion())
+}
@@ -5,27 +5,90 @@ import (
 	"flag"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
-	"go.opencensus.io/stats/view"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 var (
-	metricsBackend = flag.String("metrics-backend", "Prometheus", "Backend used for metrics")
+	metricsBackend = flag.String("metrics-backend", "Prometheus", "Backend used for metrics. One of [`prometheus`, `otlp`, `stdout`]")
 	prometheusPort = flag.Int("prometheus-port", 8888, "Prometheus port for metrics backend")
+	otlpEndpoint   = flag.String("otlp-metrics-endpoint", "http://localhost:4318/v1/metrics", "(alpha) URL which metrics are pushed to with OTLP over HTTP, when the metrics backend is `otlp`")
+	exportInterval = flag.Duration("metrics-export-interval", 10*time.Second, "(alpha) interval at which metrics are pushed, when the metrics backend is `otlp` or `stdout`")
+	metricsConfig  = flag.String("metrics-configmap", "", "(alpha) name of a ConfigMap in the gatekeeper namespace which selects and configures the metrics backend at runtime, overriding the metrics flags. Disabled if empty")
 )
 
-const prometheusExporter = "prometheus"
+const (
+	prometheusBackend = "prometheus"
+	otlpBackend       = "otlp"
+	stdoutBackend     = "stdout"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Exporter exports the metrics recorded by this process to a backend.
+type Exporter interface {
+	// Start starts exporting metrics, returning once the exporter is running.
+	Start() error
+	// Stop stops exporting metrics.
+	Stop(ctx context.Context) error
+}
+
+// Settings select and configure the metrics backend.
+type Settings struct {
+	Backend        string
+	PrometheusPort int
+	OTLPEndpoint   string
+	ExportInterval time.Duration
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		Backend:        strings.ToLower(*metricsBackend),
+		PrometheusPort: *prometheusPort,
+		OTLPEndpoint:   *otlpEndpoint,
+		ExportInterval: *exportInterval,
+	}
+}
+
+// newExporter returns the Exporter for s.
+func newExporter(s Settings) (Exporter, error) {
+	switch s.Backend {
+	case prometheusBackend:
+		return newPrometheusExporter(s.PrometheusPort)
+	case otlpBackend:
+		return newOTLPExporter(s.OTLPEndpoint, s.ExportInterval)
+	case stdoutBackend:
+		return newStdoutExporter(s.ExportInterval)
+	default:
+		return nil, fmt.Errorf("unsupported metrics backend %v", s.Backend)
+	}
+}
 
 var _ manager.Runnable = &runner{}
 
 type runner struct {
 	mgr manager.Manager
+
+	mux      sync.Mutex
+	settings Settings
+	exporter Exporter
 }
 
 func AddToManager(m manager.Manager) error {
 	mr := new(m)
-	return m.Add(mr)
+	if err := m.Add(mr); err != nil {
+		return err
+	}
+	if *metricsConfig == "" {
+		return nil
+	}
+	log.Info("watching metrics configuration", "configmap", *metricsConfig)
+	w := newConfigMapWatcher(kubernetes.NewForConfigOrDie(m.GetConfig()), util.GetNamespace(), *metricsConfig, mr)
+	return m.Add(w)
 }
 
 func new(mgr manager.Manager) *runner {
@@ -39,51 +102,57 @@ func new(mgr manager.Manager) *runner {
 func (r *runner) Start(ctx context.Context) error {
 	log.Info("Starting metrics runner")
 	defer log.Info("Stopping metrics runner workers")
-	errCh := make(chan error)
-	go func() { errCh <- r.newMetricsExporter() }()
-	select {
-	case <-ctx.Done():
-		return r.shutdownMetricsExporter(ctx)
-	case err := <-errCh:
-		if err != nil {
-			return err
-		}
+	if err := r.apply(defaultSettings()); err != nil {
+		return err
 	}
-	return nil
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return r.shutdownMetricsExporter(shutdownCtx)
 }
 
-func (r *runner) newMetricsExporter() error {
-	var e view.Exporter
-	var err error
-	mb := strings.ToLower(*metricsBackend)
-	log.Info("metrics", "backend", mb)
-	switch mb {
-	// Prometheus is the only exporter for now
-	case prometheusExporter:
-		e, err = newPrometheusExporter()
-	default:
-		err = fmt.Errorf("unsupported metrics backend %v", *metricsBackend)
+// apply switches to the backend selected by s, unless it is already in use.
+// If the new backend fails to start, the previous one is restarted.
+func (r *runner) apply(s Settings) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.exporter != nil && s == r.settings {
+		return nil
 	}
+	log.Info("metrics", "backend", s.Backend)
+	e, err := newExporter(s)
 	if err != nil {
 		return err
 	}
-	view.RegisterExporter(e)
+
+	previous := r.settings
+	if err := r.stopLocked(context.Background()); err != nil {
+		log.Error(err, "unable to stop metrics exporter", "backend", previous.Backend)
+	}
+	if err := e.Start(); err != nil {
+		if previous.Backend != "" {
+			if restored, rErr := newExporter(previous); rErr == nil && restored.Start() == nil {
+				r.exporter, r.settings = restored, previous
+			}
+		}
+		return err
+	}
+	r.exporter, r.settings = e, s
 	return nil
 }
 
 func (r *runner) shutdownMetricsExporter(ctx context.Context) error {
-	mb := strings.ToLower(*metricsBackend)
-	switch mb {
-	case prometheusExporter:
-		log.Info("shutting down prometheus server")
-		if curPromSrv != nil {
-			if err := curPromSrv.Shutdown(ctx); err != nil {
-				return err
-			}
-		}
-		return nil
-	default:
-		log.Info("nothing to shutdown for unsupported metrics backend %v", *metricsBackend)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.stopLocked(ctx)
+}
+
+func (r *runner) stopLocked(ctx context.Context) error {
+	if r.exporter == nil {
 		return nil
 	}
+	log.Info("shutting down metrics exporter", "backend", r.settings.Backend)
+	err := r.exporter.Stop(ctx)
+	r.exporter = nil
+	return err
 }
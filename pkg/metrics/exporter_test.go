@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/metric/metricdata"
+)
+
+var (
+	start = time.Unix(100, 0)
+	end   = time.Unix(160, 0)
+
+	requestCount = &metricdata.Metric{
+		Descriptor: metricdata.Descriptor{
+			Name:        "request_count",
+			Description: "Total number of requests",
+			Unit:        metricdata.UnitDimensionless,
+			Type:        metricdata.TypeCumulativeInt64,
+			LabelKeys:   []metricdata.LabelKey{{Key: "admission_status"}},
+		},
+		TimeSeries: []*metricdata.TimeSeries{{
+			LabelValues: []metricdata.LabelValue{metricdata.NewLabelValue("allow")},
+			Points:      []metricdata.Point{metricdata.NewInt64Point(end, 3)},
+			StartTime:   start,
+		}},
+	}
+
+	requestDuration = &metricdata.Metric{
+		Descriptor: metricdata.Descriptor{
+			Name: "validation_request_duration",
+			Unit: metricdata.UnitMilliseconds,
+			Type: metricdata.TypeCumulativeDistribution,
+		},
+		TimeSeries: []*metricdata.TimeSeries{{
+			Points: []metricdata.Point{metricdata.NewDistributionPoint(end, &metricdata.Distribution{
+				Count:         3,
+				Sum:           1.5,
+				BucketOptions: &metricdata.BucketOptions{Bounds: []float64{0.1, 1}},
+				Buckets:       []metricdata.Bucket{{Count: 1}, {Count: 1}, {Count: 1}},
+			})},
+			StartTime: start,
+		}},
+	}
+
+	constraints = &metricdata.Metric{
+		Descriptor: metricdata.Descriptor{
+			Name: "constraints",
+			Type: metricdata.TypeGaugeFloat64,
+		},
+		TimeSeries: []*metricdata.TimeSeries{{
+			Points: []metricdata.Point{metricdata.NewFloat64Point(end, 7)},
+		}},
+	}
+)
+
+func strPtr(s string) *string { return &s }
+
+func float64Ptr(f float64) *float64 { return &f }
+
+func TestToOTLP(t *testing.T) {
+	got := toOTLP([]*metricdata.Metric{requestCount, requestDuration, constraints})
+	want := []otlpMetric{
+		{
+			Name:        "gatekeeper_request_count",
+			Description: "Total number of requests",
+			Unit:        "1",
+			Sum: &otlpSum{
+				DataPoints: []otlpNumberDataPoint{{
+					Attributes:        []otlpAttribute{{Key: "admission_status", Value: otlpAnyValue{StringValue: "allow"}}},
+					StartTimeUnixNano: "100000000000",
+					TimeUnixNano:      "160000000000",
+					AsInt:             strPtr("3"),
+				}},
+				AggregationTemporality: otlpCumulative,
+				IsMonotonic:            true,
+			},
+		},
+		{
+			Name: "gatekeeper_validation_request_duration",
+			Unit: "ms",
+			Histogram: &otlpHistogram{
+				DataPoints: []otlpHistogramDataPoint{{
+					StartTimeUnixNano: "100000000000",
+					TimeUnixNano:      "160000000000",
+					Count:             "3",
+					Sum:               1.5,
+					BucketCounts:      []string{"1", "1", "1"},
+					ExplicitBounds:    []float64{0.1, 1},
+				}},
+				AggregationTemporality: otlpCumulative,
+			},
+		},
+		{
+			Name: "gatekeeper_constraints",
+			Gauge: &otlpGauge{DataPoints: []otlpNumberDataPoint{{
+				TimeUnixNano: "160000000000",
+				AsDouble:     float64Ptr(7),
+			}}},
+		},
+	}
+
+	if len(got.ResourceMetrics) != 1 || len(got.ResourceMetrics[0].ScopeMetrics) != 1 {
+		t.Fatalf("got %+v, want a single scope", got)
+	}
+	if diff := cmp.Diff(want, got.ResourceMetrics[0].ScopeMetrics[0].Metrics); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("got content type %q, want application/json", ct)
+		}
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	e, err := newOTLPExporter(srv.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ExportMetrics(context.Background(), []*metricdata.Metric{requestCount}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := otlpRequest{}
+	if err := json.Unmarshal(received, &req); err != nil {
+		t.Fatal(err)
+	}
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 1 || metrics[0].Name != "gatekeeper_request_count" {
+		t.Errorf("got metrics %+v, want gatekeeper_request_count", metrics)
+	}
+
+	if _, err := newOTLPExporter("", time.Second); err == nil {
+		t.Error("got no error creating an otlp exporter without an endpoint")
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	e, err := newStdoutExporter(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	e.out = out
+	if err := e.ExportMetrics(context.Background(), []*metricdata.Metric{requestCount, constraints}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one per metric: %q", len(lines), out.String())
+	}
+	m := otlpMetric{}
+	if err := json.Unmarshal([]byte(lines[1]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "gatekeeper_constraints" || m.Gauge == nil {
+		t.Errorf("got %+v, want the constraints gauge", m)
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	defaults := Settings{Backend: "prometheus", PrometheusPort: 8888, OTLPEndpoint: "http://localhost:4318/v1/metrics", ExportInterval: 10 * time.Second}
+
+	tcs := []struct {
+		name    string
+		data    map[string]string
+		want    Settings
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			want: defaults,
+		},
+		{
+			name: "otlp",
+			data: map[string]string{"backend": "OTLP", "otlp-endpoint": "http://collector:4318/v1/metrics", "export-interval": "30s"},
+			want: Settings{Backend: "otlp", PrometheusPort: 8888, OTLPEndpoint: "http://collector:4318/v1/metrics", ExportInterval: 30 * time.Second},
+		},
+		{
+			name: "prometheus port",
+			data: map[string]string{"prometheus-port": "9999"},
+			want: Settings{Backend: "prometheus", PrometheusPort: 9999, OTLPEndpoint: "http://localhost:4318/v1/metrics", ExportInterval: 10 * time.Second},
+		},
+		{
+			name:    "unknown backend",
+			data:    map[string]string{"backend": "statsd"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid interval",
+			data:    map[string]string{"export-interval": "often"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			data:    map[string]string{"backends": "stdout"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseSettings(tc.data, defaults)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRunnerApply(t *testing.T) {
+	r := new(nil)
+	stdout := Settings{Backend: stdoutBackend, ExportInterval: time.Second}
+	if err := r.apply(stdout); err != nil {
+		t.Fatal(err)
+	}
+	first := r.exporter
+	if _, ok := first.(*stdoutExporter); !ok {
+		t.Fatalf("got exporter %T, want stdout", first)
+	}
+
+	// Applying the same settings keeps the running exporter.
+	if err := r.apply(stdout); err != nil {
+		t.Fatal(err)
+	}
+	if r.exporter != first {
+		t.Error("got a new exporter for unchanged settings")
+	}
+
+	// An exporter which fails to start is replaced by the previous backend.
+	if err := r.apply(Settings{Backend: otlpBackend, OTLPEndpoint: "http://localhost:4318", ExportInterval: time.Millisecond}); err == nil {
+		t.Fatal("got no error starting an exporter with too short an interval")
+	}
+	if _, ok := r.exporter.(*stdoutExporter); !ok || r.settings != stdout {
+		t.Errorf("got exporter %T with settings %+v, want the stdout exporter restored", r.exporter, r.settings)
+	}
+
+	if err := r.shutdownMetricsExporter(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r.exporter != nil {
+		t.Error("got an exporter after shutdown")
+	}
+}
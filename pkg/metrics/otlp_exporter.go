@@ -0,0 +1,302 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/metric/metricexport"
+)
+
+const (
+	otlpTimeout = 10 * time.Second
+	// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE. Every metric
+	// recorded by Gatekeeper is cumulative since the process started.
+	otlpCumulative = 2
+)
+
+// otlpExporter pushes metrics to an OTLP endpoint over HTTP, encoded as JSON
+// so that no protobuf or gRPC dependency is needed.
+type otlpExporter struct {
+	*intervalExporter
+	endpoint string
+	client   *http.Client
+}
+
+func newOTLPExporter(endpoint string, interval time.Duration) (*otlpExporter, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("the otlp metrics backend requires an endpoint")
+	}
+	e := &otlpExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: otlpTimeout},
+	}
+	ie, err := newIntervalExporter(e, interval)
+	if err != nil {
+		return nil, err
+	}
+	e.intervalExporter = ie
+	return e, nil
+}
+
+// ExportMetrics implements metricexport.Exporter.
+func (e *otlpExporter) ExportMetrics(ctx context.Context, metrics []*metricdata.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(toOTLP(metrics))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Error(err, "unable to push metrics", "endpoint", e.endpoint)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("otlp metrics endpoint %s returned %s", e.endpoint, resp.Status)
+		log.Error(err, "unable to push metrics")
+		return err
+	}
+	return nil
+}
+
+// The types below are the JSON encoding of the subset of the OTLP metrics
+// protocol which Gatekeeper's metrics are converted to. 64-bit integers are
+// encoded as strings, as required by the protobuf JSON mapping.
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Unit        string         `json:"unit,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             *string         `json:"asInt,omitempty"`
+	AsDouble          *float64        `json:"asDouble,omitempty"`
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []string        `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64       `json:"explicitBounds,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func toOTLP(metrics []*metricdata.Metric) otlpRequest {
+	var out []otlpMetric
+	for _, m := range metrics {
+		if om, ok := toOTLPMetric(m); ok {
+			out = append(out, om)
+		}
+	}
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpAnyValue{StringValue: namespace}},
+		}},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: namespace},
+			Metrics: out,
+		}},
+	}}}
+}
+
+// toOTLPMetric converts m, returning false for types which OTLP cannot
+// represent in the same way. Gatekeeper does not record summaries.
+func toOTLPMetric(m *metricdata.Metric) (otlpMetric, bool) {
+	d := m.Descriptor
+	om := otlpMetric{
+		Name:        metricFullName(d.Name),
+		Description: d.Description,
+		Unit:        string(d.Unit),
+	}
+	switch d.Type {
+	case metricdata.TypeGaugeInt64, metricdata.TypeGaugeFloat64:
+		om.Gauge = &otlpGauge{DataPoints: numberPoints(m)}
+	case metricdata.TypeCumulativeInt64, metricdata.TypeCumulativeFloat64:
+		om.Sum = &otlpSum{
+			DataPoints:             numberPoints(m),
+			AggregationTemporality: otlpCumulative,
+			IsMonotonic:            true,
+		}
+	case metricdata.TypeCumulativeDistribution:
+		om.Histogram = &otlpHistogram{
+			DataPoints:             histogramPoints(m),
+			AggregationTemporality: otlpCumulative,
+		}
+	default:
+		return otlpMetric{}, false
+	}
+	return om, true
+}
+
+func numberPoints(m *metricdata.Metric) []otlpNumberDataPoint {
+	var points []otlpNumberDataPoint
+	for _, ts := range m.TimeSeries {
+		attrs := attributes(m.Descriptor.LabelKeys, ts.LabelValues)
+		for _, p := range ts.Points {
+			dp := otlpNumberDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: unixNano(ts.StartTime),
+				TimeUnixNano:      unixNano(p.Time),
+			}
+			switch v := p.Value.(type) {
+			case int64:
+				s := strconv.FormatInt(v, 10)
+				dp.AsInt = &s
+			case float64:
+				dp.AsDouble = &v
+			default:
+				continue
+			}
+			points = append(points, dp)
+		}
+	}
+	return points
+}
+
+func histogramPoints(m *metricdata.Metric) []otlpHistogramDataPoint {
+	var points []otlpHistogramDataPoint
+	for _, ts := range m.TimeSeries {
+		attrs := attributes(m.Descriptor.LabelKeys, ts.LabelValues)
+		for _, p := range ts.Points {
+			dist, ok := p.Value.(*metricdata.Distribution)
+			if !ok {
+				continue
+			}
+			dp := otlpHistogramDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: unixNano(ts.StartTime),
+				TimeUnixNano:      unixNano(p.Time),
+				Count:             strconv.FormatInt(dist.Count, 10),
+				Sum:               dist.Sum,
+			}
+			if dist.BucketOptions != nil {
+				dp.ExplicitBounds = dist.BucketOptions.Bounds
+				for _, b := range dist.Buckets {
+					dp.BucketCounts = append(dp.BucketCounts, strconv.FormatInt(b.Count, 10))
+				}
+			}
+			points = append(points, dp)
+		}
+	}
+	return points
+}
+
+func attributes(keys []metricdata.LabelKey, values []metricdata.LabelValue) []otlpAttribute {
+	var attrs []otlpAttribute
+	for i, k := range keys {
+		if i >= len(values) || !values[i].Present {
+			continue
+		}
+		attrs = append(attrs, otlpAttribute{Key: k.Key, Value: otlpAnyValue{StringValue: values[i].Value}})
+	}
+	return attrs
+}
+
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// metricFullName returns the name of a metric as exported to every backend,
+// which is the name Prometheus scrapes it by.
+func metricFullName(name string) string {
+	return namespace + "_" + name
+}
+
+// intervalExporter periodically reads the metrics recorded by this process
+// and exports them with a metricexport.Exporter.
+type intervalExporter struct {
+	reader *metricexport.IntervalReader
+}
+
+func newIntervalExporter(e metricexport.Exporter, interval time.Duration) (*intervalExporter, error) {
+	r, err := metricexport.NewIntervalReader(metricexport.NewReader(), e)
+	if err != nil {
+		return nil, err
+	}
+	r.ReportingInterval = interval
+	return &intervalExporter{reader: r}, nil
+}
+
+// Start implements Exporter.
+func (e *intervalExporter) Start() error {
+	return e.reader.Start()
+}
+
+// Stop implements Exporter. Metrics are exported one last time before it
+// returns.
+func (e *intervalExporter) Stop(context.Context) error {
+	e.reader.Stop()
+	e.reader.Flush()
+	return nil
+}
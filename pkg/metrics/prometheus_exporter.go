@@ -1,11 +1,14 @@
 package metrics
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 
 	"contrib.go.opencensus.io/exporter/prometheus"
-	"go.opencensus.io/stats/view"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	ctlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -16,26 +19,59 @@ var log = logf.Log.WithName("metrics")
 
 const namespace = "gatekeeper"
 
-func newPrometheusExporter() (view.Exporter, error) {
-	e, err := prometheus.NewExporter(prometheus.Options{
-		Namespace:  namespace,
-		Registerer: ctlmetrics.Registry,
-		Gatherer:   ctlmetrics.Registry,
+var (
+	// The OpenCensus collector registers itself with the registry, so it is
+	// created once and shared by every Prometheus exporter.
+	promOnce     sync.Once
+	promExporter *prometheus.Exporter
+	promErr      error
+)
+
+type prometheusExporter struct {
+	exporter *prometheus.Exporter
+	port     int
+	srv      *http.Server
+}
+
+func newPrometheusExporter(port int) (*prometheusExporter, error) {
+	promOnce.Do(func() {
+		promExporter, promErr = prometheus.NewExporter(prometheus.Options{
+			Namespace:  namespace,
+			Registerer: ctlmetrics.Registry,
+			Gatherer:   ctlmetrics.Registry,
+		})
 	})
-	if err != nil {
-		log.Error(err, "Failed to create the Prometheus exporter.")
-		return nil, err
+	if promErr != nil {
+		log.Error(promErr, "Failed to create the Prometheus exporter.")
+		return nil, promErr
 	}
-	errCh := make(chan error)
+	return &prometheusExporter{exporter: promExporter, port: port}, nil
+}
+
+// Start implements Exporter.
+func (e *prometheusExporter) Start() error {
 	log.Info("Starting server for OpenCensus Prometheus exporter")
 	// Start the server for Prometheus scraping
-	srv := startNewPromSrv(e, *prometheusPort)
-	errCh <- srv.ListenAndServe()
-	err = <-errCh
+	e.srv = startNewPromSrv(e.exporter, e.port)
+	ln, err := net.Listen("tcp", e.srv.Addr)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	go func() {
+		if err := e.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(err, "Prometheus server failed")
+		}
+	}()
+	return nil
+}
+
+// Stop implements Exporter.
+func (e *prometheusExporter) Stop(ctx context.Context) error {
+	log.Info("shutting down prometheus server")
+	if e.srv == nil {
+		return nil
 	}
-	return e, nil
+	return e.srv.Shutdown(ctx)
 }
 
 func startNewPromSrv(e *prometheus.Exporter, port int) *http.Server {
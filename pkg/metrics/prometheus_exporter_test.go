@@ -1,24 +1,29 @@
 package metrics
 
 import (
+	"context"
 	"testing"
-	"time"
 )
 
 func TestPrometheusExporter(t *testing.T) {
 	const expectedAddr = ":8888"
 
-	go func() {
-		e, err := newPrometheusExporter()
-		if err != nil {
+	e, err := newPrometheusExporter(8888)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e == nil {
+		t.Fatal("newPrometheusExporter() should not return nil")
+	}
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := e.Stop(context.Background()); err != nil {
 			t.Error(err)
 		}
-		if e == nil {
-			t.Error("newPrometheusExporter() should not return nil")
-		}
 	}()
 
-	time.Sleep(100 * time.Millisecond)
 	if curPromSrv.Addr != expectedAddr {
 		t.Errorf("Expected address %v but got %v", expectedAddr, curPromSrv.Addr)
 	}
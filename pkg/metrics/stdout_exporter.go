@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.opencensus.io/metric/metricdata"
+)
+
+// stdoutExporter periodically writes every metric to stdout as a line of
+// JSON, in the same form as an OTLP metric. It lets metrics be collected with
+// the logs of the pod where no metrics backend is available.
+type stdoutExporter struct {
+	*intervalExporter
+	mux sync.Mutex
+	out io.Writer
+}
+
+func newStdoutExporter(interval time.Duration) (*stdoutExporter, error) {
+	e := &stdoutExporter{out: os.Stdout}
+	ie, err := newIntervalExporter(e, interval)
+	if err != nil {
+		return nil, err
+	}
+	e.intervalExporter = ie
+	return e, nil
+}
+
+// ExportMetrics implements metricexport.Exporter.
+func (e *stdoutExporter) ExportMetrics(_ context.Context, metrics []*metricdata.Metric) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	enc := json.NewEncoder(e.out)
+	for _, m := range metrics {
+		om, ok := toOTLPMetric(m)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(om); err != nil {
+			return err
+		}
+	}
+	return nil
+}
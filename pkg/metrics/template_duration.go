@@ -0,0 +1,7 @@
+package metrics
+
+import "flag"
+
+// TemplateDuration is whether the webhook and audit record the time spent
+// evaluating each constraint matching a reviewed object.
+var TemplateDuration = flag.Bool("metrics-template-duration", false, "(alpha) record the time spent evaluating each constraint matching a reviewed object, labelled with the kind of its template. Rego is evaluated as with --rego-memory-limit, so it cannot be set with --remote-opa-url. Finding the constraints matching an object adds a query to each review")
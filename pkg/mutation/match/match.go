@@ -1,9 +1,10 @@
 package match
 
 import (
-	"errors"
 	"strings"
 
+	"github.com/open-policy-agent/gatekeeper/pkg/cluster"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -50,6 +51,14 @@ type Match struct {
 	ExcludedNamespaces []string                      `json:"excludedNamespaces,omitempty"`
 	LabelSelector      *metav1.LabelSelector         `json:"labelSelector,omitempty"`
 	NamespaceSelector  *metav1.LabelSelector         `json:"namespaceSelector,omitempty"`
+	// Name is the name of an object. If defined, it matches against objects with the
+	// specified name. Name also supports a prefix-based glob. For example,
+	// `name: pod-*` would match both `pod-a` and `pod-b`.
+	Name string `json:"name,omitempty"`
+	// ClusterSelector selects the clusters to apply to by the labels identifying
+	// the cluster Gatekeeper runs in, set with the `--cluster-label` flag. If
+	// defined, objects in clusters it does not select are not matched.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
 }
 
 // Kinds accepts a list of objects with apiGroups and kinds fields
@@ -68,81 +77,76 @@ type Kinds struct {
 // Matches verifies if the given object belonging to the given namespace
 // matches the current mutator.
 func Matches(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
-	if isNamespace(obj) && ns == nil {
-		return false, errors.New("invalid call to Matches(), ns must not be nil for Namespace objects")
-	}
-
-	topLevelMatchers := []matchFunc{
-		kindsMatch,
-		scopeMatch,
-		namespacesMatch,
-		excludedNamespacesMatch,
-		labelSelectorMatch,
-		namespaceSelectorMatch,
-	}
-
-	for _, fn := range topLevelMatchers {
-		ok, err := fn(match, obj, ns)
-		if err != nil {
-			return false, err
-		}
-		if !ok {
-			return false, nil
-		}
-	}
+	return NewMatcher(match).Matches(obj, ns)
+}
 
-	return true, nil
+// matchFunc defines the matching logic of a Top Level Matcher.  A TLM receives the Matcher of the
+// match criteria, an object, and the namespace of the object and decides if there is a reason why
+// the object does not match.  If the TLM associated with the matching function is not defined by
+// the user, the matchFunc should return true.
+type matchFunc func(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error)
+
+// topLevelMatchers are the Top Level Matchers an object must satisfy to be
+// matched, in the order they are evaluated. The criteria selecting the
+// namespace of the object are the namespaceMatchers, evaluated by
+// namespaceMatch so that Matchers remember their result for each namespace.
+var topLevelMatchers = []matchFunc{
+	kindsMatch,
+	scopeMatch,
+	namespaceMatch,
+	labelSelectorMatch,
+	nameMatch,
+	clusterSelectorMatch,
 }
 
-// matchFunc defines the matching logic of a Top Level Matcher.  A TLM receives the match criteria,
-// an object, and the namespace of the object and decides if there is a reason why the object does
-// not match.  If the TLM associated with the matching function is not defined by the user, the
-// matchFunc should return true.
-type matchFunc func(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error)
+// namespaceMatchers are the Top Level Matchers selecting the namespace of an
+// object, whose result depends on nothing else of the object unless it is a
+// Namespace.
+var namespaceMatchers = []matchFunc{
+	namespacesMatch,
+	excludedNamespacesMatch,
+	namespaceSelectorMatch,
+}
 
-func namespaceSelectorMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
-	if match.NamespaceSelector == nil {
+func namespaceSelectorMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	if m.match.NamespaceSelector == nil {
 		return true, nil
 	}
+	if m.namespaceSelectorErr != nil {
+		return false, m.namespaceSelectorErr
+	}
 
 	clusterScoped := ns == nil || isNamespace(obj)
 
-	selector, err := metav1.LabelSelectorAsSelector(match.NamespaceSelector)
-	if err != nil {
-		return false, err
-	}
-
 	switch {
 	case isNamespace(obj): // if the object is a namespace, namespace selector matches against the object
-		return selector.Matches(labels.Set(obj.GetLabels())), nil
+		return m.namespaceSelector.Matches(labels.Set(obj.GetLabels())), nil
 	case clusterScoped:
 		return true, nil
 	}
 
-	return selector.Matches(labels.Set(ns.Labels)), nil
+	return m.namespaceSelector.Matches(labels.Set(ns.Labels)), nil
 }
 
-func labelSelectorMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
-	if match.LabelSelector == nil {
+func labelSelectorMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	if m.match.LabelSelector == nil {
 		return true, nil
 	}
-
-	selector, err := metav1.LabelSelectorAsSelector(match.LabelSelector)
-	if err != nil {
-		return false, err
+	if m.labelSelectorErr != nil {
+		return false, m.labelSelectorErr
 	}
 
-	return selector.Matches(labels.Set(obj.GetLabels())), nil
+	return m.labelSelector.Matches(labels.Set(obj.GetLabels())), nil
 }
 
-func excludedNamespacesMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
+func excludedNamespacesMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
 	// If we don't have a namespace, we can't disqualify the match
 	if ns == nil {
 		return true, nil
 	}
 
-	for _, n := range match.ExcludedNamespaces {
-		if ns.Name == n || prefixMatch(n, ns.Name) {
+	for _, n := range m.match.ExcludedNamespaces {
+		if util.NamespacePatternMatches(n, ns.Name) {
 			return false, nil
 		}
 	}
@@ -150,31 +154,54 @@ func excludedNamespacesMatch(match *Match, obj client.Object, ns *corev1.Namespa
 	return true, nil
 }
 
-func namespacesMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
+// ExcludedNamespaceIndex returns the index of the excluded namespaces of
+// match, or nil if it excludes none. Mutators hold it to reject objects in
+// excluded namespaces as soon as they know they apply to their kind, without
+// scanning the excluded namespaces or evaluating the rest of match.
+func ExcludedNamespaceIndex(match *Match) *util.NamespaceIndex {
+	if len(match.ExcludedNamespaces) == 0 {
+		return nil
+	}
+	return util.NewNamespaceIndex(match.ExcludedNamespaces)
+}
+
+func namespacesMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
 	// If we don't have a namespace, we can't disqualify the match
 	if ns == nil {
 		return true, nil
 	}
 
-	for _, n := range match.Namespaces {
+	for _, n := range m.match.Namespaces {
 		if ns.Name == n || prefixMatch(n, ns.Name) {
 			return true, nil
 		}
 	}
 
-	if len(match.Namespaces) > 0 {
+	if len(m.match.Namespaces) > 0 {
 		return false, nil
 	}
 
 	return true, nil
 }
 
-func kindsMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
-	if len(match.Kinds) == 0 {
+func nameMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	if m.match.Name == "" {
+		return true, nil
+	}
+
+	return obj.GetName() == m.match.Name || prefixMatch(m.match.Name, obj.GetName()), nil
+}
+
+func clusterSelectorMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	return cluster.Selects(m.match.ClusterSelector)
+}
+
+func kindsMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	if len(m.match.Kinds) == 0 {
 		return true, nil
 	}
 
-	for _, kk := range match.Kinds {
+	for _, kk := range m.match.Kinds {
 		kindMatches := false
 		groupMatches := false
 
@@ -206,15 +233,15 @@ func kindsMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, er
 	return false, nil
 }
 
-func scopeMatch(match *Match, obj client.Object, ns *corev1.Namespace) (bool, error) {
+func scopeMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
 	clusterScoped := ns == nil || isNamespace(obj)
 
-	if match.Scope == apiextensionsv1.ClusterScoped &&
+	if m.match.Scope == apiextensionsv1.ClusterScoped &&
 		!clusterScoped {
 		return false, nil
 	}
 
-	if match.Scope == apiextensionsv1.NamespaceScoped &&
+	if m.match.Scope == apiextensionsv1.NamespaceScoped &&
 		clusterScoped {
 		return false, nil
 	}
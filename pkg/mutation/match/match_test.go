@@ -5,11 +5,13 @@ import (
 	"testing"
 
 	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/cluster"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -258,6 +260,21 @@ func TestMatch(t *testing.T) {
 			namespace:   &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
 			shouldMatch: false,
 		},
+		{
+			tname:   "namespace is excluded by glob within the name",
+			toMatch: makeObject("kind", "group", "team-a-dev", "name"),
+			match: Match{
+				Kinds: []Kinds{
+					{
+						Kinds:     []string{"kind"},
+						APIGroups: []string{"group"},
+					},
+				},
+				ExcludedNamespaces: []string{"team-*-dev"},
+			},
+			namespace:   &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a-dev"}},
+			shouldMatch: false,
+		},
 		{
 			tname:   "namespace scoped fails if cluster scoped",
 			toMatch: makeObject("kind", "group", "", "name"),
@@ -431,6 +448,34 @@ func TestMatch(t *testing.T) {
 			},
 			shouldMatch: false,
 		},
+		{
+			tname:       "match name",
+			toMatch:     makeObject("kind", "group", "namespace", "name"),
+			match:       Match{Name: "name"},
+			namespace:   &corev1.Namespace{},
+			shouldMatch: true,
+		},
+		{
+			tname:       "don't match other name",
+			toMatch:     makeObject("kind", "group", "namespace", "name"),
+			match:       Match{Name: "other"},
+			namespace:   &corev1.Namespace{},
+			shouldMatch: false,
+		},
+		{
+			tname:       "match name prefix",
+			toMatch:     makeObject("kind", "group", "namespace", "name-a"),
+			match:       Match{Name: "name-*"},
+			namespace:   &corev1.Namespace{},
+			shouldMatch: true,
+		},
+		{
+			tname:       "don't match name prefix",
+			toMatch:     makeObject("kind", "group", "namespace", "other-a"),
+			match:       Match{Name: "name-*"},
+			namespace:   &corev1.Namespace{},
+			shouldMatch: false,
+		},
 	}
 	for _, tc := range table {
 		t.Run(tc.tname, func(t *testing.T) {
@@ -455,6 +500,59 @@ func TestMatch(t *testing.T) {
 			if matches != tc.shouldMatch {
 				t.Errorf("%s: expecting match to be %v, was %v", tc.tname, tc.shouldMatch, matches)
 			}
+			// A Matcher agrees with Matches.
+			matches, err = NewMatcher(&tc.match).Matches(tc.toMatch, ns)
+			if err != nil {
+				t.Error("Matcher failed for ", tc.tname)
+			}
+			if matches != tc.shouldMatch {
+				t.Errorf("%s: expecting Matcher to match %v, was %v", tc.tname, tc.shouldMatch, matches)
+			}
+		})
+	}
+}
+
+func TestMatchClusterSelector(t *testing.T) {
+	cluster.SetLabels(labels.Set{"env": "prod"})
+	defer cluster.SetLabels(nil)
+
+	table := []struct {
+		tname       string
+		selector    *metav1.LabelSelector
+		shouldMatch bool
+	}{
+		{
+			tname:       "no selector matches",
+			shouldMatch: true,
+		},
+		{
+			tname:       "selector of the cluster's labels matches",
+			selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			shouldMatch: true,
+		},
+		{
+			tname:       "selector of other labels does not match",
+			selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}},
+			shouldMatch: false,
+		},
+		{
+			tname: "selector excluding the cluster does not match",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}},
+			}},
+			shouldMatch: false,
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.tname, func(t *testing.T) {
+			m := &Match{ClusterSelector: tc.selector}
+			matches, err := Matches(m, makeObject("kind", "group", "namespace", "name"), &corev1.Namespace{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if matches != tc.shouldMatch {
+				t.Errorf("expecting match to be %v, was %v", tc.shouldMatch, matches)
+			}
 		})
 	}
 }
@@ -0,0 +1,109 @@
+package match
+
+import (
+	"errors"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxNamespaces bounds the namespaces a Matcher remembers. Once reached, they
+// are all forgotten, which drops deleted namespaces along with the others.
+const maxNamespaces = 10000
+
+// Matcher matches objects against a Match as Matches does, with its
+// selectors parsed once. Whether the namespace criteria of the Match select
+// a namespace is remembered until the namespace changes, so that they are
+// evaluated once per namespace rather than once per object in it. Matchers
+// are safe for concurrent use.
+type Matcher struct {
+	match             *Match
+	labelSelector     labels.Selector
+	namespaceSelector labels.Selector
+	// labelSelectorErr and namespaceSelectorErr are why the selectors of
+	// match could not be parsed.
+	labelSelectorErr     error
+	namespaceSelectorErr error
+
+	mux        sync.RWMutex
+	namespaces map[string]namespaceResult
+}
+
+// namespaceResult is whether the namespace criteria select a namespace, as of
+// a version of it.
+type namespaceResult struct {
+	uid             k8stypes.UID
+	resourceVersion string
+	matches         bool
+}
+
+// NewMatcher returns a Matcher of match, which must not be modified while
+// the Matcher is used.
+func NewMatcher(match *Match) *Matcher {
+	m := &Matcher{match: match}
+	m.labelSelector, m.labelSelectorErr = parseSelector(match.LabelSelector)
+	m.namespaceSelector, m.namespaceSelectorErr = parseSelector(match.NamespaceSelector)
+	return m
+}
+
+// parseSelector returns the Selector of s, or nil if s is nil.
+func parseSelector(s *metav1.LabelSelector) (labels.Selector, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return metav1.LabelSelectorAsSelector(s)
+}
+
+// Matches returns whether obj, in namespace ns, is matched, as Matches would.
+func (m *Matcher) Matches(obj client.Object, ns *corev1.Namespace) (bool, error) {
+	if isNamespace(obj) && ns == nil {
+		return false, errors.New("invalid call to Matches(), ns must not be nil for Namespace objects")
+	}
+	return allMatch(topLevelMatchers, m, obj, ns)
+}
+
+// allMatch returns whether every one of fns matches obj in ns.
+func allMatch(fns []matchFunc, m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	for _, fn := range fns {
+		ok, err := fn(m, obj, ns)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// namespaceMatch returns whether the namespaceMatchers select obj in ns,
+// remembering the result for each version of ns.
+func namespaceMatch(m *Matcher, obj client.Object, ns *corev1.Namespace) (bool, error) {
+	// Namespaces are selected by their own labels, which may differ from
+	// those of ns while they are being changed, so they are not remembered.
+	// Neither are namespaces without a resourceVersion, which may have been
+	// made up by the caller.
+	if ns == nil || isNamespace(obj) || ns.ResourceVersion == "" {
+		return allMatch(namespaceMatchers, m, obj, ns)
+	}
+
+	m.mux.RLock()
+	r, ok := m.namespaces[ns.Name]
+	m.mux.RUnlock()
+	if ok && r.uid == ns.UID && r.resourceVersion == ns.ResourceVersion {
+		return r.matches, nil
+	}
+
+	matches, err := allMatch(namespaceMatchers, m, obj, ns)
+	if err != nil {
+		return false, err
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.namespaces == nil || len(m.namespaces) >= maxNamespaces {
+		m.namespaces = make(map[string]namespaceResult)
+	}
+	m.namespaces[ns.Name] = namespaceResult{uid: ns.UID, resourceVersion: ns.ResourceVersion, matches: matches}
+	return matches, nil
+}
@@ -0,0 +1,115 @@
+package match
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+func newNamespace(uid, resourceVersion string, labels map[string]string) *corev1.Namespace {
+	ns := &corev1.Namespace{}
+	ns.SetName("team-a")
+	ns.SetUID(k8stypes.UID(uid))
+	ns.SetResourceVersion(resourceVersion)
+	ns.SetLabels(labels)
+	return ns
+}
+
+func TestMatcherRemembersNamespaces(t *testing.T) {
+	m := NewMatcher(&Match{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+	})
+	obj := makeObject("Pod", "", "team-a", "pod")
+	prod := map[string]string{"env": "prod"}
+	dev := map[string]string{"env": "dev"}
+
+	for _, tc := range []struct {
+		name string
+		ns   *corev1.Namespace
+		want bool
+	}{
+		{name: "selected", ns: newNamespace("a", "1", prod), want: true},
+		// The labels of the same version of a namespace cannot change, so
+		// the result is remembered.
+		{name: "same version", ns: newNamespace("a", "1", dev), want: true},
+		{name: "new version", ns: newNamespace("a", "2", dev), want: false},
+		{name: "recreated", ns: newNamespace("b", "2", prod), want: true},
+		// Namespaces without a version are always evaluated.
+		{name: "no version", ns: newNamespace("", "", dev), want: false},
+		{name: "no version again", ns: newNamespace("", "", prod), want: true},
+	} {
+		got, err := m.Matches(obj, tc.ns)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got matches %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// The object criteria are evaluated for every object.
+	m = NewMatcher(&Match{Name: "pod-*"})
+	ns := newNamespace("a", "1", prod)
+	for name, want := range map[string]bool{"pod-a": true, "other": false} {
+		got, err := m.Matches(makeObject("Pod", "", "team-a", name), ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: got matches %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMatcherForgetsNamespaces(t *testing.T) {
+	m := NewMatcher(&Match{Namespaces: []string{"*"}})
+	obj := makeObject("Pod", "", "", "pod")
+	for i := 0; i <= maxNamespaces; i++ {
+		ns := newNamespace("a", "1", nil)
+		ns.SetName(fmt.Sprintf("ns-%d", i))
+		if _, err := m.Matches(obj, ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(m.namespaces); got != 1 {
+		t.Errorf("got %d remembered namespaces, want those above %d forgotten", got, maxNamespaces)
+	}
+}
+
+func TestMatcherInvalidSelector(t *testing.T) {
+	m := NewMatcher(&Match{
+		LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Unknown"}}},
+	})
+	if _, err := m.Matches(makeObject("Pod", "", "", "pod"), newNamespace("a", "1", nil)); err == nil {
+		t.Error("got no error for an invalid selector")
+	}
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	match := &Match{
+		Kinds:              []Kinds{{APIGroups: []string{""}, Kinds: []string{"Pod"}}},
+		ExcludedNamespaces: []string{"kube-*"},
+		NamespaceSelector:  &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}}}},
+	}
+	obj := makeObject("Pod", "", "team-a", "pod")
+	ns := newNamespace("a", "1", map[string]string{"env": "prod"})
+
+	b.Run("Matches", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Matches(match, obj, ns); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Matcher", func(b *testing.B) {
+		m := NewMatcher(match)
+		for i := 0; i < b.N; i++ {
+			if _, err := m.Matches(obj, ns); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
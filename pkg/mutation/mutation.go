@@ -19,6 +19,15 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const (
+	// MutationsAnnotation lists the mutators applied to an object, when
+	// mutation annotations are enabled.
+	MutationsAnnotation = "gatekeeper.sh/mutations"
+	// MutationIDAnnotation identifies the mutation of an object, when
+	// mutation annotations are enabled.
+	MutationIDAnnotation = "gatekeeper.sh/mutation-id"
+)
+
 // MutationEnabled indicates if the mutation feature is enabled.
 var (
 	MutationEnabled            *bool
@@ -3,8 +3,10 @@ package assign
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
+	"strconv"
 
 	"github.com/google/go-cmp/cmp"
 	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
@@ -15,6 +17,7 @@ import (
 	patht "github.com/open-policy-agent/gatekeeper/pkg/mutation/path/tester"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/schema"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -39,6 +42,10 @@ type Mutator struct {
 	bindings  []runtimeschema.GroupVersionKind
 	tester    *patht.Tester
 	valueTest *mutationsv1alpha1.AssignIf
+	// excluded indexes the excluded namespaces of the match of assign.
+	excluded *util.NamespaceIndex
+	// matcher matches objects against the match of assign.
+	matcher *match.Matcher
 }
 
 // Mutator implements mutatorWithSchema.
@@ -48,7 +55,10 @@ func (m *Mutator) Matches(obj client.Object, ns *corev1.Namespace) bool {
 	if !match.AppliesTo(m.assign.Spec.ApplyTo, obj) {
 		return false
 	}
-	matches, err := match.Matches(&m.assign.Spec.Match, obj, ns)
+	if ns != nil && m.excluded.Matches(ns.Name) {
+		return false
+	}
+	matches, err := m.matcher.Matches(obj, ns)
 	if err != nil {
 		log.Error(err, "Matches failed for assign", "assign", m.assign.Name)
 		return false
@@ -61,7 +71,11 @@ func (m *Mutator) TerminalType() parser.NodeType {
 }
 
 func (m *Mutator) Mutate(obj *unstructured.Unstructured) (bool, error) {
-	return core.Mutate(m.Path(), m.tester, m.testValue, core.NewDefaultSetter(m), obj)
+	var setter core.Setter = core.NewDefaultSetter(m)
+	if m.assign.Spec.Parameters.Operation == mutationsv1alpha1.DeleteOp {
+		setter = &core.DeleteSetter{}
+	}
+	return core.Mutate(m.Path(), m.tester, m.testValue, setter, obj)
 }
 
 // valueTest returns true if it is okay for the mutation func to override the value.
@@ -145,6 +159,10 @@ func (m *Mutator) DeepCopy() types.Mutator {
 			Nodes: make([]parser.Node, len(m.path.Nodes)),
 		},
 		bindings: make([]runtimeschema.GroupVersionKind, len(m.bindings)),
+		// NamespaceIndexes are immutable and Matchers safe for concurrent
+		// use, so they are shared.
+		excluded: m.excluded,
+		matcher:  m.matcher,
 	}
 
 	copy(res.path.Nodes, m.path.Nodes)
@@ -165,8 +183,20 @@ func MutatorForAssign(assign *mutationsv1alpha1.Assign) (*Mutator, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "invalid location format `%s` for Assign %s", assign.Spec.Location, assign.GetName())
 	}
+	if err := validateListKeys(path, assign.GetName()); err != nil {
+		return nil, err
+	}
 
-	if hasMetadataRoot(path) {
+	switch assign.Spec.Parameters.Operation {
+	case "", mutationsv1alpha1.SetOp, mutationsv1alpha1.DeleteOp:
+	default:
+		return nil, fmt.Errorf("unrecognized operation %q for Assign %s", assign.Spec.Parameters.Operation, assign.GetName())
+	}
+	deleting := assign.Spec.Parameters.Operation == mutationsv1alpha1.DeleteOp
+
+	// Labels and annotations may be deleted, as they are not part of the
+	// identity of an object.
+	if hasMetadataRoot(path) && !(deleting && isLabelOrAnnotation(path)) {
 		return nil, fmt.Errorf("assign %s can't change metadata", assign.GetName())
 	}
 
@@ -175,18 +205,12 @@ func MutatorForAssign(assign *mutationsv1alpha1.Assign) (*Mutator, error) {
 		return nil, err
 	}
 
-	toAssign := make(map[string]interface{})
-	err = json.Unmarshal(assign.Spec.Parameters.Assign.Raw, &toAssign)
-	if err != nil {
-		return nil, errors.Wrapf(err, "invalid format for parameters.assign %s for Assign %s", assign.Spec.Parameters.Assign.Raw, assign.GetName())
+	var value interface{}
+	if deleting {
+		err = validateDelete(path, assign)
+	} else {
+		value, err = assignedValue(path, assign)
 	}
-
-	value, ok := toAssign["value"]
-	if !ok {
-		return nil, fmt.Errorf("spec.parameters.assign for Assign %s must have a value field", assign.GetName())
-	}
-
-	err = validateObjectAssignedToList(path, value, assign.GetName())
 	if err != nil {
 		return nil, err
 	}
@@ -216,17 +240,74 @@ func MutatorForAssign(assign *mutationsv1alpha1.Assign) (*Mutator, error) {
 		return nil, fmt.Errorf("applyTo required for Assign mutator %s", assign.GetName())
 	}
 
+	// The Matcher refers to the match of the copy held by the Mutator.
+	assign = assign.DeepCopy()
 	return &Mutator{
 		id:          id,
-		assign:      assign.DeepCopy(),
+		assign:      assign,
 		assignValue: value,
 		bindings:    gvks,
 		path:        path,
 		tester:      tester,
 		valueTest:   &valueTests,
+		excluded:    match.ExcludedNamespaceIndex(&assign.Spec.Match),
+		matcher:     match.NewMatcher(&assign.Spec.Match),
 	}, nil
 }
 
+// assignedValue returns the value assign sets at path.
+func assignedValue(path parser.Path, assign *mutationsv1alpha1.Assign) (interface{}, error) {
+	toAssign := make(map[string]interface{})
+	err := json.Unmarshal(assign.Spec.Parameters.Assign.Raw, &toAssign)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid format for parameters.assign %s for Assign %s", assign.Spec.Parameters.Assign.Raw, assign.GetName())
+	}
+
+	value, ok := toAssign["value"]
+	if !ok {
+		return nil, fmt.Errorf("spec.parameters.assign for Assign %s must have a value field", assign.GetName())
+	}
+
+	if valueType := assign.Spec.Parameters.ValueType; valueType != "" {
+		value, err = coerceValue(value, valueType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters.assign.value for Assign %s: %w", assign.GetName(), err)
+		}
+	}
+
+	err = validateObjectAssignedToList(path, value, assign.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// validateDelete returns an error if assign, which deletes the field at path,
+// also sets a value or does not name a single list element to delete.
+func validateDelete(path parser.Path, assign *mutationsv1alpha1.Assign) error {
+	if listNode, ok := path.Nodes[len(path.Nodes)-1].(*parser.List); ok {
+		if listNode.Glob {
+			return errors.New("can't delete from a globbed list, Assign: " + assign.GetName())
+		}
+		if listNode.KeyValue == nil {
+			return errors.New("invalid key value for a non globbed object, Assign: " + assign.GetName())
+		}
+	}
+	if raw := assign.Spec.Parameters.Assign.Raw; len(raw) != 0 {
+		toAssign := make(map[string]interface{})
+		if err := json.Unmarshal(raw, &toAssign); err != nil {
+			return errors.Wrapf(err, "invalid format for parameters.assign %s for Assign %s", raw, assign.GetName())
+		}
+		if _, ok := toAssign["value"]; ok {
+			return fmt.Errorf("spec.parameters.assign for Assign %s must not have a value field with operation delete", assign.GetName())
+		}
+	}
+	if assign.Spec.Parameters.ValueType != "" {
+		return fmt.Errorf("spec.parameters.valueType for Assign %s must not be set with operation delete", assign.GetName())
+	}
+	return nil
+}
+
 func gatherPathTests(assign *mutationsv1alpha1.Assign) ([]patht.Test, error) {
 	pts := assign.Spec.Parameters.PathTests
 	var pathTests []patht.Test
@@ -260,6 +341,19 @@ func hasMetadataRoot(path parser.Path) bool {
 	return false
 }
 
+// isLabelOrAnnotation returns whether path is of a single label or annotation,
+// like metadata.annotations.foo.
+func isLabelOrAnnotation(path parser.Path) bool {
+	if len(path.Nodes) != 3 {
+		return false
+	}
+	field, ok := path.Nodes[1].(*parser.Object)
+	if !ok || (field.Reference != "labels" && field.Reference != "annotations") {
+		return false
+	}
+	return path.Nodes[2].Type() == parser.ObjectNode
+}
+
 // checkKeyNotChanged does not allow to change the key field of
 // a list element. A path like foo[name: bar].name is rejected.
 func checkKeyNotChanged(p parser.Path, assignName string) error {
@@ -312,15 +406,107 @@ func validateObjectAssignedToList(p parser.Path, value interface{}, assignName s
 	}
 	valueMap, ok := value.(map[string]interface{})
 	if !ok {
-		return errors.New("only full objects can be appended to lists, Assign: " + assignName)
+		return fmt.Errorf("only full objects can be appended to lists, Assign: %s: got %s assigned to `%s`", assignName, jsonType(value), p)
+	}
+	keyValue := valueMap[listNode.KeyField]
+	if key, ok := listNode.KeyValue.(int64); ok {
+		// Numbers are decoded from JSON as float64, while integers in objects
+		// and in the location are int64.
+		if f, ok := keyValue.(float64); ok && f == float64(key) {
+			keyValue = key
+			valueMap[listNode.KeyField] = key
+		}
 	}
-	if listNode.KeyValue != valueMap[listNode.KeyField] {
-		return fmt.Errorf("adding object to list with different key %s: list key %v, object key %v, assign: %s", listNode.KeyField, listNode.KeyValue, valueMap[listNode.KeyField], assignName)
+	if listNode.KeyValue != keyValue {
+		return fmt.Errorf("adding object to list with different key %s: list key %v, object key %v, assign: %s", listNode.KeyField, listNode.KeyValue, keyValue, assignName)
 	}
 
 	return nil
 }
 
+// validateListKeys returns an error pointing at the first list of p which
+// cannot match any element, as it has no key field.
+func validateListKeys(p parser.Path, assignName string) error {
+	for i, node := range p.Nodes {
+		if listNode, ok := node.(*parser.List); ok && listNode.KeyField == "" {
+			return fmt.Errorf("invalid location format `%s` for Assign %s: list at `%s` must have a key field", p, assignName, parser.Path{Nodes: p.Nodes[:i+1]})
+		}
+	}
+	return nil
+}
+
+// coerceValue converts the scalar value to valueType, returning an error if
+// it has no equivalent of that type. Values decoded from JSON hold numbers as
+// float64.
+func coerceValue(value interface{}, valueType mutationsv1alpha1.ValueType) (interface{}, error) {
+	switch valueType {
+	case mutationsv1alpha1.ValueTypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	case mutationsv1alpha1.ValueTypeInteger:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+				return int64(v), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case mutationsv1alpha1.ValueTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return f, nil
+			}
+		}
+	case mutationsv1alpha1.ValueTypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch v {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown valueType %q", valueType)
+	}
+	// value was decoded from JSON, so it can be encoded again.
+	raw, _ := json.Marshal(value)
+	return nil, fmt.Errorf("cannot convert %s %s to %s", jsonType(value), raw, valueType)
+}
+
+// jsonType names the JSON type of a value decoded from JSON.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "list"
+	default:
+		return "object"
+	}
+}
+
 func getSortedGVKs(bindings []match.ApplyTo) []runtimeschema.GroupVersionKind {
 	// deduplicate GVKs
 	gvksMap := map[runtimeschema.GroupVersionKind]struct{}{}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -12,6 +13,7 @@ import (
 	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
 	path "github.com/open-policy-agent/gatekeeper/pkg/mutation/path/tester"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -1510,6 +1512,34 @@ func TestApplyTo(t *testing.T) {
 	}
 }
 
+func TestExcludedNamespaces(t *testing.T) {
+	a := &mutationsv1alpha1.Assign{ObjectMeta: metav1.ObjectMeta{Name: "Foo"}}
+	a.Spec.Location = "spec.hello"
+	a.Spec.Parameters.Assign = makeValue("bar")
+	a.Spec.ApplyTo = []match.ApplyTo{{Groups: []string{""}, Kinds: []string{"Foo"}, Versions: []string{"v1"}}}
+	a.Spec.Match.ExcludedNamespaces = []string{"kube-system", "team-*", "*-sandbox"}
+	m, err := MutatorForAssign(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mutator := range []types.Mutator{m, m.DeepCopy()} {
+		for ns, want := range map[string]bool{
+			"kube-system": false,
+			"team-a":      false,
+			"dev-sandbox": false,
+			"default":     true,
+		} {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Foo"})
+			obj.SetNamespace(ns)
+			if got := mutator.Matches(obj, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}); got != want {
+				t.Errorf("got Matches() = %t in namespace %q, want %t", got, ns, want)
+			}
+		}
+	}
+}
+
 var testPod = &v1.Pod{
 	TypeMeta: metav1.TypeMeta{
 		APIVersion: "v1",
@@ -1725,3 +1755,323 @@ func nestedMapSlice(u map[string]interface{}, fields ...string) ([]map[string]in
 	}
 	return out, nil
 }
+
+func TestValueType(t *testing.T) {
+	tcs := []struct {
+		name      string
+		valueType mutationsv1alpha1.ValueType
+		value     interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{name: "unset keeps value", value: "true", want: "true"},
+		{name: "string to boolean", valueType: mutationsv1alpha1.ValueTypeBoolean, value: "true", want: true},
+		{name: "boolean stays boolean", valueType: mutationsv1alpha1.ValueTypeBoolean, value: false, want: false},
+		{name: "invalid boolean", valueType: mutationsv1alpha1.ValueTypeBoolean, value: "yes", wantErr: true},
+		{name: "number to boolean", valueType: mutationsv1alpha1.ValueTypeBoolean, value: 1, wantErr: true},
+		{name: "string to integer", valueType: mutationsv1alpha1.ValueTypeInteger, value: "8080", want: int64(8080)},
+		{name: "number to integer", valueType: mutationsv1alpha1.ValueTypeInteger, value: 3, want: int64(3)},
+		{name: "fraction to integer", valueType: mutationsv1alpha1.ValueTypeInteger, value: 3.5, wantErr: true},
+		{name: "invalid integer", valueType: mutationsv1alpha1.ValueTypeInteger, value: "8080a", wantErr: true},
+		{name: "string to number", valueType: mutationsv1alpha1.ValueTypeNumber, value: "0.5", want: 0.5},
+		{name: "infinite number", valueType: mutationsv1alpha1.ValueTypeNumber, value: "Inf", wantErr: true},
+		{name: "boolean to string", valueType: mutationsv1alpha1.ValueTypeString, value: true, want: "true"},
+		{name: "number to string", valueType: mutationsv1alpha1.ValueTypeString, value: 8080, want: "8080"},
+		{name: "object to string", valueType: mutationsv1alpha1.ValueTypeString, value: map[string]interface{}{"a": "b"}, wantErr: true},
+		{name: "unknown type", valueType: "date", value: "2021-01-01", wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &mutationsv1alpha1.Assign{
+				ObjectMeta: metav1.ObjectMeta{Name: "Foo"},
+				Spec: mutationsv1alpha1.AssignSpec{
+					ApplyTo:  []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Foo"}}},
+					Location: "spec.value",
+					Parameters: mutationsv1alpha1.Parameters{
+						Assign:    makeValue(tc.value),
+						ValueType: tc.valueType,
+					},
+				},
+			}
+			m, err := MutatorForAssign(a)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("got no error, want an error converting the value")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := newFoo(map[string]interface{}{})
+			if _, err := m.Mutate(obj); err != nil {
+				t.Fatal(err)
+			}
+			if err := ensureObj(obj, tc.want, "spec", "value"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	containers := func(names ...string) []interface{} {
+		var out []interface{}
+		for _, name := range names {
+			out = append(out, map[string]interface{}{"name": name})
+		}
+		return out
+	}
+	tcs := []struct {
+		name        string
+		location    string
+		value       runtime.RawExtension
+		valueType   mutationsv1alpha1.ValueType
+		pathTests   []mutationsv1alpha1.PathTest
+		in          []interface{}
+		obj         *unstructured.Unstructured
+		wantErr     bool
+		wantMutated bool
+		want        *unstructured.Unstructured
+	}{
+		{
+			name:        "delete field",
+			location:    "spec.hostNetwork",
+			obj:         newFoo(map[string]interface{}{"hostNetwork": true, "dnsPolicy": "Default"}),
+			wantMutated: true,
+			want:        newFoo(map[string]interface{}{"dnsPolicy": "Default"}),
+		},
+		{
+			name:     "missing field",
+			location: "spec.hostNetwork",
+			obj:      newFoo(map[string]interface{}{"dnsPolicy": "Default"}),
+			want:     newFoo(map[string]interface{}{"dnsPolicy": "Default"}),
+		},
+		{
+			name:     "missing parents are not created",
+			location: "spec.securityContext.runAsUser",
+			obj:      newFoo(map[string]interface{}{}),
+			want:     newFoo(map[string]interface{}{}),
+		},
+		{
+			name:        "delete list element",
+			location:    "spec.containers[name: sidecar]",
+			obj:         newFoo(map[string]interface{}{"containers": containers("main", "sidecar", "other")}),
+			wantMutated: true,
+			want:        newFoo(map[string]interface{}{"containers": containers("main", "other")}),
+		},
+		{
+			name:     "missing list element",
+			location: "spec.containers[name: sidecar]",
+			obj:      newFoo(map[string]interface{}{"containers": containers("main")}),
+			want:     newFoo(map[string]interface{}{"containers": containers("main")}),
+		},
+		{
+			name:     "missing list element is not created",
+			location: "spec.containers[name: sidecar].securityContext",
+			obj:      newFoo(map[string]interface{}{"containers": containers("main")}),
+			want:     newFoo(map[string]interface{}{"containers": containers("main")}),
+		},
+		{
+			name:     "delete from globbed list",
+			location: "spec.containers[name: *].securityContext",
+			obj: newFoo(map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "main", "securityContext": map[string]interface{}{"privileged": true}},
+				map[string]interface{}{"name": "sidecar"},
+			}}),
+			wantMutated: true,
+			want:        newFoo(map[string]interface{}{"containers": containers("main", "sidecar")}),
+		},
+		{
+			name:     "path test fails",
+			location: "spec.securityContext.runAsUser",
+			pathTests: []mutationsv1alpha1.PathTest{
+				{SubPath: "spec.securityContext", Condition: path.MustNotExist},
+			},
+			obj:  newFoo(map[string]interface{}{"securityContext": map[string]interface{}{"runAsUser": int64(0)}}),
+			want: newFoo(map[string]interface{}{"securityContext": map[string]interface{}{"runAsUser": int64(0)}}),
+		},
+		{
+			name:        "value test passes",
+			location:    "spec.dnsPolicy",
+			in:          []interface{}{"None"},
+			obj:         newFoo(map[string]interface{}{"dnsPolicy": "None"}),
+			wantMutated: true,
+			want:        newFoo(map[string]interface{}{}),
+		},
+		{
+			name:     "value test fails",
+			location: "spec.dnsPolicy",
+			in:       []interface{}{"None"},
+			obj:      newFoo(map[string]interface{}{"dnsPolicy": "Default"}),
+			want:     newFoo(map[string]interface{}{"dnsPolicy": "Default"}),
+		},
+		{
+			name:     "delete annotation",
+			location: "metadata.annotations.deprecated",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "my-foo", "annotations": map[string]interface{}{"deprecated": "true", "kept": "true"}},
+			}},
+			wantMutated: true,
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "my-foo", "annotations": map[string]interface{}{"kept": "true"}},
+			}},
+		},
+		{
+			name:     "delete name",
+			location: "metadata.name",
+			wantErr:  true,
+		},
+		{
+			name:     "delete list key",
+			location: "spec.containers[name: main].name",
+			wantErr:  true,
+		},
+		{
+			name:     "delete globbed list element",
+			location: "spec.containers[name: *]",
+			wantErr:  true,
+		},
+		{
+			name:     "value set",
+			location: "spec.hostNetwork",
+			value:    makeValue(false),
+			wantErr:  true,
+		},
+		{
+			name:      "value type set",
+			location:  "spec.hostNetwork",
+			valueType: mutationsv1alpha1.ValueTypeBoolean,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &mutationsv1alpha1.Assign{
+				ObjectMeta: metav1.ObjectMeta{Name: "Foo"},
+				Spec: mutationsv1alpha1.AssignSpec{
+					ApplyTo:  []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Foo"}}},
+					Location: tc.location,
+					Parameters: mutationsv1alpha1.Parameters{
+						Assign:    tc.value,
+						ValueType: tc.valueType,
+						PathTests: tc.pathTests,
+						Operation: mutationsv1alpha1.DeleteOp,
+					},
+				},
+			}
+			if tc.in != nil {
+				bs, err := json.Marshal(&mutationsv1alpha1.AssignIf{In: tc.in})
+				if err != nil {
+					t.Fatal(err)
+				}
+				a.Spec.Parameters.AssignIf = runtime.RawExtension{Raw: bs}
+			}
+			m, err := MutatorForAssign(a)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("got no error, want an error creating the mutator")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			mutated, err := m.Mutate(tc.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mutated != tc.wantMutated {
+				t.Errorf("got mutated %v, want %v", mutated, tc.wantMutated)
+			}
+			if diff := cmp.Diff(tc.want, tc.obj); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLocationValidation(t *testing.T) {
+	tcs := []struct {
+		name     string
+		location string
+		value    interface{}
+		wantErr  string
+	}{
+		{
+			name:     "syntax error",
+			location: "spec.containers[name foo].image",
+			value:    "nginx",
+			wantErr:  "error at position 21",
+		},
+		{
+			name:     "list without key field",
+			location: `spec.containers["": foo].image`,
+			value:    "nginx",
+			wantErr:  "list at `spec.containers[\"\": foo]` must have a key field",
+		},
+		{
+			name:     "scalar assigned to list element",
+			location: "spec.containers[name: foo]",
+			value:    "nginx",
+			wantErr:  "got string assigned to `spec.containers[name: foo]`",
+		},
+		{
+			name:     "object with other key",
+			location: "spec.containers[name: foo]",
+			value:    map[string]interface{}{"name": "bar"},
+			wantErr:  "list key foo, object key bar",
+		},
+		{
+			name:     "integer key",
+			location: "spec.ports[port: 80]",
+			value:    map[string]interface{}{"port": 80, "name": "http"},
+		},
+		{
+			name:     "other integer key",
+			location: "spec.ports[port: 80]",
+			value:    map[string]interface{}{"port": 8080},
+			wantErr:  "list key 80, object key 8080",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &mutationsv1alpha1.Assign{
+				ObjectMeta: metav1.ObjectMeta{Name: "Foo"},
+				Spec: mutationsv1alpha1.AssignSpec{
+					ApplyTo:    []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Foo"}}},
+					Location:   tc.location,
+					Parameters: mutationsv1alpha1.Parameters{Assign: makeValue(tc.value)},
+				},
+			}
+			err := IsValidAssign(a)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestIntegerKeyAssigned(t *testing.T) {
+	m := newAssignMutator(&assignTestCfg{
+		applyTo: []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Foo"}}},
+		path:    "spec.ports[port: 80]",
+		value:   makeValue(map[string]interface{}{"port": 80, "name": "http"}),
+	})
+	obj := newFoo(map[string]interface{}{
+		"ports": []interface{}{map[string]interface{}{"port": int64(80)}},
+	})
+	if _, err := m.Mutate(obj); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{map[string]interface{}{"port": int64(80), "name": "http"}}
+	if err := ensureObj(obj, want, "spec", "ports"); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
@@ -13,9 +14,11 @@ import (
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/path/parser"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/path/tester"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -48,17 +51,28 @@ type Mutator struct {
 	id             types.ID
 	assignMetadata *mutationsv1alpha1.AssignMetadata
 	assignValue    string
+	// fromNamespaceLabel is the label of the object's Namespace whose value is
+	// assigned, rather than assignValue, if set.
+	fromNamespaceLabel string
 
 	path parser.Path
 
 	tester *tester.Tester
+	// excluded indexes the excluded namespaces of the match of
+	// assignMetadata.
+	excluded *util.NamespaceIndex
+	// matcher matches objects against the match of assignMetadata.
+	matcher *match.Matcher
 }
 
 // Mutator implements mutator.
-var _ types.Mutator = &Mutator{}
+var _ types.NamespaceMutator = &Mutator{}
 
 func (m *Mutator) Matches(obj client.Object, ns *corev1.Namespace) bool {
-	matches, err := match.Matches(&m.assignMetadata.Spec.Match, obj, ns)
+	if ns != nil && m.excluded.Matches(ns.Name) {
+		return false
+	}
+	matches, err := m.matcher.Matches(obj, ns)
 	if err != nil {
 		log.Error(err, "Matches failed for assign metadata", "assignMeta", m.assignMetadata.Name)
 		return false
@@ -71,9 +85,32 @@ func (m *Mutator) Mutate(obj *unstructured.Unstructured) (bool, error) {
 	// function instead of using a generic function. AssignMetadata only ever
 	// mutates metadata.annotations or metadata.labels, and we spend ~70% of
 	// compute covering cases that aren't valid for this Mutator.
+	if m.fromNamespaceLabel != "" {
+		// The value is only known once the Namespace is.
+		return false, nil
+	}
 	return core.Mutate(m.path, m.tester, nil, core.NewDefaultSetter(m), obj)
 }
 
+// MutateInNamespace assigns the value of the fromNamespaceLabel label of ns,
+// if the mutator has one, and otherwise mutates as Mutate. Objects which are
+// cluster-scoped, or whose Namespace does not have the label, are not mutated.
+func (m *Mutator) MutateInNamespace(obj *unstructured.Unstructured, ns *corev1.Namespace) (bool, error) {
+	if m.fromNamespaceLabel == "" {
+		return m.Mutate(obj)
+	}
+	if ns == nil {
+		return false, nil
+	}
+	value, ok := ns.GetLabels()[m.fromNamespaceLabel]
+	if !ok {
+		return false, nil
+	}
+	withValue := *m
+	withValue.assignValue = value
+	return core.Mutate(m.path, m.tester, nil, core.NewDefaultSetter(&withValue), obj)
+}
+
 func (m *Mutator) ID() types.ID {
 	return m.id
 }
@@ -101,11 +138,16 @@ func (m *Mutator) HasDiff(mutator types.Mutator) bool {
 
 func (m *Mutator) DeepCopy() types.Mutator {
 	res := &Mutator{
-		id:             m.id,
-		assignMetadata: m.assignMetadata.DeepCopy(),
-		assignValue:    m.assignValue,
-		path:           m.path.DeepCopy(),
-		tester:         m.tester.DeepCopy(),
+		id:                 m.id,
+		assignMetadata:     m.assignMetadata.DeepCopy(),
+		assignValue:        m.assignValue,
+		fromNamespaceLabel: m.fromNamespaceLabel,
+		path:               m.path.DeepCopy(),
+		tester:             m.tester.DeepCopy(),
+		// NamespaceIndexes are immutable and Matchers safe for concurrent
+		// use, so they are shared.
+		excluded: m.excluded,
+		matcher:  m.matcher,
 	}
 	return res
 }
@@ -133,13 +175,27 @@ func MutatorForAssignMetadata(assignMeta *mutationsv1alpha1.AssignMetadata) (*Mu
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid format for parameters.assign")
 	}
-	value, ok := assign["value"]
-	if !ok {
-		return nil, errors.New("spec.parameters.assign must have a string value field for AssignMetadata " + assignMeta.GetName())
-	}
-	valueString, isString := value.(string)
-	if !isString {
-		return nil, errors.New("spec.parameters.assign.value field must be a string for AssignMetadata " + assignMeta.GetName())
+	value, hasValue := assign["value"]
+	label, hasLabel := assign["fromNamespaceLabel"]
+	if hasValue == hasLabel {
+		return nil, errors.New("spec.parameters.assign must have exactly one of a string value or fromNamespaceLabel field for AssignMetadata " + assignMeta.GetName())
+	}
+	var valueString, labelString string
+	if hasValue {
+		var isString bool
+		valueString, isString = value.(string)
+		if !isString {
+			return nil, errors.New("spec.parameters.assign.value field must be a string for AssignMetadata " + assignMeta.GetName())
+		}
+	} else {
+		var isString bool
+		labelString, isString = label.(string)
+		if !isString || labelString == "" {
+			return nil, errors.New("spec.parameters.assign.fromNamespaceLabel field must be a non-empty string for AssignMetadata " + assignMeta.GetName())
+		}
+		if errs := validation.IsQualifiedName(labelString); len(errs) > 0 {
+			return nil, fmt.Errorf("spec.parameters.assign.fromNamespaceLabel field must be a label key for AssignMetadata %s: %s", assignMeta.GetName(), strings.Join(errs, "; "))
+		}
 	}
 
 	t, err := tester.New(path, []tester.Test{
@@ -149,12 +205,17 @@ func MutatorForAssignMetadata(assignMeta *mutationsv1alpha1.AssignMetadata) (*Mu
 		return nil, err
 	}
 
+	// The Matcher refers to the match of the copy held by the Mutator.
+	assignMeta = assignMeta.DeepCopy()
 	return &Mutator{
-		id:             types.MakeID(assignMeta),
-		assignMetadata: assignMeta.DeepCopy(),
-		assignValue:    valueString,
-		path:           path,
-		tester:         t,
+		id:                 types.MakeID(assignMeta),
+		assignMetadata:     assignMeta,
+		assignValue:        valueString,
+		fromNamespaceLabel: labelString,
+		path:               path,
+		tester:             t,
+		excluded:           match.ExcludedNamespaceIndex(&assignMeta.Spec.Match),
+		matcher:            match.NewMatcher(&assignMeta.Spec.Match),
 	}, nil
 }
 
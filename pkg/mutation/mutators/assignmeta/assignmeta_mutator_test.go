@@ -0,0 +1,130 @@
+package assignmeta
+
+import (
+	"testing"
+
+	"github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func fromNamespaceLabel(label, location string) *v1alpha1.AssignMetadata {
+	return &v1alpha1.AssignMetadata{
+		Spec: v1alpha1.AssignMetadataSpec{
+			Location: location,
+			Parameters: v1alpha1.MetadataParameters{
+				Assign: runtime.RawExtension{Raw: []byte(`{"fromNamespaceLabel": "` + label + `"}`)},
+			},
+		},
+	}
+}
+
+func TestMutatorForAssignMetadata(t *testing.T) {
+	tcs := []struct {
+		name    string
+		assign  string
+		wantErr bool
+	}{
+		{name: "value", assign: `{"value": "bar"}`},
+		{name: "from namespace label", assign: `{"fromNamespaceLabel": "example.com/team"}`},
+		{name: "neither", assign: `{}`, wantErr: true},
+		{name: "both", assign: `{"value": "bar", "fromNamespaceLabel": "team"}`, wantErr: true},
+		{name: "non-string value", assign: `{"value": 1}`, wantErr: true},
+		{name: "empty label", assign: `{"fromNamespaceLabel": ""}`, wantErr: true},
+		{name: "invalid label", assign: `{"fromNamespaceLabel": "not a label"}`, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			am := &v1alpha1.AssignMetadata{
+				Spec: v1alpha1.AssignMetadataSpec{
+					Location:   "metadata.labels.team",
+					Parameters: v1alpha1.MetadataParameters{Assign: runtime.RawExtension{Raw: []byte(tc.assign)}},
+				},
+			}
+			_, err := MutatorForAssignMetadata(am)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMutateInNamespace(t *testing.T) {
+	namespace := func(labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: labels}}
+	}
+
+	tcs := []struct {
+		name        string
+		assign      *v1alpha1.AssignMetadata
+		labels      map[string]string
+		ns          *corev1.Namespace
+		wantMutated bool
+		wantLabel   string
+	}{
+		{
+			name:        "copies the label of the namespace",
+			assign:      fromNamespaceLabel("team", "metadata.labels.team"),
+			ns:          namespace(map[string]string{"team": "payments"}),
+			wantMutated: true,
+			wantLabel:   "payments",
+		},
+		{
+			name:   "namespace without the label",
+			assign: fromNamespaceLabel("team", "metadata.labels.team"),
+			ns:     namespace(map[string]string{"owner": "payments"}),
+		},
+		{
+			name:   "cluster-scoped object",
+			assign: fromNamespaceLabel("team", "metadata.labels.team"),
+		},
+		{
+			name:      "label already set",
+			assign:    fromNamespaceLabel("team", "metadata.labels.team"),
+			labels:    map[string]string{"team": "billing"},
+			ns:        namespace(map[string]string{"team": "payments"}),
+			wantLabel: "billing",
+		},
+		{
+			name:        "assigned value ignores the namespace",
+			assign:      assignMetadata("payments", "metadata.labels.team"),
+			ns:          namespace(map[string]string{"team": "billing"}),
+			wantMutated: true,
+			wantLabel:   "payments",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := MutatorForAssignMetadata(tc.assign)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &unstructured.Unstructured{Object: make(map[string]interface{})}
+			obj.SetLabels(tc.labels)
+
+			mutated, err := m.MutateInNamespace(obj, tc.ns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mutated != tc.wantMutated {
+				t.Errorf("got mutated %v, want %v", mutated, tc.wantMutated)
+			}
+			if got := obj.GetLabels()["team"]; got != tc.wantLabel {
+				t.Errorf("got label %q, want %q", got, tc.wantLabel)
+			}
+		})
+	}
+
+	m, err := MutatorForAssignMetadata(fromNamespaceLabel("team", "metadata.labels.team"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := &unstructured.Unstructured{Object: make(map[string]interface{})}
+	if mutated, err := m.Mutate(obj); mutated || err != nil {
+		t.Errorf("got Mutate() = %v, %v without a namespace, want false, nil", mutated, err)
+	}
+}
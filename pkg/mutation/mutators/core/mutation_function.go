@@ -19,7 +19,9 @@ var log = logf.Log.WithName("mutation").WithValues(logging.Process, "mutation")
 type Setter interface {
 	// SetValue takes the object that needs mutating and the key of the
 	// field on that object that should be mutated. It is up to the
-	// implementor to actually mutate the object.
+	// implementor to actually mutate the object. obj is a copy which may be
+	// modified, but the values it holds are shared with the original object
+	// and must be replaced rather than modified in place.
 	SetValue(obj map[string]interface{}, key string) error
 
 	// KeyedListOkay returns whether this setter can handle keyed lists.
@@ -69,6 +71,24 @@ func (s *DefaultSetter) SetValue(obj map[string]interface{}, key string) error {
 	return nil
 }
 
+var _ Setter = &DeleteSetter{}
+
+// DeleteSetter is a setter that removes the field, or keyed list element, at
+// the specified path. Missing parents of the field are not created, and a
+// missing field results in no mutation.
+type DeleteSetter struct{}
+
+func (s *DeleteSetter) KeyedListOkay() bool { return true }
+
+func (s *DeleteSetter) KeyedListValue() (map[string]interface{}, error) {
+	return nil, errors.New("a deleting setter has no keyed list value")
+}
+
+func (s *DeleteSetter) SetValue(obj map[string]interface{}, key string) error {
+	delete(obj, key)
+	return nil
+}
+
 func Mutate(
 	path parser.Path,
 	tester *path.Tester,
@@ -91,8 +111,12 @@ func Mutate(
 	if obj == nil {
 		return false, errors.New("attempting to mutate a nil object")
 	}
-	mutated, _, err := s.mutateInternal(obj.Object, 0)
-	return mutated, err
+	mutated, newObj, err := s.mutateInternal(obj.Object, 0)
+	if err != nil || !mutated {
+		return false, err
+	}
+	obj.Object = newObj.(map[string]interface{})
+	return true, nil
 }
 
 type mutatorState struct {
@@ -104,8 +128,18 @@ type mutatorState struct {
 	setter    Setter
 }
 
+// deleting returns whether the field at the path is removed rather than set.
+func (s *mutatorState) deleting() bool {
+	_, ok := s.setter.(*DeleteSetter)
+	return ok
+}
+
 // mutateInternal mutates the resource recursively. It returns false if there has been no change
 // to any downstream objects in the tree, indicating that the mutation should not be persisted.
+//
+// Mutation is copy-on-write: current is never modified. Only the maps and lists
+// along the mutated path are cloned, and the returned value shares every
+// untouched subtree with current.
 func (s *mutatorState) mutateInternal(current interface{}, depth int) (bool, interface{}, error) {
 	pathEntry := s.path.Nodes[depth]
 	switch castPathEntry := pathEntry.(type) {
@@ -129,12 +163,19 @@ func (s *mutatorState) mutateInternal(current interface{}, depth int) (bool, int
 			if s.valueTest != nil && !s.valueTest(next, exists) {
 				return false, nil, nil
 			}
-			if err := s.setter.SetValue(currentAsObject, castPathEntry.Reference); err != nil {
+			if !exists && s.deleting() {
+				return false, nil, nil
+			}
+			cloned := shallowCopyObject(currentAsObject)
+			if err := s.setter.SetValue(cloned, castPathEntry.Reference); err != nil {
 				return false, nil, err
 			}
-			return true, currentAsObject, nil
+			return true, cloned, nil
 		}
 		if !exists { // Next element is missing and needs to be added
+			if s.deleting() {
+				return false, nil, nil
+			}
 			var err error
 			next, err = s.createMissingElement(depth)
 			if err != nil {
@@ -145,24 +186,24 @@ func (s *mutatorState) mutateInternal(current interface{}, depth int) (bool, int
 		if err != nil {
 			return false, nil, err
 		}
-		if mutated {
-			currentAsObject[castPathEntry.Reference] = next
+		if !mutated {
+			return false, currentAsObject, nil
 		}
-		return mutated, currentAsObject, nil
+		cloned := shallowCopyObject(currentAsObject)
+		cloned[castPathEntry.Reference] = next
+		return true, cloned, nil
 	case *parser.List:
 		elementFound := false
 		currentAsList, ok := current.([]interface{})
 		if !ok { // Path entry type does not match current object
 			return false, nil, fmt.Errorf("mismatch between path entry (type: List) and received object (type: %T). Path: %+v", current, castPathEntry)
 		}
-		shallowCopy := make([]interface{}, len(currentAsList))
-		copy(shallowCopy, currentAsList)
 		// base case
 		if len(s.path.Nodes)-1 == depth {
 			if !s.setter.KeyedListOkay() {
 				return false, nil, ErrNonKeyedSetter
 			}
-			return s.setListElementToValue(shallowCopy, castPathEntry, depth)
+			return s.setListElementToValue(shallowCopyList(currentAsList), castPathEntry, depth)
 		}
 
 		glob := castPathEntry.Glob
@@ -171,14 +212,22 @@ func (s *mutatorState) mutateInternal(current interface{}, depth int) (bool, int
 		if glob && !s.tester.ExistsOkay(depth) {
 			return false, nil, nil
 		}
+		// result is only cloned from currentAsList once an element is mutated.
+		result := currentAsList
 		mutated := false
-		for _, listElement := range shallowCopy {
+		for i, listElement := range currentAsList {
 			if glob {
-				m, _, err := s.mutateInternal(listElement, depth+1)
+				m, next, err := s.mutateInternal(listElement, depth+1)
 				if err != nil {
 					return false, nil, err
 				}
-				mutated = mutated || m
+				if m {
+					if !mutated {
+						result = shallowCopyList(currentAsList)
+					}
+					result[i] = next
+					mutated = true
+				}
 				elementFound = true
 			} else if listElementAsObject, ok := listElement.(map[string]interface{}); ok {
 				if elementValue, ok := listElementAsObject[key]; ok {
@@ -186,11 +235,17 @@ func (s *mutatorState) mutateInternal(current interface{}, depth int) (bool, int
 						if !s.tester.ExistsOkay(depth) {
 							return false, nil, nil
 						}
-						m, _, err := s.mutateInternal(listElement, depth+1)
+						m, next, err := s.mutateInternal(listElement, depth+1)
 						if err != nil {
 							return false, nil, err
 						}
-						mutated = mutated || m
+						if m {
+							if !mutated {
+								result = shallowCopyList(currentAsList)
+							}
+							result[i] = next
+							mutated = true
+						}
 						elementFound = true
 					}
 				}
@@ -198,21 +253,23 @@ func (s *mutatorState) mutateInternal(current interface{}, depth int) (bool, int
 		}
 		// If no matching element in the array was found in non Globbed list, create a new element
 		if !castPathEntry.Glob && !elementFound {
-			if !s.tester.MissingOkay(depth) {
+			if !s.tester.MissingOkay(depth) || s.deleting() {
 				return false, nil, nil
 			}
 			next, err := s.createMissingElement(depth)
 			if err != nil {
 				return false, nil, err
 			}
-			shallowCopy = append(shallowCopy, next)
-			m, _, err := s.mutateInternal(next, depth+1)
+			m, next, err := s.mutateInternal(next, depth+1)
 			if err != nil {
 				return false, nil, err
 			}
-			mutated = mutated || m
+			if m {
+				result = append(shallowCopyList(currentAsList), next)
+				mutated = true
+			}
 		}
-		return mutated, shallowCopy, nil
+		return mutated, result, nil
 	default:
 		return false, nil, fmt.Errorf("invalid type pathEntry type: %T", pathEntry)
 	}
@@ -222,6 +279,9 @@ func (s *mutatorState) setListElementToValue(currentAsList []interface{}, listPa
 	if listPathEntry.Glob {
 		return false, nil, fmt.Errorf("last path entry can not be globbed")
 	}
+	if s.deleting() {
+		return s.deleteListElement(currentAsList, listPathEntry, depth)
+	}
 
 	newValueAsObject, err := s.setter.KeyedListValue()
 	if err != nil {
@@ -255,6 +315,25 @@ func (s *mutatorState) setListElementToValue(currentAsList []interface{}, listPa
 	return true, append(currentAsList, newValueAsObject), nil
 }
 
+// deleteListElement removes the element of currentAsList whose key matches
+// listPathEntry, if there is one.
+func (s *mutatorState) deleteListElement(currentAsList []interface{}, listPathEntry *parser.List, depth int) (bool, []interface{}, error) {
+	if listPathEntry.KeyValue == nil {
+		return false, nil, errors.New("encountered nil key value when deleting a list element")
+	}
+	for i, listElement := range currentAsList {
+		if elementValue, found, err := nestedFieldNoCopy(listElement, listPathEntry.KeyField); err != nil {
+			return false, nil, err
+		} else if found && listPathEntry.KeyValue == elementValue {
+			if !s.tester.ExistsOkay(depth) {
+				return false, nil, nil
+			}
+			return true, append(currentAsList[:i], currentAsList[i+1:]...), nil
+		}
+	}
+	return false, nil, nil
+}
+
 func (s *mutatorState) createMissingElement(depth int) (interface{}, error) {
 	var next interface{}
 	pathEntry := s.path.Nodes[depth]
@@ -282,6 +361,23 @@ func (s *mutatorState) createMissingElement(depth int) (interface{}, error) {
 	return next, nil
 }
 
+// shallowCopyObject returns a copy of obj which shares its values.
+func shallowCopyObject(obj map[string]interface{}) map[string]interface{} {
+	cloned := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		cloned[k] = v
+	}
+	return cloned
+}
+
+// shallowCopyList returns a copy of list which shares its elements, with room
+// for one more to be appended without reallocating.
+func shallowCopyList(list []interface{}) []interface{} {
+	cloned := make([]interface{}, len(list), len(list)+1)
+	copy(cloned, list)
+	return cloned
+}
+
 func nestedFieldNoCopy(current interface{}, key string) (interface{}, bool, error) {
 	currentAsMap, ok := current.(map[string]interface{})
 	if !ok {
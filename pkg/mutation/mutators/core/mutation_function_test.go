@@ -3,8 +3,11 @@ package core_test
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
@@ -233,6 +236,39 @@ func TestGlobbedList(t *testing.T) {
 	}
 }
 
+func TestCopyOnWrite(t *testing.T) {
+	locations := []string{
+		"spec.dnsPolicy",
+		"spec.element.should.be.added",
+		`spec.containers["name": "testname2"].ports["name": "portName2B"].hostIP`,
+		`spec.containers["name": "notExists"].image`,
+		`spec.containers["name": *].ports["name": *].protocol`,
+	}
+	for _, location := range locations {
+		t.Run(location, func(t *testing.T) {
+			pod := prepareTestPod(t)
+			original := pod.Object
+			want := pod.DeepCopy().Object
+			metadata := original["metadata"].(map[string]interface{})
+
+			testFunc := func(u *unstructured.Unstructured) {
+				if !cmp.Equal(original, want) {
+					t.Errorf("original object was modified: %s", cmp.Diff(want, original))
+				}
+				if cmp.Equal(u.Object, want) {
+					t.Error("got no mutation")
+				}
+				if got := u.Object["metadata"].(map[string]interface{}); reflect.ValueOf(got).Pointer() != reflect.ValueOf(metadata).Pointer() {
+					t.Error("unchanged metadata was copied")
+				}
+			}
+			if err := testAssignMutation("", "v1", "Pod", location, ParameterTestValue, pod, testFunc, t); err != nil {
+				t.Errorf("Unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
 func TestNonExistingPathEntry(t *testing.T) {
 	testFunc := func(unstr *unstructured.Unstructured) {
 		element, found, err := unstructured.NestedString(unstr.Object, "spec", "element", "should", "be", "added")
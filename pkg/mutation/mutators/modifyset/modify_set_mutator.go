@@ -14,6 +14,7 @@ import (
 	patht "github.com/open-policy-agent/gatekeeper/pkg/mutation/path/tester"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/schema"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -36,6 +37,10 @@ type Mutator struct {
 	// bindings are the set of GVKs this Mutator applies to.
 	bindings []runtimeschema.GroupVersionKind
 	tester   *patht.Tester
+	// excluded indexes the excluded namespaces of the match of modifySet.
+	excluded *util.NamespaceIndex
+	// matcher matches objects against the match of modifySet.
+	matcher *match.Matcher
 }
 
 // Mutator implements mutatorWithSchema.
@@ -45,7 +50,10 @@ func (m *Mutator) Matches(obj client.Object, ns *corev1.Namespace) bool {
 	if !match.AppliesTo(m.modifySet.Spec.ApplyTo, obj) {
 		return false
 	}
-	matches, err := match.Matches(&m.modifySet.Spec.Match, obj, ns)
+	if ns != nil && m.excluded.Matches(ns.Name) {
+		return false
+	}
+	matches, err := m.matcher.Matches(obj, ns)
 	if err != nil {
 		log.Error(err, "Matches failed for modify set", "modifyset", m.modifySet.Name)
 		return false
@@ -118,6 +126,10 @@ func (m *Mutator) DeepCopy() types.Mutator {
 			Nodes: make([]parser.Node, len(m.path.Nodes)),
 		},
 		bindings: make([]runtimeschema.GroupVersionKind, len(m.bindings)),
+		// NamespaceIndexes are immutable and Matchers safe for concurrent
+		// use, so they are shared.
+		excluded: m.excluded,
+		matcher:  m.matcher,
 	}
 
 	copy(res.path.Nodes, m.path.Nodes)
@@ -167,12 +179,16 @@ func MutatorForModifySet(modifySet *mutationsv1alpha1.ModifySet) (*Mutator, erro
 		return nil, fmt.Errorf("applyTo required for ModifySet mutator %s", modifySet.GetName())
 	}
 
+	// The Matcher refers to the match of the copy held by the Mutator.
+	modifySet = modifySet.DeepCopy()
 	return &Mutator{
 		id:        id,
-		modifySet: modifySet.DeepCopy(),
+		modifySet: modifySet,
 		bindings:  gvks,
 		path:      path,
 		tester:    tester,
+		excluded:  match.ExcludedNamespaceIndex(&modifySet.Spec.Match),
+		matcher:   match.NewMatcher(&modifySet.Spec.Match),
 	}, nil
 }
 
@@ -266,6 +282,9 @@ func (s setter) setValueMerge(obj map[string]interface{}, key string) error {
 	if !ok {
 		return fmt.Errorf("%+v is not a list of values, cannot treat it as a set", val)
 	}
+	// vals is shared with the original object, so it must not be appended to
+	// in place.
+	vals = vals[:len(vals):len(vals)]
 outer:
 	for _, v := range s.values {
 		for _, existing := range vals {
@@ -291,6 +310,9 @@ func (s setter) setValuePrune(obj map[string]interface{}, key string) error {
 	if !ok {
 		return fmt.Errorf("%+v is not a list of values, cannot treat it as a set", val)
 	}
+	// vals is shared with the original object, so elements are removed from a
+	// copy of it.
+	vals = append(make([]interface{}, 0, len(vals)), vals...)
 outer:
 	for _, v := range s.values {
 		for i, existing := range vals {
@@ -0,0 +1,45 @@
+package namespacedefaults
+
+import (
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// mutator is a mutator compiled from a NamespaceDefaults.
+//
+// It hides the schema of the mutator it wraps from the mutation System, so
+// the defaults of a namespace owner can never conflict with, and so disable,
+// the mutators of cluster administrators. Its paths are fixed fields of the
+// Pod schema, so it does not need the System to check them.
+type mutator struct {
+	types.Mutator
+	// createOnly is whether only objects being created are mutated.
+	createOnly bool
+}
+
+var _ types.Mutator = &mutator{}
+
+func (m *mutator) Matches(obj client.Object, ns *corev1.Namespace) bool {
+	// Objects are given a creation timestamp once admitted, so objects
+	// without one are being created.
+	if created := obj.GetCreationTimestamp(); m.createOnly && !created.IsZero() {
+		return false
+	}
+	return m.Mutator.Matches(obj, ns)
+}
+
+func (m *mutator) HasDiff(other types.Mutator) bool {
+	o, ok := other.(*mutator)
+	if !ok {
+		return true
+	}
+	return m.createOnly != o.createOnly || m.Mutator.HasDiff(o.Mutator)
+}
+
+func (m *mutator) DeepCopy() types.Mutator {
+	return &mutator{
+		Mutator:    m.Mutator.DeepCopy(),
+		createOnly: m.createOnly,
+	}
+}
@@ -0,0 +1,249 @@
+// Package namespacedefaults compiles NamespaceDefaults into the mutators
+// which apply them.
+//
+// NamespaceDefaults are created by namespace owners rather than cluster
+// administrators, so what they may express is deliberately narrow: labels,
+// tolerations and image pull secrets, added to objects in the namespace of
+// the NamespaceDefaults which do not already have them.
+package namespacedefaults
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators/assignmeta"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators/modifyset"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/path/parser"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Enabled is whether NamespaceDefaults are compiled into mutators.
+var Enabled = flag.Bool("enable-namespace-defaults", false, "(alpha) apply the labels, tolerations and image pull secrets declared by NamespaceDefaults to the objects in their namespace. Requires mutation to be enabled")
+
+// reservedDomain is the domain of the labels Gatekeeper sets, which
+// NamespaceDefaults may not add.
+const reservedDomain = "gatekeeper.sh"
+
+// MutatorsFor returns the mutators applying the defaults of nd, or an error
+// listing every invalid default.
+//
+// Each label is added by its own mutator, so a label an object already has
+// does not prevent the others from being added. Tolerations and image pull
+// secrets are only added to Pods being created, as they cannot be changed
+// once a Pod exists.
+func MutatorsFor(nd *mutationsv1alpha1.NamespaceDefaults) ([]types.Mutator, error) {
+	if errs := validate(nd); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	var mutators []types.Mutator
+	for _, key := range sortedKeys(nd.Spec.Labels) {
+		m, err := labelMutator(nd, key, nd.Spec.Labels[key])
+		if err != nil {
+			return nil, err
+		}
+		mutators = append(mutators, m)
+	}
+
+	if len(nd.Spec.Tolerations) > 0 {
+		values := make([]interface{}, 0, len(nd.Spec.Tolerations))
+		for i := range nd.Spec.Tolerations {
+			value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&nd.Spec.Tolerations[i])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		m, err := podSetMutator(nd, "tolerations", values)
+		if err != nil {
+			return nil, err
+		}
+		mutators = append(mutators, m)
+	}
+
+	if len(nd.Spec.ImagePullSecrets) > 0 {
+		values := make([]interface{}, 0, len(nd.Spec.ImagePullSecrets))
+		for i := range nd.Spec.ImagePullSecrets {
+			value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&nd.Spec.ImagePullSecrets[i])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		m, err := podSetMutator(nd, "imagePullSecrets", values)
+		if err != nil {
+			return nil, err
+		}
+		mutators = append(mutators, m)
+	}
+
+	return mutators, nil
+}
+
+// validate returns the invalid defaults of nd.
+func validate(nd *mutationsv1alpha1.NamespaceDefaults) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	for _, key := range sortedKeys(nd.Spec.Labels) {
+		path := spec.Child("labels").Key(key)
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path, key, msg))
+		}
+		if isReserved(key) {
+			errs = append(errs, field.Forbidden(path, "labels in the "+reservedDomain+" domain are reserved"))
+		}
+		for _, msg := range validation.IsValidLabelValue(nd.Spec.Labels[key]) {
+			errs = append(errs, field.Invalid(path, nd.Spec.Labels[key], msg))
+		}
+	}
+
+	for i, t := range nd.Spec.Tolerations {
+		errs = append(errs, validateToleration(spec.Child("tolerations").Index(i), t)...)
+	}
+
+	for i, s := range nd.Spec.ImagePullSecrets {
+		path := spec.Child("imagePullSecrets").Index(i).Child("name")
+		if s.Name == "" {
+			errs = append(errs, field.Required(path, ""))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(s.Name) {
+			errs = append(errs, field.Invalid(path, s.Name, msg))
+		}
+	}
+
+	return errs
+}
+
+// validateToleration returns the errors of t the API server would reject a
+// Pod for.
+func validateToleration(path *field.Path, t corev1.Toleration) field.ErrorList {
+	var errs field.ErrorList
+
+	if t.Key != "" {
+		for _, msg := range validation.IsQualifiedName(t.Key) {
+			errs = append(errs, field.Invalid(path.Child("key"), t.Key, msg))
+		}
+	}
+
+	switch t.Operator {
+	case corev1.TolerationOpEqual, "":
+		if t.Key == "" {
+			errs = append(errs, field.Invalid(path.Child("operator"), t.Operator, "operator must be Exists when key is empty"))
+		}
+		for _, msg := range validation.IsValidLabelValue(t.Value) {
+			errs = append(errs, field.Invalid(path.Child("value"), t.Value, msg))
+		}
+	case corev1.TolerationOpExists:
+		if t.Value != "" {
+			errs = append(errs, field.Invalid(path.Child("value"), t.Value, "value must be empty when operator is Exists"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("operator"), t.Operator,
+			[]string{string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists)}))
+	}
+
+	switch t.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("effect"), t.Effect,
+			[]string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}))
+	}
+
+	if t.TolerationSeconds != nil && t.Effect != corev1.TaintEffectNoExecute {
+		errs = append(errs, field.Invalid(path.Child("effect"), t.Effect, "effect must be NoExecute when tolerationSeconds is set"))
+	}
+
+	return errs
+}
+
+// isReserved returns whether the label key is in the domain of Gatekeeper.
+func isReserved(key string) bool {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return false
+	}
+	prefix := key[:i]
+	return prefix == reservedDomain || strings.HasSuffix(prefix, "."+reservedDomain)
+}
+
+// labelMutator returns the mutator adding the label key with value to the
+// objects in the namespace of nd.
+func labelMutator(nd *mutationsv1alpha1.NamespaceDefaults, key, value string) (types.Mutator, error) {
+	assign, err := json.Marshal(map[string]interface{}{"value": value})
+	if err != nil {
+		return nil, err
+	}
+	location := parser.Path{Nodes: []parser.Node{
+		&parser.Object{Reference: "metadata"},
+		&parser.Object{Reference: "labels"},
+		&parser.Object{Reference: key},
+	}}
+
+	am := &mutationsv1alpha1.AssignMetadata{}
+	setMeta(am, nd, "labels/"+key)
+	am.Spec.Match = match.Match{
+		Scope:      apiextensionsv1.NamespaceScoped,
+		Namespaces: []string{nd.Namespace},
+	}
+	am.Spec.Location = location.String()
+	am.Spec.Parameters.Assign = runtime.RawExtension{Raw: assign}
+
+	m, err := assignmeta.MutatorForAssignMetadata(am)
+	if err != nil {
+		return nil, err
+	}
+	return &mutator{Mutator: m}, nil
+}
+
+// podSetMutator returns the mutator adding values to the list in the spec
+// field name of the Pods being created in the namespace of nd.
+func podSetMutator(nd *mutationsv1alpha1.NamespaceDefaults, name string, values []interface{}) (types.Mutator, error) {
+	ms := &mutationsv1alpha1.ModifySet{}
+	setMeta(ms, nd, name)
+	ms.Spec.ApplyTo = []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}}
+	ms.Spec.Match = match.Match{
+		Scope:      apiextensionsv1.NamespaceScoped,
+		Kinds:      []match.Kinds{{APIGroups: []string{""}, Kinds: []string{"Pod"}}},
+		Namespaces: []string{nd.Namespace},
+	}
+	ms.Spec.Location = "spec." + name
+	ms.Spec.Parameters.Operation = mutationsv1alpha1.MergeOp
+	ms.Spec.Parameters.Values.FromList = values
+
+	m, err := modifyset.MutatorForModifySet(ms)
+	if err != nil {
+		return nil, err
+	}
+	return &mutator{Mutator: m, createOnly: true}, nil
+}
+
+// setMeta names obj, a mutator compiled from nd, after nd and the default
+// it applies, so the IDs of the mutators of different NamespaceDefaults and
+// of other mutators never collide.
+func setMeta(obj client.Object, nd *mutationsv1alpha1.NamespaceDefaults, suffix string) {
+	obj.GetObjectKind().SetGroupVersionKind(mutationsv1alpha1.GroupVersion.WithKind("NamespaceDefaults"))
+	obj.SetNamespace(nd.Namespace)
+	obj.SetName(fmt.Sprintf("%s/%s", nd.Name, suffix))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,226 @@
+package namespacedefaults
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newNamespaceDefaults(spec mutationsv1alpha1.NamespaceDefaultsSpec) *mutationsv1alpha1.NamespaceDefaults {
+	nd := &mutationsv1alpha1.NamespaceDefaults{Spec: spec}
+	nd.SetNamespace("team-a")
+	nd.SetName("defaults")
+	return nd
+}
+
+func TestMutatorsFor_Invalid(t *testing.T) {
+	seconds := int64(60)
+	tcs := []struct {
+		name    string
+		spec    mutationsv1alpha1.NamespaceDefaultsSpec
+		wantErr string
+	}{
+		{
+			name:    "invalid label key",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{Labels: map[string]string{"team name": "a"}},
+			wantErr: "spec.labels[team name]",
+		},
+		{
+			name:    "invalid label value",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{Labels: map[string]string{"team": "a b"}},
+			wantErr: "spec.labels[team]",
+		},
+		{
+			name:    "reserved label",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{Labels: map[string]string{"admission.gatekeeper.sh/ignore": "yes"}},
+			wantErr: "reserved",
+		},
+		{
+			name:    "unknown toleration operator",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{Tolerations: []corev1.Toleration{{Key: "gpu", Operator: "In"}}},
+			wantErr: "spec.tolerations[0].operator",
+		},
+		{
+			name:    "toleration of all keys with value",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{Tolerations: []corev1.Toleration{{Value: "true"}}},
+			wantErr: "spec.tolerations[0].operator",
+		},
+		{
+			name:    "toleration seconds without NoExecute",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{Tolerations: []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists, TolerationSeconds: &seconds}}},
+			wantErr: "spec.tolerations[0].effect",
+		},
+		{
+			name:    "unnamed pull secret",
+			spec:    mutationsv1alpha1.NamespaceDefaultsSpec{ImagePullSecrets: []corev1.LocalObjectReference{{}}},
+			wantErr: "spec.imagePullSecrets[0].name",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := MutatorsFor(newNamespaceDefaults(tc.spec))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMutatorsFor_IDs(t *testing.T) {
+	ms, err := MutatorsFor(newNamespaceDefaults(mutationsv1alpha1.NamespaceDefaultsSpec{
+		Labels:           map[string]string{"team": "a", "example.com/cost-center": "42"},
+		Tolerations:      []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists}},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range ms {
+		got = append(got, m.ID().String())
+	}
+	want := []string{
+		"NamespaceDefaults.mutations.gatekeeper.sh team-a/defaults/labels/example.com/cost-center",
+		"NamespaceDefaults.mutations.gatekeeper.sh team-a/defaults/labels/team",
+		"NamespaceDefaults.mutations.gatekeeper.sh team-a/defaults/tolerations",
+		"NamespaceDefaults.mutations.gatekeeper.sh team-a/defaults/imagePullSecrets",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	copied := ms[2].DeepCopy()
+	if ms[2].HasDiff(copied) {
+		t.Error("copy of mutator differs from it")
+	}
+}
+
+func newPod(t *testing.T, namespace string, created bool) *unstructured.Unstructured {
+	t.Helper()
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "pod",
+			Labels:    map[string]string{"team": "b"},
+		},
+		Spec: corev1.PodSpec{
+			Containers:  []corev1.Container{{Name: "main", Image: "nginx"}},
+			Tolerations: []corev1.Toleration{{Key: "spot", Operator: corev1.TolerationOpExists}},
+		},
+	}
+	if created {
+		pod.SetCreationTimestamp(metav1.Now())
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestMutatorsFor_Mutate(t *testing.T) {
+	s := mutation.NewSystem(mutation.SystemOpts{})
+	ms, err := MutatorsFor(newNamespaceDefaults(mutationsv1alpha1.NamespaceDefaultsSpec{
+		Labels:           map[string]string{"team": "a", "cost-center": "42"},
+		Tolerations:      []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range ms {
+		if err := s.Upsert(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An administrator's mutator of a toleration by key implies a schema
+	// which conflicts with merging into the tolerations as a set. It must
+	// still be applied.
+	raw, err := json.Marshal(map[string]interface{}{"value": "NoExecute"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &mutationsv1alpha1.Assign{}
+	a.SetName("spot-effect")
+	a.Spec.ApplyTo = []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}}
+	a.Spec.Location = "spec.tolerations[key: spot].effect"
+	a.Spec.Parameters.Assign = runtime.RawExtension{Raw: raw}
+	assign, err := mutators.MutatorForAssign(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upsert(assign); err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name            string
+		namespace       string
+		created         bool
+		wantLabels      map[string]string
+		wantTolerations int
+		wantSecrets     int
+	}{
+		{
+			name:            "pod being created",
+			namespace:       "team-a",
+			wantLabels:      map[string]string{"team": "b", "cost-center": "42"},
+			wantTolerations: 2,
+			wantSecrets:     1,
+		},
+		{
+			name:            "existing pod",
+			namespace:       "team-a",
+			created:         true,
+			wantLabels:      map[string]string{"team": "b", "cost-center": "42"},
+			wantTolerations: 1,
+		},
+		{
+			name:            "pod in other namespace",
+			namespace:       "team-b",
+			wantLabels:      map[string]string{"team": "b"},
+			wantTolerations: 1,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := newPod(t, tc.namespace, tc.created)
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: tc.namespace}}
+			if _, err := s.Mutate(obj, ns); err != nil {
+				t.Fatal(err)
+			}
+
+			pod := &corev1.Pod{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantLabels, pod.Labels); diff != "" {
+				t.Error(diff)
+			}
+			if len(pod.Spec.Tolerations) != tc.wantTolerations {
+				t.Errorf("got tolerations %v, want %d", pod.Spec.Tolerations, tc.wantTolerations)
+			}
+			if len(pod.Spec.ImagePullSecrets) != tc.wantSecrets {
+				t.Errorf("got image pull secrets %v, want %d", pod.Spec.ImagePullSecrets, tc.wantSecrets)
+			}
+			if pod.Spec.Tolerations[0].Effect != corev1.TaintEffectNoExecute {
+				t.Errorf("got toleration %v, want effect set by Assign", pod.Spec.Tolerations[0])
+			}
+		})
+	}
+}
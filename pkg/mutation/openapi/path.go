@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/path/parser"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/schema"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// preserveUnknownFieldsExtension is the extension of the schemas of CRDs
+// allowing fields their schemas do not define.
+const preserveUnknownFieldsExtension = "x-kubernetes-preserve-unknown-fields"
+
+// quantitySuffix is the suffix of the name of the model of resource
+// quantities, which are strings but also accept numbers.
+const quantitySuffix = "resource.Quantity"
+
+// validatePath returns an error if nodes is not a path through s, or if value
+// does not match the type of the field at the end of nodes. If terminal is a
+// Set, value holds elements of the list at the end of nodes.
+func validatePath(s proto.Schema, nodes []parser.Node, value interface{}, terminal parser.NodeType) error {
+	for i, n := range nodes {
+		at := parser.Path{Nodes: nodes[:i+1]}.String()
+		s = resolve(s)
+		if _, ok := s.(*proto.Arbitrary); ok {
+			// Nothing is known of what is below this field.
+			return nil
+		}
+
+		switch node := n.(type) {
+		case parser.Object:
+			next, err := field(s, node.Reference, at)
+			if err != nil || next == nil {
+				return err
+			}
+			s = next
+		case *parser.Object:
+			next, err := field(s, node.Reference, at)
+			if err != nil || next == nil {
+				return err
+			}
+			s = next
+		case parser.List:
+			next, err := element(s, node.KeyField, at)
+			if err != nil || next == nil {
+				return err
+			}
+			s = next
+		case *parser.List:
+			next, err := element(s, node.KeyField, at)
+			if err != nil || next == nil {
+				return err
+			}
+			s = next
+		default:
+			return nil
+		}
+	}
+
+	if terminal != schema.Set {
+		return validateValue(s, value, parser.Path{Nodes: nodes}.String())
+	}
+
+	at := parser.Path{Nodes: nodes}.String()
+	switch t := resolve(s).(type) {
+	case *proto.Arbitrary:
+		return nil
+	case *proto.Array:
+		return validateValue(&proto.Array{SubType: t.SubType}, value, at)
+	default:
+		return fmt.Errorf("%s is %s, not a list", at, describe(t))
+	}
+}
+
+// field returns the schema of the field named name of the object s. It
+// returns nil if s allows any field.
+func field(s proto.Schema, name, at string) (proto.Schema, error) {
+	switch t := s.(type) {
+	case *proto.Kind:
+		f, found := t.Fields[name]
+		if found {
+			return f, nil
+		}
+		if preservesUnknownFields(t) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s is not a field of the schema", at)
+	case *proto.Map:
+		return t.SubType, nil
+	default:
+		return nil, fmt.Errorf("the parent of %s is %s, not an object", at, describe(t))
+	}
+}
+
+// element returns the schema of the elements of the list s, whose elements
+// are identified by keyField.
+func element(s proto.Schema, keyField, at string) (proto.Schema, error) {
+	list, ok := s.(*proto.Array)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list, but %s", at, describe(s))
+	}
+	if kind, ok := resolve(list.SubType).(*proto.Kind); ok && keyField != "" {
+		if _, found := kind.Fields[keyField]; !found && !preservesUnknownFields(kind) {
+			return nil, fmt.Errorf("%s is keyed by %q, which is not a field of its elements", at, keyField)
+		}
+	}
+	return list.SubType, nil
+}
+
+// validateValue returns an error if value does not match the schema s of the
+// field at.
+func validateValue(s proto.Schema, value interface{}, at string) error {
+	if value == nil {
+		return nil
+	}
+	if ref, ok := s.(proto.Reference); ok && strings.HasSuffix(ref.Reference(), quantitySuffix) {
+		switch value.(type) {
+		case string, float64, int64, int:
+			return nil
+		}
+		return fmt.Errorf("%s must be a quantity, got %T", at, value)
+	}
+
+	switch t := resolve(s).(type) {
+	case *proto.Primitive:
+		if !primitiveMatches(t, value) {
+			return fmt.Errorf("%s must be of type %s, got %T", at, t.Type, value)
+		}
+	case *proto.Array:
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be a list, got %T", at, value)
+		}
+		for i, elem := range list {
+			if err := validateValue(t.SubType, elem, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case *proto.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object, got %T", at, value)
+		}
+		for k, v := range m {
+			if err := validateValue(t.SubType, v, at+"."+k); err != nil {
+				return err
+			}
+		}
+	case *proto.Kind:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object, got %T", at, value)
+		}
+		for k, v := range m {
+			f, found := t.Fields[k]
+			if !found {
+				if preservesUnknownFields(t) {
+					continue
+				}
+				return fmt.Errorf("%s.%s is not a field of the schema", at, k)
+			}
+			if err := validateValue(f, v, at+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// primitiveMatches returns true if value is of the type of p.
+func primitiveMatches(p *proto.Primitive, value interface{}) bool {
+	switch p.Type {
+	case proto.String:
+		if _, ok := value.(string); ok {
+			return true
+		}
+		return p.Format == "int-or-string" && isInteger(value)
+	case proto.Integer:
+		return isInteger(value)
+	case proto.Number:
+		switch value.(type) {
+		case float64, int64, int:
+			return true
+		}
+		return false
+	case proto.Boolean:
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+func isInteger(value interface{}) bool {
+	switch v := value.(type) {
+	case int, int64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	default:
+		return false
+	}
+}
+
+// resolve returns the schema s refers to.
+func resolve(s proto.Schema) proto.Schema {
+	for {
+		ref, ok := s.(proto.Reference)
+		if !ok {
+			return s
+		}
+		s = ref.SubSchema()
+	}
+}
+
+func preservesUnknownFields(s proto.Schema) bool {
+	preserve, _ := s.GetExtensions()[preserveUnknownFieldsExtension].(bool)
+	return preserve
+}
+
+// describe returns the type of s, for error messages.
+func describe(s proto.Schema) string {
+	switch t := s.(type) {
+	case *proto.Kind, *proto.Map:
+		return "an object"
+	case *proto.Array:
+		return "a list"
+	case *proto.Primitive:
+		if t.Type == proto.Integer {
+			return "an integer"
+		}
+		return "a " + t.Type
+	default:
+		return "a value"
+	}
+}
@@ -0,0 +1,198 @@
+// Package openapi validates mutators against the OpenAPI schemas the API
+// server publishes for the kinds they apply to.
+//
+// The API server prunes fields which are not part of the schema of a kind
+// without reporting an error, so a mutator assigning a misspelled field
+// appears to work while having no effect. A Validator rejects such mutators
+// when they are ingested instead.
+package openapi
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/schema"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/util/proto"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("mutation-openapi")
+
+// Enabled is whether mutators are validated against the OpenAPI schemas of
+// the kinds they apply to when they are ingested.
+var Enabled = flag.Bool("validate-mutator-schemas", false, "(alpha) reject mutators whose location is not a field of the OpenAPI schema of a kind they apply to, or whose value does not match the type of that field. Kinds without a published schema are not checked")
+
+// ErrSchemaMismatch reports that a mutator would write a field which is not
+// part of the schema of a kind it applies to.
+const ErrSchemaMismatch = util.Error("mutator does not match the schema of the kind it mutates")
+
+const (
+	// maxAge is how long fetched schemas are used before they are fetched
+	// again, so changes to CRDs are eventually observed.
+	maxAge = 5 * time.Minute
+	// minRefetchInterval is the least time between fetches caused by a kind
+	// without a schema, which may belong to a CRD created since the last fetch.
+	minRefetchInterval = 10 * time.Second
+)
+
+// Validator validates mutators against the OpenAPI schemas served by an API
+// server. It is safe for concurrent use.
+type Validator struct {
+	// fetch returns the schemas served by the API server, keyed by the kinds
+	// they define.
+	fetch func() (map[runtimeschema.GroupVersionKind]proto.Schema, error)
+	now   func() time.Time
+
+	// mux guards schemas and fetched.
+	mux sync.Mutex
+	// schemas are the schemas of kinds, keyed by GroupVersionKind.
+	schemas map[runtimeschema.GroupVersionKind]proto.Schema
+	// fetched is when schemas were fetched.
+	fetched time.Time
+}
+
+// NewValidator returns a Validator of the schemas served by client.
+func NewValidator(client discovery.OpenAPISchemaInterface) *Validator {
+	return &Validator{
+		fetch: func() (map[runtimeschema.GroupVersionKind]proto.Schema, error) {
+			return fetchSchemas(client)
+		},
+		now: time.Now,
+	}
+}
+
+// Validate returns an error wrapping ErrSchemaMismatch if the path of m is
+// not a field of the schema of a kind m applies to, or if the value of m
+// does not match the type of that field. Kinds without a published schema,
+// and mutators which do not declare the kinds they apply to, are not checked.
+func (v *Validator) Validate(m types.Mutator) error {
+	withSchema, ok := m.(schema.MutatorWithSchema)
+	if !ok {
+		return nil
+	}
+	bindings := withSchema.SchemaBindings()
+	if len(bindings) == 0 {
+		return nil
+	}
+
+	schemas, err := v.schemasOf(bindings)
+	if err != nil {
+		// Schemas are best-effort; a failure to fetch them must not stop
+		// mutators from being ingested.
+		log.Error(err, "unable to fetch OpenAPI schemas, skipping validation", "mutator", m.ID())
+		return nil
+	}
+
+	value, err := m.Value()
+	if err != nil {
+		return err
+	}
+	nodes := m.Path().Nodes
+	for _, gvk := range bindings {
+		s, found := schemas[gvk]
+		if !found {
+			continue
+		}
+		if err := validatePath(s, nodes, value, withSchema.TerminalType()); err != nil {
+			return fmt.Errorf("%w: %v location %q: %v", ErrSchemaMismatch, gvk, m.Path().String(), err)
+		}
+	}
+	return nil
+}
+
+// schemasOf returns the schemas of the kinds of gvks which have one, fetching
+// them if they are stale or one of gvks is missing.
+func (v *Validator) schemasOf(gvks []runtimeschema.GroupVersionKind) (map[runtimeschema.GroupVersionKind]proto.Schema, error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	age := v.now().Sub(v.fetched)
+	refetch := v.schemas == nil || age > maxAge
+	if !refetch && age > minRefetchInterval {
+		for _, gvk := range gvks {
+			if _, found := v.schemas[gvk]; !found {
+				refetch = true
+				break
+			}
+		}
+	}
+	if refetch {
+		schemas, err := v.fetch()
+		if err != nil {
+			if v.schemas != nil {
+				// Stale schemas are better than none.
+				log.Error(err, "unable to refresh OpenAPI schemas")
+				return v.schemas, nil
+			}
+			return nil, err
+		}
+		v.schemas = schemas
+		v.fetched = v.now()
+	}
+	return v.schemas, nil
+}
+
+// fetchSchemas returns the schemas served by client, keyed by the kinds they
+// define.
+func fetchSchemas(client discovery.OpenAPISchemaInterface) (map[runtimeschema.GroupVersionKind]proto.Schema, error) {
+	doc, err := client.OpenAPISchema()
+	if err != nil {
+		return nil, err
+	}
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[runtimeschema.GroupVersionKind]proto.Schema)
+	for _, name := range models.ListModels() {
+		model := models.LookupModel(name)
+		if model == nil {
+			continue
+		}
+		for _, gvk := range groupVersionKinds(model) {
+			schemas[gvk] = model
+		}
+	}
+	return schemas, nil
+}
+
+// groupVersionKindExtension is the extension of a model naming the kinds it
+// defines.
+const groupVersionKindExtension = "x-kubernetes-group-version-kind"
+
+// groupVersionKinds returns the kinds s is the schema of.
+func groupVersionKinds(s proto.Schema) []runtimeschema.GroupVersionKind {
+	values, ok := s.GetExtensions()[groupVersionKindExtension].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var gvks []runtimeschema.GroupVersionKind
+	for _, value := range values {
+		var group, version, kind interface{}
+		switch m := value.(type) {
+		case map[interface{}]interface{}:
+			group, version, kind = m["group"], m["version"], m["kind"]
+		case map[string]interface{}:
+			group, version, kind = m["group"], m["version"], m["kind"]
+		default:
+			continue
+		}
+		gvk := runtimeschema.GroupVersionKind{}
+		gvk.Group, _ = group.(string)
+		gvk.Version, _ = version.(string)
+		gvk.Kind, _ = kind.(string)
+		if gvk.Version == "" || gvk.Kind == "" {
+			continue
+		}
+		gvks = append(gvks, gvk)
+	}
+	return gvks
+}
@@ -0,0 +1,301 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// ref is a reference to a named model.
+type ref struct {
+	proto.BaseSchema
+	name string
+	sub  proto.Schema
+}
+
+var _ proto.Reference = &ref{}
+
+func (r *ref) Accept(v proto.SchemaVisitor) { v.VisitReference(r) }
+func (r *ref) GetName() string              { return r.name }
+func (r *ref) Reference() string            { return r.name }
+func (r *ref) SubSchema() proto.Schema      { return r.sub }
+
+func primitive(t string) *proto.Primitive {
+	return &proto.Primitive{Type: t}
+}
+
+var (
+	podGVK    = runtimeschema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	widgetGVK = runtimeschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+	quantity = &ref{name: "io.k8s.apimachinery.pkg.api.resource.Quantity", sub: primitive(proto.String)}
+
+	container = &proto.Kind{Fields: map[string]proto.Schema{
+		"name":  primitive(proto.String),
+		"image": primitive(proto.String),
+		"resources": &proto.Kind{Fields: map[string]proto.Schema{
+			"limits": &proto.Map{SubType: quantity},
+		}},
+		"ports": &proto.Array{SubType: &proto.Kind{Fields: map[string]proto.Schema{
+			"containerPort": primitive(proto.Integer),
+		}}},
+	}}
+
+	pod = &proto.Kind{Fields: map[string]proto.Schema{
+		"spec": &ref{name: "io.k8s.api.core.v1.PodSpec", sub: &proto.Kind{Fields: map[string]proto.Schema{
+			"containers":   &proto.Array{SubType: &ref{name: "io.k8s.api.core.v1.Container", sub: container}},
+			"hostNetwork":  primitive(proto.Boolean),
+			"nodeSelector": &proto.Map{SubType: primitive(proto.String)},
+		}}},
+	}}
+
+	widget = &proto.Kind{Fields: map[string]proto.Schema{
+		"spec": &proto.Kind{
+			BaseSchema: proto.BaseSchema{Extensions: map[string]interface{}{preserveUnknownFieldsExtension: true}},
+			Fields:     map[string]proto.Schema{"size": primitive(proto.Integer)},
+		},
+	}}
+)
+
+func newTestValidator(fetches *int) *Validator {
+	now := time.Now()
+	return &Validator{
+		fetch: func() (map[runtimeschema.GroupVersionKind]proto.Schema, error) {
+			*fetches++
+			return map[runtimeschema.GroupVersionKind]proto.Schema{podGVK: pod, widgetGVK: widget}, nil
+		},
+		now: func() time.Time { return now },
+	}
+}
+
+func newAssign(t *testing.T, gvk runtimeschema.GroupVersionKind, location string, value interface{}) types.Mutator {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{"value": value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &mutationsv1alpha1.Assign{}
+	a.SetName("assign")
+	a.Spec.ApplyTo = []match.ApplyTo{{Groups: []string{gvk.Group}, Versions: []string{gvk.Version}, Kinds: []string{gvk.Kind}}}
+	a.Spec.Location = location
+	a.Spec.Parameters.Assign = runtime.RawExtension{Raw: raw}
+	m, err := mutators.MutatorForAssign(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func newModifySet(t *testing.T, location string, values ...interface{}) types.Mutator {
+	t.Helper()
+	ms := &mutationsv1alpha1.ModifySet{}
+	ms.SetName("modifyset")
+	ms.Spec.ApplyTo = []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}}
+	ms.Spec.Location = location
+	ms.Spec.Parameters.Values.FromList = values
+	m, err := mutators.MutatorForModifySet(ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestValidator_Validate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		mutator func(t *testing.T) types.Mutator
+		wantErr bool
+	}{
+		{
+			name: "scalar field",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.hostNetwork", false)
+			},
+		},
+		{
+			name: "misspelled field",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.hostNetwrok", false)
+			},
+			wantErr: true,
+		},
+		{
+			name: "value of wrong type",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.hostNetwork", "false")
+			},
+			wantErr: true,
+		},
+		{
+			name: "field of list element",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.containers[name: *].image", "nginx")
+			},
+		},
+		{
+			name: "unknown list key",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.containers[id: main].image", "nginx")
+			},
+			wantErr: true,
+		},
+		{
+			name: "list element",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.containers[name: sidecar]", map[string]interface{}{
+					"name":  "sidecar",
+					"ports": []interface{}{map[string]interface{}{"containerPort": 80}},
+				})
+			},
+		},
+		{
+			name: "list element with unknown field",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.containers[name: sidecar]", map[string]interface{}{
+					"name":     "sidecar",
+					"ports":    []interface{}{map[string]interface{}{"containerPort": 80}},
+					"imageTag": "latest",
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "nested value of wrong type",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.containers[name: sidecar].ports", []interface{}{
+					map[string]interface{}{"containerPort": "http"},
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "map entry",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.nodeSelector.disktype", "ssd")
+			},
+		},
+		{
+			name: "quantity as number",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.containers[name: *].resources.limits.cpu", 1)
+			},
+		},
+		{
+			name: "field below scalar",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.hostNetwork.enabled", true)
+			},
+			wantErr: true,
+		},
+		{
+			name: "object traversed as list",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, podGVK, "spec.nodeSelector[name: foo].bar", "baz")
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown field preserved",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, widgetGVK, "spec.color", "blue")
+			},
+		},
+		{
+			name: "kind without schema",
+			mutator: func(t *testing.T) types.Mutator {
+				return newAssign(t, runtimeschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"}, "spec.anything", 1)
+			},
+		},
+		{
+			name: "set elements",
+			mutator: func(t *testing.T) types.Mutator {
+				return newModifySet(t, "spec.containers[name: *].ports", map[string]interface{}{"containerPort": int64(443)})
+			},
+		},
+		{
+			name: "set elements of wrong type",
+			mutator: func(t *testing.T) types.Mutator {
+				return newModifySet(t, "spec.containers[name: *].ports", "443")
+			},
+			wantErr: true,
+		},
+		{
+			name: "set on non-list",
+			mutator: func(t *testing.T) types.Mutator {
+				return newModifySet(t, "spec.hostNetwork", true)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fetches := 0
+			v := newTestValidator(&fetches)
+			err := v.Validate(tc.mutator(t))
+			if tc.wantErr && !errors.Is(err, ErrSchemaMismatch) {
+				t.Errorf("got error %v, want %v", err, ErrSchemaMismatch)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("got error %v, want none", err)
+			}
+		})
+	}
+}
+
+func TestValidator_Refetch(t *testing.T) {
+	fetches := 0
+	v := newTestValidator(&fetches)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	known := newAssign(t, podGVK, "spec.hostNetwork", true)
+	unknown := newAssign(t, runtimeschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"}, "spec.size", 1)
+
+	steps := []struct {
+		name        string
+		after       time.Duration
+		mutator     types.Mutator
+		wantFetches int
+	}{
+		{name: "first validation", mutator: known, wantFetches: 1},
+		{name: "fresh schemas", after: time.Second, mutator: known, wantFetches: 1},
+		{name: "missing kind soon after fetch", after: time.Second, mutator: unknown, wantFetches: 1},
+		{name: "missing kind", after: minRefetchInterval, mutator: unknown, wantFetches: 2},
+		{name: "stale schemas", after: maxAge + time.Second, mutator: known, wantFetches: 3},
+	}
+	for _, step := range steps {
+		now = now.Add(step.after)
+		if err := v.Validate(step.mutator); err != nil {
+			t.Fatalf("%s: got error %v", step.name, err)
+		}
+		if fetches != step.wantFetches {
+			t.Errorf("%s: got %d fetches, want %d", step.name, fetches, step.wantFetches)
+		}
+	}
+}
+
+func TestGroupVersionKinds(t *testing.T) {
+	s := &proto.Kind{BaseSchema: proto.BaseSchema{Extensions: map[string]interface{}{
+		groupVersionKindExtension: []interface{}{
+			map[interface{}]interface{}{"group": "", "version": "v1", "kind": "Pod"},
+			map[string]interface{}{"group": "example.com", "version": "v1", "kind": "Widget"},
+			map[string]interface{}{"group": "example.com"},
+		},
+	}}}
+
+	got := groupVersionKinds(s)
+	want := []runtimeschema.GroupVersionKind{podGVK, widgetGVK}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package mutation
+
+import (
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// DisableMutatorsAnnotation lists the mutators which are not applied to
+	// the annotated object, separated by commas. Each is the name of a
+	// mutator, or its kind and name such as `Assign/set-pull-policy`.
+	DisableMutatorsAnnotation = "gatekeeper.sh/disable-mutators"
+	// DisabledMutationsAnnotation records the mutators which matched an
+	// object but were disabled by DisableMutatorsAnnotation, when mutation
+	// annotations are enabled.
+	DisabledMutationsAnnotation = "gatekeeper.sh/disabled-mutations"
+)
+
+var optOutNamespaces = util.NewFlagSet()
+
+func init() {
+	flag.Var(optOutNamespaces, "mutation-opt-out-namespace", "(alpha) Objects in the specified namespace may disable mutators with the "+DisableMutatorsAnnotation+" annotation. To allow multiple namespaces, this flag can be declared more than once.")
+}
+
+// disabledMutators is the set of mutators an object opted out of.
+type disabledMutators map[string]bool
+
+// disabledMutatorsFor returns the mutators obj opted out of, or nil if it did
+// not or is not in a namespace allowed to.
+func disabledMutatorsFor(obj *unstructured.Unstructured) disabledMutators {
+	value, ok := obj.GetAnnotations()[DisableMutatorsAnnotation]
+	if !ok {
+		return nil
+	}
+	if !optOutNamespaces[obj.GetNamespace()] || obj.GetNamespace() == "" {
+		log.Info("ignoring "+DisableMutatorsAnnotation+" outside of allowed namespaces",
+			"kind", obj.GetKind(), "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+
+	disabled := make(disabledMutators)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabled[name] = true
+		}
+	}
+	return disabled
+}
+
+// has returns whether the mutator with id is disabled.
+func (d disabledMutators) has(id types.ID) bool {
+	return d[id.Name] || d[id.Kind+"/"+id.Name]
+}
+
+// annotateDisabled records the disabled mutators which matched obj, returning
+// whether obj was changed.
+func annotateDisabled(obj *unstructured.Unstructured, matched map[types.ID]types.Mutator) bool {
+	var names []string
+	for _, m := range matched {
+		names = append(names, m.String())
+	}
+	sort.Strings(names)
+	value := strings.Join(names, ", ")
+
+	annotations := obj.GetAnnotations()
+	if current, ok := annotations[DisabledMutationsAnnotation]; ok && current == value {
+		return false
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[DisabledMutationsAnnotation] = value
+	obj.SetAnnotations(annotations)
+	return true
+}
@@ -0,0 +1,101 @@
+package mutation
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDisableMutators(t *testing.T) {
+	optOutNamespaces["incident"] = true
+	annotationsEnabled := *MutationAnnotationsEnabled
+	*MutationAnnotationsEnabled = true
+	defer func() {
+		delete(optOutNamespaces, "incident")
+		*MutationAnnotationsEnabled = annotationsEnabled
+	}()
+
+	tcs := []struct {
+		name          string
+		namespace     string
+		annotation    string
+		wantLabels    map[string]string
+		wantMutated   bool
+		wantRecorded  bool
+		wantConverged bool
+	}{
+		{
+			name:        "no annotation",
+			namespace:   "incident",
+			wantLabels:  map[string]string{"a": "a", "b": "b"},
+			wantMutated: true,
+		},
+		{
+			name:         "mutator disabled by name",
+			namespace:    "incident",
+			annotation:   "add-a",
+			wantLabels:   map[string]string{"b": "b"},
+			wantMutated:  true,
+			wantRecorded: true,
+		},
+		{
+			name:         "mutators disabled by kind and name",
+			namespace:    "incident",
+			annotation:   "Assign/add-a, Assign/add-b",
+			wantMutated:  true,
+			wantRecorded: true,
+		},
+		{
+			name:        "other kind is not disabled",
+			namespace:   "incident",
+			annotation:  "AssignMetadata/add-a",
+			wantLabels:  map[string]string{"a": "a", "b": "b"},
+			wantMutated: true,
+		},
+		{
+			name:        "namespace not allowed to opt out",
+			namespace:   "default",
+			annotation:  "add-a",
+			wantLabels:  map[string]string{"a": "a", "b": "b"},
+			wantMutated: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSystem(SystemOpts{})
+			for _, m := range []*fakeMutator{
+				{MID: types.ID{Kind: "Assign", Name: "add-a"}, Labels: map[string]string{"a": "a"}},
+				{MID: types.ID{Kind: "Assign", Name: "add-b"}, Labels: map[string]string{"b": "b"}},
+			} {
+				if err := s.Upsert(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("Pod")
+			obj.SetName("pod")
+			obj.SetNamespace(tc.namespace)
+			if tc.annotation != "" {
+				obj.SetAnnotations(map[string]string{DisableMutatorsAnnotation: tc.annotation})
+			}
+
+			mutated, err := s.Mutate(obj, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mutated != tc.wantMutated {
+				t.Errorf("got mutated %v, want %v", mutated, tc.wantMutated)
+			}
+			if diff := cmp.Diff(tc.wantLabels, obj.GetLabels()); diff != "" {
+				t.Error(diff)
+			}
+			if _, recorded := obj.GetAnnotations()[DisabledMutationsAnnotation]; recorded != tc.wantRecorded {
+				t.Errorf("got disabled mutations recorded %v, want %v", recorded, tc.wantRecorded)
+			}
+		})
+	}
+}
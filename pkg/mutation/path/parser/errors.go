@@ -41,3 +41,24 @@ func (e invalidIntegerError) Is(target error) bool {
 	_, ok := target.(invalidIntegerError)
 	return ok
 }
+
+// Error is an error parsing a path, at a position of the input.
+type Error struct {
+	Inner error
+	// Position is the offset in bytes from the start of the input of the
+	// token which could not be parsed.
+	Position int
+}
+
+func (e Error) Error() string {
+	var innerMsg string
+	if e.Inner != nil {
+		innerMsg = e.Inner.Error()
+	}
+	return fmt.Sprintf("error at position %d: %s", e.Position, innerMsg)
+}
+
+// Unwrap allows errors.Is() to inspect the underlying error.
+func (e Error) Unwrap() error {
+	return e.Inner
+}
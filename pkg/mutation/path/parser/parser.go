@@ -26,7 +26,10 @@ type parser struct {
 	scanner   *token.Scanner
 	curToken  token.Token
 	peekToken token.Token
-	err       error
+	// curPos and peekPos are the positions in input of curToken and peekToken.
+	curPos  int
+	peekPos int
+	err     error
 }
 
 // Parse parses the provided input and returns an abstract representation if successful.
@@ -42,14 +45,18 @@ func newParser(input string) *parser {
 		scanner: s,
 	}
 	p.curToken = p.scanner.Next()
+	p.curPos = p.scanner.Start()
 	p.peekToken = p.scanner.Next()
+	p.peekPos = p.scanner.Start()
 	return p
 }
 
 // next advances to the next token in the stream.
 func (p *parser) next() {
 	p.curToken = p.peekToken
+	p.curPos = p.peekPos
 	p.peekToken = p.scanner.Next()
+	p.peekPos = p.scanner.Start()
 }
 
 // expect returns whether the next token matches our expectation,
@@ -89,7 +96,7 @@ func (p *parser) Parse() (Path, error) {
 		case p.expect(token.SEPARATOR):
 			if p.expectPeek(token.EOF) {
 				// block trailing separators
-				p.setError(ErrTrailingSeparator)
+				p.setError(p.curPos, ErrTrailingSeparator)
 				return Path{}, p.err
 			}
 			// Skip past the separator
@@ -97,13 +104,13 @@ func (p *parser) Parse() (Path, error) {
 		case p.expect(token.EOF):
 			// Allowed. Loop will exit.
 		default:
-			p.setError(fmt.Errorf("%w: expected '.' or eof, got: %s", ErrUnexpectedToken, p.peekToken.String()))
+			p.setError(p.peekPos, fmt.Errorf("%w: expected '.' or eof, got: %s", ErrUnexpectedToken, p.peekToken.String()))
 			return Path{}, p.err
 		}
 	}
 
 	if p.curToken.Type != token.EOF {
-		p.setError(fmt.Errorf("%w: expected field name or eof, got: %s", ErrUnexpectedToken, p.curToken.String()))
+		p.setError(p.curPos, fmt.Errorf("%w: expected field name or eof, got: %s", ErrUnexpectedToken, p.curToken.String()))
 	}
 	if p.err != nil {
 		return Path{}, p.err
@@ -119,14 +126,14 @@ func (p *parser) parseList() Node {
 
 	// keyField is required
 	if !p.expect(token.IDENT) {
-		p.setError(fmt.Errorf("%w: expected keyField in listSpec, got: %s", ErrUnexpectedToken, p.peekToken.String()))
+		p.setError(p.peekPos, fmt.Errorf("%w: expected keyField in listSpec, got: %s", ErrUnexpectedToken, p.peekToken.String()))
 		return nil
 	}
 
 	out.KeyField = p.curToken.Literal
 
 	if !p.expect(token.COLON) {
-		p.setError(fmt.Errorf("%w: expected ':' following keyField %s, got: %s", ErrUnexpectedToken, out.KeyField, p.peekToken.String()))
+		p.setError(p.peekPos, fmt.Errorf("%w: expected ':' following keyField %s, got: %s", ErrUnexpectedToken, out.KeyField, p.peekToken.String()))
 		return nil
 	}
 
@@ -138,17 +145,17 @@ func (p *parser) parseList() Node {
 	case p.expect(token.INT):
 		val, err := parseInt64(p.curToken.Literal)
 		if err != nil {
-			p.setError(fmt.Errorf("%w: parsing key value for key: %s", err, out.KeyField))
+			p.setError(p.curPos, fmt.Errorf("%w: parsing key value for key: %s", err, out.KeyField))
 			return nil
 		}
 		out.KeyValue = val
 	default:
-		p.setError(fmt.Errorf("%w: expected key value or glob in listSpec, got: %s", ErrUnexpectedToken, p.peekToken.String()))
+		p.setError(p.peekPos, fmt.Errorf("%w: expected key value or glob in listSpec, got: %s", ErrUnexpectedToken, p.peekToken.String()))
 		return nil
 	}
 
 	if !p.expect(token.RBRACKET) {
-		p.setError(fmt.Errorf("%w: expected ']' following listSpec, got: %s", ErrUnexpectedToken, p.peekToken.String()))
+		p.setError(p.peekPos, fmt.Errorf("%w: expected ']' following listSpec, got: %s", ErrUnexpectedToken, p.peekToken.String()))
 		return nil
 	}
 	return out
@@ -159,12 +166,13 @@ func (p *parser) parseObject() Node {
 	return out
 }
 
-func (p *parser) setError(err error) {
+// setError records err, which occurred at position pos of the input.
+func (p *parser) setError(pos int, err error) {
 	// Support only the first error for now
 	if p.err != nil {
 		return
 	}
-	p.err = err
+	p.err = Error{Inner: err, Position: pos}
 }
 
 // parseInt64 will return the int64 representation of the decimal encoded in the string s.
@@ -447,3 +447,31 @@ func TestDeepCopy(t *testing.T) {
 		})
 	}
 }
+
+func TestParserErrorPosition(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{input: `.spec`, want: 0},
+		{input: `spec.`, want: 4},
+		{input: `spec.containers[name foo]`, want: 21},
+		{input: `spec.containers[name: foo`, want: 25},
+		{input: `spec.containers[: foo]`, want: 16},
+		{input: `spec.ports[port: 99999999999999999999]`, want: 17},
+		{input: `spec  containers`, want: 6},
+		{input: `spec.containers[name: foo]bar`, want: 26},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := Parse(tc.input)
+			var perr Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("got error %v, want an Error", err)
+			}
+			if perr.Position != tc.want {
+				t.Errorf("got error %v, want it at position %d", err, tc.want)
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ type Scanner struct {
 	input   string
 	pos     int // Current position
 	readPos int // Next position to read
+	start   int // Position of the last token returned by Next
 	ch      rune
 	err     error // Last error if any
 }
@@ -48,6 +49,7 @@ func (s *Scanner) Next() Token {
 	var err error
 	tok := Token{Type: ERROR}
 	s.skipWhitespace()
+	s.start = s.pos
 
 	switch {
 	// A match on these first set of cases leaves s.ch positioned at the next character to process.
@@ -92,6 +94,12 @@ func (s *Scanner) Next() Token {
 	return tok
 }
 
+// Start returns the position in the input of the first character of the last
+// token returned by Next, or the length of the input for EOF.
+func (s *Scanner) Start() int {
+	return s.start
+}
+
 // read consumes the next rune and advances.
 func (s *Scanner) read() rune {
 	if s.readPos >= len(s.input) {
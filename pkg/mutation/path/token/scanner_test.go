@@ -370,3 +370,18 @@ func TestScanner_EOF(t *testing.T) {
 		}
 	}
 }
+
+func TestScanner_Start(t *testing.T) {
+	s := NewScanner(` foo[ "a b": 12].bar `)
+	want := []int{1, 4, 6, 11, 13, 15, 16, 17, 21}
+	var got []int
+	for tok := s.Next(); ; tok = s.Next() {
+		got = append(got, s.Start())
+		if tok.Type == EOF {
+			break
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
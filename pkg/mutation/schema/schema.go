@@ -186,3 +186,17 @@ func (db *DB) HasConflicts(id types.ID) bool {
 	defer db.mutex.RUnlock()
 	return db.conflicts[id]
 }
+
+// NumSchemas returns the number of GVKs with an implied schema.
+func (db *DB) NumSchemas() int {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	return len(db.schemas)
+}
+
+// NumConflicts returns the number of Mutators with conflicting schemas.
+func (db *DB) NumConflicts() int {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	return len(db.conflicts)
+}
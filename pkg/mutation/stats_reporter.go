@@ -2,15 +2,52 @@ package mutation
 
 import (
 	"context"
+	"time"
 
 	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/types"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
 )
 
 const (
-	mutationSystemIterationsMetricName = "mutation_system_iterations"
+	mutationSystemIterationsMetricName  = "mutation_system_iterations"
+	mutatorEvaluationsMetricName        = "mutator_evaluations"
+	mutatorEvaluationDurationMetricName = "mutator_evaluation_duration_seconds"
+	mutationSystemCacheSizeMetricName   = "mutation_system_cache_size"
+)
+
+// MutatorOutcome is the result of evaluating a single Mutator against an object.
+type MutatorOutcome string
+
+const (
+	// MutatorApplied denotes a Mutator which matched and changed the object.
+	MutatorApplied MutatorOutcome = "applied"
+	// MutatorUnchanged denotes a Mutator which matched but left the object unchanged.
+	MutatorUnchanged MutatorOutcome = "unchanged"
+	// MutatorSkipped denotes a Mutator which did not match the object.
+	MutatorSkipped MutatorOutcome = "skipped"
+	// MutatorConflicted denotes a Mutator which was not applied because its schema
+	// conflicts with another Mutator.
+	MutatorConflicted MutatorOutcome = "conflicted"
+	// MutatorErrored denotes a Mutator which failed to mutate the object.
+	MutatorErrored MutatorOutcome = "errored"
+	// MutatorDisabled denotes a Mutator which matched the object but was
+	// disabled by an annotation on it.
+	MutatorDisabled MutatorOutcome = "disabled"
+)
+
+// SystemCache identifies one of the caches kept by the mutation System.
+type SystemCache string
+
+const (
+	// MutatorsCache is the set of Mutators in the System.
+	MutatorsCache SystemCache = "mutators"
+	// SchemasCache is the set of per-GVK implied schemas.
+	SchemasCache SystemCache = "schemas"
+	// ConflictsCache is the set of Mutators with conflicting schemas.
+	ConflictsCache SystemCache = "conflicts"
 )
 
 // SystemConvergenceStatus defines the outcomes of the attempted mutation of an object by the
@@ -19,6 +56,9 @@ type SystemConvergenceStatus string
 
 var (
 	systemConvergenceKey = tag.MustNewKey("success")
+	mutatorKindKey       = tag.MustNewKey("mutator_kind")
+	mutatorOutcomeKey    = tag.MustNewKey("outcome")
+	cacheKey             = tag.MustNewKey("cache")
 
 	// SystemConvergenceTrue denotes a successfully converged mutation system request.
 	SystemConvergenceTrue SystemConvergenceStatus = "true"
@@ -29,6 +69,21 @@ var (
 		mutationSystemIterationsMetricName,
 		"The distribution of Mutation System iterations before convergence",
 		stats.UnitDimensionless)
+
+	mutatorEvaluationsM = stats.Int64(
+		mutatorEvaluationsMetricName,
+		"Total number of Mutator evaluations",
+		stats.UnitDimensionless)
+
+	mutatorEvaluationDurationM = stats.Float64(
+		mutatorEvaluationDurationMetricName,
+		"The distribution of time spent evaluating Mutators of a kind while mutating an object",
+		stats.UnitSeconds)
+
+	cacheSizeM = stats.Int64(
+		mutationSystemCacheSizeMetricName,
+		"The current number of entries in the caches of the mutation System",
+		stats.UnitDimensionless)
 )
 
 func init() {
@@ -40,6 +95,27 @@ func init() {
 			Aggregation: view.Distribution(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 20, 50, 100, 200, 500),
 			TagKeys:     []tag.Key{systemConvergenceKey},
 		},
+		{
+			Name:        mutatorEvaluationsMetricName,
+			Description: mutatorEvaluationsM.Description(),
+			Measure:     mutatorEvaluationsM,
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{mutatorKindKey, mutatorOutcomeKey},
+		},
+		{
+			Name:        mutatorEvaluationDurationMetricName,
+			Description: mutatorEvaluationDurationM.Description(),
+			Measure:     mutatorEvaluationDurationM,
+			Aggregation: view.Distribution(0.0001, 0.0002, 0.0005, 0.001, 0.002, 0.005, 0.01, 0.02, 0.05, 0.1, 0.2, 0.5, 1),
+			TagKeys:     []tag.Key{mutatorKindKey},
+		},
+		{
+			Name:        mutationSystemCacheSizeMetricName,
+			Description: cacheSizeM.Description(),
+			Measure:     cacheSizeM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{cacheKey},
+		},
 	}
 
 	if err := view.Register(views...); err != nil {
@@ -50,6 +126,9 @@ func init() {
 // StatsReporter reports mutator-related metrics.
 type StatsReporter interface {
 	ReportIterationConvergence(scs SystemConvergenceStatus, iterations int) error
+	ReportMutatorEvaluations(kind string, outcome MutatorOutcome, n int) error
+	ReportMutatorEvaluationDuration(kind string, d time.Duration) error
+	ReportCacheSize(cache SystemCache, n int) error
 }
 
 // reporter implements StatsReporter interface.
@@ -74,3 +153,90 @@ func (r *reporter) ReportIterationConvergence(scs SystemConvergenceStatus, itera
 
 	return metrics.Record(ctx, systemIterationsM.M(int64(iterations)))
 }
+
+// ReportMutatorEvaluations reports that n Mutators of the given kind were evaluated
+// against an object with the given outcome.
+func (r *reporter) ReportMutatorEvaluations(kind string, outcome MutatorOutcome, n int) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(mutatorKindKey, kind),
+		tag.Insert(mutatorOutcomeKey, string(outcome)),
+	)
+	if err != nil {
+		return err
+	}
+
+	return metrics.Record(ctx, mutatorEvaluationsM.M(int64(n)))
+}
+
+// ReportMutatorEvaluationDuration reports the total time spent evaluating Mutators
+// of the given kind while mutating a single object.
+func (r *reporter) ReportMutatorEvaluationDuration(kind string, d time.Duration) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(mutatorKindKey, kind),
+	)
+	if err != nil {
+		return err
+	}
+
+	return metrics.Record(ctx, mutatorEvaluationDurationM.M(d.Seconds()))
+}
+
+// ReportCacheSize reports the number of entries in one of the System's caches.
+func (r *reporter) ReportCacheSize(cache SystemCache, n int) error {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(cacheKey, string(cache)),
+	)
+	if err != nil {
+		return err
+	}
+
+	return metrics.Record(ctx, cacheSizeM.M(int64(n)))
+}
+
+// mutatorEvaluations tallies the Mutator evaluations made while mutating a single
+// object, so they can be reported once rather than per evaluation. A nil
+// *mutatorEvaluations discards everything recorded.
+type mutatorEvaluations struct {
+	outcomes  map[string]map[MutatorOutcome]int
+	durations map[string]time.Duration
+}
+
+func newMutatorEvaluations() *mutatorEvaluations {
+	return &mutatorEvaluations{
+		outcomes:  make(map[string]map[MutatorOutcome]int),
+		durations: make(map[string]time.Duration),
+	}
+}
+
+func (e *mutatorEvaluations) record(m types.Mutator, outcome MutatorOutcome, d time.Duration) {
+	if e == nil {
+		return
+	}
+	kind := m.ID().Kind
+	if e.outcomes[kind] == nil {
+		e.outcomes[kind] = make(map[MutatorOutcome]int)
+	}
+	e.outcomes[kind][outcome]++
+	e.durations[kind] += d
+}
+
+func (e *mutatorEvaluations) report(r StatsReporter) {
+	if e == nil {
+		return
+	}
+	for kind, outcomes := range e.outcomes {
+		for outcome, n := range outcomes {
+			if err := r.ReportMutatorEvaluations(kind, outcome, n); err != nil {
+				log.Error(err, "failed to report mutator evaluations")
+			}
+		}
+	}
+	for kind, d := range e.durations {
+		if err := r.ReportMutatorEvaluationDuration(kind, d); err != nil {
+			log.Error(err, "failed to report mutator evaluation duration")
+		}
+	}
+}
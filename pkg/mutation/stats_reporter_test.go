@@ -3,6 +3,7 @@ package mutation
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"go.opencensus.io/stats/view"
 )
@@ -51,6 +52,89 @@ func TestReportIterationConvergence(t *testing.T) {
 	}
 }
 
+func TestReportMutatorEvaluations(t *testing.T) {
+	r := NewStatsReporter()
+
+	if err := r.ReportMutatorEvaluations("Assign", MutatorApplied, 2); err != nil {
+		t.Errorf("ReportMutatorEvaluations error: %v", err)
+	}
+	if err := r.ReportMutatorEvaluations("Assign", MutatorApplied, 3); err != nil {
+		t.Errorf("ReportMutatorEvaluations error: %v", err)
+	}
+	if err := r.ReportMutatorEvaluations("Assign", MutatorSkipped, 1); err != nil {
+		t.Errorf("ReportMutatorEvaluations error: %v", err)
+	}
+
+	rows, err := view.RetrieveData(mutatorEvaluationsMetricName)
+	if err != nil {
+		t.Errorf("Error when retrieving data: %v from %v", err, mutatorEvaluationsMetricName)
+	}
+	if len(rows) != 2 {
+		t.Errorf("got '%v' view length %v, want %v", mutatorEvaluationsMetricName, len(rows), 2)
+	}
+	for _, row := range rows {
+		if !hasTag(row, mutatorOutcomeKey.Name(), string(MutatorApplied)) {
+			continue
+		}
+		sum, ok := row.Data.(*view.SumData)
+		if !ok {
+			t.Fatalf("Data is not of type *view.SumData")
+		}
+		if sum.Value != 5 {
+			t.Errorf("got applied sum %v, want %v", sum.Value, 5)
+		}
+	}
+}
+
+func TestReportMutatorEvaluationDuration(t *testing.T) {
+	r := NewStatsReporter()
+
+	if err := r.ReportMutatorEvaluationDuration("ModifySet", 2*time.Millisecond); err != nil {
+		t.Errorf("ReportMutatorEvaluationDuration error: %v", err)
+	}
+
+	rows, err := view.RetrieveData(mutatorEvaluationDurationMetricName)
+	if err != nil {
+		t.Errorf("Error when retrieving data: %v from %v", err, mutatorEvaluationDurationMetricName)
+	}
+	if len(rows) != 1 || !hasTag(rows[0], mutatorKindKey.Name(), "ModifySet") {
+		t.Fatalf("got rows %v, want one row for ModifySet", rows)
+	}
+	distData, ok := rows[0].Data.(*view.DistributionData)
+	if !ok {
+		t.Fatalf("Data is not of type *view.DistributionData")
+	}
+	if distData.Count != 1 || distData.Max != 0.002 {
+		t.Errorf("got count %v max %v, want count 1 max 0.002", distData.Count, distData.Max)
+	}
+}
+
+func TestReportCacheSize(t *testing.T) {
+	r := NewStatsReporter()
+
+	if err := r.ReportCacheSize(MutatorsCache, 3); err != nil {
+		t.Errorf("ReportCacheSize error: %v", err)
+	}
+	if err := r.ReportCacheSize(MutatorsCache, 7); err != nil {
+		t.Errorf("ReportCacheSize error: %v", err)
+	}
+
+	rows, err := view.RetrieveData(mutationSystemCacheSizeMetricName)
+	if err != nil {
+		t.Errorf("Error when retrieving data: %v from %v", err, mutationSystemCacheSizeMetricName)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got '%v' view length %v, want %v", mutationSystemCacheSizeMetricName, len(rows), 1)
+	}
+	lastValue, ok := rows[0].Data.(*view.LastValueData)
+	if !ok {
+		t.Fatalf("Data is not of type *view.LastValueData")
+	}
+	if lastValue.Value != 7 {
+		t.Errorf("got cache size %v, want %v", lastValue.Value, 7)
+	}
+}
+
 func verifyDistributionRow(rows []*view.Row, tag SystemConvergenceStatus, count, min, max int) error {
 	for _, r := range rows {
 		if !hasTag(r, systemConvergenceKey.Name(), string(tag)) {
@@ -2,11 +2,13 @@ package mutation
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/schema"
@@ -17,12 +19,28 @@ import (
 )
 
 // System keeps the list of mutators and provides an interface to apply mutations.
+//
+// Upsert and Remove change the mutators under a lock, and then replace the
+// snapshot of the mutators which Mutate applies. Mutate reads the current
+// snapshot without locking, so admission requests never wait for mutators to
+// be changed and always apply a consistent set of mutators.
 type System struct {
 	schemaDB        schema.DB
 	orderedMutators []types.Mutator
 	mutatorsMap     map[types.ID]types.Mutator
 	mux             sync.RWMutex
 	reporter        StatsReporter
+
+	// current holds the *snapshot applied by Mutate.
+	current atomic.Value
+}
+
+// snapshot is an immutable set of mutators, in the order they are applied.
+type snapshot struct {
+	mutators []types.Mutator
+	// conflicts holds the IDs of the mutators whose schema conflicts with
+	// another mutator, which are not applied.
+	conflicts map[types.ID]bool
 }
 
 // SystemOpts allows for optional dependencies to be passed into the mutation System.
@@ -32,12 +50,35 @@ type SystemOpts struct {
 
 // NewSystem initializes an empty mutation system.
 func NewSystem(options SystemOpts) *System {
-	return &System{
+	s := &System{
 		schemaDB:        *schema.New(),
 		orderedMutators: make([]types.Mutator, 0),
 		mutatorsMap:     make(map[types.ID]types.Mutator),
 		reporter:        options.Reporter,
 	}
+	s.current.Store(&snapshot{})
+	return s
+}
+
+// swap replaces the snapshot applied by Mutate with one of the current
+// mutators. Mutators are not modified once added, so they are shared rather
+// than copied. Must be called while holding s.mux for writing.
+func (s *System) swap() {
+	snap := &snapshot{
+		mutators:  make([]types.Mutator, len(s.orderedMutators)),
+		conflicts: make(map[types.ID]bool),
+	}
+	copy(snap.mutators, s.orderedMutators)
+	for _, m := range snap.mutators {
+		if s.schemaDB.HasConflicts(m.ID()) {
+			snap.conflicts[m.ID()] = true
+		}
+	}
+	s.current.Store(snap)
+}
+
+func (s *System) snapshot() *snapshot {
+	return s.current.Load().(*snapshot)
 }
 
 // Upsert updates or insert the given object, and returns
@@ -45,11 +86,14 @@ func NewSystem(options SystemOpts) *System {
 func (s *System) Upsert(m types.Mutator) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
+	defer s.reportCacheSizes()
 
 	current, ok := s.mutatorsMap[m.ID()]
 	if ok && !m.HasDiff(current) {
 		return nil
 	}
+	// Conflicts of other mutators may change even if m is not added.
+	defer s.swap()
 
 	toAdd := m.DeepCopy()
 
@@ -89,19 +133,29 @@ func (s *System) Upsert(m types.Mutator) error {
 // Mutate applies the mutation in place to the given object. Returns
 // true if a mutation was performed.
 func (s *System) Mutate(obj *unstructured.Unstructured, ns *corev1.Namespace) (bool, error) {
-	s.mux.RLock()
-	defer s.mux.RUnlock()
+	snap := s.snapshot()
 	mutationUUID := uuid.New()
-	original := obj.DeepCopy()
-	maxIterations := len(s.orderedMutators) + 1
+	// Mutators replace rather than modify what they change, so the original
+	// object and that from each iteration are kept without copying them.
+	original := &unstructured.Unstructured{Object: obj.Object}
+	maxIterations := len(snap.mutators) + 1
 
 	var allAppliedMutations [][]types.Mutator
 	if *MutationLoggingEnabled || *MutationAnnotationsEnabled {
 		allAppliedMutations = [][]types.Mutator{}
 	}
 
+	// Mutators the object opted out of are skipped. Those which matched are
+	// recorded in the mutation annotations.
+	disabled := disabledMutatorsFor(obj)
+	matchedDisabled := make(map[types.ID]types.Mutator)
+
 	iterations := 0
 	convergence := SystemConvergenceFalse
+	var evaluations *mutatorEvaluations
+	if s.reporter != nil {
+		evaluations = newMutatorEvaluations()
+	}
 	defer func() {
 		if s.reporter == nil {
 			return
@@ -111,46 +165,75 @@ func (s *System) Mutate(obj *unstructured.Unstructured, ns *corev1.Namespace) (b
 		if err != nil {
 			log.Error(err, "failed to report mutator ingestion request")
 		}
+		evaluations.report(s.reporter)
 	}()
 
 	for i := 0; i < maxIterations; i++ {
 		iterations++
 		var appliedMutations []types.Mutator
-		old := obj.DeepCopy()
+		old := obj.Object
 
-		for _, m := range s.orderedMutators {
-			if s.schemaDB.HasConflicts(m.ID()) {
+		for _, m := range snap.mutators {
+			if snap.conflicts[m.ID()] {
 				// Don't try to apply Mutators which have conflicts.
+				evaluations.record(m, MutatorConflicted, 0)
 				continue
 			}
 
-			if m.Matches(obj, ns) {
-				mutated, err := m.Mutate(obj)
-				if mutated {
-					appliedMutations = append(appliedMutations, m)
-				}
-				if err != nil {
-					return false, errors.Wrapf(err, "mutation %s for mutator %v failed for %s %s %s %s",
-						mutationUUID,
-						m.ID(),
-						obj.GroupVersionKind().Group,
-						obj.GroupVersionKind().Kind,
-						obj.GetNamespace(),
-						obj.GetName())
-				}
+			start := time.Now()
+			if !m.Matches(obj, ns) {
+				evaluations.record(m, MutatorSkipped, time.Since(start))
+				continue
+			}
+			if disabled.has(m.ID()) {
+				evaluations.record(m, MutatorDisabled, time.Since(start))
+				matchedDisabled[m.ID()] = m
+				continue
+			}
+
+			var mutated bool
+			var err error
+			if nm, ok := m.(types.NamespaceMutator); ok {
+				mutated, err = nm.MutateInNamespace(obj, ns)
+			} else {
+				mutated, err = m.Mutate(obj)
+			}
+			if mutated {
+				appliedMutations = append(appliedMutations, m)
+			}
+			switch {
+			case err != nil:
+				evaluations.record(m, MutatorErrored, time.Since(start))
+			case mutated:
+				evaluations.record(m, MutatorApplied, time.Since(start))
+			default:
+				evaluations.record(m, MutatorUnchanged, time.Since(start))
+			}
+			if err != nil {
+				return false, errors.Wrapf(err, "mutation %s for mutator %v failed for %s %s %s %s",
+					mutationUUID,
+					m.ID(),
+					obj.GroupVersionKind().Group,
+					obj.GroupVersionKind().Kind,
+					obj.GetNamespace(),
+					obj.GetName())
 			}
 		}
 
 		if len(appliedMutations) == 0 {
 			// If no mutations were applied, we can safely assume the object is
 			// identical to before.
-			return i > 0, nil
+			if i == 0 {
+				return recordDisabled(obj, matchedDisabled), nil
+			}
+			recordDisabled(obj, matchedDisabled)
+			return true, nil
 		}
 
-		if cmp.Equal(old, obj) {
+		if equalValues(old, obj.Object) {
 			if i == 0 {
 				convergence = SystemConvergenceTrue
-				return false, nil
+				return recordDisabled(obj, matchedDisabled), nil
 			}
 			if *MutationLoggingEnabled {
 				logAppliedMutations("Mutation applied", mutationUUID, original, allAppliedMutations)
@@ -162,6 +245,7 @@ func (s *System) Mutate(obj *unstructured.Unstructured, ns *corev1.Namespace) (b
 					log.Error(err, "Error applying mutation annotations", "mutation id", mutationUUID)
 				}
 			}
+			recordDisabled(obj, matchedDisabled)
 
 			convergence = SystemConvergenceTrue
 			return true, nil
@@ -184,6 +268,30 @@ func (s *System) Mutate(obj *unstructured.Unstructured, ns *corev1.Namespace) (b
 		obj.GetName())
 }
 
+// recordDisabled logs and annotates the disabled mutators which matched obj,
+// returning whether obj was changed.
+func recordDisabled(obj *unstructured.Unstructured, matched map[types.ID]types.Mutator) bool {
+	if len(matched) == 0 {
+		return false
+	}
+	if *MutationLoggingEnabled {
+		var names []string
+		for _, m := range matched {
+			names = append(names, m.String())
+		}
+		sort.Strings(names)
+		log.Info("Mutators disabled by annotation",
+			logging.ResourceKind, obj.GetKind(),
+			logging.ResourceNamespace, obj.GetNamespace(),
+			logging.ResourceName, obj.GetName(),
+			"mutators", strings.Join(names, ", "))
+	}
+	if !*MutationAnnotationsEnabled {
+		return false
+	}
+	return annotateDisabled(obj, matched)
+}
+
 func mutationAnnotations(obj *unstructured.Unstructured, allAppliedMutations [][]types.Mutator, mutationUUID uuid.UUID) error {
 	mutatorStringSet := make(map[string]struct{})
 	for _, mutationsForIteration := range allAppliedMutations {
@@ -204,8 +312,8 @@ func mutationAnnotations(obj *unstructured.Unstructured, allAppliedMutations [][
 	if !ok {
 		return fmt.Errorf("Incorrect metadata type")
 	}
-	annotations["gatekeeper.sh/mutations"] = strings.Join(mutatorStrings, ", ")
-	annotations["gatekeeper.sh/mutation-id"] = mutationUUID
+	annotations[MutationsAnnotation] = strings.Join(mutatorStrings, ", ")
+	annotations[MutationIDAnnotation] = mutationUUID.String()
 	return nil
 }
 
@@ -240,10 +348,12 @@ func logAppliedMutations(message string, mutationUUID uuid.UUID, obj *unstructur
 func (s *System) Remove(id types.ID) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
+	defer s.reportCacheSizes()
 
 	if _, ok := s.mutatorsMap[id]; !ok {
 		return nil
 	}
+	defer s.swap()
 
 	s.schemaDB.Remove(id)
 
@@ -270,8 +380,46 @@ func (s *System) Remove(id types.ID) error {
 	return nil
 }
 
+// reportCacheSizes reports the sizes of the System's caches. Must be called
+// while holding s.mux.
+func (s *System) reportCacheSizes() {
+	if s.reporter == nil {
+		return
+	}
+	sizes := map[SystemCache]int{
+		MutatorsCache:  len(s.mutatorsMap),
+		SchemasCache:   s.schemaDB.NumSchemas(),
+		ConflictsCache: s.schemaDB.NumConflicts(),
+	}
+	for cache, n := range sizes {
+		if err := s.reporter.ReportCacheSize(cache, n); err != nil {
+			log.Error(err, "failed to report mutation system cache size")
+		}
+	}
+}
+
+// MutatorState describes a Mutator held by the System.
+type MutatorState struct {
+	ID types.ID
+	// Conflicts is true if the Mutator is not applied because its schema
+	// conflicts with another Mutator.
+	Conflicts bool
+}
+
+// MutatorStates returns the Mutators held by the System, in the order they are applied.
+func (s *System) MutatorStates() []MutatorState {
+	snap := s.snapshot()
+	states := make([]MutatorState, len(snap.mutators))
+	for i, m := range snap.mutators {
+		states[i] = MutatorState{ID: m.ID(), Conflicts: snap.conflicts[m.ID()]}
+	}
+	return states
+}
+
 // Get mutator for given id.
 func (s *System) Get(id types.ID) types.Mutator {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
 	mutator, found := s.mutatorsMap[id]
 	if !found {
 		return nil
@@ -316,3 +464,42 @@ func equal(id1, id2 types.ID) bool {
 	}
 	return false
 }
+
+// equalValues returns whether the JSON values a and b are equal. Mutation
+// shares unchanged subtrees between versions of an object, so maps and lists
+// which are the same are not compared element by element.
+func equalValues(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) || (a == nil) != (b == nil) {
+			return false
+		}
+		if reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer() {
+			return true
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !equalValues(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) || (a == nil) != (b == nil) {
+			return false
+		}
+		if len(a) > 0 && &a[0] == &b[0] {
+			return true
+		}
+		for i := range a {
+			if !equalValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
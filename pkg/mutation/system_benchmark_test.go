@@ -2,11 +2,14 @@ package mutation
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -60,3 +63,72 @@ func BenchmarkSystem_Mutate(b *testing.B) {
 		_, _ = s.Mutate(u, nil)
 	}
 }
+
+// largePod returns a Pod with many containers, each with a long list of
+// environment variables.
+func largePod(b *testing.B) *unstructured.Unstructured {
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+	}
+	for i := 0; i < 20; i++ {
+		c := corev1.Container{Name: fmt.Sprintf("container-%d", i), Image: "nginx:1.21"}
+		for j := 0; j < 50; j++ {
+			c.Env = append(c.Env, corev1.EnvVar{Name: fmt.Sprintf("VAR_%d", j), Value: "value"})
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, c)
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func BenchmarkSystem_Mutate_LargePod(b *testing.B) {
+	tcs := []struct {
+		name     string
+		location string
+		value    interface{}
+	}{
+		{name: "single field", location: "spec.dnsPolicy", value: "Default"},
+		{name: "keyed list element", location: `spec.containers[name: container-10].imagePullPolicy`, value: "Always"},
+		{name: "globbed list", location: "spec.containers[name: *].imagePullPolicy", value: "Always"},
+	}
+
+	for _, tc := range tcs {
+		b.Run(tc.name, func(b *testing.B) {
+			s := NewSystem(SystemOpts{})
+			a := assign(tc.value, tc.location)
+			a.Spec.ApplyTo = []match.ApplyTo{{
+				Groups:   []string{""},
+				Versions: []string{"v1"},
+				Kinds:    []string{"Pod"},
+			}}
+			m, err := mutators.MutatorForAssign(a)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := s.Upsert(m); err != nil {
+				b.Fatal(err)
+			}
+			pod := largePod(b)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				u := pod.DeepCopy()
+				b.StartTimer()
+
+				mutated, err := s.Mutate(u, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if !mutated {
+					b.Fatal("got no mutation")
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package mutation
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -37,6 +38,8 @@ func (m *fakeMutator) Mutate(obj *unstructured.Unstructured) (bool, error) {
 		return false, nil
 	}
 	m.MutationCount++
+	// Mutators must not modify the object in place, see types.Mutator.
+	obj.Object = runtime.DeepCopyJSON(obj.Object)
 
 	current := obj.GetLabels()
 	if current == nil {
@@ -332,6 +335,105 @@ func TestMutation(t *testing.T) {
 	}
 }
 
+// fakeNamespaceMutator sets the "namespace" label to the name of the
+// Namespace of the object.
+type fakeNamespaceMutator struct {
+	*fakeMutator
+}
+
+func (m *fakeNamespaceMutator) MutateInNamespace(obj *unstructured.Unstructured, ns *corev1.Namespace) (bool, error) {
+	if ns == nil || obj.GetLabels()["namespace"] == ns.Name {
+		return false, nil
+	}
+	obj.Object = runtime.DeepCopyJSON(obj.Object)
+	obj.SetLabels(map[string]string{"namespace": ns.Name})
+	return true, nil
+}
+
+func (m *fakeNamespaceMutator) DeepCopy() types.Mutator {
+	return &fakeNamespaceMutator{fakeMutator: m.fakeMutator.DeepCopy().(*fakeMutator)}
+}
+
+func TestSystem_NamespaceMutator(t *testing.T) {
+	c := NewSystem(SystemOpts{})
+	if err := c.Upsert(&fakeNamespaceMutator{&fakeMutator{MID: types.ID{Group: "aaa", Kind: "aaa", Name: "aaa"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetNamespace("foo")
+	mutated, err := c.Mutate(obj, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mutated || obj.GetLabels()["namespace"] != "foo" {
+		t.Errorf("got mutated %v and labels %v, want the label of the namespace", mutated, obj.GetLabels())
+	}
+}
+
+func TestSystem_SnapshotUnchangedByReconfiguration(t *testing.T) {
+	c := NewSystem(SystemOpts{})
+	for _, name := range []string{"aaa", "ccc"} {
+		if err := c.Upsert(&fakeMutator{MID: types.ID{Group: "aaa", Kind: "aaa", Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := c.snapshot()
+	if err := c.Upsert(&fakeMutator{MID: types.ID{Group: "aaa", Kind: "aaa", Name: "bbb"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove(types.ID{Group: "aaa", Kind: "aaa", Name: "aaa"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range before.mutators {
+		got = append(got, m.ID().Name)
+	}
+	if diff := cmp.Diff([]string{"aaa", "ccc"}, got); diff != "" {
+		t.Errorf("snapshot changed after it was replaced: %s", diff)
+	}
+
+	var states []string
+	for _, state := range c.MutatorStates() {
+		states = append(states, state.ID.Name)
+	}
+	if diff := cmp.Diff([]string{"bbb", "ccc"}, states); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestSystem_MutateWhileReconfiguring(t *testing.T) {
+	c := NewSystem(SystemOpts{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			id := types.ID{Group: "aaa", Kind: "aaa", Name: fmt.Sprintf("m%d", i%10)}
+			if i%3 == 0 {
+				_ = c.Remove(id)
+				continue
+			}
+			// fakeMutators without labels do not record being applied, which
+			// would race with comparing them.
+			_ = c.Upsert(&fakeMutator{MID: id})
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		if _, err := c.Mutate(obj, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 func mustParse(s string) parser.Path {
 	p, err := parser.Parse(s)
 	if err != nil {
@@ -521,6 +623,9 @@ type fakeReporter struct {
 	called            bool
 	convergenceStatus SystemConvergenceStatus
 	iterations        int
+	evaluations       map[string]map[MutatorOutcome]int
+	durations         map[string]int
+	cacheSizes        map[SystemCache]int
 }
 
 func (fr *fakeReporter) ReportIterationConvergence(scs SystemConvergenceStatus, iterations int) error {
@@ -530,6 +635,33 @@ func (fr *fakeReporter) ReportIterationConvergence(scs SystemConvergenceStatus,
 	return nil
 }
 
+func (fr *fakeReporter) ReportMutatorEvaluations(kind string, outcome MutatorOutcome, n int) error {
+	if fr.evaluations == nil {
+		fr.evaluations = make(map[string]map[MutatorOutcome]int)
+	}
+	if fr.evaluations[kind] == nil {
+		fr.evaluations[kind] = make(map[MutatorOutcome]int)
+	}
+	fr.evaluations[kind][outcome] += n
+	return nil
+}
+
+func (fr *fakeReporter) ReportMutatorEvaluationDuration(kind string, d time.Duration) error {
+	if fr.durations == nil {
+		fr.durations = make(map[string]int)
+	}
+	fr.durations[kind]++
+	return nil
+}
+
+func (fr *fakeReporter) ReportCacheSize(cache SystemCache, n int) error {
+	if fr.cacheSizes == nil {
+		fr.cacheSizes = make(map[SystemCache]int)
+	}
+	fr.cacheSizes[cache] = n
+	return nil
+}
+
 // TestSystem_ReportingInjection verifies that a system with injected reporting calls the
 // reporting functions.
 func TestSystem_ReportingInjection(t *testing.T) {
@@ -590,4 +722,18 @@ func TestSystem_ReportingInjection(t *testing.T) {
 	if fr.iterations != 4 {
 		t.Errorf("Expected system to report %v iterations but found %v", 4, fr.iterations)
 	}
+
+	wantEvaluations := map[string]map[MutatorOutcome]int{"aaa": {MutatorApplied: 16}}
+	if diff := cmp.Diff(wantEvaluations, fr.evaluations); diff != "" {
+		t.Errorf("unexpected mutator evaluations: %s", diff)
+	}
+
+	if fr.durations["aaa"] != 1 {
+		t.Errorf("Expected system to report evaluation duration once but found %v", fr.durations["aaa"])
+	}
+
+	wantCacheSizes := map[SystemCache]int{MutatorsCache: 4, SchemasCache: 0, ConflictsCache: 0}
+	if diff := cmp.Diff(wantCacheSizes, fr.cacheSizes); diff != "" {
+		t.Errorf("unexpected cache sizes: %s", diff)
+	}
 }
@@ -30,7 +30,9 @@ func (id ID) String() string {
 type Mutator interface {
 	// Matches tells if the given object is eligible for this mutation.
 	Matches(obj client.Object, ns *corev1.Namespace) bool
-	// Mutate applies the mutation to the given object
+	// Mutate applies the mutation to the given object. The maps and lists
+	// already held by obj must not be modified in place, as earlier versions
+	// of the object share them; those along the mutated path are replaced.
 	Mutate(obj *unstructured.Unstructured) (bool, error)
 	// ID returns the id of the current mutator.
 	ID() ID
@@ -44,6 +46,16 @@ type Mutator interface {
 	String() string
 }
 
+// NamespaceMutator is a Mutator whose mutation depends on the Namespace of
+// the object it mutates.
+type NamespaceMutator interface {
+	Mutator
+	// MutateInNamespace applies the mutation to the given object, which is in
+	// ns. ns is nil for cluster-scoped objects, and is the object itself for
+	// Namespaces.
+	MutateInNamespace(obj *unstructured.Unstructured, ns *corev1.Namespace) (bool, error)
+}
+
 // MakeID builds an ID object for the given object.
 func MakeID(obj client.Object) ID {
 	return ID{
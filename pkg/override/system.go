@@ -0,0 +1,172 @@
+// Package override lets namespace owners downgrade constraints from deny to
+// warn within their namespace with NamespacePolicyOverrides.
+//
+// A NamespacePolicyOverride refers to constraints by kind and optionally
+// name. Whether namespace owners may create overrides is left to RBAC, but a
+// constraint is only downgraded if spec.overrides.overridable of the Config,
+// which cluster admins own, allows it to be. The webhook and audit report the
+// downgraded violations with the warn enforcement action, and audit records
+// which constraints each override downgrades in its status.
+package override
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Enabled is whether NamespacePolicyOverrides are loaded and downgrade
+// constraints.
+var Enabled = flag.Bool("enable-namespace-overrides", false, "(alpha) downgrade constraints from deny to warn in the namespaces of NamespacePolicyOverrides, if the Config allows the constraints to be overridden")
+
+// System holds the NamespacePolicyOverrides of the cluster, and the
+// constraints the Config allows them to downgrade.
+type System struct {
+	mux sync.RWMutex
+	// overrides are keyed by namespace, then name.
+	overrides   map[string]map[string]*exceptionsv1alpha1.NamespacePolicyOverride
+	overridable []configv1alpha1.OverridableConstraint
+}
+
+var system = NewSystem()
+
+// Get returns the System of this process.
+func Get() *System {
+	return system
+}
+
+// NewSystem returns a System without NamespacePolicyOverrides, which allows no
+// constraint to be overridden.
+func NewSystem() *System {
+	return &System{
+		overrides: make(map[string]map[string]*exceptionsv1alpha1.NamespacePolicyOverride),
+	}
+}
+
+// Validate returns an error if o cannot be used to downgrade constraints.
+func Validate(o *exceptionsv1alpha1.NamespacePolicyOverride) error {
+	if len(o.Spec.Constraints) == 0 {
+		return errors.New("spec.constraints must not be empty")
+	}
+	for i, c := range o.Spec.Constraints {
+		if c.Kind == "" {
+			return fmt.Errorf("spec.constraints[%d].kind must be set", i)
+		}
+	}
+	return nil
+}
+
+// Upsert adds o, or replaces the NamespacePolicyOverride of the same
+// namespace and name.
+func (s *System) Upsert(o *exceptionsv1alpha1.NamespacePolicyOverride) error {
+	if err := Validate(o); err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	ns := s.overrides[o.GetNamespace()]
+	if ns == nil {
+		ns = make(map[string]*exceptionsv1alpha1.NamespacePolicyOverride)
+		s.overrides[o.GetNamespace()] = ns
+	}
+	ns[o.GetName()] = o.DeepCopy()
+	return nil
+}
+
+// Remove removes the NamespacePolicyOverride named name in namespace.
+func (s *System) Remove(namespace, name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.overrides[namespace], name)
+	if len(s.overrides[namespace]) == 0 {
+		delete(s.overrides, namespace)
+	}
+}
+
+// SetOverridable replaces the constraints which may be overridden.
+func (s *System) SetOverridable(overridable []configv1alpha1.OverridableConstraint) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.overridable = append([]configv1alpha1.OverridableConstraint(nil), overridable...)
+}
+
+// Partition splits the constraints of o into those the Config allows to be
+// overridden and those it does not.
+func (s *System) Partition(o *exceptionsv1alpha1.NamespacePolicyOverride) (active, denied []exceptionsv1alpha1.ConstraintReference) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, c := range o.Spec.Constraints {
+		if s.allowedLocked(c) {
+			active = append(active, c)
+		} else {
+			denied = append(denied, c)
+		}
+	}
+	return active, denied
+}
+
+// allowedLocked returns whether ref may be overridden. A reference to every
+// constraint of a kind is only allowed if every constraint of the kind is.
+func (s *System) allowedLocked(ref exceptionsv1alpha1.ConstraintReference) bool {
+	for _, o := range s.overridable {
+		if o.Kind == ref.Kind && (o.Name == "" || o.Name == ref.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overriding returns the sorted names of the NamespacePolicyOverrides in
+// namespace which downgrade constraint.
+func (s *System) Overriding(constraint *unstructured.Unstructured, namespace string) []string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var names []string
+	for name, o := range s.overrides[namespace] {
+		for _, c := range o.Spec.Constraints {
+			if refersTo(c, constraint) && s.allowedLocked(c) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply downgrades the results which deny an object in a namespace with a
+// NamespacePolicyOverride to warn. Downgraded results are replaced with
+// copies, and returned with the names of the overrides downgrading them.
+func (s *System) Apply(results []*types.Result) map[*types.Result][]string {
+	downgraded := make(map[*types.Result][]string)
+	for i, r := range results {
+		if r.EnforcementAction != string(util.Deny) || r.Constraint == nil {
+			continue
+		}
+		obj, ok := r.Resource.(*unstructured.Unstructured)
+		if !ok || obj.GetNamespace() == "" {
+			continue
+		}
+		names := s.Overriding(r.Constraint, obj.GetNamespace())
+		if len(names) == 0 {
+			continue
+		}
+		warn := *r
+		warn.EnforcementAction = string(util.Warn)
+		results[i] = &warn
+		downgraded[&warn] = names
+	}
+	return downgraded
+}
+
+func refersTo(ref exceptionsv1alpha1.ConstraintReference, constraint *unstructured.Unstructured) bool {
+	return ref.Kind == constraint.GetKind() && (ref.Name == "" || ref.Name == constraint.GetName())
+}
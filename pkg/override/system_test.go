@@ -0,0 +1,154 @@
+package override
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newOverride(namespace, name string, refs ...exceptionsv1alpha1.ConstraintReference) *exceptionsv1alpha1.NamespacePolicyOverride {
+	return &exceptionsv1alpha1.NamespacePolicyOverride{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       exceptionsv1alpha1.NamespacePolicyOverrideSpec{Constraints: refs},
+	}
+}
+
+func newConstraint(kind, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	u.SetKind(kind)
+	u.SetName(name)
+	return u
+}
+
+func newObject(kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newSystem(t *testing.T, overridable []configv1alpha1.OverridableConstraint, overrides ...*exceptionsv1alpha1.NamespacePolicyOverride) *System {
+	s := NewSystem()
+	s.SetOverridable(overridable)
+	for _, o := range overrides {
+		if err := s.Upsert(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		refs    []exceptionsv1alpha1.ConstraintReference
+		wantErr bool
+	}{
+		{name: "valid", refs: []exceptionsv1alpha1.ConstraintReference{{Kind: "K8sRequiredLabels"}}},
+		{name: "no constraints", wantErr: true},
+		{name: "no kind", refs: []exceptionsv1alpha1.ConstraintReference{{Name: "owner"}}, wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(newOverride("a", "o", tc.refs...))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSystem_Partition(t *testing.T) {
+	s := newSystem(t, []configv1alpha1.OverridableConstraint{
+		{Kind: "K8sRequiredLabels"},
+		{Kind: "K8sContainerLimits", Name: "limits"},
+	})
+	o := newOverride("a", "o",
+		exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels"},
+		exceptionsv1alpha1.ConstraintReference{Kind: "K8sContainerLimits", Name: "limits"},
+		exceptionsv1alpha1.ConstraintReference{Kind: "K8sContainerLimits"},
+		exceptionsv1alpha1.ConstraintReference{Kind: "K8sPSPPrivilegedContainer"},
+	)
+
+	active, denied := s.Partition(o)
+	wantActive := []exceptionsv1alpha1.ConstraintReference{
+		{Kind: "K8sRequiredLabels"},
+		{Kind: "K8sContainerLimits", Name: "limits"},
+	}
+	wantDenied := []exceptionsv1alpha1.ConstraintReference{
+		{Kind: "K8sContainerLimits"},
+		{Kind: "K8sPSPPrivilegedContainer"},
+	}
+	if diff := cmp.Diff(wantActive, active); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(wantDenied, denied); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestSystem_Apply(t *testing.T) {
+	labels := newConstraint("K8sRequiredLabels", "owner")
+	privileged := newConstraint("K8sPSPPrivilegedContainer", "privileged")
+
+	s := newSystem(t,
+		[]configv1alpha1.OverridableConstraint{{Kind: "K8sRequiredLabels"}},
+		newOverride("a", "labels", exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels", Name: "owner"}),
+		newOverride("a", "privileged", exceptionsv1alpha1.ConstraintReference{Kind: "K8sPSPPrivilegedContainer"}),
+	)
+
+	deny := func(constraint, obj *unstructured.Unstructured) *types.Result {
+		return &types.Result{Constraint: constraint, Resource: obj, EnforcementAction: "deny"}
+	}
+	inA := newObject("Pod", "a", "pod")
+	inB := newObject("Pod", "b", "pod")
+	dryrun := &types.Result{Constraint: labels, Resource: inA, EnforcementAction: "dryrun"}
+	results := []*types.Result{
+		deny(labels, inA),
+		deny(privileged, inA),
+		deny(labels, inB),
+		deny(labels, newObject("Namespace", "", "a")),
+		dryrun,
+	}
+	original := results[0]
+
+	downgraded := s.Apply(results)
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.EnforcementAction)
+	}
+	// The privileged constraint is not overridable, and only objects in the
+	// namespace of the override are downgraded.
+	want := []string{"warn", "deny", "deny", "deny", "dryrun"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(map[*types.Result][]string{results[0]: {"labels"}}, downgraded); diff != "" {
+		t.Error(diff)
+	}
+	if original.EnforcementAction != "deny" {
+		t.Error("got the original result modified")
+	}
+
+	// Once the Config no longer allows the override, the constraint is enforced.
+	s.SetOverridable(nil)
+	results = []*types.Result{deny(labels, inA)}
+	if downgraded := s.Apply(results); len(downgraded) != 0 {
+		t.Errorf("got %v downgraded", downgraded)
+	}
+
+	s.SetOverridable([]configv1alpha1.OverridableConstraint{{Kind: "K8sRequiredLabels"}})
+	s.Remove("a", "labels")
+	if downgraded := s.Apply(results); len(downgraded) != 0 {
+		t.Errorf("got %v downgraded after removing the override", downgraded)
+	}
+}
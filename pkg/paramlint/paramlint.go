@@ -0,0 +1,170 @@
+// Package paramlint finds likely mistakes in the parameters of constraints.
+//
+// A constraint whose parameters are valid for its template may still not
+// enforce what its author meant, for example when an allowlist is left empty
+// or a regex matches every string. Such mistakes are reported as Warnings in
+// the status of the constraint, which is enforced regardless.
+package paramlint
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Enabled is whether the parameters of constraints are linted when they are
+// added.
+var Enabled = flag.Bool("lint-constraint-parameters", false, "(alpha) report likely mistakes in the parameters of constraints, such as empty allowlists, regexes matching everything and CIDRs covering every address, as warnings in their status")
+
+// Codes of the Warnings.
+const (
+	// EmptyAllowlist is the code of empty allowlists of deny constraints.
+	EmptyAllowlist = "empty_allowlist"
+	// MatchAllRegex is the code of regexes matching every string.
+	MatchAllRegex = "match_all_regex"
+	// MatchAllCIDR is the code of CIDRs covering every address.
+	MatchAllCIDR = "match_all_cidr"
+)
+
+// Lint returns the Warnings about the parameters of constraint, ordered by
+// location.
+func Lint(constraint *unstructured.Unstructured) []v1beta1.Warning {
+	params, found, err := unstructured.NestedFieldNoCopy(constraint.Object, "spec", "parameters")
+	if err != nil || !found {
+		return nil
+	}
+	action, err := util.GetEnforcementAction(constraint.Object)
+	if err != nil {
+		return nil
+	}
+	l := &linter{deny: action == util.Deny}
+	l.lint("parameters", "", params)
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		return l.warnings[i].Location < l.warnings[j].Location
+	})
+	return l.warnings
+}
+
+type linter struct {
+	// deny is whether the constraint denies requests, in which case an empty
+	// allowlist may reject every object it matches.
+	deny     bool
+	warnings []v1beta1.Warning
+}
+
+// lint lints value, found at location under the key named key.
+func (l *linter) lint(location, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, elem := range v {
+			l.lint(location+"."+k, k, elem)
+		}
+	case []interface{}:
+		if len(v) == 0 && l.deny && isAllowlist(key) {
+			l.warn(EmptyAllowlist, location, "%s is an empty allowlist, so the constraint may deny every object it matches", location)
+		}
+		for i, elem := range v {
+			l.lint(fmt.Sprintf("%s[%d]", location, i), key, elem)
+		}
+	case string:
+		if isRegex(key) && matchesEverything(v) {
+			l.warn(MatchAllRegex, location, "%s is a regex matching every string: %q", location, v)
+		}
+		if _, cidr, err := net.ParseCIDR(v); err == nil {
+			if ones, _ := cidr.Mask.Size(); ones == 0 {
+				l.warn(MatchAllCIDR, location, "%s is a CIDR covering every address: %q", location, v)
+			}
+		}
+	}
+}
+
+func (l *linter) warn(code, location, format string, args ...interface{}) {
+	l.warnings = append(l.warnings, v1beta1.Warning{
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Location: location,
+	})
+}
+
+// isAllowlist returns whether the parameter named key lists allowed values,
+// judging by its name.
+func isAllowlist(key string) bool {
+	k := strings.ToLower(key)
+	return strings.HasPrefix(k, "allowed") || strings.Contains(k, "allowlist") || strings.Contains(k, "whitelist")
+}
+
+// isRegex returns whether the parameter named key holds regexes, judging by
+// its name.
+func isRegex(key string) bool {
+	k := strings.ToLower(key)
+	return strings.Contains(k, "regex") || strings.Contains(k, "pattern")
+}
+
+// matchesEverything returns whether the regex pattern matches every string
+// without a newline, as Rego's regex.match would. Invalid regexes match
+// nothing.
+func matchesEverything(pattern string) bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	re = unwrap(re.Simplify())
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	start := len(subs) > 0 && isBegin(unwrap(subs[0]))
+	if start {
+		subs = subs[1:]
+	}
+	end := len(subs) > 0 && isEnd(unwrap(subs[len(subs)-1]))
+	if end {
+		subs = subs[:len(subs)-1]
+	}
+
+	if !start || !end {
+		// A regex which is not anchored at both ends matches within every
+		// string if it matches the empty string.
+		return regexp.MustCompile(pattern).MatchString("")
+	}
+	// Anchored at both ends, the regex must match any run of characters.
+	for _, sub := range subs {
+		sub = unwrap(sub)
+		if sub.Op == syntax.OpEmptyMatch {
+			continue
+		}
+		if sub.Op != syntax.OpStar || !isAnyChar(unwrap(sub.Sub[0])) {
+			return false
+		}
+	}
+	return len(subs) > 0
+}
+
+// unwrap returns re without the capture groups around it.
+func unwrap(re *syntax.Regexp) *syntax.Regexp {
+	for re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+	return re
+}
+
+func isBegin(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpBeginText || re.Op == syntax.OpBeginLine
+}
+
+func isEnd(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpEndText || re.Op == syntax.OpEndLine
+}
+
+func isAnyChar(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpAnyChar || re.Op == syntax.OpAnyCharNotNL
+}
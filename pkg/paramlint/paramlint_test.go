@@ -0,0 +1,128 @@
+package paramlint
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newConstraint(spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	u.SetKind("K8sAllowedRepos")
+	u.SetName("repos")
+	return u
+}
+
+func TestLint(t *testing.T) {
+	tcs := []struct {
+		name string
+		spec map[string]interface{}
+		want []v1beta1.Warning
+	}{
+		{
+			name: "no parameters",
+			spec: map[string]interface{}{},
+		},
+		{
+			name: "sensible parameters",
+			spec: map[string]interface{}{"parameters": map[string]interface{}{
+				"allowedRepos": []interface{}{"gcr.io/"},
+				"namePattern":  "^prod-.*$",
+				"cidrs":        []interface{}{"10.0.0.0/8"},
+			}},
+		},
+		{
+			name: "empty allowlist",
+			spec: map[string]interface{}{"parameters": map[string]interface{}{
+				"allowedRepos": []interface{}{},
+				"exemptImages": []interface{}{},
+			}},
+			want: []v1beta1.Warning{{
+				Code:     EmptyAllowlist,
+				Message:  "parameters.allowedRepos is an empty allowlist, so the constraint may deny every object it matches",
+				Location: "parameters.allowedRepos",
+			}},
+		},
+		{
+			name: "empty allowlist of a dryrun constraint",
+			spec: map[string]interface{}{
+				"enforcementAction": "dryrun",
+				"parameters":        map[string]interface{}{"allowedRepos": []interface{}{}},
+			},
+		},
+		{
+			name: "regexes matching everything",
+			spec: map[string]interface{}{"parameters": map[string]interface{}{
+				"regexes": []interface{}{"^ok$", "^.*$"},
+				"pattern": "(x*)",
+				"nested":  map[string]interface{}{"nameRegex": ""},
+			}},
+			want: []v1beta1.Warning{{
+				Code:     MatchAllRegex,
+				Message:  `parameters.nested.nameRegex is a regex matching every string: ""`,
+				Location: "parameters.nested.nameRegex",
+			}, {
+				Code:     MatchAllRegex,
+				Message:  `parameters.pattern is a regex matching every string: "(x*)"`,
+				Location: "parameters.pattern",
+			}, {
+				Code:     MatchAllRegex,
+				Message:  `parameters.regexes[1] is a regex matching every string: "^.*$"`,
+				Location: "parameters.regexes[1]",
+			}},
+		},
+		{
+			name: "CIDRs covering every address",
+			spec: map[string]interface{}{"parameters": map[string]interface{}{
+				"allowedCIDRs": []interface{}{"10.0.0.0/8", "0.0.0.0/0", "::/0"},
+			}},
+			want: []v1beta1.Warning{{
+				Code:     MatchAllCIDR,
+				Message:  `parameters.allowedCIDRs[1] is a CIDR covering every address: "0.0.0.0/0"`,
+				Location: "parameters.allowedCIDRs[1]",
+			}, {
+				Code:     MatchAllCIDR,
+				Message:  `parameters.allowedCIDRs[2] is a CIDR covering every address: "::/0"`,
+				Location: "parameters.allowedCIDRs[2]",
+			}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Lint(newConstraint(tc.spec))); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMatchesEverything(t *testing.T) {
+	tcs := []struct {
+		pattern string
+		want    bool
+	}{
+		{pattern: "", want: true},
+		{pattern: ".*", want: true},
+		{pattern: "^.*$", want: true},
+		{pattern: `\A(.*)\z`, want: true},
+		{pattern: "^a*", want: true},
+		{pattern: "foo|.*", want: true},
+		{pattern: "^$"},
+		{pattern: "^.+$"},
+		{pattern: "^prod-.*$"},
+		{pattern: "foo"},
+		{pattern: "("},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.pattern, func(t *testing.T) {
+			if got := matchesEverything(tc.pattern); got != tc.want {
+				t.Errorf("got matchesEverything(%q) = %v, want %v", tc.pattern, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Package policyfreeze lets operators freeze the policy of a pod during a
+// control-plane incident.
+//
+// While the policy is frozen, the pod keeps enforcing and auditing the
+// ConstraintTemplates and constraints it had loaded, but does not ingest
+// changes to them. Changes, including deletions, are deferred until the
+// policy is thawed, so a degraded API server or etcd cannot leave the pod
+// with a partially loaded policy. The freeze is controlled through the
+// introspection endpoint, which only needs the API server to review tokens,
+// and is kept in memory, so it ends when the pod restarts.
+//
+// A single ConstraintTemplate or constraint can also be frozen with
+// Annotation, for example while the repository it is deployed from is being
+// changed. Its controller keeps the version it had loaded, which it reports as
+// frozen in its status, until the annotation is removed.
+package policyfreeze
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Path is the introspection path controlling the freeze.
+const Path = "/debug/policyfreeze"
+
+// RetryInterval is how often deferred changes are retried while the policy
+// is frozen.
+const RetryInterval = 10 * time.Second
+
+// maxDeferred is the number of deferred objects listed in the status.
+const maxDeferred = 20
+
+var (
+	// Enabled is whether the freeze can be controlled.
+	Enabled = flag.Bool("enable-policy-freeze", false, "(alpha) serve "+Path+" on the introspection endpoint, where POST freezes the ConstraintTemplates and constraints loaded by this pod and DELETE thaws them. Requires --introspection-addr")
+
+	log = logf.Log.WithName("policy-freeze")
+)
+
+// Annotation is set to "true" on a ConstraintTemplate or constraint to keep
+// the version of it which is loaded, ignoring changes to it until the
+// annotation is removed. Deletions are not deferred.
+const Annotation = "policy.gatekeeper.sh/frozen"
+
+// Annotated returns whether obj is frozen with Annotation.
+func Annotated(obj metav1.Object) bool {
+	return obj.GetAnnotations()[Annotation] == "true"
+}
+
+// Validate returns an error if the freeze cannot be controlled.
+func Validate() error {
+	if *Enabled && *introspection.Addr == "" {
+		return errors.New("--enable-policy-freeze requires --introspection-addr")
+	}
+	return nil
+}
+
+// Freeze records whether the policy of the pod is frozen, and which changes
+// were deferred because it is.
+type Freeze struct {
+	mux      sync.RWMutex
+	frozen   bool
+	since    time.Time
+	reason   string
+	deferred map[string]bool
+
+	reporter *reporter
+	now      func() time.Time
+}
+
+var freeze = New()
+
+// Get returns the Freeze of this pod.
+func Get() *Freeze {
+	return freeze
+}
+
+// New returns a thawed Freeze.
+func New() *Freeze {
+	return &Freeze{
+		deferred: make(map[string]bool),
+		reporter: newStatsReporter(),
+		now:      time.Now,
+	}
+}
+
+// Frozen returns whether the policy is frozen.
+func (f *Freeze) Frozen() bool {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+	return f.frozen
+}
+
+// Freeze freezes the policy for reason, returning false if it already was.
+func (f *Freeze) Freeze(reason string) bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.frozen {
+		return false
+	}
+	f.frozen = true
+	f.since = f.now()
+	f.reason = reason
+	log.Info("policy frozen, changes to ConstraintTemplates and constraints are deferred", "reason", reason)
+	f.report()
+	return true
+}
+
+// Thaw thaws the policy, returning false if it was not frozen. Deferred
+// changes are ingested when they are next retried.
+func (f *Freeze) Thaw() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if !f.frozen {
+		return false
+	}
+	log.Info("policy thawed, ingesting deferred changes", "frozen_for", f.now().Sub(f.since).String(), "deferred", len(f.deferred))
+	f.frozen = false
+	f.since = time.Time{}
+	f.reason = ""
+	f.deferred = make(map[string]bool)
+	f.report()
+	return true
+}
+
+// Defer records that the change to the object identified by key was deferred.
+func (f *Freeze) Defer(key string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.deferred[key] {
+		return
+	}
+	f.deferred[key] = true
+	f.report()
+}
+
+// report records the freeze in metrics. The lock must be held.
+func (f *Freeze) report() {
+	if err := f.reporter.report(f.frozen, len(f.deferred)); err != nil {
+		log.Error(err, "failed to report policy freeze")
+	}
+}
+
+// Status returns the state of the freeze.
+func (f *Freeze) Status() *v1beta1.PolicyFreezeStatus {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+	s := &v1beta1.PolicyFreezeStatus{Frozen: f.frozen, Reason: f.reason, DeferredCount: len(f.deferred)}
+	if f.frozen {
+		since := metav1.NewTime(f.since)
+		s.Since = &since
+	}
+	for key := range f.deferred {
+		s.Deferred = append(s.Deferred, key)
+	}
+	sort.Strings(s.Deferred)
+	if len(s.Deferred) > maxDeferred {
+		s.Deferred = s.Deferred[:maxDeferred]
+	}
+	return s
+}
+
+// request is the body of a request freezing the policy.
+type request struct {
+	Reason string `json:"reason"`
+}
+
+// ServeHTTP freezes the policy on POST and thaws it on DELETE, and responds
+// with the Status.
+func (f *Freeze) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		req := request{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		f.Freeze(req.Reason)
+	case http.MethodDelete:
+		f.Thaw()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f.Status()); err != nil {
+		log.Error(err, "writing policy freeze status")
+	}
+}
@@ -0,0 +1,157 @@
+package policyfreeze
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestFreeze(now time.Time) *Freeze {
+	f := New()
+	f.now = func() time.Time { return now }
+	return f
+}
+
+func TestFreeze(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := newTestFreeze(now)
+
+	if f.Frozen() {
+		t.Fatal("got frozen, want thawed")
+	}
+	if f.Thaw() {
+		t.Error("thawing a thawed policy returned true")
+	}
+	if !f.Freeze("etcd is degraded") {
+		t.Error("freezing returned false")
+	}
+	if f.Freeze("again") {
+		t.Error("freezing a frozen policy returned true")
+	}
+	if !f.Frozen() {
+		t.Fatal("got thawed, want frozen")
+	}
+
+	f.Defer("ConstraintTemplate/k8srequiredlabels")
+	f.Defer("K8sRequiredLabels/owner")
+	f.Defer("ConstraintTemplate/k8srequiredlabels")
+
+	got := f.Status()
+	if got.Since == nil || !got.Since.Time.Equal(now) {
+		t.Errorf("got since %v, want %v", got.Since, now)
+	}
+	got.Since = nil
+	want := &v1beta1.PolicyFreezeStatus{
+		Frozen:        true,
+		Reason:        "etcd is degraded",
+		DeferredCount: 2,
+		Deferred:      []string{"ConstraintTemplate/k8srequiredlabels", "K8sRequiredLabels/owner"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	// Thawing forgets the deferred changes, which are retried.
+	if !f.Thaw() {
+		t.Error("thawing returned false")
+	}
+	if diff := cmp.Diff(&v1beta1.PolicyFreezeStatus{}, f.Status()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestStatusTruncatesDeferred(t *testing.T) {
+	f := newTestFreeze(time.Now())
+	f.Freeze("")
+	for i := 0; i < maxDeferred+5; i++ {
+		f.Defer(fmt.Sprintf("K8sRequiredLabels/c%02d", i))
+	}
+	got := f.Status()
+	if got.DeferredCount != maxDeferred+5 {
+		t.Errorf("got deferred count %d, want %d", got.DeferredCount, maxDeferred+5)
+	}
+	if len(got.Deferred) != maxDeferred {
+		t.Errorf("got %d deferred, want %d", len(got.Deferred), maxDeferred)
+	}
+	if got.Deferred[0] != "K8sRequiredLabels/c00" {
+		t.Errorf("got first deferred %q, want K8sRequiredLabels/c00", got.Deferred[0])
+	}
+}
+
+func TestAnnotated(t *testing.T) {
+	tcs := []struct {
+		annotations map[string]string
+		want        bool
+	}{
+		{annotations: nil, want: false},
+		{annotations: map[string]string{Annotation: "true"}, want: true},
+		{annotations: map[string]string{Annotation: "false"}, want: false},
+		{annotations: map[string]string{Annotation: ""}, want: false},
+	}
+	for _, tc := range tcs {
+		obj := &unstructured.Unstructured{}
+		obj.SetAnnotations(tc.annotations)
+		if got := Annotated(obj); got != tc.want {
+			t.Errorf("got Annotated() = %v with annotations %v, want %v", got, tc.annotations, tc.want)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	f := newTestFreeze(time.Now())
+
+	serve := func(method, body string) (int, *v1beta1.PolicyFreezeStatus) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, httptest.NewRequest(method, Path, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+		status := &v1beta1.PolicyFreezeStatus{}
+		if err := json.Unmarshal(w.Body.Bytes(), status); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, status
+	}
+
+	if _, got := serve(http.MethodGet, ""); got.Frozen {
+		t.Error("GET: got frozen, want thawed")
+	}
+	_, got := serve(http.MethodPost, `{"reason": "etcd is degraded"}`)
+	if !got.Frozen || got.Reason != "etcd is degraded" || got.Since == nil {
+		t.Errorf("POST: got %+v, want frozen for the reason", got)
+	}
+	if !f.Frozen() {
+		t.Error("POST did not freeze the policy")
+	}
+	if _, got := serve(http.MethodGet, ""); !got.Frozen {
+		t.Error("GET: got thawed, want frozen")
+	}
+	if _, got := serve(http.MethodDelete, ""); got.Frozen {
+		t.Error("DELETE: got frozen, want thawed")
+	}
+
+	// A freeze needs no reason.
+	if _, got := serve(http.MethodPost, ""); !got.Frozen {
+		t.Error("POST without a body: got thawed, want frozen")
+	}
+	f.Thaw()
+
+	if code, _ := serve(http.MethodPost, "{"); code != http.StatusBadRequest {
+		t.Errorf("POST with an invalid body: got status %d, want %d", code, http.StatusBadRequest)
+	}
+	if f.Frozen() {
+		t.Error("POST with an invalid body froze the policy")
+	}
+	if code, _ := serve(http.MethodPut, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: got status %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+}
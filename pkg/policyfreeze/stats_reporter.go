@@ -0,0 +1,61 @@
+package policyfreeze
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+const (
+	frozenMetricName   = "policy_frozen"
+	deferredMetricName = "policy_freeze_deferred_changes"
+)
+
+var (
+	frozenM = stats.Int64(
+		frozenMetricName,
+		"Whether ingesting ConstraintTemplates and constraints is frozen, 1 if frozen and 0 otherwise",
+		stats.UnitDimensionless)
+
+	deferredM = stats.Int64(
+		deferredMetricName,
+		"The number of ConstraintTemplates and constraints whose changes are deferred by the freeze",
+		stats.UnitDimensionless)
+)
+
+func init() {
+	views := []*view.View{
+		{
+			Name:        frozenMetricName,
+			Description: frozenM.Description(),
+			Measure:     frozenM,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        deferredMetricName,
+			Description: deferredM.Description(),
+			Measure:     deferredM,
+			Aggregation: view.LastValue(),
+		},
+	}
+
+	if err := view.Register(views...); err != nil {
+		panic(err)
+	}
+}
+
+type reporter struct{}
+
+func newStatsReporter() *reporter {
+	return &reporter{}
+}
+
+func (r *reporter) report(frozen bool, deferred int) error {
+	var f int64
+	if frozen {
+		f = 1
+	}
+	return metrics.Record(context.Background(), frozenM.M(f), stats.WithMeasurements(deferredM.M(int64(deferred))))
+}
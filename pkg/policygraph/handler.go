@@ -0,0 +1,65 @@
+package policygraph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Path is the introspection path serving the Graph.
+const Path = "/debug/policygraph"
+
+const buildTimeout = 30 * time.Second
+
+var log = logf.Log.WithName("policy-graph")
+
+// Handler serves the Graph of the policy read from r. The "impact" query
+// parameter limits it to what the node with that ID affects, and
+// "format=dot" serves it in the DOT language rather than as JSON.
+func Handler(r client.Reader, withMutators bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := req.URL.Query()
+		format := query.Get("format")
+		if format != "" && format != "json" && format != "dot" {
+			http.Error(w, "format must be json or dot", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), buildTimeout)
+		defer cancel()
+		g, err := Build(ctx, r, withMutators)
+		if err != nil {
+			log.Error(err, "building policy graph")
+			http.Error(w, "unable to build the policy graph: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if id := query.Get("impact"); id != "" {
+			if g = g.Impact(id); g == nil {
+				http.Error(w, "no node "+id, http.StatusNotFound)
+				return
+			}
+		}
+
+		if format == "dot" {
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			if _, err := w.Write([]byte(g.DOT())); err != nil {
+				log.Error(err, "writing policy graph")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(g); err != nil {
+			log.Error(err, "writing policy graph")
+		}
+	})
+}
@@ -0,0 +1,444 @@
+// Package policygraph computes how the policy objects of a cluster depend on
+// each other, so operators can see what deleting or changing one of them
+// affects before they do.
+//
+// The graph is directed in the direction of impact: an edge from A to B means
+// that changing or deleting A changes how B behaves. ConstraintTemplates
+// define their constraints, the Config syncs the kinds templates read from
+// data.inventory, and mutators change the objects of the kinds constraints
+// review. Everything reachable from a node is its blast radius.
+package policygraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	constraintstatusv1beta1 "github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/inventory"
+	"github.com/open-policy-agent/gatekeeper/pkg/keys"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodeType is the type of a Node.
+type NodeType string
+
+const (
+	// TemplateNode is a ConstraintTemplate.
+	TemplateNode NodeType = "ConstraintTemplate"
+	// ConstraintNode is a constraint.
+	ConstraintNode NodeType = "Constraint"
+	// ConfigNode is the Config.
+	ConfigNode NodeType = "Config"
+	// SyncedKindNode is a kind replicated into data.inventory, or read from it.
+	SyncedKindNode NodeType = "SyncedKind"
+	// MutatorNode is a mutator.
+	MutatorNode NodeType = "Mutator"
+	// KindNode is a kind of object reviewed by constraints or mutated by
+	// mutators. Its group or kind may be "*".
+	KindNode NodeType = "Kind"
+)
+
+// Relation is how the source of an Edge affects its target.
+type Relation string
+
+const (
+	// Defines is the relation of a ConstraintTemplate to its constraints.
+	Defines Relation = "defines"
+	// Syncs is the relation of the Config to the kinds it replicates.
+	Syncs Relation = "syncs"
+	// ReadBy is the relation of a replicated kind to the templates reading it.
+	ReadBy Relation = "readBy"
+	// Mutates is the relation of a mutator to the kinds it applies to.
+	Mutates Relation = "mutates"
+	// ReviewedBy is the relation of a kind to the constraints matching it.
+	ReviewedBy Relation = "reviewedBy"
+)
+
+// Node is a policy object, or a kind of object policy applies to.
+type Node struct {
+	// ID is the type of the node followed by its name, such as
+	// "Constraint/K8sRequiredLabels/owner".
+	ID   string   `json:"id"`
+	Type NodeType `json:"type"`
+	Name string   `json:"name"`
+	// Missing is true for kinds read by templates which are not replicated.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Edge is a dependency of To on From.
+type Edge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Relation Relation `json:"relation"`
+}
+
+// Graph is the dependency graph of the policy of a cluster. Nodes and edges
+// are sorted.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// builder accumulates a Graph, ignoring duplicate nodes and edges.
+type builder struct {
+	nodes map[string]Node
+	edges map[Edge]bool
+}
+
+func newBuilder() *builder {
+	return &builder{nodes: make(map[string]Node), edges: make(map[Edge]bool)}
+}
+
+// node adds n unless a node with its ID exists, and returns the ID.
+func (b *builder) node(n Node) string {
+	if _, ok := b.nodes[n.ID]; !ok {
+		b.nodes[n.ID] = n
+	}
+	return n.ID
+}
+
+func (b *builder) edge(from, to string, rel Relation) {
+	b.edges[Edge{From: from, To: to, Relation: rel}] = true
+}
+
+func (b *builder) graph() *Graph {
+	g := &Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	for e := range b.edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Relation < b.Relation
+	})
+	return g
+}
+
+// Build computes the Graph of the policy read from r. Mutators are only
+// included if withMutators is true, as their CRDs are not installed otherwise.
+func Build(ctx context.Context, r client.Reader, withMutators bool) (*Graph, error) {
+	b := newBuilder()
+
+	templates := &v1beta1.ConstraintTemplateList{}
+	if err := r.List(ctx, templates); err != nil {
+		return nil, fmt.Errorf("listing ConstraintTemplates: %w", err)
+	}
+	// readers are the IDs of the templates reading each replicated kind, and
+	// dynamic those of templates which may read any kind.
+	readers := make(map[schema.GroupVersionKind][]string)
+	var dynamic []string
+	// constraints are the selectors of the match of each constraint.
+	constraints := make(map[string][]selector)
+	for i := range templates.Items {
+		ct := &templates.Items[i]
+		id := b.node(Node{ID: string(TemplateNode) + "/" + ct.GetName(), Type: TemplateNode, Name: ct.GetName()})
+		// Templates whose Rego does not parse are taken to read every kind,
+		// as they do when readiness only waits for referenced data.
+		usage, err := inventory.ExtractV1Beta1(ct)
+		if err != nil || len(usage.Dynamic) != 0 {
+			dynamic = append(dynamic, id)
+		} else {
+			for _, gvk := range usage.GVKs {
+				readers[gvk] = append(readers[gvk], id)
+			}
+		}
+
+		kind := ct.Spec.CRD.Spec.Names.Kind
+		if kind == "" {
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: constraintstatusv1beta1.ConstraintsGroup, Version: "v1beta1", Kind: kind + "List"})
+		if err := r.List(ctx, list); err != nil {
+			// The CRD of a new template may not have been created yet.
+			if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("listing %s constraints: %w", kind, err)
+		}
+		for j := range list.Items {
+			c := &list.Items[j]
+			cid := b.node(Node{ID: string(ConstraintNode) + "/" + kind + "/" + c.GetName(), Type: ConstraintNode, Name: kind + "/" + c.GetName()})
+			b.edge(id, cid, Defines)
+			kinds, _, err := unstructured.NestedSlice(c.Object, "spec", "match", "kinds")
+			if err != nil {
+				return nil, fmt.Errorf("reading the match of %s: %w", cid, err)
+			}
+			constraints[cid] = constraintSelectors(kinds)
+		}
+	}
+
+	if err := b.addConfig(ctx, r, readers, dynamic); err != nil {
+		return nil, err
+	}
+
+	// mutated are the kinds mutators apply to.
+	var mutated []selector
+	if withMutators {
+		var err error
+		if mutated, err = b.addMutators(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+
+	// Kinds link mutators to the constraints reviewing what they mutate. The
+	// kinds constraints match are included even if nothing mutates them, so
+	// the graph shows what each constraint reviews.
+	for cid, selectors := range constraints {
+		for _, s := range selectors {
+			b.edge(b.kindNode(s), cid, ReviewedBy)
+		}
+		for _, m := range mutated {
+			for _, s := range selectors {
+				if s.overlaps(m) {
+					b.edge(b.kindNode(m), cid, ReviewedBy)
+				}
+			}
+		}
+	}
+	return b.graph(), nil
+}
+
+// addConfig adds the Config and the kinds it replicates, and links the kinds
+// templates read to them. Kinds read but not replicated are missing.
+func (b *builder) addConfig(ctx context.Context, r client.Reader, readers map[schema.GroupVersionKind][]string, dynamic []string) error {
+	cfg := &configv1alpha1.Config{}
+	err := r.Get(ctx, keys.Config, cfg)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting the Config: %w", err)
+	}
+	synced := make(map[schema.GroupVersionKind]bool)
+	if err == nil {
+		id := b.node(Node{ID: string(ConfigNode) + "/" + cfg.GetName(), Type: ConfigNode, Name: cfg.GetName()})
+		for _, entry := range cfg.Spec.Sync.SyncOnly {
+			gvk := schema.GroupVersionKind{Group: entry.Group, Version: entry.Version, Kind: entry.Kind}
+			synced[gvk] = true
+			b.edge(id, b.syncedKindNode(gvk, false), Syncs)
+		}
+	}
+	for gvk, ids := range readers {
+		kid := b.syncedKindNode(gvk, !synced[gvk])
+		for _, id := range ids {
+			b.edge(kid, id, ReadBy)
+		}
+	}
+	for gvk := range synced {
+		for _, id := range dynamic {
+			b.edge(syncedKindID(gvk), id, ReadBy)
+		}
+	}
+	return nil
+}
+
+// addMutators adds the mutators, linked to the kinds they apply to, and
+// returns those kinds.
+func (b *builder) addMutators(ctx context.Context, r client.Reader) ([]selector, error) {
+	var all []selector
+	add := func(kind, name string, selectors []selector) {
+		id := b.node(Node{ID: string(MutatorNode) + "/" + kind + "/" + name, Type: MutatorNode, Name: kind + "/" + name})
+		for _, s := range selectors {
+			b.edge(id, b.kindNode(s), Mutates)
+		}
+		all = append(all, selectors...)
+	}
+
+	assigns := &mutationsv1alpha1.AssignList{}
+	if err := r.List(ctx, assigns); err != nil {
+		return nil, fmt.Errorf("listing Assign mutators: %w", err)
+	}
+	for i := range assigns.Items {
+		add("Assign", assigns.Items[i].GetName(), applyToSelectors(assigns.Items[i].Spec.ApplyTo))
+	}
+
+	modifySets := &mutationsv1alpha1.ModifySetList{}
+	if err := r.List(ctx, modifySets); err != nil {
+		return nil, fmt.Errorf("listing ModifySet mutators: %w", err)
+	}
+	for i := range modifySets.Items {
+		add("ModifySet", modifySets.Items[i].GetName(), applyToSelectors(modifySets.Items[i].Spec.ApplyTo))
+	}
+
+	// AssignMetadata applies to every kind its match selects.
+	assignMetadata := &mutationsv1alpha1.AssignMetadataList{}
+	if err := r.List(ctx, assignMetadata); err != nil {
+		return nil, fmt.Errorf("listing AssignMetadata mutators: %w", err)
+	}
+	for i := range assignMetadata.Items {
+		add("AssignMetadata", assignMetadata.Items[i].GetName(), matchSelectors(assignMetadata.Items[i].Spec.Match.Kinds))
+	}
+	return all, nil
+}
+
+func syncedKindID(gvk schema.GroupVersionKind) string {
+	return string(SyncedKindNode) + "/" + gvk.GroupVersion().String() + "/" + gvk.Kind
+}
+
+func (b *builder) syncedKindNode(gvk schema.GroupVersionKind, missing bool) string {
+	return b.node(Node{ID: syncedKindID(gvk), Type: SyncedKindNode, Name: gvk.GroupVersion().String() + "/" + gvk.Kind, Missing: missing})
+}
+
+func (b *builder) kindNode(s selector) string {
+	name := s.String()
+	return b.node(Node{ID: string(KindNode) + "/" + name, Type: KindNode, Name: name})
+}
+
+// selector selects the kinds of a group. Either may be "*".
+type selector struct {
+	group string
+	kind  string
+}
+
+// String returns the group and kind of s, such as "apps/Deployment", or just
+// the kind for the core group.
+func (s selector) String() string {
+	if s.group == "" {
+		return s.kind
+	}
+	return s.group + "/" + s.kind
+}
+
+// overlaps returns whether s and o select a common kind.
+func (s selector) overlaps(o selector) bool {
+	return overlaps(s.group, o.group) && overlaps(s.kind, o.kind)
+}
+
+func overlaps(a, b string) bool {
+	return a == "*" || b == "*" || a == b
+}
+
+var selectAll = []selector{{group: "*", kind: "*"}}
+
+// constraintSelectors returns the selectors of the kinds of a constraint
+// match, as read from an unstructured constraint. A match without kinds
+// selects every kind.
+func constraintSelectors(kinds []interface{}) []selector {
+	var selectors []selector
+	for _, k := range kinds {
+		entry, ok := k.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		groups, _, _ := unstructured.NestedStringSlice(entry, "apiGroups")
+		names, _, _ := unstructured.NestedStringSlice(entry, "kinds")
+		selectors = append(selectors, cross(groups, names)...)
+	}
+	if len(selectors) == 0 {
+		return selectAll
+	}
+	return selectors
+}
+
+func matchSelectors(kinds []match.Kinds) []selector {
+	var selectors []selector
+	for _, k := range kinds {
+		selectors = append(selectors, cross(k.APIGroups, k.Kinds)...)
+	}
+	if len(selectors) == 0 {
+		return selectAll
+	}
+	return selectors
+}
+
+func applyToSelectors(applyTo []match.ApplyTo) []selector {
+	var selectors []selector
+	for _, a := range applyTo {
+		selectors = append(selectors, cross(a.Groups, a.Kinds)...)
+	}
+	return selectors
+}
+
+// cross returns the selectors of every kind in every group. Empty lists of
+// groups or kinds select all of them.
+func cross(groups, kinds []string) []selector {
+	if len(groups) == 0 {
+		groups = []string{"*"}
+	}
+	if len(kinds) == 0 {
+		kinds = []string{"*"}
+	}
+	var selectors []selector
+	for _, g := range groups {
+		for _, k := range kinds {
+			selectors = append(selectors, selector{group: g, kind: k})
+		}
+	}
+	return selectors
+}
+
+// Impact returns the subgraph of g reachable from the node with ID id, which
+// is what changing or deleting it affects, or nil if there is no such node.
+func (g *Graph) Impact(id string) *Graph {
+	out := make(map[string][]Edge)
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e)
+	}
+	nodes := make(map[string]Node, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	if _, ok := nodes[id]; !ok {
+		return nil
+	}
+
+	b := newBuilder()
+	queue := []string{id}
+	b.node(nodes[id])
+	for len(queue) != 0 {
+		from := queue[0]
+		queue = queue[1:]
+		for _, e := range out[from] {
+			b.edge(e.From, e.To, e.Relation)
+			if _, seen := b.nodes[e.To]; !seen {
+				b.node(nodes[e.To])
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	return b.graph()
+}
+
+// DOT returns g in the DOT language of Graphviz. Missing nodes are dashed.
+func (g *Graph) DOT() string {
+	sb := &strings.Builder{}
+	sb.WriteString("digraph policy {\n\trankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(sb, "\t%q [label=%q, shape=%s", n.ID, string(n.Type)+"\n"+n.Name, shapes[n.Type])
+		if n.Missing {
+			sb.WriteString(", style=dashed")
+		}
+		sb.WriteString("];\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(sb, "\t%q -> %q [label=%q];\n", e.From, e.To, e.Relation)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+var shapes = map[NodeType]string{
+	TemplateNode:   "box",
+	ConstraintNode: "ellipse",
+	ConfigNode:     "folder",
+	SyncedKindNode: "cylinder",
+	MutatorNode:    "hexagon",
+	KindNode:       "plaintext",
+}
@@ -0,0 +1,225 @@
+package policygraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeReader serves fixed templates, constraints, Config and mutators.
+type fakeReader struct {
+	templates   []v1beta1.ConstraintTemplate
+	constraints map[string][]unstructured.Unstructured
+	config      *configv1alpha1.Config
+	assigns     []mutationsv1alpha1.Assign
+}
+
+func (r *fakeReader) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	cfg, ok := obj.(*configv1alpha1.Config)
+	if !ok {
+		return fmt.Errorf("unexpected Get of %T", obj)
+	}
+	if r.config == nil {
+		return errors.NewNotFound(schema.GroupResource{Resource: "configs"}, key.Name)
+	}
+	r.config.DeepCopyInto(cfg)
+	return nil
+}
+
+func (r *fakeReader) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	switch l := list.(type) {
+	case *v1beta1.ConstraintTemplateList:
+		l.Items = r.templates
+	case *unstructured.UnstructuredList:
+		l.Items = r.constraints[strings.TrimSuffix(l.GetKind(), "List")]
+	case *mutationsv1alpha1.AssignList:
+		l.Items = r.assigns
+	case *mutationsv1alpha1.AssignMetadataList, *mutationsv1alpha1.ModifySetList:
+	default:
+		return fmt.Errorf("unexpected List of %T", list)
+	}
+	return nil
+}
+
+func newTemplate(name, kind, rego string) v1beta1.ConstraintTemplate {
+	ct := v1beta1.ConstraintTemplate{}
+	ct.SetName(name)
+	ct.Spec.CRD.Spec.Names.Kind = kind
+	ct.Spec.Targets = []v1beta1.Target{{Target: "admission.k8s.gatekeeper.sh", Rego: rego}}
+	return ct
+}
+
+func newConstraint(kind, name string, kinds ...interface{}) unstructured.Unstructured {
+	u := unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+	u.SetKind(kind)
+	u.SetName(name)
+	if len(kinds) != 0 {
+		u.Object["spec"] = map[string]interface{}{"match": map[string]interface{}{"kinds": kinds}}
+	}
+	return u
+}
+
+func newReader() *fakeReader {
+	cfg := &configv1alpha1.Config{}
+	cfg.SetName("config")
+	cfg.Spec.Sync.SyncOnly = []configv1alpha1.SyncOnlyEntry{{Version: "v1", Kind: "Namespace"}}
+
+	assign := mutationsv1alpha1.Assign{}
+	assign.SetName("pull-policy")
+	assign.Spec.ApplyTo = []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}}
+
+	return &fakeReader{
+		templates: []v1beta1.ConstraintTemplate{
+			newTemplate("uniquelabel", "UniqueLabel", `package uniquelabel
+violation[{"msg": "duplicate"}] {
+  data.inventory.cluster["v1"].Namespace[_]
+  data.inventory.namespace[_]["v1"]["Service"][_]
+}`),
+			newTemplate("podlimits", "PodLimits", `package podlimits
+violation[{"msg": "no limits"}] {
+  not input.review.object.spec.containers[_].resources.limits
+}`),
+		},
+		constraints: map[string][]unstructured.Unstructured{
+			"UniqueLabel": {newConstraint("UniqueLabel", "owner", map[string]interface{}{"apiGroups": []interface{}{""}, "kinds": []interface{}{"Namespace"}})},
+			"PodLimits":   {newConstraint("PodLimits", "all")},
+		},
+		config:  cfg,
+		assigns: []mutationsv1alpha1.Assign{assign},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	g, err := Build(context.Background(), newReader(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNodes := []Node{
+		{ID: "Config/config", Type: ConfigNode, Name: "config"},
+		{ID: "Constraint/PodLimits/all", Type: ConstraintNode, Name: "PodLimits/all"},
+		{ID: "Constraint/UniqueLabel/owner", Type: ConstraintNode, Name: "UniqueLabel/owner"},
+		{ID: "ConstraintTemplate/podlimits", Type: TemplateNode, Name: "podlimits"},
+		{ID: "ConstraintTemplate/uniquelabel", Type: TemplateNode, Name: "uniquelabel"},
+		{ID: "Kind/*/*", Type: KindNode, Name: "*/*"},
+		{ID: "Kind/Namespace", Type: KindNode, Name: "Namespace"},
+		{ID: "Kind/Pod", Type: KindNode, Name: "Pod"},
+		{ID: "Mutator/Assign/pull-policy", Type: MutatorNode, Name: "Assign/pull-policy"},
+		{ID: "SyncedKind/v1/Namespace", Type: SyncedKindNode, Name: "v1/Namespace"},
+		{ID: "SyncedKind/v1/Service", Type: SyncedKindNode, Name: "v1/Service", Missing: true},
+	}
+	if diff := cmp.Diff(wantNodes, g.Nodes); diff != "" {
+		t.Error(diff)
+	}
+	wantEdges := []Edge{
+		{From: "Config/config", To: "SyncedKind/v1/Namespace", Relation: Syncs},
+		{From: "ConstraintTemplate/podlimits", To: "Constraint/PodLimits/all", Relation: Defines},
+		{From: "ConstraintTemplate/uniquelabel", To: "Constraint/UniqueLabel/owner", Relation: Defines},
+		{From: "Kind/*/*", To: "Constraint/PodLimits/all", Relation: ReviewedBy},
+		{From: "Kind/Namespace", To: "Constraint/UniqueLabel/owner", Relation: ReviewedBy},
+		// The constraint matching every kind reviews the Pods mutated.
+		{From: "Kind/Pod", To: "Constraint/PodLimits/all", Relation: ReviewedBy},
+		{From: "Mutator/Assign/pull-policy", To: "Kind/Pod", Relation: Mutates},
+		{From: "SyncedKind/v1/Namespace", To: "ConstraintTemplate/uniquelabel", Relation: ReadBy},
+		{From: "SyncedKind/v1/Service", To: "ConstraintTemplate/uniquelabel", Relation: ReadBy},
+	}
+	if diff := cmp.Diff(wantEdges, g.Edges); diff != "" {
+		t.Error(diff)
+	}
+
+	// Without mutation, mutators are not listed.
+	g, err = Build(context.Background(), newReader(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range g.Nodes {
+		if n.Type == MutatorNode {
+			t.Errorf("got mutator %s without mutation", n.ID)
+		}
+	}
+}
+
+func TestImpact(t *testing.T) {
+	g, err := Build(context.Background(), newReader(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	impact := g.Impact("Config/config")
+	var ids []string
+	for _, n := range impact.Nodes {
+		ids = append(ids, n.ID)
+	}
+	want := []string{"Config/config", "Constraint/UniqueLabel/owner", "ConstraintTemplate/uniquelabel", "SyncedKind/v1/Namespace"}
+	if diff := cmp.Diff(want, ids); diff != "" {
+		t.Error(diff)
+	}
+	if len(impact.Edges) != 3 {
+		t.Errorf("got edges %v, want the 3 from the Config", impact.Edges)
+	}
+
+	if g.Impact("ConstraintTemplate/missing") != nil {
+		t.Error("got the impact of a node which does not exist")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(newReader(), true)
+	serve := func(method, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, Path+query, nil))
+		return w
+	}
+
+	w := serve(http.MethodGet, "?impact=Mutator/Assign/pull-policy")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	g := &Graph{}
+	if err := json.Unmarshal(w.Body.Bytes(), g); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 3 || len(g.Edges) != 2 {
+		t.Errorf("got %v, want the mutator, Pods and the constraint reviewing them", g)
+	}
+
+	w = serve(http.MethodGet, "?format=dot&impact=ConstraintTemplate/podlimits")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	for _, want := range []string{
+		"digraph policy {",
+		`"ConstraintTemplate/podlimits" -> "Constraint/PodLimits/all" [label="defines"];`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("got DOT %q, want it to contain %q", w.Body, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		method, query string
+		want          int
+	}{
+		{method: http.MethodGet, query: "?impact=ConstraintTemplate/missing", want: http.StatusNotFound},
+		{method: http.MethodGet, query: "?format=yaml", want: http.StatusBadRequest},
+		{method: http.MethodPost, want: http.StatusMethodNotAllowed},
+	} {
+		if got := serve(tc.method, tc.query).Code; got != tc.want {
+			t.Errorf("%s %s: got status %d, want %d", tc.method, tc.query, got, tc.want)
+		}
+	}
+}
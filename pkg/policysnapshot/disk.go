@@ -0,0 +1,184 @@
+package policysnapshot
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// fileName is the name of the Snapshot saved in Dir.
+const fileName = "policy-snapshot.json.gz"
+
+// saveInterval is how often the Snapshot is saved if it changed.
+const saveInterval = 30 * time.Second
+
+var (
+	// Dir is the directory the Snapshot is saved to and restored from.
+	Dir = flag.String("policy-snapshot-dir", "", "(alpha) directory, such as a mounted PersistentVolume, where the pod saves its compiled policy and replicated data once ready, and restores them from at startup. The pod is ready as soon as they are restored")
+
+	// MaxAge is the age above which a Snapshot saved to Dir is not restored.
+	MaxAge = flag.Duration("policy-snapshot-max-age", 24*time.Hour, "(alpha) the age above which a snapshot saved to --policy-snapshot-dir is not restored. 0 restores snapshots of any age")
+)
+
+// Save writes s to dir, replacing the Snapshot saved before. The Snapshot is
+// written to a temporary file which is then renamed, so that a pod stopping
+// while saving does not leave a partial Snapshot behind.
+func Save(dir string, s *Snapshot) (err error) {
+	f, err := os.CreateTemp(dir, "."+fileName+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, fileName))
+}
+
+// Load returns the Snapshot saved to dir, or nil without an error if there is
+// none.
+func Load(dir string) (*Snapshot, error) {
+	f, err := os.Open(filepath.Join(dir, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", f.Name(), err)
+	}
+	s := &Snapshot{}
+	if err := json.NewDecoder(zr).Decode(s); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", f.Name(), err)
+	}
+	return s, nil
+}
+
+// RestoreFromDir restores the Snapshot saved to dir into d, which must be
+// wrapped by r, returning whether it was restored. Snapshots older than
+// maxAge are not restored, unless maxAge is 0. Like RestoreFromLeader,
+// failures are logged rather than returned.
+func (r *Recorder) RestoreFromDir(ctx context.Context, dir string, maxAge time.Duration, d drivers.Driver) bool {
+	start := time.Now()
+	s, err := Load(dir)
+	if err != nil {
+		log.Error(err, "unable to load saved policy snapshot, ingesting policy from the API server")
+		return false
+	}
+	if s == nil {
+		log.Info("no saved policy snapshot, ingesting policy from the API server", "dir", dir)
+		return false
+	}
+	if s.Saved == nil {
+		log.Info("saved policy snapshot has no time, ingesting policy from the API server")
+		return false
+	}
+	if age := start.Sub(*s.Saved); maxAge != 0 && age > maxAge {
+		log.Info("saved policy snapshot is too old, ingesting policy from the API server", "age", age.String(), "maxAge", maxAge.String())
+		return false
+	}
+	if err := r.Restore(ctx, d, s); err != nil {
+		log.Error(err, "unable to restore saved policy snapshot, ingesting policy from the API server")
+		if err := r.Prune(ctx, d); err != nil {
+			log.Error(err, "unable to prune partially restored policy snapshot")
+		}
+		return false
+	}
+	log.Info("restored saved policy snapshot", "saved", s.Saved.String(), "moduleSets", len(s.ModuleSets), "dataEntries", len(s.Data), "duration", time.Since(start).String())
+	return true
+}
+
+var _ manager.Runnable = &saver{}
+
+// saver saves the Snapshot of a Recorder to a directory whenever it changed,
+// once satisfied returns true.
+type saver struct {
+	recorder  *Recorder
+	dir       string
+	satisfied func() bool
+	now       func() time.Time
+	// saved is whether the Snapshot was saved, and changes the change count
+	// of the Recorder when it last was.
+	saved   bool
+	changes uint64
+}
+
+// Saver returns a Runnable which saves the Snapshot of r to dir every 30
+// seconds if it changed, and when the manager stops. Nothing is saved until
+// satisfied returns true, as the state of r is incomplete until then.
+func (r *Recorder) Saver(dir string, satisfied func() bool) manager.Runnable {
+	return &saver{recorder: r, dir: dir, satisfied: satisfied, now: time.Now}
+}
+
+// Start implements manager.Runnable.
+func (s *saver) Start(ctx context.Context) error {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.save()
+			return nil
+		case <-ticker.C:
+			s.save()
+		}
+	}
+}
+
+// save saves the Snapshot if the policy has been ingested and changed since it
+// was last saved. Until what was restored has been pruned, it may hold policy
+// which is no longer in the cluster, and is not saved either. Failures are
+// logged, and the Snapshot is saved again next time.
+func (s *saver) save() {
+	if !s.satisfied() || s.recorder.Stale() != 0 {
+		return
+	}
+	changes := s.recorder.changeCount()
+	if s.saved && changes == s.changes {
+		return
+	}
+	snapshot := s.recorder.Snapshot()
+	now := s.now()
+	snapshot.Saved = &now
+	if err := Save(s.dir, snapshot); err != nil {
+		log.Error(err, "unable to save policy snapshot", "dir", s.dir)
+		return
+	}
+	s.saved = true
+	s.changes = changes
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every pod
+// saves its own Snapshot.
+func (s *saver) NeedLeaderElection() bool {
+	return false
+}
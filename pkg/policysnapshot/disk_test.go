@@ -0,0 +1,177 @@
+package policysnapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	if s, err := Load(dir); err != nil || s != nil {
+		t.Fatalf("got %v, %v loading from an empty directory, want nothing", s, err)
+	}
+
+	ctx := context.Background()
+	r := NewRecorder()
+	d := r.Wrap(newMapDriver())
+	if err := d.PutModules(ctx, "templates/a", []string{"package a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutData(ctx, "/constraints/a", map[string]interface{}{"kind": "K"}); err != nil {
+		t.Fatal(err)
+	}
+	want := r.Snapshot()
+	saved := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	want.Saved = &saved
+	if err := Save(dir, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Error(diff)
+	}
+	// Only the Snapshot is left in the directory.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != fileName {
+		t.Errorf("got %v in the directory, want only %s", entries, fileName)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, fileName), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("got no error loading a corrupt snapshot")
+	}
+}
+
+func TestRestoreFromDir(t *testing.T) {
+	ctx := context.Background()
+	leader := NewRecorder()
+	if err := leader.Wrap(newMapDriver()).PutModules(ctx, "templates/a", []string{"package a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		saved  *time.Time
+		maxAge time.Duration
+		want   bool
+	}{
+		{name: "recent", saved: timePtr(time.Now().Add(-time.Minute)), maxAge: time.Hour, want: true},
+		{name: "too old", saved: timePtr(time.Now().Add(-2 * time.Hour)), maxAge: time.Hour},
+		{name: "any age", saved: timePtr(time.Now().Add(-2 * time.Hour)), want: true},
+		{name: "no time"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := leader.Snapshot()
+			s.Saved = tc.saved
+			if err := Save(dir, s); err != nil {
+				t.Fatal(err)
+			}
+
+			r := NewRecorder()
+			inner := newMapDriver()
+			if got := r.RestoreFromDir(ctx, dir, tc.maxAge, r.Wrap(inner)); got != tc.want {
+				t.Fatalf("got restored %v, want %v", got, tc.want)
+			}
+			if got := len(inner.moduleSets) == 1; got != tc.want {
+				t.Errorf("got module sets %v", inner.moduleSets)
+			}
+		})
+	}
+
+	r := NewRecorder()
+	if r.RestoreFromDir(ctx, t.TempDir(), 0, r.Wrap(newMapDriver())) {
+		t.Error("got restored from an empty directory")
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestSaver(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	satisfied := false
+
+	r := NewRecorder()
+	d := r.Wrap(newMapDriver())
+	s := r.Saver(dir, func() bool { return satisfied }).(*saver)
+	s.now = func() time.Time { return now }
+
+	savedAt := func() *time.Time {
+		t.Helper()
+		snapshot, err := Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot == nil {
+			return nil
+		}
+		return snapshot.Saved
+	}
+
+	// Nothing is saved until the policy has been ingested.
+	s.save()
+	if got := savedAt(); got != nil {
+		t.Fatalf("got a snapshot saved at %v before the policy was ingested", got)
+	}
+
+	satisfied = true
+	s.save()
+	if got := savedAt(); got == nil || !got.Equal(now) {
+		t.Fatalf("got a snapshot saved at %v, want %v", got, now)
+	}
+
+	// An unchanged policy is not saved again.
+	now = now.Add(time.Minute)
+	s.save()
+	if got := savedAt(); !got.Equal(now.Add(-time.Minute)) {
+		t.Errorf("got an unchanged snapshot saved again at %v", got)
+	}
+
+	if err := d.PutModules(ctx, "templates/a", []string{"package a"}); err != nil {
+		t.Fatal(err)
+	}
+	s.save()
+	if got := savedAt(); !got.Equal(now) {
+		t.Errorf("got a snapshot saved at %v, want the changed policy saved at %v", got, now)
+	}
+
+	// Restored policy is not saved until it is pruned.
+	other := NewRecorder()
+	if err := other.Wrap(newMapDriver()).PutModules(ctx, "templates/b", []string{"package b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Restore(ctx, d, other.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	s.save()
+	if got := savedAt(); got.Equal(now) {
+		t.Error("got a snapshot saved before the restored policy was pruned")
+	}
+	if err := r.Prune(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	s.save()
+	if got := savedAt(); !got.Equal(now) {
+		t.Errorf("got a snapshot saved at %v, want the pruned policy saved at %v", got, now)
+	}
+}
@@ -0,0 +1,239 @@
+// Package policysnapshot lets a starting pod restore the policy of a running
+// replica, rather than becoming ready only once it has ingested every
+// ConstraintTemplate, constraint and replicated object from the API server.
+//
+// A Recorder keeps the Rego modules and data put into the constraint
+// framework. Each pod serves them as a Snapshot from its introspection
+// endpoint once it is ready. At startup, a pod fetches the Snapshot of the pod
+// leading the policy-snapshot subsystem and restores it before its
+// controllers start, and is ready as soon as it has. Pods may also save their
+// Snapshot to a directory, such as a mounted PersistentVolume, and restore it
+// from there when no other pod is running. The controllers then ingest the
+// current state as usual. Whatever was restored but not put again by the time
+// they have ingested everything is pruned.
+package policysnapshot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/gatekeeper/pkg/election"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Path is the introspection path serving the Snapshot of a pod.
+const Path = "/debug/policysnapshot"
+
+const fetchTimeout = time.Minute
+
+const (
+	// signatureHeader holds the base64-encoded signature of a served
+	// Snapshot, and certificateHeader the DER-encoded certificate of the key
+	// which signed it.
+	signatureHeader   = "Gatekeeper-Snapshot-Signature"
+	certificateHeader = "Gatekeeper-Snapshot-Certificate"
+)
+
+var (
+	// Enabled is whether pods serve and restore Snapshots.
+	Enabled = flag.Bool("restore-policy-snapshot", false, "(alpha) at startup, restore the compiled policy and replicated data of the pod leading the policy-snapshot subsystem, and become ready once it is restored rather than once everything is ingested from the API server. Requires --leader-elect=policy-snapshot and --introspection-addr reachable from other pods")
+
+	log = logf.Log.WithName("policy-snapshot")
+)
+
+// +kubebuilder:rbac:urls=/debug/policysnapshot,verbs=get
+
+// Validate returns an error if other pods cannot fetch the Snapshot of this
+// pod, this pod cannot find the pod to fetch its Snapshot from, or Dir is not
+// a directory.
+func Validate() error {
+	if *Dir != "" {
+		info, err := os.Stat(*Dir)
+		if err != nil {
+			return fmt.Errorf("--policy-snapshot-dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("--policy-snapshot-dir %s is not a directory", *Dir)
+		}
+	}
+	if !*Enabled {
+		return nil
+	}
+	if !election.IsElected(election.PolicySnapshot) {
+		return fmt.Errorf("--restore-policy-snapshot requires --leader-elect=%s", election.PolicySnapshot)
+	}
+	if *introspection.Addr == "" {
+		return errors.New("--restore-policy-snapshot requires --introspection-addr")
+	}
+	host, _, err := net.SplitHostPort(*introspection.Addr)
+	if err != nil {
+		return fmt.Errorf("parsing --introspection-addr: %w", err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return fmt.Errorf("--restore-policy-snapshot requires --introspection-addr to be reachable from other pods, not %s", host)
+	}
+	return nil
+}
+
+// Fetch returns the Snapshot served by the pod leading the policy-snapshot
+// subsystem, authenticated with the service account of cfg. The Snapshot is
+// fetched over https from a pod serving the webhook certificate set with
+// introspection.SetCerts, and its signature is verified against the same CA.
+// It returns nil without an error if there is no other pod to fetch it from.
+func Fetch(ctx context.Context, cfg *rest.Config) (*Snapshot, error) {
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	leader, err := election.Leader(ctx, client, election.PolicySnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("finding the leader: %w", err)
+	}
+	if leader == "" || leader == util.GetPodName() {
+		return nil, nil
+	}
+	pod, err := client.CoreV1().Pods(util.GetNamespace()).Get(ctx, leader, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting leader pod %s: %w", leader, err)
+	}
+	if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+		return nil, nil
+	}
+
+	_, port, err := net.SplitHostPort(*introspection.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing --introspection-addr: %w", err)
+	}
+	certs := introspection.GetCerts()
+	httpClient, err := certs.PeerClient(cfg, fetchTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return fetch(ctx, httpClient, certs, "https://"+net.JoinHostPort(pod.Status.PodIP, port)+Path)
+}
+
+// fetch returns the Snapshot served at url, once its signature is verified
+// with certs.
+func fetch(ctx context.Context, client *http.Client, certs introspection.Certs, url string) (*Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response from %s: %w", url, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(resp.Header.Get(signatureHeader))
+	if err != nil {
+		return nil, fmt.Errorf("decoding signature from %s: %w", url, err)
+	}
+	cert, err := base64.StdEncoding.DecodeString(resp.Header.Get(certificateHeader))
+	if err != nil {
+		return nil, fmt.Errorf("decoding certificate from %s: %w", url, err)
+	}
+	if err := certs.Verify(body, signature, cert); err != nil {
+		return nil, fmt.Errorf("verifying signature from %s: %w", url, err)
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(body, s); err != nil {
+		return nil, fmt.Errorf("decoding response from %s: %w", url, err)
+	}
+	return s, nil
+}
+
+// RestoreFromLeader fetches the Snapshot of the leader and restores it into
+// d, which must be wrapped by r, returning whether it was restored. Failures
+// are logged rather than returned, and anything partially restored is pruned,
+// so that the pod goes on to ingest its policy as if restoring were disabled.
+func (r *Recorder) RestoreFromLeader(ctx context.Context, cfg *rest.Config, d drivers.Driver) bool {
+	start := time.Now()
+	s, err := Fetch(ctx, cfg)
+	if err != nil {
+		log.Error(err, "unable to fetch policy snapshot, ingesting policy from the API server")
+		return false
+	}
+	if s == nil {
+		log.Info("no leader to fetch a policy snapshot from, ingesting policy from the API server")
+		return false
+	}
+	if err := r.Restore(ctx, d, s); err != nil {
+		log.Error(err, "unable to restore policy snapshot, ingesting policy from the API server")
+		if err := r.Prune(ctx, d); err != nil {
+			log.Error(err, "unable to prune partially restored policy snapshot")
+		}
+		return false
+	}
+	log.Info("restored policy snapshot", "moduleSets", len(s.ModuleSets), "dataEntries", len(s.Data), "duration", time.Since(start).String())
+	return true
+}
+
+const pruneInterval = time.Second
+
+var _ manager.Runnable = &pruner{}
+
+// pruner prunes restored state from a driver once satisfied returns true.
+type pruner struct {
+	recorder  *Recorder
+	driver    drivers.Driver
+	satisfied func() bool
+}
+
+// Pruner returns a Runnable which calls Prune on d once satisfied returns
+// true, meaning every template, constraint and replicated object has been
+// ingested.
+func (r *Recorder) Pruner(d drivers.Driver, satisfied func() bool) manager.Runnable {
+	return &pruner{recorder: r, driver: d, satisfied: satisfied}
+}
+
+// Start implements manager.Runnable.
+func (p *pruner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if !p.satisfied() {
+			continue
+		}
+		stale := p.recorder.Stale()
+		if err := p.recorder.Prune(ctx, p.driver); err != nil {
+			return fmt.Errorf("pruning restored policy: %w", err)
+		}
+		log.Info("pruned restored policy which is no longer in the cluster", "entries", stale)
+		return nil
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every pod
+// prunes what it restored.
+func (p *pruner) NeedLeaderElection() bool {
+	return false
+}
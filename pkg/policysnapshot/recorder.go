@@ -0,0 +1,379 @@
+package policysnapshot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	"github.com/open-policy-agent/gatekeeper/pkg/version"
+)
+
+// Snapshot is the state of a constraint framework driver: the Rego modules
+// compiled from ConstraintTemplates, and the constraints and replicated
+// objects put into its data.
+type Snapshot struct {
+	// Version identifies the build of Gatekeeper which took the Snapshot.
+	// Modules are generated by the constraint framework, so a Snapshot is
+	// only restored by the same build.
+	Version    string                     `json:"version"`
+	Modules    map[string]string          `json:"modules,omitempty"`
+	ModuleSets map[string][]string        `json:"moduleSets,omitempty"`
+	Data       map[string]json.RawMessage `json:"data,omitempty"`
+	// Saved is when the Snapshot was saved to disk, if it was.
+	Saved *time.Time `json:"saved,omitempty"`
+}
+
+func buildVersion() string {
+	return version.Version + "/" + version.Vcs
+}
+
+// restoringKey marks the context of changes made while restoring a Snapshot.
+type restoringKey struct{}
+
+// Recorder keeps the modules and data put into the drivers wrapped by it, so
+// they can be served as a Snapshot. State restored from another pod's
+// Snapshot is tracked until it is put again, and may then be pruned.
+type Recorder struct {
+	mux        sync.RWMutex
+	modules    map[string]string
+	moduleSets map[string][]string
+	data       map[string]json.RawMessage
+	// changes counts the changes recorded, so that unchanged state is not
+	// saved again.
+	changes uint64
+
+	// stale holds the modules, module sets and data restored from a Snapshot
+	// which have not been put since, keyed by kind and then name.
+	stale map[string]map[string]bool
+	// pruning is held for writing while pruning, so that nothing is put
+	// between being found stale and being deleted.
+	pruning sync.RWMutex
+}
+
+const (
+	moduleKind    = "module"
+	moduleSetKind = "moduleSet"
+	dataKind      = "data"
+)
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		modules:    make(map[string]string),
+		moduleSets: make(map[string][]string),
+		data:       make(map[string]json.RawMessage),
+		stale: map[string]map[string]bool{
+			moduleKind:    {},
+			moduleSetKind: {},
+			dataKind:      {},
+		},
+	}
+}
+
+// Wrap returns d, recording successful changes to its modules and data in r.
+func (r *Recorder) Wrap(d drivers.Driver) drivers.Driver {
+	return &recordingDriver{Driver: d, recorder: r}
+}
+
+// Snapshot returns the state recorded by r.
+func (r *Recorder) Snapshot() *Snapshot {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	s := &Snapshot{
+		Version:    buildVersion(),
+		Modules:    make(map[string]string, len(r.modules)),
+		ModuleSets: make(map[string][]string, len(r.moduleSets)),
+		Data:       make(map[string]json.RawMessage, len(r.data)),
+	}
+	for k, v := range r.modules {
+		s.Modules[k] = v
+	}
+	for k, v := range r.moduleSets {
+		s.ModuleSets[k] = v
+	}
+	for k, v := range r.data {
+		s.Data[k] = v
+	}
+	return s
+}
+
+// changeCount returns the number of changes recorded by r.
+func (r *Recorder) changeCount() uint64 {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return r.changes
+}
+
+// Handler serves the Snapshot of r as JSON once ready returns true. Until
+// then the state of r may be incomplete, and is not served. The Snapshot is
+// signed with the serving key of certs, so that it is only restored if it was
+// served by a Gatekeeper pod.
+func (r *Recorder) Handler(ready func() bool, certs introspection.Certs) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !ready() {
+			http.Error(w, "policy has not been fully ingested", http.StatusServiceUnavailable)
+			return
+		}
+		body, err := json.Marshal(r.Snapshot())
+		if err != nil {
+			log.Error(err, "encoding policy snapshot")
+			http.Error(w, "unable to encode policy snapshot", http.StatusInternalServerError)
+			return
+		}
+		signature, cert, err := certs.Sign(body)
+		if err != nil {
+			log.Error(err, "signing policy snapshot")
+			http.Error(w, "unable to sign policy snapshot", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(signatureHeader, base64.StdEncoding.EncodeToString(signature))
+		w.Header().Set(certificateHeader, base64.StdEncoding.EncodeToString(cert))
+		if _, err := w.Write(body); err != nil {
+			log.Error(err, "writing policy snapshot")
+		}
+	})
+}
+
+// Restore puts the modules and data of s into d, which must be wrapped by r.
+// Everything restored is pruned by Prune unless it is put again first. If an
+// error is returned, part of s may have been restored.
+func (r *Recorder) Restore(ctx context.Context, d drivers.Driver, s *Snapshot) error {
+	if s.Version != buildVersion() {
+		return fmt.Errorf("snapshot was taken by Gatekeeper %q, not %q", s.Version, buildVersion())
+	}
+	ctx = context.WithValue(ctx, restoringKey{}, true)
+
+	for _, path := range sortedKeys(s.Data) {
+		var v interface{}
+		if err := json.Unmarshal(s.Data[path], &v); err != nil {
+			return fmt.Errorf("decoding data at %s: %w", path, err)
+		}
+		if err := d.PutData(ctx, path, v); err != nil {
+			return fmt.Errorf("restoring data at %s: %w", path, err)
+		}
+	}
+	for _, name := range sortedKeys(s.Modules) {
+		if err := d.PutModule(ctx, name, s.Modules[name]); err != nil {
+			return fmt.Errorf("restoring module %s: %w", name, err)
+		}
+	}
+	for _, prefix := range sortedKeys(s.ModuleSets) {
+		if err := d.PutModules(ctx, prefix, s.ModuleSets[prefix]); err != nil {
+			return fmt.Errorf("restoring module set %s: %w", prefix, err)
+		}
+	}
+	return nil
+}
+
+// Stale returns the number of modules, module sets and data documents which
+// were restored and have not been put since.
+func (r *Recorder) Stale() int {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	n := 0
+	for _, names := range r.stale {
+		n += len(names)
+	}
+	return n
+}
+
+// Prune deletes everything from d which was restored and has not been put
+// since. It is called once every template, constraint and replicated object
+// has been ingested, so that what remains is no longer in the cluster.
+func (r *Recorder) Prune(ctx context.Context, d drivers.Driver) error {
+	r.pruning.Lock()
+	defer r.pruning.Unlock()
+	for _, kind := range []string{moduleSetKind, moduleKind, dataKind} {
+		for _, name := range r.staleNames(kind) {
+			var err error
+			switch kind {
+			case moduleSetKind:
+				_, err = d.DeleteModules(ctx, name)
+			case moduleKind:
+				_, err = d.DeleteModule(ctx, name)
+			case dataKind:
+				_, err = d.DeleteData(ctx, name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) staleNames(kind string) []string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	var names []string
+	for name := range r.stale[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// put records that name of the given kind was put, marking it stale if it
+// was restored. Restoring what this pod had already put itself, such as the
+// modules every client starts with, leaves it as it was. Must be called while
+// holding r.mux.
+func (r *Recorder) put(ctx context.Context, kind, name string, existed bool) {
+	if restoring, _ := ctx.Value(restoringKey{}).(bool); !restoring {
+		delete(r.stale[kind], name)
+	} else if !existed {
+		r.stale[kind][name] = true
+	}
+}
+
+// deleteData forgets path and every path below it. Must be called while
+// holding r.mux.
+func (r *Recorder) deleteData(path string) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for p := range r.data {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(r.data, p)
+			delete(r.stale[dataKind], p)
+		}
+	}
+}
+
+type recordingDriver struct {
+	drivers.Driver
+	recorder *Recorder
+}
+
+var _ drivers.Driver = &recordingDriver{}
+
+func (d *recordingDriver) PutModule(ctx context.Context, name string, src string) error {
+	d.recorder.pruning.RLock()
+	defer d.recorder.pruning.RUnlock()
+	if err := d.Driver.PutModule(ctx, name, src); err != nil {
+		return err
+	}
+	r := d.recorder
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.changes++
+	_, existed := r.modules[name]
+	r.modules[name] = src
+	r.put(ctx, moduleKind, name, existed)
+	return nil
+}
+
+func (d *recordingDriver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	d.recorder.pruning.RLock()
+	defer d.recorder.pruning.RUnlock()
+	if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+		return err
+	}
+	r := d.recorder
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.changes++
+	if len(srcs) == 0 {
+		delete(r.moduleSets, namePrefix)
+		delete(r.stale[moduleSetKind], namePrefix)
+		return nil
+	}
+	_, existed := r.moduleSets[namePrefix]
+	r.moduleSets[namePrefix] = append([]string(nil), srcs...)
+	r.put(ctx, moduleSetKind, namePrefix, existed)
+	return nil
+}
+
+func (d *recordingDriver) DeleteModule(ctx context.Context, name string) (bool, error) {
+	deleted, err := d.Driver.DeleteModule(ctx, name)
+	if err != nil {
+		return deleted, err
+	}
+	r := d.recorder
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.changes++
+	delete(r.modules, name)
+	delete(r.stale[moduleKind], name)
+	return deleted, nil
+}
+
+func (d *recordingDriver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	n, err := d.Driver.DeleteModules(ctx, namePrefix)
+	if err != nil {
+		return n, err
+	}
+	r := d.recorder
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.changes++
+	delete(r.moduleSets, namePrefix)
+	delete(r.stale[moduleSetKind], namePrefix)
+	return n, nil
+}
+
+func (d *recordingDriver) PutData(ctx context.Context, path string, data interface{}) error {
+	d.recorder.pruning.RLock()
+	defer d.recorder.pruning.RUnlock()
+	// The data is encoded before it is put, as the caller may go on to
+	// modify it.
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := d.Driver.PutData(ctx, path, data); err != nil {
+		return err
+	}
+	r := d.recorder
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.changes++
+	_, existed := r.data[path]
+	r.data[path] = b
+	r.put(ctx, dataKind, path, existed)
+	return nil
+}
+
+func (d *recordingDriver) DeleteData(ctx context.Context, path string) (bool, error) {
+	deleted, err := d.Driver.DeleteData(ctx, path)
+	if err != nil {
+		return deleted, err
+	}
+	r := d.recorder
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.changes++
+	r.deleteData(path)
+	return deleted, nil
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]string:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string][]string:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]json.RawMessage:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,346 @@
+package policysnapshot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+)
+
+// mapDriver holds the modules and data put into it.
+type mapDriver struct {
+	drivers.Driver
+	modules    map[string]string
+	moduleSets map[string][]string
+	data       map[string]interface{}
+}
+
+func newMapDriver() *mapDriver {
+	return &mapDriver{
+		modules:    make(map[string]string),
+		moduleSets: make(map[string][]string),
+		data:       make(map[string]interface{}),
+	}
+}
+
+func (d *mapDriver) PutModule(_ context.Context, name string, src string) error {
+	d.modules[name] = src
+	return nil
+}
+
+func (d *mapDriver) PutModules(_ context.Context, prefix string, srcs []string) error {
+	d.moduleSets[prefix] = srcs
+	return nil
+}
+
+func (d *mapDriver) DeleteModule(_ context.Context, name string) (bool, error) {
+	delete(d.modules, name)
+	return true, nil
+}
+
+func (d *mapDriver) DeleteModules(_ context.Context, prefix string) (int, error) {
+	delete(d.moduleSets, prefix)
+	return 1, nil
+}
+
+func (d *mapDriver) PutData(_ context.Context, path string, data interface{}) error {
+	d.data[path] = data
+	return nil
+}
+
+func (d *mapDriver) DeleteData(_ context.Context, path string) (bool, error) {
+	for p := range d.data {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(d.data, p)
+		}
+	}
+	return true, nil
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	d := r.Wrap(newMapDriver())
+
+	steps := []func() error{
+		func() error { return d.PutModule(ctx, "hooks", "package hooks") },
+		func() error { return d.PutModules(ctx, "templates/a", []string{"package a"}) },
+		func() error { return d.PutModules(ctx, "templates/b", []string{"package b"}) },
+		func() error {
+			return d.PutData(ctx, "/external/t/namespace/ns/v1/Pod/a", map[string]interface{}{"kind": "Pod"})
+		},
+		func() error {
+			return d.PutData(ctx, "/external/t/namespace/ns/v1/Pod/b", map[string]interface{}{"kind": "Pod"})
+		},
+		func() error { return d.PutData(ctx, "/constraints/t/cluster/k/c", map[string]interface{}{"kind": "K"}) },
+		func() error { _, err := d.DeleteModules(ctx, "templates/b"); return err },
+		func() error { _, err := d.DeleteData(ctx, "/external/t/namespace/ns/v1/Pod/b"); return err },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	got := r.Snapshot()
+	if got.Version != buildVersion() {
+		t.Errorf("got version %q, want %q", got.Version, buildVersion())
+	}
+	if diff := cmp.Diff(map[string]string{"hooks": "package hooks"}, got.Modules); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(map[string][]string{"templates/a": {"package a"}}, got.ModuleSets); diff != "" {
+		t.Error(diff)
+	}
+	wantData := map[string]string{
+		"/external/t/namespace/ns/v1/Pod/a": `{"kind":"Pod"}`,
+		"/constraints/t/cluster/k/c":        `{"kind":"K"}`,
+	}
+	gotData := make(map[string]string)
+	for path, v := range got.Data {
+		gotData[path] = string(v)
+	}
+	if diff := cmp.Diff(wantData, gotData); diff != "" {
+		t.Error(diff)
+	}
+
+	// Resetting the client deletes everything below a target.
+	if _, err := d.DeleteData(ctx, "/external/t"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Snapshot().Data["/external/t/namespace/ns/v1/Pod/a"]; ok {
+		t.Error("got data below a deleted path")
+	}
+}
+
+func TestRestoreAndPrune(t *testing.T) {
+	ctx := context.Background()
+	leader := NewRecorder()
+	ld := leader.Wrap(newMapDriver())
+	for _, prefix := range []string{"templates/a", "templates/b"} {
+		if err := ld.PutModules(ctx, prefix, []string{"package " + prefix}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{"/constraints/a", "/constraints/b"} {
+		if err := ld.PutData(ctx, path, map[string]interface{}{"path": path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ld.PutModule(ctx, "hooks", "package hooks"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRecorder()
+	inner := newMapDriver()
+	d := r.Wrap(inner)
+	// Every client puts its hooks before anything is restored.
+	if err := d.PutModule(ctx, "hooks", "package hooks"); err != nil {
+		t.Fatal(err)
+	}
+
+	s := leader.Snapshot()
+	if err := r.Restore(ctx, d, s); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.moduleSets) != 2 || len(inner.data) != 2 {
+		t.Fatalf("got module sets %v and data %v, want both restored", inner.moduleSets, inner.data)
+	}
+	if got := inner.data["/constraints/a"].(map[string]interface{})["path"]; got != "/constraints/a" {
+		t.Errorf("got restored data %v, want the data of the snapshot", got)
+	}
+	if got := r.Stale(); got != 4 {
+		t.Errorf("got %d stale entries, want the 4 restored", got)
+	}
+
+	// Only template a and constraint a are still in the cluster.
+	if err := d.PutModules(ctx, "templates/a", []string{"package templates/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutData(ctx, "/constraints/a", map[string]interface{}{"path": "/constraints/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Prune(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(map[string][]string{"templates/a": {"package templates/a"}}, inner.moduleSets); diff != "" {
+		t.Error(diff)
+	}
+	if _, ok := inner.data["/constraints/b"]; ok || len(inner.data) != 1 {
+		t.Errorf("got data %v, want only /constraints/a", inner.data)
+	}
+	if _, ok := inner.modules["hooks"]; !ok {
+		t.Error("got hooks pruned, though they were put before restoring")
+	}
+	if got := r.Stale(); got != 0 {
+		t.Errorf("got %d stale entries after pruning", got)
+	}
+
+	s.Version = "other"
+	if err := r.Restore(ctx, d, s); err == nil {
+		t.Error("got no error restoring the snapshot of another build")
+	}
+}
+
+// newTestCerts returns Certs holding a serving certificate for the webhook
+// service and the CA issuing it, as written by the certificate rotator.
+func newTestCerts(t *testing.T) introspection.Certs {
+	t.Helper()
+	const serverName = "gatekeeper-webhook-service.gatekeeper-system.svc"
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gatekeeper-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{serverName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caTmpl, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"ca.crt":  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		"tls.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"tls.key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return introspection.NewCerts(dir, serverName)
+}
+
+func TestHandlerAndFetch(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	if err := r.Wrap(newMapDriver()).PutModules(ctx, "templates/a", []string{"package a"}); err != nil {
+		t.Fatal(err)
+	}
+	certs := newTestCerts(t)
+
+	var ready int32
+	srv := httptest.NewServer(r.Handler(func() bool { return atomic.LoadInt32(&ready) == 1 }, certs))
+	defer srv.Close()
+
+	if _, err := fetch(ctx, srv.Client(), certs, srv.URL); err == nil {
+		t.Error("got a snapshot before the policy was ingested")
+	}
+
+	atomic.StoreInt32(&ready, 1)
+	got, err := fetch(ctx, srv.Client(), certs, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(r.Snapshot(), got, cmpopts.EquateEmpty()); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := fetch(ctx, srv.Client(), newTestCerts(t), srv.URL); err == nil {
+		t.Error("got a snapshot signed by a key issued by another CA")
+	}
+
+	resp, err := srv.Client().Post(srv.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d for POST, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestFetchUnverified(t *testing.T) {
+	ctx := context.Background()
+	r := NewRecorder()
+	if err := r.Wrap(newMapDriver()).PutModules(ctx, "templates/a", []string{"package a"}); err != nil {
+		t.Fatal(err)
+	}
+	certs := newTestCerts(t)
+	signed := r.Handler(func() bool { return true }, certs)
+
+	tcs := []struct {
+		name   string
+		modify func(h http.Header, body []byte) []byte
+	}{
+		{
+			name: "unsigned",
+			modify: func(h http.Header, body []byte) []byte {
+				h.Del(signatureHeader)
+				h.Del(certificateHeader)
+				return body
+			},
+		},
+		{
+			name: "tampered",
+			modify: func(h http.Header, body []byte) []byte {
+				return []byte(strings.Replace(string(body), "package a", "package b", 1))
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				rec := httptest.NewRecorder()
+				signed.ServeHTTP(rec, req)
+				body := tc.modify(rec.Header(), rec.Body.Bytes())
+				for k, v := range rec.Header() {
+					w.Header()[k] = v
+				}
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+
+			if _, err := fetch(ctx, srv.Client(), certs, srv.URL); err == nil {
+				t.Error("got a snapshot which failed verification")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package prune
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+)
+
+// templatePath matches the prefix the constraint framework puts the modules of
+// a template under.
+var templatePath = regexp.MustCompile(`^templates\["[^"]+"\]\["[^"]+"\]$`)
+
+func templatePrefix(target, kind string) string {
+	return fmt.Sprintf(`templates["%s"]["%s"]`, target, kind)
+}
+
+// Wrap returns d, inferring the paths read by the templates whose modules are
+// put into it. Templates are tracked this way, rather than by the
+// ConstraintTemplate controller, so that templates restored from a snapshot
+// are accounted for.
+func (p *Pruner) Wrap(d drivers.Driver) drivers.Driver {
+	return &pruningDriver{Driver: d, pruner: p}
+}
+
+type pruningDriver struct {
+	drivers.Driver
+	pruner *Pruner
+}
+
+func (d *pruningDriver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	if err := d.Driver.PutModules(ctx, namePrefix, srcs); err != nil {
+		return err
+	}
+	if !templatePath.MatchString(namePrefix) {
+		return nil
+	}
+	if len(srcs) == 0 {
+		// Putting no modules deletes those under namePrefix.
+		d.pruner.deleteModules(namePrefix)
+		return nil
+	}
+	d.pruner.putModules(namePrefix, srcs)
+	return nil
+}
+
+func (d *pruningDriver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	n, err := d.Driver.DeleteModules(ctx, namePrefix)
+	if err != nil {
+		return n, err
+	}
+	if templatePath.MatchString(namePrefix) {
+		d.pruner.deleteModules(namePrefix)
+	}
+	return n, nil
+}
@@ -0,0 +1,360 @@
+// Package prune removes the fields of reviewed objects which no
+// ConstraintTemplate reads, so that the webhook converts and evaluates less of
+// very large objects.
+//
+// The paths of the object a template reads are inferred from the references
+// its Rego makes to input.review.object and input.review.oldObject, or
+// declared with the Annotation. The constraint framework reviews an object
+// with every template in a single query, so objects are pruned to the union
+// of the paths read by every template. apiVersion, kind and metadata are
+// always kept, as constraints are matched against them. If any template may
+// read the whole object, such as by assigning input.review.object to a
+// variable, objects are not pruned.
+package prune
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/opa/ast"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// Annotation is set on a ConstraintTemplate to the comma-separated paths of
+// the reviewed object its Rego reads, such as "spec.containers,spec.volumes",
+// if they cannot be inferred.
+const Annotation = "review.gatekeeper.sh/object-paths"
+
+// Enabled is whether the webhook prunes the objects it reviews.
+var Enabled = flag.Bool("prune-review-objects", false, "(alpha) remove the fields of the objects reviewed by the webhook which no ConstraintTemplate reads. The paths a template reads are inferred from its Rego, or declared with the "+Annotation+" annotation")
+
+// alwaysKept are the paths of every object which are never pruned.
+var alwaysKept = [][]string{{"apiVersion"}, {"kind"}, {"metadata"}}
+
+var (
+	inputRef   = ast.InputRootRef
+	reviewTerm = ast.StringTerm("review")
+	objectKeys = map[string]bool{"object": true, "oldObject": true}
+)
+
+// Usage is what a template reads of the reviewed object.
+type Usage struct {
+	// Paths are the paths of the object read.
+	Paths [][]string
+	// Whole is whether the whole object may be read.
+	Whole bool
+}
+
+// Infer returns what the Rego modules srcs read of the reviewed object.
+func Infer(srcs []string) (Usage, error) {
+	var u Usage
+	for i, src := range srcs {
+		m, err := ast.ParseModule(fmt.Sprintf("module[%d]", i), src)
+		if err != nil {
+			return Usage{}, err
+		}
+		if m == nil {
+			continue
+		}
+
+		aliases := map[ast.Var]ast.Ref{ast.InputRootDocument.Value.(ast.Var): inputRef}
+		for _, imp := range m.Imports {
+			path, ok := imp.Path.Value.(ast.Ref)
+			if !ok || !path.HasPrefix(inputRef) {
+				continue
+			}
+			alias := imp.Alias
+			if alias == "" {
+				alias = ast.Var(strings.Trim(path[len(path)-1].String(), `"`))
+			}
+			aliases[alias] = path
+		}
+
+		var vis *ast.GenericVisitor
+		vis = ast.NewGenericVisitor(func(x interface{}) bool {
+			switch v := x.(type) {
+			case ast.Ref:
+				u.addRef(v, aliases)
+				// The head of v has been accounted for, but the terms of v may
+				// themselves refer to the input.
+				for _, t := range v[1:] {
+					vis.Walk(t)
+				}
+				return true
+			case ast.Var:
+				// A bare reference to the input, or to an import of it.
+				if _, ok := aliases[v]; ok {
+					u.Whole = true
+				}
+			}
+			return false
+		})
+		for _, rule := range m.Rules {
+			vis.Walk(rule)
+		}
+	}
+	return u, nil
+}
+
+// addRef records what ref reads of the reviewed object.
+func (u *Usage) addRef(ref ast.Ref, aliases map[ast.Var]ast.Ref) {
+	head, ok := ref[0].Value.(ast.Var)
+	if !ok {
+		return
+	}
+	path, ok := aliases[head]
+	if !ok {
+		return
+	}
+	ref = path.Concat(ref[1:])
+
+	// ref is input...
+	if len(ref) < 2 {
+		u.Whole = true
+		return
+	}
+	if !ref[1].Equal(reviewTerm) {
+		if _, ok := ref[1].Value.(ast.String); !ok {
+			// input[x] may be the review.
+			u.Whole = true
+		}
+		return
+	}
+	// ref is input.review...
+	if len(ref) < 3 {
+		u.Whole = true
+		return
+	}
+	key, ok := ref[2].Value.(ast.String)
+	if !ok {
+		u.Whole = true
+		return
+	}
+	if !objectKeys[string(key)] {
+		return
+	}
+	// ref is input.review.object...
+	var fields []string
+	for _, t := range ref[3:] {
+		s, ok := t.Value.(ast.String)
+		if !ok {
+			break
+		}
+		fields = append(fields, string(s))
+	}
+	if len(fields) == 0 {
+		u.Whole = true
+		return
+	}
+	u.Paths = append(u.Paths, fields)
+}
+
+// ParsePaths parses the comma-separated, dot-separated paths of the
+// Annotation.
+func ParsePaths(s string) ([][]string, error) {
+	var paths [][]string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		fields := strings.Split(p, ".")
+		for _, f := range fields {
+			if f == "" {
+				return nil, fmt.Errorf("invalid path %q", p)
+			}
+		}
+		paths = append(paths, fields)
+	}
+	return paths, nil
+}
+
+// Validate returns an error if the Annotation of templ is invalid.
+func Validate(templ *templates.ConstraintTemplate) error {
+	s, ok := templ.GetAnnotations()[Annotation]
+	if !ok {
+		return nil
+	}
+	if _, err := ParsePaths(s); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", Annotation, err)
+	}
+	return nil
+}
+
+// node is a tree of the paths which are kept.
+type node struct {
+	// all is whether everything below the node is kept.
+	all      bool
+	children map[string]*node
+}
+
+func (n *node) add(path []string) {
+	if n.all {
+		return
+	}
+	if len(path) == 0 {
+		n.all = true
+		n.children = nil
+		return
+	}
+	if n.children == nil {
+		n.children = make(map[string]*node)
+	}
+	c, ok := n.children[path[0]]
+	if !ok {
+		c = &node{}
+		n.children[path[0]] = c
+	}
+	c.add(path[1:])
+}
+
+// prune returns raw without the fields below n which are not kept. Values
+// which are not JSON objects are kept whole.
+func (n *node) prune(raw json.RawMessage) (json.RawMessage, error) {
+	if n.all {
+		return raw, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return raw, nil
+	}
+	kept := make(map[string]json.RawMessage, len(n.children))
+	for key, child := range n.children {
+		v, ok := fields[key]
+		if !ok {
+			continue
+		}
+		p, err := child.prune(v)
+		if err != nil {
+			return nil, err
+		}
+		kept[key] = p
+	}
+	return json.Marshal(kept)
+}
+
+// Pruner prunes objects to the paths read by the templates whose modules
+// pass through the drivers it wraps.
+type Pruner struct {
+	mux sync.RWMutex
+	// inferred are the usages inferred from the modules of each template,
+	// keyed by the prefix of their modules.
+	inferred map[string]Usage
+	// declared are the paths declared by the Annotation of templates, keyed
+	// by the prefix of their modules.
+	declared map[string][][]string
+	// tree holds the kept paths, or is nil if objects are not pruned.
+	tree *node
+}
+
+var pruner = NewPruner()
+
+// Get returns the Pruner of this process.
+func Get() *Pruner {
+	return pruner
+}
+
+// NewPruner returns a Pruner without templates.
+func NewPruner() *Pruner {
+	p := &Pruner{
+		inferred: make(map[string]Usage),
+		declared: make(map[string][][]string),
+	}
+	p.rebuildLocked()
+	return p
+}
+
+// Declare records the paths declared by the Annotation of templ, which
+// replace those inferred from its Rego. An invalid Annotation is ignored.
+func (p *Pruner) Declare(templ *templates.ConstraintTemplate) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	kind := templ.Spec.CRD.Spec.Names.Kind
+	s, ok := templ.GetAnnotations()[Annotation]
+	paths, err := ParsePaths(s)
+	for _, t := range templ.Spec.Targets {
+		prefix := templatePrefix(t.Target, kind)
+		if ok && err == nil {
+			p.declared[prefix] = paths
+		} else {
+			delete(p.declared, prefix)
+		}
+	}
+	p.rebuildLocked()
+}
+
+// PruneRequest returns a copy of req whose object and old object are pruned,
+// or req if objects are not pruned.
+func (p *Pruner) PruneRequest(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionRequest, error) {
+	p.mux.RLock()
+	tree := p.tree
+	p.mux.RUnlock()
+	if tree == nil {
+		return req, nil
+	}
+
+	pruned := *req
+	if len(req.Object.Raw) > 0 {
+		raw, err := tree.prune(req.Object.Raw)
+		if err != nil {
+			return nil, err
+		}
+		pruned.Object.Raw = raw
+		pruned.Object.Object = nil
+	}
+	if len(req.OldObject.Raw) > 0 {
+		raw, err := tree.prune(req.OldObject.Raw)
+		if err != nil {
+			return nil, err
+		}
+		pruned.OldObject.Raw = raw
+		pruned.OldObject.Object = nil
+	}
+	return &pruned, nil
+}
+
+// rebuildLocked rebuilds the tree of kept paths. Must be called while holding
+// p.mux.
+func (p *Pruner) rebuildLocked() {
+	tree := &node{}
+	for _, path := range alwaysKept {
+		tree.add(path)
+	}
+	for prefix, u := range p.inferred {
+		if paths, ok := p.declared[prefix]; ok {
+			u = Usage{Paths: paths}
+		}
+		if u.Whole {
+			p.tree = nil
+			return
+		}
+		for _, path := range u.Paths {
+			tree.add(path)
+		}
+	}
+	p.tree = tree
+}
+
+func (p *Pruner) putModules(prefix string, srcs []string) {
+	u, err := Infer(srcs)
+	if err != nil {
+		// The Rego driver rejects modules which do not parse.
+		u = Usage{Whole: true}
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.inferred[prefix] = u
+	p.rebuildLocked()
+}
+
+func (p *Pruner) deleteModules(prefix string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	delete(p.inferred, prefix)
+	delete(p.declared, prefix)
+	p.rebuildLocked()
+}
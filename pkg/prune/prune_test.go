@@ -0,0 +1,232 @@
+package prune
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const target = "admission.k8s.gatekeeper.sh"
+
+func TestInfer(t *testing.T) {
+	tcs := []struct {
+		name string
+		srcs []string
+		want Usage
+	}{
+		{
+			name: "object paths",
+			srcs: []string{`package foo
+violation[{"msg": msg}] {
+	c := input.review.object.spec.containers[_]
+	not input.review.oldObject.status
+	input.parameters.labels[_] == c.name
+	msg := "bad"
+}`},
+			want: Usage{Paths: [][]string{{"spec", "containers"}, {"status"}}},
+		},
+		{
+			name: "paths of other fields of the review",
+			srcs: []string{`package foo
+violation[{"msg": input.review.operation}] { input.review.userInfo.username == "admin" }`},
+			want: Usage{},
+		},
+		{
+			name: "bracketed paths",
+			srcs: []string{`package foo
+violation[{"msg": "bad"}] { input["review"]["object"]["spec"].replicas > 3 }`},
+			want: Usage{Paths: [][]string{{"spec", "replicas"}}},
+		},
+		{
+			name: "imported object",
+			srcs: []string{`package foo
+import input.review.object as obj
+violation[{"msg": "bad"}] { obj.spec.hostNetwork }`},
+			want: Usage{Paths: [][]string{{"spec", "hostNetwork"}}},
+		},
+		{
+			name: "variable key",
+			srcs: []string{`package foo
+violation[{"msg": "bad"}] { input.review.object[k].x }`},
+			want: Usage{Whole: true},
+		},
+		{
+			name: "assigned object",
+			srcs: []string{`package foo
+violation[{"msg": "bad"}] { obj := input.review.object; obj.spec.hostNetwork }`},
+			want: Usage{Whole: true},
+		},
+		{
+			name: "bare input",
+			srcs: []string{`package foo
+violation[{"msg": "bad"}] { has_field(input, "review") }`},
+			want: Usage{Whole: true},
+		},
+		{
+			name: "imported review",
+			srcs: []string{`package foo
+import input.review
+violation[{"msg": "bad"}] { x := review }`},
+			want: Usage{Whole: true},
+		},
+		{
+			name: "library",
+			srcs: []string{
+				`package foo
+violation[{"msg": "bad"}] { data.lib.privileged }`,
+				`package lib
+privileged { input.review.object.spec.containers[_].securityContext.privileged }`,
+			},
+			want: Usage{Paths: [][]string{{"spec", "containers"}}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Infer(tc.srcs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	got, err := ParsePaths("spec.containers, spec.volumes,,status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"spec", "containers"}, {"spec", "volumes"}, {"status"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+	if _, err := ParsePaths("spec..containers"); err == nil {
+		t.Error("got no error parsing an empty field")
+	}
+}
+
+func newTemplate(kind string, annotations map[string]string) *templates.ConstraintTemplate {
+	templ := &templates.ConstraintTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: kind, Annotations: annotations},
+	}
+	templ.Spec.CRD.Spec.Names.Kind = kind
+	templ.Spec.Targets = []templates.Target{{Target: target}}
+	return templ
+}
+
+func newRequest(t *testing.T, obj map[string]interface{}) *admissionv1.AdmissionRequest {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &admissionv1.AdmissionRequest{Object: runtime.RawExtension{Raw: raw}}
+}
+
+func prunedObject(t *testing.T, p *Pruner, req *admissionv1.AdmissionRequest) map[string]interface{} {
+	pruned, err := p.PruneRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(pruned.Object.Raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestPruner(t *testing.T) {
+	ctx := context.Background()
+	p := NewPruner()
+	d := p.Wrap(local.New())
+	if err := d.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newRequest(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pod"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "c"}},
+			"volumes":    []interface{}{map[string]interface{}{"name": "v"}},
+		},
+		"status": map[string]interface{}{"phase": "Running"},
+	})
+
+	put := func(kind, src string) {
+		if err := d.PutModules(ctx, templatePrefix(target, kind), []string{src}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("Containers", `package containers
+violation[{"msg": c.name}] { c := input.review.object.spec.containers[_] }`)
+
+	want := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pod"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "c"}},
+		},
+	}
+	if diff := cmp.Diff(want, prunedObject(t, p, req)); diff != "" {
+		t.Error(diff)
+	}
+
+	// The annotation replaces the paths inferred from the Rego.
+	p.Declare(newTemplate("Containers", map[string]string{Annotation: "status.phase"}))
+	want = map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pod"},
+		"status":     map[string]interface{}{"phase": "Running"},
+	}
+	if diff := cmp.Diff(want, prunedObject(t, p, req)); diff != "" {
+		t.Error(diff)
+	}
+	p.Declare(newTemplate("Containers", nil))
+
+	// Objects are not pruned while any template reads the whole object.
+	put("Whole", `package whole
+violation[{"msg": "bad"}] { obj := input.review.object; obj.spec }`)
+	pruned, err := p.PruneRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != req {
+		t.Error("got the object pruned while a template reads the whole object")
+	}
+
+	if _, err := d.DeleteModules(ctx, templatePrefix(target, "Whole")); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pod"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "c"}},
+		},
+	}
+	if diff := cmp.Diff(want, prunedObject(t, p, req)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(newTemplate("Foo", map[string]string{Annotation: "spec.containers"})); err != nil {
+		t.Error(err)
+	}
+	if err := Validate(newTemplate("Foo", map[string]string{Annotation: "spec."})); err == nil {
+		t.Error("got no error validating an invalid annotation")
+	}
+}
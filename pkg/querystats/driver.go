@@ -0,0 +1,57 @@
+package querystats
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("query-stats")
+
+// reviewPath matches the path the constraint framework queries to review an
+// object.
+var reviewPath = regexp.MustCompile(`^hooks\["([^"]+)"\]\.violation$`)
+
+// Wrap returns d, recording the queries it evaluates with a context carrying
+// a Recorder. Queries evaluated with other contexts are passed through.
+func Wrap(d drivers.Driver) drivers.Driver {
+	return &statsDriver{Driver: d}
+}
+
+type statsDriver struct {
+	drivers.Driver
+}
+
+var _ drivers.Driver = &statsDriver{}
+
+func (d *statsDriver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	r := fromContext(ctx)
+	if r == nil {
+		return d.Driver.Query(ctx, path, input, opts...)
+	}
+
+	start := time.Now()
+	resp, err := d.Driver.Query(ctx, path, input, opts...)
+	r.add(Stats{Queries: 1, EvalDuration: time.Since(start)})
+	if err != nil {
+		return resp, err
+	}
+
+	if match := reviewPath.FindStringSubmatch(path); match != nil {
+		// The constraints matching the object are not part of the response,
+		// so they are queried separately. This query is not counted, so the
+		// Stats reflect the cost of the review itself.
+		matched, err := d.Driver.Query(ctx, fmt.Sprintf(`hooks["%s"].library.matching_constraints`, match[1]), input)
+		if err != nil {
+			log.V(1).Info("unable to count the constraints matching a review", "error", err.Error())
+		} else {
+			r.add(Stats{ConstraintsMatched: len(matched.Results)})
+		}
+	}
+	return resp, nil
+}
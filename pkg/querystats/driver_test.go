@@ -0,0 +1,103 @@
+package querystats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+)
+
+// fakeDriver answers reviews with one violation and matches two constraints.
+type fakeDriver struct {
+	drivers.Driver
+	queried []string
+}
+
+func (d *fakeDriver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	d.queried = append(d.queried, path)
+	CountExternalDataCall(ctx)
+	switch path {
+	case `hooks["target"].violation`:
+		return &types.Response{Results: []*types.Result{{Msg: "denied"}}}, nil
+	case `hooks["target"].library.matching_constraints`:
+		return &types.Response{Results: []*types.Result{{}, {}}}, nil
+	}
+	return nil, errors.New("unknown query")
+}
+
+func TestDriver_Query(t *testing.T) {
+	fake := &fakeDriver{}
+	d := Wrap(fake)
+
+	ctx, stats := NewContext(context.Background())
+	resp, err := d.Query(ctx, `hooks["target"].violation`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 {
+		t.Errorf("got %d results, want the results of the review", len(resp.Results))
+	}
+
+	got := stats.Stats()
+	if got.EvalDuration <= 0 {
+		t.Errorf("got eval duration %v, want it recorded", got.EvalDuration)
+	}
+	got.EvalDuration = 0
+	want := Stats{Queries: 1, ConstraintsMatched: 2, ExternalDataCalls: 2}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDriver_Query_NotRecorded(t *testing.T) {
+	fake := &fakeDriver{}
+	d := Wrap(fake)
+	if _, err := d.Query(context.Background(), `hooks["target"].violation`, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Matching constraints are only counted for reviews which are recorded.
+	if diff := cmp.Diff([]string{`hooks["target"].violation`}, fake.queried); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDriver_Query_Error(t *testing.T) {
+	d := Wrap(&fakeDriver{})
+	ctx, stats := NewContext(context.Background())
+	if _, err := d.Query(ctx, "unknown", nil); err == nil {
+		t.Fatal("got no error, want the error of the wrapped driver")
+	}
+	if got := stats.Stats(); got.Queries != 1 || got.ConstraintsMatched != 0 {
+		t.Errorf("got %+v, want one query and no constraints matched", got)
+	}
+}
+
+func TestStats_Add(t *testing.T) {
+	s := Stats{Queries: 1, EvalDuration: 1, ConstraintsMatched: 2, ExternalDataCalls: 3, CacheHits: 4}
+	s.Add(Stats{Queries: 1, EvalDuration: 2, ConstraintsMatched: 1, ExternalDataCalls: 1, CacheHits: 1})
+	want := Stats{Queries: 2, EvalDuration: 3, ConstraintsMatched: 3, ExternalDataCalls: 4, CacheHits: 5}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRecordConstraint(t *testing.T) {
+	d := ConstraintDuration{Kind: "K8sRequiredLabels", Name: "must-have-owner", Duration: time.Millisecond}
+	RecordConstraint(context.Background(), d)
+	if Recording(context.Background()) {
+		t.Error("got a context without a Recorder recording")
+	}
+
+	ctx, stats := NewContext(context.Background())
+	if !Recording(ctx) {
+		t.Error("got a context with a Recorder not recording")
+	}
+	RecordConstraint(ctx, d)
+	if diff := cmp.Diff([]ConstraintDuration{d}, stats.Constraints()); diff != "" {
+		t.Error(diff)
+	}
+}
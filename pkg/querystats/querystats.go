@@ -0,0 +1,148 @@
+// Package querystats instruments the queries the constraint framework
+// evaluates to review an object.
+//
+// The framework's responses do not say what a review cost. A Recorder put
+// into the context of a review collects Stats of every query evaluated with
+// that context by a driver wrapped with Wrap, and of the builtins those
+// queries call, so callers such as the webhook and audit can report them
+// alongside the results of the review. Drivers which time the evaluation of
+// each constraint record it in the same Recorder.
+package querystats
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+)
+
+// Enabled is whether the webhook and audit collect and log the Stats of their
+// reviews.
+var Enabled = flag.Bool("query-stats", false, "(alpha) collect statistics of the queries reviewing each object, logged by the webhook for every request and by audit for every run. Counting the constraints matching an object adds a query to each review")
+
+// Stats are statistics of the queries evaluated to review an object.
+type Stats struct {
+	// Queries is the number of queries evaluated.
+	Queries int `json:"queries"`
+	// EvalDuration is the time spent evaluating queries.
+	EvalDuration time.Duration `json:"evalDuration"`
+	// ConstraintsMatched is the number of constraints whose match criteria
+	// selected the reviewed object, whether or not it violates them.
+	ConstraintsMatched int `json:"constraintsMatched"`
+	// ExternalDataCalls is the number of calls of custom builtins, which may
+	// fetch data from outside of the cluster.
+	ExternalDataCalls int `json:"externalDataCalls"`
+	// CacheHits is the number of lookups of replicated objects answered from
+	// an index built by an earlier lookup.
+	CacheHits int `json:"cacheHits"`
+}
+
+// Add adds the statistics of other to s.
+func (s *Stats) Add(other Stats) {
+	s.Queries += other.Queries
+	s.EvalDuration += other.EvalDuration
+	s.ConstraintsMatched += other.ConstraintsMatched
+	s.ExternalDataCalls += other.ExternalDataCalls
+	s.CacheHits += other.CacheHits
+}
+
+// KeysAndValues returns s as the key/value pairs of a structured log entry.
+func (s Stats) KeysAndValues() []interface{} {
+	return []interface{}{
+		"queries", s.Queries,
+		"eval_duration", s.EvalDuration.String(),
+		"constraints_matched", s.ConstraintsMatched,
+		"external_data_calls", s.ExternalDataCalls,
+		"cache_hits", s.CacheHits,
+	}
+}
+
+// ConstraintDuration is the time spent evaluating a constraint matching the
+// reviewed object, whether or not the object violates it.
+type ConstraintDuration struct {
+	// Kind is the kind of the constraint, which names its template.
+	Kind string
+	Name string
+	// Duration is the time spent evaluating the template of the constraint
+	// for it.
+	Duration time.Duration
+}
+
+// Recorder collects the Stats of the queries evaluated with its context.
+// It is safe for concurrent use.
+type Recorder struct {
+	mux         sync.Mutex
+	stats       Stats
+	constraints []ConstraintDuration
+}
+
+// recorderKey is the context key of the Recorder of a review.
+type recorderKey struct{}
+
+// NewContext returns a copy of ctx carrying a new Recorder, which collects the
+// Stats of the queries evaluated with the returned context.
+func NewContext(ctx context.Context) (context.Context, *Recorder) {
+	r := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// fromContext returns the Recorder of ctx, or nil if it has none.
+func fromContext(ctx context.Context) *Recorder {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Stats returns the statistics collected so far.
+func (r *Recorder) Stats() Stats {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.stats
+}
+
+// Constraints returns the durations of the constraints recorded so far.
+func (r *Recorder) Constraints() []ConstraintDuration {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]ConstraintDuration(nil), r.constraints...)
+}
+
+func (r *Recorder) add(stats Stats) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.stats.Add(stats)
+}
+
+// CountExternalDataCall records a call of a custom builtin by a query
+// evaluated with ctx. It does nothing if ctx has no Recorder.
+func CountExternalDataCall(ctx context.Context) {
+	if r := fromContext(ctx); r != nil {
+		r.add(Stats{ExternalDataCalls: 1})
+	}
+}
+
+// CountCacheHit records a lookup answered from an index by a query evaluated
+// with ctx. It does nothing if ctx has no Recorder.
+func CountCacheHit(ctx context.Context) {
+	if r := fromContext(ctx); r != nil {
+		r.add(Stats{CacheHits: 1})
+	}
+}
+
+// Recording returns whether ctx has a Recorder, so that drivers only time
+// constraints when the time is collected.
+func Recording(ctx context.Context) bool {
+	return fromContext(ctx) != nil
+}
+
+// RecordConstraint records the time spent evaluating a constraint by a query
+// evaluated with ctx. It does nothing if ctx has no Recorder.
+func RecordConstraint(ctx context.Context, d ConstraintDuration) {
+	if r := fromContext(ctx); r != nil {
+		r.mux.Lock()
+		defer r.mux.Unlock()
+		r.constraints = append(r.constraints, d)
+	}
+}
@@ -17,6 +17,7 @@ package readiness
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"sync"
@@ -25,7 +26,12 @@ import (
 	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
 	mutationv1alpha "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
+	"github.com/open-policy-agent/gatekeeper/pkg/inventory"
 	"github.com/open-policy-agent/gatekeeper/pkg/keys"
 	"github.com/open-policy-agent/gatekeeper/pkg/syncutil"
 	"github.com/pkg/errors"
@@ -38,13 +44,24 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-var log = logf.Log.WithName("readiness-tracker")
+var (
+	log = logf.Log.WithName("readiness-tracker")
+
+	// ReferencedDataOnly is whether readiness waits only for replicated kinds
+	// which ConstraintTemplates read from data.inventory.
+	ReferencedDataOnly = flag.Bool("readiness-referenced-data-only", false, "(alpha) become ready without waiting for replicated kinds which no ConstraintTemplate reads from data.inventory. Kinds are found from the Rego of templates, and every kind is waited for if any template reads data.inventory in a way which cannot be determined without evaluating it")
+)
 
 const (
 	constraintGroup = "constraints.gatekeeper.sh"
 	statsPeriod     = 15 * time.Second
 )
 
+var (
+	expansionTemplateGVK = expansionv1alpha1.GroupVersion.WithKind("ExpansionTemplate")
+	exceptionGVK         = exceptionsv1alpha1.GroupVersion.WithKind("Exception")
+)
+
 // Lister lists resources from a cache.
 type Lister interface {
 	List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error
@@ -52,8 +69,14 @@ type Lister interface {
 
 // Tracker tracks readiness for templates, constraints and data.
 type Tracker struct {
-	mu        sync.RWMutex // protects "satisfied" circuit-breaker
+	mu        sync.RWMutex // protects "satisfied" circuit-breaker, "restored" and "inventory"
 	satisfied bool         // indicates whether tracker has been satisfied at least once
+	restored  bool         // indicates whether policy was restored from a ready replica
+
+	// referencedDataOnly is whether only data read by templates is waited for.
+	referencedDataOnly bool
+	// inventory is what the expected templates read from data.inventory.
+	inventory inventory.Usage
 
 	lister Lister
 
@@ -62,6 +85,8 @@ type Tracker struct {
 	assignMetadata *objectTracker
 	assign         *objectTracker
 	modifySet      *objectTracker
+	expansions     *objectTracker
+	exceptions     *objectTracker
 	constraints    *trackerMap
 	data           *trackerMap
 
@@ -69,6 +94,8 @@ type Tracker struct {
 	constraintTrackers *syncutil.SingleRunner
 	statsEnabled       syncutil.SyncBool
 	mutationEnabled    bool
+	expansionEnabled   bool
+	exceptionsEnabled  bool
 }
 
 // NewTracker creates a new Tracker and initializes the internal trackers.
@@ -86,25 +113,79 @@ func newTracker(lister Lister, mutationEnabled bool, fn objDataFactory) *Tracker
 		ready:              make(chan struct{}),
 		constraintTrackers: &syncutil.SingleRunner{},
 
-		mutationEnabled: mutationEnabled,
+		mutationEnabled:    mutationEnabled,
+		expansionEnabled:   *expansion.ExpansionEnabled,
+		exceptionsEnabled:  *exception.Enabled,
+		referencedDataOnly: *ReferencedDataOnly,
 	}
 	if mutationEnabled {
 		tracker.assignMetadata = newObjTracker(mutationv1alpha.GroupVersion.WithKind("AssignMetadata"), fn)
 		tracker.assign = newObjTracker(mutationv1alpha.GroupVersion.WithKind("Assign"), fn)
 		tracker.modifySet = newObjTracker(mutationv1alpha.GroupVersion.WithKind("ModifySet"), fn)
 	}
+	if tracker.expansionEnabled {
+		tracker.expansions = newObjTracker(expansionTemplateGVK, fn)
+	}
+	if tracker.exceptionsEnabled {
+		tracker.exceptions = newObjTracker(exceptionGVK, fn)
+	}
 	return &tracker
 }
 
 // CheckSatisfied implements healthz.Checker to report readiness based on tracker status.
 // Returns nil if all expectations have been satisfied, otherwise returns an error.
 func (t *Tracker) CheckSatisfied(_ *http.Request) error {
+	if t.isRestored() && t.mutationSatisfied() && t.expansionSatisfied() && t.exceptionsSatisfied() {
+		return nil
+	}
 	if !t.Satisfied() {
 		return errors.New("expectations not satisfied")
 	}
 	return nil
 }
 
+// Restored records that the templates, constraints and data of a replica
+// whose expectations were satisfied have been restored. The readiness check
+// then only waits for mutators, ExpansionTemplates and Exceptions. Satisfied still waits for every expectation.
+func (t *Tracker) Restored() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.restored = true
+}
+
+func (t *Tracker) isRestored() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.restored
+}
+
+// mutationSatisfied returns true if mutation is disabled or all mutators
+// have been observed.
+func (t *Tracker) mutationSatisfied() bool {
+	if !t.mutationEnabled {
+		return true
+	}
+	return t.assignMetadata.Satisfied() && t.assign.Satisfied() && t.modifySet.Satisfied()
+}
+
+// expansionSatisfied returns true if expansion is disabled or all
+// ExpansionTemplates have been observed.
+func (t *Tracker) expansionSatisfied() bool {
+	if !t.expansionEnabled {
+		return true
+	}
+	return t.expansions.Satisfied()
+}
+
+// exceptionsSatisfied returns true if Exceptions are disabled or all
+// Exceptions have been observed.
+func (t *Tracker) exceptionsSatisfied() bool {
+	if !t.exceptionsEnabled {
+		return true
+	}
+	return t.exceptions.Satisfied()
+}
+
 // For returns Expectations for the requested resource kind.
 func (t *Tracker) For(gvk schema.GroupVersionKind) Expectations {
 	switch {
@@ -127,6 +208,16 @@ func (t *Tracker) For(gvk schema.GroupVersionKind) Expectations {
 			return t.modifySet
 		}
 		return noopExpectations{}
+	case gvk == expansionTemplateGVK:
+		if t.expansionEnabled {
+			return t.expansions
+		}
+		return noopExpectations{}
+	case gvk == exceptionGVK:
+		if t.exceptionsEnabled {
+			return t.exceptions
+		}
+		return noopExpectations{}
 	}
 
 	// Avoid new constraint trackers after templates have been populated.
@@ -190,7 +281,7 @@ func (t *Tracker) Satisfied() bool {
 	}
 
 	if t.mutationEnabled {
-		if !t.assignMetadata.Satisfied() || !t.assign.Satisfied() || !t.modifySet.Satisfied() {
+		if !t.mutationSatisfied() {
 			return false
 		}
 		log.V(1).Info("all expectations satisfied", "tracker", "assignMetadata")
@@ -198,6 +289,20 @@ func (t *Tracker) Satisfied() bool {
 		log.V(1).Info("all expectations satisfied", "tracker", "modifySet")
 	}
 
+	if t.expansionEnabled {
+		if !t.expansionSatisfied() {
+			return false
+		}
+		log.V(1).Info("all expectations satisfied", "tracker", "expansionTemplates")
+	}
+
+	if t.exceptionsEnabled {
+		if !t.exceptionsSatisfied() {
+			return false
+		}
+		log.V(1).Info("all expectations satisfied", "tracker", "exceptions")
+	}
+
 	if !t.templates.Satisfied() {
 		return false
 	}
@@ -214,6 +319,9 @@ func (t *Tracker) Satisfied() bool {
 	}
 	configKinds := t.config.kinds()
 	for _, gvk := range configKinds {
+		if !t.dataRead(gvk) {
+			continue
+		}
 		if !t.data.Get(gvk).Satisfied() {
 			return false
 		}
@@ -226,6 +334,37 @@ func (t *Tracker) Satisfied() bool {
 	return true
 }
 
+// dataRead returns whether data of gvk is read by the expected templates, and
+// so must be satisfied for the tracker to be. Every kind is read unless only
+// referenced data is waited for. Must be called once templates are satisfied,
+// so that the usage of every template has been recorded.
+func (t *Tracker) dataRead(gvk schema.GroupVersionKind) bool {
+	if !t.referencedDataOnly {
+		return true
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.inventory.Reads(gvk)
+}
+
+// expectInventory records what ct reads from data.inventory. Templates whose
+// Rego does not parse are taken to read every kind.
+func (t *Tracker) expectInventory(ct *v1beta1.ConstraintTemplate) {
+	usage, err := inventory.ExtractV1Beta1(ct)
+	if err != nil {
+		usage = &inventory.Usage{Dynamic: []string{fmt.Sprintf("%s: %v", ct.GetName(), err)}}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, gvk := range usage.GVKs {
+		if !t.inventory.Reads(gvk) {
+			t.inventory.GVKs = append(t.inventory.GVKs, gvk)
+		}
+	}
+	t.inventory.Dynamic = append(t.inventory.Dynamic, usage.Dynamic...)
+}
+
 // Run runs the tracker and blocks until it completes.
 // The provided context can be canceled to signal a shutdown request.
 func (t *Tracker) Run(ctx context.Context) error {
@@ -247,6 +386,16 @@ func (t *Tracker) Run(ctx context.Context) error {
 			return t.trackModifySet(gctx)
 		})
 	}
+	if t.expansionEnabled {
+		grp.Go(func() error {
+			return t.trackExpansionTemplates(gctx)
+		})
+	}
+	if t.exceptionsEnabled {
+		grp.Go(func() error {
+			return t.trackExceptions(gctx)
+		})
+	}
 	grp.Go(func() error {
 		return t.trackConstraintTemplates(gctx)
 	})
@@ -297,7 +446,9 @@ func (t *Tracker) Populated() bool {
 		// If !t.mutationEnabled and we call this, it yields a null pointer exception
 		mutationPopulated = t.assignMetadata.Populated() && t.assign.Populated() && t.modifySet.Populated()
 	}
-	return t.templates.Populated() && t.config.Populated() && mutationPopulated && t.constraints.Populated() && t.data.Populated()
+	expansionPopulated := !t.expansionEnabled || t.expansions.Populated()
+	exceptionsPopulated := !t.exceptionsEnabled || t.exceptions.Populated()
+	return t.templates.Populated() && t.config.Populated() && mutationPopulated && expansionPopulated && exceptionsPopulated && t.constraints.Populated() && t.data.Populated()
 }
 
 // collectForObjectTracker identifies objects that are unsatisfied for the provided
@@ -406,6 +557,32 @@ func (t *Tracker) collectInvalidExpectations(ctx context.Context) {
 			continue
 		}
 	}
+
+	// collect deleted but expected mutators
+	if t.mutationEnabled {
+		for _, ot := range []*objectTracker{t.assignMetadata, t.assign, t.modifySet} {
+			err = t.collectForObjectTracker(ctx, ot, nil)
+			if err != nil {
+				log.Error(err, "while collecting for the Mutator type", "gvk", ot.gvk)
+			}
+		}
+	}
+
+	// collect deleted but expected ExpansionTemplates
+	if t.expansionEnabled {
+		err = t.collectForObjectTracker(ctx, t.expansions, nil)
+		if err != nil {
+			log.Error(err, "while collecting for the ExpansionTemplate tracker")
+		}
+	}
+
+	// collect deleted but expected Exceptions
+	if t.exceptionsEnabled {
+		err = t.collectForObjectTracker(ctx, t.exceptions, nil)
+		if err != nil {
+			log.Error(err, "while collecting for the Exception tracker")
+		}
+	}
 }
 
 func (t *Tracker) trackAssignMetadata(ctx context.Context) error {
@@ -484,6 +661,52 @@ func (t *Tracker) trackModifySet(ctx context.Context) error {
 	return nil
 }
 
+func (t *Tracker) trackExpansionTemplates(ctx context.Context) error {
+	defer func() {
+		t.expansions.ExpectationsDone()
+		log.V(1).Info("ExpansionTemplate expectations populated")
+		_ = t.constraintTrackers.Wait()
+	}()
+
+	expansionList := &expansionv1alpha1.ExpansionTemplateList{}
+	lister := retryLister(t.lister, retryAll)
+	if err := lister.List(ctx, expansionList); err != nil {
+		return fmt.Errorf("listing ExpansionTemplate: %w", err)
+	}
+	log.V(1).Info("setting expectations for ExpansionTemplate", "ExpansionTemplate Count", len(expansionList.Items))
+
+	for index := range expansionList.Items {
+		log.V(1).Info("expecting ExpansionTemplate", "name", expansionList.Items[index].GetName())
+		// Observations are keyed by kind, which typed list items may lack.
+		expansionList.Items[index].SetGroupVersionKind(expansionTemplateGVK)
+		t.expansions.Expect(&expansionList.Items[index])
+	}
+	return nil
+}
+
+func (t *Tracker) trackExceptions(ctx context.Context) error {
+	defer func() {
+		t.exceptions.ExpectationsDone()
+		log.V(1).Info("Exception expectations populated")
+		_ = t.constraintTrackers.Wait()
+	}()
+
+	exceptionList := &exceptionsv1alpha1.ExceptionList{}
+	lister := retryLister(t.lister, retryAll)
+	if err := lister.List(ctx, exceptionList); err != nil {
+		return fmt.Errorf("listing Exception: %w", err)
+	}
+	log.V(1).Info("setting expectations for Exception", "Exception Count", len(exceptionList.Items))
+
+	for index := range exceptionList.Items {
+		log.V(1).Info("expecting Exception", "name", exceptionList.Items[index].GetName())
+		// Observations are keyed by kind, which typed list items may lack.
+		exceptionList.Items[index].SetGroupVersionKind(exceptionGVK)
+		t.exceptions.Expect(&exceptionList.Items[index])
+	}
+	return nil
+}
+
 func (t *Tracker) trackConstraintTemplates(ctx context.Context) error {
 	defer func() {
 		t.templates.ExpectationsDone()
@@ -508,6 +731,9 @@ func (t *Tracker) trackConstraintTemplates(ctx context.Context) error {
 		ct := &templates.Items[i]
 		log.V(1).Info("expecting template", "name", ct.GetName())
 		t.templates.Expect(ct)
+		if t.referencedDataOnly {
+			t.expectInventory(ct)
+		}
 
 		gvk := schema.GroupVersionKind{
 			Group:   constraintGroup,
@@ -742,6 +968,12 @@ func (t *Tracker) statsPrinter(ctx context.Context) {
 			logUnsatisfiedAssign(t)
 			logUnsatisfiedModifySet(t)
 		}
+		if t.expansionEnabled {
+			logUnsatisfiedExpansionTemplates(t)
+		}
+		if t.exceptionsEnabled {
+			logUnsatisfiedExceptions(t)
+		}
 	}
 }
 
@@ -763,6 +995,18 @@ func logUnsatisfiedModifySet(t *Tracker) {
 	}
 }
 
+func logUnsatisfiedExpansionTemplates(t *Tracker) {
+	for _, etKey := range t.expansions.unsatisfied() {
+		log.Info("unsatisfied ExpansionTemplate", "name", etKey.namespacedName)
+	}
+}
+
+func logUnsatisfiedExceptions(t *Tracker) {
+	for _, eKey := range t.exceptions.unsatisfied() {
+		log.Info("unsatisfied Exception", "name", eKey.namespacedName)
+	}
+}
+
 // Returns the constraint GVK that would be generated by a template.
 func constraintGVK(ct *templates.ConstraintTemplate) schema.GroupVersionKind {
 	return schema.GroupVersionKind{
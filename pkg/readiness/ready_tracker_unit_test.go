@@ -17,13 +17,19 @@ package readiness
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/onsi/gomega"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	configv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	mutationv1alpha "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/keys"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -133,3 +139,117 @@ func Test_ReadyTracker_TryCancelTemplate_Retries(t *testing.T) {
 
 	g.Expect(rt.Satisfied()).To(gomega.BeTrue(), "tracker with 0 retries and cancellation should be satisfied")
 }
+
+// deletedAssignLister lists a single Assign when setting expectations, but no
+// objects when collecting expectations for deleted objects.
+type deletedAssignLister struct{}
+
+func (dl deletedAssignLister) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if l, ok := list.(*mutationv1alpha.AssignList); ok {
+		l.Items = []mutationv1alpha.Assign{{ObjectMeta: v1.ObjectMeta{Name: "deleted-assign"}}}
+	}
+	return nil
+}
+
+// Verify that expectations for mutators deleted before they were observed are collected.
+func Test_ReadyTracker_CollectDeletedMutators(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	rt := newTracker(deletedAssignLister{}, true, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := rt.Run(ctx)
+		if err != nil {
+			t.Errorf("Tracker Run() failed with error: %v", err)
+		}
+	}()
+	defer cancel()
+
+	g.Eventually(func() bool {
+		return rt.Populated()
+	}, "10s").Should(gomega.BeTrue())
+
+	g.Expect(rt.Satisfied()).NotTo(gomega.BeTrue(), "tracker expecting an unobserved Assign should not be satisfied")
+
+	rt.collectInvalidExpectations(ctx)
+
+	g.Expect(rt.Satisfied()).To(gomega.BeTrue(), "tracker should be satisfied once the deleted Assign is collected")
+}
+
+var (
+	namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	podGVK       = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+)
+
+// inventoryLister lists a template reading Namespaces from data.inventory, a
+// Config replicating Namespaces and Pods, and one of each.
+type inventoryLister struct{}
+
+func (dl inventoryLister) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	switch l := list.(type) {
+	case *v1beta1.ConstraintTemplateList:
+		ct := v1beta1.ConstraintTemplate{ObjectMeta: v1.ObjectMeta{Name: "uniquenamespacelabel"}}
+		ct.Spec.CRD.Spec.Names.Kind = "UniqueNamespaceLabel"
+		ct.Spec.Targets = []v1beta1.Target{{
+			Target: "admission.k8s.gatekeeper.sh",
+			Rego: `package uniquenamespacelabel
+violation[{"msg": "duplicate"}] {
+  data.inventory.cluster["v1"].Namespace[_].metadata.labels.team == input.review.object.metadata.labels.team
+}`,
+		}}
+		l.Items = []v1beta1.ConstraintTemplate{ct}
+	case *configv1alpha1.ConfigList:
+		cfg := configv1alpha1.Config{ObjectMeta: v1.ObjectMeta{Name: keys.Config.Name, Namespace: keys.Config.Namespace}}
+		cfg.Spec.Sync.SyncOnly = []configv1alpha1.SyncOnlyEntry{
+			{Version: "v1", Kind: "Namespace"},
+			{Version: "v1", Kind: "Pod"},
+		}
+		l.Items = []configv1alpha1.Config{cfg}
+	case *unstructured.UnstructuredList:
+		gvk := l.GroupVersionKind().GroupVersion().WithKind(strings.TrimSuffix(l.GetKind(), "List"))
+		if gvk != namespaceGVK && gvk != podGVK {
+			// There are no constraints.
+			return nil
+		}
+		u := unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetName("object")
+		l.Items = []unstructured.Unstructured{u}
+	}
+	return nil
+}
+
+// Verify that only data read by templates is waited for if referencedDataOnly is set.
+func Test_ReadyTracker_ReferencedDataOnly(t *testing.T) {
+	for _, referencedDataOnly := range []bool{false, true} {
+		g := gomega.NewWithT(t)
+
+		rt := newTracker(inventoryLister{}, false, nil)
+		rt.referencedDataOnly = referencedDataOnly
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			err := rt.Run(ctx)
+			if err != nil {
+				t.Errorf("Tracker Run() failed with error: %v", err)
+			}
+		}()
+
+		g.Eventually(func() bool {
+			return rt.Populated()
+		}, "10s").Should(gomega.BeTrue())
+
+		templates := &v1beta1.ConstraintTemplateList{}
+		g.Expect(inventoryLister{}.List(ctx, templates)).To(gomega.Succeed())
+		rt.templates.Observe(&templates.Items[0])
+		namespace := &unstructured.Unstructured{}
+		namespace.SetGroupVersionKind(namespaceGVK)
+		namespace.SetName("object")
+		rt.ForData(namespaceGVK).Observe(namespace)
+
+		// The Pod is replicated, but never observed.
+		g.Expect(rt.Satisfied()).To(gomega.Equal(referencedDataOnly), "referencedDataOnly=%v", referencedDataOnly)
+		cancel()
+	}
+}
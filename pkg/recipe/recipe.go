@@ -0,0 +1,263 @@
+// Package recipe compiles PolicyRecipes into the ConstraintTemplates and
+// constraints which enforce them.
+//
+// Each recipe is implemented by a ConstraintTemplate embedded in this
+// package, which embeds the tests it must pass in spec.targets[].tests. The
+// template is shared by every PolicyRecipe using the recipe, each of which is
+// compiled into its own constraint.
+package recipe
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	recipesv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/recipes/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// Enabled is whether PolicyRecipes are compiled into ConstraintTemplates and
+// constraints.
+var Enabled = flag.Bool("enable-policy-recipes", false, "(alpha) compile PolicyRecipes into the ConstraintTemplates and constraints which enforce them")
+
+// The recipes a PolicyRecipe may use, named after their field in the spec.
+const (
+	RequiredLabels    = "requiredLabels"
+	AllowedRegistries = "allowedRegistries"
+	ResourceLimits    = "resourceLimits"
+)
+
+// Recipes are the names of every recipe.
+var Recipes = []string{RequiredLabels, AllowedRegistries, ResourceLimits}
+
+const (
+	// RecipeLabel labels generated ConstraintTemplates with the recipe they
+	// implement.
+	RecipeLabel = "recipes.gatekeeper.sh/recipe"
+	// PolicyRecipeLabel labels generated constraints with the name of their
+	// PolicyRecipe.
+	PolicyRecipeLabel = "recipes.gatekeeper.sh/policy-recipe"
+)
+
+//go:embed templates/*.yaml
+var templateFS embed.FS
+
+// The quantities the template of ResourceLimits can compare, which exclude
+// the exponent notation Kubernetes also accepts.
+var (
+	cpuQuantity    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?m?$`)
+	memoryQuantity = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(E|P|T|G|M|k|m|Ki|Mi|Gi|Ti|Pi|Ei)?$`)
+)
+
+// files are the files of the templates implementing each recipe.
+var files = map[string]string{
+	RequiredLabels:    "templates/requiredlabels.yaml",
+	AllowedRegistries: "templates/allowedregistries.yaml",
+	ResourceLimits:    "templates/resourcelimits.yaml",
+}
+
+// Template returns the ConstraintTemplate implementing recipe, including its
+// tests.
+func Template(recipe string) (*unstructured.Unstructured, error) {
+	file, ok := files[recipe]
+	if !ok {
+		return nil, fmt.Errorf("unknown recipe %q", recipe)
+	}
+	raw, err := templateFS.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(raw, &u.Object); err != nil {
+		return nil, fmt.Errorf("parsing the template of recipe %q: %w", recipe, err)
+	}
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[RecipeLabel] = recipe
+	u.SetLabels(labels)
+	return u, nil
+}
+
+// Of returns the name of the recipe r uses, or "" if it uses none.
+func Of(r *recipesv1alpha1.PolicyRecipe) string {
+	switch {
+	case r.Spec.RequiredLabels != nil:
+		return RequiredLabels
+	case r.Spec.AllowedRegistries != nil:
+		return AllowedRegistries
+	case r.Spec.ResourceLimits != nil:
+		return ResourceLimits
+	}
+	return ""
+}
+
+// Compile returns the ConstraintTemplate and the constraint r compiles to, or
+// an error listing every invalid field of r. The constraint has the name of r.
+func Compile(r *recipesv1alpha1.PolicyRecipe) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	name, errs := validate(r)
+	if len(errs) > 0 {
+		return nil, nil, errs.ToAggregate()
+	}
+
+	templ, err := Template(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	kind, _, err := unstructured.NestedString(templ.Object, "spec", "crd", "spec", "names", "kind")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	constraint := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	constraint.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	constraint.SetKind(kind)
+	constraint.SetName(r.GetName())
+	constraint.SetLabels(map[string]string{PolicyRecipeLabel: r.GetName()})
+
+	m := r.Spec.Match.DeepCopy()
+	if len(m.Kinds) == 0 && name != RequiredLabels {
+		m.Kinds = []match.Kinds{{APIGroups: []string{""}, Kinds: []string{"Pod"}}}
+	}
+	constraintMatch, err := runtime.DefaultUnstructuredConverter.ToUnstructured(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	spec := map[string]interface{}{
+		"parameters": parameters(r),
+	}
+	if len(constraintMatch) != 0 {
+		spec["match"] = constraintMatch
+	}
+	if r.Spec.EnforcementAction != "" {
+		spec["enforcementAction"] = r.Spec.EnforcementAction
+	}
+	constraint.Object["spec"] = spec
+	return templ, constraint, nil
+}
+
+// validate returns the name of the recipe r uses and the invalid fields of r.
+func validate(r *recipesv1alpha1.PolicyRecipe) (string, field.ErrorList) {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	var set []string
+	if r.Spec.RequiredLabels != nil {
+		set = append(set, RequiredLabels)
+		errs = append(errs, validateRequiredLabels(spec.Child(RequiredLabels), r.Spec.RequiredLabels)...)
+	}
+	if r.Spec.AllowedRegistries != nil {
+		set = append(set, AllowedRegistries)
+		errs = append(errs, validateAllowedRegistries(spec.Child(AllowedRegistries), r.Spec.AllowedRegistries)...)
+	}
+	if r.Spec.ResourceLimits != nil {
+		set = append(set, ResourceLimits)
+		errs = append(errs, validateResourceLimits(spec.Child(ResourceLimits), r.Spec.ResourceLimits)...)
+	}
+	switch len(set) {
+	case 0:
+		errs = append(errs, field.Required(spec, "exactly one of "+strings.Join(Recipes, ", ")+" must be set"))
+	case 1:
+	default:
+		errs = append(errs, field.Forbidden(spec, "exactly one recipe may be set, got "+strings.Join(set, ", ")))
+	}
+
+	if a := r.Spec.EnforcementAction; a != "" {
+		if err := util.ValidateEnforcementAction(util.EnforcementAction(a)); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("enforcementAction"), a, err.Error()))
+		}
+	}
+
+	if len(set) != 1 {
+		return "", errs
+	}
+	return set[0], errs
+}
+
+func validateRequiredLabels(path *field.Path, rl *recipesv1alpha1.RequiredLabels) field.ErrorList {
+	var errs field.ErrorList
+	if len(rl.Labels) == 0 {
+		errs = append(errs, field.Required(path.Child("labels"), ""))
+	}
+	for i, l := range rl.Labels {
+		p := path.Child("labels").Index(i)
+		for _, msg := range validation.IsQualifiedName(l.Key) {
+			errs = append(errs, field.Invalid(p.Child("key"), l.Key, msg))
+		}
+		if l.AllowedRegex == "" {
+			continue
+		}
+		if _, err := regexp.Compile(l.AllowedRegex); err != nil {
+			errs = append(errs, field.Invalid(p.Child("allowedRegex"), l.AllowedRegex, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateAllowedRegistries(path *field.Path, ar *recipesv1alpha1.AllowedRegistries) field.ErrorList {
+	var errs field.ErrorList
+	if len(ar.Registries) == 0 {
+		errs = append(errs, field.Required(path.Child("registries"), ""))
+	}
+	for i, r := range ar.Registries {
+		if r == "" {
+			// An empty prefix would allow every image.
+			errs = append(errs, field.Required(path.Child("registries").Index(i), ""))
+		}
+	}
+	return errs
+}
+
+func validateResourceLimits(path *field.Path, rl *recipesv1alpha1.ResourceLimits) field.ErrorList {
+	var errs field.ErrorList
+	if rl.CPU == "" && rl.Memory == "" {
+		errs = append(errs, field.Required(path, "at least one of cpu and memory must be set"))
+	}
+	if rl.CPU != "" && !cpuQuantity.MatchString(rl.CPU) {
+		errs = append(errs, field.Invalid(path.Child("cpu"), rl.CPU, "must be a number of cores, or of millicores suffixed with m"))
+	}
+	if rl.Memory != "" && !memoryQuantity.MatchString(rl.Memory) {
+		errs = append(errs, field.Invalid(path.Child("memory"), rl.Memory, "must be a number of bytes with an optional suffix such as M or Mi"))
+	}
+	return errs
+}
+
+// parameters returns the parameters of the constraint of r.
+func parameters(r *recipesv1alpha1.PolicyRecipe) map[string]interface{} {
+	params := make(map[string]interface{})
+	switch {
+	case r.Spec.RequiredLabels != nil:
+		labels := make([]interface{}, 0, len(r.Spec.RequiredLabels.Labels))
+		for _, l := range r.Spec.RequiredLabels.Labels {
+			label := map[string]interface{}{"key": l.Key}
+			if l.AllowedRegex != "" {
+				label["allowedRegex"] = l.AllowedRegex
+			}
+			labels = append(labels, label)
+		}
+		params["labels"] = labels
+	case r.Spec.AllowedRegistries != nil:
+		registries := make([]interface{}, 0, len(r.Spec.AllowedRegistries.Registries))
+		for _, reg := range r.Spec.AllowedRegistries.Registries {
+			registries = append(registries, reg)
+		}
+		params["registries"] = registries
+	case r.Spec.ResourceLimits != nil:
+		if r.Spec.ResourceLimits.CPU != "" {
+			params["cpu"] = r.Spec.ResourceLimits.CPU
+		}
+		if r.Spec.ResourceLimits.Memory != "" {
+			params["memory"] = r.Spec.ResourceLimits.Memory
+		}
+	}
+	return params
+}
@@ -0,0 +1,225 @@
+package recipe
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
+	opaclient "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	recipesv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/recipes/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/selftest"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func toTemplate(t *testing.T, u *unstructured.Unstructured) *templates.ConstraintTemplate {
+	t.Helper()
+	versioned := &v1beta1.ConstraintTemplate{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, versioned); err != nil {
+		t.Fatal(err)
+	}
+	templ, err := versioned.ToVersionless()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templ
+}
+
+// TestTemplates runs the tests embedded in the template of every recipe.
+func TestTemplates(t *testing.T) {
+	for _, name := range Recipes {
+		t.Run(name, func(t *testing.T) {
+			u, err := Template(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.GetLabels()[RecipeLabel]; got != name {
+				t.Errorf("got recipe label %q, want %q", got, name)
+			}
+			tests, err := selftest.Read(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tests) == 0 {
+				t.Fatal("got no tests")
+			}
+			if err := selftest.Run(context.Background(), toTemplate(t, u), tests); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if _, err := Template("unknown"); err == nil {
+		t.Error("got no error for an unknown recipe")
+	}
+}
+
+func TestCompile(t *testing.T) {
+	r := &recipesv1alpha1.PolicyRecipe{}
+	r.SetName("trusted-registries")
+	r.Spec.AllowedRegistries = &recipesv1alpha1.AllowedRegistries{Registries: []string{"registry.example.com/"}}
+	r.Spec.Match.Namespaces = []string{"prod-*"}
+	r.Spec.EnforcementAction = "dryrun"
+
+	templ, constraint, err := Compile(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templ.GetName() != "recipeallowedregistries" {
+		t.Errorf("got template %q, want recipeallowedregistries", templ.GetName())
+	}
+	if constraint.GetKind() != "RecipeAllowedRegistries" || constraint.GetName() != "trusted-registries" {
+		t.Errorf("got constraint %s %s, want RecipeAllowedRegistries trusted-registries", constraint.GetKind(), constraint.GetName())
+	}
+	want := map[string]interface{}{
+		"enforcementAction": "dryrun",
+		"match": map[string]interface{}{
+			// Kinds default to Pods.
+			"kinds":      []interface{}{map[string]interface{}{"apiGroups": []interface{}{""}, "kinds": []interface{}{"Pod"}}},
+			"namespaces": []interface{}{"prod-*"},
+		},
+		"parameters": map[string]interface{}{"registries": []interface{}{"registry.example.com/"}},
+	}
+	if diff := cmp.Diff(want, constraint.Object["spec"]); diff != "" {
+		t.Error(diff)
+	}
+
+	// Kinds listed in the match are kept.
+	r.Spec.Match.Kinds = []match.Kinds{{APIGroups: []string{"apps"}, Kinds: []string{"Deployment"}}}
+	if _, constraint, err = Compile(r); err != nil {
+		t.Fatal(err)
+	}
+	kinds, _, _ := unstructured.NestedSlice(constraint.Object, "spec", "match", "kinds")
+	if diff := cmp.Diff([]interface{}{map[string]interface{}{"apiGroups": []interface{}{"apps"}, "kinds": []interface{}{"Deployment"}}}, kinds); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestCompileEnforces reviews objects against the compiled template and
+// constraint.
+func TestCompileEnforces(t *testing.T) {
+	ctx := context.Background()
+	r := &recipesv1alpha1.PolicyRecipe{}
+	r.SetName("owned")
+	r.Spec.RequiredLabels = &recipesv1alpha1.RequiredLabels{Labels: []recipesv1alpha1.RequiredLabel{{Key: "owner"}}}
+	r.Spec.Match.Kinds = []match.Kinds{{APIGroups: []string{""}, Kinds: []string{"Namespace"}}}
+	templ, constraint, err := Compile(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := opaclient.NewBackend(opaclient.Driver(local.New(local.Tracing(false))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := backend.NewClient(opaclient.Targets(&target.K8sValidationTarget{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AddTemplate(ctx, toTemplate(t, templ)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AddConstraint(ctx, constraint); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		kind   string
+		labels map[string]string
+		denied bool
+	}{
+		{kind: "Namespace", denied: true},
+		{kind: "Namespace", labels: map[string]string{"owner": "alice"}},
+		// Other kinds are not matched.
+		{kind: "ConfigMap"},
+	} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(tc.kind)
+		obj.SetName("team-a")
+		obj.SetLabels(tc.labels)
+		resp, err := client.Review(ctx, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(resp.Results()) != 0; got != tc.denied {
+			t.Errorf("%s with labels %v: got denied %v, want %v", tc.kind, tc.labels, got, tc.denied)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tcs := []struct {
+		name string
+		spec recipesv1alpha1.PolicyRecipeSpec
+		want []string
+	}{
+		{
+			name: "no recipe",
+			want: []string{"spec: Required value: exactly one of requiredLabels, allowedRegistries, resourceLimits must be set"},
+		},
+		{
+			name: "two recipes",
+			spec: recipesv1alpha1.PolicyRecipeSpec{
+				RequiredLabels:    &recipesv1alpha1.RequiredLabels{Labels: []recipesv1alpha1.RequiredLabel{{Key: "owner"}}},
+				AllowedRegistries: &recipesv1alpha1.AllowedRegistries{Registries: []string{"registry.example.com/"}},
+			},
+			want: []string{"exactly one recipe may be set, got requiredLabels, allowedRegistries"},
+		},
+		{
+			name: "invalid labels",
+			spec: recipesv1alpha1.PolicyRecipeSpec{
+				RequiredLabels: &recipesv1alpha1.RequiredLabels{Labels: []recipesv1alpha1.RequiredLabel{{Key: "not a key"}, {Key: "owner", AllowedRegex: "["}}},
+			},
+			want: []string{"spec.requiredLabels.labels[0].key", "spec.requiredLabels.labels[1].allowedRegex"},
+		},
+		{
+			name: "empty registry",
+			spec: recipesv1alpha1.PolicyRecipeSpec{
+				AllowedRegistries: &recipesv1alpha1.AllowedRegistries{Registries: []string{""}},
+			},
+			want: []string{"spec.allowedRegistries.registries[0]: Required value"},
+		},
+		{
+			name: "invalid limits",
+			spec: recipesv1alpha1.PolicyRecipeSpec{
+				ResourceLimits: &recipesv1alpha1.ResourceLimits{CPU: "1e3", Memory: "1Gb"},
+			},
+			want: []string{"spec.resourceLimits.cpu", "spec.resourceLimits.memory"},
+		},
+		{
+			name: "no limits",
+			spec: recipesv1alpha1.PolicyRecipeSpec{ResourceLimits: &recipesv1alpha1.ResourceLimits{}},
+			want: []string{"at least one of cpu and memory must be set"},
+		},
+		{
+			name: "invalid enforcement action",
+			spec: recipesv1alpha1.PolicyRecipeSpec{
+				RequiredLabels:    &recipesv1alpha1.RequiredLabels{Labels: []recipesv1alpha1.RequiredLabel{{Key: "owner"}}},
+				EnforcementAction: "block",
+			},
+			want: []string{"spec.enforcementAction"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recipesv1alpha1.PolicyRecipe{Spec: tc.spec}
+			r.SetName("invalid")
+			_, _, err := Compile(r)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got error %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// Package regocost estimates how expensive the Rego of a ConstraintTemplate is
+// to evaluate, by static analysis rather than by running it.
+//
+// The estimate is a coarse cost class together with the findings which led to
+// it, so reviewers can spot policies likely to slow down the webhook before
+// they reach a cluster. The analysis looks for patterns which are known to
+// scale badly: comprehensions nested inside one another, iteration over the
+// objects replicated into data.inventory, and regular expressions evaluated
+// once per iteration.
+package regocost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// Class is a coarse estimate of the cost of evaluating Rego.
+type Class string
+
+const (
+	// Low is the class of Rego without any costly patterns.
+	Low Class = "Low"
+	// Medium is the class of Rego whose cost grows with the size of the
+	// reviewed object or of the inventory.
+	Medium Class = "Medium"
+	// High is the class of Rego whose cost grows with a product of sizes, for
+	// example the inventory times the reviewed object.
+	High Class = "High"
+)
+
+var classRank = map[Class]int{Low: 0, Medium: 1, High: 2}
+
+// Exceeds returns true if c is a higher class than max.
+func Exceeds(c, max Class) bool {
+	return classRank[c] > classRank[max]
+}
+
+// Codes of findings.
+const (
+	// NestedComprehension is reported for comprehensions nested within
+	// other comprehensions.
+	NestedComprehension = "nested_comprehension"
+	// InventoryScan is reported for iteration over data.inventory.
+	InventoryScan = "inventory_scan"
+	// NestedInventoryScan is reported for iteration over data.inventory
+	// within another iteration or a comprehension.
+	NestedInventoryScan = "nested_inventory_scan"
+	// RegexInLoop is reported for regular expressions evaluated within an
+	// iteration.
+	RegexInLoop = "regex_in_loop"
+	// DynamicRegexInLoop is reported for regular expressions built at
+	// evaluation time within an iteration, which are compiled on every
+	// iteration.
+	DynamicRegexInLoop = "dynamic_regex_in_loop"
+)
+
+// regexBuiltins are the built-in functions whose first operand is a regular
+// expression pattern.
+var regexBuiltins = map[string]bool{
+	"re_match":                         true,
+	"regex.match":                      true,
+	"regex.is_valid":                   true,
+	"regex.find_n":                     true,
+	"regex.find_all_string_submatch_n": true,
+	"regex.split":                      true,
+	"regex.replace":                    true,
+	"regex.template_match":             true,
+	"regex.globs_match":                true,
+}
+
+// Finding is a costly pattern found in Rego.
+type Finding struct {
+	Code     string
+	Class    Class
+	Message  string
+	Location string
+}
+
+// Estimate is the estimated cost of evaluating Rego.
+type Estimate struct {
+	// Class is the highest class of any finding, or Low without findings.
+	Class    Class
+	Findings []Finding
+}
+
+// EstimateTemplate estimates the cost of the Rego of every target of t,
+// including its libraries.
+func EstimateTemplate(t *templates.ConstraintTemplate) (*Estimate, error) {
+	modules := make(map[string]string)
+	for _, target := range t.Spec.Targets {
+		modules[target.Target] = target.Rego
+		for i, lib := range target.Libs {
+			modules[fmt.Sprintf("%s/libs[%d]", target.Target, i)] = lib
+		}
+	}
+	return EstimateModules(modules)
+}
+
+// EstimateModules estimates the cost of the Rego modules, keyed by file name.
+func EstimateModules(modules map[string]string) (*Estimate, error) {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a := &analyzer{}
+	for _, name := range names {
+		m, err := ast.ParseModule(name, modules[name])
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			continue
+		}
+		for _, r := range m.Rules {
+			a.rule(r)
+		}
+	}
+
+	e := &Estimate{Class: Low, Findings: a.findings}
+	for _, f := range e.Findings {
+		if Exceeds(f.Class, e.Class) {
+			e.Class = f.Class
+		}
+	}
+	return e, nil
+}
+
+type analyzer struct {
+	findings []Finding
+}
+
+// scope is the context an expression is evaluated in.
+type scope struct {
+	rule string
+	// bound holds the variables bound by preceding expressions.
+	bound ast.VarSet
+	// loops is the number of iterations the expression is evaluated within.
+	loops int
+	// comprehensions is the number of comprehensions the expression is
+	// evaluated within.
+	comprehensions int
+}
+
+func (s scope) inLoop() bool {
+	return s.loops > 0 || s.comprehensions > 0
+}
+
+func (a *analyzer) rule(r *ast.Rule) {
+	for ; r != nil; r = r.Else {
+		bound := ast.NewVarSet()
+		for _, arg := range r.Head.Args {
+			bound.Update(arg.Vars())
+		}
+		s := a.body(r.Body, scope{rule: r.Head.Name.String(), bound: bound})
+		// The key and value of the head are evaluated for every solution of
+		// the body.
+		for _, t := range []*ast.Term{r.Head.Key, r.Head.Value} {
+			if t != nil {
+				a.expr(ast.NewExpr(t), &s)
+			}
+		}
+	}
+}
+
+// body analyzes the expressions of b in turn, each within the iterations
+// introduced by those preceding it, and returns the scope following b.
+func (a *analyzer) body(b ast.Body, s scope) scope {
+	s.bound = s.bound.Copy()
+	for _, expr := range b {
+		a.expr(expr, &s)
+		s.bound.Update(expr.Vars(ast.VarVisitorParams{SkipClosures: true}))
+	}
+	return s
+}
+
+// expr analyzes expr. Iteration over data.inventory is judged by the
+// iterations preceding expr, while calls and comprehensions are also within
+// the iterations introduced by expr itself.
+func (a *analyzer) expr(expr *ast.Expr, s *scope) {
+	iterations := 0
+	ast.NewGenericVisitor(func(x interface{}) bool {
+		t, ok := x.(*ast.Term)
+		if !ok {
+			return false
+		}
+		switch v := t.Value.(type) {
+		case *ast.ArrayComprehension, *ast.SetComprehension, *ast.ObjectComprehension:
+			return true
+		case ast.Ref:
+			iterations += a.ref(v, t.Location, *s)
+		}
+		return false
+	}).Walk(expr)
+	s.loops += iterations
+
+	if expr.IsCall() {
+		a.call(expr.Operator(), expr.Operands(), expr.Location, *s)
+	}
+	ast.NewGenericVisitor(func(x interface{}) bool {
+		t, ok := x.(*ast.Term)
+		if !ok {
+			return false
+		}
+		switch v := t.Value.(type) {
+		case *ast.ArrayComprehension:
+			a.comprehension(v.Body, t.Location, *s)
+			return true
+		case *ast.SetComprehension:
+			a.comprehension(v.Body, t.Location, *s)
+			return true
+		case *ast.ObjectComprehension:
+			a.comprehension(v.Body, t.Location, *s)
+			return true
+		case ast.Call:
+			if ref, ok := v[0].Value.(ast.Ref); ok {
+				a.call(ref, v[1:], t.Location, *s)
+			}
+		}
+		return false
+	}).Walk(expr)
+}
+
+func (a *analyzer) comprehension(b ast.Body, loc *ast.Location, s scope) {
+	s.comprehensions++
+	if s.comprehensions > 1 {
+		class := Medium
+		if s.comprehensions > 2 {
+			class = High
+		}
+		a.report(NestedComprehension, class, loc, s,
+			fmt.Sprintf("comprehension nested %d deep is evaluated for every element of the comprehensions enclosing it", s.comprehensions))
+	}
+	a.body(b, s)
+}
+
+// ref reports iteration over data.inventory, returning the number of
+// variables ref iterates over.
+func (a *analyzer) ref(ref ast.Ref, loc *ast.Location, s scope) int {
+	iterations := 0
+	for _, t := range ref[1:] {
+		if v, ok := t.Value.(ast.Var); ok && (v.IsWildcard() || !s.bound.Contains(v)) {
+			iterations++
+		}
+	}
+	if iterations == 0 || !isInventory(ref) {
+		return iterations
+	}
+	if s.inLoop() {
+		a.report(NestedInventoryScan, High, loc, s,
+			fmt.Sprintf("%s is scanned once per element of an enclosing iteration", ref.GroundPrefix()))
+	} else {
+		a.report(InventoryScan, Medium, loc, s,
+			fmt.Sprintf("%s is scanned, so cost grows with the number of replicated objects", ref.GroundPrefix()))
+	}
+	return iterations
+}
+
+func (a *analyzer) call(op ast.Ref, operands []*ast.Term, loc *ast.Location, s scope) {
+	name := op.String()
+	if !regexBuiltins[name] || !s.inLoop() || len(operands) == 0 {
+		return
+	}
+	if _, constant := operands[0].Value.(ast.String); constant {
+		a.report(RegexInLoop, Medium, loc, s,
+			fmt.Sprintf("%s is evaluated once per element of an enclosing iteration", name))
+		return
+	}
+	a.report(DynamicRegexInLoop, High, loc, s,
+		fmt.Sprintf("%s compiles a pattern built at evaluation time once per element of an enclosing iteration", name))
+}
+
+func (a *analyzer) report(code string, class Class, loc *ast.Location, s scope, msg string) {
+	a.findings = append(a.findings, Finding{
+		Code:     code,
+		Class:    class,
+		Message:  fmt.Sprintf("rule %s: %s", s.rule, msg),
+		Location: location(loc),
+	})
+}
+
+func isInventory(ref ast.Ref) bool {
+	if len(ref) < 2 || !ref[0].Equal(ast.DefaultRootDocument) {
+		return false
+	}
+	s, ok := ref[1].Value.(ast.String)
+	return ok && string(s) == "inventory"
+}
+
+func location(loc *ast.Location) string {
+	if loc == nil {
+		return ""
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%s:%d", loc.File, loc.Row), ":")
+}
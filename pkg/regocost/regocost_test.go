@@ -0,0 +1,203 @@
+package regocost
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+)
+
+func codes(e *Estimate) []string {
+	var out []string
+	for _, f := range e.Findings {
+		out = append(out, f.Code)
+	}
+	return out
+}
+
+func TestEstimateModules(t *testing.T) {
+	tcs := []struct {
+		name      string
+		rego      string
+		wantClass Class
+		wantCodes []string
+	}{
+		{
+			name: "no costly patterns",
+			rego: `package k8srequiredlabels
+violation[{"msg": msg}] {
+  provided := {label | input.review.object.metadata.labels[label]}
+  required := {label | label := input.parameters.labels[_]}
+  missing := required - provided
+  count(missing) > 0
+  msg := sprintf("missing labels: %v", [missing])
+}`,
+			wantClass: Low,
+		},
+		{
+			name: "regex outside any iteration",
+			rego: `package k8sname
+violation[{"msg": "bad name"}] {
+  not re_match("^[a-z]+$", input.review.object.metadata.name)
+}`,
+			wantClass: Low,
+		},
+		{
+			name: "constant regex in loop",
+			rego: `package k8sallowedrepos
+violation[{"msg": "bad image"}] {
+  container := input.review.object.spec.containers[_]
+  not regex.match("^gcr.io/", container.image)
+}`,
+			wantClass: Medium,
+			wantCodes: []string{RegexInLoop},
+		},
+		{
+			name: "regex iterating in the same expression",
+			rego: `package k8sallowedrepos
+violation[{"msg": "bad image"}] {
+  re_match("^gcr.io/", input.review.object.spec.containers[_].image)
+}`,
+			wantClass: Medium,
+			wantCodes: []string{RegexInLoop},
+		},
+		{
+			name: "dynamic regex in loop",
+			rego: `package k8sallowedrepos
+violation[{"msg": "bad image"}] {
+  container := input.review.object.spec.containers[_]
+  repo := input.parameters.repos[_]
+  not re_match(concat("", ["^", repo]), container.image)
+}`,
+			wantClass: High,
+			wantCodes: []string{DynamicRegexInLoop},
+		},
+		{
+			name: "bound variables are lookups, not iteration",
+			rego: `package k8sname
+violation[{"msg": "bad name"}] {
+  name := input.review.object.metadata.name
+  pattern := input.parameters.patterns[name]
+  re_match(pattern, name)
+}`,
+			wantClass: Low,
+		},
+		{
+			name: "inventory scan",
+			rego: `package k8suniqueingresshost
+violation[{"msg": "duplicate host"}] {
+  host := input.review.object.spec.rules[_].host
+  other := data.inventory.namespace[ns][_]["Ingress"][name]
+  other.spec.rules[_].host == host
+}`,
+			wantClass: High,
+			wantCodes: []string{NestedInventoryScan},
+		},
+		{
+			name: "inventory scan outside any iteration",
+			rego: `package k8suniqueserviceselector
+violation[{"msg": "duplicate selector"}] {
+  other := data.inventory.namespace[ns][_]["Service"][name]
+  other.spec.selector == input.review.object.spec.selector
+}`,
+			wantClass: Medium,
+			wantCodes: []string{InventoryScan},
+		},
+		{
+			name: "inventory lookup by bound key",
+			rego: `package k8snamespaceexists
+violation[{"msg": "missing namespace"}] {
+  ns := input.review.object.metadata.namespace
+  not data.inventory.cluster["v1"]["Namespace"][ns]
+}`,
+			wantClass: Low,
+		},
+		{
+			name: "inventory scan in comprehension",
+			rego: `package k8suniquelabel
+violation[{"msg": "duplicate label"}] {
+  value := input.review.object.metadata.labels[input.parameters.label]
+  others := {o | o := data.inventory.namespace[_][_][_][_]; o.metadata.labels[input.parameters.label] == value}
+  count(others) > 0
+}`,
+			wantClass: High,
+			wantCodes: []string{NestedInventoryScan},
+		},
+		{
+			name: "nested comprehensions",
+			rego: `package nested
+two = x {
+  x := {a | a := [b | b := input.review.object.spec.containers[_]]}
+}
+three = x {
+  x := {a | a := [b | b := {c | c := input.review.object.spec.containers[_].ports[_]}]}
+}`,
+			wantClass: High,
+			wantCodes: []string{NestedComprehension, NestedComprehension, NestedComprehension},
+		},
+		{
+			name: "comprehension in rule head",
+			rego: `package nested
+names = {n | n := [m | m := input.review.object.spec.containers[_].name]}`,
+			wantClass: Medium,
+			wantCodes: []string{NestedComprehension},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := EstimateModules(map[string]string{"template": tc.rego})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Class != tc.wantClass {
+				t.Errorf("got class %s, want %s: %+v", got.Class, tc.wantClass, got.Findings)
+			}
+			if diff := cmp.Diff(tc.wantCodes, codes(got)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestEstimateTemplate(t *testing.T) {
+	ct := &templates.ConstraintTemplate{
+		Spec: templates.ConstraintTemplateSpec{
+			Targets: []templates.Target{{
+				Target: "admission.k8s.gatekeeper.sh",
+				Rego: `package foo
+import data.lib.images
+violation[{"msg": "bad image"}] {
+  images.bad(input.review.object.spec.containers[_].image)
+}`,
+				Libs: []string{`package lib.images
+bad(image) {
+  re_match("^docker.io/", input.parameters.images[_])
+  image == input.parameters.images[_]
+}`},
+			}},
+		},
+	}
+
+	got, err := EstimateTemplate(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Estimate{
+		Class: Medium,
+		Findings: []Finding{{
+			Code:     RegexInLoop,
+			Class:    Medium,
+			Message:  "rule bad: re_match is evaluated once per element of an enclosing iteration",
+			Location: "admission.k8s.gatekeeper.sh/libs[0]:3",
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+
+	ct.Spec.Targets[0].Rego = "package foo\nviolation[{"
+	if _, err := EstimateTemplate(ct); err == nil {
+		t.Error("got no error estimating unparsable Rego")
+	}
+}
@@ -0,0 +1,103 @@
+package regolimit
+
+import (
+	"context"
+	"runtime/metrics"
+
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// allocsMetric is the cumulative number of bytes allocated on the heap.
+const allocsMetric = "/gc/heap/allocs:bytes"
+
+// sampleInterval is the number of trace events between samples of the bytes
+// allocated while evaluating the same template, so that a template building a
+// large comprehension is stopped soon after exceeding its limit.
+const sampleInterval = 256
+
+// budget is a query tracer which counts the bytes allocated while the modules
+// of each template are evaluated, and cancels the query once a template
+// exceeds its limit.
+type budget struct {
+	// templates are the templates of modules, keyed by module name.
+	templates map[string]template
+	limits    map[template]int64
+	cancel    context.CancelFunc
+
+	sample []metrics.Sample
+	// last is the cumulative number of bytes allocated when last sampled.
+	last uint64
+	// current is the template being evaluated, if any.
+	current  template
+	events   int
+	used     map[template]int64
+	exceeded *LimitError
+}
+
+var _ topdown.QueryTracer = &budget{}
+
+func newBudget(templates map[string]template, limits map[template]int64, cancel context.CancelFunc) *budget {
+	b := &budget{
+		templates: templates,
+		limits:    limits,
+		cancel:    cancel,
+		sample:    []metrics.Sample{{Name: allocsMetric}},
+		used:      make(map[template]int64),
+	}
+	b.last = b.allocated()
+	return b
+}
+
+func (b *budget) Enabled() bool {
+	return true
+}
+
+func (b *budget) Config() topdown.TraceConfig {
+	// Plugging local variables is expensive and not needed to attribute
+	// events to templates.
+	return topdown.TraceConfig{PlugLocalVars: false}
+}
+
+func (b *budget) TraceEvent(evt topdown.Event) {
+	if b.exceeded != nil {
+		return
+	}
+	t := b.current
+	if evt.Location != nil {
+		t = b.templates[evt.Location.File]
+	}
+	b.events++
+	if t == b.current && b.events%sampleInterval != 0 {
+		return
+	}
+	b.charge()
+	b.current = t
+}
+
+// charge counts the bytes allocated since the last sample against the
+// current template, cancelling the query if it exceeds its limit.
+func (b *budget) charge() {
+	now := b.allocated()
+	delta := int64(now - b.last)
+	b.last = now
+	if b.current == (template{}) {
+		return
+	}
+	limit, ok := b.limits[b.current]
+	if !ok {
+		return
+	}
+	b.used[b.current] += delta
+	if b.used[b.current] > limit {
+		b.exceeded = &LimitError{Target: b.current.target, Kind: b.current.kind, Limit: limit}
+		b.cancel()
+	}
+}
+
+func (b *budget) allocated() uint64 {
+	metrics.Read(b.sample)
+	if b.sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return b.sample[0].Value.Uint64()
+}
@@ -0,0 +1,528 @@
+package regolimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/engine"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Module sets are named as in the local driver, so module names are the same
+// whichever driver holds them.
+const (
+	moduleSetPrefix = "__modset_"
+	moduleSetSep    = "_idx_"
+)
+
+// templateModule matches the names of the modules the constraint framework
+// generates from a template.
+var templateModule = regexp.MustCompile(`^` + moduleSetPrefix + `templates\["([^"]+)"\]\["([^"]+)"\]` + moduleSetSep + `\d+$`)
+
+var log = logf.Log.WithName("rego-limit")
+
+// reviewPath matches the path the constraint framework queries to review an
+// object.
+var reviewPath = regexp.MustCompile(`^hooks\["([^"]+)"\]\.violation$`)
+
+// template identifies a template by the target it is compiled for and the
+// kind of its constraints.
+type template struct {
+	target string
+	kind   string
+}
+
+type module struct {
+	text   string
+	parsed *ast.Module
+}
+
+// Driver is a constraint framework driver which evaluates queries like the
+// local Rego driver, failing those in which a template exceeds its memory
+// limit.
+type Driver struct {
+	capabilities *ast.Capabilities
+	defaultLimit int64
+
+	mux      sync.RWMutex
+	compiler *ast.Compiler
+	modules  map[string]*module
+	store    storage.Store
+	// templates are the templates whose modules are held, keyed by module
+	// name.
+	templates map[string]template
+
+	limitsMux sync.RWMutex
+	// limits are the limits of templates with the Annotation.
+	limits map[template]int64
+}
+
+var (
+	_ drivers.Driver  = &Driver{}
+	_ engine.Selector = &Driver{}
+)
+
+// NewDriver returns a Driver limiting every template to defaultLimit bytes,
+// unless it has the Annotation. A limit of 0 is no limit. The named builtins
+// may not be called.
+func NewDriver(defaultLimit int64, disabledBuiltins ...string) *Driver {
+	caps := ast.CapabilitiesForThisVersion()
+	disabled := make(map[string]bool, len(disabledBuiltins))
+	for _, name := range disabledBuiltins {
+		disabled[name] = true
+	}
+	var enabled []*ast.Builtin
+	for _, b := range caps.Builtins {
+		if !disabled[b.Name] {
+			enabled = append(enabled, b)
+		}
+	}
+	caps.Builtins = enabled
+
+	return &Driver{
+		capabilities: caps,
+		defaultLimit: defaultLimit,
+		compiler:     ast.NewCompiler().WithCapabilities(caps),
+		modules:      make(map[string]*module),
+		store:        inmem.New(),
+		templates:    make(map[string]template),
+		limits:       make(map[template]int64),
+	}
+}
+
+// Select implements engine.Selector, recording the limit templ sets with the
+// Annotation.
+func (d *Driver) Select(ctx context.Context, templ *templates.ConstraintTemplate) error {
+	var l int64
+	value, annotated := templ.GetAnnotations()[Annotation]
+	if annotated {
+		var err error
+		if l, err = parseLimit(value); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", Annotation, err)
+		}
+	}
+
+	d.limitsMux.Lock()
+	defer d.limitsMux.Unlock()
+	for _, t := range templ.Spec.Targets {
+		key := template{target: t.Target, kind: templ.Spec.CRD.Spec.Names.Kind}
+		if annotated {
+			d.limits[key] = l
+		} else {
+			delete(d.limits, key)
+		}
+	}
+	return nil
+}
+
+// limitsLocked returns the limits of the held templates which are limited.
+// Must be called while holding d.mux.
+func (d *Driver) limitsLocked() map[template]int64 {
+	d.limitsMux.RLock()
+	defer d.limitsMux.RUnlock()
+
+	limits := make(map[template]int64)
+	for _, t := range d.templates {
+		l, ok := d.limits[t]
+		if !ok {
+			l = d.defaultLimit
+		}
+		if l > 0 {
+			limits[t] = l
+		}
+	}
+	return limits
+}
+
+func (d *Driver) Init(ctx context.Context) error {
+	return nil
+}
+
+func (d *Driver) PutModule(ctx context.Context, name string, src string) error {
+	if name == "" {
+		return errors.New("module name cannot be empty")
+	}
+	if strings.HasPrefix(name, moduleSetPrefix) {
+		return fmt.Errorf("single modules not allowed to use name prefix %s", moduleSetPrefix)
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	_, err := d.alterModulesLocked(ctx, map[string]string{name: src}, nil)
+	return err
+}
+
+func (d *Driver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	if err := checkModuleSetName(namePrefix); err != nil {
+		return err
+	}
+	insert := make(map[string]string, len(srcs))
+	for idx, src := range srcs {
+		insert[fmt.Sprintf("%s%d", moduleSetName(namePrefix), idx)] = src
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var remove []string
+	for _, name := range d.listModuleSetLocked(namePrefix) {
+		if _, ok := insert[name]; !ok {
+			remove = append(remove, name)
+		}
+	}
+	_, err := d.alterModulesLocked(ctx, insert, remove)
+	return err
+}
+
+func (d *Driver) DeleteModule(ctx context.Context, name string) (bool, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if _, ok := d.modules[name]; !ok {
+		return false, nil
+	}
+	n, err := d.alterModulesLocked(ctx, nil, []string{name})
+	return n == 1, err
+}
+
+func (d *Driver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	if err := checkModuleSetName(namePrefix); err != nil {
+		return 0, err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.alterModulesLocked(ctx, nil, d.listModuleSetLocked(namePrefix))
+}
+
+func checkModuleSetName(namePrefix string) error {
+	if namePrefix == "" {
+		return errors.New("modules name prefix cannot be empty")
+	}
+	if strings.Contains(namePrefix, moduleSetSep) {
+		return fmt.Errorf("modules name prefix not allowed to contain the sequence %s", moduleSetSep)
+	}
+	return nil
+}
+
+func moduleSetName(namePrefix string) string {
+	return moduleSetPrefix + namePrefix + moduleSetSep
+}
+
+// listModuleSetLocked returns the names of the modules put under namePrefix.
+// Must be called while holding d.mux.
+func (d *Driver) listModuleSetLocked(namePrefix string) []string {
+	prefix := moduleSetName(namePrefix)
+	var names []string
+	for name := range d.modules {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// alterModulesLocked inserts and removes modules, returning the number
+// removed. Nothing is changed if the resulting modules do not compile. Must
+// be called while holding d.mux for writing.
+func (d *Driver) alterModulesLocked(ctx context.Context, insert map[string]string, remove []string) (int, error) {
+	updated := make(map[string]*ast.Module, len(d.modules)+len(insert))
+	for name, m := range d.modules {
+		updated[name] = m.parsed
+	}
+	for _, name := range remove {
+		delete(updated, name)
+	}
+	inserted := make(map[string]*module, len(insert))
+	for name, src := range insert {
+		m, err := ast.ParseModule(name, src)
+		if err != nil {
+			return 0, err
+		}
+		if m == nil {
+			return 0, fmt.Errorf("module %s is empty", name)
+		}
+		updated[name] = m
+		inserted[name] = &module{text: src, parsed: m}
+	}
+
+	txn, err := d.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range remove {
+		if err := d.store.DeletePolicy(ctx, txn, name); err != nil {
+			d.store.Abort(ctx, txn)
+			return 0, err
+		}
+	}
+	c := ast.NewCompiler().WithPathConflictsCheck(storage.NonEmpty(ctx, d.store, txn)).
+		WithCapabilities(d.capabilities)
+	if c.Compile(updated); c.Failed() {
+		d.store.Abort(ctx, txn)
+		return 0, c.Errors
+	}
+	for name, m := range inserted {
+		if err := d.store.UpsertPolicy(ctx, txn, name, []byte(m.text)); err != nil {
+			d.store.Abort(ctx, txn)
+			return 0, err
+		}
+	}
+	if err := d.store.Commit(ctx, txn); err != nil {
+		return 0, err
+	}
+
+	for _, name := range remove {
+		delete(d.modules, name)
+		delete(d.templates, name)
+	}
+	for name, m := range inserted {
+		d.modules[name] = m
+		if match := templateModule.FindStringSubmatch(name); match != nil {
+			d.templates[name] = template{target: match[1], kind: match[2]}
+		}
+	}
+	d.compiler = c
+	return len(remove), nil
+}
+
+func (d *Driver) PutData(ctx context.Context, path string, data interface{}) error {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	p, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	txn, err := d.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return err
+	}
+	if _, err := d.store.Read(ctx, txn, p); err != nil {
+		if !storage.IsNotFound(err) {
+			d.store.Abort(ctx, txn)
+			return err
+		}
+		if err := storage.MakeDir(ctx, d.store, txn, p[:len(p)-1]); err != nil {
+			d.store.Abort(ctx, txn)
+			return err
+		}
+	}
+	if err := d.store.Write(ctx, txn, storage.AddOp, p, data); err != nil {
+		d.store.Abort(ctx, txn)
+		return err
+	}
+	if err := ast.CheckPathConflicts(d.compiler, storage.NonEmpty(ctx, d.store, txn)); len(err) > 0 {
+		d.store.Abort(ctx, txn)
+		return err
+	}
+	return d.store.Commit(ctx, txn)
+}
+
+func (d *Driver) DeleteData(ctx context.Context, path string) (bool, error) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	p, err := parsePath(path)
+	if err != nil {
+		return false, err
+	}
+	txn, err := d.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return false, err
+	}
+	if err := d.store.Write(ctx, txn, storage.RemoveOp, p, nil); err != nil {
+		d.store.Abort(ctx, txn)
+		if storage.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := d.store.Commit(ctx, txn); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func parsePath(path string) (storage.Path, error) {
+	p, ok := storage.ParsePathEscaped(path)
+	if !ok || len(p) == 0 {
+		return nil, fmt.Errorf("bad data path: %s", path)
+	}
+	return p, nil
+}
+
+// eval evaluates query, failing with a LimitError if a template exceeds its
+// limit. The trace is returned if tracing is enabled. If sw is not nil, it
+// measures the time spent evaluating each template.
+func (d *Driver) eval(ctx context.Context, query string, input interface{}, tracing bool, sw *stopwatch) (rego.ResultSet, *string, error) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	limits := d.limitsLocked()
+
+	args := []func(*rego.Rego){
+		rego.Compiler(d.compiler),
+		rego.Store(d.store),
+		rego.Input(input),
+		rego.Query(query),
+	}
+	var b *budget
+	if len(limits) != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		b = newBudget(d.templates, limits, cancel)
+		args = append(args, rego.QueryTracer(b))
+	}
+	if sw != nil {
+		sw.start(d.templates)
+		args = append(args, rego.QueryTracer(sw))
+	}
+	var buf *topdown.BufferTracer
+	if tracing {
+		buf = topdown.NewBufferTracer()
+		args = append(args, rego.QueryTracer(buf))
+	}
+
+	rs, err := rego.New(args...).Eval(ctx)
+	if sw != nil {
+		sw.stop()
+	}
+	if b != nil && b.exceeded != nil {
+		err = b.exceeded
+	}
+	if buf == nil {
+		return rs, nil, err
+	}
+	w := &bytes.Buffer{}
+	topdown.PrettyTrace(w, *buf)
+	trace := w.String()
+	return rs, &trace, err
+}
+
+func (d *Driver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	cfg := &drivers.QueryCfg{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	inp, err := json.MarshalIndent(input, "", "   ")
+	if err != nil {
+		return nil, err
+	}
+	// The constraints of a review are timed if the time is recorded.
+	var sw *stopwatch
+	match := reviewPath.FindStringSubmatch(path)
+	if match != nil && querystats.Recording(ctx) {
+		sw = &stopwatch{}
+	}
+	// Bind each result to a variable, as the local driver does.
+	rs, trace, err := d.eval(ctx, fmt.Sprintf("data.%s[result]", path), input, cfg.TracingEnabled, sw)
+	if err != nil {
+		return nil, err
+	}
+	if sw != nil {
+		// The review is not failed for want of its timing.
+		if err := d.recordConstraints(ctx, match[1], input, sw.elapsed); err != nil {
+			log.V(1).Info("unable to time the constraints matching a review", "error", err.Error())
+		}
+	}
+	var results []*types.Result
+	for _, r := range rs {
+		result := &types.Result{}
+		b, err := json.Marshal(r.Bindings["result"])
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	i := string(inp)
+	return &types.Response{
+		Trace:   trace,
+		Results: results,
+		Input:   &i,
+	}, nil
+}
+
+// recordConstraints records the time spent evaluating each constraint of
+// target matching input, given the time spent evaluating each template. The
+// constraints of a template are evaluated in the same query, so the time spent
+// evaluating a template is shared evenly between its matching constraints.
+func (d *Driver) recordConstraints(ctx context.Context, target string, input interface{}, elapsed map[template]time.Duration) error {
+	rs, _, err := d.eval(ctx, fmt.Sprintf(`data.hooks["%s"].library.matching_constraints[constraint]`, target), input, false, nil)
+	if err != nil {
+		return err
+	}
+	type constraint struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	var matched []constraint
+	counts := make(map[template]int)
+	for _, r := range rs {
+		b, err := json.Marshal(r.Bindings["constraint"])
+		if err != nil {
+			return err
+		}
+		var c constraint
+		if err := json.Unmarshal(b, &c); err != nil {
+			return err
+		}
+		matched = append(matched, c)
+		counts[template{target: target, kind: c.Kind}]++
+	}
+	for _, c := range matched {
+		t := template{target: target, kind: c.Kind}
+		querystats.RecordConstraint(ctx, querystats.ConstraintDuration{
+			Kind:     c.Kind,
+			Name:     c.Metadata.Name,
+			Duration: elapsed[t] / time.Duration(counts[t]),
+		})
+	}
+	return nil
+}
+
+func (d *Driver) Dump(ctx context.Context) (string, error) {
+	d.mux.RLock()
+	mods := make(map[string]string, len(d.modules))
+	for name, m := range d.modules {
+		mods[name] = m.parsed.String()
+	}
+	d.mux.RUnlock()
+
+	rs, _, err := d.eval(ctx, "data", nil, false, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(rs) > 1 {
+		return "", errors.New("too many dump results")
+	}
+	var data interface{}
+	for _, r := range rs {
+		for _, e := range r.Expressions {
+			data = e.Value
+		}
+	}
+	b, err := json.MarshalIndent(map[string]interface{}{
+		"modules": mods,
+		"data":    data,
+	}, "", "   ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,271 @@
+package regolimit
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+)
+
+const (
+	hooksModule = `package hooks
+
+violation[r] { r := data.templates[_][_].violation[_] }`
+	bigModule = `package templates.target.Big
+
+violation[{"msg": "big"}] {
+	s := {x | x := numbers.range(1, 100000)[_]}
+	count(s) > 0
+}`
+	smallModule = `package templates.target.Small
+
+violation[{"msg": "small"}] { input.review.denied }`
+)
+
+func newDriver(t *testing.T, limit int64) *Driver {
+	t.Helper()
+	ctx := context.Background()
+	d := NewDriver(limit)
+	if err := d.PutModule(ctx, "hooks", hooksModule); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, `templates["target"]["Big"]`, []string{bigModule}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, `templates["target"]["Small"]`, []string{smallModule}); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newTemplate(kind, limit string) *templates.ConstraintTemplate {
+	ct := &templates.ConstraintTemplate{}
+	ct.SetName(kind)
+	if limit != "" {
+		ct.SetAnnotations(map[string]string{Annotation: limit})
+	}
+	ct.Spec.CRD.Spec.Names.Kind = kind
+	ct.Spec.Targets = []templates.Target{{Target: "target"}}
+	return ct
+}
+
+func query(d *Driver) ([]string, error) {
+	input := map[string]interface{}{"review": map[string]interface{}{"denied": true}}
+	resp, err := d.Query(context.Background(), "hooks.violation", input)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []string
+	for _, r := range resp.Results {
+		msgs = append(msgs, r.Msg)
+	}
+	sort.Strings(msgs)
+	return msgs, nil
+}
+
+func TestDriver_Query(t *testing.T) {
+	tcs := []struct {
+		name        string
+		limit       int64
+		annotations map[string]string
+		want        []string
+		wantErr     *LimitError
+	}{
+		{
+			name: "unlimited",
+			want: []string{"big", "small"},
+		},
+		{
+			name:  "within limit",
+			limit: 1 << 40,
+			want:  []string{"big", "small"},
+		},
+		{
+			name:    "default limit exceeded",
+			limit:   1 << 20,
+			wantErr: &LimitError{Target: "target", Kind: "Big", Limit: 1 << 20},
+		},
+		{
+			name:        "annotation removes limit",
+			limit:       1 << 20,
+			annotations: map[string]string{"Big": "0"},
+			want:        []string{"big", "small"},
+		},
+		{
+			name:        "annotation sets limit",
+			annotations: map[string]string{"Big": "1Mi"},
+			wantErr:     &LimitError{Target: "target", Kind: "Big", Limit: 1 << 20},
+		},
+		{
+			name:        "annotation of other template",
+			annotations: map[string]string{"Small": "1Mi"},
+			want:        []string{"big", "small"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newDriver(t, tc.limit)
+			for kind, limit := range tc.annotations {
+				if err := d.Select(context.Background(), newTemplate(kind, limit)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := query(d)
+			if tc.wantErr != nil {
+				var limitErr *LimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				if diff := cmp.Diff(tc.wantErr, limitErr); diff != "" {
+					t.Error(diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDriver_Select(t *testing.T) {
+	d := newDriver(t, 1<<20)
+	ctx := context.Background()
+	if err := d.Select(ctx, newTemplate("Big", "0")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := query(d); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing the annotation restores the default limit.
+	if err := d.Select(ctx, newTemplate("Big", "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := query(d); err == nil {
+		t.Error("got no error, want the default limit to be exceeded")
+	}
+
+	if err := d.Select(ctx, newTemplate("Big", "lots")); err == nil {
+		t.Error("got no error, want an invalid annotation to be rejected")
+	}
+}
+
+func TestDriver_DeleteModules(t *testing.T) {
+	d := newDriver(t, 1<<20)
+	n, err := d.DeleteModules(context.Background(), `templates["target"]["Big"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got %d modules deleted, want 1", n)
+	}
+	got, err := query(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"small"}, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tcs := []struct {
+		limit   string
+		want    int64
+		wantErr bool
+	}{
+		{limit: "0", want: 0},
+		{limit: "64Mi", want: 64 << 20},
+		{limit: "1G", want: 1000000000},
+		{limit: "-1Mi", wantErr: true},
+		{limit: "lots", wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.limit, func(t *testing.T) {
+			got, err := parseLimit(tc.limit)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	err := &LimitError{Target: "target", Kind: "Big", Limit: 64 << 20}
+	want := "template Big of target target exceeded its memory limit of 64Mi while evaluating the query"
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestDriver_Query_TimesConstraints(t *testing.T) {
+	ctx := context.Background()
+	d := newDriver(t, 0)
+	if err := d.PutModule(ctx, "review", `package hooks["target"]
+
+violation[r] {
+	constraint := data.hooks["target"].library.matching_constraints[_]
+	r := data.templates["target"][constraint.kind].violation[_]
+}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModule(ctx, "library", `package hooks["target"].library
+
+matching_constraints[c] { c := data.constraints[_] }`); err != nil {
+		t.Fatal(err)
+	}
+	constraint := func(kind, name string) map[string]interface{} {
+		return map[string]interface{}{"kind": kind, "metadata": map[string]interface{}{"name": name}}
+	}
+	if err := d.PutData(ctx, "/constraints", []interface{}{
+		constraint("Big", "big"),
+		constraint("Small", "small-a"),
+		constraint("Small", "small-b"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	input := map[string]interface{}{"review": map[string]interface{}{"denied": true}}
+	if _, err := d.Query(ctx, `hooks["target"].violation`, input); err != nil {
+		t.Fatal(err)
+	}
+
+	statsCtx, stats := querystats.NewContext(ctx)
+	if _, err := d.Query(statsCtx, `hooks["target"].violation`, input); err != nil {
+		t.Fatal(err)
+	}
+	durations := make(map[string]time.Duration)
+	for _, c := range stats.Constraints() {
+		durations[c.Kind+"/"+c.Name] = c.Duration
+	}
+	if len(durations) != 3 {
+		t.Fatalf("got durations %v, want one per matching constraint", durations)
+	}
+	if durations["Small/small-a"] != durations["Small/small-b"] {
+		t.Errorf("got durations %v, want the constraints of a template to share its time", durations)
+	}
+	if durations["Big/big"] <= durations["Small/small-a"] {
+		t.Errorf("got durations %v, want Big to take longer than Small", durations)
+	}
+
+	// Other queries are not timed.
+	statsCtx, stats = querystats.NewContext(ctx)
+	if _, err := d.Query(statsCtx, `hooks["target"].library.matching_constraints`, input); err != nil {
+		t.Fatal(err)
+	}
+	if got := stats.Constraints(); len(got) != 0 {
+		t.Errorf("got durations %v for a query other than a review", got)
+	}
+}
@@ -0,0 +1,82 @@
+// Package regolimit bounds the memory a ConstraintTemplate may use to evaluate
+// a query, so that a template which builds a massive comprehension fails with
+// an error naming it instead of exhausting the memory of the pod.
+//
+// A Driver replaces the local Rego driver of the constraint framework. It
+// evaluates queries in the same way, but with a tracer which counts the bytes
+// allocated while the modules of each template are evaluated, and cancels the
+// query once a template exceeds its limit. The count is sampled from the Go
+// runtime, which only counts allocations process-wide, so allocations by
+// concurrent queries are counted against whichever template is being
+// evaluated. Limits should therefore be well above what templates normally
+// use: they guard against runaway templates, not against templates which are
+// merely expensive.
+package regolimit
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Annotation is set on a ConstraintTemplate to the memory limit of the
+// template, as a quantity such as "64Mi", overriding --rego-memory-limit. A
+// limit of "0" lets the template use any amount of memory.
+const Annotation = "rego.gatekeeper.sh/memory-limit"
+
+var limit = flag.String("rego-memory-limit", "", "(alpha) the number of bytes a ConstraintTemplate may allocate while evaluating a single query, as a quantity such as `256Mi`, above which the query fails with an error naming the template. Templates may override it with the "+Annotation+" annotation. 0 limits only templates with the annotation. Disabled if empty")
+
+// Enabled returns whether templates are evaluated with memory limits.
+func Enabled() bool {
+	return *limit != ""
+}
+
+// Validate returns an error if --rego-memory-limit is not a valid limit.
+func Validate() error {
+	if !Enabled() {
+		return nil
+	}
+	if _, err := parseLimit(*limit); err != nil {
+		return fmt.Errorf("invalid --rego-memory-limit: %w", err)
+	}
+	return nil
+}
+
+// NewFromFlags returns a Driver limiting templates to --rego-memory-limit,
+// with the named builtins disabled.
+func NewFromFlags(disabledBuiltins []string) (*Driver, error) {
+	l, err := parseLimit(*limit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --rego-memory-limit: %w", err)
+	}
+	return NewDriver(l, disabledBuiltins...), nil
+}
+
+// parseLimit parses a memory limit, returning its number of bytes.
+func parseLimit(s string) (int64, error) {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	if q.Sign() < 0 {
+		return 0, errors.New("memory limit must not be negative")
+	}
+	return q.Value(), nil
+}
+
+// LimitError is returned by Driver.Query if a template exceeds its memory
+// limit.
+type LimitError struct {
+	// Target and Kind identify the template.
+	Target string
+	Kind   string
+	// Limit is the memory limit of the template, in bytes.
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("template %s of target %s exceeded its memory limit of %s while evaluating the query",
+		e.Kind, e.Target, resource.NewQuantity(e.Limit, resource.BinarySI))
+}
@@ -0,0 +1,57 @@
+package regolimit
+
+import (
+	"time"
+
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// stopwatch is a query tracer which measures the time spent evaluating the
+// modules of each template. The time between two trace events is counted
+// against the template of the first, so calls of builtins, such as those
+// fetching external data, are counted against the template calling them.
+type stopwatch struct {
+	// templates are the templates of modules, keyed by module name.
+	templates map[string]template
+
+	last time.Time
+	// current is the template being evaluated, if any.
+	current template
+	elapsed map[template]time.Duration
+}
+
+var _ topdown.QueryTracer = &stopwatch{}
+
+// start starts measuring a query evaluated with the modules of templates.
+func (s *stopwatch) start(templates map[string]template) {
+	s.templates = templates
+	s.elapsed = make(map[template]time.Duration)
+	s.current = template{}
+	s.last = time.Now()
+}
+
+func (s *stopwatch) Enabled() bool {
+	return true
+}
+
+func (s *stopwatch) Config() topdown.TraceConfig {
+	return topdown.TraceConfig{PlugLocalVars: false}
+}
+
+func (s *stopwatch) TraceEvent(evt topdown.Event) {
+	if evt.Location == nil {
+		return
+	}
+	s.stop()
+	s.current = s.templates[evt.Location.File]
+}
+
+// stop counts the time since the last event against the current template. It
+// is called once more when the query is evaluated.
+func (s *stopwatch) stop() {
+	now := time.Now()
+	if s.current != (template{}) {
+		s.elapsed[s.current] += now.Sub(s.last)
+	}
+	s.last = now
+}
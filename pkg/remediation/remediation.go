@@ -0,0 +1,89 @@
+// Package remediation reads the JSON patches ConstraintTemplates may return
+// with a violation to fix it, so that tooling can offer to apply them.
+//
+// The constraint framework only passes the msg and details of a violation on,
+// so a patch is returned under the remediation key of its details:
+//
+//	violation[{"msg": msg, "details": {"remediation": [
+//	  {"op": "add", "path": "/metadata/labels/owner", "value": "unknown"},
+//	]}}] {
+//	  ...
+//	}
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	constraintTypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+)
+
+// DetailsKey is the key of the details of a violation holding its patch.
+const DetailsKey = "remediation"
+
+// Operation is an operation of a JSON patch, as defined by RFC 6902.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is a JSON patch, as defined by RFC 6902.
+type Patch []Operation
+
+// String returns p encoded as JSON.
+func (p Patch) String() string {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Sprintf("%v", []Operation(p))
+	}
+	return string(b)
+}
+
+var fromOps = map[string]bool{"move": true, "copy": true}
+
+var valueOps = map[string]bool{"add": true, "replace": true, "test": true}
+
+// Get returns the patch in the details of r, or nil if it has none. An error
+// is returned if the patch is not a valid JSON patch.
+func Get(r *constraintTypes.Result) (Patch, error) {
+	details, ok := r.Metadata["details"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	raw, ok := details[DetailsKey]
+	if !ok {
+		return nil, nil
+	}
+
+	// The details were decoded from JSON, so re-encoding them cannot fail.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var patch Patch
+	if err := json.Unmarshal(b, &patch); err != nil {
+		return nil, fmt.Errorf("remediation must be a list of JSON patch operations: %w", err)
+	}
+	if len(patch) == 0 {
+		return nil, nil
+	}
+
+	for i, op := range patch {
+		if !valueOps[op.Op] && !fromOps[op.Op] && op.Op != "remove" {
+			return nil, fmt.Errorf("remediation operation %d has unknown op %q", i, op.Op)
+		}
+		if op.Path != "" && !strings.HasPrefix(op.Path, "/") {
+			return nil, fmt.Errorf("remediation operation %d has invalid path %q", i, op.Path)
+		}
+		if valueOps[op.Op] && op.Value == nil {
+			return nil, fmt.Errorf("remediation operation %d is %s without a value", i, op.Op)
+		}
+		if fromOps[op.Op] && op.From == "" {
+			return nil, fmt.Errorf("remediation operation %d is %s without from", i, op.Op)
+		}
+	}
+	return patch, nil
+}
@@ -0,0 +1,72 @@
+package remediation
+
+import (
+	"encoding/json"
+	"testing"
+
+	constraintTypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+)
+
+func result(details string) *constraintTypes.Result {
+	r := &constraintTypes.Result{}
+	if details == "" {
+		return r
+	}
+	var d interface{}
+	if err := json.Unmarshal([]byte(details), &d); err != nil {
+		panic(err)
+	}
+	r.Metadata = map[string]interface{}{"details": d}
+	return r
+}
+
+func TestGet(t *testing.T) {
+	tcs := []struct {
+		name    string
+		details string
+		want    string
+		wantErr bool
+	}{
+		{name: "no details"},
+		{name: "no remediation", details: `{"missing": ["owner"]}`},
+		{name: "empty remediation", details: `{"remediation": []}`},
+		{
+			name:    "add label",
+			details: `{"remediation": [{"op": "add", "path": "/metadata/labels/owner", "value": "unknown"}]}`,
+			want:    `[{"op":"add","path":"/metadata/labels/owner","value":"unknown"}]`,
+		},
+		{
+			name:    "null value",
+			details: `{"remediation": [{"op": "replace", "path": "/spec/hostNetwork", "value": null}]}`,
+			want:    `[{"op":"replace","path":"/spec/hostNetwork","value":null}]`,
+		},
+		{
+			name:    "remove and move",
+			details: `{"remediation": [{"op": "remove", "path": "/metadata/labels/a"}, {"op": "move", "from": "/metadata/labels/b", "path": "/metadata/labels/c"}]}`,
+			want:    `[{"op":"remove","path":"/metadata/labels/a"},{"op":"move","path":"/metadata/labels/c","from":"/metadata/labels/b"}]`,
+		},
+		{name: "not a list", details: `{"remediation": "add the owner label"}`, wantErr: true},
+		{name: "unknown op", details: `{"remediation": [{"op": "append", "path": "/a"}]}`, wantErr: true},
+		{name: "relative path", details: `{"remediation": [{"op": "remove", "path": "a"}]}`, wantErr: true},
+		{name: "add without value", details: `{"remediation": [{"op": "add", "path": "/a"}]}`, wantErr: true},
+		{name: "copy without from", details: `{"remediation": [{"op": "copy", "path": "/a"}]}`, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Get(result(tc.details))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if tc.want == "" {
+				if got != nil {
+					t.Errorf("got patch %s, want none", got)
+				}
+				return
+			}
+			if got.String() != tc.want {
+				t.Errorf("got patch %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package remoteopa
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/storage"
+)
+
+// builtBundle is a bundle written for a generation of the driver.
+type builtBundle struct {
+	generation uint64
+	revision   string
+	raw        []byte
+}
+
+// bundleLocked returns the modules and data of d as a bundle, and the
+// generation it was built from. Its revision is a digest of its content, so
+// pods holding the same policy serve bundles with the same revision. The data
+// of the bundle is shared with the store of d, so it must be written while
+// still holding d.mux.
+func (d *Driver) bundleLocked(ctx context.Context) (*bundle.Bundle, uint64, error) {
+	data, err := storage.ReadOne(ctx, d.store, storage.Path{})
+	if err != nil {
+		return nil, 0, err
+	}
+	dataMap, _ := data.(map[string]interface{})
+	if dataMap == nil {
+		dataMap = make(map[string]interface{})
+	}
+
+	digest := sha256.New()
+	if err := json.NewEncoder(digest).Encode(dataMap); err != nil {
+		return nil, 0, err
+	}
+
+	names := make([]string, 0, len(d.modules))
+	for name := range d.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b := &bundle.Bundle{Data: dataMap}
+	for _, name := range names {
+		path := "/" + url.PathEscape(name) + ".rego"
+		b.Modules = append(b.Modules, bundle.ModuleFile{
+			URL:    path,
+			Path:   path,
+			Raw:    []byte(d.modules[name]),
+			Parsed: d.parsed[name],
+		})
+		digest.Write([]byte(path))
+		digest.Write([]byte{0})
+		digest.Write([]byte(d.modules[name]))
+		digest.Write([]byte{0})
+	}
+	b.Manifest.Revision = hex.EncodeToString(digest.Sum(nil))
+	return b, d.generation, nil
+}
+
+// built returns the current bundle of d, writing it only if d has changed
+// since it was last written.
+func (d *Driver) built(ctx context.Context) (*builtBundle, error) {
+	d.bundleMux.Lock()
+	defer d.bundleMux.Unlock()
+
+	d.mux.RLock()
+	if d.bundle != nil && d.bundle.generation == d.generation {
+		d.mux.RUnlock()
+		return d.bundle, nil
+	}
+	defer d.mux.RUnlock()
+	b, generation, err := d.bundleLocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(*b); err != nil {
+		return nil, err
+	}
+	d.bundle = &builtBundle{generation: generation, revision: b.Manifest.Revision, raw: buf.Bytes()}
+	return d.bundle, nil
+}
+
+// BundleHandler serves the bundle of d once ready returns true. Until then the
+// policy of d may be incomplete, and serving it would have the external OPA
+// drop policy it already enforces. Requests whose If-None-Match header holds
+// the ETag of the current bundle are answered with 304 Not Modified.
+func (d *Driver) BundleHandler(ready func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !ready() {
+			http.Error(w, "policy has not been fully ingested", http.StatusServiceUnavailable)
+			return
+		}
+		b, err := d.built(req.Context())
+		if err != nil {
+			log.Error(err, "building bundle")
+			http.Error(w, "unable to build bundle", http.StatusInternalServerError)
+			return
+		}
+		etag := `"` + b.revision + `"`
+		w.Header().Set("ETag", etag)
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		if _, err := w.Write(b.raw); err != nil {
+			log.Error(err, "writing bundle")
+		}
+	})
+}
@@ -0,0 +1,333 @@
+package remoteopa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+// Module sets are named as in the local driver, so module names are the same
+// whichever driver holds them.
+const (
+	moduleSetPrefix = "__modset_"
+	moduleSetSep    = "_idx_"
+)
+
+// Driver is a constraint framework driver which keeps modules and data to be
+// served as a bundle, and evaluates queries with an external OPA.
+type Driver struct {
+	url       *url.URL
+	tokenFile string
+	client    *http.Client
+
+	mux sync.RWMutex
+	// modules are the sources of the modules put into the driver, keyed by
+	// name.
+	modules map[string]string
+	// parsed are the parsed modules, keyed by name. Every change is compiled
+	// so that Rego errors are returned to the constraint framework, as they
+	// would be by the local driver.
+	parsed map[string]*ast.Module
+	store  storage.Store
+	// generation is incremented on every change, so the bundle is only
+	// rebuilt once it is out of date.
+	generation uint64
+
+	bundleMux sync.Mutex
+	bundle    *builtBundle
+}
+
+var _ drivers.Driver = &Driver{}
+
+// NewDriver returns a Driver querying the OPA at rawURL with client,
+// authenticated with the bearer token in tokenFile if it is not empty.
+func NewDriver(rawURL, tokenFile string, client *http.Client) (*Driver, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing remote OPA URL: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return &Driver{
+		url:       u,
+		tokenFile: tokenFile,
+		client:    client,
+		modules:   make(map[string]string),
+		parsed:    make(map[string]*ast.Module),
+		store:     inmem.New(),
+	}, nil
+}
+
+func (d *Driver) Init(ctx context.Context) error {
+	return nil
+}
+
+func (d *Driver) PutModule(ctx context.Context, name string, src string) error {
+	if name == "" {
+		return fmt.Errorf("module name cannot be empty")
+	}
+	if strings.HasPrefix(name, moduleSetPrefix) {
+		return fmt.Errorf("single modules not allowed to use name prefix %s", moduleSetPrefix)
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	_, err := d.alterModulesLocked(map[string]string{name: src}, nil)
+	return err
+}
+
+func (d *Driver) PutModules(ctx context.Context, namePrefix string, srcs []string) error {
+	if err := checkModuleSetName(namePrefix); err != nil {
+		return err
+	}
+	insert := make(map[string]string, len(srcs))
+	for idx, src := range srcs {
+		insert[fmt.Sprintf("%s%d", moduleSetName(namePrefix), idx)] = src
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	var remove []string
+	for _, name := range d.listModuleSetLocked(namePrefix) {
+		if _, ok := insert[name]; !ok {
+			remove = append(remove, name)
+		}
+	}
+	_, err := d.alterModulesLocked(insert, remove)
+	return err
+}
+
+func (d *Driver) DeleteModule(ctx context.Context, name string) (bool, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if _, ok := d.modules[name]; !ok {
+		return false, nil
+	}
+	n, err := d.alterModulesLocked(nil, []string{name})
+	return n == 1, err
+}
+
+func (d *Driver) DeleteModules(ctx context.Context, namePrefix string) (int, error) {
+	if err := checkModuleSetName(namePrefix); err != nil {
+		return 0, err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.alterModulesLocked(nil, d.listModuleSetLocked(namePrefix))
+}
+
+func checkModuleSetName(namePrefix string) error {
+	if namePrefix == "" {
+		return fmt.Errorf("modules name prefix cannot be empty")
+	}
+	if strings.Contains(namePrefix, moduleSetSep) {
+		return fmt.Errorf("modules name prefix not allowed to contain the sequence %s", moduleSetSep)
+	}
+	return nil
+}
+
+func moduleSetName(namePrefix string) string {
+	return moduleSetPrefix + namePrefix + moduleSetSep
+}
+
+// listModuleSetLocked returns the names of the modules put under namePrefix.
+// Must be called while holding d.mux.
+func (d *Driver) listModuleSetLocked(namePrefix string) []string {
+	prefix := moduleSetName(namePrefix)
+	var names []string
+	for name := range d.modules {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// alterModulesLocked inserts and removes modules, returning the number
+// removed. Nothing is changed if the resulting modules do not compile. Must
+// be called while holding d.mux for writing.
+func (d *Driver) alterModulesLocked(insert map[string]string, remove []string) (int, error) {
+	updated := make(map[string]*ast.Module, len(d.parsed)+len(insert))
+	for name, m := range d.parsed {
+		updated[name] = m
+	}
+	for _, name := range remove {
+		delete(updated, name)
+	}
+	for name, src := range insert {
+		m, err := ast.ParseModule(name, src)
+		if err != nil {
+			return 0, err
+		}
+		if m == nil {
+			return 0, fmt.Errorf("module %s is empty", name)
+		}
+		updated[name] = m
+	}
+
+	c := ast.NewCompiler()
+	if c.Compile(updated); c.Failed() {
+		return 0, c.Errors
+	}
+
+	for _, name := range remove {
+		delete(d.modules, name)
+	}
+	for name, src := range insert {
+		d.modules[name] = src
+	}
+	d.parsed = updated
+	d.generation++
+	return len(remove), nil
+}
+
+func (d *Driver) PutData(ctx context.Context, path string, data interface{}) error {
+	p, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	err = storage.Txn(ctx, d.store, storage.WriteParams, func(txn storage.Transaction) error {
+		if _, err := d.store.Read(ctx, txn, p); err != nil {
+			if !storage.IsNotFound(err) {
+				return err
+			}
+			if err := storage.MakeDir(ctx, d.store, txn, p[:len(p)-1]); err != nil {
+				return err
+			}
+		}
+		return d.store.Write(ctx, txn, storage.AddOp, p, data)
+	})
+	if err != nil {
+		return err
+	}
+	d.generation++
+	return nil
+}
+
+func (d *Driver) DeleteData(ctx context.Context, path string) (bool, error) {
+	p, err := parsePath(path)
+	if err != nil {
+		return false, err
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	err = storage.Txn(ctx, d.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return d.store.Write(ctx, txn, storage.RemoveOp, p, nil)
+	})
+	if storage.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	d.generation++
+	return true, nil
+}
+
+func parsePath(path string) (storage.Path, error) {
+	p, ok := storage.ParsePathEscaped(path)
+	if !ok || len(p) == 0 {
+		return nil, fmt.Errorf("bad data path: %s", path)
+	}
+	return p, nil
+}
+
+// Query evaluates path with the external OPA. Tracing is not supported, so
+// the returned Response never has a Trace.
+func (d *Driver) Query(ctx context.Context, path string, input interface{}, opts ...drivers.QueryOpt) (*types.Response, error) {
+	inp, err := json.MarshalIndent(input, "", "   ")
+	if err != nil {
+		return nil, err
+	}
+	u, err := d.dataURL(path)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.tokenFile != "" {
+		token, err := ioutil.ReadFile(d.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading remote OPA token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying remote OPA: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("querying remote OPA: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	// The result is undefined, and so missing, until the OPA has loaded a
+	// bundle defining path.
+	var decoded struct {
+		Result []*types.Result `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("decoding remote OPA response: %w", err)
+	}
+	i := string(inp)
+	return &types.Response{
+		Results: decoded.Result,
+		Input:   &i,
+	}, nil
+}
+
+// dataURL returns the URL of the Data API document at path, a reference
+// relative to data such as `hooks["admission.k8s.gatekeeper.sh"].violation`.
+func (d *Driver) dataURL(path string) (string, error) {
+	ref, err := ast.ParseRef("data." + path)
+	if err != nil {
+		return "", fmt.Errorf("parsing query path %q: %w", path, err)
+	}
+	segments := make([]string, 0, len(ref)-1)
+	for _, term := range ref[1:] {
+		s, ok := term.Value.(ast.String)
+		if !ok {
+			return "", fmt.Errorf("query path %q may only reference documents by name", path)
+		}
+		segments = append(segments, url.PathEscape(string(s)))
+	}
+	return d.url.String() + "/v1/data/" + strings.Join(segments, "/"), nil
+}
+
+func (d *Driver) Dump(ctx context.Context) (string, error) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	data, err := storage.ReadOne(ctx, d.store, storage.Path{})
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(map[string]interface{}{
+		"modules": d.modules,
+		"data":    data,
+	}, "", "   ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
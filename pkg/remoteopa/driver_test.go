@@ -0,0 +1,184 @@
+package remoteopa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/opa/bundle"
+)
+
+const (
+	hooksModule = `package hooks
+
+violation[r] { r := data.templates[_][_].violation[_] }`
+	templateModule = `package templates.target.Kind
+
+violation[{"msg": "denied"}] { input.review.denied }`
+)
+
+func newDriver(t *testing.T, u string) *Driver {
+	t.Helper()
+	d, err := NewDriver(u, "", http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDriver_Query(t *testing.T) {
+	var gotPath string
+	var gotInput interface{}
+	opa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.EscapedPath()
+		var body struct {
+			Input interface{} `json:"input"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		gotInput = body.Input
+		if req.URL.Path == "/v1/data/hooks/undefined/violation" {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"result": [{"msg": "denied", "enforcementAction": "deny"}]}`))
+	}))
+	defer opa.Close()
+
+	d := newDriver(t, opa.URL+"/")
+	input := map[string]interface{}{"review": map[string]interface{}{"denied": true}}
+	resp, err := d.Query(context.Background(), `hooks["admission.k8s.gatekeeper.sh"].violation`, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/v1/data/hooks/admission.k8s.gatekeeper.sh/violation" {
+		t.Errorf("got query of %s", gotPath)
+	}
+	if diff := cmp.Diff(input, gotInput); diff != "" {
+		t.Error(diff)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Msg != "denied" || resp.Results[0].EnforcementAction != "deny" {
+		t.Errorf("got results %v", resp.Results)
+	}
+
+	resp, err = d.Query(context.Background(), `hooks.undefined.violation`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 0 {
+		t.Errorf("got results %v for an undefined document, want none", resp.Results)
+	}
+}
+
+func TestDriver_Query_Error(t *testing.T) {
+	opa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, `{"code": "internal_error"}`, http.StatusInternalServerError)
+	}))
+	defer opa.Close()
+
+	d := newDriver(t, opa.URL)
+	if _, err := d.Query(context.Background(), `hooks["target"].violation`, nil); err == nil {
+		t.Error("got no error from a failing OPA")
+	}
+	if _, err := d.Query(context.Background(), `hooks[x].violation`, nil); err == nil {
+		t.Error("got no error querying a path with a variable")
+	}
+}
+
+func TestDriver_PutModules(t *testing.T) {
+	ctx := context.Background()
+	d := newDriver(t, "http://opa")
+	if err := d.PutModule(ctx, "hooks", hooksModule); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, `templates["target"]["Kind"]`, []string{templateModule}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, `templates["target"]["Broken"]`, []string{"package broken\n\nviolation[r] { r := undefined_function(1) }"}); err == nil {
+		t.Error("got no error putting modules which do not compile")
+	}
+	if _, ok := d.modules[moduleSetName(`templates["target"]["Broken"]`)+"0"]; ok {
+		t.Error("got modules which do not compile put")
+	}
+	if n, err := d.DeleteModules(ctx, `templates["target"]["Kind"]`); err != nil || n != 1 {
+		t.Errorf("got %d, %v deleting a module set, want 1 module deleted", n, err)
+	}
+	if deleted, err := d.DeleteModule(ctx, "missing"); err != nil || deleted {
+		t.Errorf("got %v, %v deleting a missing module, want false", deleted, err)
+	}
+}
+
+func TestDriver_BundleHandler(t *testing.T) {
+	ctx := context.Background()
+	d := newDriver(t, "http://opa")
+	if err := d.PutModule(ctx, "hooks", hooksModule); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutModules(ctx, `templates["target"]["Kind"]`, []string{templateModule}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PutData(ctx, "/constraints/target/cluster/constraints.gatekeeper.sh/Kind/c", map[string]interface{}{"kind": "Kind"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ready := false
+	handler := d.BundleHandler(func() bool { return ready })
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, BundlePath, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get(""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d before ready, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	ready = true
+
+	rec := get("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	b, err := bundle.NewReader(rec.Body).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modules []string
+	for _, m := range b.Modules {
+		modules = append(modules, string(m.Raw))
+	}
+	sort.Strings(modules)
+	if diff := cmp.Diff([]string{hooksModule, templateModule}, modules); diff != "" {
+		t.Error(diff)
+	}
+	wantData := map[string]interface{}{
+		"constraints": map[string]interface{}{"target": map[string]interface{}{"cluster": map[string]interface{}{
+			"constraints.gatekeeper.sh": map[string]interface{}{"Kind": map[string]interface{}{"c": map[string]interface{}{"kind": "Kind"}}},
+		}}},
+	}
+	if diff := cmp.Diff(wantData, b.Data); diff != "" {
+		t.Error(diff)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag != `"`+b.Manifest.Revision+`"` {
+		t.Errorf("got ETag %s for revision %s", etag, b.Manifest.Revision)
+	}
+
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Errorf("got status %d for an unchanged bundle, want %d", rec.Code, http.StatusNotModified)
+	}
+	if deleted, err := d.DeleteData(ctx, "/constraints/target/cluster/constraints.gatekeeper.sh/Kind/c"); err != nil || !deleted {
+		t.Fatalf("got %v, %v deleting data, want true", deleted, err)
+	}
+	if rec := get(etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("got status %d and ETag %s after a change, want a new bundle", rec.Code, rec.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,83 @@
+// Package remoteopa lets policy be evaluated by an external OPA, such as a
+// centrally run OPA or Styra cluster, rather than by the Gatekeeper pods.
+//
+// A Driver replaces the local Rego driver of the constraint framework. It
+// keeps the Rego modules generated from ConstraintTemplates, the constraints
+// and the replicated data, and serves them as an OPA bundle from the
+// introspection endpoint once the pod is ready. The external OPA polls the
+// bundle, and the Driver queries it over its REST API to review objects and
+// audit the cluster. Changes to policy are therefore only enforced once the
+// external OPA has downloaded the next bundle.
+package remoteopa
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// BundlePath is the introspection path serving the policy of a pod as an OPA
+// bundle.
+const BundlePath = "/bundles/gatekeeper.tar.gz"
+
+var (
+	// URL is the base URL of the external OPA. Policy is evaluated locally if
+	// it is empty.
+	URL = flag.String("remote-opa-url", "", "(alpha) base URL of an external OPA to evaluate policy with instead of the Gatekeeper pods, for example `https://opa.opa-system:8181`. The OPA must load the bundle each pod serves at "+BundlePath+" of --introspection-addr. Disabled if empty")
+
+	tokenFile = flag.String("remote-opa-token-file", "", "(alpha) file holding the bearer token to authenticate to the external OPA with. It is read for every query, so it may be rotated")
+	caFile    = flag.String("remote-opa-ca-file", "", "(alpha) file holding the PEM encoded certificates of the authorities to verify the external OPA with, instead of the system roots")
+	timeout   = flag.Duration("remote-opa-timeout", 3*time.Second, "(alpha) timeout of each query to the external OPA")
+
+	log = logf.Log.WithName("remote-opa")
+)
+
+// Validate returns an error if the external OPA cannot be queried, or cannot
+// download the bundle of this pod.
+func Validate() error {
+	if *URL == "" {
+		return nil
+	}
+	u, err := url.Parse(*URL)
+	if err != nil {
+		return fmt.Errorf("parsing --remote-opa-url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("--remote-opa-url must be an http or https URL, not %q", *URL)
+	}
+	if *introspection.Addr == "" {
+		return errors.New("--remote-opa-url requires --introspection-addr to serve the bundle of policy")
+	}
+	if *timeout <= 0 {
+		return errors.New("--remote-opa-timeout must be positive")
+	}
+	return nil
+}
+
+// NewFromFlags returns a Driver querying the external OPA of --remote-opa-url.
+func NewFromFlags() (*Driver, error) {
+	client := &http.Client{Timeout: *timeout}
+	if *caFile != "" {
+		pem, err := ioutil.ReadFile(*caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading --remote-opa-ca-file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", *caFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+		client.Transport = transport
+	}
+	return NewDriver(*URL, *tokenFile, client)
+}
@@ -0,0 +1,207 @@
+// Package review reviews streams of objects against the constraint framework.
+//
+// Reviewing many objects at once, as the framework's Audit does, returns the
+// results for every object in a single response, so memory grows with the
+// number of violations. Stream instead hands the results for each object to
+// the caller as soon as that object is reviewed, and holds no more than one
+// object's results at a time. StreamParallel reviews several objects at once,
+// holding the results of at most one object per worker.
+package review
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+)
+
+// Reviewer reviews single objects. It is satisfied by the constraint
+// framework's client.
+type Reviewer interface {
+	Review(ctx context.Context, obj interface{}, opts ...opa.QueryOpt) (*types.Responses, error)
+}
+
+var _ Reviewer = &opa.Client{}
+
+// Iterator yields the objects to review.
+type Iterator interface {
+	// Next returns the next object to review, or false once no objects remain.
+	Next(ctx context.Context) (interface{}, bool, error)
+}
+
+// Result is the outcome of reviewing a single object.
+type Result struct {
+	Object  interface{}
+	Results []*types.Result
+	// Duration is how long the object took to review.
+	Duration time.Duration
+	// Stats are the statistics of the queries reviewing the object, if
+	// --query-stats or --metrics-template-duration is set and the driver of
+	// the Reviewer records them.
+	Stats querystats.Stats
+	// Constraints are the time spent evaluating each constraint matching the
+	// object, if --metrics-template-duration is set and the driver of the
+	// Reviewer times them.
+	Constraints []querystats.ConstraintDuration
+}
+
+// Limiter bounds how many objects StreamParallel reviews at once, beyond its
+// number of workers.
+type Limiter interface {
+	// Acquire blocks until obj may be reviewed, returning a function to call
+	// once it has been reviewed. Returns an error if ctx is done first.
+	Acquire(ctx context.Context, obj interface{}) (func(), error)
+}
+
+// Stream reviews each object yielded by objects, calling fn with the result
+// before moving on to the next object.
+//
+// Objects which fail review are skipped, and their errors returned once every
+// object has been reviewed. An error from objects or fn stops the stream
+// immediately.
+func Stream(ctx context.Context, r Reviewer, objects Iterator, fn func(Result) error) error {
+	var errs opa.Errors
+	for {
+		obj, ok, err := objects.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		result, err := reviewObject(ctx, r, nil, obj)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// StreamParallel is Stream with up to workers objects reviewed at once, each
+// once limiter lets it be if limiter is not nil. Objects are yielded and fn
+// called from a single goroutine at a time, so neither need be safe for
+// concurrent use, but fn is called in the order objects finish review rather
+// than the order they were yielded.
+func StreamParallel(ctx context.Context, r Reviewer, objects Iterator, workers int, limiter Limiter, fn func(Result) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pending := make(chan interface{})
+	// nextErr is written before pending is closed, so is safe to read once
+	// every worker is done.
+	var nextErr error
+	go func() {
+		defer close(pending)
+		for {
+			obj, ok, err := objects.Next(ctx)
+			if err != nil {
+				nextErr = err
+				return
+			}
+			if !ok {
+				return
+			}
+			select {
+			case pending <- obj:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	type outcome struct {
+		result Result
+		err    error
+	}
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range pending {
+				result, err := reviewObject(ctx, r, limiter, obj)
+				select {
+				case outcomes <- outcome{result: result, err: err}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	var errs opa.Errors
+	var fnErr error
+	for o := range outcomes {
+		switch {
+		case fnErr != nil:
+			// Drain the outcomes of the objects under review once stopped.
+		case o.err != nil:
+			errs = append(errs, o.err)
+		default:
+			if err := fn(o.result); err != nil {
+				fnErr = err
+				cancel()
+			}
+		}
+	}
+
+	switch {
+	case fnErr != nil:
+		return fnErr
+	case nextErr != nil:
+		return nextErr
+	case len(errs) > 0:
+		return errs
+	}
+	return nil
+}
+
+// reviewObject reviews obj once limiter, if not nil, lets it be reviewed.
+func reviewObject(ctx context.Context, r Reviewer, limiter Limiter, obj interface{}) (Result, error) {
+	if limiter != nil {
+		release, err := limiter.Acquire(ctx, obj)
+		if err != nil {
+			return Result{}, err
+		}
+		defer release()
+	}
+
+	start := time.Now()
+	reviewCtx := ctx
+	var stats *querystats.Recorder
+	if *querystats.Enabled || *metrics.TemplateDuration {
+		reviewCtx, stats = querystats.NewContext(ctx)
+	}
+	resp, err := r.Review(reviewCtx, obj)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Object: obj, Results: resp.Results(), Duration: time.Since(start)}
+	if stats != nil {
+		result.Stats = stats.Stats()
+		if *metrics.TemplateDuration {
+			result.Constraints = stats.Constraints()
+		}
+	}
+	return result, nil
+}
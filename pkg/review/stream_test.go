@@ -0,0 +1,220 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+)
+
+type sliceIterator []interface{}
+
+func (it *sliceIterator) Next(_ context.Context) (interface{}, bool, error) {
+	if len(*it) == 0 {
+		return nil, false, nil
+	}
+	obj := (*it)[0]
+	*it = (*it)[1:]
+	return obj, true, nil
+}
+
+// fakeReviewer returns one violation for each object named "bad", and fails
+// to review objects named "broken".
+type fakeReviewer struct{}
+
+func (fakeReviewer) Review(_ context.Context, obj interface{}, _ ...opa.QueryOpt) (*types.Responses, error) {
+	resp := types.NewResponses()
+	switch obj {
+	case "broken":
+		return resp, errors.New("review failed")
+	case "bad":
+		resp.ByTarget["target"] = &types.Response{Target: "target", Results: []*types.Result{{Msg: "bad object"}}}
+	}
+	return resp, nil
+}
+
+func TestStream(t *testing.T) {
+	objects := &sliceIterator{"good", "bad", "broken", "bad"}
+	var reviewed []interface{}
+	violations := 0
+	err := Stream(context.Background(), fakeReviewer{}, objects, func(r Result) error {
+		reviewed = append(reviewed, r.Object)
+		violations += len(r.Results)
+		return nil
+	})
+
+	var errs opa.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("got error %v, want the one failed review", err)
+	}
+	if len(reviewed) != 3 {
+		t.Errorf("got %d objects reviewed, want every object which did not fail review", len(reviewed))
+	}
+	if violations != 2 {
+		t.Errorf("got %d violations, want 2", violations)
+	}
+}
+
+func TestStreamStops(t *testing.T) {
+	objects := &sliceIterator{"bad", "bad", "bad"}
+	stop := errors.New("stop")
+	calls := 0
+	err := Stream(context.Background(), fakeReviewer{}, objects, func(r Result) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("got error %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want the stream to stop after the first", calls)
+	}
+	if len(*objects) != 2 {
+		t.Errorf("got %d objects left, want 2", len(*objects))
+	}
+}
+
+func TestStreamParallel(t *testing.T) {
+	objects := &sliceIterator{"good", "bad", "broken", "bad", "good", "bad"}
+	reviewed := 0
+	violations := 0
+	err := StreamParallel(context.Background(), fakeReviewer{}, objects, 3, nil, func(r Result) error {
+		reviewed++
+		violations += len(r.Results)
+		return nil
+	})
+
+	var errs opa.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("got error %v, want the one failed review", err)
+	}
+	if reviewed != 5 {
+		t.Errorf("got %d objects reviewed, want every object which did not fail review", reviewed)
+	}
+	if violations != 3 {
+		t.Errorf("got %d violations, want 3", violations)
+	}
+}
+
+func TestStreamParallelStops(t *testing.T) {
+	objects := &sliceIterator{"bad", "bad", "bad", "bad", "bad"}
+	stop := errors.New("stop")
+	calls := 0
+	err := StreamParallel(context.Background(), fakeReviewer{}, objects, 2, nil, func(r Result) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("got error %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want the stream to stop after the first", calls)
+	}
+}
+
+// countingLimiter lets up to max objects be reviewed at once, recording the
+// most which were.
+type countingLimiter struct {
+	mux      sync.Mutex
+	slots    chan struct{}
+	inFlight int
+	most     int
+}
+
+func (l *countingLimiter) Acquire(ctx context.Context, _ interface{}) (func(), error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	l.mux.Lock()
+	l.inFlight++
+	if l.inFlight > l.most {
+		l.most = l.inFlight
+	}
+	l.mux.Unlock()
+	return func() {
+		l.mux.Lock()
+		l.inFlight--
+		l.mux.Unlock()
+		<-l.slots
+	}, nil
+}
+
+// slowReviewer takes a moment to review each object, so that workers overlap.
+type slowReviewer struct{}
+
+func (slowReviewer) Review(_ context.Context, _ interface{}, _ ...opa.QueryOpt) (*types.Responses, error) {
+	time.Sleep(10 * time.Millisecond)
+	return types.NewResponses(), nil
+}
+
+func TestStreamParallelLimiter(t *testing.T) {
+	objects := &sliceIterator{"a", "b", "c", "d", "e", "f", "g", "h"}
+	limiter := &countingLimiter{slots: make(chan struct{}, 2)}
+	reviewed := 0
+	err := StreamParallel(context.Background(), slowReviewer{}, objects, 4, limiter, func(r Result) error {
+		reviewed++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewed != 8 {
+		t.Errorf("got %d objects reviewed, want 8", reviewed)
+	}
+	if limiter.most > 2 {
+		t.Errorf("got %d objects reviewed at once, want at most 2", limiter.most)
+	}
+}
+
+// timingReviewer records the time spent evaluating one constraint and one
+// call of a custom builtin for each object, as a driver recording them would.
+type timingReviewer struct{}
+
+func (timingReviewer) Review(ctx context.Context, _ interface{}, _ ...opa.QueryOpt) (*types.Responses, error) {
+	querystats.CountExternalDataCall(ctx)
+	querystats.RecordConstraint(ctx, querystats.ConstraintDuration{Kind: "K8sRequiredLabels", Name: "labels", Duration: time.Millisecond})
+	return types.NewResponses(), nil
+}
+
+func TestStreamRecording(t *testing.T) {
+	tcs := []struct {
+		name             string
+		queryStats       bool
+		templateDuration bool
+		wantCalls        int
+		wantConstraints  int
+	}{
+		{name: "disabled"},
+		{name: "query stats", queryStats: true, wantCalls: 1},
+		{name: "template duration", templateDuration: true, wantCalls: 1, wantConstraints: 1},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			oldStats, oldDuration := *querystats.Enabled, *metrics.TemplateDuration
+			*querystats.Enabled, *metrics.TemplateDuration = tc.queryStats, tc.templateDuration
+			defer func() { *querystats.Enabled, *metrics.TemplateDuration = oldStats, oldDuration }()
+
+			err := Stream(context.Background(), timingReviewer{}, &sliceIterator{"good"}, func(r Result) error {
+				if r.Stats.ExternalDataCalls != tc.wantCalls {
+					t.Errorf("got %d builtin calls recorded, want %d", r.Stats.ExternalDataCalls, tc.wantCalls)
+				}
+				if len(r.Constraints) != tc.wantConstraints {
+					t.Errorf("got constraint durations %v, want %d", r.Constraints, tc.wantConstraints)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tcs := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "steps ranges and lists", expr: "*/15 9-17 1,15 1-12/2 1-5"},
+		{name: "Sunday as 7", expr: "0 0 * * 7"},
+		{name: "too few fields", expr: "* * * *", wantErr: true},
+		{name: "too many fields", expr: "* * * * * *", wantErr: true},
+		{name: "out of range", expr: "60 * * * *", wantErr: true},
+		{name: "inverted range", expr: "* 17-9 * * *", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "named day", expr: "* * * * MON", wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseCron(tc.expr)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2021-06-06 is a Sunday.
+	sunday := time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC)
+	tcs := []struct {
+		name string
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{name: "every minute", expr: "* * * * *", t: sunday.Add(37 * time.Minute), want: true},
+		{name: "step matches", expr: "*/15 * * * *", t: sunday.Add(45 * time.Minute), want: true},
+		{name: "step does not match", expr: "*/15 * * * *", t: sunday.Add(46 * time.Minute)},
+		{name: "Sunday as 7", expr: "0 0 * * 7", t: sunday, want: true},
+		{name: "Sunday as 0", expr: "0 0 * * 0", t: sunday, want: true},
+		{name: "weekday on Sunday", expr: "0 0 * * 1-5", t: sunday},
+		{name: "weekday on Monday", expr: "0 0 * * 1-5", t: sunday.AddDate(0, 0, 1), want: true},
+		{name: "day of month or day of week", expr: "0 0 15 * 0", t: sunday, want: true},
+		{name: "day of month and any day of week", expr: "0 0 15 * *", t: sunday},
+		{name: "month", expr: "0 0 * 7 *", t: sunday},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := parseCron(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.matches(tc.t); got != tc.want {
+				t.Errorf("got matches(%v) = %v, want %v", tc.t, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package schedule limits when constraints are enforced with the
+// enforcementSchedule of their spec.
+//
+// A schedule lists active windows, outside of which the constraint is not
+// enforced, and frozen windows, within which it is not enforced. Each window
+// opens when its cron expression fires, in the time zone of the schedule, and
+// stays open for its duration. The webhook and audit drop the results of
+// constraints which are not active.
+package schedule
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	// Time zones are loaded from the binary, as images may not provide them.
+	_ "time/tzdata"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("schedule")
+
+// Enabled is whether the enforcementSchedule of constraints is honored.
+var Enabled = flag.Bool("enable-enforcement-schedules", false, "(alpha) only enforce constraints within the active windows, and outside the frozen windows, of their spec.enforcementSchedule")
+
+// maxDuration bounds the duration of a window.
+const maxDuration = 31 * 24 * time.Hour
+
+// Window is a period starting whenever Cron fires.
+type Window struct {
+	// Cron is a five-field cron expression.
+	Cron string `json:"cron"`
+	// Duration is how long the window stays open, such as "8h".
+	Duration string `json:"duration"`
+}
+
+// Schedule is the enforcementSchedule of a constraint.
+type Schedule struct {
+	// TimeZone is the IANA time zone the windows are in. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// ActiveWindows, if set, are the only windows the constraint is enforced
+	// in.
+	ActiveWindows []Window `json:"activeWindows,omitempty"`
+	// FrozenWindows are windows the constraint is not enforced in.
+	FrozenWindows []Window `json:"frozenWindows,omitempty"`
+}
+
+type window struct {
+	cron     *cron
+	duration time.Duration
+}
+
+// compiled is a parsed Schedule.
+type compiled struct {
+	location       *time.Location
+	active, frozen []window
+}
+
+// Read returns the enforcementSchedule of constraint, or nil if it has none.
+func Read(constraint *unstructured.Unstructured) (*Schedule, error) {
+	raw, found, err := unstructured.NestedMap(constraint.Object, "spec", "enforcementSchedule")
+	if err != nil || !found {
+		return nil, err
+	}
+	s := &Schedule{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate returns an error if constraint has an invalid enforcementSchedule.
+func Validate(constraint *unstructured.Unstructured) error {
+	s, err := Read(constraint)
+	if err != nil {
+		return fmt.Errorf("invalid spec.enforcementSchedule: %w", err)
+	}
+	if s == nil {
+		return nil
+	}
+	if _, err := compile(s); err != nil {
+		return fmt.Errorf("invalid spec.enforcementSchedule: %w", err)
+	}
+	return nil
+}
+
+func compile(s *Schedule) (*compiled, error) {
+	c := &compiled{location: time.UTC}
+	if s.TimeZone != "" {
+		loc, err := time.LoadLocation(s.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		c.location = loc
+	}
+	var err error
+	if c.active, err = compileWindows("activeWindows", s.ActiveWindows); err != nil {
+		return nil, err
+	}
+	if c.frozen, err = compileWindows("frozenWindows", s.FrozenWindows); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func compileWindows(field string, windows []Window) ([]window, error) {
+	var result []window
+	for i, w := range windows {
+		c, err := parseCron(w.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		d, err := time.ParseDuration(w.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		if d < time.Minute || d > maxDuration {
+			return nil, fmt.Errorf("%s[%d]: duration must be between 1m and %v, got %v", field, i, maxDuration, d)
+		}
+		result = append(result, window{cron: c, duration: d})
+	}
+	return result, nil
+}
+
+// activeAt returns whether the constraint is enforced at t.
+func (c *compiled) activeAt(t time.Time) bool {
+	t = t.In(c.location)
+	if len(c.active) != 0 && !inAny(c.active, t) {
+		return false
+	}
+	return !inAny(c.frozen, t)
+}
+
+// inAny returns whether t is within any of windows, which it is if the cron
+// expression of a window fired in the minutes since t minus its duration.
+func inAny(windows []window, t time.Time) bool {
+	minute := t.Truncate(time.Minute)
+	for _, w := range windows {
+		for d := time.Duration(0); d < w.duration; d += time.Minute {
+			if w.cron.matches(minute.Add(-d)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// System caches the schedules of constraints, and whether each is active in
+// the current minute.
+type System struct {
+	now func() time.Time
+
+	mux sync.Mutex
+	// entries are keyed by the kind and name of constraints.
+	entries map[string]*entry
+}
+
+type entry struct {
+	// raw is the enforcementSchedule the entry was compiled from.
+	raw      map[string]interface{}
+	schedule *compiled
+	// minute is the minute active was computed for.
+	minute time.Time
+	active bool
+}
+
+var system = NewSystem()
+
+// Get returns the System of this process.
+func Get() *System {
+	return system
+}
+
+// NewSystem returns a System without schedules.
+func NewSystem() *System {
+	return &System{now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Active returns whether constraint is enforced now. Constraints without a
+// schedule, or with an invalid one, are always enforced.
+func (s *System) Active(constraint *unstructured.Unstructured) bool {
+	field, found, err := unstructured.NestedFieldNoCopy(constraint.Object, "spec", "enforcementSchedule")
+	raw, ok := field.(map[string]interface{})
+	if err != nil || !found || !ok {
+		return true
+	}
+	key := constraint.GetKind() + "/" + constraint.GetName()
+	minute := s.now().Truncate(time.Minute)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !reflect.DeepEqual(e.raw, raw) {
+		e = &entry{raw: runtime.DeepCopyJSON(raw)}
+		sched := &Schedule{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, sched)
+		if err == nil {
+			e.schedule, err = compile(sched)
+		}
+		if err != nil {
+			log.Error(err, "ignoring invalid enforcementSchedule", "constraint", key)
+		}
+		s.entries[key] = e
+	}
+	if e.schedule == nil {
+		return true
+	}
+	if !e.minute.Equal(minute) {
+		e.active = e.schedule.activeAt(minute)
+		e.minute = minute
+	}
+	return e.active
+}
+
+// Remove forgets the schedule of the constraint of kind named name.
+func (s *System) Remove(kind, name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.entries, kind+"/"+name)
+}
+
+// Filter returns the results of constraints which are active, and the
+// constraints of the others.
+func (s *System) Filter(results []*types.Result) (kept []*types.Result, inactive []*unstructured.Unstructured) {
+	seen := make(map[*unstructured.Unstructured]bool)
+	for _, r := range results {
+		if r.Constraint == nil || s.Active(r.Constraint) {
+			kept = append(kept, r)
+			continue
+		}
+		if !seen[r.Constraint] {
+			seen[r.Constraint] = true
+			inactive = append(inactive, r.Constraint)
+		}
+	}
+	return kept, inactive
+}
+
+// AddToCRD adds the schema of enforcementSchedule to the spec of every version
+// of the constraint CRD crd.
+func AddToCRD(crd *apiextensionsv1.CustomResourceDefinition) error {
+	return util.AddSpecProperty(crd, "enforcementSchedule", scheduleSchema())
+}
+
+func scheduleSchema() apiextensionsv1.JSONSchemaProps {
+	windows := apiextensionsv1.JSONSchemaProps{
+		Type: "array",
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+			Schema: &apiextensionsv1.JSONSchemaProps{
+				Type:     "object",
+				Required: []string{"cron", "duration"},
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"cron":     {Type: "string"},
+					"duration": {Type: "string"},
+				},
+			},
+		},
+	}
+	return apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"timeZone":      {Type: "string"},
+			"activeWindows": windows,
+			"frozenWindows": windows,
+		},
+	}
+}
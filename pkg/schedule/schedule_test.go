@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newConstraint(name string, schedule map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	u.SetKind("K8sRequiredLabels")
+	u.SetName(name)
+	if schedule != nil {
+		u.Object["spec"] = map[string]interface{}{"enforcementSchedule": schedule}
+	}
+	return u
+}
+
+// businessHours is enforced from 9 to 17 on weekdays in New York, except
+// during the change freeze on the first day of each month.
+var businessHours = map[string]interface{}{
+	"timeZone": "America/New_York",
+	"activeWindows": []interface{}{
+		map[string]interface{}{"cron": "0 9 * * 1-5", "duration": "8h"},
+	},
+	"frozenWindows": []interface{}{
+		map[string]interface{}{"cron": "0 0 1 * *", "duration": "24h"},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		name     string
+		schedule map[string]interface{}
+		wantErr  bool
+	}{
+		{name: "no schedule"},
+		{name: "valid", schedule: businessHours},
+		{
+			name:     "unknown time zone",
+			schedule: map[string]interface{}{"timeZone": "Mars/Olympus_Mons"},
+			wantErr:  true,
+		},
+		{
+			name: "invalid cron",
+			schedule: map[string]interface{}{"activeWindows": []interface{}{
+				map[string]interface{}{"cron": "0 9 * *", "duration": "8h"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "invalid duration",
+			schedule: map[string]interface{}{"frozenWindows": []interface{}{
+				map[string]interface{}{"cron": "0 9 * * *", "duration": "eight hours"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "duration too long",
+			schedule: map[string]interface{}{"frozenWindows": []interface{}{
+				map[string]interface{}{"cron": "0 9 * * *", "duration": "745h"},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(newConstraint("c", tc.schedule))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		name     string
+		schedule map[string]interface{}
+		now      time.Time
+		want     bool
+	}{
+		{name: "no schedule", now: time.Date(2021, 6, 5, 3, 0, 0, 0, ny), want: true},
+		{name: "within active window", schedule: businessHours, now: time.Date(2021, 6, 7, 9, 0, 0, 0, ny), want: true},
+		{name: "end of active window", schedule: businessHours, now: time.Date(2021, 6, 7, 16, 59, 0, 0, ny), want: true},
+		{name: "after active window", schedule: businessHours, now: time.Date(2021, 6, 7, 17, 0, 0, 0, ny)},
+		{name: "weekend", schedule: businessHours, now: time.Date(2021, 6, 5, 12, 0, 0, 0, ny)},
+		// 13:00 UTC is 9:00 in New York.
+		{name: "other time zone", schedule: businessHours, now: time.Date(2021, 6, 7, 13, 30, 0, 0, time.UTC), want: true},
+		{name: "frozen", schedule: businessHours, now: time.Date(2021, 6, 1, 12, 0, 0, 0, ny)},
+		{
+			name:     "invalid schedule",
+			schedule: map[string]interface{}{"timeZone": "Mars/Olympus_Mons"},
+			now:      time.Date(2021, 6, 5, 3, 0, 0, 0, ny),
+			want:     true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSystem()
+			s.now = func() time.Time { return tc.now }
+			if got := s.Active(newConstraint("c", tc.schedule)); got != tc.want {
+				t.Errorf("got Active() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestActiveUpdates(t *testing.T) {
+	s := NewSystem()
+	now := time.Date(2021, 6, 7, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	frozen := map[string]interface{}{"frozenWindows": []interface{}{
+		map[string]interface{}{"cron": "0 12 * * *", "duration": "1h"},
+	}}
+	c := newConstraint("c", frozen)
+	if s.Active(c) {
+		t.Error("got constraint active within its frozen window")
+	}
+
+	now = now.Add(time.Hour)
+	if !s.Active(c) {
+		t.Error("got constraint inactive after its frozen window")
+	}
+
+	// The schedule is recompiled when it changes.
+	frozen["frozenWindows"] = []interface{}{
+		map[string]interface{}{"cron": "0 12 * * *", "duration": "2h"},
+	}
+	if s.Active(newConstraint("c", frozen)) {
+		t.Error("got constraint active within its updated frozen window")
+	}
+
+	s.Remove(c.GetKind(), c.GetName())
+	if len(s.entries) != 0 {
+		t.Errorf("got %d entries after removing the constraint, want 0", len(s.entries))
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := NewSystem()
+	s.now = func() time.Time { return time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC) }
+	always := newConstraint("always", nil)
+	weekdays := newConstraint("weekdays", map[string]interface{}{"activeWindows": []interface{}{
+		map[string]interface{}{"cron": "0 0 * * 1-5", "duration": "24h"},
+	}})
+	results := []*types.Result{
+		{Msg: "a", Constraint: always},
+		{Msg: "b", Constraint: weekdays},
+		{Msg: "c", Constraint: weekdays},
+	}
+
+	kept, inactive := s.Filter(results)
+	if len(kept) != 1 || kept[0].Msg != "a" {
+		t.Errorf("got kept results %v, want only a", kept)
+	}
+	if len(inactive) != 1 || inactive[0] != weekdays {
+		t.Errorf("got inactive constraints %v, want only weekdays", inactive)
+	}
+}
+
+func TestAddToCRD(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1", Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {Type: "object"},
+						},
+					},
+				}},
+				{Name: "v1alpha1"},
+			},
+		},
+	}
+	if err := AddToCRD(crd); err != nil {
+		t.Fatal(err)
+	}
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	if _, ok := spec.Properties["enforcementSchedule"]; !ok {
+		t.Error("got no enforcementSchedule in the spec schema")
+	}
+
+	crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+	if err := AddToCRD(crd); !errors.Is(err, util.ErrSpecNotObject) {
+		t.Errorf("got error %v, want %v", err, util.ErrSpecNotObject)
+	}
+}
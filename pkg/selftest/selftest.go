@@ -0,0 +1,166 @@
+// Package selftest runs the tests embedded in ConstraintTemplates.
+//
+// Each target of a template may list tests in spec.targets[].tests: objects
+// the template must allow or deny when constrained with the given parameters.
+// The typed ConstraintTemplate does not know the field, so tests are read from
+// the template as unstructured. When enabled, the template controller runs a
+// template's tests before ingesting it, and refuses to activate the template
+// if any fails.
+package selftest
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	opaclient "github.com/open-policy-agent/frameworks/constraint/pkg/client"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"github.com/open-policy-agent/gatekeeper/pkg/target"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Enabled is whether ConstraintTemplates are tested before they are
+// activated.
+var Enabled = flag.Bool("enable-template-self-tests", false, "(alpha) run the tests in spec.targets[].tests of ConstraintTemplates when they are ingested, and refuse to activate templates whose tests fail")
+
+const (
+	// ExpectAllow is the Expect of a Test whose object must not violate the
+	// template.
+	ExpectAllow = "allow"
+	// ExpectDeny is the Expect of a Test whose object must violate the
+	// template.
+	ExpectDeny = "deny"
+)
+
+// constraintName is the name of the Constraint objects are tested with.
+const constraintName = "self-test"
+
+// Test is an object a template must allow or deny.
+type Test struct {
+	// Name identifies the Test in failures.
+	Name string `json:"name"`
+	// Object is reviewed by a Constraint of the template.
+	Object map[string]interface{} `json:"object"`
+	// Parameters are the parameters of the Constraint.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Expect is whether the Constraint must allow or deny Object.
+	Expect string `json:"expect"`
+}
+
+// Read returns the Tests of each target of the ConstraintTemplate u, keyed by
+// target.
+func Read(u *unstructured.Unstructured) (map[string][]Test, error) {
+	targets, _, err := unstructured.NestedSlice(u.Object, "spec", "targets")
+	if err != nil {
+		return nil, err
+	}
+	tests := make(map[string][]Test)
+	for i, t := range targets {
+		target, ok := t.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spec.targets[%d] is not an object", i)
+		}
+		raw, ok := target["tests"].([]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := target["target"].(string)
+		for j, r := range raw {
+			m, ok := r.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("spec.targets[%d].tests[%d] is not an object", i, j)
+			}
+			var test Test
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &test); err != nil {
+				return nil, fmt.Errorf("spec.targets[%d].tests[%d]: %w", i, j, err)
+			}
+			tests[name] = append(tests[name], test)
+		}
+	}
+	return tests, nil
+}
+
+// Run runs tests against templ, returning an error describing every Test
+// which failed.
+func Run(ctx context.Context, templ *templates.ConstraintTemplate, tests map[string][]Test) error {
+	var count int
+	for _, ts := range tests {
+		count += len(ts)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	validation := &target.K8sValidationTarget{}
+	for name := range tests {
+		if name != validation.GetName() {
+			return fmt.Errorf("tests of unknown target %q", name)
+		}
+	}
+
+	backend, err := opaclient.NewBackend(opaclient.Driver(local.New(local.Tracing(false))))
+	if err != nil {
+		return err
+	}
+	client, err := backend.NewClient(opaclient.Targets(validation))
+	if err != nil {
+		return err
+	}
+	if _, err := client.AddTemplate(ctx, templ); err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, test := range tests[validation.GetName()] {
+		if err := runTest(ctx, client, templ.Spec.CRD.Spec.Names.Kind, test); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", test.Name, err))
+		}
+	}
+	if len(failures) != 0 {
+		return fmt.Errorf("%d of %d tests failed: %s", len(failures), count, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func runTest(ctx context.Context, client *opaclient.Client, kind string, test Test) error {
+	if test.Expect != ExpectAllow && test.Expect != ExpectDeny {
+		return fmt.Errorf("expect must be %q or %q, got %q", ExpectAllow, ExpectDeny, test.Expect)
+	}
+	if test.Object == nil {
+		return fmt.Errorf("object must be set")
+	}
+
+	constraint := &unstructured.Unstructured{}
+	constraint.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	constraint.SetKind(kind)
+	constraint.SetName(constraintName)
+	if test.Parameters != nil {
+		if err := unstructured.SetNestedField(constraint.Object, runtime.DeepCopyJSON(test.Parameters), "spec", "parameters"); err != nil {
+			return err
+		}
+	}
+	// Replaces the Constraint of the previous Test.
+	if _, err := client.AddConstraint(ctx, constraint); err != nil {
+		return fmt.Errorf("adding constraint: %w", err)
+	}
+
+	resp, err := client.Review(ctx, &unstructured.Unstructured{Object: runtime.DeepCopyJSON(test.Object)})
+	if err != nil {
+		return fmt.Errorf("reviewing object: %w", err)
+	}
+	results := resp.Results()
+	switch {
+	case test.Expect == ExpectAllow && len(results) != 0:
+		var msgs []string
+		for _, r := range results {
+			msgs = append(msgs, r.Msg)
+		}
+		return fmt.Errorf("got denied with %q, want allowed", strings.Join(msgs, ", "))
+	case test.Expect == ExpectDeny && len(results) == 0:
+		return fmt.Errorf("got allowed, want denied")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package selftest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const templateYAML = `
+apiVersion: templates.gatekeeper.sh/v1beta1
+kind: ConstraintTemplate
+metadata:
+  name: k8srequiredlabels
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sRequiredLabels
+  targets:
+  - target: admission.k8s.gatekeeper.sh
+    rego: |
+      package k8srequiredlabels
+      violation[{"msg": msg}] {
+        label := input.parameters.labels[_]
+        not input.review.object.metadata.labels[label]
+        msg := sprintf("missing label %v", [label])
+      }
+    tests:
+    - name: labeled
+      expect: allow
+      parameters:
+        labels: ["owner"]
+      object:
+        apiVersion: v1
+        kind: Namespace
+        metadata:
+          name: labeled
+          labels:
+            owner: me
+    - name: unlabeled
+      expect: deny
+      parameters:
+        labels: ["owner"]
+      object:
+        apiVersion: v1
+        kind: Namespace
+        metadata:
+          name: unlabeled
+`
+
+func readTemplate(t *testing.T) (*unstructured.Unstructured, *templates.ConstraintTemplate) {
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(templateYAML), &u.Object); err != nil {
+		t.Fatal(err)
+	}
+	templ := &templates.ConstraintTemplate{}
+	if err := yaml.Unmarshal([]byte(templateYAML), templ); err != nil {
+		t.Fatal(err)
+	}
+	return u, templ
+}
+
+func TestRead(t *testing.T) {
+	u, _ := readTemplate(t)
+	tests, err := Read(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, test := range tests["admission.k8s.gatekeeper.sh"] {
+		got = append(got, test.Name+"/"+test.Expect)
+	}
+	if diff := cmp.Diff([]string{"labeled/allow", "unlabeled/deny"}, got); diff != "" {
+		t.Error(diff)
+	}
+
+	if err := unstructured.SetNestedSlice(u.Object, []interface{}{"invalid"}, "spec", "targets"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(u); err == nil {
+		t.Error("got no error reading an invalid target")
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	u, templ := readTemplate(t)
+	tests, err := Read(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(ctx, templ, tests); err != nil {
+		t.Fatal(err)
+	}
+
+	// The template does not deny objects with other labels.
+	failing := tests["admission.k8s.gatekeeper.sh"]
+	failing[0].Expect = ExpectDeny
+	failing = append(failing, Test{Name: "invalid", Object: failing[0].Object, Expect: "warn"})
+	tests["admission.k8s.gatekeeper.sh"] = failing
+	err = Run(ctx, templ, tests)
+	if err == nil {
+		t.Fatal("got no error running failing tests")
+	}
+	for _, want := range []string{"2 of 3 tests failed", "labeled: got allowed", `invalid: expect must be`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %q, want it to contain %q", err, want)
+		}
+	}
+
+	if err := Run(ctx, templ, map[string][]Test{"other.target": {{Name: "other"}}}); err == nil {
+		t.Error("got no error running tests of an unknown target")
+	}
+}
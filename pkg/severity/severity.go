@@ -0,0 +1,116 @@
+// Package severity reads how severe the violations of constraints are.
+//
+// A constraint may set spec.severity to one of the Levels, and
+// spec.severityWeight to override the weight of its Level. Severities are
+// recorded with violations so they can be prioritized, and weights are summed
+// by audit into the compliance score of each namespace.
+package severity
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Level is the severity of the violations of a constraint.
+type Level string
+
+const (
+	// None is the Level of constraints which do not set a severity.
+	None     Level = "none"
+	Low      Level = "low"
+	Medium   Level = "medium"
+	High     Level = "high"
+	Critical Level = "critical"
+)
+
+// Levels are the Levels constraints may set, from least to most severe.
+var Levels = []Level{Low, Medium, High, Critical}
+
+// KnownLevels are all defined Levels.
+var KnownLevels = []Level{None, Low, Medium, High, Critical}
+
+// weights are the default weights of Levels.
+var weights = map[Level]float64{
+	None:     1,
+	Low:      1,
+	Medium:   3,
+	High:     7,
+	Critical: 10,
+}
+
+// Of returns the Level and weight of the violations of constraint. Invalid
+// values are ignored, as the webhook rejects them.
+func Of(constraint *unstructured.Unstructured) (Level, float64) {
+	level := None
+	s, found, err := unstructured.NestedString(constraint.Object, "spec", "severity")
+	if err == nil && found && isLevel(Level(s)) {
+		level = Level(s)
+	}
+	weight := weights[level]
+	if w, ok := readWeight(constraint); ok && w >= 0 {
+		weight = w
+	}
+	return level, weight
+}
+
+// Validate returns an error if constraint has an invalid severity or
+// severityWeight.
+func Validate(constraint *unstructured.Unstructured) error {
+	s, found, err := unstructured.NestedFieldNoCopy(constraint.Object, "spec", "severity")
+	if err != nil {
+		return err
+	}
+	if found {
+		level, ok := s.(string)
+		if !ok || !isLevel(Level(level)) {
+			return fmt.Errorf("spec.severity must be one of %v, got %v", Levels, s)
+		}
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(constraint.Object, "spec", "severityWeight"); found {
+		if w, ok := readWeight(constraint); !ok || w < 0 {
+			return fmt.Errorf("spec.severityWeight must be a non-negative number")
+		}
+	}
+	return nil
+}
+
+// readWeight returns spec.severityWeight of constraint, if it is a number.
+func readWeight(constraint *unstructured.Unstructured) (float64, bool) {
+	w, found, err := unstructured.NestedFieldNoCopy(constraint.Object, "spec", "severityWeight")
+	if err != nil || !found {
+		return 0, false
+	}
+	switch v := w.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func isLevel(l Level) bool {
+	for _, level := range Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// AddToCRD adds the schemas of severity and severityWeight to the spec of
+// every version of the constraint CRD crd.
+func AddToCRD(crd *apiextensionsv1.CustomResourceDefinition) error {
+	var levels []apiextensionsv1.JSON
+	for _, l := range Levels {
+		levels = append(levels, apiextensionsv1.JSON{Raw: []byte(`"` + l + `"`)})
+	}
+	if err := util.AddSpecProperty(crd, "severity", apiextensionsv1.JSONSchemaProps{Type: "string", Enum: levels}); err != nil {
+		return err
+	}
+	zero := 0.0
+	return util.AddSpecProperty(crd, "severityWeight", apiextensionsv1.JSONSchemaProps{Type: "number", Minimum: &zero})
+}
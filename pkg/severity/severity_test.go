@@ -0,0 +1,92 @@
+package severity
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newConstraint(spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	u.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	u.SetKind("K8sRequiredLabels")
+	u.SetName("c")
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	return u
+}
+
+func TestOf(t *testing.T) {
+	tcs := []struct {
+		name       string
+		spec       map[string]interface{}
+		wantLevel  Level
+		wantWeight float64
+	}{
+		{name: "no spec", wantLevel: None, wantWeight: 1},
+		{name: "high", spec: map[string]interface{}{"severity": "high"}, wantLevel: High, wantWeight: 7},
+		{name: "integer weight", spec: map[string]interface{}{"severity": "high", "severityWeight": int64(20)}, wantLevel: High, wantWeight: 20},
+		{name: "fractional weight", spec: map[string]interface{}{"severityWeight": 0.5}, wantLevel: None, wantWeight: 0.5},
+		{name: "invalid severity", spec: map[string]interface{}{"severity": "urgent"}, wantLevel: None, wantWeight: 1},
+		{name: "negative weight", spec: map[string]interface{}{"severity": "critical", "severityWeight": int64(-1)}, wantLevel: Critical, wantWeight: 10},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			level, weight := Of(newConstraint(tc.spec))
+			if level != tc.wantLevel || weight != tc.wantWeight {
+				t.Errorf("got Of() = %v, %v, want %v, %v", level, weight, tc.wantLevel, tc.wantWeight)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		spec    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "no spec"},
+		{name: "valid", spec: map[string]interface{}{"severity": "medium", "severityWeight": 4.5}},
+		{name: "unknown severity", spec: map[string]interface{}{"severity": "urgent"}, wantErr: true},
+		{name: "none is not settable", spec: map[string]interface{}{"severity": "none"}, wantErr: true},
+		{name: "non-string severity", spec: map[string]interface{}{"severity": int64(1)}, wantErr: true},
+		{name: "negative weight", spec: map[string]interface{}{"severityWeight": int64(-1)}, wantErr: true},
+		{name: "non-numeric weight", spec: map[string]interface{}{"severityWeight": "heavy"}, wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(newConstraint(tc.spec))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddToCRD(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1", Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type:       "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{"spec": {Type: "object"}},
+					},
+				}},
+			},
+		},
+	}
+	if err := AddToCRD(crd); err != nil {
+		t.Fatal(err)
+	}
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	if got := len(spec.Properties["severity"].Enum); got != len(Levels) {
+		t.Errorf("got %d severities in the schema, want %d", got, len(Levels))
+	}
+	if _, ok := spec.Properties["severityWeight"]; !ok {
+		t.Error("got no severityWeight in the spec schema")
+	}
+}
@@ -0,0 +1,76 @@
+// Package shutdown coordinates stopping a Gatekeeper pod without dropping
+// admission requests.
+//
+// The API server keeps sending admission requests to a pod until it is
+// removed from the webhook Service's endpoints, which only happens some time
+// after the pod starts terminating. Stopping the webhook server as soon as
+// the termination signal arrives would refuse the requests sent meanwhile.
+// Instead the pod first fails its readiness check while still serving, and
+// only stops the manager once the endpoints have had time to update.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("shutdown").WithValues(logging.Process, "shutdown")
+
+var (
+	// Delay is how long the pod keeps serving after a termination signal.
+	Delay = flag.Duration("shutdown-delay", 0, "(alpha) how long to keep serving admission requests after receiving a termination signal, while failing readiness so new requests are routed to other pods")
+	// GracePeriod is how long in-flight work is given to finish once the
+	// manager is stopped.
+	GracePeriod = flag.Duration("shutdown-grace-period", 30*time.Second, "(alpha) how long in-flight admission reviews and pending status updates are given to finish during shutdown")
+)
+
+// ErrDraining is reported by the readiness check once the pod is draining.
+var ErrDraining = errors.New("shutting down")
+
+// Drainer delays stopping the manager after a termination signal.
+type Drainer struct {
+	delay    time.Duration
+	mux      sync.RWMutex
+	draining bool
+}
+
+// NewDrainer returns a Drainer which keeps serving for delay after a
+// termination signal.
+func NewDrainer(delay time.Duration) *Drainer {
+	return &Drainer{delay: delay}
+}
+
+// Context returns a context which is canceled the Drainer's delay after
+// signaled is. Readiness fails from the moment signaled is canceled.
+func (d *Drainer) Context(signaled context.Context) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-signaled.Done()
+		d.mux.Lock()
+		d.draining = true
+		d.mux.Unlock()
+
+		log.Info("draining before shutdown", "delay", d.delay.String())
+		time.Sleep(d.delay)
+		log.Info("shutting down")
+		cancel()
+	}()
+	return ctx
+}
+
+// Checker is a healthz.Checker which fails once the pod is draining.
+func (d *Drainer) Checker(_ *http.Request) error {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	if d.draining {
+		return ErrDraining
+	}
+	return nil
+}
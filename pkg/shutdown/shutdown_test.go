@@ -0,0 +1,40 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrainer(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	d := NewDrainer(delay)
+	signaled, signal := context.WithCancel(context.Background())
+	ctx := d.Context(signaled)
+
+	if err := d.Checker(nil); err != nil {
+		t.Fatalf("got readiness error %v before the signal", err)
+	}
+
+	start := time.Now()
+	signal()
+	for !errors.Is(d.Checker(nil), ErrDraining) {
+		if time.Since(start) > delay {
+			t.Fatal("readiness did not fail while draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("context canceled before the delay passed")
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * delay):
+		t.Fatal("context not canceled after the delay")
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("context canceled after %v, want at least %v", elapsed, delay)
+	}
+}
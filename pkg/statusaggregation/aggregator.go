@@ -0,0 +1,284 @@
+package statusaggregation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	fetchTimeout    = 5 * time.Second
+)
+
+// peerSelector selects the pods whose Reports are aggregated.
+var peerSelector = client.MatchingLabels{"gatekeeper.sh/system": "yes"}
+
+// Validate returns an error if other pods cannot reach the Reports of this pod.
+func Validate() error {
+	if !*Enabled {
+		return nil
+	}
+	if *introspection.Addr == "" {
+		return errors.New("--aggregate-constraint-status requires --introspection-addr")
+	}
+	host, _, err := net.SplitHostPort(*introspection.Addr)
+	if err != nil {
+		return fmt.Errorf("parsing --introspection-addr: %w", err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return fmt.Errorf("--aggregate-constraint-status requires --introspection-addr to be reachable from other pods, not %s", host)
+	}
+	return nil
+}
+
+var _ manager.Runnable = &Aggregator{}
+
+// Aggregator periodically collects the Reports of every Gatekeeper pod and
+// writes them to the status of each constraint.
+type Aggregator struct {
+	// reader reads constraints, and podReader lists pods without caching them.
+	reader    client.Reader
+	podReader client.Reader
+	writer    client.StatusClient
+	reports   *Reports
+	self      string
+	port      string
+	http      *http.Client
+	interval  time.Duration
+
+	// peers holds the Reports last collected from each pod, so a pod which
+	// briefly fails to respond keeps its entries.
+	peers map[string][]Report
+	// written holds the constraints whose status was last written with
+	// entries, so they are cleared once no pod reports them.
+	written map[string]bool
+}
+
+// New creates an Aggregator collecting Reports from the introspection
+// endpoint of every pod over https, authenticated with the service account of
+// mgr. Pods must serve the webhook certificate set with
+// introspection.SetCerts.
+func New(mgr manager.Manager) (*Aggregator, error) {
+	_, port, err := net.SplitHostPort(*introspection.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing --introspection-addr: %w", err)
+	}
+	client, err := introspection.GetCerts().PeerClient(mgr.GetConfig(), fetchTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Aggregator{
+		reader:    mgr.GetCache(),
+		podReader: mgr.GetAPIReader(),
+		writer:    mgr.GetClient(),
+		reports:   Get(),
+		self:      util.GetPodName(),
+		port:      port,
+		http:      client,
+		interval:  defaultInterval,
+		peers:     make(map[string][]Report),
+		written:   make(map[string]bool),
+	}, nil
+}
+
+// Start implements manager.Runnable.
+func (a *Aggregator) Start(ctx context.Context) error {
+	log.Info("aggregating constraint status", "interval", a.interval)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			a.aggregate(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Status is
+// aggregated by the pods assigned the status operation, or its elected leader.
+func (a *Aggregator) NeedLeaderElection() bool {
+	return false
+}
+
+// aggregate collects the Reports of every pod and writes the status of each
+// constraint which changed.
+func (a *Aggregator) aggregate(ctx context.Context) {
+	if err := a.collect(ctx); err != nil {
+		log.Error(err, "collecting constraint status")
+		return
+	}
+
+	statuses := make(map[string][]v1beta1.ConstraintPodStatusStatus)
+	for _, reps := range a.peers {
+		for _, rep := range reps {
+			k := key(rep.Kind, rep.Name)
+			statuses[k] = append(statuses[k], rep.Status)
+		}
+	}
+	for k := range a.written {
+		if _, ok := statuses[k]; !ok {
+			statuses[k] = nil
+		}
+	}
+
+	for k, s := range statuses {
+		kind, name := splitKey(k)
+		written, err := a.write(ctx, kind, name, s)
+		if err != nil {
+			if apierrors.IsConflict(err) {
+				log.V(1).Info("constraint changed while writing status, retrying later", "kind", kind, "name", name)
+			} else {
+				log.Error(err, "writing constraint status", "kind", kind, "name", name)
+			}
+			continue
+		}
+		if written {
+			a.written[k] = true
+		} else {
+			delete(a.written, k)
+		}
+	}
+}
+
+// collect replaces the Reports of pods which responded, and forgets pods
+// which no longer exist.
+func (a *Aggregator) collect(ctx context.Context) error {
+	pods := &corev1.PodList{}
+	if err := a.podReader.List(ctx, pods, client.InNamespace(util.GetNamespace()), peerSelector); err != nil {
+		return err
+	}
+
+	var (
+		mux   sync.Mutex
+		wg    sync.WaitGroup
+		peers = make(map[string][]Report, len(pods.Items))
+	)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Name == a.self {
+			peers[pod.Name] = a.reports.List()
+			continue
+		}
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reps, err := a.fetch(ctx, pod.Status.PodIP)
+			mux.Lock()
+			defer mux.Unlock()
+			if err != nil {
+				log.V(1).Info("unable to collect constraint status", "pod", pod.Name, "error", err.Error())
+				if last, ok := a.peers[pod.Name]; ok {
+					peers[pod.Name] = last
+				}
+				return
+			}
+			peers[pod.Name] = reps
+		}()
+	}
+	wg.Wait()
+	a.peers = peers
+	return nil
+}
+
+func (a *Aggregator) fetch(ctx context.Context, ip string) ([]Report, error) {
+	url := "https://" + net.JoinHostPort(ip, a.port) + ReportsPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+	var reps []Report
+	if err := json.NewDecoder(resp.Body).Decode(&reps); err != nil {
+		return nil, fmt.Errorf("decoding response from %s: %w", url, err)
+	}
+	return reps, nil
+}
+
+// write sets the status of each pod for the constraint, returning whether any
+// entries were written. The constraint is not updated if its status is
+// unchanged.
+func (a *Aggregator) write(ctx context.Context, kind, name string, statuses []v1beta1.ConstraintPodStatusStatus) (bool, error) {
+	instance := &unstructured.Unstructured{}
+	instance.SetGroupVersionKind(schema.GroupVersionKind{Group: v1beta1.ConstraintsGroup, Version: "v1beta1", Kind: kind})
+	if err := a.reader.Get(ctx, types.NamespacedName{Name: name}, instance); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// Only report statuses for this incarnation of the constraint, as in the
+	// status controller.
+	var current []v1beta1.ConstraintPodStatusStatus
+	for _, s := range statuses {
+		if s.ConstraintUID == instance.GetUID() {
+			current = append(current, s)
+		}
+	}
+	sort.Slice(current, func(i, j int) bool {
+		return current[i].ID < current[j].ID
+	})
+
+	byPod := make([]interface{}, 0, len(current))
+	for i := range current {
+		o, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&current[i])
+		if err != nil {
+			return false, err
+		}
+		byPod = append(byPod, o)
+	}
+
+	old, _, err := unstructured.NestedSlice(instance.Object, "status", "byPod")
+	if err != nil {
+		return false, err
+	}
+	if len(old) == 0 && len(byPod) == 0 || reflect.DeepEqual(old, byPod) {
+		return len(byPod) > 0, nil
+	}
+
+	if err := unstructured.SetNestedSlice(instance.Object, byPod, "status", "byPod"); err != nil {
+		return false, err
+	}
+	if err := a.writer.Status().Update(ctx, instance); err != nil {
+		return false, err
+	}
+	return len(byPod) > 0, nil
+}
+
+func splitKey(k string) (string, string) {
+	parts := strings.SplitN(k, "/", 2)
+	return parts[0], parts[1]
+}
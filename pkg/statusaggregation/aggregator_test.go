@@ -0,0 +1,251 @@
+package statusaggregation
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	"github.com/open-policy-agent/gatekeeper/pkg/introspection"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeCluster serves pods and constraints, recording status updates.
+type fakeCluster struct {
+	client.Client
+	pods        []corev1.Pod
+	constraints map[string]*unstructured.Unstructured
+	updates     int
+}
+
+func (c *fakeCluster) List(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+	list.(*corev1.PodList).Items = c.pods
+	return nil
+}
+
+func (c *fakeCluster) Get(_ context.Context, key client.ObjectKey, obj client.Object) error {
+	stored, ok := c.constraints[key.Name]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Group: v1beta1.ConstraintsGroup}, key.Name)
+	}
+	stored.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (c *fakeCluster) Status() client.StatusWriter {
+	return &fakeStatusWriter{c: c}
+}
+
+type fakeStatusWriter struct {
+	client.StatusWriter
+	c *fakeCluster
+}
+
+func (w *fakeStatusWriter) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	w.c.constraints[obj.GetName()] = obj.(*unstructured.Unstructured).DeepCopy()
+	w.c.updates++
+	return nil
+}
+
+func runningPod(name, ip string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+	}
+}
+
+func status(id string, uid types.UID) v1beta1.ConstraintPodStatusStatus {
+	return v1beta1.ConstraintPodStatusStatus{ID: id, ConstraintUID: uid, Enforced: true, ObservedGeneration: 1}
+}
+
+func byPodIDs(t *testing.T, c *fakeCluster) []string {
+	t.Helper()
+	byPod, _, err := unstructured.NestedSlice(c.constraints["foo"].Object, "status", "byPod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, s := range byPod {
+		ids = append(ids, s.(map[string]interface{})["id"].(string))
+	}
+	return ids
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+
+	peerReports := NewReports()
+	peerReports.Set("K8sRequiredLabels", "foo", status("webhook-b", "uid-1"))
+	peerReports.Set("K8sRequiredLabels", "bar", status("webhook-b", "uid-old"))
+	peerUp := true
+	peer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !peerUp {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != ReportsPath {
+			t.Errorf("got request for %s, want %s", r.URL.Path, ReportsPath)
+		}
+		peerReports.ServeHTTP(w, r)
+	}))
+	defer peer.Close()
+	u, err := url.Parse(peer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foo := &unstructured.Unstructured{}
+	foo.SetGroupVersionKind(schema.GroupVersionKind{Group: v1beta1.ConstraintsGroup, Version: "v1beta1", Kind: "K8sRequiredLabels"})
+	foo.SetName("foo")
+	foo.SetUID("uid-1")
+	cluster := &fakeCluster{
+		pods:        []corev1.Pod{runningPod("audit-a", "10.0.0.1"), runningPod("webhook-b", host), {ObjectMeta: metav1.ObjectMeta{Name: "pending-c"}}},
+		constraints: map[string]*unstructured.Unstructured{"foo": foo},
+	}
+
+	local := NewReports()
+	local.Set("K8sRequiredLabels", "foo", status("audit-a", "uid-1"))
+	a := &Aggregator{
+		reader:    cluster,
+		podReader: cluster,
+		writer:    cluster,
+		reports:   local,
+		self:      "audit-a",
+		port:      port,
+		http:      peer.Client(),
+		peers:     make(map[string][]Report),
+		written:   make(map[string]bool),
+	}
+
+	steps := []struct {
+		name        string
+		change      func()
+		wantUpdates int
+		wantIDs     []string
+	}{
+		{
+			name:        "statuses of every pod are merged",
+			change:      func() {},
+			wantUpdates: 1,
+			wantIDs:     []string{"audit-a", "webhook-b"},
+		},
+		{
+			name:        "unchanged status is not written",
+			change:      func() {},
+			wantUpdates: 1,
+			wantIDs:     []string{"audit-a", "webhook-b"},
+		},
+		{
+			name:        "unreachable peer keeps its last status",
+			change:      func() { peerUp = false },
+			wantUpdates: 1,
+			wantIDs:     []string{"audit-a", "webhook-b"},
+		},
+		{
+			name: "deleted peer is removed",
+			change: func() {
+				cluster.pods = cluster.pods[:1]
+			},
+			wantUpdates: 2,
+			wantIDs:     []string{"audit-a"},
+		},
+		{
+			name: "status no pod reports is cleared",
+			change: func() {
+				local.Remove("K8sRequiredLabels", "foo")
+			},
+			wantUpdates: 3,
+			wantIDs:     nil,
+		},
+		{
+			name:        "cleared status is not written again",
+			change:      func() {},
+			wantUpdates: 3,
+			wantIDs:     nil,
+		},
+	}
+	for _, s := range steps {
+		s.change()
+		a.aggregate(ctx)
+		if cluster.updates != s.wantUpdates {
+			t.Errorf("%s: got %d updates, want %d", s.name, cluster.updates, s.wantUpdates)
+		}
+		got := byPodIDs(t, cluster)
+		if len(got) != len(s.wantIDs) {
+			t.Errorf("%s: got byPod %v, want %v", s.name, got, s.wantIDs)
+			continue
+		}
+		for i := range got {
+			if got[i] != s.wantIDs[i] {
+				t.Errorf("%s: got byPod %v, want %v", s.name, got, s.wantIDs)
+				break
+			}
+		}
+	}
+}
+
+func TestReportsServeHTTP(t *testing.T) {
+	r := NewReports()
+	r.Set("K8sRequiredLabels", "foo", status("pod-a", "uid-1"))
+	r.Set("K8sAllowedRepos", "bar", status("pod-a", "uid-2"))
+	r.Remove("K8sRequiredLabels", "missing")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReportsPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got []Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Kind != "K8sAllowedRepos" || got[1].Status.ConstraintUID != "uid-1" {
+		t.Errorf("got reports %+v, want both constraints sorted by kind", got)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ReportsPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d for a POST, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	enabled, addr := *Enabled, *introspection.Addr
+	defer func() {
+		*Enabled, *introspection.Addr = enabled, addr
+	}()
+
+	tcs := []struct {
+		enabled bool
+		addr    string
+		wantErr bool
+	}{
+		{enabled: false, addr: "", wantErr: false},
+		{enabled: true, addr: "", wantErr: true},
+		{enabled: true, addr: "127.0.0.1:8888", wantErr: true},
+		{enabled: true, addr: "localhost:8888", wantErr: true},
+		{enabled: true, addr: "8888", wantErr: true},
+		{enabled: true, addr: ":8888", wantErr: false},
+	}
+	for _, tc := range tcs {
+		*Enabled, *introspection.Addr = tc.enabled, tc.addr
+		if err := Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("enabled %v, addr %q: got error %v, want error %v", tc.enabled, tc.addr, err, tc.wantErr)
+		}
+	}
+}
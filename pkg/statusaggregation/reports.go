@@ -0,0 +1,107 @@
+// Package statusaggregation writes the status of every pod for a constraint
+// without a ConstraintPodStatus object per pod and constraint.
+//
+// By default, each pod writes a ConstraintPodStatus for every constraint, and
+// the status controller copies them into the status of the constraint. The
+// number of objects written, and so the load on etcd, grows with the product
+// of pods and constraints. With aggregation enabled, each pod instead keeps
+// its statuses in memory and serves them from the introspection endpoint. The
+// elected status pod periodically collects them from its peers and writes a
+// single status per constraint, only when it changed.
+package statusaggregation
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/gatekeeper/apis/status/v1beta1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReportsPath is the introspection path serving the Reports of a pod.
+const ReportsPath = "/debug/constraintstatus"
+
+var (
+	// Enabled is whether constraint status is aggregated in memory rather than
+	// through ConstraintPodStatus objects.
+	Enabled = flag.Bool("aggregate-constraint-status", false, "(alpha) collect the status of constraints from every pod in memory and write a single status per constraint, instead of a ConstraintPodStatus object per pod and constraint. Requires --introspection-addr to be reachable from other pods")
+
+	log = logf.Log.WithName("status-aggregation")
+)
+
+// +kubebuilder:rbac:urls=/debug/constraintstatus,verbs=get
+
+// Report is the status of a constraint in one pod.
+type Report struct {
+	Kind   string                            `json:"kind"`
+	Name   string                            `json:"name"`
+	Status v1beta1.ConstraintPodStatusStatus `json:"status"`
+}
+
+// Reports holds the status of every constraint ingested by this pod.
+type Reports struct {
+	mux     sync.RWMutex
+	reports map[string]Report
+}
+
+var _ http.Handler = &Reports{}
+
+var reports = NewReports()
+
+// Get returns the Reports of this process.
+func Get() *Reports {
+	return reports
+}
+
+// NewReports returns empty Reports.
+func NewReports() *Reports {
+	return &Reports{reports: make(map[string]Report)}
+}
+
+// Set records the status of a constraint.
+func (r *Reports) Set(kind, name string, status v1beta1.ConstraintPodStatusStatus) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.reports[key(kind, name)] = Report{Kind: kind, Name: name, Status: *status.DeepCopy()}
+}
+
+// Remove forgets the status of a constraint.
+func (r *Reports) Remove(kind, name string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	delete(r.reports, key(kind, name))
+}
+
+// List returns every Report, sorted by constraint.
+func (r *Reports) List() []Report {
+	r.mux.RLock()
+	list := make([]Report, 0, len(r.reports))
+	for _, rep := range r.reports {
+		list = append(list, rep)
+	}
+	r.mux.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return key(list[i].Kind, list[i].Name) < key(list[j].Kind, list[j].Name)
+	})
+	return list
+}
+
+// ServeHTTP serves the Reports as JSON.
+func (r *Reports) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.List()); err != nil {
+		log.Error(err, "writing reports")
+	}
+}
+
+func key(kind, name string) string {
+	return kind + "/" + name
+}
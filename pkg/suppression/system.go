@@ -0,0 +1,174 @@
+// Package suppression hides accepted violations from audit according to
+// Suppressions, so that known risks stop alerting without being forgotten.
+//
+// A Suppression refers to constraints by kind and optionally name, and selects
+// the objects whose violations it suppresses with the same match criteria as
+// mutators. Unlike an Exception, a Suppression does not change what the
+// webhook admits: audit keeps the violations it suppresses out of the status
+// of constraints, events, tickets and violation metrics, and instead records
+// them in the status of the Suppression and counts them in a metric of their
+// own.
+package suppression
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var (
+	// Enabled is whether Suppressions are loaded and suppress violations.
+	Enabled = flag.Bool("enable-suppressions", false, "(alpha) hide the violations selected by Suppressions from the status of constraints and the exports of audit, recording them in the status of the Suppressions instead")
+
+	log = logf.Log.WithName("suppression")
+)
+
+// System holds the Suppressions of the cluster.
+type System struct {
+	mux          sync.RWMutex
+	suppressions map[string]*entry
+}
+
+// entry is a Suppression, with the Matcher of its match.
+type entry struct {
+	suppression *exceptionsv1alpha1.Suppression
+	matcher     *match.Matcher
+}
+
+var system = NewSystem()
+
+// Get returns the System of this process.
+func Get() *System {
+	return system
+}
+
+// NewSystem returns a System without Suppressions.
+func NewSystem() *System {
+	return &System{suppressions: make(map[string]*entry)}
+}
+
+// Validate returns an error if s cannot be used to suppress violations.
+func Validate(s *exceptionsv1alpha1.Suppression) error {
+	if len(s.Spec.Constraints) == 0 {
+		return errors.New("spec.constraints must not be empty")
+	}
+	for i, c := range s.Spec.Constraints {
+		if c.Kind == "" {
+			return fmt.Errorf("spec.constraints[%d].kind must be set", i)
+		}
+	}
+	if s.Spec.Justification.Reason == "" {
+		return errors.New("spec.justification.reason must be set")
+	}
+	for field, sel := range map[string]*metav1.LabelSelector{
+		"labelSelector":     s.Spec.Match.LabelSelector,
+		"namespaceSelector": s.Spec.Match.NamespaceSelector,
+	} {
+		if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+			return fmt.Errorf("invalid spec.match.%s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// Upsert adds sup, or replaces the Suppression of the same name.
+func (s *System) Upsert(sup *exceptionsv1alpha1.Suppression) error {
+	if err := Validate(sup); err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	sup = sup.DeepCopy()
+	s.suppressions[sup.GetName()] = &entry{suppression: sup, matcher: match.NewMatcher(&sup.Spec.Match)}
+	return nil
+}
+
+// Remove removes the Suppression named name.
+func (s *System) Remove(name string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.suppressions, name)
+}
+
+// Suppressing returns the sorted names of the Suppressions which suppress the
+// violations of constraint by obj. ns is the namespace of obj, or nil if obj
+// is cluster-scoped.
+func (s *System) Suppressing(constraint, obj *unstructured.Unstructured, ns *corev1.Namespace) ([]string, error) {
+	if isNamespace(obj) {
+		// Namespaces are matched against themselves, as for mutators.
+		ns = &corev1.Namespace{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ns); err != nil {
+			return nil, err
+		}
+	} else if obj.GetNamespace() == "" && (ns == nil || ns.GetName() == "") {
+		ns = nil
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	var names []string
+	for name, en := range s.suppressions {
+		if !refersTo(en.suppression, constraint) {
+			continue
+		}
+		matches, err := en.matcher.Matches(obj, ns)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Filter returns the results which are not suppressed by a Suppression, and
+// the names of the Suppressions suppressing the others. ns is the namespace of
+// the reviewed objects.
+func (s *System) Filter(results []*types.Result, ns *corev1.Namespace) ([]*types.Result, map[*types.Result][]string) {
+	var kept []*types.Result
+	suppressed := make(map[*types.Result][]string)
+	for _, r := range results {
+		obj, ok := r.Resource.(*unstructured.Unstructured)
+		if !ok || r.Constraint == nil {
+			kept = append(kept, r)
+			continue
+		}
+		names, err := s.Suppressing(r.Constraint, obj, ns)
+		if err != nil {
+			log.Error(err, "unable to match suppressions", "kind", obj.GetKind(), "name", obj.GetName())
+		}
+		if len(names) == 0 {
+			kept = append(kept, r)
+			continue
+		}
+		suppressed[r] = names
+	}
+	return kept, suppressed
+}
+
+func refersTo(s *exceptionsv1alpha1.Suppression, constraint *unstructured.Unstructured) bool {
+	for _, c := range s.Spec.Constraints {
+		if c.Kind == constraint.GetKind() && (c.Name == "" || c.Name == constraint.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNamespace(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group == "" && gvk.Kind == "Namespace"
+}
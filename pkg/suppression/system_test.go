@@ -0,0 +1,212 @@
+package suppression
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newSuppression(name string, m match.Match, refs ...exceptionsv1alpha1.ConstraintReference) *exceptionsv1alpha1.Suppression {
+	return &exceptionsv1alpha1.Suppression{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: exceptionsv1alpha1.SuppressionSpec{
+			Constraints:   refs,
+			Match:         m,
+			Justification: exceptionsv1alpha1.Justification{Reason: "accepted risk"},
+		},
+	}
+}
+
+func newConstraint(kind, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("constraints.gatekeeper.sh/v1beta1")
+	u.SetKind(kind)
+	u.SetName(name)
+	return u
+}
+
+func newObject(kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newSystem(t *testing.T, suppressions ...*exceptionsv1alpha1.Suppression) *System {
+	s := NewSystem()
+	for _, sup := range suppressions {
+		if err := s.Upsert(sup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *exceptionsv1alpha1.Suppression {
+		return newSuppression("s", match.Match{}, exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels"})
+	}
+
+	tcs := []struct {
+		name    string
+		mutate  func(s *exceptionsv1alpha1.Suppression)
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			mutate: func(s *exceptionsv1alpha1.Suppression) {},
+		},
+		{
+			name:    "no constraints",
+			mutate:  func(s *exceptionsv1alpha1.Suppression) { s.Spec.Constraints = nil },
+			wantErr: true,
+		},
+		{
+			name:    "constraint without kind",
+			mutate:  func(s *exceptionsv1alpha1.Suppression) { s.Spec.Constraints[0].Kind = "" },
+			wantErr: true,
+		},
+		{
+			name:    "no reason",
+			mutate:  func(s *exceptionsv1alpha1.Suppression) { s.Spec.Justification.Reason = "" },
+			wantErr: true,
+		},
+		{
+			name: "invalid namespace selector",
+			mutate: func(s *exceptionsv1alpha1.Suppression) {
+				s.Spec.Match.NamespaceSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: "Bogus"}},
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := valid()
+			tc.mutate(s)
+			err := Validate(s)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("got Validate() error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSuppressing(t *testing.T) {
+	labels := exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels"}
+	onlyFoo := exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels", Name: "foo"}
+	inLegacy := match.Match{Namespaces: []string{"legacy"}}
+	legacy := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	tcs := []struct {
+		name         string
+		suppressions []*exceptionsv1alpha1.Suppression
+		constraint   *unstructured.Unstructured
+		obj          *unstructured.Unstructured
+		ns           *corev1.Namespace
+		want         []string
+	}{
+		{
+			name:       "no suppressions",
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+		},
+		{
+			name:         "every constraint of a kind",
+			suppressions: []*exceptionsv1alpha1.Suppression{newSuppression("s", inLegacy, labels)},
+			constraint:   newConstraint("K8sRequiredLabels", "foo"),
+			obj:          newObject("Pod", "legacy", "a"),
+			ns:           legacy,
+			want:         []string{"s"},
+		},
+		{
+			name:         "named constraint",
+			suppressions: []*exceptionsv1alpha1.Suppression{newSuppression("s", inLegacy, onlyFoo)},
+			constraint:   newConstraint("K8sRequiredLabels", "foo"),
+			obj:          newObject("Pod", "legacy", "a"),
+			ns:           legacy,
+			want:         []string{"s"},
+		},
+		{
+			name:         "other constraint of the kind",
+			suppressions: []*exceptionsv1alpha1.Suppression{newSuppression("s", inLegacy, onlyFoo)},
+			constraint:   newConstraint("K8sRequiredLabels", "bar"),
+			obj:          newObject("Pod", "legacy", "a"),
+			ns:           legacy,
+		},
+		{
+			name:         "unmatched object",
+			suppressions: []*exceptionsv1alpha1.Suppression{newSuppression("s", inLegacy, labels)},
+			constraint:   newConstraint("K8sRequiredLabels", "foo"),
+			obj:          newObject("Pod", "other", "a"),
+			ns:           other,
+		},
+		{
+			name: "several suppressions",
+			suppressions: []*exceptionsv1alpha1.Suppression{
+				newSuppression("b", match.Match{}, labels),
+				newSuppression("a", inLegacy, onlyFoo),
+			},
+			constraint: newConstraint("K8sRequiredLabels", "foo"),
+			obj:        newObject("Pod", "legacy", "a"),
+			ns:         legacy,
+			want:       []string{"a", "b"},
+		},
+		{
+			name:         "namespace matched against itself",
+			suppressions: []*exceptionsv1alpha1.Suppression{newSuppression("s", inLegacy, labels)},
+			constraint:   newConstraint("K8sRequiredLabels", "foo"),
+			obj:          newObject("Namespace", "", "legacy"),
+			ns:           &corev1.Namespace{},
+			want:         []string{"s"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSystem(t, tc.suppressions...)
+			got, err := s.Suppressing(tc.constraint, tc.obj, tc.ns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := newSystem(t, newSuppression("s", match.Match{Namespaces: []string{"legacy"}},
+		exceptionsv1alpha1.ConstraintReference{Kind: "K8sRequiredLabels", Name: "foo"}))
+	legacy := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}}
+	obj := newObject("Pod", "legacy", "a")
+
+	suppressed := &types.Result{Constraint: newConstraint("K8sRequiredLabels", "foo"), Resource: obj}
+	kept := &types.Result{Constraint: newConstraint("K8sRequiredLabels", "bar"), Resource: obj}
+
+	gotKept, gotSuppressed := s.Filter([]*types.Result{suppressed, kept}, legacy)
+	if len(gotKept) != 1 || gotKept[0] != kept {
+		t.Errorf("got kept %v, want only the result of constraint bar", gotKept)
+	}
+	if diff := cmp.Diff(map[*types.Result][]string{suppressed: {"s"}}, gotSuppressed); diff != "" {
+		t.Error(diff)
+	}
+
+	s.Remove("s")
+	if gotKept, _ := s.Filter([]*types.Result{suppressed, kept}, legacy); len(gotKept) != 2 {
+		t.Errorf("got %d results kept after removing the suppression, want 2", len(gotKept))
+	}
+}
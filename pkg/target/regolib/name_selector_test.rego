@@ -0,0 +1,37 @@
+package target
+
+test_name_empty_match {
+  matches_name({}) with input.review as {"object": {"metadata": {"name": "foo"}}}
+}
+
+test_name_exact_match {
+  matches_name({"name": "foo"}) with input.review as {"object": {"metadata": {"name": "foo"}}}
+}
+
+test_name_exact_no_match {
+  not matches_name({"name": "foo"}) with input.review as {"object": {"metadata": {"name": "foobar"}}}
+}
+
+test_name_prefix_match {
+  matches_name({"name": "foo*"}) with input.review as {"object": {"metadata": {"name": "foobar"}}}
+}
+
+test_name_prefix_with_dot_match {
+  matches_name({"name": "foo*"}) with input.review as {"object": {"metadata": {"name": "foo.bar"}}}
+}
+
+test_name_prefix_no_match {
+  not matches_name({"name": "foo*"}) with input.review as {"object": {"metadata": {"name": "barfoo"}}}
+}
+
+test_name_from_review {
+  matches_name({"name": "foo"}) with input.review as {"name": "foo"}
+}
+
+test_name_generated_no_match {
+  not matches_name({"name": "foo*"}) with input.review as {"object": {"metadata": {"generateName": "foo-"}}}
+}
+
+test_name_from_old_object {
+  matches_name({"name": "foo"}) with input.review as {"oldObject": {"metadata": {"name": "foo"}}}
+}
@@ -0,0 +1,29 @@
+package target
+
+test_operations_empty_match {
+  matches_operations({}) with input.review as {"operation": "DELETE"}
+}
+
+test_operations_match {
+  matches_operations({"operations": ["CREATE", "DELETE"]}) with input.review as {"operation": "DELETE"}
+}
+
+test_operations_no_match {
+  not matches_operations({"operations": ["DELETE"]}) with input.review as {"operation": "UPDATE"}
+}
+
+test_operations_wildcard_match {
+  matches_operations({"operations": ["*"]}) with input.review as {"operation": "CONNECT"}
+}
+
+test_operations_audit_match {
+  matches_operations({"operations": ["UPDATE"]}) with input.review as {}
+}
+
+test_operations_audit_empty_operation_match {
+  matches_operations({"operations": ["CREATE"]}) with input.review as {"operation": ""}
+}
+
+test_operations_audit_no_match {
+  not matches_operations({"operations": ["DELETE"]}) with input.review as {}
+}
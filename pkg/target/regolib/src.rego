@@ -34,6 +34,14 @@ matching_constraints[constraint] {
 
   matches_scope(match)
 
+  matches_name(match)
+
+  matches_operations(match)
+
+  matches_thresholds(match)
+
+  matches_object_size(constraint)
+
   label_selector := get_default(match, "labelSelector", {})
   any_labelselector_match(label_selector)
 }
@@ -174,6 +182,148 @@ matches_scope(match) {
   get_default(input.review, "namespace", "") == ""
 }
 
+#######################
+# Name Selector Logic #
+#######################
+
+# The name of the object under review. Objects created with generateName
+# have no name yet.
+review_name(review) = name {
+  name := review.object.metadata.name
+}
+
+review_name(review) = name {
+  not review.object.metadata.name
+  name := review.oldObject.metadata.name
+}
+
+review_name(review) = name {
+  not review.object.metadata.name
+  not review.oldObject.metadata.name
+  name := get_default(review, "name", "")
+}
+
+matches_name(match) {
+  not has_field(match, "name")
+}
+
+matches_name(match) {
+  has_field(match, "name")
+  not endswith(match.name, "*")
+  match.name == review_name(input.review)
+}
+
+# Prefix-based matching
+matches_name(match) {
+  has_field(match, "name")
+  endswith(match.name, "*")
+  startswith(review_name(input.review), trim_suffix(match.name, "*"))
+}
+
+############################
+# Operation Selector Logic #
+############################
+
+matches_operations(match) {
+  not has_field(match, "operations")
+}
+
+matches_operations(match) {
+  match.operations[_] == "*"
+}
+
+matches_operations(match) {
+  match.operations[_] == input.review.operation
+}
+
+# Audit and gator review existing objects without an operation, which are
+# matched as though they were being created or updated.
+matches_operations(match) {
+  get_default(input.review, "operation", "") == ""
+  audited := {"CREATE", "UPDATE"}
+  audited[match.operations[_]]
+}
+
+############################
+# Threshold Selector Logic #
+############################
+
+matches_thresholds(match) {
+  not has_field(match, "thresholds")
+}
+
+matches_thresholds(match) {
+  has_field(match, "thresholds")
+  not any_threshold_unmet(match.thresholds)
+}
+
+any_threshold_unmet(thresholds) {
+  threshold := thresholds[_]
+  not threshold_met(threshold)
+}
+
+threshold_met(threshold) {
+  quantity := field_quantity(input.review.object, threshold.field)
+  compare_quantity(threshold.operator, quantity, threshold.value)
+}
+
+# The quantity of a field is its value if it is a number, and its number of
+# items if it is an array or object. Other fields have no quantity.
+field_quantity(obj, field) = quantity {
+  value := field_value(obj, field)
+  is_number(value)
+  quantity := value
+}
+
+field_quantity(obj, field) = quantity {
+  value := field_value(obj, field)
+  is_array(value)
+  quantity := count(value)
+}
+
+field_quantity(obj, field) = quantity {
+  value := field_value(obj, field)
+  is_object(value)
+  quantity := count(value)
+}
+
+# field_value returns the value at the dot-separated path field of obj. Only
+# the path is walked, so large objects are cheap to match.
+field_value(obj, field) = value {
+  path := split(field, ".")
+  walk(json.filter(obj, [concat("/", path)]), [path, value])
+}
+
+compare_quantity("Gt", quantity, value) {
+  quantity > value
+}
+
+compare_quantity("Gte", quantity, value) {
+  quantity >= value
+}
+
+compare_quantity("Lt", quantity, value) {
+  quantity < value
+}
+
+compare_quantity("Lte", quantity, value) {
+  quantity <= value
+}
+
+compare_quantity("Eq", quantity, value) {
+  quantity == value
+}
+
+# Objects larger than --max-evaluated-object-size are only reviewed by
+# constraints which opt into large objects.
+matches_object_size(constraint) {
+  not input.review._unstable.largeObject
+}
+
+matches_object_size(constraint) {
+  constraint.metadata.annotations["admission.gatekeeper.sh/evaluate-large-objects"] == "true"
+}
+
 ########################
 # Label Selector Logic #
 ########################
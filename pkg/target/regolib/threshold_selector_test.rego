@@ -0,0 +1,46 @@
+package target
+
+deployment := {"spec": {
+  "replicas": 3,
+  "template": {"spec": {"containers": [{"name": "a"}, {"name": "b"}]}},
+  "selector": {"matchLabels": {"app": "a"}},
+}}
+
+test_thresholds_empty_match {
+  matches_thresholds({}) with input.review as {"object": deployment}
+}
+
+test_thresholds_number_match {
+  matches_thresholds({"thresholds": [{"field": "spec.replicas", "operator": "Gte", "value": 3}]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_number_no_match {
+  not matches_thresholds({"thresholds": [{"field": "spec.replicas", "operator": "Gt", "value": 3}]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_array_match {
+  matches_thresholds({"thresholds": [{"field": "spec.template.spec.containers", "operator": "Gt", "value": 1}]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_object_match {
+  matches_thresholds({"thresholds": [{"field": "spec.selector.matchLabels", "operator": "Eq", "value": 1}]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_all_must_match {
+  not matches_thresholds({"thresholds": [
+    {"field": "spec.replicas", "operator": "Lte", "value": 3},
+    {"field": "spec.template.spec.containers", "operator": "Lt", "value": 2},
+  ]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_missing_field_no_match {
+  not matches_thresholds({"thresholds": [{"field": "spec.paused", "operator": "Lt", "value": 1}]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_string_field_no_match {
+  not matches_thresholds({"thresholds": [{"field": "spec.selector.matchLabels.app", "operator": "Gte", "value": 0}]}) with input.review as {"object": deployment}
+}
+
+test_thresholds_no_object_no_match {
+  not matches_thresholds({"thresholds": [{"field": "spec.replicas", "operator": "Gte", "value": 0}]}) with input.review as {}
+}
@@ -0,0 +1,108 @@
+package target
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/util"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The benchmarks below compare the review HandleReview returns for an audited
+// object with two alternatives: a review which encodes the object lazily,
+// only when the review itself is encoded, and encoding the object with
+// pooled buffers. Each benchmark converts the review as the local driver
+// does: encoding it for the Input of the response, then round tripping it
+// through JSON into the input of the query.
+
+// lazyReview is a review whose object is encoded only when the review is.
+type lazyReview struct {
+	obj *unstructured.Unstructured
+}
+
+func (r *lazyReview) MarshalJSON() ([]byte, error) {
+	gvk := r.obj.GroupVersionKind()
+	return json.Marshal(struct {
+		Kind   metav1.GroupVersionKind `json:"kind"`
+		Name   string                  `json:"name"`
+		Object map[string]interface{}  `json:"object"`
+	}{
+		Kind:   metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+		Name:   r.obj.GetName(),
+		Object: r.obj.Object,
+	})
+}
+
+var encodeBuffers = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+// pooledReview is unstructuredToAdmissionRequest, encoding the object into a
+// pooled buffer.
+func pooledReview(obj *unstructured.Unstructured) (*admissionv1.AdmissionRequest, error) {
+	buf := encodeBuffers.Get().(*bytes.Buffer)
+	defer encodeBuffers.Put(buf)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(obj.Object); err != nil {
+		return nil, err
+	}
+	gvk := obj.GroupVersionKind()
+	return &admissionv1.AdmissionRequest{
+		Kind: metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+		// The request outlives the buffer, so the encoding is copied.
+		Object: runtime.RawExtension{Raw: append([]byte(nil), bytes.TrimSpace(buf.Bytes())...)},
+		Name:   obj.GetName(),
+	}, nil
+}
+
+// convertInput converts review as the local driver converts the input of a
+// query.
+func convertInput(b *testing.B, review interface{}) {
+	input := map[string]interface{}{"review": review}
+	if _, err := json.MarshalIndent(input, "", "   "); err != nil {
+		b.Fatal(err)
+	}
+	var x interface{} = input
+	if err := util.RoundTrip(&x); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := ast.InterfaceToValue(x); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkReviewInput(b *testing.B) {
+	obj := reviewTestObject(50)
+	h := &K8sValidationTarget{}
+
+	b.Run("eager", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, review, err := h.HandleReview(obj)
+			if err != nil {
+				b.Fatal(err)
+			}
+			convertInput(b, review)
+		}
+	})
+	b.Run("lazy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			convertInput(b, &lazyReview{obj: obj})
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			review, err := pooledReview(obj)
+			if err != nil {
+				b.Fatal(err)
+			}
+			convertInput(b, review)
+		}
+	})
+}
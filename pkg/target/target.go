@@ -3,6 +3,7 @@ package target
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/url"
 	"path"
 	"text/template"
@@ -64,8 +65,15 @@ func processWipeData() (bool, string, interface{}, error) {
 type AugmentedReview struct {
 	AdmissionRequest *admissionv1.AdmissionRequest
 	Namespace        *corev1.Namespace
+	// LargeObject is whether the object of the request is too large to be
+	// reviewed by constraints which do not set LargeObjectsAnnotation.
+	LargeObject bool
 }
 
+// LargeObjectsAnnotation is set to "true" on constraints which review large
+// objects.
+const LargeObjectsAnnotation = "admission.gatekeeper.sh/evaluate-large-objects"
+
 type gkReview struct {
 	*admissionv1.AdmissionRequest
 	Unstable *unstable `json:"_unstable,omitempty"`
@@ -77,7 +85,8 @@ type AugmentedUnstructured struct {
 }
 
 type unstable struct {
-	Namespace *corev1.Namespace `json:"namespace,omitempty"`
+	Namespace   *corev1.Namespace `json:"namespace,omitempty"`
+	LargeObject bool              `json:"largeObject,omitempty"`
 }
 
 func processUnstructured(o *unstructured.Unstructured) (bool, string, interface{}, error) {
@@ -116,9 +125,9 @@ func (h *K8sValidationTarget) HandleReview(obj interface{}) (bool, interface{},
 	case *admissionv1.AdmissionRequest:
 		return true, data, nil
 	case AugmentedReview:
-		return true, &gkReview{AdmissionRequest: data.AdmissionRequest, Unstable: &unstable{Namespace: data.Namespace}}, nil
+		return true, &gkReview{AdmissionRequest: data.AdmissionRequest, Unstable: &unstable{Namespace: data.Namespace, LargeObject: data.LargeObject}}, nil
 	case *AugmentedReview:
-		return true, &gkReview{AdmissionRequest: data.AdmissionRequest, Unstable: &unstable{Namespace: data.Namespace}}, nil
+		return true, &gkReview{AdmissionRequest: data.AdmissionRequest, Unstable: &unstable{Namespace: data.Namespace, LargeObject: data.LargeObject}}, nil
 	case AugmentedUnstructured:
 		admissionRequest, err := augmentedUnstructuredToAdmissionRequest(data)
 		if err != nil {
@@ -162,6 +171,10 @@ func augmentedUnstructuredToAdmissionRequest(obj AugmentedUnstructured) (gkRevie
 	return review, nil
 }
 
+// unstructuredToAdmissionRequest encodes obj eagerly. BenchmarkReviewInput
+// compares it with encoding obj lazily or into pooled buffers, neither of which
+// saves enough to be worth the complexity, as the driver encodes the review
+// again whatever it holds.
 func unstructuredToAdmissionRequest(obj unstructured.Unstructured) (admissionv1.AdmissionRequest, error) {
 	resourceJSON, err := json.Marshal(obj.Object)
 	if err != nil {
@@ -183,6 +196,26 @@ func unstructuredToAdmissionRequest(obj unstructured.Unstructured) (admissionv1.
 	return req, nil
 }
 
+// OperationReview returns the review of the admission request performing
+// operation on obj, as the webhook receives it. ns is the Namespace of obj, if
+// any. A DELETE carries the existing object as oldObject, which the webhook
+// also reviews as the object.
+func OperationReview(obj *unstructured.Unstructured, operation admissionv1.Operation, ns *corev1.Namespace) (*AugmentedReview, error) {
+	req, err := unstructuredToAdmissionRequest(*obj)
+	if err != nil {
+		return nil, err
+	}
+	req.Operation = operation
+	// Requests for Namespaces are not within a namespace.
+	if req.Kind.Group != "" || req.Kind.Kind != "Namespace" {
+		req.Namespace = obj.GetNamespace()
+	}
+	if operation == admissionv1.Delete {
+		req.OldObject = req.Object
+	}
+	return &AugmentedReview{AdmissionRequest: &req, Namespace: ns}, nil
+}
+
 func getString(m map[string]interface{}, k string) (string, error) {
 	val, exists, err := unstructured.NestedFieldNoCopy(m, "kind", k)
 	if err != nil {
@@ -211,6 +244,34 @@ func nestedMap(rmap map[string]interface{}, field string) (map[string]interface{
 	return objMap, true, nil
 }
 
+// Limits of the float64 values which can be exactly converted to int64.
+const (
+	minInt64Float = -(1 << 63)
+	maxInt64Float = 1 << 63
+)
+
+// normalizeNumbers converts in place the integral float64 values of v, as
+// decoded from JSON into an interface{}, to int64. This matches decoding the
+// same JSON into an unstructured.Unstructured, without the cost of
+// re-encoding v.
+func normalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeNumbers(e)
+		}
+	case float64:
+		if t == math.Trunc(t) && t >= minInt64Float && t < maxInt64Float {
+			return int64(t)
+		}
+	}
+	return v
+}
+
 func (h *K8sValidationTarget) HandleViolation(result *types.Result) error {
 	rmap, ok := result.Review.(map[string]interface{})
 	if !ok {
@@ -249,18 +310,12 @@ func (h *K8sValidationTarget) HandleViolation(result *types.Result) error {
 		}
 	}
 
+	// objMap is a copy, so it may be modified and used as the resource
+	// without re-encoding it.
+	normalizeNumbers(objMap)
 	objMap["apiVersion"] = apiVersion
 	objMap["kind"] = kind
-
-	js, err := json.Marshal(objMap)
-	if err != nil {
-		return errors.Wrap(err, "HandleViolation:Marshal(Object)")
-	}
-	obj := &unstructured.Unstructured{}
-	if err := json.Unmarshal(js, obj); err != nil {
-		return errors.Wrap(err, "HandleViolation:Unmarshal(unstructured)")
-	}
-	result.Resource = obj
+	result.Resource = &unstructured.Unstructured{Object: objMap}
 	return nil
 }
 
@@ -341,6 +396,7 @@ func (h *K8sValidationTarget) MatchSchema() apiextensions.JSONSchemaProps {
 			"excludedNamespaces": wildcardNSList,
 			"labelSelector":      labelSelectorSchema,
 			"namespaceSelector":  labelSelectorSchema,
+			"clusterSelector":    labelSelectorSchema,
 			"scope": {
 				Type: "string",
 				Enum: []apiextensions.JSON{
@@ -349,38 +405,72 @@ func (h *K8sValidationTarget) MatchSchema() apiextensions.JSONSchemaProps {
 					"Namespaced",
 				},
 			},
+			"name": {Type: "string"},
+			"operations": {
+				Type: "array",
+				Items: &apiextensions.JSONSchemaPropsOrArray{
+					Schema: &apiextensions.JSONSchemaProps{
+						Type: "string",
+						Enum: []apiextensions.JSON{
+							"*",
+							"CREATE",
+							"UPDATE",
+							"DELETE",
+							"CONNECT",
+						},
+					},
+				},
+			},
+			"thresholds": {
+				Type: "array",
+				Items: &apiextensions.JSONSchemaPropsOrArray{
+					Schema: &apiextensions.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"field", "operator", "value"},
+						Properties: map[string]apiextensions.JSONSchemaProps{
+							"field": {Type: "string"},
+							"operator": {
+								Type: "string",
+								Enum: []apiextensions.JSON{
+									"Gt",
+									"Gte",
+									"Lt",
+									"Lte",
+									"Eq",
+								},
+							},
+							"value": {Type: "number"},
+						},
+					},
+				},
+			},
 		},
 	}
 }
 
 func (h *K8sValidationTarget) ValidateConstraint(u *unstructured.Unstructured) error {
-	labelSelector, found, err := unstructured.NestedMap(u.Object, "spec", "match", "labelSelector")
-	if err != nil {
-		return err
-	}
-
-	if found && labelSelector != nil {
-		labelSelectorObj, err := convertToLabelSelector(labelSelector)
-		if err != nil {
+	for _, name := range []string{"labelSelector", "namespaceSelector", "clusterSelector"} {
+		if err := validateSelector(u, name); err != nil {
 			return err
 		}
-		errorList := validation.ValidateLabelSelector(labelSelectorObj, field.NewPath("spec", "labelSelector"))
-		if len(errorList) > 0 {
-			return errorList.ToAggregate()
-		}
 	}
+	return nil
+}
 
-	namespaceSelector, found, err := unstructured.NestedMap(u.Object, "spec", "match", "namespaceSelector")
+// validateSelector returns an error if the label selector spec.match.<name> of
+// u is invalid.
+func validateSelector(u *unstructured.Unstructured, name string) error {
+	selector, found, err := unstructured.NestedMap(u.Object, "spec", "match", name)
 	if err != nil {
 		return err
 	}
 
-	if found && namespaceSelector != nil {
-		namespaceSelectorObj, err := convertToLabelSelector(namespaceSelector)
+	if found && selector != nil {
+		selectorObj, err := convertToLabelSelector(selector)
 		if err != nil {
 			return err
 		}
-		errorList := validation.ValidateLabelSelector(namespaceSelectorObj, field.NewPath("spec", "labelSelector"))
+		errorList := validation.ValidateLabelSelector(selectorObj, field.NewPath("spec", "match", name))
 		if len(errorList) > 0 {
 			return errorList.ToAggregate()
 		}
@@ -3,6 +3,7 @@ package target
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/ghodss/yaml"
@@ -102,6 +103,22 @@ func setScope(scope string) buildArg {
 	}
 }
 
+func setName(name string) buildArg {
+	return func(obj *unstructured.Unstructured) {
+		if err := unstructured.SetNestedField(obj.Object, name, "spec", "match", "name"); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func setOperations(ops ...string) buildArg {
+	return func(obj *unstructured.Unstructured) {
+		if err := unstructured.SetNestedStringSlice(obj.Object, ops, "spec", "match", "operations"); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func makeConstraint(o ...buildArg) *unstructured.Unstructured {
 	u := &unstructured.Unstructured{}
 	u.SetName("my-constraint")
@@ -463,3 +480,246 @@ func TestConstraintEnforcement(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchNameAndOperations(t *testing.T) {
+	tcs := []struct {
+		name         string
+		objName      string
+		operation    admissionv1.Operation
+		constraint   *unstructured.Unstructured
+		allowed      bool
+		auditAllowed bool
+	}{
+		{
+			name:         "match name",
+			objName:      "protected",
+			operation:    admissionv1.Create,
+			constraint:   makeConstraint(setName("protected")),
+			allowed:      false,
+			auditAllowed: false,
+		},
+		{
+			name:         "no match name",
+			objName:      "unprotected",
+			operation:    admissionv1.Create,
+			constraint:   makeConstraint(setName("protected")),
+			allowed:      true,
+			auditAllowed: true,
+		},
+		{
+			name:         "match name prefix",
+			objName:      "protected-db",
+			operation:    admissionv1.Create,
+			constraint:   makeConstraint(setName("protected-*")),
+			allowed:      false,
+			auditAllowed: false,
+		},
+		{
+			name:         "match operation",
+			objName:      "protected",
+			operation:    admissionv1.Delete,
+			constraint:   makeConstraint(setName("protected"), setOperations("DELETE")),
+			allowed:      false,
+			auditAllowed: true,
+		},
+		{
+			name:         "no match operation",
+			objName:      "protected",
+			operation:    admissionv1.Update,
+			constraint:   makeConstraint(setName("protected"), setOperations("DELETE")),
+			allowed:      true,
+			auditAllowed: true,
+		},
+		{
+			name:         "audit matches as update",
+			objName:      "protected",
+			operation:    admissionv1.Create,
+			constraint:   makeConstraint(setOperations("UPDATE")),
+			allowed:      true,
+			auditAllowed: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			target := &K8sValidationTarget{}
+			driver := local.New()
+			backend, err := client.NewBackend(client.Driver(driver))
+			if err != nil {
+				t.Fatalf("Could not initialize backend: %s", err)
+			}
+			c, err := backend.NewClient(client.Targets(target))
+			if err != nil {
+				t.Fatalf("unable to set up OPA client: %s", err)
+			}
+
+			tmpl := &templates.ConstraintTemplate{}
+			if err := yaml.Unmarshal([]byte(testTemplate), tmpl); err != nil {
+				t.Fatalf("unable to unmarshal template: %s", err)
+			}
+			if _, err := c.AddTemplate(context.Background(), tmpl); err != nil {
+				t.Fatalf("unable to add template: %s", err)
+			}
+			if _, err := c.AddConstraint(context.Background(), tc.constraint); err != nil {
+				t.Fatalf("unable to add constraint: %s", err)
+			}
+
+			obj := makeResource("some", "Thing")
+			obj.SetName(tc.objName)
+			objData, err := json.Marshal(obj.Object)
+			if err != nil {
+				t.Fatalf("unable to marshal obj: %s", err)
+			}
+			req := &admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Group: "some", Version: "v1", Kind: "Thing"},
+				Name:      tc.objName,
+				Operation: tc.operation,
+				Object:    runtime.RawExtension{Raw: objData},
+			}
+			res, err := c.Review(context.Background(), &AugmentedReview{AdmissionRequest: req})
+			if err != nil {
+				t.Fatalf("Error reviewing request: %s", err)
+			}
+			if (len(res.Results()) == 0) != tc.allowed {
+				t.Errorf("allowed = %v, expected %v", !tc.allowed, tc.allowed)
+			}
+
+			res, err = c.Review(context.Background(), obj)
+			if err != nil {
+				t.Fatalf("Error reviewing object: %s", err)
+			}
+			if (len(res.Results()) == 0) != tc.auditAllowed {
+				t.Errorf("audit allowed = %v, expected %v", !tc.auditAllowed, tc.auditAllowed)
+			}
+		})
+	}
+}
+
+func setThreshold(field, operator string, value interface{}) buildArg {
+	return func(obj *unstructured.Unstructured) {
+		thresholds, _, _ := unstructured.NestedSlice(obj.Object, "spec", "match", "thresholds")
+		thresholds = append(thresholds, map[string]interface{}{"field": field, "operator": operator, "value": value})
+		if err := unstructured.SetNestedSlice(obj.Object, thresholds, "spec", "match", "thresholds"); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestMatchThresholds(t *testing.T) {
+	tcs := []struct {
+		name       string
+		replicas   int64
+		containers int
+		constraint *unstructured.Unstructured
+		allowed    bool
+		invalid    bool
+	}{
+		{
+			name:       "match number",
+			replicas:   5,
+			constraint: makeConstraint(setThreshold("spec.replicas", "Gte", int64(3))),
+			allowed:    false,
+		},
+		{
+			name:       "no match number",
+			replicas:   1,
+			constraint: makeConstraint(setThreshold("spec.replicas", "Gte", int64(3))),
+			allowed:    true,
+		},
+		{
+			name:       "match fraction",
+			replicas:   1,
+			constraint: makeConstraint(setThreshold("spec.replicas", "Lt", 1.5)),
+			allowed:    false,
+		},
+		{
+			name:       "match count",
+			containers: 2,
+			constraint: makeConstraint(setThreshold("spec.template.spec.containers", "Gt", int64(1))),
+			allowed:    false,
+		},
+		{
+			name:       "no match count",
+			containers: 1,
+			constraint: makeConstraint(setThreshold("spec.template.spec.containers", "Gt", int64(1))),
+			allowed:    true,
+		},
+		{
+			name:       "every threshold must be met",
+			replicas:   5,
+			containers: 1,
+			constraint: makeConstraint(
+				setThreshold("spec.replicas", "Gte", int64(3)),
+				setThreshold("spec.template.spec.containers", "Gt", int64(1)),
+			),
+			allowed: true,
+		},
+		{
+			name:       "no match missing field",
+			constraint: makeConstraint(setThreshold("spec.paused", "Lt", int64(1))),
+			allowed:    true,
+		},
+		{
+			name:       "invalid operator",
+			constraint: makeConstraint(setThreshold("spec.replicas", ">=", int64(3))),
+			invalid:    true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			target := &K8sValidationTarget{}
+			driver := local.New()
+			backend, err := client.NewBackend(client.Driver(driver))
+			if err != nil {
+				t.Fatalf("Could not initialize backend: %s", err)
+			}
+			c, err := backend.NewClient(client.Targets(target))
+			if err != nil {
+				t.Fatalf("unable to set up OPA client: %s", err)
+			}
+
+			tmpl := &templates.ConstraintTemplate{}
+			if err := yaml.Unmarshal([]byte(testTemplate), tmpl); err != nil {
+				t.Fatalf("unable to unmarshal template: %s", err)
+			}
+			if _, err := c.AddTemplate(context.Background(), tmpl); err != nil {
+				t.Fatalf("unable to add template: %s", err)
+			}
+			_, err = c.AddConstraint(context.Background(), tc.constraint)
+			if tc.invalid {
+				if err == nil {
+					t.Fatal("got no error adding constraint, want invalid")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to add constraint: %s", err)
+			}
+
+			obj := makeResource("apps", "Deployment")
+			obj.SetName("deployment")
+			if tc.replicas != 0 {
+				if err := unstructured.SetNestedField(obj.Object, tc.replicas, "spec", "replicas"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.containers != 0 {
+				containers := make([]interface{}, tc.containers)
+				for i := range containers {
+					containers[i] = map[string]interface{}{"name": fmt.Sprintf("c%d", i)}
+				}
+				if err := unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res, err := c.Review(context.Background(), obj)
+			if err != nil {
+				t.Fatalf("Error reviewing object: %s", err)
+			}
+			if (len(res.Results()) == 0) != tc.allowed {
+				t.Errorf("allowed = %v, expected %v", !tc.allowed, tc.allowed)
+			}
+		})
+	}
+}
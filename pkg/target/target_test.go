@@ -2,6 +2,7 @@ package target
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	admissionv1 "k8s.io/api/admission/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
@@ -223,6 +225,56 @@ func TestValidateConstraint(t *testing.T) {
 `,
 			ErrorExpected: true,
 		},
+		{
+			Name: "Invalid ClusterSelector",
+			Constraint: `
+{
+	"apiVersion": "constraints.gatekeeper.sh/v1beta1",
+	"kind": "K8sAllowedRepos",
+	"metadata": {
+		"name": "prod-clusters-only"
+	},
+	"spec": {
+		"match": {
+			"clusterSelector": {
+				"matchExpressions": [{
+					"key": "env",
+					"operator": "Blah",
+					"values": ["prod"]
+				}]
+			}
+		},
+		"parameters": {
+			"repos": ["openpolicyagent"]
+		}
+	}
+}
+`,
+			ErrorExpected: true,
+		},
+		{
+			Name: "Valid ClusterSelector",
+			Constraint: `
+{
+	"apiVersion": "constraints.gatekeeper.sh/v1beta1",
+	"kind": "K8sAllowedRepos",
+	"metadata": {
+		"name": "prod-clusters-only"
+	},
+	"spec": {
+		"match": {
+			"clusterSelector": {
+				"matchLabels": {"env": "prod"}
+			}
+		},
+		"parameters": {
+			"repos": ["openpolicyagent"]
+		}
+	}
+}
+`,
+			ErrorExpected: false,
+		},
 		{
 			Name: "Valid EnforcementAction",
 			Constraint: `
@@ -327,6 +379,32 @@ func TestHandleViolation(t *testing.T) {
 	"metadata": {"name": "somename"},
 	"spec": {"value": "yep"}
 }
+`,
+		},
+		{
+			Name: "Valid Review (Numbers)",
+			Review: `
+{
+	"kind": {
+		"group": "apps",
+		"version": "v1",
+		"kind": "Deployment"
+	},
+	"name": "somename",
+	"operation": "CREATE",
+	"object": {
+		"metadata": {"name": "somename"},
+		"spec": {"replicas": 3, "ratio": 0.5, "ports": [80, 443.5]}
+	}
+}
+`,
+			ExpectedObj: `
+{
+	"apiVersion": "apps/v1",
+	"kind": "Deployment",
+	"metadata": {"name": "somename"},
+	"spec": {"replicas": 3, "ratio": 0.5, "ports": [80, 443.5]}
+}
 `,
 		},
 		{
@@ -430,3 +508,112 @@ func TestProcessData(t *testing.T) {
 		})
 	}
 }
+
+func reviewTestObject(containers int) *unstructured.Unstructured {
+	var cs []interface{}
+	for i := 0; i < containers; i++ {
+		cs = append(cs, map[string]interface{}{
+			"name":  fmt.Sprintf("container-%d", i),
+			"image": "nginx:1.21",
+			"ports": []interface{}{map[string]interface{}{"containerPort": int64(8080 + i)}},
+		})
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"containers": cs},
+	}}
+	u.SetAPIVersion("v1")
+	u.SetKind("Pod")
+	u.SetName("foo")
+	u.SetNamespace("bar")
+	return u
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+	var got interface{}
+	if err := json.Unmarshal([]byte(`{"a": 1, "b": [2.5, -3, 1e300], "c": {"d": 0}, "e": "4"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	got = normalizeNumbers(got)
+	want := map[string]interface{}{
+		"a": int64(1),
+		"b": []interface{}{2.5, int64(-3), 1e300},
+		"c": map[string]interface{}{"d": int64(0)},
+		"e": "4",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOperationReview(t *testing.T) {
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetName("foo")
+	pod.SetNamespace("bar")
+	ns := &unstructured.Unstructured{}
+	ns.SetAPIVersion("v1")
+	ns.SetKind("Namespace")
+	ns.SetName("bar")
+
+	tcs := []struct {
+		name          string
+		obj           *unstructured.Unstructured
+		operation     admissionv1.Operation
+		wantNamespace string
+		wantOldObject bool
+	}{
+		{name: "create pod", obj: pod, operation: admissionv1.Create, wantNamespace: "bar"},
+		{name: "delete pod", obj: pod, operation: admissionv1.Delete, wantNamespace: "bar", wantOldObject: true},
+		{name: "delete namespace", obj: ns, operation: admissionv1.Delete, wantOldObject: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			review, err := OperationReview(tc.obj, tc.operation, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := review.AdmissionRequest
+			if req.Operation != tc.operation {
+				t.Errorf("got operation %q, want %q", req.Operation, tc.operation)
+			}
+			if req.Namespace != tc.wantNamespace {
+				t.Errorf("got namespace %q, want %q", req.Namespace, tc.wantNamespace)
+			}
+			if req.Name != tc.obj.GetName() || req.Kind.Kind != tc.obj.GetKind() {
+				t.Errorf("got %v %q, want %v %q", req.Kind, req.Name, tc.obj.GetKind(), tc.obj.GetName())
+			}
+			if got := req.OldObject.Raw != nil; got != tc.wantOldObject {
+				t.Errorf("got oldObject %v, want %v", got, tc.wantOldObject)
+			}
+			if tc.wantOldObject && string(req.OldObject.Raw) != string(req.Object.Raw) {
+				t.Errorf("got oldObject %s, want the object %s", req.OldObject.Raw, req.Object.Raw)
+			}
+		})
+	}
+}
+
+func BenchmarkHandleViolation(b *testing.B) {
+	review := map[string]interface{}{
+		"kind":   map[string]interface{}{"group": "", "version": "v1", "kind": "Pod"},
+		"object": reviewTestObject(50).Object,
+	}
+	js, err := json.Marshal(review)
+	if err != nil {
+		b.Fatal(err)
+	}
+	h := &K8sValidationTarget{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// The framework decodes every result from JSON.
+		var r interface{}
+		if err := json.Unmarshal(js, &r); err != nil {
+			b.Fatal(err)
+		}
+		if err := h.HandleViolation(&types.Result{Review: r}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package ticketing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMapName is the name of the ConfigMap holding the resume tokens of
+// webhooks.
+const ConfigMapName = "gatekeeper-violation-tickets"
+
+// Store keeps the resume token of each webhook, keyed by a sink key derived
+// from its URL.
+type Store interface {
+	// Load returns the resume token of sink, or "" if it has none.
+	Load(ctx context.Context, sink string) (string, error)
+	// Save replaces the resume token of sink.
+	Save(ctx context.Context, sink, token string) error
+}
+
+// resumeToken records the violations delivered to a webhook.
+type resumeToken struct {
+	Delivered map[string]delivery `json:"delivered"`
+}
+
+type delivery struct {
+	Ticket    string    `json:"ticket,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// sinkKey returns the key of the resume token of the webhook at url. Tokens
+// are not shared between webhooks, so violations are posted to a new webhook
+// even if they were delivered to a previous one.
+func sinkKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "sink-" + hex.EncodeToString(sum[:8])
+}
+
+// restore loads the violations delivered to the webhook from the resume
+// token, once, returning whether they are known.
+func (n *Notifier) restore(ctx context.Context) bool {
+	if n.store == nil || n.restored {
+		return true
+	}
+	raw, err := n.store.Load(ctx, sinkKey(n.url))
+	if err != nil {
+		log.Error(err, "unable to load the violations delivered to the violation ticket webhook, not posting violations")
+		return false
+	}
+	token := resumeToken{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &token); err != nil {
+			// A corrupt token cannot be recovered by retrying.
+			log.Error(err, "ignoring invalid resume token of the violation ticket webhook")
+		}
+	}
+
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	for id, d := range token.Delivered {
+		// The violation was persistent when it was delivered.
+		n.tracked[id] = &tracked{firstSeen: d.FirstSeen, audits: n.minAudits, notified: true, ticket: d.Ticket}
+	}
+	n.restored = true
+	log.Info("restored the violations delivered to the violation ticket webhook", "count", len(token.Delivered))
+	return true
+}
+
+// save saves the violations delivered to the webhook in the resume token, if
+// they changed. Failures are retried by the next audit.
+func (n *Notifier) save(ctx context.Context) {
+	if n.store == nil {
+		return
+	}
+	n.mux.Lock()
+	if !n.dirty {
+		n.mux.Unlock()
+		return
+	}
+	token := resumeToken{Delivered: make(map[string]delivery)}
+	for id, t := range n.tracked {
+		if t.notified {
+			token.Delivered[id] = delivery{Ticket: t.ticket, FirstSeen: t.firstSeen}
+		}
+	}
+	n.dirty = false
+	n.mux.Unlock()
+
+	raw, err := json.Marshal(token)
+	if err == nil {
+		err = n.store.Save(ctx, sinkKey(n.url), string(raw))
+	}
+	if err != nil {
+		log.Error(err, "unable to save the violations delivered to the violation ticket webhook")
+		n.mux.Lock()
+		n.dirty = true
+		n.mux.Unlock()
+	}
+}
+
+// ConfigMapStore keeps resume tokens in the ConfigMap named ConfigMapName.
+type ConfigMapStore struct {
+	client client.Client
+	reader client.Reader
+	key    types.NamespacedName
+}
+
+var _ Store = &ConfigMapStore{}
+
+// NewConfigMapStore returns a Store keeping resume tokens in a ConfigMap in
+// namespace. Reads are made with reader so ConfigMaps do not need to be
+// cached.
+func NewConfigMapStore(c client.Client, reader client.Reader, namespace string) *ConfigMapStore {
+	return &ConfigMapStore{
+		client: c,
+		reader: reader,
+		key:    types.NamespacedName{Namespace: namespace, Name: ConfigMapName},
+	}
+}
+
+// +kubebuilder:rbac:groups="",namespace=gatekeeper-system,resources=configmaps,verbs=get;create;update
+
+func (s *ConfigMapStore) Load(ctx context.Context, sink string) (string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.reader.Get(ctx, s.key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return cm.Data[sink], nil
+}
+
+func (s *ConfigMapStore) Save(ctx context.Context, sink, token string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.reader.Get(ctx, s.key, cm)
+		create := apierrors.IsNotFound(err)
+		if err != nil && !create {
+			return err
+		}
+		if create {
+			cm = &corev1.ConfigMap{}
+			cm.SetNamespace(s.key.Namespace)
+			cm.SetName(s.key.Name)
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[sink] = token
+
+		if create {
+			err := s.client.Create(ctx, cm)
+			if apierrors.IsAlreadyExists(err) {
+				// Retry as an update.
+				return apierrors.NewConflict(corev1.Resource("configmaps"), s.key.Name, err)
+			}
+			return err
+		}
+		return s.client.Update(ctx, cm)
+	})
+}
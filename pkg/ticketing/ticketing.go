@@ -0,0 +1,254 @@
+// Package ticketing files tickets for violations which persist across audits.
+//
+// Audit reports each violation it records in the status of a constraint to a
+// Notifier. Once a violation has been seen by enough consecutive audits, and
+// for long enough, the Notifier posts it to an outbound webhook which can
+// create a ticket in a system such as Jira or ServiceNow. The ID of the ticket
+// returned by the webhook is recorded alongside the violation in the status of
+// the constraint.
+//
+// Each violation is posted once. The violations delivered to the webhook are
+// recorded in a resume token kept in a Store, so a restarted Gatekeeper does
+// not post them again.
+package ticketing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	defaultMinAudits = 3
+	webhookTimeout   = 10 * time.Second
+	// maxResponseSize bounds how much of a webhook response is read.
+	maxResponseSize = 1 << 20
+)
+
+var (
+	webhookURL = flag.String("violation-ticket-url", "", "(alpha) URL of a webhook which is posted violations that persist across audits, so a ticket can be filed for them. The ticketID returned by the webhook is recorded on the violation in the constraint status. Disabled if empty")
+	minAudits  = flag.Int("violation-ticket-min-audits", defaultMinAudits, "(alpha) number of consecutive audits which must report a violation before it is posted to --violation-ticket-url")
+	minAge     = flag.Duration("violation-ticket-min-age", 0, "(alpha) how long a violation must have been reported by consecutive audits before it is posted to --violation-ticket-url, for example `1h`")
+
+	log = logf.Log.WithName("ticketing")
+)
+
+// Violation is the payload posted to the webhook for a persistent violation.
+type Violation struct {
+	// ID identifies the violation of a constraint by an object, and is the same
+	// for every audit and every Gatekeeper pod.
+	ID                string    `json:"id"`
+	Constraint        Reference `json:"constraint"`
+	Resource          Reference `json:"resource"`
+	EnforcementAction string    `json:"enforcementAction"`
+	Severity          string    `json:"severity,omitempty"`
+	Message           string    `json:"message"`
+	// FirstSeen is when the first of the consecutive audits reporting the
+	// violation ran.
+	FirstSeen time.Time `json:"firstSeen"`
+	// AuditTimestamp is when the audit reporting the violation started.
+	AuditTimestamp string `json:"auditTimestamp"`
+}
+
+// Reference refers to a constraint or a violating object.
+type Reference struct {
+	// Group is the API group of a violating object, empty for the core group
+	// and for constraints.
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// response is the body the webhook may return. A webhook which does not
+// return a ticketID is not retried, but nothing is recorded on the violation.
+type response struct {
+	TicketID string `json:"ticketID"`
+}
+
+// ID returns the ID of the violation of constraint by resource with message.
+// The version of resource is not part of the ID, so a violation keeps its ID
+// when the API version the object is listed with changes.
+func ID(constraint, resource Reference, message string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		constraint.Kind, constraint.Namespace, constraint.Name,
+		resource.Group, resource.Kind, resource.Namespace, resource.Name,
+		message,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+type tracked struct {
+	firstSeen time.Time
+	audits    int
+	// notified is true once the webhook accepted the violation.
+	notified bool
+	ticket   string
+}
+
+// Notifier tracks violations across audits and posts the persistent ones to
+// a webhook.
+type Notifier struct {
+	url       string
+	client    *http.Client
+	minAudits int
+	minAge    time.Duration
+	now       func() time.Time
+
+	// store keeps the resume token of the webhook, if set.
+	store Store
+	// restored is whether the resume token was loaded from store, and dirty
+	// whether the delivered violations changed since it was last saved.
+	restored bool
+	dirty    bool
+
+	mux     sync.Mutex
+	tracked map[string]*tracked
+}
+
+// New returns a Notifier configured by flags, keeping its resume token in
+// store, or nil if no webhook is set.
+func New(store Store) *Notifier {
+	if *webhookURL == "" {
+		return nil
+	}
+	n := NewNotifier(*webhookURL, *minAudits, *minAge)
+	n.store = store
+	return n
+}
+
+// NewNotifier returns a Notifier posting violations reported by minAudits
+// consecutive audits over at least minAge to url.
+func NewNotifier(url string, minAudits int, minAge time.Duration) *Notifier {
+	return &Notifier{
+		url:       url,
+		client:    &http.Client{Timeout: webhookTimeout},
+		minAudits: minAudits,
+		minAge:    minAge,
+		now:       time.Now,
+		tracked:   make(map[string]*tracked),
+	}
+}
+
+// Observe records the violations reported by an audit, forgetting those it
+// did not report, and posts those which have become persistent to the
+// webhook. Violations which fail to post are retried by the next audit.
+func (n *Notifier) Observe(ctx context.Context, violations []*Violation) {
+	if !n.restore(ctx) {
+		// Posting without knowing what was delivered before would repost
+		// every persistent violation.
+		return
+	}
+	defer n.save(ctx)
+
+	now := n.now()
+	var persistent []*Violation
+
+	n.mux.Lock()
+	seen := make(map[string]bool, len(violations))
+	for _, v := range violations {
+		seen[v.ID] = true
+		t, ok := n.tracked[v.ID]
+		if !ok {
+			t = &tracked{firstSeen: now}
+			n.tracked[v.ID] = t
+		}
+		t.audits++
+		v.FirstSeen = t.firstSeen
+		if !t.notified && t.audits >= n.minAudits && now.Sub(t.firstSeen) >= n.minAge {
+			persistent = append(persistent, v)
+		}
+	}
+	for id, t := range n.tracked {
+		if !seen[id] {
+			delete(n.tracked, id)
+			n.dirty = n.dirty || t.notified
+		}
+	}
+	n.mux.Unlock()
+
+	for _, v := range persistent {
+		if ctx.Err() != nil {
+			return
+		}
+		ticket, err := n.post(ctx, v)
+		if err != nil {
+			log.Error(err, "unable to post violation", "violationID", v.ID, "constraintKind", v.Constraint.Kind, "constraintName", v.Constraint.Name)
+			continue
+		}
+		n.mux.Lock()
+		if t, ok := n.tracked[v.ID]; ok {
+			t.notified = true
+			t.ticket = ticket
+			n.dirty = true
+		}
+		n.mux.Unlock()
+	}
+}
+
+// Ticket returns the ID of the ticket filed for the violation with id, if any.
+func (n *Notifier) Ticket(id string) string {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	if t, ok := n.tracked[id]; ok {
+		return t.ticket
+	}
+	return ""
+}
+
+// Record records that ticket was filed for the violation with id, for example
+// by a previous Gatekeeper pod, so it is not posted again.
+func (n *Notifier) Record(id, ticket string) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	if t, ok := n.tracked[id]; ok && t.ticket == "" {
+		t.notified = true
+		t.ticket = ticket
+		n.dirty = true
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, v *Violation) (string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The ID is the same whenever the violation is posted, so webhooks can
+	// discard duplicates.
+	req.Header.Set("Idempotency-Key", v.ID)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("violation ticket webhook %s returned %s", n.url, resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", err
+	}
+	r := response{}
+	if len(bytes.TrimSpace(raw)) != 0 {
+		if err := json.Unmarshal(raw, &r); err != nil {
+			log.Error(err, "unable to decode violation ticket webhook response", "violationID", v.ID)
+		}
+	}
+	return r.TicketID, nil
+}
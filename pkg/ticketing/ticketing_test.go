@@ -0,0 +1,237 @@
+package ticketing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	requiredLabels = Reference{Kind: "K8sRequiredLabels", Name: "must-have-owner"}
+	podA           = Reference{Kind: "Pod", Namespace: "ns1", Name: "a"}
+	podB           = Reference{Kind: "Pod", Namespace: "ns1", Name: "b"}
+	message        = `you must provide labels: {"owner"}`
+)
+
+type webhook struct {
+	mux      sync.Mutex
+	received []Violation
+	keys     []string
+	status   int
+	body     string
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	v := Violation{}
+	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.received = append(w.received, v)
+	w.keys = append(w.keys, r.Header.Get("Idempotency-Key"))
+	if w.status != 0 {
+		rw.WriteHeader(w.status)
+	}
+	_, _ = rw.Write([]byte(w.body))
+}
+
+func (w *webhook) ids() []string {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	var ids []string
+	for _, v := range w.received {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+func violation(resource Reference) *Violation {
+	return &Violation{Message: message, ID: ID(requiredLabels, resource, message), Constraint: requiredLabels, Resource: resource}
+}
+
+func TestID(t *testing.T) {
+	if ID(requiredLabels, podA, message) != ID(requiredLabels, podA, message) {
+		t.Error("got different IDs for the same violation")
+	}
+	if ID(requiredLabels, podA, message) == ID(requiredLabels, podB, message) {
+		t.Error("got the same ID for violations of different objects")
+	}
+	// Fields are delimited, so moving characters between them changes the ID.
+	if ID(Reference{Kind: "K", Name: "ab"}, podA, message) == ID(Reference{Kind: "Ka", Name: "b"}, podA, message) {
+		t.Error("got the same ID for violations of different constraints")
+	}
+	if ID(requiredLabels, podA, message) == ID(requiredLabels, podA, "you must provide labels: {\"team\"}") {
+		t.Error("got the same ID for violations with different messages")
+	}
+	deployment := Reference{Group: "apps", Kind: "Deployment", Namespace: "ns1", Name: "a"}
+	if ID(requiredLabels, deployment, message) == ID(requiredLabels, Reference{Group: "extensions", Kind: "Deployment", Namespace: "ns1", Name: "a"}, message) {
+		t.Error("got the same ID for violations of objects in different groups")
+	}
+}
+
+func TestObserve(t *testing.T) {
+	wh := &webhook{body: `{"ticketID": "PLAT-1"}`}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	now := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	n := NewNotifier(srv.URL, 2, time.Hour)
+	n.now = func() time.Time { return now }
+	ctx := context.Background()
+	idA := ID(requiredLabels, podA, message)
+
+	n.Observe(ctx, []*Violation{violation(podA)})
+	if got := wh.ids(); len(got) != 0 {
+		t.Fatalf("got %d violations posted after one audit, want 0", len(got))
+	}
+
+	// Seen by enough audits, but not for long enough.
+	now = now.Add(30 * time.Minute)
+	n.Observe(ctx, []*Violation{violation(podA)})
+	if got := wh.ids(); len(got) != 0 {
+		t.Fatalf("got %d violations posted before the minimum age, want 0", len(got))
+	}
+
+	now = now.Add(30 * time.Minute)
+	v := violation(podA)
+	n.Observe(ctx, []*Violation{v, violation(podB)})
+	if got := wh.ids(); len(got) != 1 || got[0] != idA {
+		t.Fatalf("got violations %v posted, want only %s", got, idA)
+	}
+	if want := now.Add(-time.Hour); !v.FirstSeen.Equal(want) {
+		t.Errorf("got firstSeen %v, want %v", v.FirstSeen, want)
+	}
+	if got := n.Ticket(idA); got != "PLAT-1" {
+		t.Errorf("got ticket %q, want %q", got, "PLAT-1")
+	}
+
+	// A violation is posted only once.
+	now = now.Add(time.Hour)
+	n.Observe(ctx, []*Violation{violation(podA)})
+	if got := wh.ids(); len(got) != 1 {
+		t.Errorf("got %d violations posted, want 1", len(got))
+	}
+
+	// podB was not reported by the last audit, so it is tracked afresh.
+	n.Observe(ctx, []*Violation{violation(podA), violation(podB)})
+	if got := wh.ids(); len(got) != 1 {
+		t.Errorf("got %d violations posted, want 1", len(got))
+	}
+}
+
+func TestObserveRetriesFailures(t *testing.T) {
+	wh := &webhook{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	n := NewNotifier(srv.URL, 1, 0)
+	ctx := context.Background()
+	n.Observe(ctx, []*Violation{violation(podA)})
+
+	wh.mux.Lock()
+	wh.status = http.StatusOK
+	wh.mux.Unlock()
+	n.Observe(ctx, []*Violation{violation(podA)})
+	n.Observe(ctx, []*Violation{violation(podA)})
+
+	if got := wh.ids(); len(got) != 2 {
+		t.Errorf("got %d posts, want 2: the failure and its retry", len(got))
+	}
+	if got := n.Ticket(ID(requiredLabels, podA, message)); got != "" {
+		t.Errorf("got ticket %q from an empty response, want none", got)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	wh := &webhook{}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	n := NewNotifier(srv.URL, 2, 0)
+	ctx := context.Background()
+	id := ID(requiredLabels, podA, message)
+	n.Observe(ctx, []*Violation{violation(podA)})
+	n.Record(id, "PLAT-2")
+	n.Observe(ctx, []*Violation{violation(podA)})
+
+	if got := wh.ids(); len(got) != 0 {
+		t.Errorf("got %d violations posted, want 0 as a ticket was recorded", len(got))
+	}
+	if got := n.Ticket(id); got != "PLAT-2" {
+		t.Errorf("got ticket %q, want %q", got, "PLAT-2")
+	}
+}
+
+// memoryStore is a Store keeping resume tokens in memory.
+type memoryStore struct {
+	tokens map[string]string
+	err    error
+}
+
+func (s *memoryStore) Load(_ context.Context, sink string) (string, error) {
+	return s.tokens[sink], s.err
+}
+
+func (s *memoryStore) Save(_ context.Context, sink, token string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.tokens[sink] = token
+	return nil
+}
+
+func TestObserveResumes(t *testing.T) {
+	wh := &webhook{body: `{"ticketID": "PLAT-3"}`}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	store := &memoryStore{tokens: make(map[string]string)}
+	ctx := context.Background()
+	id := ID(requiredLabels, podA, message)
+
+	n := NewNotifier(srv.URL, 1, 0)
+	n.store = store
+	n.Observe(ctx, []*Violation{violation(podA)})
+	wh.mux.Lock()
+	keys := wh.keys
+	wh.mux.Unlock()
+	if len(keys) != 1 || keys[0] != id {
+		t.Fatalf("got Idempotency-Key headers %v, want %s", keys, id)
+	}
+
+	// A restarted Notifier does not post the violation again, and knows its
+	// ticket.
+	restarted := NewNotifier(srv.URL, 1, 0)
+	restarted.store = store
+	restarted.Observe(ctx, []*Violation{violation(podA)})
+	if got := wh.ids(); len(got) != 1 {
+		t.Errorf("got %d violations posted after a restart, want 1", len(got))
+	}
+	if got := restarted.Ticket(id); got != "PLAT-3" {
+		t.Errorf("got ticket %q after a restart, want %q", got, "PLAT-3")
+	}
+
+	// A resolved violation is forgotten, so it is posted again if it recurs.
+	restarted.Observe(ctx, nil)
+	again := NewNotifier(srv.URL, 1, 0)
+	again.store = store
+	again.Observe(ctx, []*Violation{violation(podA)})
+	if got := wh.ids(); len(got) != 2 {
+		t.Errorf("got %d violations posted, want 2 as the violation recurred", len(got))
+	}
+
+	// Nothing is posted while the delivered violations cannot be loaded.
+	failing := NewNotifier(srv.URL, 1, 0)
+	failing.store = &memoryStore{err: errors.New("unavailable")}
+	failing.Observe(ctx, []*Violation{violation(podB)})
+	if got := wh.ids(); len(got) != 2 {
+		t.Errorf("got %d violations posted, want 2 as the resume token is unavailable", len(got))
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"errors"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// ErrSpecNotObject indicates the spec of a constraint CRD has no object
+// schema.
+var ErrSpecNotObject = errors.New("spec of constraint CRD is not an object schema")
+
+// AddSpecProperty adds the property name with schema to the spec of every
+// version of the constraint CRD crd.
+func AddSpecProperty(crd *apiextensionsv1.CustomResourceDefinition, name string, schema apiextensionsv1.JSONSchemaProps) error {
+	for i := range crd.Spec.Versions {
+		v := &crd.Spec.Versions[i]
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		spec, ok := v.Schema.OpenAPIV3Schema.Properties["spec"]
+		if !ok || spec.Type != "object" {
+			return ErrSpecNotObject
+		}
+		if spec.Properties == nil {
+			spec.Properties = make(map[string]apiextensionsv1.JSONSchemaProps)
+		}
+		spec.Properties[name] = schema
+		v.Schema.OpenAPIV3Schema.Properties["spec"] = spec
+	}
+	return nil
+}
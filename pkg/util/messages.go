@@ -0,0 +1,26 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/frameworks/constraint/pkg/types"
+)
+
+// ViolationMessage formats msg, the message of a violation of the named
+// constraint, as the webhook reports it to users.
+func ViolationMessage(constraint, msg string) string {
+	return fmt.Sprintf("[%s] %s", constraint, msg)
+}
+
+// DenyMessage returns the message the webhook denies a request with if it
+// has results, or "" if none of them deny it.
+func DenyMessage(results []*types.Result) string {
+	var msgs []string
+	for _, r := range results {
+		if r.EnforcementAction == string(Deny) {
+			msgs = append(msgs, ViolationMessage(r.Constraint.GetName(), r.Msg))
+		}
+	}
+	return strings.Join(msgs, "\n")
+}
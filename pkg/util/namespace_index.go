@@ -0,0 +1,117 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NamespaceIndex matches namespaces against a list of namespace patterns,
+// such as excluded namespaces, without comparing a namespace with every
+// pattern. A pattern without "*" matches the namespace of that name, a
+// pattern ending in its only "*" matches the namespaces with that prefix,
+// and any other "*" matches any sequence of characters.
+//
+// A nil NamespaceIndex matches no namespace. NamespaceIndexes are immutable,
+// so they can be shared by concurrent callers.
+type NamespaceIndex struct {
+	exact    map[string]bool
+	prefixes *prefixTrie
+	globs    []*regexp.Regexp
+}
+
+// NewNamespaceIndex returns a NamespaceIndex of patterns.
+func NewNamespaceIndex(patterns []string) *NamespaceIndex {
+	idx := &NamespaceIndex{exact: make(map[string]bool)}
+	for _, p := range patterns {
+		switch n := strings.Count(p, "*"); {
+		case n == 0:
+			idx.exact[p] = true
+		case n == 1 && strings.HasSuffix(p, "*"):
+			if idx.prefixes == nil {
+				idx.prefixes = &prefixTrie{}
+			}
+			idx.prefixes.add(strings.TrimSuffix(p, "*"))
+		default:
+			idx.globs = append(idx.globs, globRegexp(p))
+		}
+	}
+	return idx
+}
+
+// Matches returns true if any pattern of idx matches namespace.
+func (idx *NamespaceIndex) Matches(namespace string) bool {
+	if idx == nil {
+		return false
+	}
+	if idx.exact[namespace] || idx.prefixes.hasPrefixOf(namespace) {
+		return true
+	}
+	for _, re := range idx.globs {
+		if re.MatchString(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// NamespacePatternMatches returns true if pattern matches namespace, as a
+// NamespaceIndex of pattern would.
+func NamespacePatternMatches(pattern, namespace string) bool {
+	switch n := strings.Count(pattern, "*"); {
+	case n == 0:
+		return pattern == namespace
+	case n == 1 && strings.HasSuffix(pattern, "*"):
+		return strings.HasPrefix(namespace, strings.TrimSuffix(pattern, "*"))
+	default:
+		return globRegexp(pattern).MatchString(namespace)
+	}
+}
+
+// globRegexp returns the regular expression matching the namespaces matched
+// by pattern, in which each "*" matches any sequence of characters.
+func globRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// prefixTrie holds prefixes by character, so the prefixes of a string are
+// found in a single pass over it.
+type prefixTrie struct {
+	// terminal is whether a prefix ends at this node.
+	terminal bool
+	children map[byte]*prefixTrie
+}
+
+func (t *prefixTrie) add(prefix string) {
+	node := t
+	for i := 0; i < len(prefix); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*prefixTrie)
+		}
+		child, ok := node.children[prefix[i]]
+		if !ok {
+			child = &prefixTrie{}
+			node.children[prefix[i]] = child
+		}
+		node = child
+	}
+	node.terminal = true
+}
+
+// hasPrefixOf returns true if any prefix of t is a prefix of s.
+func (t *prefixTrie) hasPrefixOf(s string) bool {
+	node := t
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == len(s) {
+			return false
+		}
+		node = node.children[s[i]]
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package util
+
+import "testing"
+
+func TestNamespaceIndex(t *testing.T) {
+	idx := NewNamespaceIndex([]string{"kube-system", "team-*", "*-sandbox", "a*b*c", "x"})
+	tcs := []struct {
+		namespace string
+		want      bool
+	}{
+		{namespace: "kube-system", want: true},
+		{namespace: "kube-public", want: false},
+		{namespace: "team-", want: true},
+		{namespace: "team-a", want: true},
+		{namespace: "team", want: false},
+		{namespace: "dev-sandbox", want: true},
+		{namespace: "dev-sandbox-2", want: false},
+		{namespace: "abc", want: true},
+		{namespace: "a-b-c", want: true},
+		{namespace: "a-b-d", want: false},
+		{namespace: "x", want: true},
+		{namespace: "xy", want: false},
+		{namespace: "", want: false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.namespace, func(t *testing.T) {
+			if got := idx.Matches(tc.namespace); got != tc.want {
+				t.Errorf("got Matches(%q) = %v, want %v", tc.namespace, got, tc.want)
+			}
+			var patterns []string
+			for _, p := range []string{"kube-system", "team-*", "*-sandbox", "a*b*c", "x"} {
+				if NamespacePatternMatches(p, tc.namespace) {
+					patterns = append(patterns, p)
+				}
+			}
+			if got := len(patterns) > 0; got != tc.want {
+				t.Errorf("got NamespacePatternMatches of %q = %v, want %v", tc.namespace, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceIndex_MatchAll(t *testing.T) {
+	idx := NewNamespaceIndex([]string{"*"})
+	for _, ns := range []string{"", "default"} {
+		if !idx.Matches(ns) {
+			t.Errorf("got %q not matched by \"*\"", ns)
+		}
+	}
+}
+
+func TestNamespaceIndex_Nil(t *testing.T) {
+	var idx *NamespaceIndex
+	if idx.Matches("default") {
+		t.Error("got a nil index matching a namespace")
+	}
+	if NewNamespaceIndex(nil).Matches("") {
+		t.Error("got an empty index matching a namespace")
+	}
+}
@@ -2,6 +2,7 @@ package webhook
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -14,8 +15,9 @@ import (
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -46,6 +48,8 @@ var (
 const (
 	serviceAccountName = "gatekeeper-admin"
 	mutationsGroup     = "mutations.gatekeeper.sh"
+	expansionGroup     = "expansion.gatekeeper.sh"
+	exceptionsGroup    = "exceptions.gatekeeper.sh"
 	namespaceKind      = "Namespace"
 )
 
@@ -97,6 +101,8 @@ func (h *webhookHandler) isGatekeeperResource(req *admission.Request) bool {
 	if req.AdmissionRequest.Kind.Group == "templates.gatekeeper.sh" ||
 		req.AdmissionRequest.Kind.Group == "constraints.gatekeeper.sh" ||
 		req.AdmissionRequest.Kind.Group == mutationsGroup ||
+		req.AdmissionRequest.Kind.Group == expansionGroup ||
+		req.AdmissionRequest.Kind.Group == exceptionsGroup ||
 		req.AdmissionRequest.Kind.Group == "config.gatekeeper.sh" ||
 		req.AdmissionRequest.Kind.Group == "status.gatekeeper.sh" {
 		return true
@@ -129,8 +135,25 @@ func (h *webhookHandler) tracingLevel(ctx context.Context, req *admission.Reques
 }
 
 func (h *webhookHandler) skipExcludedNamespace(req *admissionv1.AdmissionRequest, excludedProcess process.Process) (bool, error) {
-	obj := &unstructured.Unstructured{}
-	if _, _, err := deserializer.Decode(req.Object.Raw, nil, obj); err != nil {
+	// Gatekeeper's own resources, including its cluster-scoped CRDs, are skipped.
+	if process.IsSelf(excludedProcess, schema.GroupKind{Group: req.Kind.Group, Kind: req.Kind.Kind}, req.Namespace, req.Name) {
+		return true, nil
+	}
+
+	// The namespace of a request is known without decoding its object, unless
+	// it creates a Namespace whose name is generated.
+	isNamespace := req.Kind.Kind == namespaceKind && req.Kind.Group == ""
+	if !isNamespace {
+		return h.processExcluder.IsExcluded(excludedProcess, req.Namespace), nil
+	}
+	if req.Name != "" {
+		return h.processExcluder.IsExcluded(excludedProcess, req.Name), nil
+	}
+
+	// Only the type and metadata of the object are needed, so the rest of it
+	// is skipped rather than decoded.
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
 		return false, err
 	}
 
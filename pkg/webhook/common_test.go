@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func excludingHandler() *webhookHandler {
+	excluder := process.New()
+	excluder.Add([]v1alpha1.MatchEntry{{
+		Processes:          []string{string(process.Webhook)},
+		ExcludedNamespaces: []util.PrefixWildcard{"kube-*"},
+	}})
+	return &webhookHandler{processExcluder: excluder}
+}
+
+// rawRequest returns a request for obj, with the kind, namespace and name
+// set by the API server.
+func rawRequest(t testing.TB, obj runtime.Object) *admissionv1.AdmissionRequest {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	req := &admissionv1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+		Object: runtime.RawExtension{Raw: raw},
+		Name:   accessor.GetName(),
+	}
+	if gvk.Kind != namespaceKind {
+		req.Namespace = accessor.GetNamespace()
+	}
+	return req
+}
+
+func TestSkipExcludedNamespace(t *testing.T) {
+	pod := func(ns string) *corev1.Pod {
+		return &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: ns},
+		}
+	}
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+		}
+	}
+
+	tcs := []struct {
+		name string
+		obj  runtime.Object
+		want bool
+	}{
+		{name: "object in excluded namespace", obj: pod("kube-system"), want: true},
+		{name: "object in other namespace", obj: pod("default"), want: false},
+		{name: "excluded namespace", obj: namespace("kube-public"), want: true},
+		{name: "other namespace", obj: namespace("default"), want: false},
+	}
+	h := excludingHandler()
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := h.skipExcludedNamespace(rawRequest(t, tc.obj), process.Webhook)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got excluded %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// The name of a Namespace whose name is generated is read from the
+	// object.
+	generated := rawRequest(t, namespace("kube-generated"))
+	generated.Name = ""
+	got, err := h.skipExcludedNamespace(generated, process.Webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Error("got Namespace with a generated name not excluded")
+	}
+
+	// Other requests are not decoded.
+	notDecoded := rawRequest(t, pod("kube-system"))
+	notDecoded.Object.Raw = []byte("not json")
+	got, err = h.skipExcludedNamespace(notDecoded, process.Webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Error("got object in excluded namespace not excluded")
+	}
+
+	namespaceRequest := &admissionv1.AdmissionRequest{Kind: metav1.GroupVersionKind{Version: "v1", Kind: namespaceKind}}
+	if _, err := h.skipExcludedNamespace(namespaceRequest, process.Webhook); err == nil {
+		t.Error("got no error for a Namespace request without a name or an object")
+	}
+}
+
+func BenchmarkSkipExcludedNamespace(b *testing.B) {
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+	}
+	for i := 0; i < 20; i++ {
+		c := corev1.Container{Name: fmt.Sprintf("container-%d", i), Image: "nginx:1.21"}
+		for j := 0; j < 50; j++ {
+			c.Env = append(c.Env, corev1.EnvVar{Name: fmt.Sprintf("VAR_%d", j), Value: "value"})
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, c)
+	}
+	req := rawRequest(b, pod)
+	h := excludingHandler()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.skipExcludedNamespace(req, process.Webhook); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	rtypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
+	"github.com/open-policy-agent/gatekeeper/pkg/util"
+)
+
+var dryrunBaselineWindow = flag.Duration("dryrun-baseline-window", 0, "(alpha) the window over which the validation webhook reports the share of reviewed requests each dryrun constraint would have denied, as the validation_dryrun_denial_ratio metric. 0 disables the metric")
+
+// dryrunBaselineBuckets is the number of buckets a dryrunBaseline window is
+// split into. The window slides by a bucket at a time.
+const dryrunBaselineBuckets = 12
+
+// dryrunBaseline tallies the requests reviewed by the validation webhook, and
+// those each dryrun constraint would have denied, over a sliding window. It
+// answers what share of recent requests a constraint would deny before its
+// enforcement action is changed to deny.
+type dryrunBaseline struct {
+	bucket time.Duration
+	now    func() time.Time
+
+	mux sync.Mutex
+	// buckets is a ring of the completed buckets of the window, followed by
+	// the current bucket.
+	buckets [dryrunBaselineBuckets + 1]baselineBucket
+	current int
+	// start is when the current bucket started.
+	start time.Time
+	// reported are the constraints whose ratio was last reported above 0.
+	reported map[baselineKey]bool
+}
+
+type baselineBucket struct {
+	requests int64
+	denials  map[baselineKey]int64
+}
+
+// baselineKey identifies a constraint by its kind and the value of its
+// constraint_name tag, which is empty unless constraint names are recorded.
+type baselineKey struct {
+	kind string
+	name string
+}
+
+func newDryrunBaseline(window time.Duration) *dryrunBaseline {
+	return &dryrunBaseline{
+		bucket:   window / dryrunBaselineBuckets,
+		now:      time.Now,
+		reported: make(map[baselineKey]bool),
+	}
+}
+
+// dryrunKeys returns the constraints with the dryrun enforcement action
+// which produced results for a request, each once.
+func dryrunKeys(res []*rtypes.Result) map[baselineKey]bool {
+	keys := make(map[baselineKey]bool)
+	for _, r := range res {
+		if r.EnforcementAction != string(util.Dryrun) || r.Constraint == nil {
+			continue
+		}
+		name, _ := metrics.ConstraintNameTagValue(r.Constraint.GetName())
+		keys[baselineKey{kind: r.Constraint.GetKind(), name: name}] = true
+	}
+	return keys
+}
+
+// observe tallies a reviewed request which the constraints of denials would
+// have denied. When the window has slid since the previous request, returns
+// the ratio of requests in the window each constraint would have denied,
+// including a ratio of 0 for those it no longer has denials for.
+func (b *dryrunBaseline) observe(denials map[baselineKey]bool) map[baselineKey]float64 {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	var ratios map[baselineKey]float64
+	if b.slide() {
+		ratios = b.ratios()
+	}
+
+	current := &b.buckets[b.current]
+	current.requests++
+	for k := range denials {
+		if current.denials == nil {
+			current.denials = make(map[baselineKey]int64)
+		}
+		current.denials[k]++
+	}
+	return ratios
+}
+
+// slide starts a new current bucket for every bucket which has elapsed since
+// the current one started. Returns whether any has.
+func (b *dryrunBaseline) slide() bool {
+	now := b.now()
+	if b.start.IsZero() {
+		b.start = now
+		return false
+	}
+	elapsed := int(now.Sub(b.start) / b.bucket)
+	if elapsed <= 0 {
+		return false
+	}
+	b.start = b.start.Add(time.Duration(elapsed) * b.bucket)
+	for i := 0; i < elapsed && i < len(b.buckets); i++ {
+		b.current = (b.current + 1) % len(b.buckets)
+		b.buckets[b.current] = baselineBucket{}
+	}
+	return true
+}
+
+// ratios returns the ratio of requests each constraint would have denied in
+// the completed buckets.
+func (b *dryrunBaseline) ratios() map[baselineKey]float64 {
+	var requests int64
+	denials := make(map[baselineKey]int64)
+	for i := range b.buckets {
+		if i == b.current {
+			continue
+		}
+		requests += b.buckets[i].requests
+		for k, n := range b.buckets[i].denials {
+			denials[k] += n
+		}
+	}
+
+	ratios := make(map[baselineKey]float64)
+	for k := range b.reported {
+		if denials[k] == 0 {
+			ratios[k] = 0
+			delete(b.reported, k)
+		}
+	}
+	for k, n := range denials {
+		ratios[k] = float64(n) / float64(requests)
+		b.reported[k] = true
+	}
+	return ratios
+}
+
+// reportDryrunDenials counts the requests dryrun constraints would have
+// denied, and tallies them in the dryrun baseline if it is enabled.
+func (h *validationHandler) reportDryrunDenials(ctx context.Context, res []*rtypes.Result) {
+	if h.reporter == nil {
+		return
+	}
+	keys := dryrunKeys(res)
+	for k := range keys {
+		if err := h.reporter.ReportDryrunDenial(ctx, k.kind, k.name); err != nil {
+			log.Error(err, "failed to report dryrun denial")
+		}
+	}
+	if h.dryrunBaseline == nil {
+		return
+	}
+	for k, ratio := range h.dryrunBaseline.observe(keys) {
+		if err := h.reporter.ReportDryrunDenialRatio(ctx, k.kind, k.name, ratio); err != nil {
+			log.Error(err, "failed to report dryrun denial ratio")
+		}
+	}
+}
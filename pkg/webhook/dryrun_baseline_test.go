@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	rtypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func dryrunResult(kind, name, action string) *rtypes.Result {
+	constraint := &unstructured.Unstructured{}
+	constraint.SetKind(kind)
+	constraint.SetName(name)
+	return &rtypes.Result{Constraint: constraint, EnforcementAction: action}
+}
+
+func TestDryrunKeys(t *testing.T) {
+	got := dryrunKeys([]*rtypes.Result{
+		dryrunResult("K8sRequiredLabels", "a", "dryrun"),
+		dryrunResult("K8sRequiredLabels", "a", "dryrun"),
+		dryrunResult("K8sAllowedRepos", "b", "dryrun"),
+		dryrunResult("K8sAllowedRepos", "c", "deny"),
+		dryrunResult("K8sAllowedRepos", "d", "warn"),
+	})
+	// Constraint names are not recorded by default.
+	want := map[baselineKey]bool{
+		{kind: "K8sRequiredLabels"}: true,
+		{kind: "K8sAllowedRepos"}:   true,
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(baselineKey{})); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDryrunBaseline(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newDryrunBaseline(12 * time.Minute)
+	b.now = func() time.Time { return now }
+
+	labels := baselineKey{kind: "K8sRequiredLabels"}
+	repos := baselineKey{kind: "K8sAllowedRepos"}
+	opts := cmp.AllowUnexported(baselineKey{})
+
+	// Ratios are only reported once a bucket completes.
+	for i := 0; i < 4; i++ {
+		denials := map[baselineKey]bool{}
+		if i == 0 {
+			denials[labels] = true
+			denials[repos] = true
+		} else if i == 1 {
+			denials[labels] = true
+		}
+		if got := b.observe(denials); got != nil {
+			t.Fatalf("got ratios %v before the first bucket completed", got)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	got := b.observe(nil)
+	want := map[baselineKey]float64{labels: 0.5, repos: 0.25}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Error(diff)
+	}
+
+	// Within a bucket, nothing is reported.
+	now = now.Add(30 * time.Second)
+	if got := b.observe(map[baselineKey]bool{labels: true}); got != nil {
+		t.Errorf("got ratios %v within a bucket", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	got = b.observe(nil)
+	want = map[baselineKey]float64{labels: 3.0 / 6, repos: 1.0 / 6}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Error(diff)
+	}
+
+	// Once the window slides past the denials of repos, it is reported at 0
+	// once. The window now starts with the second bucket.
+	now = now.Add(11 * time.Minute)
+	got = b.observe(nil)
+	want = map[baselineKey]float64{labels: 1.0 / 3, repos: 0}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Error(diff)
+	}
+
+	now = now.Add(time.Hour)
+	got = b.observe(nil)
+	want = map[baselineKey]float64{labels: 0}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	opa "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/gatekeeper/apis"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -51,7 +52,7 @@ func init() {
 // TODO enable this once mutation is beta +kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch;update
 
 // AddMutatingWebhook registers the mutating webhook server with the manager.
-func AddMutatingWebhook(mgr manager.Manager, client *opa.Client, processExcluder *process.Excluder, mutationSystem *mutation.System) error {
+func AddMutatingWebhook(mgr manager.Manager, client *opa.Client, processExcluder *process.Excluder, mutationSystem *mutation.System, _ *expansion.System) error {
 	if !*mutation.MutationEnabled {
 		return nil
 	}
@@ -67,20 +68,22 @@ func AddMutatingWebhook(mgr manager.Manager, client *opa.Client, processExcluder
 		scheme.Scheme,
 		corev1.EventSource{Component: "gatekeeper-mutation-webhook"})
 
-	wh := &admission.Webhook{
-		Handler: &mutationHandler{
-			webhookHandler: webhookHandler{
-				client:          mgr.GetClient(),
-				reader:          mgr.GetAPIReader(),
-				reporter:        reporter,
-				processExcluder: processExcluder,
-				eventRecorder:   recorder,
-				gkNamespace:     util.GetNamespace(),
-			},
-			mutationSystem: mutationSystem,
-			deserializer:   codecs.UniversalDeserializer(),
+	handler := &mutationHandler{
+		webhookHandler: webhookHandler{
+			client:          mgr.GetClient(),
+			reader:          mgr.GetAPIReader(),
+			reporter:        reporter,
+			processExcluder: processExcluder,
+			eventRecorder:   recorder,
+			gkNamespace:     util.GetNamespace(),
 		},
+		mutationSystem: mutationSystem,
+		deserializer:   codecs.UniversalDeserializer(),
 	}
+	if *reinvocationWindow > 0 && *mutation.MutationAnnotationsEnabled {
+		handler.reinvocations = newReinvocationTracker(*reinvocationWindow)
+	}
+	wh := &admission.Webhook{Handler: handler}
 
 	// TODO(https://github.com/open-policy-agent/gatekeeper/issues/661): remove log injection if the race condition in the cited bug is eliminated.
 	// Otherwise we risk having unstable logger names for the webhook.
@@ -98,6 +101,9 @@ type mutationHandler struct {
 	webhookHandler
 	mutationSystem *mutation.System
 	deserializer   runtime.Decoder
+	// reinvocations detects mutations undone by webhooks invoked after this
+	// one, if set.
+	reinvocations *reinvocationTracker
 }
 
 // Handle the mutation request
@@ -183,6 +189,13 @@ func (h *mutationHandler) mutateRequest(ctx context.Context, req *admission.Requ
 		return admission.Errored(int32(http.StatusInternalServerError), err)
 	}
 
+	// The id of the mutation applied to obj before the request was
+	// reinvoked, read before obj is mutated again.
+	var previousID string
+	if h.reinvocations != nil {
+		previousID = reinvokedMutationID(req, &obj)
+	}
+
 	mutated, err := h.mutationSystem.Mutate(&obj, ns)
 	if err != nil {
 		log.Error(err, "failed to mutate object", "object", string(req.Object.Raw))
@@ -198,6 +211,9 @@ func (h *mutationHandler) mutateRequest(ctx context.Context, req *admission.Requ
 		return admission.Errored(int32(http.StatusInternalServerError), err)
 	}
 	resp := admission.PatchResponseFromRaw(req.Object.Raw, newJSON)
+	if h.reinvocations != nil {
+		h.trackReinvocation(ctx, previousID, &obj, &resp)
+	}
 	return resp
 }
 
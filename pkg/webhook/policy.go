@@ -28,14 +28,29 @@ import (
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	rtypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
 	"github.com/open-policy-agent/gatekeeper/apis"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
 	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/builtins"
 	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/decisionlog"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
 	"github.com/open-policy-agent/gatekeeper/pkg/keys"
 	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/matchedkinds"
+	"github.com/open-policy-agent/gatekeeper/pkg/metrics"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators/assign"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators/assignmeta"
 	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators/modifyset"
+	"github.com/open-policy-agent/gatekeeper/pkg/override"
+	"github.com/open-policy-agent/gatekeeper/pkg/prune"
+	"github.com/open-policy-agent/gatekeeper/pkg/querystats"
+	"github.com/open-policy-agent/gatekeeper/pkg/remediation"
+	"github.com/open-policy-agent/gatekeeper/pkg/schedule"
+	"github.com/open-policy-agent/gatekeeper/pkg/severity"
+	"github.com/open-policy-agent/gatekeeper/pkg/suppression"
 	"github.com/open-policy-agent/gatekeeper/pkg/target"
 	"github.com/open-policy-agent/gatekeeper/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -43,6 +58,8 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -56,7 +73,10 @@ import (
 // https://kubernetes.io/docs/reference/access-authn-authz/extensible-admission-controllers/#response
 const httpStatusWarning = 299
 
-var maxServingThreads = flag.Int("max-serving-threads", -1, "(alpha) cap the number of threads handling non-trivial requests, -1 means an infinite number of threads")
+var (
+	maxServingThreads = flag.Int("max-serving-threads", -1, "(alpha) cap the number of threads handling non-trivial requests, -1 means an infinite number of threads")
+	maxObjectSize     = flag.Int("max-evaluated-object-size", 0, "(alpha) size in bytes above which objects are only reviewed by constraints annotated with "+target.LargeObjectsAnnotation+": \"true\", bounding the time spent evaluating templates against very large objects. 0 means objects of any size are reviewed by every constraint")
+)
 
 func init() {
 	AddToManagerFuncs = append(AddToManagerFuncs, AddPolicyWebhook)
@@ -70,7 +90,7 @@ func init() {
 // +kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch
 
 // AddPolicyWebhook registers the policy webhook server with the manager.
-func AddPolicyWebhook(mgr manager.Manager, opa *opa.Client, processExcluder *process.Excluder, mutationSystem *mutation.System) error {
+func AddPolicyWebhook(mgr manager.Manager, opa *opa.Client, processExcluder *process.Excluder, mutationSystem *mutation.System, expansionSystem *expansion.System) error {
 	reporter, err := newStatsReporter()
 	if err != nil {
 		return err
@@ -95,6 +115,37 @@ func AddPolicyWebhook(mgr manager.Manager, opa *opa.Client, processExcluder *pro
 	if *maxServingThreads > 0 {
 		handler.semaphore = make(chan struct{}, *maxServingThreads)
 	}
+	if *exception.Enabled {
+		handler.exceptionSystem = exception.Get()
+	}
+	if *override.Enabled {
+		handler.overrideSystem = override.Get()
+	}
+	if *schedule.Enabled {
+		handler.scheduleSystem = schedule.Get()
+	}
+	if *prune.Enabled {
+		handler.pruner = prune.Get()
+	}
+	if *matchedkinds.DenyUnmatched {
+		handler.matchedKinds = matchedkinds.Get()
+	}
+	if *dryrunBaselineWindow > 0 {
+		handler.dryrunBaseline = newDryrunBaseline(*dryrunBaselineWindow)
+	}
+	if *expansion.ExpansionEnabled {
+		handler.expansionSystem = expansionSystem
+	}
+	decisionLogger, err := decisionlog.NewFromFlags()
+	if err != nil {
+		return err
+	}
+	if decisionLogger != nil {
+		if err := mgr.Add(decisionLogger); err != nil {
+			return err
+		}
+		handler.decisionLogger = decisionLogger
+	}
 	wh := &admission.Webhook{Handler: handler}
 	// TODO(https://github.com/open-policy-agent/gatekeeper/issues/661): remove log injection if the race condition in the cited bug is eliminated.
 	// Otherwise we risk having unstable logger names for the webhook.
@@ -109,8 +160,29 @@ var _ admission.Handler = &validationHandler{}
 
 type validationHandler struct {
 	webhookHandler
-	opa       *opa.Client
-	semaphore chan struct{}
+	opa            *opa.Client
+	semaphore      chan struct{}
+	decisionLogger *decisionlog.Logger
+	// expansionSystem generates the resources reviewed along with the object
+	// of a request, if set.
+	expansionSystem *expansion.System
+	// exceptionSystem exempts objects from constraints, if set.
+	exceptionSystem *exception.System
+	// overrideSystem downgrades constraints from deny to warn in namespaces
+	// with a NamespacePolicyOverride, if set.
+	overrideSystem *override.System
+	// scheduleSystem drops the results of constraints outside their
+	// enforcementSchedule, if set.
+	scheduleSystem *schedule.System
+	// pruner removes the fields of reviewed objects no template reads, if
+	// set.
+	pruner *prune.Pruner
+	// matchedKinds holds the kinds selected by constraints if requests for
+	// other kinds are denied.
+	matchedKinds *matchedkinds.Kinds
+	// dryrunBaseline tallies the requests dryrun constraints would have
+	// denied over a sliding window, if set.
+	dryrunBaseline *dryrunBaseline
 }
 
 // Handle the validation request
@@ -163,6 +235,14 @@ func (h *validationHandler) Handle(ctx context.Context, req admission.Request) a
 		}
 	}()
 
+	var res []*rtypes.Result
+	var reviewErr error
+	if h.decisionLogger != nil {
+		defer func() {
+			h.logDecision(&req, requestResponse, res, reviewErr, time.Since(timeStart))
+		}()
+	}
+
 	// namespace is excluded from webhook using config
 	isExcludedNamespace, err := h.skipExcludedNamespace(&req.AdmissionRequest, process.Webhook)
 	if err != nil {
@@ -174,6 +254,19 @@ func (h *validationHandler) Handle(ctx context.Context, req admission.Request) a
 		return admission.ValidationResponse(true, "Namespace is set to be ignored by Gatekeeper config")
 	}
 
+	if h.matchedKinds != nil {
+		gvk := schema.GroupVersionKind{Group: req.Kind.Group, Version: req.Kind.Version, Kind: req.Kind.Kind}
+		if !matchedkinds.Exempt(gvk) && !h.matchedKinds.Matched(gvk) {
+			vResp := admission.ValidationResponse(false, *matchedkinds.DenyMessage)
+			if vResp.Result == nil {
+				vResp.Result = &metav1.Status{}
+			}
+			vResp.Result.Code = http.StatusForbidden
+			requestResponse = denyResponse
+			return vResp
+		}
+	}
+
 	resp, err := h.reviewRequest(ctx, &req)
 	if err != nil {
 		log.Error(err, "error executing query")
@@ -183,11 +276,21 @@ func (h *validationHandler) Handle(ctx context.Context, req admission.Request) a
 		}
 		vResp.Result.Code = http.StatusInternalServerError
 		requestResponse = errorResponse
+		reviewErr = err
 		return vResp
 	}
 
-	res := resp.Results()
+	res = resp.Results()
+	h.reportDryrunDenials(ctx, res)
 	denyMsgs, warnMsgs := h.getValidationMessages(res, &req)
+	if isLargeObject(&req.AdmissionRequest) {
+		if h.reporter != nil {
+			if err := h.reporter.ReportLargeObject(ctx); err != nil {
+				log.Error(err, "failed to report large object")
+			}
+		}
+		warnMsgs = append(warnMsgs, fmt.Sprintf("object is larger than %d bytes, so only constraints annotated with %s: \"true\" reviewed it", *maxObjectSize, target.LargeObjectsAnnotation))
+	}
 
 	if len(denyMsgs) > 0 {
 		vResp := admission.ValidationResponse(false, strings.Join(denyMsgs, "\n"))
@@ -214,8 +317,64 @@ func (h *validationHandler) Handle(ctx context.Context, req admission.Request) a
 	return vResp
 }
 
+// logDecision records the verdict reached for req to the decision log.
+func (h *validationHandler) logDecision(req *admission.Request, response requestResponse, res []*rtypes.Result, reviewErr error, latency time.Duration) {
+	d := decisionlog.Decision{
+		Timestamp:         time.Now(),
+		RequestUID:        string(req.AdmissionRequest.UID),
+		Operation:         string(req.AdmissionRequest.Operation),
+		ResourceGroup:     req.AdmissionRequest.Kind.Group,
+		ResourceVersion:   req.AdmissionRequest.Kind.Version,
+		ResourceKind:      req.AdmissionRequest.Kind.Kind,
+		ResourceNamespace: req.AdmissionRequest.Namespace,
+		ResourceName:      req.AdmissionRequest.Name,
+		SubResource:       req.AdmissionRequest.SubResource,
+		Username:          req.AdmissionRequest.UserInfo.Username,
+		LatencySeconds:    latency.Seconds(),
+	}
+
+	switch response {
+	case allowResponse:
+		d.Verdict = decisionlog.Allow
+	case denyResponse:
+		d.Verdict = decisionlog.Deny
+	default:
+		d.Verdict = decisionlog.Error
+	}
+	if reviewErr != nil {
+		d.Error = reviewErr.Error()
+	}
+
+	for _, r := range res {
+		result := decisionlog.Result{
+			EnforcementAction: r.EnforcementAction,
+			Message:           r.Msg,
+		}
+		if r.Constraint != nil {
+			result.ConstraintKind = r.Constraint.GetKind()
+			result.ConstraintName = r.Constraint.GetName()
+			level, _ := severity.Of(r.Constraint)
+			result.Severity = string(level)
+		}
+		if d.Verdict == decisionlog.Allow && r.EnforcementAction == string(util.Warn) {
+			d.Verdict = decisionlog.Warn
+		}
+		d.Results = append(d.Results, result)
+	}
+
+	if h.decisionLogger.IncludeObject() && req.AdmissionRequest.Object.Raw != nil {
+		obj := &unstructured.Unstructured{}
+		if _, _, err := deserializer.Decode(req.AdmissionRequest.Object.Raw, nil, obj); err == nil {
+			d.Object = obj.Object
+		}
+	}
+
+	h.decisionLogger.Log(d)
+}
+
 func (h *validationHandler) getValidationMessages(res []*rtypes.Result, req *admission.Request) ([]string, []string) {
-	var denyMsgs, warnMsgs []string
+	var denyMsgs []string
+	var warnings []warning
 	var resourceName string
 	if len(res) > 0 && (*logDenies || *emitAdmissionEvents) {
 		resourceName = req.AdmissionRequest.Name
@@ -232,6 +391,7 @@ func (h *validationHandler) getValidationMessages(res []*rtypes.Result, req *adm
 		if err := util.ValidateEnforcementAction(util.EnforcementAction(r.EnforcementAction)); err != nil {
 			continue
 		}
+		level, _ := severity.Of(r.Constraint)
 		if *logDenies {
 			log.WithValues(
 				logging.Process, "admission",
@@ -241,11 +401,13 @@ func (h *validationHandler) getValidationMessages(res []*rtypes.Result, req *adm
 				logging.ConstraintAPIVersion, r.Constraint.GroupVersionKind().Version,
 				logging.ConstraintKind, r.Constraint.GetKind(),
 				logging.ConstraintAction, r.EnforcementAction,
+				logging.ConstraintSeverity, level,
 				logging.ResourceGroup, req.AdmissionRequest.Kind.Group,
 				logging.ResourceAPIVersion, req.AdmissionRequest.Kind.Version,
 				logging.ResourceKind, req.AdmissionRequest.Kind.Kind,
 				logging.ResourceNamespace, req.AdmissionRequest.Namespace,
 				logging.ResourceName, resourceName,
+				logging.RequestSubResource, req.AdmissionRequest.SubResource,
 				logging.RequestUsername, req.AdmissionRequest.UserInfo.Username,
 			).Info("denied admission")
 		}
@@ -258,11 +420,13 @@ func (h *validationHandler) getValidationMessages(res []*rtypes.Result, req *adm
 				logging.ConstraintAPIVersion: r.Constraint.GroupVersionKind().Version,
 				logging.ConstraintKind:       r.Constraint.GetKind(),
 				logging.ConstraintAction:     r.EnforcementAction,
+				logging.ConstraintSeverity:   string(level),
 				logging.ResourceGroup:        req.AdmissionRequest.Kind.Group,
 				logging.ResourceAPIVersion:   req.AdmissionRequest.Kind.Version,
 				logging.ResourceKind:         req.AdmissionRequest.Kind.Kind,
 				logging.ResourceNamespace:    req.AdmissionRequest.Namespace,
 				logging.ResourceName:         resourceName,
+				logging.RequestSubResource:   req.AdmissionRequest.SubResource,
 				logging.RequestUsername:      req.AdmissionRequest.UserInfo.Username,
 			}
 			var eventMsg, reason string
@@ -298,19 +462,34 @@ func (h *validationHandler) getValidationMessages(res []*rtypes.Result, req *adm
 		}
 
 		if r.EnforcementAction == string(util.Deny) {
-			denyMsgs = append(denyMsgs, fmt.Sprintf("[%s] %s", r.Constraint.GetName(), r.Msg))
+			denyMsgs = append(denyMsgs, util.ViolationMessage(r.Constraint.GetName(), r.Msg))
 		}
 
 		if r.EnforcementAction == string(util.Warn) {
-			warnMsgs = append(warnMsgs, fmt.Sprintf("[%s] %s", r.Constraint.GetName(), r.Msg))
+			warnings = append(warnings, warning{kind: r.Constraint.GetKind(), msg: util.ViolationMessage(r.Constraint.GetName(), r.Msg)})
+		}
+
+		if r.EnforcementAction == string(util.Deny) || r.EnforcementAction == string(util.Warn) {
+			patch, err := remediation.Get(r)
+			if err != nil {
+				log.Error(err, "ignoring invalid remediation", logging.ConstraintName, r.Constraint.GetName(), logging.ConstraintKind, r.Constraint.GetKind())
+			}
+			if patch != nil {
+				warnings = append(warnings, warning{kind: r.Constraint.GetKind(), msg: fmt.Sprintf("[%s] remediation: %s", r.Constraint.GetName(), patch)})
+			}
 		}
 	}
-	return denyMsgs, warnMsgs
+	return denyMsgs, composeWarnings(warnings)
 }
 
 // validateGatekeeperResources returns whether an issue is user error (vs internal) and any errors
 // validating internal resources.
 func (h *validationHandler) validateGatekeeperResources(ctx context.Context, req *admission.Request) (bool, error) {
+	// The object of a DELETE is the existing one, which must stay deletable
+	// even if it would no longer be accepted.
+	if req.AdmissionRequest.Operation == admissionv1.Delete {
+		return false, nil
+	}
 	gvk := req.AdmissionRequest.Kind
 
 	switch {
@@ -328,6 +507,14 @@ func (h *validationHandler) validateGatekeeperResources(ctx context.Context, req
 		return h.validateAssign(req)
 	case req.AdmissionRequest.Kind.Group == mutationsGroup && req.AdmissionRequest.Kind.Kind == "ModifySet":
 		return h.validateModifySet(req)
+	case req.AdmissionRequest.Kind.Group == expansionGroup && req.AdmissionRequest.Kind.Kind == "ExpansionTemplate":
+		return h.validateExpansionTemplate(req)
+	case req.AdmissionRequest.Kind.Group == exceptionsGroup && req.AdmissionRequest.Kind.Kind == "Exception":
+		return h.validateException(req)
+	case req.AdmissionRequest.Kind.Group == exceptionsGroup && req.AdmissionRequest.Kind.Kind == "NamespacePolicyOverride":
+		return h.validateNamespacePolicyOverride(req)
+	case req.AdmissionRequest.Kind.Group == exceptionsGroup && req.AdmissionRequest.Kind.Kind == "Suppression":
+		return h.validateSuppression(req)
 	}
 
 	return false, nil
@@ -345,6 +532,12 @@ func (h *validationHandler) validateTemplate(ctx context.Context, req *admission
 	if _, err := h.opa.CreateCRD(ctx, unversioned); err != nil {
 		return true, err
 	}
+	if err := builtins.Validate(unversioned); err != nil {
+		return true, err
+	}
+	if err := prune.Validate(unversioned); err != nil {
+		return true, err
+	}
 	return false, nil
 }
 
@@ -356,6 +549,12 @@ func (h *validationHandler) validateConstraint(ctx context.Context, req *admissi
 	if err := h.opa.ValidateConstraint(ctx, obj); err != nil {
 		return true, err
 	}
+	if err := schedule.Validate(obj); err != nil {
+		return true, err
+	}
+	if err := severity.Validate(obj); err != nil {
+		return true, err
+	}
 
 	enforcementActionString, found, err := unstructured.NestedString(obj.Object, "spec", "enforcementAction")
 	if err != nil {
@@ -435,6 +634,70 @@ func (h *validationHandler) validateModifySet(req *admission.Request) (bool, err
 	return false, nil
 }
 
+func (h *validationHandler) validateExpansionTemplate(req *admission.Request) (bool, error) {
+	obj, _, err := deserializer.Decode(req.AdmissionRequest.Object.Raw, nil, &expansionv1alpha1.ExpansionTemplate{})
+	if err != nil {
+		return false, err
+	}
+	t, ok := obj.(*expansionv1alpha1.ExpansionTemplate)
+	if !ok {
+		return false, fmt.Errorf("Deserialized object is not of type ExpansionTemplate")
+	}
+
+	if err := expansion.Validate(t); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+func (h *validationHandler) validateException(req *admission.Request) (bool, error) {
+	obj, _, err := deserializer.Decode(req.AdmissionRequest.Object.Raw, nil, &exceptionsv1alpha1.Exception{})
+	if err != nil {
+		return false, err
+	}
+	e, ok := obj.(*exceptionsv1alpha1.Exception)
+	if !ok {
+		return false, fmt.Errorf("Deserialized object is not of type Exception")
+	}
+
+	if err := exception.Validate(e); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+func (h *validationHandler) validateNamespacePolicyOverride(req *admission.Request) (bool, error) {
+	obj, _, err := deserializer.Decode(req.AdmissionRequest.Object.Raw, nil, &exceptionsv1alpha1.NamespacePolicyOverride{})
+	if err != nil {
+		return false, err
+	}
+	o, ok := obj.(*exceptionsv1alpha1.NamespacePolicyOverride)
+	if !ok {
+		return false, fmt.Errorf("Deserialized object is not of type NamespacePolicyOverride")
+	}
+
+	if err := override.Validate(o); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+func (h *validationHandler) validateSuppression(req *admission.Request) (bool, error) {
+	obj, _, err := deserializer.Decode(req.AdmissionRequest.Object.Raw, nil, &exceptionsv1alpha1.Suppression{})
+	if err != nil {
+		return false, err
+	}
+	sup, ok := obj.(*exceptionsv1alpha1.Suppression)
+	if !ok {
+		return false, fmt.Errorf("Deserialized object is not of type Suppression")
+	}
+
+	if err := suppression.Validate(sup); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
 // traceSwitch returns true if a request should be traced.
 func (h *validationHandler) reviewRequest(ctx context.Context, req *admission.Request) (*rtypes.Responses, error) {
 	// if we have a maximum number of concurrent serving goroutines, try to acquire
@@ -456,7 +719,14 @@ func (h *validationHandler) reviewRequest(ctx context.Context, req *admission.Re
 	if req.Kind.Kind == namespaceKind && req.Kind.Group == "" {
 		req.Namespace = ""
 	}
-	review := &target.AugmentedReview{AdmissionRequest: &req.AdmissionRequest}
+	review := &target.AugmentedReview{AdmissionRequest: &req.AdmissionRequest, LargeObject: isLargeObject(&req.AdmissionRequest)}
+	if h.pruner != nil {
+		pruned, err := h.pruner.PruneRequest(&req.AdmissionRequest)
+		if err != nil {
+			return nil, err
+		}
+		review.AdmissionRequest = pruned
+	}
 	if req.AdmissionRequest.Namespace != "" {
 		ns := &corev1.Namespace{}
 		if err := h.client.Get(ctx, types.NamespacedName{Name: req.AdmissionRequest.Namespace}, ns); err != nil {
@@ -472,7 +742,27 @@ func (h *validationHandler) reviewRequest(ctx context.Context, req *admission.Re
 		review.Namespace = ns
 	}
 
+	var stats *querystats.Recorder
+	if *querystats.Enabled || *metrics.TemplateDuration {
+		ctx, stats = querystats.NewContext(ctx)
+	}
+	if *querystats.Enabled {
+		defer func() {
+			log.Info("query stats", append([]interface{}{
+				logging.Process, "admission",
+				logging.EventType, "query_stats",
+				logging.ResourceGroup, req.AdmissionRequest.Kind.Group,
+				logging.ResourceAPIVersion, req.AdmissionRequest.Kind.Version,
+				logging.ResourceKind, req.AdmissionRequest.Kind.Kind,
+				logging.ResourceNamespace, req.AdmissionRequest.Namespace,
+				logging.ResourceName, req.AdmissionRequest.Name,
+			}, stats.Stats().KeysAndValues()...)...)
+		}()
+	}
 	resp, err := h.opa.Review(ctx, review, opa.Tracing(trace))
+	if err == nil && *metrics.TemplateDuration {
+		h.reportTemplateLatency(ctx, stats.Constraints())
+	}
 	if trace {
 		log.Info(resp.TraceDump())
 	}
@@ -484,7 +774,148 @@ func (h *validationHandler) reviewRequest(ctx context.Context, req *admission.Re
 			log.Info(dump)
 		}
 	}
-	return resp, err
+	if err != nil {
+		return resp, err
+	}
+	if h.expansionSystem != nil && req.AdmissionRequest.Operation != admissionv1.Delete {
+		if err := h.reviewExpanded(ctx, req, review.Namespace, resp); err != nil {
+			return nil, err
+		}
+	}
+	if h.scheduleSystem != nil {
+		h.scheduleResults(resp)
+	}
+	if h.exceptionSystem != nil {
+		h.exemptResults(resp, review.Namespace)
+	}
+	if h.overrideSystem != nil {
+		h.overrideResults(resp)
+	}
+	return resp, nil
+}
+
+// isLargeObject returns whether the object or old object of req is larger
+// than --max-evaluated-object-size.
+func isLargeObject(req *admissionv1.AdmissionRequest) bool {
+	return *maxObjectSize > 0 && (len(req.Object.Raw) > *maxObjectSize || len(req.OldObject.Raw) > *maxObjectSize)
+}
+
+// scheduleResults drops the results of constraints outside their
+// enforcementSchedule from resp.
+func (h *validationHandler) scheduleResults(resp *rtypes.Responses) {
+	for _, r := range resp.ByTarget {
+		kept, inactive := h.scheduleSystem.Filter(r.Results)
+		for _, c := range inactive {
+			log.V(1).Info(
+				"constraint outside its enforcementSchedule",
+				logging.ConstraintKind, c.GetKind(),
+				logging.ConstraintName, c.GetName(),
+			)
+		}
+		r.Results = kept
+	}
+}
+
+// exemptResults drops the results exempted by an Exception from resp.
+func (h *validationHandler) exemptResults(resp *rtypes.Responses, ns *corev1.Namespace) {
+	for _, r := range resp.ByTarget {
+		kept, exempted := h.exceptionSystem.Filter(r.Results, ns)
+		for res, names := range exempted {
+			log.V(1).Info(
+				"violation exempted",
+				logging.ConstraintKind, res.Constraint.GetKind(),
+				logging.ConstraintName, res.Constraint.GetName(),
+				"exceptions", names,
+			)
+		}
+		r.Results = kept
+	}
+}
+
+// overrideResults downgrades the results of resp which a
+// NamespacePolicyOverride downgrades from deny to warn.
+func (h *validationHandler) overrideResults(resp *rtypes.Responses) {
+	for _, r := range resp.ByTarget {
+		for res, names := range h.overrideSystem.Apply(r.Results) {
+			log.V(1).Info(
+				"violation downgraded to warn",
+				logging.ConstraintKind, res.Constraint.GetKind(),
+				logging.ConstraintName, res.Constraint.GetName(),
+				"overrides", names,
+			)
+		}
+	}
+}
+
+// reviewExpanded reviews the resources generated from the object of req,
+// adding their results to resp. The message of each result names the
+// ExpansionTemplates which generated the resource, its kind, and the field of
+// the object of req it was generated from.
+func (h *validationHandler) reviewExpanded(ctx context.Context, req *admission.Request, ns *corev1.Namespace, resp *rtypes.Responses) error {
+	obj := &unstructured.Unstructured{}
+	if _, _, err := deserializer.Decode(req.AdmissionRequest.Object.Raw, nil, obj); err != nil {
+		return err
+	}
+	resultants, err := h.expansionSystem.Expand(obj, ns)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range resultants {
+		templates := strings.Join(r.TemplateNames(), " -> ")
+		raw, err := r.Obj.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		gvk := r.Obj.GroupVersionKind()
+		child := req.AdmissionRequest
+		child.Kind = metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}
+		child.Resource = metav1.GroupVersionResource{}
+		child.Name = r.Obj.GetName()
+		child.Object = runtime.RawExtension{Raw: raw}
+		child.OldObject = runtime.RawExtension{}
+
+		childResp, err := h.opa.Review(ctx, &target.AugmentedReview{AdmissionRequest: &child, Namespace: ns, LargeObject: isLargeObject(&child)})
+		if err != nil {
+			return fmt.Errorf("reviewing %s generated by ExpansionTemplates %s: %w", gvk.Kind, templates, err)
+		}
+		for name, tr := range childResp.ByTarget {
+			for _, res := range tr.Results {
+				res.Msg = fmt.Sprintf("[Implied by %s] %s: %s", templates, impliedResource(r), res.Msg)
+				if r.EnforcementAction != "" {
+					res.EnforcementAction = r.EnforcementAction
+				}
+			}
+			if cur, ok := resp.ByTarget[name]; ok {
+				cur.Results = append(cur.Results, tr.Results...)
+			} else {
+				resp.ByTarget[name] = tr
+			}
+		}
+	}
+	return nil
+}
+
+// impliedResource describes the resource generated as r, for example
+// "Pod from spec.template".
+func impliedResource(r *expansion.Resultant) string {
+	if r.SourcePath == "" {
+		return r.Obj.GetKind()
+	}
+	return fmt.Sprintf("%s from %s", r.Obj.GetKind(), r.SourcePath)
+}
+
+// reportTemplateLatency records the time spent evaluating each constraint
+// matching a request, whether or not the request violates it.
+func (h *validationHandler) reportTemplateLatency(ctx context.Context, constraints []querystats.ConstraintDuration) {
+	if h.reporter == nil {
+		return
+	}
+	for _, c := range constraints {
+		if err := h.reporter.ReportValidationTemplate(ctx, c.Kind, c.Name, c.Duration); err != nil {
+			log.Error(err, "failed to report template latency")
+		}
+	}
 }
 
 func getViolationRef(gkNamespace, rkind, rname, rnamespace, ckind, cname, cnamespace string) *corev1.ObjectReference {
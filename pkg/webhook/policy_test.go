@@ -2,15 +2,28 @@ package webhook
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
 	templv1beta1 "github.com/open-policy-agent/frameworks/constraint/pkg/apis/templates/v1beta1"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/local"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	rtypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
 	"github.com/open-policy-agent/gatekeeper/apis/config/v1alpha1"
+	exceptionsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/exceptions/v1alpha1"
+	expansionv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/expansion/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/exception"
+	"github.com/open-policy-agent/gatekeeper/pkg/expansion"
+	"github.com/open-policy-agent/gatekeeper/pkg/matchedkinds"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/override"
 	"github.com/open-policy-agent/gatekeeper/pkg/target"
 	testclients "github.com/open-policy-agent/gatekeeper/test/clients"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -176,6 +189,7 @@ func TestTemplateValidation(t *testing.T) {
 	tc := []struct {
 		Name          string
 		Template      string
+		Operation     admissionv1.Operation
 		ErrorExpected bool
 	}{
 		{
@@ -188,6 +202,12 @@ func TestTemplateValidation(t *testing.T) {
 			Template:      badRegoTemplate,
 			ErrorExpected: true,
 		},
+		{
+			Name:          "Deleting Invalid Template",
+			Template:      badRegoTemplate,
+			Operation:     admissionv1.Delete,
+			ErrorExpected: false,
+		},
 	}
 	for _, tt := range tc {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -210,6 +230,7 @@ func TestTemplateValidation(t *testing.T) {
 					Object: runtime.RawExtension{
 						Raw: b,
 					},
+					Operation: tt.Operation,
 				},
 			}
 			_, err = handler.validateGatekeeperResources(context.Background(), review)
@@ -582,6 +603,27 @@ func TestGetValidationMessages(t *testing.T) {
 		Constraint:        newConstraint("Foo", "ph", "random", t),
 		EnforcementAction: "random",
 	}
+	remediationDetails := map[string]interface{}{"details": map[string]interface{}{
+		"remediation": []interface{}{map[string]interface{}{"op": "add", "path": "/metadata/labels/owner", "value": "unknown"}},
+	}}
+	resDenyRemediation := &rtypes.Result{
+		Msg:               "test",
+		Metadata:          remediationDetails,
+		Constraint:        newConstraint("Foo", "ph", "deny", t),
+		EnforcementAction: "deny",
+	}
+	resDryRunRemediation := &rtypes.Result{
+		Msg:               "test",
+		Metadata:          remediationDetails,
+		Constraint:        newConstraint("Foo", "ph", "dryrun", t),
+		EnforcementAction: "dryrun",
+	}
+	resDenyInvalidRemediation := &rtypes.Result{
+		Msg:               "test",
+		Metadata:          map[string]interface{}{"details": map[string]interface{}{"remediation": "add the owner label"}},
+		Constraint:        newConstraint("Foo", "ph", "deny", t),
+		EnforcementAction: "deny",
+	}
 
 	tc := []struct {
 		Name                 string
@@ -659,6 +701,30 @@ func TestGetValidationMessages(t *testing.T) {
 			ExpectedDenyMsgCount: 0,
 			ExpectedWarnMsgCount: 0,
 		},
+		{
+			Name: "Deny With Remediation",
+			Result: []*rtypes.Result{
+				resDenyRemediation,
+			},
+			ExpectedDenyMsgCount: 1,
+			ExpectedWarnMsgCount: 1,
+		},
+		{
+			Name: "Dry Run With Remediation",
+			Result: []*rtypes.Result{
+				resDryRunRemediation,
+			},
+			ExpectedDenyMsgCount: 0,
+			ExpectedWarnMsgCount: 0,
+		},
+		{
+			Name: "Deny With Invalid Remediation",
+			Result: []*rtypes.Result{
+				resDenyInvalidRemediation,
+			},
+			ExpectedDenyMsgCount: 1,
+			ExpectedWarnMsgCount: 0,
+		},
 		{
 			Name: "Random EnforcementAction",
 			Result: []*rtypes.Result{
@@ -744,3 +810,555 @@ func TestValidateConfigResource(t *testing.T) {
 		})
 	}
 }
+
+const (
+	denyPodsTemplate = `
+apiVersion: templates.gatekeeper.sh/v1beta1
+kind: ConstraintTemplate
+metadata:
+  name: k8sdenypods
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sDenyPods
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package denypods
+
+        violation[{"msg": "pods are denied"}] {
+          input.review.kind.kind == "Pod"
+        }
+`
+
+	denyPodsConstraint = `
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sDenyPods
+metadata:
+  name: deny-pods
+spec:
+  match:
+    kinds:
+      - apiGroups: [""]
+        kinds: ["Pod"]
+`
+
+	deployment = `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {"name": "nginx", "namespace": "ns1"},
+  "spec": {"template": {"metadata": {"labels": {"app": "nginx"}}, "spec": {"containers": [{"name": "nginx", "image": "nginx"}]}}}
+}`
+)
+
+func TestReviewExpanded(t *testing.T) {
+	tcs := []struct {
+		name              string
+		enforcementAction string
+		viaReplicaSet     bool
+		operation         admissionv1.Operation
+		wantMsgs          []string
+		wantAction        string
+	}{
+		{
+			name:       "generated resource is reviewed",
+			wantMsgs:   []string{"[Implied by expand-deployments] Pod from spec.template: pods are denied"},
+			wantAction: "deny",
+		},
+		{
+			name:              "template overrides enforcement action",
+			enforcementAction: "warn",
+			wantMsgs:          []string{"[Implied by expand-deployments] Pod from spec.template: pods are denied"},
+			wantAction:        "warn",
+		},
+		{
+			name:              "resource generated transitively is reviewed",
+			enforcementAction: "warn",
+			viaReplicaSet:     true,
+			wantMsgs:          []string{"[Implied by expand-deployments -> expand-replicasets] Pod from spec.template: pods are denied"},
+			wantAction:        "warn",
+		},
+		{
+			name:      "deletion is not expanded",
+			operation: admissionv1.Delete,
+		},
+	}
+	deployments := []match.ApplyTo{{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{"Deployment"}}}
+	pods := expansionv1alpha1.GeneratedGVK{Version: "v1", Kind: "Pod"}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			opa, err := makeOpaClient()
+			if err != nil {
+				t.Fatalf("Could not initialize OPA: %s", err)
+			}
+			cstr := &templv1beta1.ConstraintTemplate{}
+			if err := yaml.Unmarshal([]byte(denyPodsTemplate), cstr); err != nil {
+				t.Fatal(err)
+			}
+			unversioned := &templates.ConstraintTemplate{}
+			if err := runtimeScheme.Convert(cstr, unversioned, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := opa.AddTemplate(ctx, unversioned); err != nil {
+				t.Fatal(err)
+			}
+			constraint := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(denyPodsConstraint), &constraint.Object); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := opa.AddConstraint(ctx, constraint); err != nil {
+				t.Fatal(err)
+			}
+
+			tmpls := []*expansionv1alpha1.ExpansionTemplate{{
+				ObjectMeta: metav1.ObjectMeta{Name: "expand-deployments"},
+				Spec: expansionv1alpha1.ExpansionTemplateSpec{
+					ApplyTo:           deployments,
+					TemplateSource:    "spec.template",
+					GeneratedGVK:      pods,
+					EnforcementAction: tc.enforcementAction,
+				},
+			}}
+			if tc.viaReplicaSet {
+				tmpls[0].Spec.TemplateSource = ""
+				tmpls[0].Spec.GeneratedGVK = expansionv1alpha1.GeneratedGVK{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+				tmpls[0].Spec.FieldMappings = []expansionv1alpha1.FieldMapping{{From: "spec", To: "spec"}}
+				tmpls = append(tmpls, &expansionv1alpha1.ExpansionTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "expand-replicasets"},
+					Spec: expansionv1alpha1.ExpansionTemplateSpec{
+						ApplyTo:        []match.ApplyTo{{Groups: []string{"apps"}, Versions: []string{"v1"}, Kinds: []string{"ReplicaSet"}}},
+						TemplateSource: "spec.template",
+						GeneratedGVK:   pods,
+					},
+				})
+			}
+			system := expansion.NewSystem(nil)
+			for _, tmpl := range tmpls {
+				if err := system.Upsert(tmpl); err != nil {
+					t.Fatal(err)
+				}
+			}
+			handler := validationHandler{
+				opa:             opa,
+				webhookHandler:  webhookHandler{injectedConfig: &v1alpha1.Config{}, client: &nsGetter{}},
+				expansionSystem: system,
+			}
+			review := &atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					Object:    runtime.RawExtension{Raw: []byte(deployment)},
+					Namespace: "ns1",
+					Name:      "nginx",
+					Operation: tc.operation,
+				},
+			}
+			resp, err := handler.reviewRequest(ctx, review)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := resp.Results()
+			if len(res) != len(tc.wantMsgs) {
+				t.Fatalf("got %d results, want %d: %v", len(res), len(tc.wantMsgs), res)
+			}
+			for i, r := range res {
+				if r.Msg != tc.wantMsgs[i] {
+					t.Errorf("got message %q, want %q", r.Msg, tc.wantMsgs[i])
+				}
+				if r.EnforcementAction != tc.wantAction {
+					t.Errorf("got enforcement action %q, want %q", r.EnforcementAction, tc.wantAction)
+				}
+			}
+		})
+	}
+}
+
+func TestDenyUnmatchedKinds(t *testing.T) {
+	opa, err := makeOpaClient()
+	if err != nil {
+		t.Fatalf("Could not initialize OPA: %s", err)
+	}
+	kinds := matchedkinds.NewKinds()
+	constraint := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(denyPodsConstraint), &constraint.Object); err != nil {
+		t.Fatal(err)
+	}
+	if err := kinds.Set(constraint); err != nil {
+		t.Fatal(err)
+	}
+	handler := validationHandler{
+		opa:            opa,
+		webhookHandler: webhookHandler{injectedConfig: &v1alpha1.Config{}, client: &nsGetter{}, processExcluder: process.New()},
+		matchedKinds:   kinds,
+	}
+
+	tcs := []struct {
+		name        string
+		kind        metav1.GroupVersionKind
+		wantAllowed bool
+	}{
+		{
+			name:        "matched kind is reviewed",
+			kind:        metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			wantAllowed: true,
+		},
+		{
+			name:        "unmatched kind is denied",
+			kind:        metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			wantAllowed: false,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			req := atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:      tc.kind,
+					Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "` + tc.kind.Kind + `", "metadata": {"name": "foo", "namespace": "ns1"}}`)},
+					Namespace: "ns1",
+					Name:      "foo",
+					Operation: admissionv1.Create,
+				},
+			}
+			resp := handler.Handle(context.Background(), req)
+			if resp.Allowed != tc.wantAllowed {
+				t.Fatalf("got allowed %v, want %v: %v", resp.Allowed, tc.wantAllowed, resp.Result)
+			}
+			if !tc.wantAllowed && string(resp.Result.Reason) != *matchedkinds.DenyMessage {
+				t.Errorf("got reason %q, want %q", resp.Result.Reason, *matchedkinds.DenyMessage)
+			}
+		})
+	}
+}
+
+func TestExemptedViolations(t *testing.T) {
+	ctx := context.Background()
+	opa, err := makeOpaClient()
+	if err != nil {
+		t.Fatalf("Could not initialize OPA: %s", err)
+	}
+	cstr := &templv1beta1.ConstraintTemplate{}
+	if err := yaml.Unmarshal([]byte(denyPodsTemplate), cstr); err != nil {
+		t.Fatal(err)
+	}
+	unversioned := &templates.ConstraintTemplate{}
+	if err := runtimeScheme.Convert(cstr, unversioned, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddTemplate(ctx, unversioned); err != nil {
+		t.Fatal(err)
+	}
+	constraint := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(denyPodsConstraint), &constraint.Object); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddConstraint(ctx, constraint); err != nil {
+		t.Fatal(err)
+	}
+
+	system := exception.NewSystem()
+	if err := system.Upsert(&exceptionsv1alpha1.Exception{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy-pods"},
+		Spec: exceptionsv1alpha1.ExceptionSpec{
+			Constraints:   []exceptionsv1alpha1.ConstraintReference{{Kind: "K8sDenyPods"}},
+			Match:         match.Match{Namespaces: []string{"legacy"}},
+			ExpiresAt:     metav1.NewTime(time.Now().Add(time.Hour)),
+			Justification: exceptionsv1alpha1.Justification{Reason: "pods are being migrated"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	handler := validationHandler{
+		opa:             opa,
+		webhookHandler:  webhookHandler{injectedConfig: &v1alpha1.Config{}, client: &nsGetter{}},
+		exceptionSystem: system,
+	}
+
+	tcs := []struct {
+		namespace   string
+		wantResults int
+	}{
+		{namespace: "legacy"},
+		{namespace: "ns1", wantResults: 1},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.namespace, func(t *testing.T) {
+			req := &atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo", "namespace": "` + tc.namespace + `"}}`)},
+					Namespace: tc.namespace,
+					Name:      "foo",
+					Operation: admissionv1.Create,
+				},
+			}
+			resp, err := handler.reviewRequest(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res := resp.Results(); len(res) != tc.wantResults {
+				t.Errorf("got %d results, want %d: %v", len(res), tc.wantResults, res)
+			}
+		})
+	}
+}
+
+func TestOverriddenViolations(t *testing.T) {
+	ctx := context.Background()
+	opa, err := makeOpaClient()
+	if err != nil {
+		t.Fatalf("Could not initialize OPA: %s", err)
+	}
+	cstr := &templv1beta1.ConstraintTemplate{}
+	if err := yaml.Unmarshal([]byte(denyPodsTemplate), cstr); err != nil {
+		t.Fatal(err)
+	}
+	unversioned := &templates.ConstraintTemplate{}
+	if err := runtimeScheme.Convert(cstr, unversioned, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddTemplate(ctx, unversioned); err != nil {
+		t.Fatal(err)
+	}
+	constraint := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(denyPodsConstraint), &constraint.Object); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddConstraint(ctx, constraint); err != nil {
+		t.Fatal(err)
+	}
+
+	system := override.NewSystem()
+	system.SetOverridable([]v1alpha1.OverridableConstraint{{Kind: "K8sDenyPods"}})
+	if err := system.Upsert(&exceptionsv1alpha1.NamespacePolicyOverride{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "sandbox", Name: "warn-pods"},
+		Spec: exceptionsv1alpha1.NamespacePolicyOverrideSpec{
+			Constraints: []exceptionsv1alpha1.ConstraintReference{{Kind: "K8sDenyPods"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	handler := validationHandler{
+		opa:            opa,
+		webhookHandler: webhookHandler{injectedConfig: &v1alpha1.Config{}, client: &nsGetter{}},
+		overrideSystem: system,
+	}
+
+	tcs := []struct {
+		namespace  string
+		wantAction string
+	}{
+		{namespace: "sandbox", wantAction: "warn"},
+		{namespace: "ns1", wantAction: "deny"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.namespace, func(t *testing.T) {
+			req := &atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Object:    runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo", "namespace": "` + tc.namespace + `"}}`)},
+					Namespace: tc.namespace,
+					Name:      "foo",
+					Operation: admissionv1.Create,
+				},
+			}
+			resp, err := handler.reviewRequest(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := resp.Results()
+			if len(res) != 1 {
+				t.Fatalf("got %d results, want 1: %v", len(res), res)
+			}
+			if res[0].EnforcementAction != tc.wantAction {
+				t.Errorf("got enforcement action %q, want %q", res[0].EnforcementAction, tc.wantAction)
+			}
+		})
+	}
+}
+
+func TestLargeObjects(t *testing.T) {
+	ctx := context.Background()
+	opa, err := makeOpaClient()
+	if err != nil {
+		t.Fatalf("Could not initialize OPA: %s", err)
+	}
+	cstr := &templv1beta1.ConstraintTemplate{}
+	if err := yaml.Unmarshal([]byte(denyPodsTemplate), cstr); err != nil {
+		t.Fatal(err)
+	}
+	unversioned := &templates.ConstraintTemplate{}
+	if err := runtimeScheme.Convert(cstr, unversioned, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddTemplate(ctx, unversioned); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"deny-pods", "deny-large-pods"} {
+		constraint := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(denyPodsConstraint), &constraint.Object); err != nil {
+			t.Fatal(err)
+		}
+		constraint.SetName(name)
+		if name == "deny-large-pods" {
+			constraint.SetAnnotations(map[string]string{target.LargeObjectsAnnotation: "true"})
+		}
+		if _, err := opa.AddConstraint(ctx, constraint); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(size int) { *maxObjectSize = size }(*maxObjectSize)
+	*maxObjectSize = 200
+	handler := validationHandler{
+		opa:            opa,
+		webhookHandler: webhookHandler{injectedConfig: &v1alpha1.Config{}, client: &nsGetter{}, processExcluder: process.New()},
+	}
+
+	tcs := []struct {
+		name            string
+		padding         int
+		wantConstraints []string
+	}{
+		{name: "small object", wantConstraints: []string{"deny-large-pods", "deny-pods"}},
+		{name: "large object", padding: 200, wantConstraints: []string{"deny-large-pods"}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo", "namespace": "ns1", "annotations": {"padding": "` + strings.Repeat("x", tc.padding) + `"}}}`
+			req := &atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Object:    runtime.RawExtension{Raw: []byte(raw)},
+					Namespace: "ns1",
+					Name:      "foo",
+					Operation: admissionv1.Create,
+				},
+			}
+			resp, err := handler.reviewRequest(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range resp.Results() {
+				got = append(got, r.Constraint.GetName())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.wantConstraints, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("handled without a reporter", func(t *testing.T) {
+		raw := `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "foo", "namespace": "ns1", "annotations": {"padding": "` + strings.Repeat("x", 200) + `"}}}`
+		req := atypes.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Object:    runtime.RawExtension{Raw: []byte(raw)},
+				Namespace: "ns1",
+				Name:      "foo",
+				Operation: admissionv1.Create,
+			},
+		}
+		resp := handler.Handle(ctx, req)
+		if resp.Allowed {
+			t.Errorf("got allowed, want denied by deny-large-pods")
+		}
+	})
+}
+
+func TestSubResources(t *testing.T) {
+	ctx := context.Background()
+	opa, err := makeOpaClient()
+	if err != nil {
+		t.Fatalf("Could not initialize OPA: %s", err)
+	}
+	cstr := &templv1beta1.ConstraintTemplate{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: templates.gatekeeper.sh/v1beta1
+kind: ConstraintTemplate
+metadata:
+  name: k8smaxscale
+spec:
+  crd:
+    spec:
+      names:
+        kind: K8sMaxScale
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package maxscale
+
+        violation[{"msg": "too many replicas"}] {
+          input.review.subResource == "scale"
+          input.review.object.spec.replicas > 3
+        }
+`), cstr); err != nil {
+		t.Fatal(err)
+	}
+	unversioned := &templates.ConstraintTemplate{}
+	if err := runtimeScheme.Convert(cstr, unversioned, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddTemplate(ctx, unversioned); err != nil {
+		t.Fatal(err)
+	}
+	constraint := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sMaxScale
+metadata:
+  name: max-scale
+spec:
+  match:
+    kinds:
+      - apiGroups: ["autoscaling"]
+        kinds: ["Scale"]
+`), &constraint.Object); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opa.AddConstraint(ctx, constraint); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := validationHandler{
+		opa:            opa,
+		webhookHandler: webhookHandler{injectedConfig: &v1alpha1.Config{}, client: &nsGetter{}},
+	}
+
+	tcs := []struct {
+		name        string
+		replicas    int
+		subResource string
+		wantResults int
+	}{
+		{name: "scale within limit", replicas: 2, subResource: "scale"},
+		{name: "scale beyond limit", replicas: 5, subResource: "scale", wantResults: 1},
+		{name: "not a subresource", replicas: 5},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := fmt.Sprintf(`{"apiVersion": "autoscaling/v1", "kind": "Scale", "metadata": {"name": "web", "namespace": "ns1"}, "spec": {"replicas": %d}}`, tc.replicas)
+			req := &atypes.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Kind:        metav1.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "Scale"},
+					Resource:    metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+					SubResource: tc.subResource,
+					Object:      runtime.RawExtension{Raw: []byte(raw)},
+					Namespace:   "ns1",
+					Name:        "web",
+					Operation:   admissionv1.Update,
+				},
+			}
+			resp, err := handler.reviewRequest(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(resp.Results()); got != tc.wantResults {
+				t.Errorf("got %d results, want %d", got, tc.wantResults)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/gatekeeper/pkg/logging"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	reinvocationWindow    = flag.Duration("mutation-reinvocation-window", 30*time.Second, "(alpha) how long the mutation webhook remembers the paths it mutated, to detect mutations lost when it is reinvoked after other webhooks changed an object. Requires --mutation-annotations. 0 disables detection")
+	reapplyDifferingPaths = flag.Bool("mutation-reapply-differing-paths-only", false, "(alpha) when the mutation webhook is reinvoked, only patch the paths it previously mutated which other webhooks changed, leaving the rest of the object as they left it")
+)
+
+// annotationPaths are the paths of the mutation annotations, which change
+// whenever an object is mutated.
+var annotationPaths = []string{
+	"/metadata/annotations/" + escapePointer(mutation.MutationsAnnotation),
+	"/metadata/annotations/" + escapePointer(mutation.MutationIDAnnotation),
+}
+
+// reinvocationTracker remembers the paths the mutation webhook patched,
+// keyed by the id of the mutation annotated on the object. Kubernetes does not
+// mark reinvoked requests, so a request is taken to be reinvoked if its object
+// carries the id of a recent mutation it did not carry before the request.
+type reinvocationTracker struct {
+	window time.Duration
+	now    func() time.Time
+
+	mux       sync.Mutex
+	applied   map[string]appliedPaths
+	lastSweep time.Time
+}
+
+type appliedPaths struct {
+	paths   []string
+	expires time.Time
+}
+
+func newReinvocationTracker(window time.Duration) *reinvocationTracker {
+	return &reinvocationTracker{
+		window:  window,
+		now:     time.Now,
+		applied: make(map[string]appliedPaths),
+	}
+}
+
+// previous returns the paths patched by the mutation id within the window,
+// and whether there were any.
+func (t *reinvocationTracker) previous(id string) ([]string, bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	a, ok := t.applied[id]
+	if !ok || t.now().After(a.expires) {
+		return nil, false
+	}
+	return a.paths, true
+}
+
+// record remembers that the mutation id patched paths.
+func (t *reinvocationTracker) record(id string, paths []string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	now := t.now()
+	if now.Sub(t.lastSweep) > t.window {
+		for k, a := range t.applied {
+			if now.After(a.expires) {
+				delete(t.applied, k)
+			}
+		}
+		t.lastSweep = now
+	}
+	t.applied[id] = appliedPaths{paths: paths, expires: now.Add(t.window)}
+}
+
+// reinvokedMutationID returns the id of the mutation the webhook applied to
+// obj earlier in req, or "" if req is not a reinvocation. An update carrying
+// the id of its old object was mutated by an earlier request.
+func reinvokedMutationID(req *admission.Request, obj *unstructured.Unstructured) string {
+	id := obj.GetAnnotations()[mutation.MutationIDAnnotation]
+	if id == "" {
+		return ""
+	}
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) != 0 {
+		old := struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		}{}
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err == nil && old.Metadata.Annotations[mutation.MutationIDAnnotation] == id {
+			return ""
+		}
+	}
+	return id
+}
+
+// trackReinvocation records the paths patched by resp under the mutation id
+// of obj. If the request was reinvoked after the webhook applied the mutation
+// previousID, the patched paths which that mutation already patched were
+// undone by a later webhook: these are reported, and with
+// --mutation-reapply-differing-paths-only are the only ones patched again.
+func (h *mutationHandler) trackReinvocation(ctx context.Context, previousID string, obj *unstructured.Unstructured, resp *admission.Response) {
+	var previous []string
+	if previousID != "" {
+		var reinvoked bool
+		previous, reinvoked = h.reinvocations.previous(previousID)
+		if reinvoked {
+			if lost := overlapping(patchPaths(resp), previous); len(lost) > 0 {
+				log.Info("mutations lost on reinvocation",
+					"mutation id", previousID,
+					logging.ResourceKind, obj.GetKind(),
+					logging.ResourceNamespace, obj.GetNamespace(),
+					logging.ResourceName, obj.GetName(),
+					"paths", strings.Join(lost, ", "))
+				if h.reporter != nil {
+					if err := h.reporter.ReportMutationLost(ctx); err != nil {
+						log.Error(err, "failed to report lost mutations")
+					}
+				}
+			}
+			if *reapplyDifferingPaths {
+				keepOverlapping(resp, union(previous, annotationPaths))
+			}
+		}
+	}
+
+	id := obj.GetAnnotations()[mutation.MutationIDAnnotation]
+	if id == "" {
+		return
+	}
+	h.reinvocations.record(id, union(previous, patchPaths(resp)))
+}
+
+// patchPaths returns the paths patched by resp.
+func patchPaths(resp *admission.Response) []string {
+	paths := make([]string, len(resp.Patches))
+	for i, p := range resp.Patches {
+		paths[i] = p.Path
+	}
+	return paths
+}
+
+// keepOverlapping removes the patches of resp whose paths neither are, contain
+// nor are contained by any of paths.
+func keepOverlapping(resp *admission.Response, paths []string) {
+	kept := resp.Patches[:0]
+	for _, p := range resp.Patches {
+		if overlapsAny(p.Path, paths) {
+			kept = append(kept, p)
+		}
+	}
+	resp.Patches = kept
+}
+
+// overlapping returns the paths of patched, other than those of the mutation
+// annotations, which overlap any of previous.
+func overlapping(patched, previous []string) []string {
+	var result []string
+	for _, p := range patched {
+		if overlapsAny(p, previous) && !overlapsAny(p, annotationPaths) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func overlapsAny(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, p+"/") || strings.HasPrefix(p, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns the sorted, distinct paths of a and b.
+func union(a, b []string) []string {
+	set := make(map[string]bool, len(a)+len(b))
+	for _, p := range a {
+		set[p] = true
+	}
+	for _, p := range b {
+		set[p] = true
+	}
+	result := make([]string, 0, len(set))
+	for p := range set {
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// escapePointer escapes s for use as a JSON pointer token.
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	mutationsv1alpha1 "github.com/open-policy-agent/gatekeeper/apis/mutations/v1alpha1"
+	"github.com/open-policy-agent/gatekeeper/pkg/controller/config/process"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/match"
+	"github.com/open-policy-agent/gatekeeper/pkg/mutation/mutators"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newReinvocationHandler(t *testing.T) *mutationHandler {
+	sys := mutation.NewSystem(mutation.SystemOpts{})
+	// spec.sidecar is only set on Pods labeled by a later webhook.
+	injected := match.Match{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"injected": "true"}}}
+	for location, m := range map[string]match.Match{"spec.value": {}, "spec.other": {}, "spec.sidecar": injected} {
+		m, err := mutators.MutatorForAssign(&mutationsv1alpha1.Assign{
+			ObjectMeta: metav1.ObjectMeta{Name: location},
+			Spec: mutationsv1alpha1.AssignSpec{
+				ApplyTo:  []match.ApplyTo{{Groups: []string{""}, Versions: []string{"v1"}, Kinds: []string{"Pod"}}},
+				Match:    m,
+				Location: location,
+				Parameters: mutationsv1alpha1.Parameters{
+					Assign: runtime.RawExtension{Raw: []byte(`{"value": "foo"}`)},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sys.Upsert(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &mutationHandler{
+		webhookHandler: webhookHandler{
+			client:          &nsGetter{},
+			reader:          &nsGetter{},
+			reporter:        &lostReporter{},
+			processExcluder: process.New(),
+		},
+		mutationSystem: sys,
+		deserializer:   codecs.UniversalDeserializer(),
+		reinvocations:  newReinvocationTracker(time.Minute),
+	}
+}
+
+func podRequest(operation admissionv1.Operation, obj, old string) admission.Request {
+	req := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Object:    runtime.RawExtension{Raw: []byte(obj)},
+			Namespace: "ns1",
+			Operation: operation,
+		},
+	}
+	if old != "" {
+		req.OldObject = runtime.RawExtension{Raw: []byte(old)}
+	}
+	return req
+}
+
+// lostReporter counts the requests reported to have lost mutations.
+type lostReporter struct {
+	StatsReporter
+	lost int
+}
+
+func (r *lostReporter) ReportMutationRequest(context.Context, requestResponse, time.Duration) error {
+	return nil
+}
+
+func (r *lostReporter) ReportMutationLost(context.Context) error {
+	r.lost++
+	return nil
+}
+
+// mutationID returns the mutation id resp annotates the object with.
+func mutationID(t *testing.T, resp admission.Response) string {
+	for _, p := range resp.Patches {
+		switch p.Path {
+		case annotationPaths[1]:
+			return p.Value.(string)
+		case "/metadata/annotations":
+			return p.Value.(map[string]interface{})[mutation.MutationIDAnnotation].(string)
+		}
+	}
+	t.Fatalf("got no mutation id in patches %v", resp.Patches)
+	return ""
+}
+
+func sortedPatchPaths(resp admission.Response) []string {
+	paths := patchPaths(&resp)
+	sort.Strings(paths)
+	return paths
+}
+
+func TestMutationReinvocation(t *testing.T) {
+	annotationsEnabled := *mutation.MutationAnnotationsEnabled
+	*mutation.MutationAnnotationsEnabled = true
+	defer func() {
+		*mutation.MutationAnnotationsEnabled = annotationsEnabled
+		*reapplyDifferingPaths = false
+	}()
+
+	pod := func(labels, annotations, spec map[string]string) string {
+		b, err := json.Marshal(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": "pod", "namespace": "ns1", "labels": labels, "annotations": annotations},
+			"spec":       spec,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	for _, reapply := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reapply differing paths only %v", reapply), func(t *testing.T) {
+			*reapplyDifferingPaths = reapply
+			h := newReinvocationHandler(t)
+			ctx := context.Background()
+			reporter := h.reporter.(*lostReporter)
+
+			resp := h.Handle(ctx, podRequest(admissionv1.Create, pod(map[string]string{}, map[string]string{}, map[string]string{}), ""))
+			if !resp.Allowed || len(resp.Patches) == 0 {
+				t.Fatalf("got response %+v, want the object mutated", resp)
+			}
+			id := mutationID(t, resp)
+
+			// A later webhook undid the mutation of spec.value, and labeled
+			// the Pod to be mutated by another mutator.
+			labels := map[string]string{"injected": "true"}
+			mutated := map[string]string{mutation.MutationIDAnnotation: id, mutation.MutationsAnnotation: "Assign//spec.value:1, Assign//spec.other:1"}
+			resp = h.Handle(ctx, podRequest(admissionv1.Create, pod(labels, mutated, map[string]string{"value": "bar", "other": "foo"}), ""))
+			if got := reporter.lost; got != 1 {
+				t.Errorf("got %d requests with lost mutations, want 1", got)
+			}
+			want := []string{annotationPaths[1], annotationPaths[0], "/spec/sidecar", "/spec/value"}
+			if reapply {
+				want = []string{annotationPaths[1], annotationPaths[0], "/spec/value"}
+			}
+			if diff := cmp.Diff(want, sortedPatchPaths(resp)); diff != "" {
+				t.Error(diff)
+			}
+
+			// An update to an object mutated by an earlier request is not a
+			// reinvocation.
+			old := pod(labels, mutated, map[string]string{"value": "foo", "other": "foo", "sidecar": "foo"})
+			h.Handle(ctx, podRequest(admissionv1.Update, pod(labels, mutated, map[string]string{"value": "baz", "other": "foo", "sidecar": "foo"}), old))
+			if got := reporter.lost; got != 1 {
+				t.Errorf("got %d requests with lost mutations after an update, want 1", got)
+			}
+		})
+	}
+}
+
+func TestKeepOverlapping(t *testing.T) {
+	resp := admission.PatchResponseFromRaw(
+		[]byte(`{"metadata": {"labels": {"a": "b"}}, "spec": {"value": "bar"}}`),
+		[]byte(`{"metadata": {"labels": {"a": "c"}}, "spec": {"value": "foo", "containers": [{"name": "c"}]}}`))
+
+	keepOverlapping(&resp, []string{"/spec/value", "/metadata/labels"})
+
+	want := []string{"/metadata/labels/a", "/spec/value"}
+	if diff := cmp.Diff(want, sortedPatchPaths(resp)); diff != "" {
+		t.Error(diff)
+	}
+}
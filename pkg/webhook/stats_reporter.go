@@ -16,6 +16,15 @@ const (
 
 	mutationRequestCountMetricName    = "mutation_request_count"
 	mutationRequestDurationMetricName = "mutation_request_duration_seconds"
+
+	validationTemplateDurationMetricName = "validation_template_duration_seconds"
+
+	validationLargeObjectCountMetricName = "validation_request_large_object_count"
+
+	mutationReinvocationLostCountMetricName = "mutation_reinvocation_lost_count"
+
+	validationDryrunDenialCountMetricName = "validation_dryrun_denial_count"
+	validationDryrunDenialRatioMetricName = "validation_dryrun_denial_ratio"
 )
 
 var (
@@ -29,8 +38,35 @@ var (
 		"The response time in seconds",
 		stats.UnitSeconds)
 
+	validationTemplateTimeInSecM = stats.Float64(
+		validationTemplateDurationMetricName,
+		"The time in seconds spent evaluating a constraint of a template for a request",
+		stats.UnitSeconds)
+
+	validationLargeObjectM = stats.Int64(
+		validationLargeObjectCountMetricName,
+		"The number of requests whose object was larger than --max-evaluated-object-size, and was only reviewed by constraints opting into large objects",
+		stats.UnitDimensionless)
+
+	mutationReinvocationLostM = stats.Int64(
+		mutationReinvocationLostCountMetricName,
+		"The number of reinvoked mutation requests whose object had lost mutations previously applied by Gatekeeper",
+		stats.UnitDimensionless)
+
+	validationDryrunDenialM = stats.Int64(
+		validationDryrunDenialCountMetricName,
+		"The number of reviewed requests which constraints with the dryrun enforcement action would have denied",
+		stats.UnitDimensionless)
+
+	validationDryrunDenialRatioM = stats.Float64(
+		validationDryrunDenialRatioMetricName,
+		"The ratio of the requests reviewed within --dryrun-baseline-window which constraints with the dryrun enforcement action would have denied",
+		stats.UnitDimensionless)
+
 	admissionStatusKey = tag.MustNewKey("admission_status")
 	mutationStatusKey  = tag.MustNewKey("mutation_status")
+	templateKindKey    = tag.MustNewKey("template_kind")
+	constraintNameKey  = tag.MustNewKey("constraint_name")
 )
 
 func init() {
@@ -43,6 +79,11 @@ func init() {
 type StatsReporter interface {
 	ReportValidationRequest(ctx context.Context, response requestResponse, d time.Duration) error
 	ReportMutationRequest(ctx context.Context, response requestResponse, d time.Duration) error
+	ReportValidationTemplate(ctx context.Context, templateKind, constraintName string, d time.Duration) error
+	ReportLargeObject(ctx context.Context) error
+	ReportMutationLost(ctx context.Context) error
+	ReportDryrunDenial(ctx context.Context, templateKind, constraintName string) error
+	ReportDryrunDenialRatio(ctx context.Context, templateKind, constraintName string, ratio float64) error
 }
 
 // reporter implements StatsReporter interface.
@@ -61,6 +102,64 @@ func (r *reporter) ReportMutationRequest(ctx context.Context, response requestRe
 	return r.reportRequest(ctx, response, mutationStatusKey, mutationResponseTimeInSecM.M(d.Seconds()))
 }
 
+// ReportValidationTemplate records the time spent evaluating a constraint
+// matching the request.
+func (r *reporter) ReportValidationTemplate(ctx context.Context, templateKind, constraintName string, d time.Duration) error {
+	mutators := []tag.Mutator{tag.Insert(templateKindKey, templateKind)}
+	if name, ok := metrics.ConstraintNameTagValue(constraintName); ok {
+		mutators = append(mutators, tag.Insert(constraintNameKey, name))
+	}
+	ctx, err := tag.New(ctx, mutators...)
+	if err != nil {
+		return err
+	}
+
+	return metrics.Record(ctx, validationTemplateTimeInSecM.M(d.Seconds()))
+}
+
+// ReportLargeObject counts a request whose object was only reviewed by the
+// constraints opting into large objects.
+func (r *reporter) ReportLargeObject(ctx context.Context) error {
+	return metrics.Record(ctx, validationLargeObjectM.M(1))
+}
+
+// ReportMutationLost counts a reinvoked mutation request whose object lost
+// mutations Gatekeeper previously applied.
+func (r *reporter) ReportMutationLost(ctx context.Context) error {
+	return metrics.Record(ctx, mutationReinvocationLostM.M(1))
+}
+
+// ReportDryrunDenial counts a request the dryrun constraint would have
+// denied. constraintName is the value of the constraint_name tag, which is
+// not recorded if empty.
+func (r *reporter) ReportDryrunDenial(ctx context.Context, templateKind, constraintName string) error {
+	ctx, err := constraintTags(ctx, templateKind, constraintName)
+	if err != nil {
+		return err
+	}
+	return metrics.Record(ctx, validationDryrunDenialM.M(1))
+}
+
+// ReportDryrunDenialRatio records the ratio of the requests in the dryrun
+// baseline window the dryrun constraint would have denied.
+func (r *reporter) ReportDryrunDenialRatio(ctx context.Context, templateKind, constraintName string, ratio float64) error {
+	ctx, err := constraintTags(ctx, templateKind, constraintName)
+	if err != nil {
+		return err
+	}
+	return metrics.Record(ctx, validationDryrunDenialRatioM.M(ratio))
+}
+
+// constraintTags tags ctx with the kind and, if set, the name of a
+// constraint.
+func constraintTags(ctx context.Context, templateKind, constraintName string) (context.Context, error) {
+	mutators := []tag.Mutator{tag.Insert(templateKindKey, templateKind)}
+	if constraintName != "" {
+		mutators = append(mutators, tag.Insert(constraintNameKey, constraintName))
+	}
+	return tag.New(ctx, mutators...)
+}
+
 // Captures req count metric, recording the count and the duration.
 func (r *reporter) reportRequest(ctx context.Context, response requestResponse, statusKey tag.Key, m stats.Measurement) error {
 	ctx, err := tag.New(
@@ -104,6 +203,39 @@ func register() error {
 			Aggregation: view.Distribution(0.001, 0.002, 0.003, 0.004, 0.005, 0.006, 0.007, 0.008, 0.009, 0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.07, 0.08, 0.09, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.5, 2, 2.5, 3),
 			TagKeys:     []tag.Key{mutationStatusKey},
 		},
+		{
+			Name:        validationTemplateDurationMetricName,
+			Description: validationTemplateTimeInSecM.Description(),
+			Measure:     validationTemplateTimeInSecM,
+			Aggregation: view.Distribution(0.001, 0.002, 0.003, 0.004, 0.005, 0.006, 0.007, 0.008, 0.009, 0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.07, 0.08, 0.09, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1, 1.5, 2, 2.5, 3),
+			TagKeys:     []tag.Key{templateKindKey, constraintNameKey},
+		},
+		{
+			Name:        validationLargeObjectCountMetricName,
+			Description: validationLargeObjectM.Description(),
+			Measure:     validationLargeObjectM,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        mutationReinvocationLostCountMetricName,
+			Description: mutationReinvocationLostM.Description(),
+			Measure:     mutationReinvocationLostM,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        validationDryrunDenialCountMetricName,
+			Description: validationDryrunDenialM.Description(),
+			Measure:     validationDryrunDenialM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{templateKindKey, constraintNameKey},
+		},
+		{
+			Name:        validationDryrunDenialRatioMetricName,
+			Description: validationDryrunDenialRatioM.Description(),
+			Measure:     validationDryrunDenialRatioM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{templateKindKey, constraintNameKey},
+		},
 	}
 	return view.Register(views...)
 }
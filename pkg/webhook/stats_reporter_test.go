@@ -60,6 +60,77 @@ func TestMutationReportRequest(t *testing.T) {
 	check(t, expectedTags, mutationRequestCountMetricName, mutationRequestDurationMetricName)
 }
 
+func TestValidationReportTemplate(t *testing.T) {
+	expectedTags := map[string]string{
+		"template_kind": "K8sRequiredLabels",
+	}
+
+	ctx := context.Background()
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Errorf("newStatsReporter() error %v", err)
+	}
+	err = r.ReportValidationTemplate(ctx, "K8sRequiredLabels", "must-have-owner", expectedDurationValueMin)
+	if err != nil {
+		t.Errorf("ReportValidationTemplate error %v", err)
+	}
+	err = r.ReportValidationTemplate(ctx, "K8sRequiredLabels", "must-have-owner", expectedDurationValueMax)
+	if err != nil {
+		t.Errorf("ReportValidationTemplate error %v", err)
+	}
+
+	row := checkData(t, validationTemplateDurationMetricName, expectedRowLength)
+	durationValue, ok := row.Data.(*view.DistributionData)
+	if !ok {
+		t.Error("ReportValidationTemplate should have aggregation Distribution()")
+	}
+	for _, tag := range row.Tags {
+		if tag.Value != expectedTags[tag.Key.Name()] {
+			t.Errorf("ReportValidationTemplate tags does not match for %v", tag.Key.Name())
+		}
+	}
+	if durationValue.Count != expectedCount {
+		t.Errorf("Metric: %v - Expected %v, got %v. ", validationTemplateDurationMetricName, expectedCount, durationValue.Count)
+	}
+}
+
+func TestValidationReportDryrunDenial(t *testing.T) {
+	ctx := context.Background()
+	r, err := newStatsReporter()
+	if err != nil {
+		t.Errorf("newStatsReporter() error %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := r.ReportDryrunDenial(ctx, "K8sRequiredLabels", ""); err != nil {
+			t.Errorf("ReportDryrunDenial error %v", err)
+		}
+	}
+	if err := r.ReportDryrunDenialRatio(ctx, "K8sRequiredLabels", "", 0.25); err != nil {
+		t.Errorf("ReportDryrunDenialRatio error %v", err)
+	}
+
+	row := checkData(t, validationDryrunDenialCountMetricName, expectedRowLength)
+	count, ok := row.Data.(*view.CountData)
+	if !ok {
+		t.Fatal("ReportDryrunDenial should have aggregation Count()")
+	}
+	if count.Value != expectedCount {
+		t.Errorf("Metric: %v - Expected %v, got %v. ", validationDryrunDenialCountMetricName, expectedCount, count.Value)
+	}
+	if len(row.Tags) != 1 || row.Tags[0].Value != "K8sRequiredLabels" {
+		t.Errorf("got tags %v, want only the template kind", row.Tags)
+	}
+
+	row = checkData(t, validationDryrunDenialRatioMetricName, expectedRowLength)
+	ratio, ok := row.Data.(*view.LastValueData)
+	if !ok {
+		t.Fatal("ReportDryrunDenialRatio should have aggregation LastValue()")
+	}
+	if ratio.Value != 0.25 {
+		t.Errorf("Metric: %v - Expected %v, got %v. ", validationDryrunDenialRatioMetricName, 0.25, ratio.Value)
+	}
+}
+
 func check(t *testing.T, expectedTags map[string]string, requestCountMetricName string, requestDurationMetricName string) {
 	// count test
 	row := checkData(t, requestCountMetricName, expectedRowLength)
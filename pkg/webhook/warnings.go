@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+var (
+	aggregateWarnings      = flag.Bool("aggregate-admission-warnings", false, "(alpha) deduplicate the warnings of an admission response and group them by ConstraintTemplate, in a stable order")
+	maxWarningsPerTemplate = flag.Int("max-admission-warnings-per-template", 5, "(alpha) with --aggregate-admission-warnings, the number of warnings returned per ConstraintTemplate, the rest being summarized as \"and N more\". 0 means no limit")
+)
+
+// warning is a warning of an admission response, raised by a constraint of
+// the ConstraintTemplate creating kind.
+type warning struct {
+	kind string
+	msg  string
+}
+
+// composeWarnings returns the messages of warnings. Unless aggregation is
+// enabled they are returned as raised.
+func composeWarnings(warnings []warning) []string {
+	if !*aggregateWarnings {
+		var msgs []string
+		for _, w := range warnings {
+			msgs = append(msgs, w.msg)
+		}
+		return msgs
+	}
+	return aggregate(warnings, *maxWarningsPerTemplate)
+}
+
+// aggregate deduplicates warnings and groups them by the kind of the
+// ConstraintTemplate raising them, ordering groups by kind and the messages of
+// each group alphabetically so responses do not depend on the order
+// constraints were evaluated in. Groups with more than max messages are
+// truncated, the remainder being counted in a final message. A max of 0 does
+// not truncate groups.
+func aggregate(warnings []warning, max int) []string {
+	byKind := make(map[string][]string)
+	seen := make(map[warning]bool)
+	for _, w := range warnings {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		byKind[w.kind] = append(byKind[w.kind], w.msg)
+	}
+
+	kinds := make([]string, 0, len(byKind))
+	for kind := range byKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var msgs []string
+	for _, kind := range kinds {
+		group := byKind[kind]
+		sort.Strings(group)
+		if max <= 0 || len(group) <= max {
+			msgs = append(msgs, group...)
+			continue
+		}
+		msgs = append(msgs, group[:max]...)
+		msgs = append(msgs, fmt.Sprintf("[%s] and %d more", kind, len(group)-max))
+	}
+	return msgs
+}
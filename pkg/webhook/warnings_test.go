@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAggregate(t *testing.T) {
+	tcs := []struct {
+		name     string
+		warnings []warning
+		max      int
+		want     []string
+	}{
+		{
+			name: "no warnings",
+		},
+		{
+			name: "grouped by kind in stable order",
+			warnings: []warning{
+				{kind: "K8sRequiredLabels", msg: "[team] missing label team"},
+				{kind: "K8sAllowedRepos", msg: "[repos] image nginx is not allowed"},
+				{kind: "K8sRequiredLabels", msg: "[owner] missing label owner"},
+			},
+			want: []string{
+				"[repos] image nginx is not allowed",
+				"[owner] missing label owner",
+				"[team] missing label team",
+			},
+		},
+		{
+			name: "duplicates removed",
+			warnings: []warning{
+				{kind: "K8sRequiredLabels", msg: "[owner] missing label owner"},
+				{kind: "K8sRequiredLabels", msg: "[owner] missing label owner"},
+			},
+			want: []string{"[owner] missing label owner"},
+		},
+		{
+			name: "groups capped",
+			max:  1,
+			warnings: []warning{
+				{kind: "K8sRequiredLabels", msg: "[team] missing label team"},
+				{kind: "K8sRequiredLabels", msg: "[owner] missing label owner"},
+				{kind: "K8sRequiredLabels", msg: "[env] missing label env"},
+				{kind: "K8sAllowedRepos", msg: "[repos] image nginx is not allowed"},
+			},
+			want: []string{
+				"[repos] image nginx is not allowed",
+				"[env] missing label env",
+				"[K8sRequiredLabels] and 2 more",
+			},
+		},
+		{
+			name: "duplicates not counted against the cap",
+			max:  1,
+			warnings: []warning{
+				{kind: "K8sRequiredLabels", msg: "[owner] missing label owner"},
+				{kind: "K8sRequiredLabels", msg: "[owner] missing label owner"},
+			},
+			want: []string{"[owner] missing label owner"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := aggregate(tc.warnings, tc.max)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestComposeWarnings_NotAggregated(t *testing.T) {
+	warnings := []warning{
+		{kind: "K8sRequiredLabels", msg: "[team] missing label team"},
+		{kind: "K8sRequiredLabels", msg: "[team] missing label team"},
+		{kind: "K8sAllowedRepos", msg: "[repos] image nginx is not allowed"},
+	}
+	want := []string{
+		"[team] missing label team",
+		"[team] missing label team",
+		"[repos] image nginx is not allowed",
+	}
+	if diff := cmp.Diff(want, composeWarnings(warnings)); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -25,10 +25,14 @@ module.exports = {
         'customize-admission',
         'metrics',
         'debug',
+        'decision-log',
         'emergency',
         'vendor-specific',
         'failing-closed',
         'mutation',
+        'expansion',
+        'exceptions',
+        'policy-recipes',
         'constrainttemplates'
       ],
     },